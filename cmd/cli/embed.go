@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/gibram-io/gibram/pkg/embed"
+)
+
+// cliVectorDim is the size of the CLI's random and mock embeddings, matching
+// the server's default -dim
+const cliVectorDim = 1536
+
+// embedCacheSize is how many embeddings the CLI remembers
+const embedCacheSize = 10000
+
+// newEmbedder returns the embedder for -embed: nil for random vectors,
+// otherwise the named provider. API keys come from OPENAI_API_KEY and
+// COHERE_API_KEY.
+func newEmbedder(provider, model string) (embed.Embedder, error) {
+	switch provider {
+	case "", "random":
+		return nil, nil
+	case "mock":
+		return embed.NewMock(cliVectorDim), nil
+	case "openai":
+		key := os.Getenv("OPENAI_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("-embed openai needs OPENAI_API_KEY")
+		}
+		return embed.NewOpenAI(key, model), nil
+	case "cohere":
+		key := os.Getenv("COHERE_API_KEY")
+		if key == "" {
+			return nil, fmt.Errorf("-embed cohere needs COHERE_API_KEY")
+		}
+		return embed.NewCohere(key, model), nil
+	}
+	return nil, fmt.Errorf("unknown embedding provider %q (want random, mock, openai or cohere)", provider)
+}
+
+// embeddings returns one embedding per text from the shell's embedder, or
+// random ones without an embedder
+func (s *shell) embeddings(texts ...string) ([][]float32, error) {
+	if s.embedder == nil {
+		vecs := make([][]float32, len(texts))
+		for i := range vecs {
+			vecs[i] = randomEmbedding(cliVectorDim)
+		}
+		return vecs, nil
+	}
+	return s.embedder.Embed(context.Background(), texts)
+}
+
+// entityText is the text embedded for an entity, as the server's
+// embedding provider builds it
+func entityText(title, description string) string {
+	if description == "" {
+		return title
+	}
+	return title + ": " + description
+}
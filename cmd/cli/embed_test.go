@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gibram-io/gibram/pkg/embed"
+)
+
+func TestNewEmbedder(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("COHERE_API_KEY", "co-test")
+
+	if e, err := newEmbedder("random", ""); e != nil || err != nil {
+		t.Errorf("newEmbedder(random) = %v, %v; want nil, nil", e, err)
+	}
+	if e, err := newEmbedder("mock", ""); err != nil {
+		t.Errorf("newEmbedder(mock) error: %v", err)
+	} else if m, ok := e.(*embed.Mock); !ok || m.Dim != cliVectorDim {
+		t.Errorf("newEmbedder(mock) = %#v, want a %d-dim Mock", e, cliVectorDim)
+	}
+	if e, err := newEmbedder("cohere", "embed-multilingual-v3.0"); err != nil {
+		t.Errorf("newEmbedder(cohere) error: %v", err)
+	} else if c, ok := e.(*embed.Cohere); !ok || c.APIKey != "co-test" || c.Model != "embed-multilingual-v3.0" {
+		t.Errorf("newEmbedder(cohere) = %#v", e)
+	}
+	if _, err := newEmbedder("openai", ""); err == nil {
+		t.Error("newEmbedder(openai) without OPENAI_API_KEY succeeded")
+	}
+	if _, err := newEmbedder("word2vec", ""); err == nil {
+		t.Error("newEmbedder(word2vec) succeeded")
+	}
+}
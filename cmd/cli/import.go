@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// IMPORT sends records in batches of up to importBatchRecords records or
+// about importBatchBytes bytes, well under the default frame limit
+const (
+	importBatchRecords = 1000
+	importBatchBytes   = 2 * 1024 * 1024
+)
+
+// maxImportLine bounds one NDJSON record; a 1536-dim embedding as JSON is
+// around 30KB
+const maxImportLine = 16 * 1024 * 1024
+
+// entityRecord is one line of an IMPORT entities file. Entities without an
+// embedding get one from the shell's embedder, as with ADDENT.
+type entityRecord struct {
+	ExternalID  string            `json:"external_id"`
+	Title       string            `json:"title"`
+	Type        string            `json:"type"`
+	Description string            `json:"description"`
+	Embedding   []float32         `json:"embedding"`
+	Metadata    map[string]string `json:"metadata"`
+}
+
+// relationshipRecord is one line of an IMPORT relationships file. Weight
+// defaults to 1.0, as with ADDREL.
+type relationshipRecord struct {
+	ExternalID  string   `json:"external_id"`
+	SourceID    uint64   `json:"source_id"`
+	TargetID    uint64   `json:"target_id"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Weight      *float32 `json:"weight"`
+	Directed    bool     `json:"directed"`
+}
+
+// importStats summarizes an IMPORT. Skipped counts records the server
+// dropped, such as duplicate external IDs or relationships to missing
+// entities.
+type importStats struct {
+	Kind      string  `json:"kind"`
+	Records   int     `json:"records"`
+	Imported  int     `json:"imported"`
+	Skipped   int     `json:"skipped"`
+	Batches   int     `json:"batches"`
+	Millis    int64   `json:"duration_ms"`
+	PerSecond float64 `json:"per_second"`
+}
+
+// importFile imports the NDJSON records in path as kind ("entities" or
+// "relationships"). On error the stats count the batches already sent.
+func (s *shell) importFile(kind, path string) (*importStats, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	switch kind {
+	case "entities":
+		return importNDJSON(kind, f, decodeEntity, func(batch []types.BulkEntityInput) (int, error) {
+			if err := s.fillEmbeddings(batch); err != nil {
+				return 0, err
+			}
+			ids, err := s.c.MSetEntities(batch)
+			return len(ids), err
+		})
+	case "relationships":
+		return importNDJSON(kind, f, decodeRelationship, func(batch []types.BulkRelationshipInput) (int, error) {
+			ids, err := s.c.MSetRelationships(batch)
+			return len(ids), err
+		})
+	}
+	return nil, fmt.Errorf("unknown import kind %q (want entities or relationships)", kind)
+}
+
+// importNDJSON decodes each non-blank line of r and sends the records in
+// batches. decode returns a record and its approximate encoded size; send
+// returns how many records of a batch were created.
+func importNDJSON[T any](kind string, r io.Reader, decode func([]byte) (T, int, error), send func([]T) (int, error)) (*importStats, error) {
+	stats := &importStats{Kind: kind}
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		stats.Millis = elapsed.Milliseconds()
+		if elapsed > 0 {
+			stats.PerSecond = float64(stats.Imported) / elapsed.Seconds()
+		}
+	}()
+
+	var batch []T
+	batchBytes := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		created, err := send(batch)
+		if err != nil {
+			return fmt.Errorf("batch %d: %w", stats.Batches+1, err)
+		}
+		stats.Batches++
+		stats.Imported += created
+		stats.Skipped += len(batch) - created
+		batch, batchBytes = batch[:0], 0
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLine)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		record, size, err := decode(line)
+		if err != nil {
+			return stats, fmt.Errorf("line %d: %w", n, err)
+		}
+		stats.Records++
+		batch = append(batch, record)
+		batchBytes += size
+		if len(batch) >= importBatchRecords || batchBytes >= importBatchBytes {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+	return stats, flush()
+}
+
+func decodeEntity(line []byte) (types.BulkEntityInput, int, error) {
+	var rec entityRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return types.BulkEntityInput{}, 0, err
+	}
+	if rec.Title == "" {
+		return types.BulkEntityInput{}, 0, fmt.Errorf("entity %q has no title", rec.ExternalID)
+	}
+
+	// Count a missing embedding at the size fillEmbeddings will give it
+	size := len(rec.ExternalID) + len(rec.Title) + len(rec.Type) + len(rec.Description) + 32
+	if len(rec.Embedding) > 0 {
+		size += 4 * len(rec.Embedding)
+	} else {
+		size += 4 * cliVectorDim
+	}
+	for k, v := range rec.Metadata {
+		size += len(k) + len(v) + 4
+	}
+	return types.BulkEntityInput{
+		ExternalID:  rec.ExternalID,
+		Title:       rec.Title,
+		Type:        rec.Type,
+		Description: rec.Description,
+		Embedding:   rec.Embedding,
+		Metadata:    rec.Metadata,
+	}, size, nil
+}
+
+// fillEmbeddings embeds the entities of batch that have no embedding, in
+// one embedder call
+func (s *shell) fillEmbeddings(batch []types.BulkEntityInput) error {
+	var missing []int
+	var texts []string
+	for i, ent := range batch {
+		if len(ent.Embedding) == 0 {
+			missing = append(missing, i)
+			texts = append(texts, entityText(ent.Title, ent.Description))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	vecs, err := s.embeddings(texts...)
+	if err != nil {
+		return err
+	}
+	for j, i := range missing {
+		batch[i].Embedding = vecs[j]
+	}
+	return nil
+}
+
+func decodeRelationship(line []byte) (types.BulkRelationshipInput, int, error) {
+	var rec relationshipRecord
+	if err := json.Unmarshal(line, &rec); err != nil {
+		return types.BulkRelationshipInput{}, 0, err
+	}
+	if rec.SourceID == 0 || rec.TargetID == 0 {
+		return types.BulkRelationshipInput{}, 0, fmt.Errorf("relationship %q needs source_id and target_id", rec.ExternalID)
+	}
+	weight := float32(1.0)
+	if rec.Weight != nil {
+		weight = *rec.Weight
+	}
+
+	size := len(rec.ExternalID) + len(rec.Type) + len(rec.Description) + 48
+	return types.BulkRelationshipInput{
+		ExternalID:  rec.ExternalID,
+		SourceID:    rec.SourceID,
+		TargetID:    rec.TargetID,
+		Type:        rec.Type,
+		Description: rec.Description,
+		Weight:      weight,
+		Directed:    rec.Directed,
+	}, size, nil
+}
+
+// printImportStats prints an IMPORT summary with its throughput
+func printImportStats(w io.Writer, stats *importStats) {
+	batches := "batches"
+	if stats.Batches == 1 {
+		batches = "batch"
+	}
+	fmt.Fprintf(w, "Imported %d of %d %s in %d %s\n", stats.Imported, stats.Records, stats.Kind, stats.Batches, batches)
+	if stats.Skipped > 0 {
+		fmt.Fprintf(w, "  Skipped: %d (rejected by the server)\n", stats.Skipped)
+	}
+	fmt.Fprintf(w, "  Duration: %v\n", time.Duration(stats.Millis)*time.Millisecond)
+	fmt.Fprintf(w, "  Throughput: %.0f %s/sec\n", stats.PerSecond, stats.Kind)
+}
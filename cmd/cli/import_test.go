@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeNDJSON(t *testing.T, name string, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	return path
+}
+
+// lastJSON decodes the last line the shell wrote into v
+func lastJSON(t *testing.T, out string, v any) {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), v); err != nil {
+		t.Fatalf("output %q is not JSON: %v", lines[len(lines)-1], err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	sh, buf := newTestShell(t)
+
+	embedding, _ := json.Marshal(randomEmbedding(1536))
+	entities := writeNDJSON(t, "entities.ndjson",
+		`{"external_id":"alice","title":"Alice","type":"person","description":"founder","metadata":{"team":"core"}}`,
+		`{"external_id":"acme","title":"Acme","type":"org","embedding":`+string(embedding)+`}`,
+		``,
+		`{"external_id":"bob","title":"Bob","type":"person"}`,
+		`{"external_id":"alice","title":"Alice again","type":"person"}`,
+	)
+	relationships := writeNDJSON(t, "relationships.ndjson",
+		`{"source_id":1,"target_id":2,"type":"FOUNDED","weight":0.9,"directed":true}`,
+		`{"source_id":3,"target_id":2,"type":"WORKS_AT"}`,
+	)
+
+	if err := sh.execute("IMPORT entities " + entities); err != nil {
+		t.Fatalf("IMPORT entities error: %v", err)
+	}
+	var stats importStats
+	lastJSON(t, buf.String(), &stats)
+	if stats.Records != 4 || stats.Imported != 3 || stats.Skipped != 1 || stats.Batches != 1 {
+		t.Errorf("IMPORT entities stats = %+v, want 3 of 4 imported in 1 batch", stats)
+	}
+
+	if err := sh.execute("IMPORT relationships " + relationships); err != nil {
+		t.Fatalf("IMPORT relationships error: %v", err)
+	}
+	lastJSON(t, buf.String(), &stats)
+	if stats.Imported != 2 {
+		t.Errorf("IMPORT relationships stats = %+v, want 2 imported", stats)
+	}
+
+	if err := sh.execute("INFO"); err != nil {
+		t.Fatalf("INFO error: %v", err)
+	}
+	var info struct {
+		EntityCount       int `json:"entity_count"`
+		RelationshipCount int `json:"relationship_count"`
+	}
+	lastJSON(t, buf.String(), &info)
+	if info.EntityCount != 3 || info.RelationshipCount != 2 {
+		t.Errorf("INFO = %+v, want 3 entities and 2 relationships", info)
+	}
+}
+
+func TestImport_Batches(t *testing.T) {
+	sh, buf := newTestShell(t)
+
+	// Random 1536-dim embeddings make each record about 6KB, so 500 of
+	// them need two batches to stay under importBatchBytes
+	lines := make([]string, 500)
+	for i := range lines {
+		lines[i] = fmt.Sprintf(`{"external_id":"e%d","title":"Entity %d","type":"concept"}`, i, i)
+	}
+	if err := sh.execute("IMPORT entities " + writeNDJSON(t, "entities.ndjson", lines...)); err != nil {
+		t.Fatalf("IMPORT entities error: %v", err)
+	}
+	var stats importStats
+	lastJSON(t, buf.String(), &stats)
+	if stats.Imported != 500 || stats.Batches != 2 {
+		t.Errorf("stats = %+v, want 500 imported in 2 batches", stats)
+	}
+}
+
+func TestImport_BadRecord(t *testing.T) {
+	sh, _ := newTestShell(t)
+	path := writeNDJSON(t, "relationships.ndjson",
+		`{"source_id":1,"target_id":2,"type":"KNOWS"}`,
+		`{"source_id":1,"type":"KNOWS"}`,
+	)
+	err := sh.execute("IMPORT relationships " + path)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("IMPORT error = %v, want one naming line 2", err)
+	}
+	if err := sh.execute("IMPORT documents " + path); err == nil {
+		t.Error("IMPORT documents succeeded, want unknown kind error")
+	}
+}
@@ -4,31 +4,88 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gibram-io/gibram/pkg/client"
+	"github.com/gibram-io/gibram/pkg/embed"
 	"github.com/gibram-io/gibram/pkg/types"
 	"github.com/gibram-io/gibram/pkg/version"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run runs the CLI and returns its exit code
+func run() int {
 	host := flag.String("h", "localhost:6161", "Server address")
 	useTLS := flag.Bool("tls", true, "Use TLS (default: true)")
 	skipVerify := flag.Bool("insecure", true, "Skip TLS certificate verification (default: true for self-signed)")
 	apiKey := flag.String("key", "", "API key for authentication")
+	jsonMode := flag.Bool("json", false, "Print results as JSON, one document per line")
+	scriptFile := flag.String("f", "", "Run the commands in this file, then exit")
+	continueOnError := flag.Bool("continue-on-error", false, "Keep running a script after a failed command (the exit code is still 1)")
+	embedProvider := flag.String("embed", "random", "Embeddings for ADDENT, ADDTU and IMPORT: random, mock, openai or cohere")
+	embedModel := flag.String("embed-model", "", "Embedding model (default: the provider's default)")
+	embedCache := flag.String("embed-cache", "", "File keeping computed embeddings between runs; use one per provider and model")
 	flag.Parse()
 
-	fmt.Println("╔═══════════════════════════════════════╗")
-	fmt.Printf("║         GibRAM CLI v%-7s        ║\n", version.Version)
-	fmt.Println("║     Type 'help' for commands          ║")
-	fmt.Println("╚═══════════════════════════════════════╝")
-	fmt.Println()
+	out := &renderer{w: os.Stdout, jsonMode: *jsonMode}
+
+	embedder, err := newEmbedder(*embedProvider, *embedModel)
+	if err != nil {
+		out.error(err)
+		return 1
+	}
+	if embedder != nil {
+		// Re-adding the same text should not pay for its embedding twice
+		cache := embed.NewCache(embedder, embedCacheSize)
+		if *embedCache != "" {
+			if err := cache.LoadFile(*embedCache); err != nil {
+				out.error(err)
+				return 1
+			}
+			defer func() {
+				if err := cache.SaveFile(*embedCache); err != nil {
+					fmt.Fprintf(os.Stderr, "Save embedding cache: %v\n", err)
+				}
+			}()
+		}
+		embedder = cache
+	}
+
+	// Commands come from a script file or piped stdin, or else a prompt
+	var script io.Reader
+	switch {
+	case *scriptFile != "":
+		f, err := os.Open(*scriptFile)
+		if err != nil {
+			out.error(err)
+			return 1
+		}
+		defer func() { _ = f.Close() }()
+		script = f
+	case !isTerminal(os.Stdin):
+		script = os.Stdin
+	}
+	prompt := script == nil && !out.jsonMode
+
+	if prompt {
+		fmt.Println("╔═══════════════════════════════════════╗")
+		fmt.Printf("║         GibRAM CLI v%-7s        ║\n", version.Version)
+		fmt.Println("║     Type 'help' for commands          ║")
+		fmt.Println("╚═══════════════════════════════════════╝")
+		fmt.Println()
+	}
 
 	// Connect with TLS config
 	config := client.DefaultPoolConfig()
@@ -38,21 +95,33 @@ func main() {
 
 	c, err := client.NewClientWithConfig(*host, "cli-session", config)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		out.error(err)
+		return 1
 	}
 	defer func() {
 		if err := c.Close(); err != nil {
-			fmt.Printf("Close error: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Close error: %v\n", err)
 		}
 	}()
 
-	fmt.Printf("Connected to %s\n\n", *host)
+	sh := &shell{c: c, out: out, embedder: embedder}
+	if script != nil {
+		if !sh.runScript(script, *continueOnError) {
+			return 1
+		}
+		return 0
+	}
+
+	if prompt {
+		fmt.Printf("Connected to %s\n\n", *host)
+	}
 
 	reader := bufio.NewReader(os.Stdin)
 
 	for {
-		fmt.Printf("gibram %s> ", *host)
+		if prompt {
+			fmt.Printf("gibram %s> ", *host)
+		}
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			break
@@ -63,367 +132,666 @@ func main() {
 			continue
 		}
 
-		parts := strings.Fields(line)
-		cmd := strings.ToUpper(parts[0])
-		args := parts[1:]
+		if err := sh.execute(line); errors.Is(err, errQuit) {
+			return 0
+		} else if err != nil {
+			out.error(err)
+		}
+	}
+	return 0
+}
 
-		switch cmd {
-		case "QUIT", "EXIT":
-			fmt.Println("Bye!")
-			return
+// isTerminal reports whether f is a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
 
-		case "HELP":
-			printHelp()
+// errQuit is returned by QUIT to end the session
+var errQuit = errors.New("quit")
 
-		case "PING":
-			start := time.Now()
-			if err := c.Ping(); err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Printf("PONG (%v)\n", time.Since(start))
-			}
+// shell runs CLI commands against a server
+type shell struct {
+	c        *client.Client
+	out      *renderer
+	embedder embed.Embedder // nil means random embeddings
+}
 
-		case "INFO":
-			info, err := c.Info()
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				continue
-			}
-			fmt.Println("┌─────────────────────────────────────┐")
-			fmt.Printf("│ Server: GraphMemoryRAG v%s      │\n", info.Version)
-			fmt.Println("├─────────────────────────────────────┤")
-			fmt.Printf("│ Documents:     %-5d                │\n", info.DocumentCount)
-			fmt.Printf("│ TextUnits:     %-5d                │\n", info.TextUnitCount)
-			fmt.Printf("│ Entities:      %-5d                │\n", info.EntityCount)
-			fmt.Printf("│ Relationships: %-5d                │\n", info.RelationshipCount)
-			fmt.Printf("│ Communities:   %-5d                │\n", info.CommunityCount)
-			fmt.Printf("│ VectorDim:     %-5d                │\n", info.VectorDim)
-			fmt.Println("└─────────────────────────────────────┘")
-
-		case "ADDDOC":
-			// ADDDOC <ext_id> <filename>
-			if len(args) < 2 {
-				fmt.Println("Usage: ADDDOC <ext_id> <filename>")
-				continue
-			}
-			id, err := c.AddDocument(args[0], args[1])
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Printf("OK (doc_id: %d)\n", id)
-			}
+// runScript runs the commands in r line by line, skipping blank lines and
+// # comments, until QUIT or the end of r. It stops at the first failed
+// command unless continueOnError is set, and reports whether all succeeded.
+func (s *shell) runScript(r io.Reader, continueOnError bool) bool {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxScriptLine)
+	ok := true
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
 
-		case "ADDTEXTUNIT", "ADDTU":
-			// ADDTU <ext_id> <doc_id> <content>
-			if len(args) < 3 {
-				fmt.Println("Usage: ADDTU <ext_id> <doc_id> <content...>")
-				continue
+		err := s.execute(line)
+		if errors.Is(err, errQuit) {
+			break
+		}
+		if err != nil {
+			s.out.error(fmt.Errorf("line %d: %w", n, err))
+			ok = false
+			if !continueOnError {
+				return false
 			}
-			docID, _ := strconv.ParseUint(args[1], 10, 64)
-			content := strings.Join(args[2:], " ")
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		s.out.error(fmt.Errorf("read script: %w", err))
+		return false
+	}
+	return ok
+}
 
-			// Generate random embedding for testing
-			embedding := randomEmbedding(1536)
+// maxScriptLine bounds the length of a script line
+const maxScriptLine = 1024 * 1024
 
-			id, err := c.AddTextUnit(args[0], docID, content, embedding, len(content)/4)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Printf("OK (textunit_id: %d)\n", id)
-			}
+// execute runs one command line, writing its result. A failed command's
+// error is returned for the caller to report.
+func (s *shell) execute(line string) error {
+	parts := strings.Fields(line)
+	if len(parts) == 0 {
+		return nil
+	}
+	cmd := strings.ToUpper(parts[0])
+	args := parts[1:]
+	c := s.c
+
+	switch cmd {
+	case "QUIT", "EXIT":
+		if !s.out.jsonMode {
+			fmt.Fprintln(s.out.w, "Bye!")
+		}
+		return errQuit
 
-		case "ADDENTITY", "ADDENT":
-			// ADDENT <ext_id> <title> <type> <description...>
-			if len(args) < 4 {
-				fmt.Println("Usage: ADDENT <ext_id> <title> <type> <description...>")
-				continue
-			}
-			description := strings.Join(args[3:], " ")
+	case "HELP":
+		s.out.result(map[string]string{"help": helpText}, func(w io.Writer) { fmt.Fprintln(w, helpText) })
 
-			// Generate random embedding for testing
-			embedding := randomEmbedding(1536)
+	case "PING":
+		start := time.Now()
+		if err := c.Ping(); err != nil {
+			return err
+		}
+		elapsed := time.Since(start)
+		s.out.result(map[string]int64{"latency_us": elapsed.Microseconds()}, func(w io.Writer) {
+			fmt.Fprintf(w, "PONG (%v)\n", elapsed)
+		})
 
-			id, err := c.AddEntity(args[0], args[1], args[2], description, embedding)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Printf("OK (entity_id: %d)\n", id)
-			}
+	case "INFO":
+		info, err := c.Info()
+		if err != nil {
+			return err
+		}
 
-		case "GETENT":
-			// GETENT <id>
-			if len(args) < 1 {
-				fmt.Println("Usage: GETENT <id>")
-				continue
-			}
-			id, _ := strconv.ParseUint(args[0], 10, 64)
-			ent, err := c.GetEntity(id)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				data, _ := json.MarshalIndent(ent, "", "  ")
-				fmt.Println(string(data))
+		// INFO TYPES adds the per-type breakdown
+		var counts *types.TypeCounts
+		if len(args) > 0 && strings.EqualFold(args[0], "TYPES") {
+			if counts, err = c.EntityStats(); err != nil {
+				return err
 			}
+		}
+		renderInfo(s.out, info, counts)
 
-		case "GETENTBYTITLE":
-			// GETENTBYTITLE <title>
-			if len(args) < 1 {
-				fmt.Println("Usage: GETENTBYTITLE <title>")
-				continue
-			}
-			title := strings.Join(args, " ")
-			ent, err := c.GetEntityByTitle(title)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				data, _ := json.MarshalIndent(ent, "", "  ")
-				fmt.Println(string(data))
-			}
+	case "ADDDOC":
+		// ADDDOC <ext_id> <filename>
+		if len(args) < 2 {
+			return usageError("ADDDOC <ext_id> <filename>")
+		}
+		id, err := c.AddDocument(args[0], args[1])
+		if err != nil {
+			return err
+		}
+		s.out.ok("doc_id", id)
 
-		case "ADDREL":
-			// ADDREL <source_id> <target_id> <type> [description...]
-			if len(args) < 3 {
-				fmt.Println("Usage: ADDREL <source_id> <target_id> <type> [description...]")
-				continue
-			}
-			sourceID, _ := strconv.ParseUint(args[0], 10, 64)
-			targetID, _ := strconv.ParseUint(args[1], 10, 64)
-			relType := args[2]
-			description := ""
-			if len(args) > 3 {
-				description = strings.Join(args[3:], " ")
-			}
+	case "ADDTEXTUNIT", "ADDTU":
+		// ADDTU <ext_id> <doc_id> <content>
+		if len(args) < 3 {
+			return usageError("ADDTU <ext_id> <doc_id> <content...>")
+		}
+		docID, _ := strconv.ParseUint(args[1], 10, 64)
+		content := strings.Join(args[2:], " ")
 
-			id, err := c.AddRelationship("", sourceID, targetID, relType, description, 1.0)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Printf("OK (rel_id: %d)\n", id)
-			}
+		embeddings, err := s.embeddings(content)
+		if err != nil {
+			return err
+		}
 
-		case "LINK":
-			// LINK <textunit_id> <entity_id>
-			if len(args) < 2 {
-				fmt.Println("Usage: LINK <textunit_id> <entity_id>")
-				continue
-			}
-			tuID, _ := strconv.ParseUint(args[0], 10, 64)
-			entID, _ := strconv.ParseUint(args[1], 10, 64)
-			if err := c.LinkTextUnitToEntity(tuID, entID); err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Println("OK")
+		id, err := c.AddTextUnit(args[0], docID, content, embeddings[0], len(content)/4)
+		if err != nil {
+			return err
+		}
+		s.out.ok("textunit_id", id)
+
+	case "ADDENTITY", "ADDENT":
+		// ADDENT <ext_id> <title> <type> <description...>
+		if len(args) < 4 {
+			return usageError("ADDENT <ext_id> <title> <type> <description...>")
+		}
+		description := strings.Join(args[3:], " ")
+
+		embeddings, err := s.embeddings(entityText(args[1], description))
+		if err != nil {
+			return err
+		}
+
+		id, err := c.AddEntity(args[0], args[1], args[2], description, embeddings[0])
+		if err != nil {
+			return err
+		}
+		s.out.ok("entity_id", id)
+
+	case "GETENT":
+		// GETENT <id>
+		if len(args) < 1 {
+			return usageError("GETENT <id>")
+		}
+		id, _ := strconv.ParseUint(args[0], 10, 64)
+		ent, err := c.GetEntity(id)
+		if err != nil {
+			return err
+		}
+		s.out.result(ent, func(w io.Writer) {
+			data, _ := json.MarshalIndent(ent, "", "  ")
+			fmt.Fprintln(w, string(data))
+		})
+
+	case "GETENTBYTITLE":
+		// GETENTBYTITLE <title>
+		if len(args) < 1 {
+			return usageError("GETENTBYTITLE <title>")
+		}
+		title := strings.Join(args, " ")
+		ent, err := c.GetEntityByTitle(title)
+		if err != nil {
+			return err
+		}
+		s.out.result(ent, func(w io.Writer) {
+			data, _ := json.MarshalIndent(ent, "", "  ")
+			fmt.Fprintln(w, string(data))
+		})
+
+	case "ADDREL":
+		// ADDREL <source_id> <target_id> <type> [description...]
+		if len(args) < 3 {
+			return usageError("ADDREL <source_id> <target_id> <type> [description...]")
+		}
+		sourceID, _ := strconv.ParseUint(args[0], 10, 64)
+		targetID, _ := strconv.ParseUint(args[1], 10, 64)
+		relType := args[2]
+		description := ""
+		if len(args) > 3 {
+			description = strings.Join(args[3:], " ")
+		}
+
+		id, err := c.AddRelationship("", sourceID, targetID, relType, description, 1.0)
+		if err != nil {
+			return err
+		}
+		s.out.ok("rel_id", id)
+
+	case "IMPORT":
+		// IMPORT <entities|relationships> <file.ndjson>
+		if len(args) < 2 {
+			return usageError("IMPORT <entities|relationships> <file.ndjson>")
+		}
+		stats, err := s.importFile(strings.ToLower(args[0]), strings.Join(args[1:], " "))
+		if err != nil {
+			if stats != nil && stats.Imported > 0 {
+				return fmt.Errorf("%w (%d %s imported before the error)", err, stats.Imported, stats.Kind)
 			}
+			return err
+		}
+		s.out.result(stats, func(w io.Writer) { printImportStats(w, stats) })
 
-		case "COMMUNITY":
-			// COMMUNITY COMPUTE [resolution]
-			// COMMUNITY LIST
-			if len(args) < 1 {
-				fmt.Println("Usage: COMMUNITY COMPUTE [resolution] | COMMUNITY LIST")
-				continue
+	case "RELTYPES":
+		// RELTYPES [limit]
+		limit := 0
+		if len(args) > 0 {
+			limit, _ = strconv.Atoi(args[0])
+		}
+		stats, err := c.RelationshipTypeStats(limit)
+		if err != nil {
+			return err
+		}
+		s.out.result(stats, func(w io.Writer) {
+			if len(stats) == 0 {
+				fmt.Fprintln(w, "(no relationships)")
+				return
 			}
-			subCmd := strings.ToUpper(args[0])
-			switch subCmd {
-			case "COMPUTE":
-				resolution := 1.0
-				if len(args) > 1 {
-					resolution, _ = strconv.ParseFloat(args[1], 64)
-				}
-				result, err := c.ComputeCommunities(resolution, 10)
-				if err != nil {
-					fmt.Printf("Error: %v\n", err)
-				} else {
-					fmt.Printf("OK - Found %d communities\n", result.Count)
-					for _, comm := range result.Communities {
-						fmt.Printf("  [%d] %s (%d entities)\n", comm.ID, comm.Title, len(comm.EntityIDs))
-					}
-				}
-			default:
-				fmt.Println("Usage: COMMUNITY COMPUTE [resolution]")
+			fmt.Fprintf(w, "%-30s %8s %10s\n", "TYPE", "COUNT", "AVG_WEIGHT")
+			for _, st := range stats {
+				fmt.Fprintf(w, "%-30s %8d %10.3f\n", st.Type, st.Count, st.AvgWeight)
 			}
+		})
+
+	case "COUNT":
+		// COUNT <document|textunit|entity|relationship|community>
+		if len(args) < 1 {
+			return usageError("COUNT <document|textunit|entity|relationship|community>")
+		}
+		count, err := c.Count(types.ItemType(strings.ToLower(args[0])))
+		if err != nil {
+			return err
+		}
+		s.out.result(map[string]uint64{"count": count}, func(w io.Writer) { fmt.Fprintln(w, count) })
 
-		case "QUERY":
-			// QUERY <topK> <hops> [max_entities] [max_textunits]
-			if len(args) < 2 {
-				fmt.Println("Usage: QUERY <topK> <hops> [max_entities] [max_textunits]")
-				continue
+	case "NEIGHBORS":
+		// NEIGHBORS <entity_id> [out|in|both] [type...]
+		if len(args) < 1 {
+			return usageError("NEIGHBORS <entity_id> [out|in|both] [type...]")
+		}
+		entID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid entity id %q", args[0])
+		}
+		direction := types.DirectionBoth
+		var relTypes []string
+		if len(args) > 1 {
+			switch strings.ToLower(args[1]) {
+			case "out":
+				direction = types.DirectionOutgoing
+			case "in":
+				direction = types.DirectionIncoming
+			case "both":
+			default:
+				return fmt.Errorf("unknown direction %q (want out, in or both)", args[1])
 			}
-			topK, _ := strconv.Atoi(args[0])
-			hops, _ := strconv.Atoi(args[1])
-			maxEnts := 50
-			maxTUs := 10
-			if len(args) > 2 {
-				maxEnts, _ = strconv.Atoi(args[2])
+			relTypes = args[2:]
+		}
+		rels, err := c.GetNeighbors(entID, direction, relTypes)
+		if err != nil {
+			return err
+		}
+		s.out.result(rels, func(w io.Writer) {
+			if len(rels) == 0 {
+				fmt.Fprintln(w, "(no relationships)")
+				return
 			}
-			if len(args) > 3 {
-				maxTUs, _ = strconv.Atoi(args[3])
+			names := entityNames{c: c}
+			for _, rel := range rels {
+				fmt.Fprintf(w, "[%d] %s (weight: %.2f)\n", rel.ID, names.edge(rel, entID), rel.Weight)
 			}
+		})
 
-			// Generate random query vector for testing
-			queryVec := randomEmbedding(1536)
-
-			spec := types.QuerySpec{
-				QueryVector:    queryVec,
-				SearchTypes:    []types.SearchType{types.SearchTypeTextUnit, types.SearchTypeEntity, types.SearchTypeCommunity},
-				TopK:           topK,
-				KHops:          hops,
-				MaxEntities:    maxEnts,
-				MaxTextUnits:   maxTUs,
-				MaxCommunities: 5,
+	case "PATH":
+		// PATH <from_id> <to_id> [maxhops]
+		if len(args) < 2 {
+			return usageError("PATH <from_id> <to_id> [maxhops]")
+		}
+		fromID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid entity id %q", args[0])
+		}
+		toID, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid entity id %q", args[1])
+		}
+		maxHops := defaultPathHops
+		if len(args) > 2 {
+			if maxHops, err = strconv.Atoi(args[2]); err != nil || maxHops < 1 {
+				return fmt.Errorf("invalid maxhops %q", args[2])
 			}
+		}
+		path, err := findPath(c, fromID, toID, maxHops)
+		if err != nil {
+			return err
+		}
+		result := map[string]any{"found": path != nil, "path": path}
+		s.out.result(result, func(w io.Writer) {
+			if path == nil {
+				fmt.Fprintf(w, "(no path within %d hops)\n", maxHops)
+				return
+			}
+			if len(path) == 0 {
+				fmt.Fprintln(w, "(same entity)")
+				return
+			}
+			fmt.Fprintf(w, "%d hop(s):\n", len(path))
+			names := entityNames{c: c}
+			at := fromID
+			for _, rel := range path {
+				fmt.Fprintf(w, "  [%d] %s\n", rel.ID, names.edge(rel, at))
+				at = otherEnd(rel, at)
+			}
+		})
+
+	case "LINK":
+		// LINK <textunit_id> <entity_id>
+		if len(args) < 2 {
+			return usageError("LINK <textunit_id> <entity_id>")
+		}
+		tuID, _ := strconv.ParseUint(args[0], 10, 64)
+		entID, _ := strconv.ParseUint(args[1], 10, 64)
+		if err := c.LinkTextUnitToEntity(tuID, entID); err != nil {
+			return err
+		}
+		s.out.ok("", 0)
 
-			result, err := c.Query(spec)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				continue
-			}
+	case "COMMUNITY":
+		// COMMUNITY COMPUTE [resolution] [leiden|louvain]
+		if len(args) < 1 || !strings.EqualFold(args[0], "COMPUTE") {
+			return usageError("COMMUNITY COMPUTE [resolution] [leiden|louvain]")
+		}
+		resolution := 1.0
+		if len(args) > 1 {
+			resolution, _ = strconv.ParseFloat(args[1], 64)
+		}
+		algorithm := ""
+		if len(args) > 2 {
+			algorithm = strings.ToLower(args[2])
+		}
+		result, err := c.ComputeCommunitiesWithAlgorithm(algorithm, resolution, 10)
+		if err != nil {
+			return err
+		}
+		jsonResult := map[string]any{"count": result.Count, "modularity": result.Modularity, "communities": result.Communities}
+		s.out.result(jsonResult, func(w io.Writer) {
+			fmt.Fprintf(w, "OK - Found %d communities (modularity %.4f)\n", result.Count, result.Modularity)
+			for _, comm := range result.Communities {
+				fmt.Fprintf(w, "  [%d] %s (%d entities)\n", comm.ID, comm.Title, len(comm.EntityIDs))
+			}
+		})
+
+	case "QUERY":
+		// QUERY <topK> <hops> [max_entities] [max_textunits]
+		if len(args) < 2 {
+			return usageError("QUERY <topK> <hops> [max_entities] [max_textunits]")
+		}
+		topK, _ := strconv.Atoi(args[0])
+		hops, _ := strconv.Atoi(args[1])
+		maxEnts := 50
+		maxTUs := 10
+		if len(args) > 2 {
+			maxEnts, _ = strconv.Atoi(args[2])
+		}
+		if len(args) > 3 {
+			maxTUs, _ = strconv.Atoi(args[3])
+		}
 
-			fmt.Printf("Query ID: %d\n", result.QueryID)
-			fmt.Printf("Stats: %d textunits, %d entities, %d communities, %dμs\n",
-				len(result.TextUnits), len(result.Entities), len(result.Communities), result.Stats.DurationMicros)
-
-			if len(result.TextUnits) > 0 {
-				fmt.Println("TextUnits:")
-				for i, tu := range result.TextUnits {
-					content := tu.TextUnit.Content
-					if len(content) > 60 {
-						content = content[:60] + "..."
-					}
-					fmt.Printf("  %d. [id=%d hop=%d sim=%.3f] %s\n", i+1, tu.TextUnit.ID, tu.Hop, tu.Similarity, content)
-				}
-			}
+		// Generate random query vector for testing
+		queryVec := randomEmbedding(cliVectorDim)
+
+		spec := types.QuerySpec{
+			QueryVector:    queryVec,
+			SearchTypes:    []types.SearchType{types.SearchTypeTextUnit, types.SearchTypeEntity, types.SearchTypeCommunity},
+			TopK:           topK,
+			KHops:          hops,
+			MaxEntities:    maxEnts,
+			MaxTextUnits:   maxTUs,
+			MaxCommunities: 5,
+		}
 
-			if len(result.Entities) > 0 {
-				fmt.Println("Entities:")
-				for i, ent := range result.Entities {
-					if i >= 5 {
-						fmt.Printf("  ... and %d more\n", len(result.Entities)-5)
-						break
-					}
-					fmt.Printf("  - %s (%s) [hop=%d]\n", ent.Entity.Title, ent.Entity.Type, ent.Hop)
-				}
-			}
+		result, err := c.Query(spec)
+		if err != nil {
+			return err
+		}
+		s.out.result(result, func(w io.Writer) { printQueryResult(w, result) })
 
-			if len(result.Relationships) > 0 {
-				fmt.Println("Relationships:")
-				for i, rel := range result.Relationships {
-					if i >= 5 {
-						fmt.Printf("  ... and %d more\n", len(result.Relationships)-5)
-						break
-					}
-					fmt.Printf("  - %s -[%s]-> %s\n", rel.SourceTitle, rel.Relationship.Type, rel.TargetTitle)
-				}
-			}
+	case "EXPLAIN":
+		// EXPLAIN <query_id>
+		if len(args) < 1 {
+			return usageError("EXPLAIN <query_id>")
+		}
+		queryID, _ := strconv.ParseUint(args[0], 10, 64)
+		explain, err := c.Explain(queryID)
+		if err != nil {
+			return err
+		}
+		s.out.result(explain, func(w io.Writer) { printExplain(w, explain) })
+
+	case "QSTATS":
+		// QSTATS [window_seconds]
+		window := time.Duration(0)
+		if len(args) > 0 {
+			secs, _ := strconv.Atoi(args[0])
+			window = time.Duration(secs) * time.Second
+		}
+		summary, err := c.QueryStatsSummary(window, false)
+		if err != nil {
+			return err
+		}
+		s.out.result(summary, func(w io.Writer) {
+			fmt.Fprintf(w, "Queries (last %ds): %d\n", summary.WindowSeconds, summary.Count)
+			if summary.Count == 0 {
+				return
+			}
+			fmt.Fprintf(w, "Latency us: avg=%.0f p50=%.0f p95=%.0f p99=%.0f\n",
+				summary.AvgLatencyMicros, summary.P50LatencyMicros, summary.P95LatencyMicros, summary.P99LatencyMicros)
+			fmt.Fprintf(w, "Avg results: textunits=%.1f entities=%.1f communities=%.1f\n",
+				summary.AvgTextUnits, summary.AvgEntities, summary.AvgCommunities)
+			fmt.Fprintf(w, "Avg k_hops: %.2f  empty: %d  relaxed: %d\n", summary.AvgKHops, summary.EmptyResults, summary.Relaxed)
+			fmt.Fprintf(w, "Cache hits: %d (%.1f%%)\n", summary.CacheHits, summary.CacheHitRate*100)
+		})
+
+	case "QUERIES":
+		// QUERIES [limit]
+		limit := 0
+		if len(args) > 0 {
+			limit, _ = strconv.Atoi(args[0])
+		}
+		records, err := c.ListQueries(limit, false)
+		if err != nil {
+			return err
+		}
+		s.out.result(records, func(w io.Writer) {
+			fmt.Fprintf(w, "Queries: %d\n", len(records))
+			for _, rec := range records {
+				fmt.Fprintf(w, "  [%d] %s top_k=%d hops=%d -> %d TUs, %d entities, %d communities (%dus)\n",
+					rec.QueryID, time.Unix(rec.At, 0).Format(time.TimeOnly), rec.Spec.TopK, rec.Spec.KHops,
+					rec.TextUnits, rec.Entities, rec.Communities, rec.Stats.DurationMicros)
+			}
+		})
+
+	case "SNAPSHOT", "SAVE":
+		if err := c.Save(""); err != nil {
+			return err
+		}
+		s.out.result(map[string]bool{"ok": true}, func(w io.Writer) { fmt.Fprintln(w, "OK - Snapshot saved") })
 
-		case "EXPLAIN":
-			// EXPLAIN <query_id>
-			if len(args) < 1 {
-				fmt.Println("Usage: EXPLAIN <query_id>")
-				continue
-			}
-			queryID, _ := strconv.ParseUint(args[0], 10, 64)
-			explain, err := c.Explain(queryID)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				continue
-			}
+	case "QUANTIZE":
+		// QUANTIZE <float32|float16|int8>
+		if len(args) < 1 {
+			return usageError("QUANTIZE <float32|float16|int8>")
+		}
+		before, after, err := c.QuantizeIndex(strings.ToLower(args[0]))
+		if err != nil {
+			return err
+		}
+		s.out.result(map[string]int64{"bytes_before": before, "bytes_after": after}, func(w io.Writer) {
+			fmt.Fprintf(w, "OK - Vector memory %d -> %d bytes\n", before, after)
+		})
 
-			fmt.Printf("Query ID: %d\n", explain.QueryID)
-			fmt.Printf("\nSeeds (%d):\n", len(explain.Seeds))
-			for i, seed := range explain.Seeds {
-				if i >= 5 {
-					fmt.Printf("  ... and %d more\n", len(explain.Seeds)-5)
-					break
-				}
-				fmt.Printf("  - [%s] id=%d ext=%s sim=%.3f\n", seed.Type, seed.ID, seed.ExternalID, seed.Similarity)
-			}
-			fmt.Printf("\nTraversal (%d steps):\n", len(explain.Traversal))
-			for i, step := range explain.Traversal {
-				if i >= 10 {
-					fmt.Printf("  ... and %d more\n", len(explain.Traversal)-10)
-					break
-				}
-				fmt.Printf("  Hop %d: %d -[%s]-> %d (weight=%.2f)\n",
-					step.Hop, step.FromEntityID, step.RelType, step.ToEntityID, step.Weight)
+	case "REBUILD":
+		// REBUILD [async]
+		if len(args) > 0 && strings.EqualFold(args[0], "async") {
+			if err := c.RebuildIndexAsync(); err != nil {
+				return err
 			}
+			s.out.result(map[string]bool{"ok": true}, func(w io.Writer) { fmt.Fprintln(w, "OK - Index rebuild started") })
+			break
+		}
+		if err := c.RebuildIndex(); err != nil {
+			return err
+		}
+		s.out.result(map[string]bool{"ok": true}, func(w io.Writer) { fmt.Fprintln(w, "OK - Index rebuilt") })
 
-		// DEPRECATED: TTL commands removed - session-level management only
-		/*
-			case "SETTTL":
-				// SETTTL <type> <id> <ttl_seconds>
-				if len(args) < 3 {
-					fmt.Println("Usage: SETTTL <document|textunit|entity|community> <id> <ttl_seconds>")
-					continue
-				}
-				itemType := types.ItemType(strings.ToLower(args[0]))
-				id, _ := strconv.ParseUint(args[1], 10, 64)
-				ttl, _ := strconv.ParseInt(args[2], 10, 64)
-				if err := c.SetTTL(itemType, id, ttl); err != nil {
-					fmt.Printf("Error: %v\n", err)
-				} else {
-					fmt.Println("OK")
-				}
-
-			case "TTL":
-				// TTL <type> <id>
-				if len(args) < 2 {
-					fmt.Println("Usage: TTL <document|textunit|entity|community> <id>")
-					continue
-				}
-				itemType := types.ItemType(strings.ToLower(args[0]))
-				id, _ := strconv.ParseUint(args[1], 10, 64)
-				ttl, err := c.GetTTL(itemType, id)
-				if err != nil {
-					fmt.Printf("Error: %v\n", err)
-				} else {
-					if ttl == -2 {
-						fmt.Println("(not found)")
-					} else if ttl == -1 {
-						fmt.Println("(no expiry)")
-					} else {
-						fmt.Printf("%d seconds\n", ttl)
-					}
-				}
-		*/
-
-		case "SNAPSHOT", "SAVE":
-			if err := c.Save(""); err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Println("OK - Snapshot saved")
+	case "INDEXSTATUS":
+		status, err := c.IndexStatus()
+		if err != nil {
+			return err
+		}
+		s.out.result(status, func(w io.Writer) {
+			fmt.Fprintf(w, "%s - %.0f%% of %d vectors\n", status.State, status.Percent, status.VectorCount)
+			if status.Error != "" {
+				fmt.Fprintf(w, "Last rebuild failed: %s\n", status.Error)
 			}
+		})
 
-		default:
-			fmt.Printf("Unknown command: %s (type 'help' for commands)\n", cmd)
+	case "ROTATEKEY":
+		// ROTATEKEY <key_id> [retire]
+		if len(args) < 1 {
+			return usageError("ROTATEKEY <key_id> [retire]")
 		}
+		retire := len(args) > 1 && strings.EqualFold(args[1], "retire")
+		result, err := c.RotateKey(args[0], "", retire)
+		if err != nil {
+			return err
+		}
+		jsonResult := map[string]any{"key_id": result.KeyID, "api_key": result.APIKey, "key_hash": result.KeyHash, "active_keys": result.ActiveKeys}
+		s.out.result(jsonResult, func(w io.Writer) {
+			fmt.Fprintf(w, "OK - New key for %s: %s\n", result.KeyID, result.APIKey)
+			fmt.Fprintf(w, "     key_hash: %s (%d active)\n", result.KeyHash, result.ActiveKeys)
+		})
+
+	default:
+		return fmt.Errorf("unknown command: %s (type 'help' for commands)", cmd)
 	}
+	return nil
 }
 
-func printHelp() {
-	fmt.Println(`Commands:
+// helpText lists the commands for HELP
+const helpText = `Commands:
   PING                                    Check connection
-  INFO                                    Server statistics
+  INFO [TYPES]                            Server statistics, optionally counts by type
 
   ADDDOC <ext_id> <filename> [ttl]        Add document
   ADDTU <ext_id> <doc_id> <content>       Add text unit (chunk)
   ADDENT <ext_id> <title> <type> <desc>   Add entity
   ADDREL <src_id> <tgt_id> <type> [desc]  Add relationship
   LINK <textunit_id> <entity_id>          Link text unit to entity
+  IMPORT <entities|relationships> <file>  Bulk load newline-delimited JSON records
+  RELTYPES [limit]                        Relationship types by frequency
+  COUNT <type>                            Count documents, textunits, entities, relationships or communities
+  NEIGHBORS <id> [out|in|both] [type...]  Direct relationships of an entity
+  PATH <from_id> <to_id> [maxhops]        Shortest path between two entities
 
   GETENT <id>                             Get entity by ID
   GETENTBYTITLE <title>                   Get entity by title
 
-  COMMUNITY COMPUTE [resolution]          Compute communities (Leiden)
+  COMMUNITY COMPUTE [resolution] [algo]   Compute communities (leiden or louvain)
 
   QUERY <topK> <hops> [maxEnts] [maxTUs]  Vector + graph query
   EXPLAIN <query_id>                      Explain query path
+  QSTATS [window_seconds]                 Aggregate query statistics
+  QUERIES [limit]                         Recent queries of the session
 
   SETTTL <type> <id> <seconds>            Set TTL
   TTL <type> <id>                         Get remaining TTL
 
   SNAPSHOT                                Force snapshot
+  QUANTIZE <float32|float16|int8>         Convert stored vectors to a precision
+  REBUILD [async]                         Rebuild the vector index graphs
+  INDEXSTATUS                             Show index rebuild progress
+  ROTATEKEY <key_id> [retire]             Add a new API key (retire = drop oldest)
   HELP                                    Show this help
-  QUIT                                    Exit`)
+  QUIT                                    Exit
+
+Scripts: run with -f <file> or pipe commands on stdin, one per line;
+lines starting with # are comments. The first failed command stops the
+script unless -continue-on-error is given.`
+
+// defaultPathHops bounds PATH searches without a maxhops argument
+const defaultPathHops = 4
+
+// maxPathExpansions bounds the entities a PATH search expands, since each
+// one costs a round trip
+const maxPathExpansions = 1000
+
+// findPath returns the relationships of a shortest path from fromID to toID
+// of at most maxHops, following relationships either way. It returns nil
+// when there is none and an empty path when fromID is toID.
+func findPath(c *client.Client, fromID, toID uint64, maxHops int) ([]*types.Relationship, error) {
+	if fromID == toID {
+		return []*types.Relationship{}, nil
+	}
+
+	via := map[uint64]*types.Relationship{fromID: nil}
+	frontier := []uint64{fromID}
+	expanded := 0
+	for hop := 0; hop < maxHops && len(frontier) > 0; hop++ {
+		var next []uint64
+		for _, id := range frontier {
+			if expanded++; expanded > maxPathExpansions {
+				return nil, fmt.Errorf("search stopped after %d entities; try fewer hops", maxPathExpansions)
+			}
+			rels, err := c.GetNeighbors(id, types.DirectionBoth, nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, rel := range rels {
+				other := otherEnd(rel, id)
+				if _, seen := via[other]; seen {
+					continue
+				}
+				via[other] = rel
+				if other == toID {
+					return pathTo(via, fromID, toID), nil
+				}
+				next = append(next, other)
+			}
+		}
+		frontier = next
+	}
+	return nil, nil
+}
+
+// pathTo walks the relationships in via back from toID to fromID
+func pathTo(via map[uint64]*types.Relationship, fromID, toID uint64) []*types.Relationship {
+	var path []*types.Relationship
+	for at := toID; at != fromID; {
+		rel := via[at]
+		path = append(path, rel)
+		at = otherEnd(rel, at)
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// otherEnd returns the entity rel connects to id
+func otherEnd(rel *types.Relationship, id uint64) uint64 {
+	if rel.SourceID == id {
+		return rel.TargetID
+	}
+	return rel.SourceID
+}
+
+// entityNames looks up and caches entity titles for printing edges
+type entityNames struct {
+	c      *client.Client
+	titles map[uint64]string
+}
+
+// name returns "TITLE (id)", or just the ID when the entity cannot be read
+func (n *entityNames) name(id uint64) string {
+	if n.titles == nil {
+		n.titles = make(map[uint64]string)
+	}
+	title, ok := n.titles[id]
+	if !ok {
+		if ent, err := n.c.GetEntity(id); err == nil {
+			title = ent.Title
+		}
+		n.titles[id] = title
+	}
+	if title == "" {
+		return strconv.FormatUint(id, 10)
+	}
+	return fmt.Sprintf("%s (%d)", title, id)
+}
+
+// edge formats rel as seen from entity from, with the arrow in the
+// relationship's direction
+func (n *entityNames) edge(rel *types.Relationship, from uint64) string {
+	if rel.SourceID == from {
+		return fmt.Sprintf("%s -[%s]-> %s", n.name(from), rel.Type, n.name(rel.TargetID))
+	}
+	return fmt.Sprintf("%s <-[%s]- %s", n.name(from), rel.Type, n.name(rel.SourceID))
 }
 
 func randomEmbedding(dim int) []float32 {
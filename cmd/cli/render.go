@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// renderer writes command results: as text for people, or with -json as one
+// JSON document per line for tools
+type renderer struct {
+	w        io.Writer
+	jsonMode bool
+}
+
+// result writes v as JSON in JSON mode and calls text otherwise
+func (r *renderer) result(v any, text func(w io.Writer)) {
+	if !r.jsonMode {
+		text(r.w)
+		return
+	}
+	if err := r.encode(v); err != nil {
+		r.error(fmt.Errorf("encode result: %w", err))
+	}
+}
+
+// encode writes v as one line of JSON
+func (r *renderer) encode(v any) error {
+	enc := json.NewEncoder(r.w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(v)
+}
+
+// ok writes a bare acknowledgement, with the ID a command created if any
+func (r *renderer) ok(idName string, id uint64) {
+	if idName == "" {
+		r.result(map[string]bool{"ok": true}, func(w io.Writer) { fmt.Fprintln(w, "OK") })
+		return
+	}
+	r.result(map[string]uint64{idName: id}, func(w io.Writer) {
+		fmt.Fprintf(w, "OK (%s: %d)\n", idName, id)
+	})
+}
+
+// error writes a failed command's error
+func (r *renderer) error(err error) {
+	if r.jsonMode {
+		_ = r.encode(map[string]string{"error": err.Error()})
+		return
+	}
+	// Wrapped usage errors, as from a script line, keep the "Error:" form
+	if usage, ok := err.(usageError); ok {
+		fmt.Fprintf(r.w, "Usage: %s\n", string(usage))
+		return
+	}
+	fmt.Fprintf(r.w, "Error: %v\n", err)
+}
+
+// usageError reports a command called with the wrong arguments; its text is
+// the command's synopsis
+type usageError string
+
+func (e usageError) Error() string {
+	return "usage: " + string(e)
+}
+
+// infoResult is INFO's JSON form: the server info, and the type breakdown
+// for INFO TYPES
+type infoResult struct {
+	*types.ServerInfo
+	Types *types.TypeCounts `json:"types,omitempty"`
+}
+
+// renderInfo writes an INFO response; counts is nil unless TYPES was asked
+func renderInfo(r *renderer, info *types.ServerInfo, counts *types.TypeCounts) {
+	r.result(infoResult{ServerInfo: info, Types: counts}, func(w io.Writer) {
+		fmt.Fprintln(w, "┌─────────────────────────────────────┐")
+		fmt.Fprintf(w, "│ Server: GraphMemoryRAG v%s      │\n", info.Version)
+		fmt.Fprintln(w, "├─────────────────────────────────────┤")
+		fmt.Fprintf(w, "│ Documents:     %-5d                │\n", info.DocumentCount)
+		fmt.Fprintf(w, "│ TextUnits:     %-5d                │\n", info.TextUnitCount)
+		fmt.Fprintf(w, "│ Entities:      %-5d                │\n", info.EntityCount)
+		fmt.Fprintf(w, "│ Relationships: %-5d                │\n", info.RelationshipCount)
+		fmt.Fprintf(w, "│ Communities:   %-5d                │\n", info.CommunityCount)
+		fmt.Fprintf(w, "│ VectorDim:     %-5d                │\n", info.VectorDim)
+		fmt.Fprintln(w, "└─────────────────────────────────────┘")
+		if counts != nil {
+			printTypeCounts(w, "Entity types", counts.EntityTypes)
+			printTypeCounts(w, "Relationship types", counts.RelationshipTypes)
+		}
+	})
+}
+
+// printTypeCounts prints a type histogram, largest first
+func printTypeCounts(w io.Writer, label string, counts map[string]int) {
+	typeNames := make([]string, 0, len(counts))
+	for typ := range counts {
+		typeNames = append(typeNames, typ)
+	}
+	sort.Slice(typeNames, func(i, j int) bool {
+		if counts[typeNames[i]] != counts[typeNames[j]] {
+			return counts[typeNames[i]] > counts[typeNames[j]]
+		}
+		return typeNames[i] < typeNames[j]
+	})
+
+	fmt.Fprintf(w, "%s:\n", label)
+	for _, typ := range typeNames {
+		fmt.Fprintf(w, "  %-24s %d\n", typ, counts[typ])
+	}
+}
+
+// printQueryResult prints a query's stats and its top results
+func printQueryResult(w io.Writer, result *types.ContextPack) {
+	fmt.Fprintf(w, "Query ID: %d\n", result.QueryID)
+	fmt.Fprintf(w, "Stats: %d textunits, %d entities, %d communities, %dμs\n",
+		len(result.TextUnits), len(result.Entities), len(result.Communities), result.Stats.DurationMicros)
+
+	if len(result.TextUnits) > 0 {
+		fmt.Fprintln(w, "TextUnits:")
+		for i, tu := range result.TextUnits {
+			content := tu.TextUnit.Content
+			if len(content) > 60 {
+				content = content[:60] + "..."
+			}
+			fmt.Fprintf(w, "  %d. [id=%d hop=%d sim=%.3f] %s\n", i+1, tu.TextUnit.ID, tu.Hop, tu.Similarity, content)
+		}
+	}
+
+	if len(result.Entities) > 0 {
+		fmt.Fprintln(w, "Entities:")
+		for i, ent := range result.Entities {
+			if i >= 5 {
+				fmt.Fprintf(w, "  ... and %d more\n", len(result.Entities)-5)
+				break
+			}
+			fmt.Fprintf(w, "  - %s (%s) [hop=%d]\n", ent.Entity.Title, ent.Entity.Type, ent.Hop)
+		}
+	}
+
+	if len(result.Relationships) > 0 {
+		fmt.Fprintln(w, "Relationships:")
+		for i, rel := range result.Relationships {
+			if i >= 5 {
+				fmt.Fprintf(w, "  ... and %d more\n", len(result.Relationships)-5)
+				break
+			}
+			fmt.Fprintf(w, "  - %s -[%s]-> %s\n", rel.SourceTitle, rel.Relationship.Type, rel.TargetTitle)
+		}
+	}
+}
+
+// printExplain prints a query's seeds and traversal steps
+func printExplain(w io.Writer, explain *types.ExplainPack) {
+	fmt.Fprintf(w, "Query ID: %d\n", explain.QueryID)
+	fmt.Fprintf(w, "\nSeeds (%d):\n", len(explain.Seeds))
+	for i, seed := range explain.Seeds {
+		if i >= 5 {
+			fmt.Fprintf(w, "  ... and %d more\n", len(explain.Seeds)-5)
+			break
+		}
+		fmt.Fprintf(w, "  - [%s] id=%d ext=%s sim=%.3f\n", seed.Type, seed.ID, seed.ExternalID, seed.Similarity)
+	}
+	fmt.Fprintf(w, "\nTraversal (%d steps):\n", len(explain.Traversal))
+	for i, step := range explain.Traversal {
+		if i >= 10 {
+			fmt.Fprintf(w, "  ... and %d more\n", len(explain.Traversal)-10)
+			break
+		}
+		fmt.Fprintf(w, "  Hop %d: %d -[%s]-> %d (weight=%.2f)\n",
+			step.Hop, step.FromEntityID, step.RelType, step.ToEntityID, step.Weight)
+	}
+}
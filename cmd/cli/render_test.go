@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+func TestRenderInfo_JSON(t *testing.T) {
+	info := &types.ServerInfo{Version: "1.2.3", EntityCount: 4, RelationshipCount: 2, VectorDim: 64}
+	counts := &types.TypeCounts{EntityTypes: map[string]int{"person": 3, "org": 1}}
+
+	var buf bytes.Buffer
+	r := &renderer{w: &buf, jsonMode: true}
+	renderInfo(r, info, counts)
+	renderInfo(r, info, nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected one JSON document per INFO, got %q", buf.String())
+	}
+
+	var got struct {
+		Version     string            `json:"version"`
+		EntityCount int               `json:"entity_count"`
+		VectorDim   int               `json:"vector_dim"`
+		Types       *types.TypeCounts `json:"types"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &got); err != nil {
+		t.Fatalf("INFO TYPES output is not JSON: %v", err)
+	}
+	if got.Version != "1.2.3" || got.EntityCount != 4 || got.VectorDim != 64 {
+		t.Errorf("INFO fields = %+v", got)
+	}
+	if got.Types == nil || got.Types.EntityTypes["person"] != 3 {
+		t.Errorf("INFO TYPES counts = %+v", got.Types)
+	}
+
+	var plain map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &plain); err != nil {
+		t.Fatalf("INFO output is not JSON: %v", err)
+	}
+	if _, ok := plain["types"]; ok {
+		t.Error("INFO without TYPES should leave out types")
+	}
+}
+
+func TestRenderer_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	r := &renderer{w: &buf}
+	r.error(usageError("GETENT <id>"))
+	r.error(errors.New("entity not found"))
+	r.error(fmt.Errorf("line 3: %w", usageError("GETENT <id>")))
+	if want := "Usage: GETENT <id>\nError: entity not found\nError: line 3: usage: GETENT <id>\n"; buf.String() != want {
+		t.Errorf("text errors = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	r.jsonMode = true
+	r.error(errors.New("entity not found"))
+	if want := `{"error":"entity not found"}` + "\n"; buf.String() != want {
+		t.Errorf("JSON error = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gibram-io/gibram/pkg/client"
+	"github.com/gibram-io/gibram/pkg/engine"
+	"github.com/gibram-io/gibram/pkg/server"
+)
+
+// newTestShell returns a JSON-mode shell connected to a fresh server, and
+// the buffer it writes to
+func newTestShell(t *testing.T) (*shell, *bytes.Buffer) {
+	t.Helper()
+	srv := server.NewServer(engine.NewEngine(1536))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	if err := ln.Close(); err != nil {
+		t.Fatalf("Failed to close listener: %v", err)
+	}
+	if err := srv.Start(addr); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	t.Cleanup(srv.Stop)
+	time.Sleep(50 * time.Millisecond)
+
+	config := client.DefaultPoolConfig()
+	config.TLSEnabled = false
+	c, err := client.NewClientWithConfig(addr, "cli-session", config)
+	if err != nil {
+		t.Fatalf("NewClientWithConfig() error: %v", err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+
+	var buf bytes.Buffer
+	return &shell{c: c, out: &renderer{w: &buf, jsonMode: true}}, &buf
+}
+
+func assertLines(t *testing.T, buf *bytes.Buffer, want ...string) {
+	t.Helper()
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRunScript(t *testing.T) {
+	sh, buf := newTestShell(t)
+	script := `# Two entities and a relationship between them
+ADDENT alice Alice person founder
+
+ADDENT acme Acme org company
+  # indented comments are skipped too
+ADDREL 1 2 WORKS_AT
+COUNT entity
+COUNT relationship
+`
+	if !sh.runScript(strings.NewReader(script), false) {
+		t.Fatalf("runScript() failed:\n%s", buf)
+	}
+	assertLines(t, buf,
+		`{"entity_id":1}`,
+		`{"entity_id":2}`,
+		`{"rel_id":1}`,
+		`{"count":2}`,
+		`{"count":1}`,
+	)
+}
+
+func TestRunScript_StopsAtFirstError(t *testing.T) {
+	script := `ADDENT alice Alice person founder
+GETENT
+ADDENT acme Acme org company
+QUIT
+ADDENT never Never thing unreachable
+`
+	sh, buf := newTestShell(t)
+	if sh.runScript(strings.NewReader(script), false) {
+		t.Error("runScript() succeeded, want failure")
+	}
+	assertLines(t, buf,
+		`{"entity_id":1}`,
+		`{"error":"line 2: usage: GETENT <id>"}`,
+	)
+
+	sh, buf = newTestShell(t)
+	if sh.runScript(strings.NewReader(script), true) {
+		t.Error("runScript() with continueOnError succeeded, want failure")
+	}
+	assertLines(t, buf,
+		`{"entity_id":1}`,
+		`{"error":"line 2: usage: GETENT <id>"}`,
+		`{"entity_id":2}`,
+	)
+}
@@ -0,0 +1,349 @@
+// GibRAM Inspect - offline snapshot inspection tool
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gibram-io/gibram/pkg/backup"
+	"github.com/gibram-io/gibram/pkg/engine"
+	"github.com/gibram-io/gibram/pkg/store"
+	"github.com/gibram-io/gibram/pkg/version"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gramMagic = []byte("GRAM")
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Report is the inspection result (also the --json output)
+type Report struct {
+	Path        string           `json:"path"`
+	Format      string           `json:"format"`                // "engine" or "container"
+	Compression string           `json:"compression,omitempty"` // "gzip" or "zstd"
+	Container   *ContainerReport `json:"container,omitempty"`
+	Version     string           `json:"version,omitempty"`
+	VectorDim   int              `json:"vector_dim,omitempty"`
+	Sessions    []SessionReport  `json:"sessions,omitempty"`
+}
+
+// ContainerReport describes a backup container file (GRAM header + sections)
+type ContainerReport struct {
+	Version   uint32          `json:"version"`
+	Timestamp int64           `json:"timestamp"`
+	LSN       uint64          `json:"lsn"`
+	Sections  []SectionReport `json:"sections"`
+}
+
+// SectionReport describes one container section
+type SectionReport struct {
+	Name  string `json:"name"`
+	Bytes int    `json:"bytes"`
+}
+
+// SessionReport summarizes one session of an engine snapshot
+type SessionReport struct {
+	SessionID           string            `json:"session_id"`
+	Documents           int               `json:"documents"`
+	TextUnits           int               `json:"text_units"`
+	Entities            int               `json:"entities"`
+	Relationships       int               `json:"relationships"`
+	Communities         int               `json:"communities"`
+	Vectors             int               `json:"vectors"`
+	BadVectorDims       int               `json:"bad_vector_dims,omitempty"` // vectors whose length differs from vector_dim
+	EntityTypes         []Count           `json:"entity_types"`
+	RelationshipTypes   []Count           `json:"relationship_types"`
+	SampleEntities      []string          `json:"sample_entities,omitempty"`
+	SampleRelationships []string          `json:"sample_relationships,omitempty"`
+	Metadata            map[string]string `json:"metadata,omitempty"`
+}
+
+// Count is a histogram bucket
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func main() {
+	jsonOut := flag.Bool("json", false, "Print the report as JSON")
+	samples := flag.Int("samples", 3, "Sample records to print per session")
+	sessionID := flag.String("session", "", "Only inspect this session")
+	showVersion := flag.Bool("version", false, "Print version and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gibram-inspect [flags] <snapshot-file>\n\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Printf("gibram-inspect %s\n", version.Version)
+		return
+	}
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	report, err := inspect(flag.Arg(0), *sessionID, *samples)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *jsonOut {
+		if err := writeJSON(os.Stdout, report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	printReport(os.Stdout, report)
+}
+
+// writeJSON writes the report as indented JSON (the --json output)
+func writeJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// inspect detects the snapshot format and builds a report
+func inspect(path, sessionID string, samples int) (*Report, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+	var r io.Reader = br
+	var compression string
+	switch {
+	case bytes.Equal(magic, gramMagic):
+		return inspectContainer(path)
+	case bytes.Equal(magic, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("open zstd snapshot: %w", err)
+		}
+		defer zr.Close()
+		r, compression = zr, "zstd"
+	case bytes.HasPrefix(magic, gzipMagic):
+		// engine.ReadSnapshot unwraps gzip itself
+		compression = "gzip"
+	}
+
+	snapshot, err := engine.ReadSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Path:        path,
+		Format:      "engine",
+		Compression: compression,
+		Version:     snapshot.Version,
+		VectorDim:   snapshot.VectorDim,
+	}
+
+	ids := make([]string, 0, len(snapshot.Sessions))
+	for id := range snapshot.Sessions {
+		if sessionID == "" || id == sessionID {
+			ids = append(ids, id)
+		}
+	}
+	if sessionID != "" && len(ids) == 0 {
+		return nil, fmt.Errorf("session %q not found in snapshot", sessionID)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		report.Sessions = append(report.Sessions, summarizeSession(id, snapshot.Sessions[id], snapshot.VectorDim, samples))
+	}
+	return report, nil
+}
+
+// inspectContainer reads a backup container (GRAM header + gzip sections)
+func inspectContainer(path string) (*Report, error) {
+	reader, err := backup.NewSnapshotReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = reader.Close() }()
+
+	header := reader.Header()
+	container := &ContainerReport{
+		Version:   header.Version,
+		Timestamp: header.Timestamp,
+		LSN:       header.LSN,
+	}
+	for {
+		name, data, err := reader.ReadSection()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read section: %w", err)
+		}
+		container.Sections = append(container.Sections, SectionReport{Name: name, Bytes: len(data)})
+	}
+
+	return &Report{Path: path, Format: "container", Container: container}, nil
+}
+
+func summarizeSession(id string, snap *store.SessionSnapshot, vectorDim, samples int) SessionReport {
+	sr := SessionReport{
+		SessionID:     id,
+		Documents:     len(snap.Documents),
+		TextUnits:     len(snap.TextUnits),
+		Entities:      len(snap.Entities),
+		Relationships: len(snap.Relationships),
+		Communities:   len(snap.Communities),
+	}
+	if snap.Session != nil {
+		sr.Metadata = snap.Session.Metadata
+	}
+
+	for _, vectors := range []map[uint64][]float32{snap.TextUnitVectors, snap.EntityVectors, snap.CommunityVectors, snap.EntityTitleVectors} {
+		for _, vec := range vectors {
+			sr.Vectors++
+			if vectorDim > 0 && len(vec) != vectorDim {
+				sr.BadVectorDims++
+			}
+		}
+	}
+
+	entityTypes := make(map[string]int)
+	for _, ent := range snap.Entities {
+		entityTypes[ent.Type]++
+	}
+	sr.EntityTypes = histogram(entityTypes)
+
+	relTypes := make(map[string]int)
+	for _, rel := range snap.Relationships {
+		relTypes[rel.Type]++
+	}
+	sr.RelationshipTypes = histogram(relTypes)
+
+	entities := snap.Entities
+	sort.Slice(entities, func(i, j int) bool { return entities[i].ID < entities[j].ID })
+	for i := 0; i < samples && i < len(entities); i++ {
+		ent := entities[i]
+		sr.SampleEntities = append(sr.SampleEntities,
+			fmt.Sprintf("id=%d ext=%s title=%q type=%s", ent.ID, ent.ExternalID, ent.Title, ent.Type))
+	}
+
+	rels := snap.Relationships
+	sort.Slice(rels, func(i, j int) bool { return rels[i].ID < rels[j].ID })
+	for i := 0; i < samples && i < len(rels); i++ {
+		rel := rels[i]
+		sr.SampleRelationships = append(sr.SampleRelationships,
+			fmt.Sprintf("id=%d %d -[%s]-> %d weight=%.2f", rel.ID, rel.SourceID, rel.Type, rel.TargetID, rel.Weight))
+	}
+
+	return sr
+}
+
+// histogram orders counts descending (ties by name)
+func histogram(counts map[string]int) []Count {
+	result := make([]Count, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, Count{Name: name, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func printReport(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "File:    %s\n", r.Path)
+
+	if r.Container != nil {
+		fmt.Fprintf(w, "Format:  backup container v%d\n", r.Container.Version)
+		fmt.Fprintf(w, "Created: %s\n", time.Unix(r.Container.Timestamp, 0).UTC().Format(time.RFC3339))
+		fmt.Fprintf(w, "LSN:     %d\n", r.Container.LSN)
+		fmt.Fprintf(w, "Sections (%d):\n", len(r.Container.Sections))
+		for _, sec := range r.Container.Sections {
+			fmt.Fprintf(w, "  %-30s %10d bytes\n", sec.Name, sec.Bytes)
+		}
+		return
+	}
+
+	if r.Compression != "" {
+		fmt.Fprintf(w, "Format:  engine snapshot %s (%s)\n", r.Version, r.Compression)
+	} else {
+		fmt.Fprintf(w, "Format:  engine snapshot %s\n", r.Version)
+	}
+	fmt.Fprintf(w, "Vector dimension: %d\n", r.VectorDim)
+	fmt.Fprintf(w, "Sessions: %d\n", len(r.Sessions))
+
+	for _, s := range r.Sessions {
+		fmt.Fprintf(w, "\nSession %s\n", s.SessionID)
+		fmt.Fprintf(w, "  Documents:     %d\n", s.Documents)
+		fmt.Fprintf(w, "  TextUnits:     %d\n", s.TextUnits)
+		fmt.Fprintf(w, "  Entities:      %d\n", s.Entities)
+		fmt.Fprintf(w, "  Relationships: %d\n", s.Relationships)
+		fmt.Fprintf(w, "  Communities:   %d\n", s.Communities)
+		fmt.Fprintf(w, "  Vectors:       %d", s.Vectors)
+		if s.BadVectorDims > 0 {
+			fmt.Fprintf(w, " (%d with wrong dimension!)", s.BadVectorDims)
+		}
+		fmt.Fprintln(w)
+
+		if len(s.Metadata) > 0 {
+			keys := make([]string, 0, len(s.Metadata))
+			for k := range s.Metadata {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			fmt.Fprintln(w, "  Metadata:")
+			for _, k := range keys {
+				fmt.Fprintf(w, "    %s = %s\n", k, s.Metadata[k])
+			}
+		}
+
+		printHistogram(w, "Entity types", s.EntityTypes)
+		printHistogram(w, "Relationship types", s.RelationshipTypes)
+
+		if len(s.SampleEntities) > 0 {
+			fmt.Fprintln(w, "  Sample entities:")
+			for _, line := range s.SampleEntities {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+		if len(s.SampleRelationships) > 0 {
+			fmt.Fprintln(w, "  Sample relationships:")
+			for _, line := range s.SampleRelationships {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+}
+
+func printHistogram(w io.Writer, title string, counts []Count) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "  %s:\n", title)
+	for _, c := range counts {
+		name := c.Name
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(w, "    %-28s %8d\n", name, c.Count)
+	}
+}
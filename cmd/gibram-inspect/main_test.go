@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gibram-io/gibram/pkg/engine"
+	"github.com/gibram-io/gibram/pkg/version"
+	"github.com/klauspost/compress/zstd"
+)
+
+// v1Snapshot is a version 1 snapshot: the bare JSON body, no header. One
+// entity vector has the wrong dimension.
+const v1Snapshot = `{
+  "version": "0.9.0",
+  "vector_dim": 2,
+  "sessions": {
+    "alpha": {
+      "session_id": "alpha",
+      "session": {"id": "alpha", "created_at": 1, "last_access": 1, "metadata": {"owner": "ops"}},
+      "documents": [{"id": 1, "external_id": "doc-1"}],
+      "text_units": [],
+      "entities": [
+        {"id": 2, "external_id": "ent-2", "title": "PERRY WARJIYO", "type": "person"},
+        {"id": 1, "external_id": "ent-1", "title": "BANK INDONESIA", "type": "organization"}
+      ],
+      "relationships": [
+        {"id": 1, "source_id": 2, "target_id": 1, "type": "GOVERNOR_OF", "weight": 0.9}
+      ],
+      "communities": [],
+      "entity_vectors": {"1": [0.1, 0.2], "2": [0.3]}
+    }
+  }
+}
+`
+
+// writeV3Snapshot writes a snapshot in the current format from an engine
+// holding two sessions
+func writeV3Snapshot(t *testing.T, path string) {
+	t.Helper()
+	writeCompressedV3Snapshot(t, path, nil)
+}
+
+// writeCompressedV3Snapshot is writeV3Snapshot through compress (nil =
+// uncompressed)
+func writeCompressedV3Snapshot(t *testing.T, path string, compress func(io.Writer) (io.WriteCloser, error)) {
+	t.Helper()
+	e := engine.NewEngine(2)
+	vec := []float32{0.6, 0.8}
+
+	ent1, err := e.AddEntity("beta", "ent-1", "Bank Indonesia", "organization", "Central bank", vec)
+	if err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+	ent2, err := e.AddEntity("beta", "ent-2", "Jakarta", "location", "Capital", vec)
+	if err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+	if _, err := e.AddRelationship("beta", "rel-1", ent1.ID, ent2.ID, "LOCATED_IN", "HQ", 0.5); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+	if _, err := e.AddDocument("gamma", "doc-1", "a.pdf"); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+	var w io.Writer = f
+	if compress != nil {
+		cw, err := compress(f)
+		if err != nil {
+			t.Fatalf("compress failed: %v", err)
+		}
+		defer func() {
+			if err := cw.Close(); err != nil {
+				t.Fatalf("Close compressor failed: %v", err)
+			}
+		}()
+		w = cw
+	}
+	if err := e.Snapshot(w); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+}
+
+// gammaSummary is the summary of the v3 snapshot's gamma session alone
+const gammaSummary = `File:    PATH
+Format:  engine snapshot VERSION
+Vector dimension: 2
+Sessions: 1
+
+Session gamma
+  Documents:     1
+  TextUnits:     0
+  Entities:      0
+  Relationships: 0
+  Communities:   0
+  Vectors:       0
+`
+
+func TestInspect_Output(t *testing.T) {
+	gammaSessions := []SessionReport{{
+		SessionID: "gamma", Documents: 1,
+		EntityTypes: []Count{}, RelationshipTypes: []Count{},
+	}}
+	tests := []struct {
+		name        string
+		write       func(t *testing.T, path string)
+		session     string
+		summary     string
+		compression string
+		sessions    []SessionReport
+	}{
+		{
+			name: "v1",
+			write: func(t *testing.T, path string) {
+				if err := os.WriteFile(path, []byte(v1Snapshot), 0644); err != nil {
+					t.Fatalf("WriteFile failed: %v", err)
+				}
+			},
+			summary: `File:    PATH
+Format:  engine snapshot 0.9.0
+Vector dimension: 2
+Sessions: 1
+
+Session alpha
+  Documents:     1
+  TextUnits:     0
+  Entities:      2
+  Relationships: 1
+  Communities:   0
+  Vectors:       2 (1 with wrong dimension!)
+  Metadata:
+    owner = ops
+  Entity types:
+    organization                        1
+    person                              1
+  Relationship types:
+    GOVERNOR_OF                         1
+  Sample entities:
+    id=1 ext=ent-1 title="BANK INDONESIA" type=organization
+  Sample relationships:
+    id=1 2 -[GOVERNOR_OF]-> 1 weight=0.90
+`,
+			sessions: []SessionReport{{
+				SessionID: "alpha", Documents: 1, Entities: 2, Relationships: 1,
+				Vectors: 2, BadVectorDims: 1,
+				EntityTypes:         []Count{{"organization", 1}, {"person", 1}},
+				RelationshipTypes:   []Count{{"GOVERNOR_OF", 1}},
+				SampleEntities:      []string{`id=1 ext=ent-1 title="BANK INDONESIA" type=organization`},
+				SampleRelationships: []string{"id=1 2 -[GOVERNOR_OF]-> 1 weight=0.90"},
+				Metadata:            map[string]string{"owner": "ops"},
+			}},
+		},
+		{
+			name:     "v3 one session",
+			write:    writeV3Snapshot,
+			session:  "gamma",
+			summary:  gammaSummary,
+			sessions: gammaSessions,
+		},
+		{
+			name: "v3 gzip",
+			write: func(t *testing.T, path string) {
+				writeCompressedV3Snapshot(t, path, func(w io.Writer) (io.WriteCloser, error) {
+					return gzip.NewWriter(w), nil
+				})
+			},
+			session:     "gamma",
+			summary:     strings.Replace(gammaSummary, "VERSION", "VERSION (gzip)", 1),
+			compression: "gzip",
+			sessions:    gammaSessions,
+		},
+		{
+			name: "v3 zstd",
+			write: func(t *testing.T, path string) {
+				writeCompressedV3Snapshot(t, path, func(w io.Writer) (io.WriteCloser, error) {
+					return zstd.NewWriter(w)
+				})
+			},
+			session:     "gamma",
+			summary:     strings.Replace(gammaSummary, "VERSION", "VERSION (zstd)", 1),
+			compression: "zstd",
+			sessions:    gammaSessions,
+		},
+		{
+			name:  "v3",
+			write: writeV3Snapshot,
+			summary: `File:    PATH
+Format:  engine snapshot VERSION
+Vector dimension: 2
+Sessions: 2
+
+Session beta
+  Documents:     0
+  TextUnits:     0
+  Entities:      2
+  Relationships: 1
+  Communities:   0
+  Vectors:       2
+  Entity types:
+    location                            1
+    organization                        1
+  Relationship types:
+    LOCATED_IN                          1
+  Sample entities:
+    id=1 ext=ent-1 title="BANK INDONESIA" type=organization
+  Sample relationships:
+    id=1 1 -[LOCATED_IN]-> 2 weight=0.50
+
+Session gamma
+  Documents:     1
+  TextUnits:     0
+  Entities:      0
+  Relationships: 0
+  Communities:   0
+  Vectors:       0
+`,
+			sessions: []SessionReport{
+				{
+					SessionID: "beta", Entities: 2, Relationships: 1, Vectors: 2,
+					EntityTypes:         []Count{{"location", 1}, {"organization", 1}},
+					RelationshipTypes:   []Count{{"LOCATED_IN", 1}},
+					SampleEntities:      []string{`id=1 ext=ent-1 title="BANK INDONESIA" type=organization`},
+					SampleRelationships: []string{"id=1 1 -[LOCATED_IN]-> 2 weight=0.50"},
+				},
+				gammaSessions[0],
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.bin")
+			tt.write(t, path)
+			report, err := inspect(path, tt.session, 1)
+			if err != nil {
+				t.Fatalf("inspect failed: %v", err)
+			}
+
+			var summary bytes.Buffer
+			printReport(&summary, report)
+			want := strings.NewReplacer("PATH", path, "VERSION", version.Version).Replace(tt.summary)
+			if summary.String() != want {
+				t.Errorf("summary =\n%s\nwant\n%s", summary.String(), want)
+			}
+
+			var dump bytes.Buffer
+			if err := writeJSON(&dump, report); err != nil {
+				t.Fatalf("writeJSON failed: %v", err)
+			}
+			var got Report
+			if err := json.Unmarshal(dump.Bytes(), &got); err != nil {
+				t.Fatalf("dump is not JSON: %v\n%s", err, dump.String())
+			}
+			if got.Path != path || got.Format != "engine" || got.Compression != tt.compression || got.VectorDim != 2 {
+				t.Errorf("dump header = %q %q %q %d", got.Path, got.Format, got.Compression, got.VectorDim)
+			}
+			if !reflect.DeepEqual(got.Sessions, tt.sessions) {
+				t.Errorf("dump sessions = %+v, want %+v", got.Sessions, tt.sessions)
+			}
+		})
+	}
+}
@@ -5,19 +5,27 @@ import (
 	"compress/gzip"
 	"context"
 	"flag"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
 
+	"github.com/gibram-io/gibram/pkg/audit"
 	"github.com/gibram-io/gibram/pkg/backup"
 	"github.com/gibram-io/gibram/pkg/config"
 	"github.com/gibram-io/gibram/pkg/engine"
+	"github.com/gibram-io/gibram/pkg/httpapi"
 	"github.com/gibram-io/gibram/pkg/logging"
 	"github.com/gibram-io/gibram/pkg/memory"
 	"github.com/gibram-io/gibram/pkg/metrics"
 	"github.com/gibram-io/gibram/pkg/server"
 	"github.com/gibram-io/gibram/pkg/shutdown"
+	"github.com/gibram-io/gibram/pkg/tracing"
+	"github.com/gibram-io/gibram/pkg/types"
+	"github.com/gibram-io/gibram/pkg/vector"
 	"github.com/gibram-io/gibram/pkg/version"
 )
 
@@ -33,6 +41,7 @@ func main() {
 	insecure := flag.Bool("insecure", false, "Run in insecure mode (no TLS, no auth) - DEV ONLY")
 	logLevel := flag.String("log-level", "", "Log level (override config)")
 	sessionCleanupInterval := flag.Duration("session-cleanup-interval", 60*time.Second, "Session cleanup interval")
+	recoverToLSN := flag.Uint64("recover-to-lsn", 0, "Recover to this WAL LSN, discarding later writes (0 = latest)")
 	flag.Parse()
 
 	// Load configuration
@@ -92,8 +101,76 @@ func main() {
 		cfg.TLS.AutoCert = false // Disable auto-cert
 	}
 
-	// Create engine (in-memory for now, can add persistence later)
+	// Create engine (in-memory; backup.durable_interval persists its state)
 	eng := engine.NewEngine(cfg.Server.VectorDim)
+	if cfg.Server.EmbeddingMinNorm > 0 || cfg.Server.EmbeddingMaxNorm > 0 {
+		eng.SetEmbeddingNormBounds(cfg.Server.EmbeddingMinNorm, cfg.Server.EmbeddingMaxNorm)
+		log.Info("  Embedding norm: %g..%g", cfg.Server.EmbeddingMinNorm, cfg.Server.EmbeddingMaxNorm)
+	}
+	if cfg.Server.NormalizeOnIngest {
+		eng.SetNormalizeOnIngest(true)
+		log.Info("  Embeddings: normalized on ingest")
+	}
+	if cfg.Server.DedupThreshold > 0 {
+		eng.SetDedupThreshold(cfg.Server.DedupThreshold)
+		log.Info("  Entity dedup: cosine >= %g", cfg.Server.DedupThreshold)
+	}
+	if cfg.Server.PopularityHalfLife > 0 {
+		eng.SetPopularityTracking(cfg.Server.PopularityHalfLife)
+		log.Info("  Popularity half-life: %s", cfg.Server.PopularityHalfLife)
+	}
+	if cfg.Server.QueryCacheSize > 0 {
+		eng.SetQueryCache(cfg.Server.QueryCacheSize, cfg.Server.QueryCacheTTL)
+		log.Info("  Query cache: %d results, TTL %s", cfg.Server.QueryCacheSize, cfg.Server.QueryCacheTTL)
+	}
+	if cfg.Server.QueryHistorySize != 0 {
+		eng.SetQueryHistorySize(max(cfg.Server.QueryHistorySize, 0))
+		log.Info("  Query history: %d per session", max(cfg.Server.QueryHistorySize, 0))
+	}
+	if cfg.Server.DistanceMetric != "" {
+		metric, err := types.ParseDistanceMetric(cfg.Server.DistanceMetric)
+		if err != nil {
+			log.Error("Invalid server.distance_metric: %v", err)
+			os.Exit(1)
+		}
+		eng.SetDistanceMetric(metric)
+		log.Info("  Distance metric: %s", metric)
+	}
+	if cfg.Server.HNSWM > 0 || cfg.Server.HNSWEfConstruction > 0 || cfg.Server.HNSWEfSearch > 0 || cfg.Server.VectorPrecision != "" {
+		indexConfig := vector.NewHNSWConfig(cfg.Server.HNSWM, cfg.Server.HNSWEfConstruction, cfg.Server.HNSWEfSearch)
+		precision, err := vector.ParsePrecision(cfg.Server.VectorPrecision)
+		if err != nil {
+			log.Error("Invalid server.vector_precision: %v", err)
+			os.Exit(1)
+		}
+		indexConfig.Precision = precision
+		eng.SetIndexConfig(indexConfig)
+		log.Info("  HNSW: M=%d efConstruction=%d efSearch=%d precision=%s", indexConfig.M, indexConfig.EfConstruction, indexConfig.EfSearch, indexConfig.Precision)
+	}
+	if cfg.Server.RecreateExpiredSessions {
+		eng.SetRecreateExpiredSessions(true)
+		log.Info("  Expired sessions: recreated on write")
+	}
+	if cfg.Session.MaxBytesPerSession > 0 {
+		eng.SetMaxSessionBytes(cfg.Session.MaxBytesPerSession)
+		log.Info("  Session memory limit: %d bytes", cfg.Session.MaxBytesPerSession)
+	}
+	if dir := cfg.Session.EvictionExportDir; dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			log.Error("Failed to create eviction export directory: %v", err)
+			os.Exit(1)
+		}
+		evictLog := logging.WithPrefix("session")
+		eng.SetSessionEvictionCallback(func(sessionID string, info types.SessionInfo) {
+			path := filepath.Join(dir, fmt.Sprintf("%s-%d.json.gz", url.PathEscape(sessionID), time.Now().Unix()))
+			if err := exportSessionFile(eng, sessionID, path); err != nil {
+				evictLog.Warn("Failed to export expired session %s: %v", sessionID, err)
+				return
+			}
+			evictLog.Info("Exported expired session %s (%d entities) to %s", sessionID, info.EntityCount, path)
+		})
+		log.Info("  Expired sessions: exported to %s", dir)
+	}
 
 	// Start session cleanup goroutine
 	eng.StartSessionCleanup(*sessionCleanupInterval)
@@ -154,16 +231,74 @@ func main() {
 		log.Warn("Snapshot dir create failed: %v (snapshots disabled)", err)
 	}
 
-	wal, err = backup.NewWAL(walDir, backup.SyncPeriodic)
+	syncMode, err := backup.ParseSyncMode(cfg.Server.WALSync)
+	if err != nil {
+		log.Error("Invalid server.wal_sync: %v", err)
+		os.Exit(1)
+	}
+	wal, err = backup.NewWAL(walDir, syncMode)
 	if err != nil {
 		log.Warn("WAL init failed: %v (backup disabled)", err)
 	} else {
-		log.Info("  WAL:        %s", walDir)
+		log.Info("  WAL:        %s (sync: %s)", walDir, syncMode)
 	}
 
 	recovery = backup.NewRecovery(cfg.Server.DataDir)
 	log.Info("  Snapshots:  %s", snapshotDir)
 
+	// Recover state: the durable state when enabled, otherwise (or on its
+	// first start) the latest snapshot with a recorded LSN, then the WAL
+	// after it
+	var sinceLSN uint64
+	var durable *engine.DurableStore
+	durableLoaded := false
+	stateDir := filepath.Join(cfg.Server.DataDir, "state")
+	if cfg.Backup.DurableInterval > 0 {
+		durable, durableLoaded, err = engine.OpenDurableStore(eng, stateDir)
+		if err != nil {
+			log.Error("Durable state load failed: %v", err)
+			os.Exit(1)
+		}
+		durable.SetCompactSize(cfg.Backup.DurableCompactSize)
+		if durableLoaded {
+			sinceLSN = durable.LSN()
+		}
+		log.Info("  State:      %s (sync every %s)", stateDir, cfg.Backup.DurableInterval)
+	}
+	if *recoverToLSN > 0 {
+		// Point-in-time recovery ignores the durable state and replays from
+		// the latest snapshot not after the target
+		snapshotPath, _, _ := recovery.SnapshotAtOrBefore(*recoverToLSN)
+		if err := recovery.RecoverTo(eng, snapshotPath, *recoverToLSN); err != nil {
+			log.Error("Recovery to LSN %d failed: %v", *recoverToLSN, err)
+			os.Exit(1)
+		}
+		log.Info("  Recovered:  to LSN %d, discarding WAL records %d-%d", *recoverToLSN, *recoverToLSN+1, currentLSN(wal))
+		durableLoaded = false
+	} else {
+		if !durableLoaded {
+			if path, lsn, ok := recovery.LatestSnapshot(); ok {
+				if err := restoreSnapshot(eng, path, log); err != nil {
+					log.Error("Snapshot restore failed: %v", err)
+					os.Exit(1)
+				}
+				sinceLSN = lsn
+			}
+		}
+		if err := recovery.ReplayWAL(eng, walDir, sinceLSN); err != nil {
+			log.Error("WAL replay failed: %v", err)
+			os.Exit(1)
+		}
+	}
+	// First start with durable state, or a point-in-time recovery: the
+	// recovered state becomes its base
+	if durable != nil && !durableLoaded {
+		if err := durable.Compact(currentLSN(wal)); err != nil {
+			log.Error("Durable state migration failed: %v", err)
+			os.Exit(1)
+		}
+	}
+
 	// Create and start Protobuf server with config
 	srv := server.NewServerWithConfig(eng, cfg)
 
@@ -171,6 +306,19 @@ func main() {
 	if wal != nil {
 		srv.SetWAL(wal)
 	}
+	srv.SetMetricsCollector(metricsCollector)
+	srv.SetMemoryTracker(memTracker)
+
+	var auditLog *audit.Logger
+	if cfg.Audit.File != "" {
+		auditLog, err = audit.Open(cfg.Audit.File)
+		if err != nil {
+			log.Error("Audit log: %v", err)
+			os.Exit(1)
+		}
+		srv.SetAuditLog(auditLog, cfg.Audit.IncludeReads)
+		log.Info("  Audit log:  %s", cfg.Audit.File)
+	}
 
 	// Setup snapshot callback - Production-grade implementation
 	srv.SetSnapshotCallback(func(path string) error {
@@ -178,6 +326,17 @@ func main() {
 			path = filepath.Join(snapshotDir, backup.GenerateSnapshotName("gibram"))
 		}
 
+		// Writes are paused during snapshots, so this LSN is exactly the
+		// last write the snapshot contains. Drop any stale LSN record first:
+		// a snapshot interrupted mid-write must not be paired with it.
+		var lsn uint64
+		if wal != nil {
+			lsn = wal.CurrentLSN()
+		}
+		if err := os.Remove(backup.SnapshotLSNPath(path)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
 		// Create snapshot file
 		f, err := os.Create(path)
 		if err != nil {
@@ -206,10 +365,18 @@ func main() {
 		if err := eng.Snapshot(gw); err != nil {
 			return err
 		}
+		if err := gw.Close(); err != nil {
+			return err
+		}
+		if err := f.Sync(); err != nil {
+			return err
+		}
 
 		// Record WAL LSN for recovery point
 		if wal != nil {
-			lsn := wal.CurrentLSN()
+			if err := backup.WriteSnapshotLSN(path, lsn); err != nil {
+				return err
+			}
 			log.Info("Snapshot completed: %s (WAL LSN: %d)", path, lsn)
 		} else {
 			log.Info("Snapshot completed: %s", path)
@@ -220,62 +387,97 @@ func main() {
 
 	// Setup restore callback - Production-grade implementation
 	srv.SetRestoreCallback(func(path string) error {
-		// Open snapshot file
-		f, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-		defer func() {
-			if err := f.Close(); err != nil {
-				log.Warn("Failed to close snapshot file: %v", err)
-			}
-		}()
+		return restoreSnapshot(eng, path, log)
+	})
 
-		// Detect if gzipped
-		var reader io.Reader
-		buf := make([]byte, 2)
-		if _, err := f.Read(buf); err != nil {
-			return err
-		}
-		if _, err := f.Seek(0, 0); err != nil {
-			return err
+	// After a point-in-time recovery, snapshot the result at the current WAL
+	// position so the next start does not replay the discarded records
+	if *recoverToLSN > 0 && durable == nil {
+		if err := srv.Save(filepath.Join(snapshotDir, backup.GenerateSnapshotName("gibram"))); err != nil {
+			log.Error("Snapshot after recovery failed: %v", err)
+			os.Exit(1)
 		}
+	}
 
-		if buf[0] == 0x1f && buf[1] == 0x8b {
-			// Gzip magic number
-			gr, err := gzip.NewReader(f)
-			if err != nil {
-				return err
-			}
-			defer func() {
-				if err := gr.Close(); err != nil {
-					log.Warn("Failed to close snapshot gzip reader: %v", err)
-				}
-			}()
-			reader = gr
-		} else {
-			reader = f
-		}
+	if err := srv.Start(cfg.Server.Addr); err != nil {
+		log.Error("Failed to start server: %v", err)
+		os.Exit(1)
+	}
 
-		log.Info("Restoring snapshot from %s", path)
+	// Periodic snapshots go through the server so writes pause while they run
+	var snapshotScheduler *backup.SnapshotScheduler
+	if cfg.Backup.SnapshotInterval > 0 {
+		snapshotScheduler = backup.NewSnapshotScheduler(cfg.Backup.SnapshotInterval, snapshotDir, cfg.Backup.SnapshotRetention, func() error {
+			return srv.Save(filepath.Join(snapshotDir, backup.GenerateSnapshotName("gibram")))
+		})
+		snapshotScheduler.Start()
+		log.Info("  Auto snapshot: every %s (keep %d)", cfg.Backup.SnapshotInterval, cfg.Backup.SnapshotRetention)
+	}
 
-		// Restore engine state
-		if err := eng.Restore(reader); err != nil {
-			return err
-		}
+	// Durable state syncs pause writes too, so their LSN matches the state
+	var durableScheduler *backup.SnapshotScheduler
+	if durable != nil {
+		durableScheduler = backup.NewSnapshotScheduler(cfg.Backup.DurableInterval, stateDir, 0, func() error {
+			return srv.WithWritesPaused(func() error {
+				return durable.Sync(currentLSN(wal))
+			})
+		})
+		durableScheduler.Start()
+	}
 
-		info := eng.Info()
-		log.Info("Restore completed: %d docs, %d textunits, %d entities, %d rels, %d communities",
-			info.DocumentCount, info.TextUnitCount, info.EntityCount,
-			info.RelationshipCount, info.CommunityCount)
+	// Prometheus endpoint
+	var metricsServer *http.Server
+	if cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(metricsCollector))
+		metricsServer = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Error("Metrics server failed: %v", err)
+			}
+		}()
+		log.Info("  Metrics:       http://%s/metrics", cfg.Metrics.Addr)
+	}
 
-		return nil
-	})
+	// JSON over HTTP gateway, over TLS when the protocol port uses it
+	var httpServer *http.Server
+	if cfg.HTTP.Addr != "" {
+		httpServer = &http.Server{Addr: cfg.HTTP.Addr, Handler: httpapi.NewHandler(srv), ReadHeaderTimeout: 10 * time.Second}
+		scheme := "http"
+		if cfg.HasTLS() {
+			tlsConfig, tlsEnabled, err := cfg.TLS.LoadOrGenerateTLSConfig(cfg.Server.DataDir)
+			if err != nil {
+				log.Error("Failed to configure HTTP TLS: %v", err)
+				os.Exit(1)
+			}
+			if tlsEnabled {
+				httpServer.TLSConfig = tlsConfig
+				scheme = "https"
+			}
+		}
+		go func() {
+			var err error
+			if httpServer.TLSConfig != nil {
+				err = httpServer.ListenAndServeTLS("", "")
+			} else {
+				err = httpServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				log.Error("HTTP gateway failed: %v", err)
+			}
+		}()
+		log.Info("  HTTP API:      %s://%s/v1", scheme, cfg.HTTP.Addr)
+	}
 
-	if err := srv.Start(cfg.Server.Addr); err != nil {
-		log.Error("Failed to start server: %v", err)
+	// OpenTelemetry traces (no-op without an endpoint)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Error("Failed to set up tracing: %v", err)
 		os.Exit(1)
 	}
+	if cfg.Tracing.Endpoint != "" {
+		log.Info("  Tracing:       OTLP to %s", cfg.Tracing.Endpoint)
+	}
 
 	// Print info
 	info := eng.Info()
@@ -296,6 +498,49 @@ func main() {
 		return nil
 	})
 
+	shutdownHandler.Register("http-gateway", 10, func(ctx context.Context) error {
+		if httpServer != nil {
+			return httpServer.Shutdown(ctx)
+		}
+		return nil
+	})
+
+	shutdownHandler.Register("audit-log", 11, func(ctx context.Context) error {
+		if auditLog != nil {
+			return auditLog.Close()
+		}
+		return nil
+	})
+
+	shutdownHandler.Register("metrics-server", 12, func(ctx context.Context) error {
+		if metricsServer != nil {
+			return metricsServer.Shutdown(ctx)
+		}
+		return nil
+	})
+
+	shutdownHandler.Register("tracing", 13, shutdownTracing)
+
+	shutdownHandler.Register("snapshot-scheduler", 15, func(ctx context.Context) error {
+		if snapshotScheduler != nil {
+			snapshotScheduler.Stop()
+		}
+		return nil
+	})
+
+	shutdownHandler.Register("durable-state", 16, func(ctx context.Context) error {
+		if durable == nil {
+			return nil
+		}
+		durableScheduler.Stop()
+		// The server is stopped, so no write can race this last sync
+		if err := durable.Sync(currentLSN(wal)); err != nil {
+			_ = durable.Close()
+			return err
+		}
+		return durable.Close()
+	})
+
 	shutdownHandler.Register("session-cleanup", 20, func(ctx context.Context) error {
 		eng.StopSessionCleanup()
 		return nil
@@ -327,6 +572,30 @@ func main() {
 		return nil
 	})
 
+	// Reload the config file on SIGHUP
+	if *configFile != "" {
+		shutdownHandler.OnReload(func() {
+			newCfg, err := config.LoadConfig(*configFile)
+			if err != nil {
+				log.Error("Config reload failed: %v", err)
+				return
+			}
+			newCfg.ApplyOverrides(config.CLIOverrides{
+				Addr:      *addr,
+				DataDir:   *dataDir,
+				VectorDim: *vectorDim,
+				LogLevel:  *logLevel,
+			})
+			if *insecure {
+				newCfg.Auth.Keys = nil
+				newCfg.TLS = config.TLSConfig{}
+			}
+			if err := srv.Reload(newCfg); err != nil {
+				log.Error("Config reload failed: %v", err)
+			}
+		})
+	}
+
 	// Start listening for signals
 	shutdownHandler.Start()
 
@@ -334,7 +603,85 @@ func main() {
 	shutdownHandler.Wait()
 
 	log.Info("Server stopped")
+}
+
+// exportSessionFile writes a gzip-compressed export of one session to path
+func exportSessionFile(eng *engine.Engine, sessionID, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(f)
+	if err := eng.ExportSession(sessionID, gw); err != nil {
+		_ = gw.Close()
+		_ = f.Close()
+		return err
+	}
+	if err := gw.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// currentLSN returns the WAL's last LSN, or 0 without a WAL
+func currentLSN(wal *backup.WAL) uint64 {
+	if wal == nil {
+		return 0
+	}
+	return wal.CurrentLSN()
+}
+
+// restoreSnapshot loads an engine snapshot, gzip-compressed or not
+func restoreSnapshot(eng *engine.Engine, path string, log *logging.Logger) error {
+	// Open snapshot file
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Warn("Failed to close snapshot file: %v", err)
+		}
+	}()
+
+	// Detect if gzipped
+	var reader io.Reader
+	buf := make([]byte, 2)
+	if _, err := f.Read(buf); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return err
+	}
+
+	if buf[0] == 0x1f && buf[1] == 0x8b {
+		// Gzip magic number
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := gr.Close(); err != nil {
+				log.Warn("Failed to close snapshot gzip reader: %v", err)
+			}
+		}()
+		reader = gr
+	} else {
+		reader = f
+	}
+
+	log.Info("Restoring snapshot from %s", path)
+
+	// Restore engine state
+	if err := eng.Restore(reader); err != nil {
+		return err
+	}
+
+	info := eng.Info()
+	log.Info("Restore completed: %d docs, %d textunits, %d entities, %d rels, %d communities",
+		info.DocumentCount, info.TextUnitCount, info.EntityCount,
+		info.RelationshipCount, info.CommunityCount)
 
-	// Suppress unused variable warnings
-	_ = recovery
+	return nil
 }
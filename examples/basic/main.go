@@ -4,14 +4,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/gibram-io/gibram/pkg/client"
+	"github.com/gibram-io/gibram/pkg/embed"
 	"github.com/gibram-io/gibram/pkg/types"
 )
 
+// embedder computes the example's embeddings; see getEmbedding
+var embedder embed.Embedder = embed.NewMock(1536)
+
 func main() {
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		embedder = embed.NewOpenAI(key, embed.DefaultOpenAIModel)
+	}
+
 	// Connect to GibRAM server with authentication
 	config := client.DefaultPoolConfig()
 	config.APIKey = "" // No auth in insecure mode
@@ -181,22 +191,12 @@ func main() {
 	fmt.Printf("  Communities: %d\n", info.CommunityCount)
 }
 
-// Mock embedding function (replace with actual OpenAI/etc call)
+// getEmbedding embeds text with the OpenAI API when OPENAI_API_KEY is set,
+// and with a deterministic mock otherwise
 func getEmbedding(text string) []float32 {
-	// In production, call your embedding model
-	// e.g., OpenAI ada-002, Cohere, etc.
-	embedding := make([]float32, 1536)
-
-	// Simple hash-based mock for demo
-	hash := 0
-	for _, c := range text {
-		hash = hash*31 + int(c)
-	}
-
-	for i := range embedding {
-		hash = hash*1103515245 + 12345
-		embedding[i] = float32(hash%1000) / 1000.0
+	vecs, err := embedder.Embed(context.Background(), []string{text})
+	if err != nil {
+		log.Fatalf("Embedding failed: %v", err)
 	}
-
-	return embedding
+	return vecs[0]
 }
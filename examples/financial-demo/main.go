@@ -581,7 +581,6 @@ func runQueryDemos(c *client.Client, vectorDim int) {
 			MaxEntities:    20,
 			MaxTextUnits:   5,
 			MaxCommunities: 5,
-			DeadlineMs:     100,
 		}
 
 		start := time.Now()
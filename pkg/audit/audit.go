@@ -0,0 +1,69 @@
+// Package audit records an append-only trail of the commands clients run
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record is one audited command, written as a JSON line
+type Record struct {
+	Time      time.Time `json:"time"`
+	KeyID     string    `json:"key_id,omitempty"` // empty when authentication is disabled
+	SessionID string    `json:"session_id,omitempty"`
+	Command   string    `json:"command"`
+	TargetID  uint64    `json:"target_id,omitempty"` // object the command named or created
+	OK        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Logger appends records to a writer, one JSON object per line
+type Logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+// New creates a logger writing to w
+func New(w io.Writer) *Logger {
+	return &Logger{w: w}
+}
+
+// Open creates a logger appending to the file at path, creating it if needed
+func Open(path string) (*Logger, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("open audit log: %w", err)
+	}
+	return &Logger{w: f, closer: f}, nil
+}
+
+// Log writes r, stamping it with the current time if unset. Each record is
+// written with a single Write so concurrent records never interleave.
+func (l *Logger) Log(r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(line)
+	return err
+}
+
+// Close closes the underlying file, if the logger opened one
+func (l *Logger) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
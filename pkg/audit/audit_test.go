@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogger_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	// Reopening appends instead of truncating
+	for i, cmd := range []string{"CMD_ADD_ENTITY", "CMD_DELETE_ENTITY"} {
+		l, err := Open(path)
+		if err != nil {
+			t.Fatalf("Open() error: %v", err)
+		}
+		if err := l.Log(Record{KeyID: "k", SessionID: "s", Command: cmd, TargetID: uint64(i + 1), OK: true}); err != nil {
+			t.Fatalf("Log() error: %v", err)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open audit file: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if records[0].Command != "CMD_ADD_ENTITY" || records[1].TargetID != 2 || records[1].Time.IsZero() {
+		t.Errorf("unexpected records: %+v", records)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // =============================================================================
@@ -1647,3 +1648,40 @@ func itoa(i int) string {
 	}
 	return string(buf[pos:])
 }
+
+func TestWAL_PeriodicSyncAdvancesFlushedLSN(t *testing.T) {
+	wal, err := NewWAL(t.TempDir(), SyncPeriodic)
+	if err != nil {
+		t.Fatalf("NewWAL() error: %v", err)
+	}
+	defer func() {
+		if err := wal.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}()
+
+	lsn, err := wal.Append(EntryInsert, "k", []byte("v"))
+	if err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	deadline := time.Now().Add(3 * DefaultSyncInterval)
+	for wal.FlushedLSN() < lsn {
+		if time.Now().After(deadline) {
+			t.Fatalf("FlushedLSN() = %d after %s, want %d", wal.FlushedLSN(), 3*DefaultSyncInterval, lsn)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseSyncMode(t *testing.T) {
+	for name, want := range map[string]SyncMode{"": SyncPeriodic, "always": SyncEveryWrite, "periodic": SyncPeriodic, "never": SyncNever} {
+		got, err := ParseSyncMode(name)
+		if err != nil || got != want {
+			t.Errorf("ParseSyncMode(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseSyncMode("sometimes"); err == nil {
+		t.Error("ParseSyncMode(sometimes) should fail")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -129,6 +130,85 @@ func (r *Recovery) Execute(plan *RecoveryPlan, restoreFunc func(path string) err
 	return nil
 }
 
+// SnapshotLSNPath returns the sidecar file recording the WAL LSN a snapshot covers
+func SnapshotLSNPath(snapshotPath string) string {
+	return snapshotPath + ".lsn"
+}
+
+// WriteSnapshotLSN records the WAL LSN covered by a completed snapshot
+func WriteSnapshotLSN(snapshotPath string, lsn uint64) error {
+	tmp := SnapshotLSNPath(snapshotPath) + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(lsn, 10)), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, SnapshotLSNPath(snapshotPath))
+}
+
+// ReadSnapshotLSN returns the WAL LSN recorded for a snapshot
+func ReadSnapshotLSN(snapshotPath string) (uint64, error) {
+	data, err := os.ReadFile(SnapshotLSNPath(snapshotPath))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+}
+
+// LatestSnapshot returns the newest snapshot in the data or snapshot
+// directory that has a recorded WAL LSN. Snapshots without one cannot be
+// combined with WAL replay and are ignored.
+func (r *Recovery) LatestSnapshot() (path string, lsn uint64, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var newest time.Time
+	r.walkSnapshots(func(f string, snapLSN uint64, modTime time.Time) {
+		if !ok || modTime.After(newest) {
+			path, lsn, ok, newest = f, snapLSN, true, modTime
+		}
+	})
+	return path, lsn, ok
+}
+
+// SnapshotAtOrBefore returns the snapshot with the highest recorded WAL LSN
+// not after targetLSN, the newest one on a tie, for RecoverTo
+func (r *Recovery) SnapshotAtOrBefore(targetLSN uint64) (path string, lsn uint64, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var newest time.Time
+	r.walkSnapshots(func(f string, snapLSN uint64, modTime time.Time) {
+		if snapLSN > targetLSN {
+			return
+		}
+		if !ok || snapLSN > lsn || (snapLSN == lsn && modTime.After(newest)) {
+			path, lsn, ok, newest = f, snapLSN, true, modTime
+		}
+	})
+	return path, lsn, ok
+}
+
+// walkSnapshots calls fn for each snapshot in the data or snapshot directory
+// that has a recorded WAL LSN
+func (r *Recovery) walkSnapshots(fn func(path string, lsn uint64, modTime time.Time)) {
+	for _, dir := range []string{r.dataDir, r.snapshotDir} {
+		files, err := filepath.Glob(filepath.Join(dir, "*.gibram"))
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			snapLSN, err := ReadSnapshotLSN(f)
+			if err != nil {
+				continue
+			}
+			info, err := os.Stat(f)
+			if err != nil {
+				continue
+			}
+			fn(f, snapLSN, info.ModTime())
+		}
+	}
+}
+
 // Cleanup removes old snapshots and WAL files
 func (r *Recovery) Cleanup(keepSnapshots, keepWALDays int) error {
 	r.mu.Lock()
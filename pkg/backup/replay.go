@@ -0,0 +1,450 @@
+// Package backup - WAL command records and replay
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+
+	"github.com/gibram-io/gibram/pkg/codec"
+	"github.com/gibram-io/gibram/pkg/engine"
+	"github.com/gibram-io/gibram/pkg/types"
+	"github.com/gibram-io/gibram/pkg/vector"
+	pb "github.com/gibram-io/gibram/proto/gibrampb"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrShortRecord is returned for WAL data too short to hold a command record
+var ErrShortRecord = errors.New("WAL record too short")
+
+// A command record stores one acknowledged mutation: the entry key is the
+// session ID and the data is [4 command type][protobuf request payload].
+// Replaying records in LSN order on top of the snapshot taken at that LSN
+// reproduces the same IDs, because IDs are assigned sequentially per session.
+
+// EncodeCommand builds the WAL data for a command record
+func EncodeCommand(cmd pb.CommandType, payload []byte) []byte {
+	buf := make([]byte, 4+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(cmd))
+	copy(buf[4:], payload)
+	return buf
+}
+
+// DecodeCommand splits command record data into command type and payload
+func DecodeCommand(data []byte) (pb.CommandType, []byte, error) {
+	if len(data) < 4 {
+		return 0, nil, ErrShortRecord
+	}
+	return pb.CommandType(binary.BigEndian.Uint32(data)), data[4:], nil
+}
+
+// commandEntryType classifies a command for the WAL entry header
+func commandEntryType(cmd pb.CommandType) EntryType {
+	switch cmd {
+	case pb.CommandType_CMD_ADD_DOCUMENT,
+		pb.CommandType_CMD_ADD_TEXTUNIT,
+		pb.CommandType_CMD_ADD_ENTITY,
+		pb.CommandType_CMD_ADD_RELATIONSHIP,
+		pb.CommandType_CMD_ADD_COMMUNITY:
+		return EntryInsert
+	case pb.CommandType_CMD_MSET_DOCUMENTS,
+		pb.CommandType_CMD_MSET_TEXTUNITS,
+		pb.CommandType_CMD_MSET_ENTITIES,
+		pb.CommandType_CMD_MSET_RELATIONSHIPS:
+		return EntryInsert
+	case pb.CommandType_CMD_DELETE_SESSION,
+		pb.CommandType_CMD_FLUSH_SESSION,
+		pb.CommandType_CMD_DELETE_DOCUMENT,
+		pb.CommandType_CMD_DELETE_TEXTUNIT,
+		pb.CommandType_CMD_DELETE_ENTITY,
+		pb.CommandType_CMD_DELETE_RELATIONSHIP,
+		pb.CommandType_CMD_DELETE_COMMUNITY,
+		pb.CommandType_CMD_MDELETE_TEXTUNITS,
+		pb.CommandType_CMD_MDELETE_ENTITIES,
+		pb.CommandType_CMD_MDELETE_RELATIONSHIPS:
+		return EntryDelete
+	default:
+		return EntryUpdate
+	}
+}
+
+// AppendCommand records an applied mutation for sessionID
+func (w *WAL) AppendCommand(sessionID string, cmd pb.CommandType, payload []byte) (uint64, error) {
+	return w.Append(commandEntryType(cmd), sessionID, EncodeCommand(cmd, payload))
+}
+
+// ReplayWAL re-applies command records with LSN greater than sinceLSN, in
+// order, to eng. Pass the LSN recorded with the restored snapshot, or 0 when
+// starting empty. A record that no longer applies is logged and skipped so
+// one bad record cannot block startup; read failures abort the replay. A
+// torn record at the end of a segment marks a crash and is ignored.
+func (r *Recovery) ReplayWAL(eng *engine.Engine, walDir string, sinceLSN uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, err := replayRange(eng, walDir, sinceLSN, math.MaxUint64)
+	return err
+}
+
+// RecoverTo rebuilds eng as it was right after WAL record targetLSN. It
+// restores snapshotPath, or clears eng when it is "", then replays the
+// records after the snapshot's LSN up to and including targetLSN. The
+// snapshot must have a recorded LSN no later than targetLSN.
+func (r *Recovery) RecoverTo(eng *engine.Engine, snapshotPath string, targetLSN uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sinceLSN uint64
+	if snapshotPath != "" {
+		lsn, err := ReadSnapshotLSN(snapshotPath)
+		if err != nil {
+			return fmt.Errorf("read snapshot LSN: %w", err)
+		}
+		if lsn > targetLSN {
+			return fmt.Errorf("snapshot %s covers LSN %d, after target %d", snapshotPath, lsn, targetLSN)
+		}
+		if err := restoreSnapshotFile(eng, snapshotPath); err != nil {
+			return fmt.Errorf("restore snapshot: %w", err)
+		}
+		sinceLSN = lsn
+	} else if err := eng.Clear(); err != nil {
+		return err
+	}
+
+	lastLSN, err := replayRange(eng, r.walDir, sinceLSN, targetLSN)
+	if err != nil {
+		return err
+	}
+	if lastLSN < targetLSN {
+		return fmt.Errorf("WAL ends at LSN %d, before target %d", lastLSN, targetLSN)
+	}
+	return nil
+}
+
+// replayRange applies the records with sinceLSN < LSN <= untilLSN and
+// returns the LSN of the last one read, or sinceLSN if there were none
+func replayRange(eng *engine.Engine, walDir string, sinceLSN, untilLSN uint64) (uint64, error) {
+	entries, torn, err := readEntries(walDir, sinceLSN+1)
+	if err != nil {
+		return 0, fmt.Errorf("read WAL: %w", err)
+	}
+	for _, path := range torn {
+		log.Printf("Recovery: ignoring torn record at the end of %s", path)
+	}
+
+	lastLSN := sinceLSN
+	applied, skipped := 0, 0
+	for _, entry := range entries {
+		if entry.LSN > untilLSN {
+			break
+		}
+		lastLSN = entry.LSN
+		if entry.Type == EntryCheckpoint {
+			continue
+		}
+		if err := applyCommand(eng, entry); err != nil {
+			log.Printf("Recovery: skipping WAL entry %d: %v", entry.LSN, err)
+			skipped++
+			continue
+		}
+		applied++
+	}
+
+	if applied > 0 || skipped > 0 {
+		log.Printf("Recovery: replayed %d WAL entries, skipped %d (LSN %d to %d)", applied, skipped, sinceLSN+1, lastLSN)
+	}
+	return lastLSN, nil
+}
+
+// restoreSnapshotFile loads an engine snapshot file, gzip-compressed or not
+func restoreSnapshotFile(eng *engine.Engine, path string) (retErr error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = err
+		}
+	}()
+
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gr.Close() }()
+		r = gr
+	}
+	return eng.Restore(r)
+}
+
+// applyCommand applies one command record the way the server handler did
+func applyCommand(eng *engine.Engine, entry *WALEntry) error {
+	cmd, payload, err := DecodeCommand(entry.Data)
+	if err != nil {
+		return err
+	}
+	sessionID := entry.Key
+
+	switch cmd {
+	case pb.CommandType_CMD_ADD_DOCUMENT:
+		var req pb.AddDocumentRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.AddDocument(sessionID, req.ExternalId, req.Filename)
+		return err
+
+	case pb.CommandType_CMD_DELETE_DOCUMENT:
+		var req pb.DeleteByIDRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		if !eng.DeleteDocument(sessionID, req.Id) {
+			return fmt.Errorf("document %d not found", req.Id)
+		}
+		return nil
+
+	case pb.CommandType_CMD_ADD_TEXTUNIT:
+		var req pb.AddTextUnitRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.AddTextUnit(sessionID, req.ExternalId, req.DocumentId, req.Content, req.Embedding, int(req.TokenCount))
+		return err
+
+	case pb.CommandType_CMD_DELETE_TEXTUNIT:
+		var req pb.DeleteByIDRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		if !eng.DeleteTextUnit(sessionID, req.Id) {
+			return fmt.Errorf("textunit %d not found", req.Id)
+		}
+		return nil
+
+	case pb.CommandType_CMD_LINK_TEXTUNIT_ENTITY:
+		var req pb.LinkTextUnitEntityRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		if !eng.LinkTextUnitToEntity(sessionID, req.TextunitId, req.EntityId) {
+			return fmt.Errorf("link textunit %d to entity %d failed", req.TextunitId, req.EntityId)
+		}
+		return nil
+
+	case pb.CommandType_CMD_ADD_ENTITY:
+		var req pb.AddEntityRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.AddEntityWithMetadata(
+			sessionID, req.ExternalId, req.Title, req.Type, req.Description, req.Embedding, req.TitleEmbedding, req.Metadata,
+		)
+		return err
+
+	case pb.CommandType_CMD_UPDATE_ENTITY_DESC:
+		var req pb.UpdateEntityDescRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		if !eng.UpdateEntityDescription(sessionID, req.Id, req.Description, req.Embedding) {
+			return fmt.Errorf("entity %d not found", req.Id)
+		}
+		if len(req.Metadata) > 0 && !eng.UpdateEntityMetadata(sessionID, req.Id, req.Metadata) {
+			return fmt.Errorf("entity %d not found", req.Id)
+		}
+		return nil
+
+	case pb.CommandType_CMD_UPDATE_ENTITY_TITLE:
+		var req pb.UpdateEntityTitleRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return eng.UpdateEntityTitle(sessionID, req.Id, req.Title)
+
+	case pb.CommandType_CMD_DELETE_ENTITY:
+		var req pb.DeleteByIDRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		if !eng.DeleteEntity(sessionID, req.Id) {
+			return fmt.Errorf("entity %d not found", req.Id)
+		}
+		return nil
+
+	case pb.CommandType_CMD_MERGE_ENTITIES:
+		var req pb.MergeEntitiesRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return eng.MergeEntities(sessionID, req.KeepId, req.MergeId)
+
+	case pb.CommandType_CMD_ADD_RELATIONSHIP:
+		var req pb.AddRelationshipRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.AddRelationshipWithOptions(
+			sessionID, req.ExternalId, req.SourceId, req.TargetId, req.Type, req.Description, req.Weight,
+			types.RelationshipOptions{ValidFrom: req.ValidFrom, ValidUntil: req.ValidUntil, Directed: req.Directed},
+		)
+		return err
+
+	case pb.CommandType_CMD_UPDATE_RELATIONSHIP_WEIGHT:
+		var req pb.UpdateRelationshipWeightRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return eng.UpdateRelationshipWeight(sessionID, req.Id, req.Weight)
+
+	case pb.CommandType_CMD_DELETE_RELATIONSHIP:
+		var req pb.DeleteByIDRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		if !eng.DeleteRelationship(sessionID, req.Id) {
+			return fmt.Errorf("relationship %d not found", req.Id)
+		}
+		return nil
+
+	case pb.CommandType_CMD_ADD_COMMUNITY:
+		var req pb.AddCommunityRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.AddCommunity(
+			sessionID, req.ExternalId, req.Title, req.Summary, req.FullContent,
+			int(req.Level), req.EntityIds, req.RelationshipIds, req.Embedding,
+		)
+		return err
+
+	case pb.CommandType_CMD_DELETE_COMMUNITY:
+		var req pb.DeleteByIDRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		if !eng.DeleteCommunity(sessionID, req.Id) {
+			return fmt.Errorf("community %d not found", req.Id)
+		}
+		return nil
+
+	case pb.CommandType_CMD_MSET_DOCUMENTS:
+		var req pb.MSetDocumentsRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.MSetDocuments(sessionID, codec.ProtoToBulkDocuments(req.Documents))
+		return err
+
+	case pb.CommandType_CMD_MSET_TEXTUNITS:
+		var req pb.MSetTextUnitsRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.MSetTextUnits(sessionID, codec.ProtoToBulkTextUnits(req.Textunits))
+		return err
+
+	case pb.CommandType_CMD_MSET_ENTITIES:
+		var req pb.MSetEntitiesRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.MSetEntities(sessionID, codec.ProtoToBulkEntities(req.Entities))
+		return err
+
+	case pb.CommandType_CMD_MSET_RELATIONSHIPS:
+		var req pb.MSetRelationshipsRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.MSetRelationships(sessionID, codec.ProtoToBulkRelationships(req.Relationships))
+		return err
+
+	case pb.CommandType_CMD_MLINK_TEXTUNIT_ENTITY:
+		var req pb.MLinkTextUnitEntityRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.MLinkTextUnitsToEntities(sessionID, codec.ProtoToBulkLinks(req.Links), req.ContinueOnError)
+		return err
+
+	case pb.CommandType_CMD_MDELETE_TEXTUNITS:
+		var req pb.MDeleteRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.MDeleteTextUnits(sessionID, req.Ids)
+		return err
+
+	case pb.CommandType_CMD_MDELETE_ENTITIES:
+		var req pb.MDeleteRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.MDeleteEntities(sessionID, req.Ids)
+		return err
+
+	case pb.CommandType_CMD_MDELETE_RELATIONSHIPS:
+		var req pb.MDeleteRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, err := eng.MDeleteRelationships(sessionID, req.Ids)
+		return err
+
+	case pb.CommandType_CMD_DELETE_SESSION:
+		if !eng.DeleteSession(sessionID) {
+			return fmt.Errorf("session %s not found", sessionID)
+		}
+		return nil
+
+	case pb.CommandType_CMD_FLUSH_SESSION:
+		return eng.FlushSession(sessionID)
+
+	case pb.CommandType_CMD_SET_SESSION_TTL:
+		var req pb.SetSessionTTLRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return eng.SetSessionTTL(sessionID, req.Ttl, req.IdleTtl)
+
+	case pb.CommandType_CMD_SET_SESSION_METADATA:
+		var req pb.SetSessionMetadataRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		return eng.SetSessionMetadata(sessionID, req.Metadata, req.Replace)
+
+	case pb.CommandType_CMD_MERGE_SESSION:
+		var req pb.MergeSessionRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		policy, err := codec.ProtoToConflictPolicy(req.OnConflict)
+		if err != nil {
+			return err
+		}
+		return eng.MergeSession(sessionID, req.SourceSessionId, policy)
+
+	case pb.CommandType_CMD_QUANTIZE_INDEX:
+		var req pb.QuantizeIndexRequest
+		if err := proto.Unmarshal(payload, &req); err != nil {
+			return err
+		}
+		_, _, err := eng.QuantizeVectorIndex(sessionID, vector.Precision(req.Precision))
+		return err
+
+	default:
+		return fmt.Errorf("unsupported command %s", cmd)
+	}
+}
@@ -0,0 +1,306 @@
+// Package backup - WAL replay tests
+package backup
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gibram-io/gibram/pkg/engine"
+	pb "github.com/gibram-io/gibram/proto/gibrampb"
+	"google.golang.org/protobuf/proto"
+)
+
+const replaySession = "replay-session"
+
+// logCommand applies a command to eng and records it, as the server does
+func logCommand(t *testing.T, w *WAL, eng *engine.Engine, cmd pb.CommandType, req proto.Message) uint64 {
+	t.Helper()
+	payload, err := proto.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal() error: %v", err)
+	}
+	if err := applyCommand(eng, &WALEntry{Key: replaySession, Data: EncodeCommand(cmd, payload)}); err != nil {
+		t.Fatalf("apply %s error: %v", cmd, err)
+	}
+	lsn, err := w.AppendCommand(replaySession, cmd, payload)
+	if err != nil {
+		t.Fatalf("AppendCommand() error: %v", err)
+	}
+	return lsn
+}
+
+func writeGraph(t *testing.T, w *WAL, eng *engine.Engine) {
+	t.Helper()
+	logCommand(t, w, eng, pb.CommandType_CMD_ADD_DOCUMENT, &pb.AddDocumentRequest{ExternalId: "doc-1", Filename: "a.txt"})
+	logCommand(t, w, eng, pb.CommandType_CMD_ADD_TEXTUNIT, &pb.AddTextUnitRequest{ExternalId: "tu-1", DocumentId: 1, Content: "text"})
+	logCommand(t, w, eng, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-1", Title: "Alpha", Type: "org"})
+	logCommand(t, w, eng, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-2", Title: "Beta", Type: "org"})
+	logCommand(t, w, eng, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-3", Title: "Gamma", Type: "person"})
+	logCommand(t, w, eng, pb.CommandType_CMD_LINK_TEXTUNIT_ENTITY, &pb.LinkTextUnitEntityRequest{TextunitId: 1, EntityId: 1})
+	logCommand(t, w, eng, pb.CommandType_CMD_ADD_RELATIONSHIP, &pb.AddRelationshipRequest{SourceId: 1, TargetId: 2, Type: "OWNS", Weight: 1})
+	logCommand(t, w, eng, pb.CommandType_CMD_DELETE_ENTITY, &pb.DeleteByIDRequest{Id: 3})
+}
+
+func assertSameGraph(t *testing.T, want, got *engine.Engine) {
+	t.Helper()
+	wantInfo, gotInfo := want.Info(), got.Info()
+	if gotInfo.DocumentCount != wantInfo.DocumentCount ||
+		gotInfo.TextUnitCount != wantInfo.TextUnitCount ||
+		gotInfo.EntityCount != wantInfo.EntityCount ||
+		gotInfo.RelationshipCount != wantInfo.RelationshipCount {
+		t.Fatalf("recovered counts = %+v, want %+v", gotInfo, wantInfo)
+	}
+	for _, title := range []string{"Alpha", "Beta", "Delta"} {
+		wantEnt, wantOK := want.GetEntityByTitle(replaySession, title)
+		gotEnt, gotOK := got.GetEntityByTitle(replaySession, title)
+		if gotOK != wantOK || (wantOK && gotEnt.ID != wantEnt.ID) {
+			t.Errorf("entity %s recovered as (%v, %v), want (%v, %v)", title, gotEnt, gotOK, wantEnt, wantOK)
+		}
+	}
+}
+
+func TestReplayWAL_CrashRecovery(t *testing.T) {
+	walDir := t.TempDir()
+	w, err := NewWAL(walDir, SyncEveryWrite)
+	if err != nil {
+		t.Fatalf("NewWAL() error: %v", err)
+	}
+
+	live := engine.NewEngine(4)
+	writeGraph(t, w, live)
+
+	// Crash: the process dies without closing the WAL; restart from nothing
+	restarted := engine.NewEngine(4)
+	if err := NewRecovery(t.TempDir()).ReplayWAL(restarted, walDir, 0); err != nil {
+		t.Fatalf("ReplayWAL() error: %v", err)
+	}
+	assertSameGraph(t, live, restarted)
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+}
+
+func TestReplayWAL_AfterSnapshot(t *testing.T) {
+	walDir := t.TempDir()
+	w, err := NewWAL(walDir, SyncEveryWrite)
+	if err != nil {
+		t.Fatalf("NewWAL() error: %v", err)
+	}
+	defer func() {
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}()
+
+	live := engine.NewEngine(4)
+	writeGraph(t, w, live)
+
+	var snap bytes.Buffer
+	if err := live.Snapshot(&snap); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+	snapLSN := w.CurrentLSN()
+
+	// Writes after the snapshot exist only in the WAL
+	logCommand(t, w, live, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{Title: "Delta", Type: "org"})
+	logCommand(t, w, live, pb.CommandType_CMD_ADD_RELATIONSHIP, &pb.AddRelationshipRequest{SourceId: 2, TargetId: 4, Type: "FUNDS", Weight: 1})
+	logCommand(t, w, live, pb.CommandType_CMD_DELETE_RELATIONSHIP, &pb.DeleteByIDRequest{Id: 1})
+
+	restarted := engine.NewEngine(4)
+	if err := restarted.Restore(&snap); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+	if err := NewRecovery(t.TempDir()).ReplayWAL(restarted, walDir, snapLSN); err != nil {
+		t.Fatalf("ReplayWAL() error: %v", err)
+	}
+	assertSameGraph(t, live, restarted)
+}
+
+func TestNewWAL_ResumesAfterTornRecord(t *testing.T) {
+	walDir := t.TempDir()
+	w, err := NewWAL(walDir, SyncEveryWrite)
+	if err != nil {
+		t.Fatalf("NewWAL() error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := w.Append(EntryInsert, "k", []byte("v")); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	// Simulate a crash mid-write: a partial record header at the tail
+	f, err := os.OpenFile(filepath.Join(walDir, "wal_00000000.log"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile() error: %v", err)
+	}
+	if _, err := f.Write([]byte{0, 0, 0, 0, 0, 0, 0, 4, 1}); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	w2, err := NewWAL(walDir, SyncEveryWrite)
+	if err != nil {
+		t.Fatalf("NewWAL() after crash error: %v", err)
+	}
+	defer func() {
+		if err := w2.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}()
+	if w2.CurrentLSN() != 3 {
+		t.Fatalf("CurrentLSN() = %d, want 3", w2.CurrentLSN())
+	}
+
+	lsn, err := w2.Append(EntryInsert, "k", []byte("v"))
+	if err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+	if lsn != 4 {
+		t.Errorf("Append() LSN = %d, want 4", lsn)
+	}
+
+	entries, err := ReadEntries(walDir, 0)
+	if err != nil {
+		t.Fatalf("ReadEntries() error: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("ReadEntries() returned %d entries, want 4", len(entries))
+	}
+}
+
+func TestReplayWAL_IgnoresTornTail(t *testing.T) {
+	tests := []struct {
+		name string
+		tear func(data []byte) []byte
+	}{
+		{"truncated", func(data []byte) []byte { return data[:len(data)-6] }},
+		{"bad checksum", func(data []byte) []byte {
+			data[len(data)-12] ^= 0xff
+			return data
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			walDir := t.TempDir()
+			w, err := NewWAL(walDir, SyncEveryWrite)
+			if err != nil {
+				t.Fatalf("NewWAL() error: %v", err)
+			}
+			live := engine.NewEngine(4)
+			writeGraph(t, w, live)
+
+			// The process dies while writing a record that was never applied
+			payload, _ := proto.Marshal(&pb.AddEntityRequest{Title: "Delta", Type: "org"})
+			if _, err := w.AppendCommand(replaySession, pb.CommandType_CMD_ADD_ENTITY, payload); err != nil {
+				t.Fatalf("AppendCommand() error: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error: %v", err)
+			}
+			segment := filepath.Join(walDir, "wal_00000000.log")
+			data, err := os.ReadFile(segment)
+			if err != nil {
+				t.Fatalf("ReadFile() error: %v", err)
+			}
+			if err := os.WriteFile(segment, tt.tear(data), 0644); err != nil {
+				t.Fatalf("WriteFile() error: %v", err)
+			}
+
+			restarted := engine.NewEngine(4)
+			if err := NewRecovery(t.TempDir()).ReplayWAL(restarted, walDir, 0); err != nil {
+				t.Fatalf("ReplayWAL() error: %v", err)
+			}
+			assertSameGraph(t, live, restarted)
+
+			entries, torn, err := readEntries(walDir, 0)
+			if err != nil {
+				t.Fatalf("readEntries() error: %v", err)
+			}
+			if len(entries) != 8 || len(torn) != 1 {
+				t.Errorf("readEntries() = %d entries, %d torn segments; want 8, 1", len(entries), len(torn))
+			}
+		})
+	}
+}
+
+func TestRecoverTo_SnapshotThenWALUpToLSN(t *testing.T) {
+	dataDir := t.TempDir()
+	w, err := NewWAL(filepath.Join(dataDir, "wal"), SyncEveryWrite)
+	if err != nil {
+		t.Fatalf("NewWAL() error: %v", err)
+	}
+	defer func() {
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}()
+
+	live := engine.NewEngine(4)
+	addEntity := func(i int) {
+		logCommand(t, w, live, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{Title: fmt.Sprintf("E%d", i), Type: "org"})
+	}
+	for i := 1; i <= 5; i++ {
+		addEntity(i)
+	}
+
+	snapDir := filepath.Join(dataDir, "snapshots")
+	if err := os.MkdirAll(snapDir, 0755); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+	snapPath := filepath.Join(snapDir, "at5.gibram")
+	var snap bytes.Buffer
+	if err := live.Snapshot(&snap); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+	if err := os.WriteFile(snapPath, snap.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile() error: %v", err)
+	}
+	if err := WriteSnapshotLSN(snapPath, w.CurrentLSN()); err != nil {
+		t.Fatalf("WriteSnapshotLSN() error: %v", err)
+	}
+
+	for i := 6; i <= 10; i++ {
+		addEntity(i)
+	}
+	if w.CurrentLSN() != 10 {
+		t.Fatalf("CurrentLSN() = %d, want 10", w.CurrentLSN())
+	}
+
+	recovery := NewRecovery(dataDir)
+	path, lsn, ok := recovery.SnapshotAtOrBefore(8)
+	if !ok || path != snapPath || lsn != 5 {
+		t.Fatalf("SnapshotAtOrBefore(8) = (%s, %d, %v), want (%s, 5, true)", path, lsn, ok, snapPath)
+	}
+
+	for _, start := range []string{snapPath, ""} {
+		recovered := engine.NewEngine(4)
+		if err := recovery.RecoverTo(recovered, start, 8); err != nil {
+			t.Fatalf("RecoverTo(%q, 8) error: %v", start, err)
+		}
+		if got := recovered.Info().EntityCount; got != 8 {
+			t.Errorf("RecoverTo(%q, 8) entity count = %d, want 8", start, got)
+		}
+		for i := 1; i <= 10; i++ {
+			_, found := recovered.GetEntityByTitle(replaySession, fmt.Sprintf("E%d", i))
+			if found != (i <= 8) {
+				t.Errorf("RecoverTo(%q, 8): entity E%d present = %v", start, i, found)
+			}
+		}
+	}
+
+	if err := recovery.RecoverTo(engine.NewEngine(4), snapPath, 3); err == nil {
+		t.Error("RecoverTo() before the snapshot's LSN should fail")
+	}
+	if err := recovery.RecoverTo(engine.NewEngine(4), snapPath, 11); err == nil {
+		t.Error("RecoverTo() past the end of the WAL should fail")
+	}
+}
@@ -0,0 +1,119 @@
+// Package backup - Periodic snapshot scheduling and retention
+package backup
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SnapshotScheduler takes a snapshot every interval and prunes snapshot
+// files in dir beyond the retention count
+type SnapshotScheduler struct {
+	interval  time.Duration
+	dir       string
+	retention int
+	snapshot  func() error
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewSnapshotScheduler creates a scheduler. snapshot must write a file named
+// by GenerateSnapshotName into dir; retention <= 0 keeps every snapshot.
+func NewSnapshotScheduler(interval time.Duration, dir string, retention int, snapshot func() error) *SnapshotScheduler {
+	return &SnapshotScheduler{
+		interval:  interval,
+		dir:       dir,
+		retention: retention,
+		snapshot:  snapshot,
+		stopCh:    make(chan struct{}),
+	}
+}
+
+// Start starts the scheduler loop
+func (s *SnapshotScheduler) Start() {
+	s.wg.Add(1)
+	go s.run()
+}
+
+// Stop stops the scheduler and waits for a running snapshot to finish
+func (s *SnapshotScheduler) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+func (s *SnapshotScheduler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := s.snapshot(); err != nil {
+				log.Printf("Scheduled snapshot failed: %v", err)
+				continue
+			}
+			if _, err := PruneSnapshots(s.dir, s.retention); err != nil {
+				log.Printf("Snapshot pruning failed: %v", err)
+			}
+		}
+	}
+}
+
+// PruneSnapshots keeps the newest keep snapshots in dir, ordered by the
+// timestamp in their GenerateSnapshotName name, and removes the rest along
+// with their LSN files. Files with other names are left alone. keep <= 0
+// removes nothing. It returns the removed snapshot paths.
+func PruneSnapshots(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.gibram"))
+	if err != nil {
+		return nil, err
+	}
+
+	type snapshotFile struct {
+		path string
+		at   time.Time
+	}
+	snapshots := make([]snapshotFile, 0, len(files))
+	for _, f := range files {
+		at, err := ParseSnapshotTime(f)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshotFile{path: f, at: at})
+	}
+	if len(snapshots) <= keep {
+		return nil, nil
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		if !snapshots[i].at.Equal(snapshots[j].at) {
+			return snapshots[i].at.After(snapshots[j].at)
+		}
+		return snapshots[i].path > snapshots[j].path
+	})
+
+	var removed []string
+	for _, snap := range snapshots[keep:] {
+		if err := os.Remove(SnapshotLSNPath(snap.path)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		if err := os.Remove(snap.path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed = append(removed, snap.path)
+	}
+	return removed, nil
+}
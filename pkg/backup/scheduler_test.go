@@ -0,0 +1,117 @@
+// Package backup - snapshot scheduler tests
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func touch(t *testing.T, path string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatalf("WriteFile(%s) error: %v", path, err)
+	}
+}
+
+func TestPruneSnapshots_KeepsNewest(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"gibram_20250101_120000.gibram",
+		"gibram_20250301_000000.gibram",
+		"gibram_20241231_235959.gibram",
+		"gibram_20250201_080000.gibram",
+		"gibram_20250102_090000.gibram",
+	}
+	for _, name := range names {
+		touch(t, filepath.Join(dir, name))
+		touch(t, SnapshotLSNPath(filepath.Join(dir, name)))
+	}
+	// Not a scheduled snapshot name: never pruned
+	touch(t, filepath.Join(dir, "manual.gibram"))
+
+	removed, err := PruneSnapshots(dir, 2)
+	if err != nil {
+		t.Fatalf("PruneSnapshots() error: %v", err)
+	}
+	if len(removed) != 3 {
+		t.Errorf("PruneSnapshots() removed %d files, want 3", len(removed))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	var got []string
+	for _, f := range files {
+		got = append(got, filepath.Base(f))
+	}
+	sort.Strings(got)
+	want := []string{
+		"gibram_20250201_080000.gibram",
+		"gibram_20250201_080000.gibram.lsn",
+		"gibram_20250301_000000.gibram",
+		"gibram_20250301_000000.gibram.lsn",
+		"manual.gibram",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("remaining files = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("remaining files = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestPruneSnapshots_WithinRetention(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, filepath.Join(dir, "gibram_20250101_120000.gibram"))
+	touch(t, filepath.Join(dir, "gibram_20250102_120000.gibram"))
+
+	for _, keep := range []int{0, 2, 5} {
+		removed, err := PruneSnapshots(dir, keep)
+		if err != nil {
+			t.Fatalf("PruneSnapshots(%d) error: %v", keep, err)
+		}
+		if len(removed) != 0 {
+			t.Errorf("PruneSnapshots(%d) removed %v, want nothing", keep, removed)
+		}
+	}
+}
+
+func TestSnapshotScheduler_FiresAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	var calls atomic.Int32
+	fired := make(chan struct{}, 16)
+
+	sched := NewSnapshotScheduler(10*time.Millisecond, dir, 1, func() error {
+		n := calls.Add(1)
+		// Distinct timestamps without waiting a second per snapshot
+		name := "gibram_20250101_0000" + itoa(int(n)+10) + ".gibram"
+		touch(t, filepath.Join(dir, name))
+		fired <- struct{}{}
+		return nil
+	})
+	sched.Start()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-fired:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("scheduler fired %d times, want 3", calls.Load())
+		}
+	}
+	sched.Stop()
+
+	after := calls.Load()
+	time.Sleep(30 * time.Millisecond)
+	if calls.Load() != after {
+		t.Error("scheduler fired after Stop")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.gibram"))
+	if len(files) != 1 {
+		t.Errorf("%d snapshots kept, want 1: %v", len(files), files)
+	}
+}
@@ -2,17 +2,24 @@
 package backup
 
 import (
+	"bufio"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
 )
 
+// ErrBadChecksum is returned for a WAL record whose checksum does not match
+// its contents
+var ErrBadChecksum = errors.New("WAL record checksum mismatch")
+
 // WAL (Write-Ahead Log) provides durability through logging
 type WAL struct {
 	dir  string
@@ -27,8 +34,15 @@ type WAL struct {
 	// Configuration
 	maxSegmentSize int64
 	syncMode       SyncMode
+
+	// Background syncer for SyncPeriodic
+	stopSync chan struct{}
+	syncDone chan struct{}
 }
 
+// DefaultSyncInterval is how often SyncPeriodic flushes the WAL to disk
+const DefaultSyncInterval = time.Second
+
 // SyncMode defines when to sync WAL to disk
 type SyncMode int
 
@@ -43,6 +57,32 @@ const (
 	SyncNever
 )
 
+// String returns the config name of the sync mode
+func (m SyncMode) String() string {
+	switch m {
+	case SyncEveryWrite:
+		return "always"
+	case SyncPeriodic:
+		return "periodic"
+	case SyncNever:
+		return "never"
+	}
+	return fmt.Sprintf("SyncMode(%d)", int(m))
+}
+
+// ParseSyncMode parses a config sync mode: always, periodic (default) or never
+func ParseSyncMode(name string) (SyncMode, error) {
+	switch name {
+	case "always":
+		return SyncEveryWrite, nil
+	case "", "periodic":
+		return SyncPeriodic, nil
+	case "never":
+		return SyncNever, nil
+	}
+	return 0, fmt.Errorf("unknown WAL sync mode %q (want always, periodic or never)", name)
+}
+
 // WALEntry represents a single WAL entry
 type WALEntry struct {
 	LSN       uint64
@@ -75,14 +115,83 @@ func NewWAL(dir string, syncMode SyncMode) (*WAL, error) {
 		syncMode:       syncMode,
 	}
 
-	// Open or create current segment
-	if err := w.openSegment(0); err != nil {
+	// Resume after any existing segments so LSNs stay monotonic across restarts
+	segment, err := w.recover()
+	if err != nil {
+		return nil, err
+	}
+	if err := w.openSegment(segment); err != nil {
 		return nil, err
 	}
 
+	if syncMode == SyncPeriodic {
+		w.stopSync = make(chan struct{})
+		w.syncDone = make(chan struct{})
+		go w.syncLoop(DefaultSyncInterval)
+	}
+
 	return w, nil
 }
 
+// syncLoop flushes appended entries every interval until Close
+func (w *WAL) syncLoop(interval time.Duration) {
+	defer close(w.syncDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopSync:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.currentLSN > w.flushedLSN {
+				if err := w.file.Sync(); err == nil {
+					w.flushedLSN = w.currentLSN
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// recover restores currentLSN from existing segments and returns the segment
+// to append to. A non-empty last segment is never reopened, so a record torn
+// by a crash always stays at the end of its file.
+func (w *WAL) recover() (int, error) {
+	files, err := filepath.Glob(filepath.Join(w.dir, "wal_*.log"))
+	if err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, nil
+	}
+	sort.Strings(files)
+
+	last := files[len(files)-1]
+	var num int
+	if _, err := fmt.Sscanf(filepath.Base(last), "wal_%08d.log", &num); err != nil {
+		return 0, fmt.Errorf("parse WAL segment name %s: %w", last, err)
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		entries, _, err := readEntriesFromFile(files[i], 0)
+		if err != nil {
+			return 0, fmt.Errorf("read WAL segment %s: %w", files[i], err)
+		}
+		if len(entries) > 0 {
+			w.currentLSN = entries[len(entries)-1].LSN
+			break
+		}
+	}
+	w.flushedLSN = w.currentLSN
+
+	if info, err := os.Stat(last); err == nil && info.Size() == 0 {
+		return num, nil
+	}
+	return num + 1, nil
+}
+
 func (w *WAL) openSegment(num int) error {
 	path := filepath.Join(w.dir, fmt.Sprintf("wal_%08d.log", num))
 	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
@@ -116,7 +225,7 @@ func (w *WAL) Append(entryType EntryType, key string, data []byte) (uint64, erro
 	}
 
 	// Calculate checksum
-	entry.Checksum = w.calculateChecksum(entry)
+	entry.Checksum = calculateChecksum(entry)
 
 	// Write entry
 	if err := w.writeEntry(entry); err != nil {
@@ -177,7 +286,7 @@ func (w *WAL) writeEntry(entry *WALEntry) error {
 	return err
 }
 
-func (w *WAL) calculateChecksum(entry *WALEntry) uint64 {
+func calculateChecksum(entry *WALEntry) uint64 {
 	h := xxhash.New()
 	if err := binary.Write(h, binary.BigEndian, entry.LSN); err != nil {
 		return 0
@@ -221,6 +330,12 @@ func (w *WAL) GetCurrentLSN() uint64 {
 
 // Close closes the WAL
 func (w *WAL) Close() error {
+	if w.stopSync != nil {
+		close(w.stopSync)
+		<-w.syncDone
+		w.stopSync = nil
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
@@ -298,7 +413,7 @@ func (w *WAL) TruncateBefore(targetLSN uint64) error {
 		}
 
 		// Check if all entries in this file are below target LSN
-		entries, err := readEntriesFromFile(path, 0)
+		entries, _, err := readEntriesFromFile(path, 0)
 		if err != nil {
 			continue
 		}
@@ -341,27 +456,40 @@ func (w *WAL) Rotate() error {
 
 // ReadEntries reads all entries from WAL directory
 func ReadEntries(dir string, fromLSN uint64) ([]*WALEntry, error) {
+	entries, _, err := readEntries(dir, fromLSN)
+	return entries, err
+}
+
+// readEntries reads all entries from the WAL directory and also returns the
+// segments that ended in a torn record
+func readEntries(dir string, fromLSN uint64) (entries []*WALEntry, torn []string, err error) {
 	files, err := filepath.Glob(filepath.Join(dir, "wal_*.log"))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	var entries []*WALEntry
 	for _, path := range files {
-		fileEntries, err := readEntriesFromFile(path, fromLSN)
+		fileEntries, fileTorn, err := readEntriesFromFile(path, fromLSN)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		entries = append(entries, fileEntries...)
+		if fileTorn {
+			torn = append(torn, path)
+		}
 	}
 
-	return entries, nil
+	return entries, torn, nil
 }
 
-func readEntriesFromFile(path string, fromLSN uint64) (entries []*WALEntry, retErr error) {
+// readEntriesFromFile reads a segment up to its end or its first torn
+// record. A record cut short or failing its checksum is a write torn by a
+// crash: it was never acknowledged, and since a non-empty segment is never
+// reopened nothing was written after it.
+func readEntriesFromFile(path string, fromLSN uint64) (entries []*WALEntry, torn bool, retErr error) {
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer func() {
 		if err := f.Close(); err != nil && retErr == nil {
@@ -369,13 +497,18 @@ func readEntriesFromFile(path string, fromLSN uint64) (entries []*WALEntry, retE
 		}
 	}()
 
+	r := bufio.NewReader(f)
 	for {
-		entry, err := readEntry(f)
+		entry, err := readEntry(r)
 		if err == io.EOF {
 			break
 		}
+		if err == io.ErrUnexpectedEOF || errors.Is(err, ErrBadChecksum) {
+			torn = true
+			break
+		}
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		if entry.LSN >= fromLSN {
@@ -383,7 +516,7 @@ func readEntriesFromFile(path string, fromLSN uint64) (entries []*WALEntry, retE
 		}
 	}
 
-	return entries, nil
+	return entries, torn, nil
 }
 
 func readEntry(r io.Reader) (*WALEntry, error) {
@@ -402,28 +535,40 @@ func readEntry(r io.Reader) (*WALEntry, error) {
 	// Read key
 	var keyLen uint32
 	if err := binary.Read(r, binary.BigEndian, &keyLen); err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 	keyBytes := make([]byte, keyLen)
 	if _, err := io.ReadFull(r, keyBytes); err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 	entry.Key = string(keyBytes)
 
 	// Read data
 	var dataLen uint32
 	if err := binary.Read(r, binary.BigEndian, &dataLen); err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 	entry.Data = make([]byte, dataLen)
 	if _, err := io.ReadFull(r, entry.Data); err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 
 	// Read checksum
 	if err := binary.Read(r, binary.BigEndian, &entry.Checksum); err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
+	}
+	if entry.Checksum != calculateChecksum(entry) {
+		return nil, fmt.Errorf("LSN %d: %w", entry.LSN, ErrBadChecksum)
 	}
 
 	return entry, nil
 }
+
+// unexpectedEOF reports running out of data inside a record as
+// io.ErrUnexpectedEOF, even when it happens between two fields
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,318 @@
+// Package client - client-side load balancing across multiple servers
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/gibram-io/gibram/proto/gibrampb"
+)
+
+// =============================================================================
+// Load Balancing
+// =============================================================================
+
+// Load balancing strategies for PoolConfig.LoadBalance
+const (
+	LoadBalanceRoundRobin = "round_robin" // rotate across healthy servers (default)
+	LoadBalanceLeastConn  = "least_conn"  // pick the server with fewest in-flight requests
+)
+
+// DefaultFailoverCooldown is how long a failed server is skipped before retry
+const DefaultFailoverCooldown = 5 * time.Second
+
+// DefaultHealthCheckInterval is how often failed servers are probed
+const DefaultHealthCheckInterval = time.Second
+
+// ServerStats reports per-server balancing state
+type ServerStats struct {
+	Addr      string
+	Healthy   bool
+	Primary   bool
+	InFlight  int
+	Active    int
+	Available int
+}
+
+// backend is one server with its own connection pool and health state
+type backend struct {
+	addr   string
+	config PoolConfig
+
+	mu   sync.Mutex
+	pool *ConnPool // nil until the first successful connect
+
+	healthy   atomic.Bool
+	downSince atomic.Int64 // unix nanos of the last failure
+	inFlight  atomic.Int32
+}
+
+// connect returns the server's pool, dialing it on first use
+func (b *backend) connect() (*ConnPool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.pool != nil {
+		return b.pool, nil
+	}
+
+	pool, err := NewConnPool(b.addr, b.config)
+	if err != nil {
+		b.markDown()
+		return nil, err
+	}
+	b.pool = pool
+	b.markUp()
+	return pool, nil
+}
+
+func (b *backend) markDown() {
+	b.healthy.Store(false)
+	b.downSince.Store(time.Now().UnixNano())
+}
+
+func (b *backend) markUp() {
+	b.healthy.Store(true)
+}
+
+// usable reports whether the server is healthy or its failover cooldown has elapsed
+func (b *backend) usable(cooldown time.Duration) bool {
+	if b.healthy.Load() {
+		return true
+	}
+	return time.Since(time.Unix(0, b.downSince.Load())) >= cooldown
+}
+
+// probe checks a server with PING, dialing it if needed, and marks it up on
+// success
+func (b *backend) probe() {
+	pool, err := b.connect()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.ConnTimeout)
+	defer cancel()
+
+	pc, err := pool.getConn(ctx)
+	if err != nil {
+		return
+	}
+	if err := pingConn(ctx, pc, b.config.ConnTimeout); err != nil {
+		pool.closeConn(pc)
+		b.markDown()
+		return
+	}
+	pool.putConn(pc)
+	b.markUp()
+}
+
+// pingConn sends PING on pc and waits for PONG
+func pingConn(ctx context.Context, pc *pooledConn, timeout time.Duration) error {
+	if err := pc.conn.SetDeadline(deadline(ctx, timeout)); err != nil {
+		return err
+	}
+	env := &pb.Envelope{
+		Version:   ProtocolVersion,
+		RequestId: pc.requestID.Add(1),
+		CmdType:   pb.CommandType_CMD_PING,
+	}
+	if err := writeEnvelope(pc.conn, env, "", 0); err != nil {
+		return err
+	}
+	resp, err := readEnvelope(pc.reader)
+	if err != nil {
+		return err
+	}
+	if resp.CmdType != pb.CommandType_CMD_PONG {
+		return fmt.Errorf("unexpected response: %v", resp.CmdType)
+	}
+	return pc.conn.SetDeadline(time.Time{})
+}
+
+func (b *backend) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.pool != nil {
+		b.pool.Close()
+	}
+}
+
+// balancer distributes requests across backends
+type balancer struct {
+	backends []*backend
+	primary  *backend // receives writes when set
+	strategy string
+	cooldown time.Duration
+	next     atomic.Uint64
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// parseAddrs splits a comma-separated address list, dropping blanks and duplicates
+func parseAddrs(addr string) []string {
+	var addrs []string
+	seen := make(map[string]bool)
+	for _, a := range strings.Split(addr, ",") {
+		a = strings.TrimSpace(a)
+		if a == "" || seen[a] {
+			continue
+		}
+		seen[a] = true
+		addrs = append(addrs, a)
+	}
+	return addrs
+}
+
+// newBalancer connects to every server and succeeds if at least one is reachable
+func newBalancer(addrs []string, config PoolConfig) (*balancer, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no server address given")
+	}
+
+	strategy := config.LoadBalance
+	switch strategy {
+	case "":
+		strategy = LoadBalanceRoundRobin
+	case LoadBalanceRoundRobin, LoadBalanceLeastConn:
+	default:
+		return nil, fmt.Errorf("unknown load balance strategy: %s", strategy)
+	}
+
+	cooldown := config.FailoverCooldown
+	if cooldown <= 0 {
+		cooldown = DefaultFailoverCooldown
+	}
+
+	lb := &balancer{strategy: strategy, cooldown: cooldown, stopCh: make(chan struct{})}
+	var lastErr error
+	connected := 0
+	for _, addr := range addrs {
+		b := &backend{addr: addr, config: config}
+		if _, err := b.connect(); err != nil {
+			lastErr = err
+		} else {
+			connected++
+		}
+		lb.backends = append(lb.backends, b)
+		if addr == config.Primary {
+			lb.primary = b
+		}
+	}
+
+	if config.Primary != "" && lb.primary == nil {
+		lb.close()
+		return nil, fmt.Errorf("primary %s is not in the server list", config.Primary)
+	}
+	if connected == 0 {
+		lb.close()
+		return nil, lastErr
+	}
+
+	if len(lb.backends) > 1 {
+		interval := config.HealthCheckInterval
+		if interval <= 0 {
+			interval = DefaultHealthCheckInterval
+		}
+		lb.wg.Add(1)
+		go lb.healthCheckLoop(interval)
+	}
+	return lb, nil
+}
+
+// healthCheckLoop probes failed servers so they rejoin as soon as they
+// answer PING rather than when a request happens to try them
+func (lb *balancer) healthCheckLoop(interval time.Duration) {
+	defer lb.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lb.stopCh:
+			return
+		case <-ticker.C:
+			for _, b := range lb.backends {
+				if !b.healthy.Load() {
+					b.probe()
+				}
+			}
+		}
+	}
+}
+
+// pickFor selects the backend for cmd: the primary for writes when one is
+// configured, otherwise a balanced pick
+func (lb *balancer) pickFor(cmd pb.CommandType, exclude *backend) *backend {
+	if lb.primary != nil && !idempotentCommands[cmd] {
+		return lb.primary
+	}
+	return lb.pick(exclude)
+}
+
+// pick selects a backend, avoiding exclude (the server that just failed) when
+// another usable one exists. If every server is cooling down, all are
+// candidates so requests are never refused outright.
+func (lb *balancer) pick(exclude *backend) *backend {
+	candidates := make([]*backend, 0, len(lb.backends))
+	for _, b := range lb.backends {
+		if b != exclude && b.usable(lb.cooldown) {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, b := range lb.backends {
+			if b != exclude {
+				candidates = append(candidates, b)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = lb.backends
+	}
+
+	if lb.strategy == LoadBalanceLeastConn {
+		best := candidates[0]
+		for _, b := range candidates[1:] {
+			if b.inFlight.Load() < best.inFlight.Load() {
+				best = b
+			}
+		}
+		return best
+	}
+
+	idx := lb.next.Add(1) - 1
+	return candidates[idx%uint64(len(candidates))]
+}
+
+func (lb *balancer) close() {
+	lb.stopOnce.Do(func() { close(lb.stopCh) })
+	lb.wg.Wait()
+	for _, b := range lb.backends {
+		b.close()
+	}
+}
+
+func (lb *balancer) stats() []ServerStats {
+	stats := make([]ServerStats, len(lb.backends))
+	for i, b := range lb.backends {
+		stats[i] = ServerStats{
+			Addr:     b.addr,
+			Healthy:  b.healthy.Load(),
+			Primary:  b == lb.primary,
+			InFlight: int(b.inFlight.Load()),
+		}
+		b.mu.Lock()
+		if b.pool != nil {
+			stats[i].Active, stats[i].Available = b.pool.Stats()
+		}
+		b.mu.Unlock()
+	}
+	return stats
+}
@@ -3,19 +3,25 @@ package client
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"net"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gibram-io/gibram/pkg/codec"
+	"github.com/gibram-io/gibram/pkg/embed"
+	"github.com/gibram-io/gibram/pkg/tracing"
 	"github.com/gibram-io/gibram/pkg/types"
 	pb "github.com/gibram-io/gibram/proto/gibrampb"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -46,6 +52,8 @@ var (
 	ErrForbidden     = errors.New("forbidden")
 	ErrRateLimited   = errors.New("rate limited")
 	ErrNotFound      = errors.New("not found")
+	ErrServerError   = errors.New("server error")
+	ErrNoEmbedder    = errors.New("no embedder configured: set PoolConfig.Embedder, or pass the embedding yourself")
 )
 
 // PoolConfig configures the connection pool
@@ -53,7 +61,10 @@ type PoolConfig struct {
 	MaxConnections int           // Max connections in pool (default: 20)
 	ConnTimeout    time.Duration // Dial timeout (default: 5s)
 	IdleTimeout    time.Duration // Idle connection timeout (default: 60s)
-	MaxRetries     int           // Max retries on connection failure (default: 3)
+	MaxRetries     int           // Deprecated: use Retry.MaxRetries (default: 3)
+
+	// Retry policy for requests that fail with connection errors
+	Retry RetryConfig
 
 	// TLS settings
 	TLSEnabled    bool // Enable TLS
@@ -61,6 +72,20 @@ type PoolConfig struct {
 
 	// Auth settings
 	APIKey string // API key for authentication
+
+	// Compression asks the server to compress large frames in both
+	// directions with zstd, or gzip on servers without it, negotiated when
+	// each connection is opened
+	Compression bool
+
+	// Load balancing (used when the client address lists several servers)
+	LoadBalance         string        // round_robin (default) or least_conn
+	FailoverCooldown    time.Duration // How long a failed server is skipped (default: 5s)
+	HealthCheckInterval time.Duration // How often failed servers are probed with PING (default: 1s)
+	Primary             string        // Server that receives all writes (default: none, writes are balanced too)
+
+	// Embedder turns query text into a vector for QueryText (default: none)
+	Embedder embed.Embedder
 }
 
 // DefaultPoolConfig returns default pool configuration
@@ -70,6 +95,7 @@ func DefaultPoolConfig() PoolConfig {
 		ConnTimeout:    DefaultConnTimeout,
 		IdleTimeout:    DefaultIdleTimeout,
 		MaxRetries:     DefaultMaxRetries,
+		Retry:          DefaultRetryConfig(),
 	}
 }
 
@@ -80,6 +106,8 @@ type pooledConn struct {
 	lastUsed      atomic.Int64
 	inUse         atomic.Bool
 	authenticated bool
+	compressAbove int    // request size above which frames are compressed (0 = never)
+	compression   string // negotiated frame compression
 	requestID     atomic.Uint64
 }
 
@@ -95,8 +123,8 @@ type ConnPool struct {
 	availableCount int32 // atomic
 }
 
-// NewConnPool creates a new connection pool
-func NewConnPool(addr string, config PoolConfig) (*ConnPool, error) {
+// withDefaults fills zero-valued settings with their defaults
+func (config PoolConfig) withDefaults() PoolConfig {
 	if config.MaxConnections <= 0 {
 		config.MaxConnections = DefaultPoolSize
 	}
@@ -109,6 +137,13 @@ func NewConnPool(addr string, config PoolConfig) (*ConnPool, error) {
 	if config.MaxRetries <= 0 {
 		config.MaxRetries = DefaultMaxRetries
 	}
+	config.Retry = config.Retry.withDefaults(config.MaxRetries)
+	return config
+}
+
+// NewConnPool creates a new connection pool
+func NewConnPool(addr string, config PoolConfig) (*ConnPool, error) {
+	config = config.withDefaults()
 
 	pool := &ConnPool{
 		addr:        addr,
@@ -156,15 +191,16 @@ func (p *ConnPool) createConn() (*pooledConn, error) {
 	pc.lastUsed.Store(time.Now().UnixNano())
 	pc.inUse.Store(true)
 
-	// Authenticate if API key is provided
-	if p.config.APIKey != "" {
+	// Authenticate if API key is provided; the same handshake negotiates
+	// compression
+	if p.config.APIKey != "" || p.config.Compression {
 		if err := p.authenticateConn(pc); err != nil {
 			if closeErr := conn.Close(); closeErr != nil {
 				return nil, fmt.Errorf("authentication failed: %v (close failed: %v)", err, closeErr)
 			}
 			return nil, fmt.Errorf("authentication failed: %w", err)
 		}
-		pc.authenticated = true
+		pc.authenticated = p.config.APIKey != ""
 	}
 
 	p.mu.Lock()
@@ -179,6 +215,9 @@ func (p *ConnPool) createConn() (*pooledConn, error) {
 func (p *ConnPool) authenticateConn(pc *pooledConn) error {
 	// Build auth request
 	authReq := &pb.AuthRequest{ApiKey: p.config.APIKey}
+	if p.config.Compression {
+		authReq.Compression = []string{codec.CompressionZstd, codec.CompressionGzip}
+	}
 	payload, _ := proto.Marshal(authReq)
 
 	env := &pb.Envelope{
@@ -189,7 +228,7 @@ func (p *ConnPool) authenticateConn(pc *pooledConn) error {
 	}
 
 	// Send
-	if err := writeEnvelope(pc.conn, env); err != nil {
+	if err := writeEnvelope(pc.conn, env, "", 0); err != nil {
 		return err
 	}
 
@@ -199,6 +238,12 @@ func (p *ConnPool) authenticateConn(pc *pooledConn) error {
 		return err
 	}
 
+	// Servers without authentication that predate the handshake reject it;
+	// carry on uncompressed
+	if respEnv.CmdType == pb.CommandType_CMD_ERROR && p.config.APIKey == "" {
+		return nil
+	}
+
 	if respEnv.CmdType == pb.CommandType_CMD_ERROR {
 		var errResp pb.Error
 		if err := proto.Unmarshal(respEnv.Payload, &errResp); err != nil {
@@ -220,6 +265,10 @@ func (p *ConnPool) authenticateConn(pc *pooledConn) error {
 		return fmt.Errorf("auth failed: %s", authResp.Message)
 	}
 
+	if name := codec.NegotiateCompression([]string{authResp.Compression}); name != "" {
+		pc.compressAbove = codec.DefaultCompressThreshold
+		pc.compression = name
+	}
 	return nil
 }
 
@@ -231,8 +280,9 @@ func decodeErrorPayload(payload []byte) (string, error) {
 	return errResp.Message, nil
 }
 
-// getConn gets a connection from the pool
-func (p *ConnPool) getConn() (*pooledConn, error) {
+// getConn gets a connection from the pool, waiting for a free one until the
+// pool's ConnTimeout or ctx is done
+func (p *ConnPool) getConn(ctx context.Context) (*pooledConn, error) {
 	if atomic.LoadInt32(&p.closed) == 1 {
 		return nil, ErrPoolClosed
 	}
@@ -273,6 +323,8 @@ func (p *ConnPool) getConn() (*pooledConn, error) {
 		}
 	case <-time.After(p.config.ConnTimeout):
 		return nil, ErrPoolExhausted
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 
 	return nil, ErrPoolExhausted
@@ -392,18 +444,12 @@ func (p *ConnPool) Stats() (active, available int) {
 // Wire Protocol Helpers
 // =============================================================================
 
-func writeEnvelope(w io.Writer, env *pb.Envelope) error {
-	data, err := proto.Marshal(env)
+func writeEnvelope(w io.Writer, env *pb.Envelope, compression string, compressAbove int) error {
+	frame, err := codec.EncodeEnvelopeCompressed(env, compression, compressAbove)
 	if err != nil {
 		return err
 	}
 
-	// Frame: [1 byte codec][4 bytes length][payload]
-	frame := make([]byte, 1+4+len(data))
-	frame[0] = byte(codec.CodecProtobuf)
-	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
-	copy(frame[5:], data)
-
 	_, err = w.Write(frame)
 	return err
 }
@@ -415,7 +461,8 @@ func readEnvelope(r *bufio.Reader) (*pb.Envelope, error) {
 		return nil, err
 	}
 
-	if codec.CodecType(codecByte) != codec.CodecProtobuf {
+	codecType := codec.CodecType(codecByte)
+	if codecType != codec.CodecProtobuf && !codec.CompressedCodec(codecType) {
 		return nil, fmt.Errorf("unsupported codec: %d", codecByte)
 	}
 
@@ -436,12 +483,7 @@ func readEnvelope(r *bufio.Reader) (*pb.Envelope, error) {
 	}
 
 	// Decode envelope
-	var env pb.Envelope
-	if err := proto.Unmarshal(payload, &env); err != nil {
-		return nil, err
-	}
-
-	return &env, nil
+	return codec.UnmarshalEnvelope(codecType, payload, MaxFrameSize)
 }
 
 // =============================================================================
@@ -449,7 +491,8 @@ func readEnvelope(r *bufio.Reader) (*pb.Envelope, error) {
 // =============================================================================
 
 type Client struct {
-	pool      *ConnPool
+	lb        *balancer
+	config    PoolConfig
 	sessionID string // Required session ID for all operations
 }
 
@@ -461,55 +504,153 @@ func NewClient(addr, sessionID string) (*Client, error) {
 
 // NewClientWithConfig creates a new client with custom pool config
 // sessionID is required for all operations (like database selection)
+// addr may be a comma-separated list of servers ("host1:6161,host2:6161");
+// requests are then balanced per config.LoadBalance, each server gets its own
+// connection pool, and failed servers are skipped until FailoverCooldown passes.
 func NewClientWithConfig(addr, sessionID string, config PoolConfig) (*Client, error) {
 	if sessionID == "" {
 		return nil, errors.New("session_id is required")
 	}
 
-	pool, err := NewConnPool(addr, config)
+	config = config.withDefaults()
+	lb, err := newBalancer(parseAddrs(addr), config)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{pool: pool, sessionID: sessionID}, nil
+	return &Client{lb: lb, config: config, sessionID: sessionID}, nil
+}
+
+// NewClusterClient creates a client for a set of replicas. Each server gets
+// its own pool; reads are balanced per config.LoadBalance and fail over on
+// connection errors, failed servers are re-probed every HealthCheckInterval,
+// and writes go to config.Primary when it is set.
+func NewClusterClient(addrs []string, sessionID string, config PoolConfig) (*Client, error) {
+	return NewClientWithConfig(strings.Join(addrs, ","), sessionID, config)
 }
 
 func (c *Client) Close() error {
-	c.pool.Close()
+	c.lb.close()
 	return nil
 }
 
-// PoolStats returns connection pool statistics
+// PoolStats returns connection pool statistics summed across servers
 func (c *Client) PoolStats() (active, available int) {
-	return c.pool.Stats()
+	for _, st := range c.lb.stats() {
+		active += st.Active
+		available += st.Available
+	}
+	return active, available
+}
+
+// Stats returns per-server health and pool statistics
+func (c *Client) Stats() []ServerStats {
+	return c.lb.stats()
 }
 
 // send sends a command and returns the response
-func (c *Client) send(cmdType pb.CommandType, payload proto.Message) (*pb.Envelope, error) {
+// Connection failures mark the server unhealthy and the retry goes to another
+// server after a backoff. Server-side error responses do not affect health
+// and are returned without a retry: the server answered, and resending could
+// repeat a write it applied or add load it is shedding. A request that may
+// have reached the server is only resent after a connection error if it is
+// idempotent or ctx allows it (see RetryConfig). When ctx is done the call returns ctx.Err() without
+// further retries. The call is traced as a client span whose context the
+// server continues.
+func (c *Client) send(ctx context.Context, cmdType pb.CommandType, payload proto.Message) (*pb.Envelope, error) {
+	ctx, span := tracing.StartCommand(ctx, cmdType, trace.SpanKindClient)
+	resp, err := c.sendWithRetry(ctx, cmdType, payload)
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (c *Client) sendWithRetry(ctx context.Context, cmdType pb.CommandType, payload proto.Message) (*pb.Envelope, error) {
 	var lastErr error
+	var failed *backend
+	connFailures := 0
+
+	for attempt := 0; attempt <= c.config.Retry.MaxRetries; attempt++ {
+		if lastErr != nil {
+			if err := sleep(ctx, c.config.Retry.backoff(connFailures)); err != nil {
+				return nil, err
+			}
+			connFailures++
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		b := c.lb.pickFor(cmdType, failed)
+		pool, err := b.connect()
+		if err != nil {
+			lastErr = err
+			failed = b
+			continue
+		}
 
-	for retry := 0; retry < c.pool.config.MaxRetries; retry++ {
-		pc, err := c.pool.getConn()
+		pc, err := pool.getConn(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if !errors.Is(err, ErrPoolExhausted) {
+				b.markDown()
+			}
 			lastErr = err
+			failed = b
 			continue
 		}
 
-		resp, err := c.doSend(pc, cmdType, payload)
+		b.inFlight.Add(1)
+		stop := abortOnDone(ctx, pc)
+		resp, err := c.doSend(ctx, pc, cmdType, payload)
+		stop()
+		b.inFlight.Add(-1)
+		if errors.Is(err, ErrServerError) {
+			pool.putConn(pc)
+			b.markUp()
+			return nil, err
+		}
 		if err != nil {
-			c.pool.closeConn(pc)
+			pool.closeConn(pc)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			b.markDown()
+			if !resendable(ctx, cmdType) {
+				return nil, err
+			}
 			lastErr = err
+			failed = b
 			continue
 		}
 
-		c.pool.putConn(pc)
+		pool.putConn(pc)
+		b.markUp()
 		return resp, nil
 	}
 
-	return nil, fmt.Errorf("after %d retries: %w", c.pool.config.MaxRetries, lastErr)
+	return nil, fmt.Errorf("after %d retries: %w", c.config.Retry.MaxRetries, lastErr)
+}
+
+// abortOnDone unblocks I/O on pc once ctx is done by moving its deadline to
+// now. Every request sets fresh deadlines, so a late call is harmless.
+func abortOnDone(ctx context.Context, pc *pooledConn) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		_ = pc.conn.SetDeadline(time.Now())
+	})
+}
+
+// deadline returns now+timeout, or the ctx deadline if that is sooner
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	d := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(d) {
+		return ctxDeadline
+	}
+	return d
 }
 
-func (c *Client) doSend(pc *pooledConn, cmdType pb.CommandType, payload proto.Message) (*pb.Envelope, error) {
+func (c *Client) doSend(ctx context.Context, pc *pooledConn, cmdType pb.CommandType, payload proto.Message) (*pb.Envelope, error) {
 	var payloadBytes []byte
 	if payload != nil {
 		var err error
@@ -520,24 +661,30 @@ func (c *Client) doSend(pc *pooledConn, cmdType pb.CommandType, payload proto.Me
 	}
 
 	env := &pb.Envelope{
-		Version:   ProtocolVersion,
-		RequestId: pc.requestID.Add(1),
-		CmdType:   cmdType,
-		Payload:   payloadBytes,
-		SessionId: c.sessionID,
+		Version:     ProtocolVersion,
+		RequestId:   pc.requestID.Add(1),
+		CmdType:     cmdType,
+		Payload:     payloadBytes,
+		SessionId:   c.sessionID,
+		Traceparent: tracing.Inject(ctx),
 	}
 
 	// Set write deadline
-	if err := pc.conn.SetWriteDeadline(time.Now().Add(c.pool.config.ConnTimeout)); err != nil {
+	if err := pc.conn.SetWriteDeadline(deadline(ctx, c.config.ConnTimeout)); err != nil {
 		return nil, err
 	}
 
-	if err := writeEnvelope(pc.conn, env); err != nil {
+	if err := writeEnvelope(pc.conn, env, pc.compression, pc.compressAbove); err != nil {
 		return nil, err
 	}
 
+	return c.readResponse(ctx, pc)
+}
+
+// readResponse reads the next envelope on pc, converting CMD_ERROR to an error
+func (c *Client) readResponse(ctx context.Context, pc *pooledConn) (*pb.Envelope, error) {
 	// Set read deadline
-	if err := pc.conn.SetReadDeadline(time.Now().Add(c.pool.config.ConnTimeout * 2)); err != nil {
+	if err := pc.conn.SetReadDeadline(deadline(ctx, c.config.ConnTimeout*2)); err != nil {
 		return nil, err
 	}
 
@@ -557,7 +704,7 @@ func (c *Client) doSend(pc *pooledConn, cmdType pb.CommandType, payload proto.Me
 		if err != nil {
 			return nil, fmt.Errorf("server error decode failed: %w", err)
 		}
-		return nil, fmt.Errorf("server error: %s", msg)
+		return nil, fmt.Errorf("%w: %s", ErrServerError, msg)
 	}
 
 	return resp, nil
@@ -568,7 +715,12 @@ func (c *Client) doSend(pc *pooledConn, cmdType pb.CommandType, payload proto.Me
 // =============================================================================
 
 func (c *Client) Ping() error {
-	resp, err := c.send(pb.CommandType_CMD_PING, nil)
+	return c.PingContext(context.Background())
+}
+
+// PingContext is like Ping but honors ctx cancellation and deadline
+func (c *Client) PingContext(ctx context.Context) error {
+	resp, err := c.send(ctx, pb.CommandType_CMD_PING, nil)
 	if err != nil {
 		return err
 	}
@@ -584,14 +736,38 @@ func (c *Client) Ping() error {
 
 // ListSessions returns all active sessions on the server
 func (c *Client) ListSessions() ([]types.SessionInfo, error) {
-	resp, err := c.send(pb.CommandType_CMD_LIST_SESSIONS, nil)
+	return c.ListSessionsContext(context.Background())
+}
+
+// ListSessionsContext is like ListSessions but honors ctx cancellation and deadline
+func (c *Client) ListSessionsContext(ctx context.Context) ([]types.SessionInfo, error) {
+	sessions, _, err := c.ListSessionsPageContext(ctx, types.SessionFilter{}, "", 0)
+	return sessions, err
+}
+
+// ListSessionsPage returns up to limit (0 = all) active sessions matching
+// filter with IDs after cursor, in ID order, and the cursor of the next page
+// ("" = no more). Filter.Visible is not sent to the server.
+func (c *Client) ListSessionsPage(filter types.SessionFilter, cursor string, limit int) ([]types.SessionInfo, string, error) {
+	return c.ListSessionsPageContext(context.Background(), filter, cursor, limit)
+}
+
+// ListSessionsPageContext is like ListSessionsPage but honors ctx cancellation and deadline
+func (c *Client) ListSessionsPageContext(ctx context.Context, filter types.SessionFilter, cursor string, limit int) ([]types.SessionInfo, string, error) {
+	req := &pb.ListSessionsRequest{
+		Prefix:       filter.Prefix,
+		CreatedAfter: filter.CreatedAfter,
+		Limit:        int32(limit),
+		Cursor:       cursor,
+	}
+	resp, err := c.send(ctx, pb.CommandType_CMD_LIST_SESSIONS, req)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var listResp pb.ListSessionsResponse
 	if err := proto.Unmarshal(resp.Payload, &listResp); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	sessions := make([]types.SessionInfo, len(listResp.Sessions))
@@ -607,45 +783,158 @@ func (c *Client) ListSessions() ([]types.SessionInfo, error) {
 			EntityCount:       int(s.EntityCount),
 			RelationshipCount: int(s.RelationshipCount),
 			CommunityCount:    int(s.CommunityCount),
+			CommunitiesDirty:  s.CommunitiesDirty,
+			MemoryBytes:       s.MemoryBytes,
+			Metadata:          s.Metadata,
 		}
 	}
 
-	return sessions, nil
+	return sessions, listResp.NextCursor, nil
 }
 
 // DeleteSession deletes a specific session (requires admin permission)
 func (c *Client) DeleteSession(sessionID string) error {
+	return c.DeleteSessionContext(context.Background(), sessionID)
+}
+
+// DeleteSessionContext is like DeleteSession but honors ctx cancellation and deadline
+func (c *Client) DeleteSessionContext(ctx context.Context, sessionID string) error {
 	// Override client's sessionID temporarily for this admin operation
 	oldSessionID := c.sessionID
 	c.sessionID = sessionID
 	defer func() { c.sessionID = oldSessionID }()
 
-	_, err := c.send(pb.CommandType_CMD_DELETE_SESSION, nil)
+	_, err := c.send(ctx, pb.CommandType_CMD_DELETE_SESSION, nil)
+	return err
+}
+
+// FlushSession removes all data from the current session, keeping the
+// session and its TTL and metadata (requires admin permission)
+func (c *Client) FlushSession() error {
+	return c.FlushSessionContext(context.Background())
+}
+
+// FlushSessionContext is like FlushSession but honors ctx cancellation and deadline
+func (c *Client) FlushSessionContext(ctx context.Context) error {
+	_, err := c.send(ctx, pb.CommandType_CMD_FLUSH_SESSION, nil)
 	return err
 }
 
 // SetSessionTTL sets TTL for current session
 func (c *Client) SetSessionTTL(ttl, idleTTL int64) error {
+	return c.SetSessionTTLContext(context.Background(), ttl, idleTTL)
+}
+
+// SetSessionTTLContext is like SetSessionTTL but honors ctx cancellation and deadline
+func (c *Client) SetSessionTTLContext(ctx context.Context, ttl, idleTTL int64) error {
 	req := &pb.SetSessionTTLRequest{
 		Ttl:     ttl,
 		IdleTtl: idleTTL,
 	}
-	_, err := c.send(pb.CommandType_CMD_SET_SESSION_TTL, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_SET_SESSION_TTL, req)
+	return err
+}
+
+// SetSessionMetadata merges key-value metadata into the current session.
+// An empty value removes the key; replace clears existing metadata first.
+func (c *Client) SetSessionMetadata(metadata map[string]string, replace bool) error {
+	return c.SetSessionMetadataContext(context.Background(), metadata, replace)
+}
+
+// SetSessionMetadataContext is like SetSessionMetadata but honors ctx cancellation and deadline
+func (c *Client) SetSessionMetadataContext(ctx context.Context, metadata map[string]string, replace bool) error {
+	req := &pb.SetSessionMetadataRequest{
+		Metadata: metadata,
+		Replace:  replace,
+	}
+	_, err := c.send(ctx, pb.CommandType_CMD_SET_SESSION_METADATA, req)
 	return err
 }
 
+// GetSessionMetadata returns the current session's key-value metadata
+func (c *Client) GetSessionMetadata() (map[string]string, error) {
+	return c.GetSessionMetadataContext(context.Background())
+}
+
+// GetSessionMetadataContext is like GetSessionMetadata but honors ctx cancellation and deadline
+func (c *Client) GetSessionMetadataContext(ctx context.Context) (map[string]string, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_SESSION_METADATA, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var mdResp pb.SessionMetadataResponse
+	if err := proto.Unmarshal(resp.Payload, &mdResp); err != nil {
+		return nil, err
+	}
+	return mdResp.Metadata, nil
+}
+
 // TouchSession updates last access time for current session
 func (c *Client) TouchSession() error {
-	_, err := c.send(pb.CommandType_CMD_TOUCH_SESSION, nil)
+	return c.TouchSessionContext(context.Background())
+}
+
+// TouchSessionContext is like TouchSession but honors ctx cancellation and deadline
+func (c *Client) TouchSessionContext(ctx context.Context) error {
+	_, err := c.send(ctx, pb.CommandType_CMD_TOUCH_SESSION, nil)
 	return err
 }
 
+// GraphDiff reports entity/relationship changes for the current session between
+// two snapshot files in the server's data directory (relative paths are taken
+// from there). An empty toPath diffs against the live session.
+func (c *Client) GraphDiff(fromPath, toPath string, cursor uint64, limit int) (*types.GraphDiff, error) {
+	return c.GraphDiffContext(context.Background(), fromPath, toPath, cursor, limit)
+}
+
+// GraphDiffContext is like GraphDiff but honors ctx cancellation and deadline
+func (c *Client) GraphDiffContext(ctx context.Context, fromPath, toPath string, cursor uint64, limit int) (*types.GraphDiff, error) {
+	req := &pb.GraphDiffRequest{
+		FromPath: fromPath,
+		ToPath:   toPath,
+		Cursor:   cursor,
+		Limit:    int32(limit),
+	}
+	resp, err := c.send(ctx, pb.CommandType_CMD_GRAPH_DIFF, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffResp pb.GraphDiffResponse
+	if err := proto.Unmarshal(resp.Payload, &diffResp); err != nil {
+		return nil, err
+	}
+
+	diff := &types.GraphDiff{
+		Changes:    make([]types.GraphChange, len(diffResp.Changes)),
+		Total:      int(diffResp.Total),
+		NextCursor: diffResp.NextCursor,
+	}
+	for i, ch := range diffResp.Changes {
+		change := types.GraphChange{Op: types.DiffOp(ch.Op)}
+		if ch.Entity != nil {
+			change.Entity = codec.ProtoToEntity(ch.Entity)
+		}
+		if ch.Relationship != nil {
+			change.Relationship = codec.ProtoToRelationship(ch.Relationship)
+		}
+		diff.Changes[i] = change
+	}
+	return diff, nil
+}
+
 // =============================================================================
 // Info & Health Commands
 // =============================================================================
 
 func (c *Client) Info() (*types.ServerInfo, error) {
-	resp, err := c.send(pb.CommandType_CMD_INFO, nil)
+	return c.InfoContext(context.Background())
+}
+
+// InfoContext is like Info but honors ctx cancellation and deadline
+func (c *Client) InfoContext(ctx context.Context) (*types.ServerInfo, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_INFO, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -663,6 +952,8 @@ func (c *Client) Info() (*types.ServerInfo, error) {
 		RelationshipCount: int(infoResp.RelationshipCount),
 		CommunityCount:    int(infoResp.CommunityCount),
 		VectorDim:         int(infoResp.VectorDim),
+		SessionCount:      int(infoResp.SessionCount),
+		DisabledCommands:  infoResp.DisabledCommands,
 	}, nil
 }
 
@@ -673,7 +964,12 @@ type HealthStatus struct {
 }
 
 func (c *Client) Health() (*HealthStatus, error) {
-	resp, err := c.send(pb.CommandType_CMD_HEALTH, nil)
+	return c.HealthContext(context.Background())
+}
+
+// HealthContext is like Health but honors ctx cancellation and deadline
+func (c *Client) HealthContext(ctx context.Context) (*HealthStatus, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_HEALTH, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -694,12 +990,17 @@ func (c *Client) Health() (*HealthStatus, error) {
 // =============================================================================
 
 func (c *Client) AddDocument(extID, filename string) (uint64, error) {
+	return c.AddDocumentContext(context.Background(), extID, filename)
+}
+
+// AddDocumentContext is like AddDocument but honors ctx cancellation and deadline
+func (c *Client) AddDocumentContext(ctx context.Context, extID, filename string) (uint64, error) {
 	req := &pb.AddDocumentRequest{
 		ExternalId: extID,
 		Filename:   filename,
 	}
 
-	resp, err := c.send(pb.CommandType_CMD_ADD_DOCUMENT, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_ADD_DOCUMENT, req)
 	if err != nil {
 		return 0, err
 	}
@@ -713,9 +1014,36 @@ func (c *Client) AddDocument(extID, filename string) (uint64, error) {
 }
 
 func (c *Client) GetDocument(id uint64) (*types.Document, error) {
+	return c.GetDocumentContext(context.Background(), id)
+}
+
+// GetDocumentContext is like GetDocument but honors ctx cancellation and deadline
+func (c *Client) GetDocumentContext(ctx context.Context, id uint64) (*types.Document, error) {
 	req := &pb.GetByIDRequest{Id: id}
 
-	resp, err := c.send(pb.CommandType_CMD_GET_DOCUMENT, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_DOCUMENT, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var docResp pb.Document
+	if err := proto.Unmarshal(resp.Payload, &docResp); err != nil {
+		return nil, err
+	}
+
+	return codec.ProtoToDocument(&docResp), nil
+}
+
+// GetDocumentByExternalID returns the document stored under extID
+func (c *Client) GetDocumentByExternalID(extID string) (*types.Document, error) {
+	return c.GetDocumentByExternalIDContext(context.Background(), extID)
+}
+
+// GetDocumentByExternalIDContext is like GetDocumentByExternalID but honors ctx cancellation and deadline
+func (c *Client) GetDocumentByExternalIDContext(ctx context.Context, extID string) (*types.Document, error) {
+	req := &pb.GetByExternalIDRequest{ExternalId: extID}
+
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_DOCUMENT_BY_EXTERNAL_ID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -729,8 +1057,13 @@ func (c *Client) GetDocument(id uint64) (*types.Document, error) {
 }
 
 func (c *Client) DeleteDocument(id uint64) error {
+	return c.DeleteDocumentContext(context.Background(), id)
+}
+
+// DeleteDocumentContext is like DeleteDocument but honors ctx cancellation and deadline
+func (c *Client) DeleteDocumentContext(ctx context.Context, id uint64) error {
 	req := &pb.DeleteByIDRequest{Id: id}
-	_, err := c.send(pb.CommandType_CMD_DELETE_DOCUMENT, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_DELETE_DOCUMENT, req)
 	return err
 }
 
@@ -739,6 +1072,11 @@ func (c *Client) DeleteDocument(id uint64) error {
 // =============================================================================
 
 func (c *Client) AddTextUnit(extID string, docID uint64, content string, embedding []float32, tokenCount int) (uint64, error) {
+	return c.AddTextUnitContext(context.Background(), extID, docID, content, embedding, tokenCount)
+}
+
+// AddTextUnitContext is like AddTextUnit but honors ctx cancellation and deadline
+func (c *Client) AddTextUnitContext(ctx context.Context, extID string, docID uint64, content string, embedding []float32, tokenCount int) (uint64, error) {
 	req := &pb.AddTextUnitRequest{
 		ExternalId: extID,
 		DocumentId: docID,
@@ -747,7 +1085,7 @@ func (c *Client) AddTextUnit(extID string, docID uint64, content string, embeddi
 		TokenCount: int32(tokenCount),
 	}
 
-	resp, err := c.send(pb.CommandType_CMD_ADD_TEXTUNIT, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_ADD_TEXTUNIT, req)
 	if err != nil {
 		return 0, err
 	}
@@ -760,10 +1098,52 @@ func (c *Client) AddTextUnit(extID string, docID uint64, content string, embeddi
 	return okResp.Id, nil
 }
 
+// AddTextUnitText adds a text unit embedded from its content with
+// PoolConfig.Embedder. The token count is estimated at four bytes a token.
+func (c *Client) AddTextUnitText(extID string, docID uint64, content string) (uint64, error) {
+	return c.AddTextUnitTextContext(context.Background(), extID, docID, content)
+}
+
+// AddTextUnitTextContext is like AddTextUnitText but honors ctx cancellation and deadline
+func (c *Client) AddTextUnitTextContext(ctx context.Context, extID string, docID uint64, content string) (uint64, error) {
+	embedding, err := c.embedText(ctx, "text unit", content)
+	if err != nil {
+		return 0, err
+	}
+	return c.AddTextUnitContext(ctx, extID, docID, content, embedding, len(content)/4)
+}
+
 func (c *Client) GetTextUnit(id uint64) (*types.TextUnit, error) {
+	return c.GetTextUnitContext(context.Background(), id)
+}
+
+// GetTextUnitContext is like GetTextUnit but honors ctx cancellation and deadline
+func (c *Client) GetTextUnitContext(ctx context.Context, id uint64) (*types.TextUnit, error) {
 	req := &pb.GetByIDRequest{Id: id}
 
-	resp, err := c.send(pb.CommandType_CMD_GET_TEXTUNIT, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_TEXTUNIT, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var tuResp pb.TextUnit
+	if err := proto.Unmarshal(resp.Payload, &tuResp); err != nil {
+		return nil, err
+	}
+
+	return codec.ProtoToTextUnit(&tuResp), nil
+}
+
+// GetTextUnitByExternalID returns the text unit stored under extID
+func (c *Client) GetTextUnitByExternalID(extID string) (*types.TextUnit, error) {
+	return c.GetTextUnitByExternalIDContext(context.Background(), extID)
+}
+
+// GetTextUnitByExternalIDContext is like GetTextUnitByExternalID but honors ctx cancellation and deadline
+func (c *Client) GetTextUnitByExternalIDContext(ctx context.Context, extID string) (*types.TextUnit, error) {
+	req := &pb.GetByExternalIDRequest{ExternalId: extID}
+
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_TEXTUNIT_BY_EXTERNAL_ID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -777,34 +1157,129 @@ func (c *Client) GetTextUnit(id uint64) (*types.TextUnit, error) {
 }
 
 func (c *Client) DeleteTextUnit(id uint64) error {
+	return c.DeleteTextUnitContext(context.Background(), id)
+}
+
+// DeleteTextUnitContext is like DeleteTextUnit but honors ctx cancellation and deadline
+func (c *Client) DeleteTextUnitContext(ctx context.Context, id uint64) error {
 	req := &pb.DeleteByIDRequest{Id: id}
-	_, err := c.send(pb.CommandType_CMD_DELETE_TEXTUNIT, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_DELETE_TEXTUNIT, req)
 	return err
 }
 
 func (c *Client) LinkTextUnitToEntity(tuID, entityID uint64) error {
+	return c.LinkTextUnitToEntityContext(context.Background(), tuID, entityID)
+}
+
+// LinkTextUnitToEntityContext is like LinkTextUnitToEntity but honors ctx cancellation and deadline
+func (c *Client) LinkTextUnitToEntityContext(ctx context.Context, tuID, entityID uint64) error {
 	req := &pb.LinkTextUnitEntityRequest{
 		TextunitId: tuID,
 		EntityId:   entityID,
 	}
-	_, err := c.send(pb.CommandType_CMD_LINK_TEXTUNIT_ENTITY, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_LINK_TEXTUNIT_ENTITY, req)
 	return err
 }
 
+// MLinkTextUnitsToEntities links many text unit -> entity pairs in one round trip.
+// Unless continueOnError is set, the server stops at the first failing pair.
+func (c *Client) MLinkTextUnitsToEntities(links []types.BulkLinkInput, continueOnError bool) ([]types.BulkLinkResult, error) {
+	return c.MLinkTextUnitsToEntitiesContext(context.Background(), links, continueOnError)
+}
+
+// MLinkTextUnitsToEntitiesContext is like MLinkTextUnitsToEntities but honors ctx cancellation and deadline
+func (c *Client) MLinkTextUnitsToEntitiesContext(ctx context.Context, links []types.BulkLinkInput, continueOnError bool) ([]types.BulkLinkResult, error) {
+	req := &pb.MLinkTextUnitEntityRequest{
+		Links:           make([]*pb.LinkTextUnitEntityRequest, len(links)),
+		ContinueOnError: continueOnError,
+	}
+	for i, l := range links {
+		req.Links[i] = &pb.LinkTextUnitEntityRequest{TextunitId: l.TextUnitID, EntityId: l.EntityID}
+	}
+
+	resp, err := c.send(ctx, pb.CommandType_CMD_MLINK_TEXTUNIT_ENTITY, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var linkResp pb.MLinkTextUnitEntityResponse
+	if err := proto.Unmarshal(resp.Payload, &linkResp); err != nil {
+		return nil, err
+	}
+
+	results := make([]types.BulkLinkResult, len(linkResp.Results))
+	for i, r := range linkResp.Results {
+		results[i] = types.BulkLinkResult{TextUnitID: r.TextunitId, EntityID: r.EntityId, Error: r.Error}
+	}
+	return results, nil
+}
+
 // =============================================================================
 // Entity Commands
 // =============================================================================
 
 func (c *Client) AddEntity(extID, title, entType, description string, embedding []float32) (uint64, error) {
-	req := &pb.AddEntityRequest{
+	return c.AddEntityContext(context.Background(), extID, title, entType, description, embedding)
+}
+
+// AddEntityContext is like AddEntity but honors ctx cancellation and deadline
+func (c *Client) AddEntityContext(ctx context.Context, extID, title, entType, description string, embedding []float32) (uint64, error) {
+	return c.AddEntityWithTitleEmbeddingContext(ctx, extID, title, entType, description, embedding, nil)
+}
+
+// AddEntityText adds an entity embedded from its title and description
+// with PoolConfig.Embedder
+func (c *Client) AddEntityText(extID, title, entType, description string) (uint64, error) {
+	return c.AddEntityTextContext(context.Background(), extID, title, entType, description)
+}
+
+// AddEntityTextContext is like AddEntityText but honors ctx cancellation and deadline
+func (c *Client) AddEntityTextContext(ctx context.Context, extID, title, entType, description string) (uint64, error) {
+	embedding, err := c.embedText(ctx, "entity", strings.TrimSpace(title+" "+description))
+	if err != nil {
+		return 0, err
+	}
+	return c.AddEntityContext(ctx, extID, title, entType, description, embedding)
+}
+
+// AddEntityWithTitleEmbedding adds an entity with a separate title embedding
+// for QuerySpec.TitleWeight scoring
+func (c *Client) AddEntityWithTitleEmbedding(extID, title, entType, description string, embedding, titleEmbedding []float32) (uint64, error) {
+	return c.AddEntityWithTitleEmbeddingContext(context.Background(), extID, title, entType, description, embedding, titleEmbedding)
+}
+
+// AddEntityWithTitleEmbeddingContext is like AddEntityWithTitleEmbedding but honors ctx cancellation and deadline
+func (c *Client) AddEntityWithTitleEmbeddingContext(ctx context.Context, extID, title, entType, description string, embedding, titleEmbedding []float32) (uint64, error) {
+	return c.addEntity(ctx, &pb.AddEntityRequest{
+		ExternalId:     extID,
+		Title:          title,
+		Type:           entType,
+		Description:    description,
+		Embedding:      embedding,
+		TitleEmbedding: titleEmbedding,
+	})
+}
+
+// AddEntityWithMetadata adds an entity with structured metadata (e.g.
+// ticker=BBRI) that queries can filter on via QuerySpec.MetadataFilters
+func (c *Client) AddEntityWithMetadata(extID, title, entType, description string, embedding []float32, metadata map[string]string) (uint64, error) {
+	return c.AddEntityWithMetadataContext(context.Background(), extID, title, entType, description, embedding, metadata)
+}
+
+// AddEntityWithMetadataContext is like AddEntityWithMetadata but honors ctx cancellation and deadline
+func (c *Client) AddEntityWithMetadataContext(ctx context.Context, extID, title, entType, description string, embedding []float32, metadata map[string]string) (uint64, error) {
+	return c.addEntity(ctx, &pb.AddEntityRequest{
 		ExternalId:  extID,
 		Title:       title,
 		Type:        entType,
 		Description: description,
 		Embedding:   embedding,
-	}
+		Metadata:    metadata,
+	})
+}
 
-	resp, err := c.send(pb.CommandType_CMD_ADD_ENTITY, req)
+func (c *Client) addEntity(ctx context.Context, req *pb.AddEntityRequest) (uint64, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_ADD_ENTITY, req)
 	if err != nil {
 		return 0, err
 	}
@@ -818,9 +1293,36 @@ func (c *Client) AddEntity(extID, title, entType, description string, embedding
 }
 
 func (c *Client) GetEntity(id uint64) (*types.Entity, error) {
+	return c.GetEntityContext(context.Background(), id)
+}
+
+// GetEntityContext is like GetEntity but honors ctx cancellation and deadline
+func (c *Client) GetEntityContext(ctx context.Context, id uint64) (*types.Entity, error) {
 	req := &pb.GetByIDRequest{Id: id}
 
-	resp, err := c.send(pb.CommandType_CMD_GET_ENTITY, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_ENTITY, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var entResp pb.Entity
+	if err := proto.Unmarshal(resp.Payload, &entResp); err != nil {
+		return nil, err
+	}
+
+	return codec.ProtoToEntity(&entResp), nil
+}
+
+// GetEntityByExternalID returns the entity stored under extID
+func (c *Client) GetEntityByExternalID(extID string) (*types.Entity, error) {
+	return c.GetEntityByExternalIDContext(context.Background(), extID)
+}
+
+// GetEntityByExternalIDContext is like GetEntityByExternalID but honors ctx cancellation and deadline
+func (c *Client) GetEntityByExternalIDContext(ctx context.Context, extID string) (*types.Entity, error) {
+	req := &pb.GetByExternalIDRequest{ExternalId: extID}
+
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_ENTITY_BY_EXTERNAL_ID, req)
 	if err != nil {
 		return nil, err
 	}
@@ -834,9 +1336,14 @@ func (c *Client) GetEntity(id uint64) (*types.Entity, error) {
 }
 
 func (c *Client) GetEntityByTitle(title string) (*types.Entity, error) {
+	return c.GetEntityByTitleContext(context.Background(), title)
+}
+
+// GetEntityByTitleContext is like GetEntityByTitle but honors ctx cancellation and deadline
+func (c *Client) GetEntityByTitleContext(ctx context.Context, title string) (*types.Entity, error) {
 	req := &pb.GetEntityByTitleRequest{Title: title}
 
-	resp, err := c.send(pb.CommandType_CMD_GET_ENTITY_BY_TITLE, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_ENTITY_BY_TITLE, req)
 	if err != nil {
 		return nil, err
 	}
@@ -850,18 +1357,75 @@ func (c *Client) GetEntityByTitle(title string) (*types.Entity, error) {
 }
 
 func (c *Client) UpdateEntityDescription(id uint64, description string, embedding []float32) error {
+	return c.UpdateEntityDescriptionContext(context.Background(), id, description, embedding)
+}
+
+// UpdateEntityDescriptionContext is like UpdateEntityDescription but honors ctx cancellation and deadline
+func (c *Client) UpdateEntityDescriptionContext(ctx context.Context, id uint64, description string, embedding []float32) error {
+	req := &pb.UpdateEntityDescRequest{
+		Id:          id,
+		Description: description,
+		Embedding:   embedding,
+	}
+	_, err := c.send(ctx, pb.CommandType_CMD_UPDATE_ENTITY_DESC, req)
+	return err
+}
+
+// UpdateEntityWithMetadata updates an entity's description and merges
+// metadata into its existing metadata. An empty value removes that key.
+func (c *Client) UpdateEntityWithMetadata(id uint64, description string, embedding []float32, metadata map[string]string) error {
+	return c.UpdateEntityWithMetadataContext(context.Background(), id, description, embedding, metadata)
+}
+
+// UpdateEntityWithMetadataContext is like UpdateEntityWithMetadata but honors ctx cancellation and deadline
+func (c *Client) UpdateEntityWithMetadataContext(ctx context.Context, id uint64, description string, embedding []float32, metadata map[string]string) error {
 	req := &pb.UpdateEntityDescRequest{
 		Id:          id,
 		Description: description,
 		Embedding:   embedding,
+		Metadata:    metadata,
+	}
+	_, err := c.send(ctx, pb.CommandType_CMD_UPDATE_ENTITY_DESC, req)
+	return err
+}
+
+// UpdateEntityTitle renames an entity. It fails if another entity in the
+// session already has the title.
+func (c *Client) UpdateEntityTitle(id uint64, title string) error {
+	return c.UpdateEntityTitleContext(context.Background(), id, title)
+}
+
+// UpdateEntityTitleContext is like UpdateEntityTitle but honors ctx cancellation and deadline
+func (c *Client) UpdateEntityTitleContext(ctx context.Context, id uint64, title string) error {
+	req := &pb.UpdateEntityTitleRequest{
+		Id:    id,
+		Title: title,
 	}
-	_, err := c.send(pb.CommandType_CMD_UPDATE_ENTITY_DESC, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_UPDATE_ENTITY_TITLE, req)
 	return err
 }
 
 func (c *Client) DeleteEntity(id uint64) error {
+	return c.DeleteEntityContext(context.Background(), id)
+}
+
+// DeleteEntityContext is like DeleteEntity but honors ctx cancellation and deadline
+func (c *Client) DeleteEntityContext(ctx context.Context, id uint64) error {
 	req := &pb.DeleteByIDRequest{Id: id}
-	_, err := c.send(pb.CommandType_CMD_DELETE_ENTITY, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_DELETE_ENTITY, req)
+	return err
+}
+
+// MergeEntities folds the duplicate mergeID into keepID: its relationships
+// and text unit links move to keepID and mergeID is deleted
+func (c *Client) MergeEntities(keepID, mergeID uint64) error {
+	return c.MergeEntitiesContext(context.Background(), keepID, mergeID)
+}
+
+// MergeEntitiesContext is like MergeEntities but honors ctx cancellation and deadline
+func (c *Client) MergeEntitiesContext(ctx context.Context, keepID, mergeID uint64) error {
+	req := &pb.MergeEntitiesRequest{KeepId: keepID, MergeId: mergeID}
+	_, err := c.send(ctx, pb.CommandType_CMD_MERGE_ENTITIES, req)
 	return err
 }
 
@@ -870,6 +1434,43 @@ func (c *Client) DeleteEntity(id uint64) error {
 // =============================================================================
 
 func (c *Client) AddRelationship(extID string, sourceID, targetID uint64, relType, description string, weight float32) (uint64, error) {
+	return c.AddRelationshipContext(context.Background(), extID, sourceID, targetID, relType, description, weight)
+}
+
+// AddRelationshipContext is like AddRelationship but honors ctx cancellation and deadline
+func (c *Client) AddRelationshipContext(ctx context.Context, extID string, sourceID, targetID uint64, relType, description string, weight float32) (uint64, error) {
+	return c.AddRelationshipWithValidityContext(ctx, extID, sourceID, targetID, relType, description, weight, 0, 0)
+}
+
+// AddRelationshipWithValidity adds a relationship that is only valid during
+// [validFrom, validUntil) in unix seconds. Zero leaves that bound open.
+func (c *Client) AddRelationshipWithValidity(extID string, sourceID, targetID uint64, relType, description string, weight float32, validFrom, validUntil int64) (uint64, error) {
+	return c.AddRelationshipWithValidityContext(context.Background(), extID, sourceID, targetID, relType, description, weight, validFrom, validUntil)
+}
+
+// AddRelationshipWithValidityContext is like AddRelationshipWithValidity but honors ctx cancellation and deadline
+func (c *Client) AddRelationshipWithValidityContext(ctx context.Context, extID string, sourceID, targetID uint64, relType, description string, weight float32, validFrom, validUntil int64) (uint64, error) {
+	req := &pb.AddRelationshipRequest{
+		ExternalId:  extID,
+		SourceId:    sourceID,
+		TargetId:    targetID,
+		Type:        relType,
+		Description: description,
+		Weight:      weight,
+		ValidFrom:   validFrom,
+		ValidUntil:  validUntil,
+	}
+	return c.addRelationship(ctx, req)
+}
+
+// AddDirectedRelationship adds a relationship that queries traverse from
+// source to target only, unless they set another TraversalDirection
+func (c *Client) AddDirectedRelationship(extID string, sourceID, targetID uint64, relType, description string, weight float32) (uint64, error) {
+	return c.AddDirectedRelationshipContext(context.Background(), extID, sourceID, targetID, relType, description, weight)
+}
+
+// AddDirectedRelationshipContext is like AddDirectedRelationship but honors ctx cancellation and deadline
+func (c *Client) AddDirectedRelationshipContext(ctx context.Context, extID string, sourceID, targetID uint64, relType, description string, weight float32) (uint64, error) {
 	req := &pb.AddRelationshipRequest{
 		ExternalId:  extID,
 		SourceId:    sourceID,
@@ -877,9 +1478,13 @@ func (c *Client) AddRelationship(extID string, sourceID, targetID uint64, relTyp
 		Type:        relType,
 		Description: description,
 		Weight:      weight,
+		Directed:    true,
 	}
+	return c.addRelationship(ctx, req)
+}
 
-	resp, err := c.send(pb.CommandType_CMD_ADD_RELATIONSHIP, req)
+func (c *Client) addRelationship(ctx context.Context, req *pb.AddRelationshipRequest) (uint64, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_ADD_RELATIONSHIP, req)
 	if err != nil {
 		return 0, err
 	}
@@ -893,9 +1498,14 @@ func (c *Client) AddRelationship(extID string, sourceID, targetID uint64, relTyp
 }
 
 func (c *Client) GetRelationship(id uint64) (*types.Relationship, error) {
+	return c.GetRelationshipContext(context.Background(), id)
+}
+
+// GetRelationshipContext is like GetRelationship but honors ctx cancellation and deadline
+func (c *Client) GetRelationshipContext(ctx context.Context, id uint64) (*types.Relationship, error) {
 	req := &pb.GetByIDRequest{Id: id}
 
-	resp, err := c.send(pb.CommandType_CMD_GET_RELATIONSHIP, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_RELATIONSHIP, req)
 	if err != nil {
 		return nil, err
 	}
@@ -908,17 +1518,289 @@ func (c *Client) GetRelationship(id uint64) (*types.Relationship, error) {
 	return codec.ProtoToRelationship(&relResp), nil
 }
 
+// GetRelationshipByExternalID returns the relationship stored under extID
+func (c *Client) GetRelationshipByExternalID(extID string) (*types.Relationship, error) {
+	return c.GetRelationshipByExternalIDContext(context.Background(), extID)
+}
+
+// GetRelationshipByExternalIDContext is like GetRelationshipByExternalID but honors ctx cancellation and deadline
+func (c *Client) GetRelationshipByExternalIDContext(ctx context.Context, extID string) (*types.Relationship, error) {
+	req := &pb.GetByExternalIDRequest{ExternalId: extID}
+
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_RELATIONSHIP_BY_EXTERNAL_ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var relResp pb.Relationship
+	if err := proto.Unmarshal(resp.Payload, &relResp); err != nil {
+		return nil, err
+	}
+
+	return codec.ProtoToRelationship(&relResp), nil
+}
+
+// UpdateRelationshipWeight sets a relationship's weight. The session's
+// communities are reported dirty until they are recomputed.
+func (c *Client) UpdateRelationshipWeight(id uint64, weight float32) error {
+	return c.UpdateRelationshipWeightContext(context.Background(), id, weight)
+}
+
+// UpdateRelationshipWeightContext is like UpdateRelationshipWeight but honors ctx cancellation and deadline
+func (c *Client) UpdateRelationshipWeightContext(ctx context.Context, id uint64, weight float32) error {
+	req := &pb.UpdateRelationshipWeightRequest{
+		Id:     id,
+		Weight: weight,
+	}
+	_, err := c.send(ctx, pb.CommandType_CMD_UPDATE_RELATIONSHIP_WEIGHT, req)
+	return err
+}
+
 func (c *Client) DeleteRelationship(id uint64) error {
+	return c.DeleteRelationshipContext(context.Background(), id)
+}
+
+// DeleteRelationshipContext is like DeleteRelationship but honors ctx cancellation and deadline
+func (c *Client) DeleteRelationshipContext(ctx context.Context, id uint64) error {
 	req := &pb.DeleteByIDRequest{Id: id}
-	_, err := c.send(pb.CommandType_CMD_DELETE_RELATIONSHIP, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_DELETE_RELATIONSHIP, req)
 	return err
 }
 
+// RelationshipTypeStats returns the top relationship types by frequency
+// (limit 0 = all types)
+func (c *Client) RelationshipTypeStats(limit int) ([]types.RelationshipTypeStat, error) {
+	return c.RelationshipTypeStatsContext(context.Background(), limit)
+}
+
+// RelationshipTypeStatsContext is like RelationshipTypeStats but honors ctx cancellation and deadline
+func (c *Client) RelationshipTypeStatsContext(ctx context.Context, limit int) ([]types.RelationshipTypeStat, error) {
+	req := &pb.RelationshipTypeStatsRequest{Limit: int32(limit)}
+	resp, err := c.send(ctx, pb.CommandType_CMD_RELATIONSHIP_TYPE_STATS, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var statsResp pb.RelationshipTypeStatsResponse
+	if err := proto.Unmarshal(resp.Payload, &statsResp); err != nil {
+		return nil, err
+	}
+
+	stats := make([]types.RelationshipTypeStat, len(statsResp.Stats))
+	for i, st := range statsResp.Stats {
+		stats[i] = types.RelationshipTypeStat{
+			Type:      st.Type,
+			Count:     int(st.Count),
+			AvgWeight: st.AvgWeight,
+		}
+	}
+	return stats, nil
+}
+
+// Count returns the number of items of one type in the session
+func (c *Client) Count(itemType types.ItemType) (uint64, error) {
+	return c.CountContext(context.Background(), itemType)
+}
+
+// CountContext is like Count but honors ctx cancellation and deadline
+func (c *Client) CountContext(ctx context.Context, itemType types.ItemType) (uint64, error) {
+	req := &pb.CountRequest{ItemType: string(itemType)}
+	resp, err := c.send(ctx, pb.CommandType_CMD_COUNT, req)
+	if err != nil {
+		return 0, err
+	}
+
+	var countResp pb.CountResponse
+	if err := proto.Unmarshal(resp.Payload, &countResp); err != nil {
+		return 0, err
+	}
+	return countResp.Count, nil
+}
+
+// EntityStats returns the session's entity and relationship counts by type
+func (c *Client) EntityStats() (*types.TypeCounts, error) {
+	return c.EntityStatsContext(context.Background())
+}
+
+// EntityStatsContext is like EntityStats but honors ctx cancellation and deadline
+func (c *Client) EntityStatsContext(ctx context.Context) (*types.TypeCounts, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_ENTITY_STATS, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	var statsResp pb.EntityStatsResponse
+	if err := proto.Unmarshal(resp.Payload, &statsResp); err != nil {
+		return nil, err
+	}
+
+	counts := &types.TypeCounts{
+		EntityTypes:       make(map[string]int, len(statsResp.EntityTypes)),
+		RelationshipTypes: make(map[string]int, len(statsResp.RelationshipTypes)),
+	}
+	for typ, n := range statsResp.EntityTypes {
+		counts.EntityTypes[typ] = int(n)
+	}
+	for typ, n := range statsResp.RelationshipTypes {
+		counts.RelationshipTypes[typ] = int(n)
+	}
+	return counts, nil
+}
+
+// RebuildIndex rebuilds the session's HNSW graphs from their stored vectors
+// and returns once the new graphs are in place
+func (c *Client) RebuildIndex() error {
+	return c.RebuildIndexContext(context.Background())
+}
+
+// RebuildIndexContext is like RebuildIndex but honors ctx cancellation and deadline
+func (c *Client) RebuildIndexContext(ctx context.Context) error {
+	_, err := c.send(ctx, pb.CommandType_CMD_REBUILD_INDEX, nil)
+	return err
+}
+
+// RebuildIndexAsync starts rebuilding the session's HNSW graphs and returns
+// at once. Queries keep using the old graphs until the rebuild finishes;
+// IndexStatus reports its progress.
+func (c *Client) RebuildIndexAsync() error {
+	return c.RebuildIndexAsyncContext(context.Background())
+}
+
+// RebuildIndexAsyncContext is like RebuildIndexAsync but honors ctx cancellation and deadline
+func (c *Client) RebuildIndexAsyncContext(ctx context.Context) error {
+	_, err := c.send(ctx, pb.CommandType_CMD_REBUILD_INDEX, &pb.RebuildIndexRequest{Async: true})
+	return err
+}
+
+// IndexStatus reports whether the session's indices are being rebuilt, the
+// rebuild's progress and the number of indexed vectors
+func (c *Client) IndexStatus() (*types.IndexStatus, error) {
+	return c.IndexStatusContext(context.Background())
+}
+
+// IndexStatusContext is like IndexStatus but honors ctx cancellation and deadline
+func (c *Client) IndexStatusContext(ctx context.Context) (*types.IndexStatus, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_INDEX_STATUS, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var statusResp pb.IndexStatusResponse
+	if err := proto.Unmarshal(resp.Payload, &statusResp); err != nil {
+		return nil, err
+	}
+	return &types.IndexStatus{
+		State:       types.IndexState(statusResp.State),
+		Percent:     statusResp.Percent,
+		VectorCount: int(statusResp.VectorCount),
+		Error:       statusResp.Error,
+		StartedAt:   statusResp.StartedAt,
+		FinishedAt:  statusResp.FinishedAt,
+	}, nil
+}
+
+// QuantizeIndex converts the session's stored vectors to precision
+// ("float32", "float16" or "int8") in place and keeps that precision for
+// vectors added later. It returns the vector memory before and after.
+func (c *Client) QuantizeIndex(precision string) (before, after int64, err error) {
+	return c.QuantizeIndexContext(context.Background(), precision)
+}
+
+// QuantizeIndexContext is like QuantizeIndex but honors ctx cancellation and deadline
+func (c *Client) QuantizeIndexContext(ctx context.Context, precision string) (before, after int64, err error) {
+	req := &pb.QuantizeIndexRequest{Precision: precision}
+	resp, err := c.send(ctx, pb.CommandType_CMD_QUANTIZE_INDEX, req)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var quantResp pb.QuantizeIndexResponse
+	if err := proto.Unmarshal(resp.Payload, &quantResp); err != nil {
+		return 0, 0, err
+	}
+	return quantResp.BytesBefore, quantResp.BytesAfter, nil
+}
+
+// GetNeighbors returns an entity's direct relationships in the given
+// direction, optionally restricted to relTypes (empty = all types)
+func (c *Client) GetNeighbors(entityID uint64, direction types.Direction, relTypes []string) ([]*types.Relationship, error) {
+	return c.GetNeighborsContext(context.Background(), entityID, direction, relTypes)
+}
+
+// GetNeighborsContext is like GetNeighbors but honors ctx cancellation and deadline
+func (c *Client) GetNeighborsContext(ctx context.Context, entityID uint64, direction types.Direction, relTypes []string) ([]*types.Relationship, error) {
+	req := &pb.GetNeighborsRequest{
+		EntityId: entityID,
+		RelTypes: relTypes,
+	}
+	switch direction {
+	case types.DirectionOutgoing:
+		req.Direction = pb.EdgeDirection_EDGE_DIRECTION_OUTGOING
+	case types.DirectionIncoming:
+		req.Direction = pb.EdgeDirection_EDGE_DIRECTION_INCOMING
+	}
+
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_NEIGHBORS, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result pb.RelationshipsResponse
+	if err := proto.Unmarshal(resp.Payload, &result); err != nil {
+		return nil, err
+	}
+
+	rels := make([]*types.Relationship, len(result.Relationships))
+	for i, r := range result.Relationships {
+		rels[i] = codec.ProtoToRelationship(r)
+	}
+	return rels, nil
+}
+
+// Subgraph returns the entities within hops of the seeds and every
+// relationship among them, capped at maxNodes entities (0 = server default)
+func (c *Client) Subgraph(seedIDs []uint64, hops, maxNodes int) ([]*types.Entity, []*types.Relationship, error) {
+	return c.SubgraphContext(context.Background(), seedIDs, hops, maxNodes)
+}
+
+// SubgraphContext is like Subgraph but honors ctx cancellation and deadline
+func (c *Client) SubgraphContext(ctx context.Context, seedIDs []uint64, hops, maxNodes int) ([]*types.Entity, []*types.Relationship, error) {
+	req := &pb.SubgraphRequest{
+		SeedIds:  seedIDs,
+		Hops:     int32(hops),
+		MaxNodes: int32(maxNodes),
+	}
+	resp, err := c.send(ctx, pb.CommandType_CMD_SUBGRAPH, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var result pb.SubgraphResponse
+	if err := proto.Unmarshal(resp.Payload, &result); err != nil {
+		return nil, nil, err
+	}
+
+	entities := make([]*types.Entity, len(result.Entities))
+	for i, ent := range result.Entities {
+		entities[i] = codec.ProtoToEntity(ent)
+	}
+	rels := make([]*types.Relationship, len(result.Relationships))
+	for i, rel := range result.Relationships {
+		rels[i] = codec.ProtoToRelationship(rel)
+	}
+	return entities, rels, nil
+}
+
 // =============================================================================
 // Community Commands
 // =============================================================================
 
 func (c *Client) AddCommunity(extID, title, summary, fullContent string, level int, entityIDs, relIDs []uint64, embedding []float32) (uint64, error) {
+	return c.AddCommunityContext(context.Background(), extID, title, summary, fullContent, level, entityIDs, relIDs, embedding)
+}
+
+// AddCommunityContext is like AddCommunity but honors ctx cancellation and deadline
+func (c *Client) AddCommunityContext(ctx context.Context, extID, title, summary, fullContent string, level int, entityIDs, relIDs []uint64, embedding []float32) (uint64, error) {
 	req := &pb.AddCommunityRequest{
 		ExternalId:      extID,
 		Title:           title,
@@ -930,7 +1812,7 @@ func (c *Client) AddCommunity(extID, title, summary, fullContent string, level i
 		Embedding:       embedding,
 	}
 
-	resp, err := c.send(pb.CommandType_CMD_ADD_COMMUNITY, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_ADD_COMMUNITY, req)
 	if err != nil {
 		return 0, err
 	}
@@ -944,9 +1826,14 @@ func (c *Client) AddCommunity(extID, title, summary, fullContent string, level i
 }
 
 func (c *Client) GetCommunity(id uint64) (*types.Community, error) {
+	return c.GetCommunityContext(context.Background(), id)
+}
+
+// GetCommunityContext is like GetCommunity but honors ctx cancellation and deadline
+func (c *Client) GetCommunityContext(ctx context.Context, id uint64) (*types.Community, error) {
 	req := &pb.GetByIDRequest{Id: id}
 
-	resp, err := c.send(pb.CommandType_CMD_GET_COMMUNITY, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_COMMUNITY, req)
 	if err != nil {
 		return nil, err
 	}
@@ -960,23 +1847,73 @@ func (c *Client) GetCommunity(id uint64) (*types.Community, error) {
 }
 
 func (c *Client) DeleteCommunity(id uint64) error {
+	return c.DeleteCommunityContext(context.Background(), id)
+}
+
+// DeleteCommunityContext is like DeleteCommunity but honors ctx cancellation and deadline
+func (c *Client) DeleteCommunityContext(ctx context.Context, id uint64) error {
 	req := &pb.DeleteByIDRequest{Id: id}
-	_, err := c.send(pb.CommandType_CMD_DELETE_COMMUNITY, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_DELETE_COMMUNITY, req)
 	return err
 }
 
+// GetEntityCommunities returns the communities containing an entity at every
+// hierarchy level, ordered by level
+func (c *Client) GetEntityCommunities(entityID uint64) ([]*types.Community, error) {
+	return c.GetEntityCommunitiesContext(context.Background(), entityID)
+}
+
+// GetEntityCommunitiesContext is like GetEntityCommunities but honors ctx cancellation and deadline
+func (c *Client) GetEntityCommunitiesContext(ctx context.Context, entityID uint64) ([]*types.Community, error) {
+	req := &pb.GetByIDRequest{Id: entityID}
+
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_ENTITY_COMMUNITIES, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var commResp pb.CommunitiesResponse
+	if err := proto.Unmarshal(resp.Payload, &commResp); err != nil {
+		return nil, err
+	}
+
+	communities := make([]*types.Community, len(commResp.Communities))
+	for i, comm := range commResp.Communities {
+		communities[i] = codec.ProtoToCommunity(comm)
+	}
+	return communities, nil
+}
+
 type ComputeCommunitiesResult struct {
 	Count       int
 	Communities []*types.Community
+	Modularity  float64 // partition quality at the requested resolution
 }
 
 func (c *Client) ComputeCommunities(resolution float64, iterations int) (*ComputeCommunitiesResult, error) {
+	return c.ComputeCommunitiesContext(context.Background(), resolution, iterations)
+}
+
+// ComputeCommunitiesContext is like ComputeCommunities but honors ctx cancellation and deadline
+func (c *Client) ComputeCommunitiesContext(ctx context.Context, resolution float64, iterations int) (*ComputeCommunitiesResult, error) {
+	return c.ComputeCommunitiesWithAlgorithmContext(ctx, "", resolution, iterations)
+}
+
+// ComputeCommunitiesWithAlgorithm computes communities with the named
+// algorithm, "leiden" or "louvain"; empty means the server default (leiden)
+func (c *Client) ComputeCommunitiesWithAlgorithm(algorithm string, resolution float64, iterations int) (*ComputeCommunitiesResult, error) {
+	return c.ComputeCommunitiesWithAlgorithmContext(context.Background(), algorithm, resolution, iterations)
+}
+
+// ComputeCommunitiesWithAlgorithmContext is like ComputeCommunitiesWithAlgorithm but honors ctx cancellation and deadline
+func (c *Client) ComputeCommunitiesWithAlgorithmContext(ctx context.Context, algorithm string, resolution float64, iterations int) (*ComputeCommunitiesResult, error) {
 	req := &pb.ComputeCommunitiesRequest{
 		Resolution: resolution,
 		Iterations: int32(iterations),
+		Algorithm:  algorithm,
 	}
 
-	resp, err := c.send(pb.CommandType_CMD_COMPUTE_COMMUNITIES, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_COMPUTE_COMMUNITIES, req)
 	if err != nil {
 		return nil, err
 	}
@@ -989,6 +1926,7 @@ func (c *Client) ComputeCommunities(resolution float64, iterations int) (*Comput
 	result := &ComputeCommunitiesResult{
 		Count:       int(commResp.Count),
 		Communities: make([]*types.Community, len(commResp.Communities)),
+		Modularity:  commResp.Modularity,
 	}
 	for i, c := range commResp.Communities {
 		result.Communities[i] = codec.ProtoToCommunity(c)
@@ -997,12 +1935,37 @@ func (c *Client) ComputeCommunities(resolution float64, iterations int) (*Comput
 	return result, nil
 }
 
+// ComputePageRank scores every entity in the session by PageRank, stores the
+// score on the entity, and returns the scores by entity ID
+func (c *Client) ComputePageRank() (map[uint64]float64, error) {
+	return c.ComputePageRankContext(context.Background())
+}
+
+// ComputePageRankContext is like ComputePageRank but honors ctx cancellation and deadline
+func (c *Client) ComputePageRankContext(ctx context.Context) (map[uint64]float64, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_COMPUTE_PAGERANK, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	var prResp pb.PageRankResponse
+	if err := proto.Unmarshal(resp.Payload, &prResp); err != nil {
+		return nil, err
+	}
+	return prResp.Scores, nil
+}
+
 type HierarchicalLeidenResult struct {
 	TotalCommunities int
 	LevelCounts      map[int]int
 }
 
 func (c *Client) HierarchicalLeiden(maxLevels int, resolution float64) (*HierarchicalLeidenResult, error) {
+	return c.HierarchicalLeidenContext(context.Background(), maxLevels, resolution)
+}
+
+// HierarchicalLeidenContext is like HierarchicalLeiden but honors ctx cancellation and deadline
+func (c *Client) HierarchicalLeidenContext(ctx context.Context, maxLevels int, resolution float64) (*HierarchicalLeidenResult, error) {
 	if maxLevels > 5 {
 		maxLevels = 5
 	}
@@ -1012,7 +1975,7 @@ func (c *Client) HierarchicalLeiden(maxLevels int, resolution float64) (*Hierarc
 		Resolution: resolution,
 	}
 
-	resp, err := c.send(pb.CommandType_CMD_HIERARCHICAL_LEIDEN, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_HIERARCHICAL_LEIDEN, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1034,7 +1997,12 @@ func (c *Client) HierarchicalLeiden(maxLevels int, resolution float64) (*Hierarc
 }
 
 func (c *Client) HierarchicalLeidenDefault() (*HierarchicalLeidenResult, error) {
-	return c.HierarchicalLeiden(5, 1.0)
+	return c.HierarchicalLeidenDefaultContext(context.Background())
+}
+
+// HierarchicalLeidenDefaultContext is like HierarchicalLeidenDefault but honors ctx cancellation and deadline
+func (c *Client) HierarchicalLeidenDefaultContext(ctx context.Context) (*HierarchicalLeidenResult, error) {
+	return c.HierarchicalLeidenContext(ctx, 5, 1.0)
 }
 
 // =============================================================================
@@ -1042,23 +2010,19 @@ func (c *Client) HierarchicalLeidenDefault() (*HierarchicalLeidenResult, error)
 // =============================================================================
 
 func (c *Client) Query(spec types.QuerySpec) (*types.ContextPack, error) {
-	// Convert search types to strings (proto uses repeated string)
-	var searchTypes []string
-	for _, st := range spec.SearchTypes {
-		searchTypes = append(searchTypes, string(st))
-	}
+	return c.QueryContext(context.Background(), spec)
+}
 
-	req := &pb.QueryRequest{
-		QueryVector:    spec.QueryVector,
-		TopK:           int32(spec.TopK),
-		KHops:          int32(spec.KHops),
-		MaxEntities:    int32(spec.MaxEntities),
-		MaxTextunits:   int32(spec.MaxTextUnits),
-		MaxCommunities: int32(spec.MaxCommunities),
-		SearchTypes:    searchTypes,
+// QueryContext is like Query but honors ctx cancellation and deadline
+func (c *Client) QueryContext(ctx context.Context, spec types.QuerySpec) (*types.ContextPack, error) {
+	// The sooner of spec.DeadlineMs and the ctx deadline applies
+	if spec.DeadlineMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(spec.DeadlineMs)*time.Millisecond)
+		defer cancel()
 	}
 
-	resp, err := c.send(pb.CommandType_CMD_QUERY, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_QUERY, codec.QuerySpecToProto(spec))
 	if err != nil {
 		return nil, err
 	}
@@ -1067,19 +2031,130 @@ func (c *Client) Query(spec types.QuerySpec) (*types.ContextPack, error) {
 	if err := proto.Unmarshal(resp.Payload, &queryResp); err != nil {
 		return nil, err
 	}
+	return contextPackFromProto(&queryResp), nil
+}
+
+// QueryText embeds text with PoolConfig.Embedder and runs spec with the
+// result as its query vector. With Cohere, use an embedder whose InputType
+// is "search_query".
+func (c *Client) QueryText(text string, spec types.QuerySpec) (*types.ContextPack, error) {
+	return c.QueryTextContext(context.Background(), text, spec)
+}
+
+// QueryTextContext is like QueryText but honors ctx cancellation and deadline
+func (c *Client) QueryTextContext(ctx context.Context, text string, spec types.QuerySpec) (*types.ContextPack, error) {
+	vec, err := c.embedText(ctx, "query", text)
+	if err != nil {
+		return nil, err
+	}
+	spec.QueryVector = vec
+	return c.QueryContext(ctx, spec)
+}
+
+// embedText embeds one text with PoolConfig.Embedder; what names the text
+// in errors
+func (c *Client) embedText(ctx context.Context, what, text string) ([]float32, error) {
+	if c.config.Embedder == nil {
+		return nil, ErrNoEmbedder
+	}
+	vecs, err := c.config.Embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("embed %s: %w", what, err)
+	}
+	if len(vecs) != 1 {
+		return nil, fmt.Errorf("embed %s: got %d embeddings for 1 text", what, len(vecs))
+	}
+	return vecs[0], nil
+}
+
+// QueryStream runs spec like Query but receives the results over several
+// frames, for result sets that may not fit in one. It yields a ContextPack
+// per frame holding that frame's results: text units first, then entities,
+// communities and relationships. The last one also carries QueryID and
+// Stats. Stopping the iteration early drops the connection.
+func (c *Client) QueryStream(spec types.QuerySpec) iter.Seq2[*types.ContextPack, error] {
+	return c.QueryStreamContext(context.Background(), spec)
+}
+
+// QueryStreamContext is like QueryStream but honors ctx cancellation and
+// deadline. Once ctx is done the iteration ends by yielding ctx.Err().
+func (c *Client) QueryStreamContext(ctx context.Context, spec types.QuerySpec) iter.Seq2[*types.ContextPack, error] {
+	return func(yield func(*types.ContextPack, error) bool) {
+		if spec.DeadlineMs > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(spec.DeadlineMs)*time.Millisecond)
+			defer cancel()
+		}
+
+		stopped := false
+		err := c.withConn(ctx, pb.CommandType_CMD_QUERY_STREAM, func(pc *pooledConn) error {
+			resp, err := c.doSend(ctx, pc, pb.CommandType_CMD_QUERY_STREAM, codec.QuerySpecToProto(spec))
+			for seq := uint64(0); ; seq++ {
+				if err != nil {
+					return err
+				}
+				if resp.CmdType != pb.CommandType_CMD_QUERY_STREAM_CHUNK {
+					return fmt.Errorf("unexpected response: %v", resp.CmdType)
+				}
+
+				var chunk pb.QueryStreamChunk
+				if err := proto.Unmarshal(resp.Payload, &chunk); err != nil {
+					return err
+				}
+				if chunk.Seq != seq {
+					return fmt.Errorf("query stream chunk %d out of order (want %d)", chunk.Seq, seq)
+				}
+				if chunk.Results == nil {
+					chunk.Results = &pb.QueryResponse{}
+				}
+				if !yield(contextPackFromProto(chunk.Results), nil) && !chunk.Last {
+					// The rest of the stream is still on the connection
+					stopped = true
+					return errQueryStreamStopped
+				}
+				if chunk.Last {
+					return nil
+				}
+
+				resp, err = c.readResponse(ctx, pc)
+			}
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// errQueryStreamStopped makes withConn drop a connection whose query stream
+// the caller stopped reading
+var errQueryStreamStopped = errors.New("query stream stopped")
+
+// queryStatsFromProto converts the query stats a response carries; the
+// per-index search counts are not sent
+func queryStatsFromProto(stats *pb.QueryStats) types.QueryStats {
+	return types.QueryStats{
+		DurationMicros: stats.GetDurationMicros(),
+		Relaxations:    stats.GetRelaxations(),
+		CacheHit:       stats.GetCacheHit(),
+	}
+}
 
+// contextPackFromProto converts a query response to a ContextPack
+func contextPackFromProto(queryResp *pb.QueryResponse) *types.ContextPack {
 	result := &types.ContextPack{
-		QueryID: queryResp.QueryId,
-		Stats: types.QueryStats{
-			DurationMicros: queryResp.Stats.DurationMicros,
-		},
+		QueryID:    queryResp.QueryId,
+		NextCursor: queryResp.NextCursor,
+		Stats:      queryStatsFromProto(queryResp.Stats),
 	}
 
 	for _, tu := range queryResp.Textunits {
 		result.TextUnits = append(result.TextUnits, types.TextUnitResult{
-			TextUnit:   codec.ProtoToTextUnit(tu.Textunit),
-			Similarity: tu.Similarity,
-			Hop:        int(tu.Hop),
+			TextUnit:      codec.ProtoToTextUnit(tu.Textunit),
+			Similarity:    tu.Similarity,
+			Hop:           int(tu.Hop),
+			TokenCount:    int(tu.TokenCount),
+			ContentLength: int(tu.ContentLength),
+			KeywordScore:  tu.KeywordScore,
 		})
 	}
 
@@ -1106,13 +2181,18 @@ func (c *Client) Query(spec types.QuerySpec) (*types.ContextPack, error) {
 		})
 	}
 
-	return result, nil
+	return result
 }
 
 func (c *Client) Explain(queryID uint64) (*types.ExplainPack, error) {
+	return c.ExplainContext(context.Background(), queryID)
+}
+
+// ExplainContext is like Explain but honors ctx cancellation and deadline
+func (c *Client) ExplainContext(ctx context.Context, queryID uint64) (*types.ExplainPack, error) {
 	req := &pb.ExplainRequest{QueryId: queryID}
 
-	resp, err := c.send(pb.CommandType_CMD_EXPLAIN, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_EXPLAIN, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1149,6 +2229,145 @@ func (c *Client) Explain(queryID uint64) (*types.ExplainPack, error) {
 	return result, nil
 }
 
+// QueryStatsSummary returns aggregate metrics for queries in the last window
+// (0 = server default). sessionOnly restricts it to this client's session;
+// keys scoped to session prefixes are always restricted.
+func (c *Client) QueryStatsSummary(window time.Duration, sessionOnly bool) (*types.QueryStatsSummary, error) {
+	return c.QueryStatsSummaryContext(context.Background(), window, sessionOnly)
+}
+
+// QueryStatsSummaryContext is like QueryStatsSummary but honors ctx cancellation and deadline
+func (c *Client) QueryStatsSummaryContext(ctx context.Context, window time.Duration, sessionOnly bool) (*types.QueryStatsSummary, error) {
+	req := &pb.QueryStatsSummaryRequest{
+		WindowSeconds: int64(window / time.Second),
+		SessionOnly:   sessionOnly,
+	}
+
+	resp, err := c.send(ctx, pb.CommandType_CMD_QUERY_STATS_SUMMARY, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var summaryResp pb.QueryStatsSummaryResponse
+	if err := proto.Unmarshal(resp.Payload, &summaryResp); err != nil {
+		return nil, err
+	}
+
+	return &types.QueryStatsSummary{
+		WindowSeconds:    summaryResp.WindowSeconds,
+		Count:            int(summaryResp.Count),
+		AvgLatencyMicros: summaryResp.AvgLatencyMicros,
+		P50LatencyMicros: summaryResp.P50LatencyMicros,
+		P95LatencyMicros: summaryResp.P95LatencyMicros,
+		P99LatencyMicros: summaryResp.P99LatencyMicros,
+		AvgTextUnits:     summaryResp.AvgTextunits,
+		AvgEntities:      summaryResp.AvgEntities,
+		AvgCommunities:   summaryResp.AvgCommunities,
+		AvgKHops:         summaryResp.AvgKHops,
+		EmptyResults:     int(summaryResp.EmptyResults),
+		Relaxed:          int(summaryResp.Relaxed),
+		CacheHits:        int(summaryResp.CacheHits),
+		CacheHitRate:     summaryResp.CacheHitRate,
+	}, nil
+}
+
+// ListQueries returns up to limit of the session's recent queries, most
+// recent first, with the spec each ran with and its stats (limit 0 = the
+// server's whole history). Query vectors are left out of the specs unless
+// includeVectors is set.
+func (c *Client) ListQueries(limit int, includeVectors bool) ([]types.QueryRecord, error) {
+	return c.ListQueriesContext(context.Background(), limit, includeVectors)
+}
+
+// ListQueriesContext is like ListQueries but honors ctx cancellation and deadline
+func (c *Client) ListQueriesContext(ctx context.Context, limit int, includeVectors bool) ([]types.QueryRecord, error) {
+	req := &pb.ListQueriesRequest{Limit: int32(limit), IncludeVectors: includeVectors}
+	resp, err := c.send(ctx, pb.CommandType_CMD_LIST_QUERIES, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var listResp pb.ListQueriesResponse
+	if err := proto.Unmarshal(resp.Payload, &listResp); err != nil {
+		return nil, err
+	}
+
+	records := make([]types.QueryRecord, 0, len(listResp.Queries))
+	for _, q := range listResp.Queries {
+		var spec types.QuerySpec
+		if q.Spec != nil {
+			spec = codec.ProtoToQuerySpec(q.Spec)
+		}
+		records = append(records, types.QueryRecord{
+			QueryID:     q.QueryId,
+			Spec:        spec,
+			Stats:       queryStatsFromProto(q.Stats),
+			At:          q.At,
+			TextUnits:   int(q.Textunits),
+			Entities:    int(q.Entities),
+			Communities: int(q.Communities),
+		})
+	}
+	return records, nil
+}
+
+// GetQueryResult fetches the full result of a query still in the session's
+// query history. A paged query returns all its results.
+func (c *Client) GetQueryResult(queryID uint64) (*types.ContextPack, error) {
+	return c.GetQueryResultContext(context.Background(), queryID)
+}
+
+// GetQueryResultContext is like GetQueryResult but honors ctx cancellation and deadline
+func (c *Client) GetQueryResultContext(ctx context.Context, queryID uint64) (*types.ContextPack, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_QUERY_RESULT, &pb.GetByIDRequest{Id: queryID})
+	if err != nil {
+		return nil, err
+	}
+
+	var queryResp pb.QueryResponse
+	if err := proto.Unmarshal(resp.Payload, &queryResp); err != nil {
+		return nil, err
+	}
+	return contextPackFromProto(&queryResp), nil
+}
+
+// CommandStats returns per-command counts and latency percentiles across
+// all sessions since the server started
+func (c *Client) CommandStats() (*types.CommandStats, error) {
+	return c.CommandStatsContext(context.Background())
+}
+
+// CommandStatsContext is like CommandStats but honors ctx cancellation and deadline
+func (c *Client) CommandStatsContext(ctx context.Context) (*types.CommandStats, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_STATS, &pb.Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	var statsResp pb.StatsResponse
+	if err := proto.Unmarshal(resp.Payload, &statsResp); err != nil {
+		return nil, err
+	}
+
+	stats := &types.CommandStats{
+		UptimeSeconds:        statsResp.UptimeSeconds,
+		SlowQueryThresholdMs: statsResp.SlowQueryThresholdMs,
+		Commands:             make([]types.CommandLatency, 0, len(statsResp.Commands)),
+	}
+	for _, cmd := range statsResp.Commands {
+		stats.Commands = append(stats.Commands, types.CommandLatency{
+			Command:          cmd.Command,
+			Count:            cmd.Count,
+			Errors:           cmd.Errors,
+			AvgLatencyMicros: cmd.AvgLatencyMicros,
+			P50LatencyMicros: cmd.P50LatencyMicros,
+			P95LatencyMicros: cmd.P95LatencyMicros,
+			P99LatencyMicros: cmd.P99LatencyMicros,
+		})
+	}
+	return stats, nil
+}
+
 // =============================================================================
 // TTL Commands
 // =============================================================================
@@ -1156,32 +2375,47 @@ func (c *Client) Explain(queryID uint64) (*types.ExplainPack, error) {
 
 /*
 func (c *Client) SetTTL(itemType types.ItemType, id uint64, ttl int64) error {
+	return c.SetTTLContext(context.Background(), itemType, id, ttl)
+}
+
+// SetTTLContext is like SetTTL but honors ctx cancellation and deadline
+func (c *Client) SetTTLContext(ctx context.Context, itemType types.ItemType, id uint64, ttl int64) error {
 	req := &pb.SetTTLRequest{
 		ItemType:   string(itemType),
 		Id:         id,
 		TtlSeconds: ttl,
 	}
-	_, err := c.send(pb.CommandType_CMD_SET_TTL, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_SET_TTL, req)
 	return err
 }
 
 func (c *Client) SetIdleTTL(itemType types.ItemType, id uint64, idleTTL int64) error {
+	return c.SetIdleTTLContext(context.Background(), itemType, id, idleTTL)
+}
+
+// SetIdleTTLContext is like SetIdleTTL but honors ctx cancellation and deadline
+func (c *Client) SetIdleTTLContext(ctx context.Context, itemType types.ItemType, id uint64, idleTTL int64) error {
 	req := &pb.SetIdleTTLRequest{
 		ItemType:       string(itemType),
 		Id:             id,
 		IdleTtlSeconds: idleTTL,
 	}
-	_, err := c.send(pb.CommandType_CMD_SET_IDLE_TTL, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_SET_IDLE_TTL, req)
 	return err
 }
 
 func (c *Client) GetTTL(itemType types.ItemType, id uint64) (int64, error) {
+	return c.GetTTLContext(context.Background(), itemType, id)
+}
+
+// GetTTLContext is like GetTTL but honors ctx cancellation and deadline
+func (c *Client) GetTTLContext(ctx context.Context, itemType types.ItemType, id uint64) (int64, error) {
 	req := &pb.GetTTLRequest{
 		ItemType: string(itemType),
 		Id:       id,
 	}
 
-	resp, err := c.send(pb.CommandType_CMD_GET_TTL, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_GET_TTL, req)
 	if err != nil {
 		return 0, err
 	}
@@ -1200,19 +2434,26 @@ func (c *Client) GetTTL(itemType types.ItemType, id uint64) (int64, error) {
 // =============================================================================
 
 func (c *Client) MSetEntities(entities []types.BulkEntityInput) ([]uint64, error) {
+	return c.MSetEntitiesContext(context.Background(), entities)
+}
+
+// MSetEntitiesContext is like MSetEntities but honors ctx cancellation and deadline
+func (c *Client) MSetEntitiesContext(ctx context.Context, entities []types.BulkEntityInput) ([]uint64, error) {
 	var pbEntities []*pb.AddEntityRequest
 	for _, e := range entities {
 		pbEntities = append(pbEntities, &pb.AddEntityRequest{
-			ExternalId:  e.ExternalID,
-			Title:       e.Title,
-			Type:        e.Type,
-			Description: e.Description,
-			Embedding:   e.Embedding,
+			ExternalId:     e.ExternalID,
+			Title:          e.Title,
+			Type:           e.Type,
+			Description:    e.Description,
+			Embedding:      e.Embedding,
+			TitleEmbedding: e.TitleEmbedding,
+			Metadata:       e.Metadata,
 		})
 	}
 
 	req := &pb.MSetEntitiesRequest{Entities: pbEntities}
-	resp, err := c.send(pb.CommandType_CMD_MSET_ENTITIES, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_MSET_ENTITIES, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1226,8 +2467,13 @@ func (c *Client) MSetEntities(entities []types.BulkEntityInput) ([]uint64, error
 }
 
 func (c *Client) MGetEntities(ids []uint64) ([]*types.Entity, error) {
+	return c.MGetEntitiesContext(context.Background(), ids)
+}
+
+// MGetEntitiesContext is like MGetEntities but honors ctx cancellation and deadline
+func (c *Client) MGetEntitiesContext(ctx context.Context, ids []uint64) ([]*types.Entity, error) {
 	req := &pb.MGetEntitiesRequest{Ids: ids}
-	resp, err := c.send(pb.CommandType_CMD_MGET_ENTITIES, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_MGET_ENTITIES, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1247,11 +2493,29 @@ func (c *Client) MGetEntities(ids []uint64) ([]*types.Entity, error) {
 
 // ListEntities returns entities after the given cursor, up to limit, in ID order.
 func (c *Client) ListEntities(cursor uint64, limit int) ([]*types.Entity, uint64, error) {
+	return c.ListEntitiesContext(context.Background(), cursor, limit)
+}
+
+// ListEntitiesContext is like ListEntities but honors ctx cancellation and deadline
+func (c *Client) ListEntitiesContext(ctx context.Context, cursor uint64, limit int) ([]*types.Entity, uint64, error) {
+	return c.ListEntitiesByTypeContext(ctx, "", cursor, limit)
+}
+
+// ListEntitiesByType is ListEntities restricted to entities of entType. A
+// page may come back short, even empty, with a non-zero cursor when the
+// server's scan budget ran out; keep paging until the cursor is 0.
+func (c *Client) ListEntitiesByType(entType string, cursor uint64, limit int) ([]*types.Entity, uint64, error) {
+	return c.ListEntitiesByTypeContext(context.Background(), entType, cursor, limit)
+}
+
+// ListEntitiesByTypeContext is like ListEntitiesByType but honors ctx cancellation and deadline
+func (c *Client) ListEntitiesByTypeContext(ctx context.Context, entType string, cursor uint64, limit int) ([]*types.Entity, uint64, error) {
 	req := &pb.ListEntitiesRequest{
 		Cursor: cursor,
 		Limit:  int32(limit),
+		Type:   entType,
 	}
-	resp, err := c.send(pb.CommandType_CMD_LIST_ENTITIES, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_LIST_ENTITIES, req)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -1270,6 +2534,11 @@ func (c *Client) ListEntities(cursor uint64, limit int) ([]*types.Entity, uint64
 }
 
 func (c *Client) MSetDocuments(docs []types.BulkDocumentInput) ([]uint64, error) {
+	return c.MSetDocumentsContext(context.Background(), docs)
+}
+
+// MSetDocumentsContext is like MSetDocuments but honors ctx cancellation and deadline
+func (c *Client) MSetDocumentsContext(ctx context.Context, docs []types.BulkDocumentInput) ([]uint64, error) {
 	var pbDocs []*pb.AddDocumentRequest
 	for _, d := range docs {
 		pbDocs = append(pbDocs, &pb.AddDocumentRequest{
@@ -1279,7 +2548,7 @@ func (c *Client) MSetDocuments(docs []types.BulkDocumentInput) ([]uint64, error)
 	}
 
 	req := &pb.MSetDocumentsRequest{Documents: pbDocs}
-	resp, err := c.send(pb.CommandType_CMD_MSET_DOCUMENTS, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_MSET_DOCUMENTS, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1293,8 +2562,13 @@ func (c *Client) MSetDocuments(docs []types.BulkDocumentInput) ([]uint64, error)
 }
 
 func (c *Client) MGetDocuments(ids []uint64) ([]*types.Document, error) {
+	return c.MGetDocumentsContext(context.Background(), ids)
+}
+
+// MGetDocumentsContext is like MGetDocuments but honors ctx cancellation and deadline
+func (c *Client) MGetDocumentsContext(ctx context.Context, ids []uint64) ([]*types.Document, error) {
 	req := &pb.MGetDocumentsRequest{Ids: ids}
-	resp, err := c.send(pb.CommandType_CMD_MGET_DOCUMENTS, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_MGET_DOCUMENTS, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1313,6 +2587,11 @@ func (c *Client) MGetDocuments(ids []uint64) ([]*types.Document, error) {
 }
 
 func (c *Client) MSetTextUnits(tus []types.BulkTextUnitInput) ([]uint64, error) {
+	return c.MSetTextUnitsContext(context.Background(), tus)
+}
+
+// MSetTextUnitsContext is like MSetTextUnits but honors ctx cancellation and deadline
+func (c *Client) MSetTextUnitsContext(ctx context.Context, tus []types.BulkTextUnitInput) ([]uint64, error) {
 	var pbTUs []*pb.AddTextUnitRequest
 	for _, t := range tus {
 		pbTUs = append(pbTUs, &pb.AddTextUnitRequest{
@@ -1325,7 +2604,7 @@ func (c *Client) MSetTextUnits(tus []types.BulkTextUnitInput) ([]uint64, error)
 	}
 
 	req := &pb.MSetTextUnitsRequest{Textunits: pbTUs}
-	resp, err := c.send(pb.CommandType_CMD_MSET_TEXTUNITS, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_MSET_TEXTUNITS, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1339,8 +2618,13 @@ func (c *Client) MSetTextUnits(tus []types.BulkTextUnitInput) ([]uint64, error)
 }
 
 func (c *Client) MGetTextUnits(ids []uint64) ([]*types.TextUnit, error) {
+	return c.MGetTextUnitsContext(context.Background(), ids)
+}
+
+// MGetTextUnitsContext is like MGetTextUnits but honors ctx cancellation and deadline
+func (c *Client) MGetTextUnitsContext(ctx context.Context, ids []uint64) ([]*types.TextUnit, error) {
 	req := &pb.MGetTextUnitsRequest{Ids: ids}
-	resp, err := c.send(pb.CommandType_CMD_MGET_TEXTUNITS, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_MGET_TEXTUNITS, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1359,6 +2643,11 @@ func (c *Client) MGetTextUnits(ids []uint64) ([]*types.TextUnit, error) {
 }
 
 func (c *Client) MSetRelationships(rels []types.BulkRelationshipInput) ([]uint64, error) {
+	return c.MSetRelationshipsContext(context.Background(), rels)
+}
+
+// MSetRelationshipsContext is like MSetRelationships but honors ctx cancellation and deadline
+func (c *Client) MSetRelationshipsContext(ctx context.Context, rels []types.BulkRelationshipInput) ([]uint64, error) {
 	var pbRels []*pb.AddRelationshipRequest
 	for _, r := range rels {
 		pbRels = append(pbRels, &pb.AddRelationshipRequest{
@@ -1368,11 +2657,14 @@ func (c *Client) MSetRelationships(rels []types.BulkRelationshipInput) ([]uint64
 			Type:        r.Type,
 			Description: r.Description,
 			Weight:      r.Weight,
+			ValidFrom:   r.ValidFrom,
+			ValidUntil:  r.ValidUntil,
+			Directed:    r.Directed,
 		})
 	}
 
 	req := &pb.MSetRelationshipsRequest{Relationships: pbRels}
-	resp, err := c.send(pb.CommandType_CMD_MSET_RELATIONSHIPS, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_MSET_RELATIONSHIPS, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1386,8 +2678,13 @@ func (c *Client) MSetRelationships(rels []types.BulkRelationshipInput) ([]uint64
 }
 
 func (c *Client) MGetRelationships(ids []uint64) ([]*types.Relationship, error) {
+	return c.MGetRelationshipsContext(context.Background(), ids)
+}
+
+// MGetRelationshipsContext is like MGetRelationships but honors ctx cancellation and deadline
+func (c *Client) MGetRelationshipsContext(ctx context.Context, ids []uint64) ([]*types.Relationship, error) {
 	req := &pb.MGetRelationshipsRequest{Ids: ids}
-	resp, err := c.send(pb.CommandType_CMD_MGET_RELATIONSHIPS, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_MGET_RELATIONSHIPS, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1405,13 +2702,79 @@ func (c *Client) MGetRelationships(ids []uint64) ([]*types.Relationship, error)
 	return rels, nil
 }
 
+// MDeleteTextUnits deletes many text units in one round trip and returns
+// how many existed
+func (c *Client) MDeleteTextUnits(ids []uint64) (int, error) {
+	return c.MDeleteTextUnitsContext(context.Background(), ids)
+}
+
+// MDeleteTextUnitsContext is like MDeleteTextUnits but honors ctx cancellation and deadline
+func (c *Client) MDeleteTextUnitsContext(ctx context.Context, ids []uint64) (int, error) {
+	return c.mdelete(ctx, pb.CommandType_CMD_MDELETE_TEXTUNITS, ids)
+}
+
+// MDeleteEntities deletes many entities in one round trip, along with their
+// relationships and text unit links, and returns how many existed
+func (c *Client) MDeleteEntities(ids []uint64) (int, error) {
+	return c.MDeleteEntitiesContext(context.Background(), ids)
+}
+
+// MDeleteEntitiesContext is like MDeleteEntities but honors ctx cancellation and deadline
+func (c *Client) MDeleteEntitiesContext(ctx context.Context, ids []uint64) (int, error) {
+	return c.mdelete(ctx, pb.CommandType_CMD_MDELETE_ENTITIES, ids)
+}
+
+// MDeleteRelationships deletes many relationships in one round trip and
+// returns how many existed
+func (c *Client) MDeleteRelationships(ids []uint64) (int, error) {
+	return c.MDeleteRelationshipsContext(context.Background(), ids)
+}
+
+// MDeleteRelationshipsContext is like MDeleteRelationships but honors ctx cancellation and deadline
+func (c *Client) MDeleteRelationshipsContext(ctx context.Context, ids []uint64) (int, error) {
+	return c.mdelete(ctx, pb.CommandType_CMD_MDELETE_RELATIONSHIPS, ids)
+}
+
+func (c *Client) mdelete(ctx context.Context, cmd pb.CommandType, ids []uint64) (int, error) {
+	resp, err := c.send(ctx, cmd, &pb.MDeleteRequest{Ids: ids})
+	if err != nil {
+		return 0, err
+	}
+
+	var result pb.MDeleteResponse
+	if err := proto.Unmarshal(resp.Payload, &result); err != nil {
+		return 0, err
+	}
+	return int(result.DeletedCount), nil
+}
+
 // ListRelationships returns relationships after the given cursor, up to limit, in ID order.
 func (c *Client) ListRelationships(cursor uint64, limit int) ([]*types.Relationship, uint64, error) {
+	return c.ListRelationshipsContext(context.Background(), cursor, limit)
+}
+
+// ListRelationshipsContext is like ListRelationships but honors ctx cancellation and deadline
+func (c *Client) ListRelationshipsContext(ctx context.Context, cursor uint64, limit int) ([]*types.Relationship, uint64, error) {
+	return c.ListRelationshipsFilteredContext(ctx, types.RelationshipFilter{}, cursor, limit)
+}
+
+// ListRelationshipsFiltered is ListRelationships restricted to relationships
+// matching filter. As with ListEntitiesByType, keep paging until the cursor
+// is 0; a page may come back short.
+func (c *Client) ListRelationshipsFiltered(filter types.RelationshipFilter, cursor uint64, limit int) ([]*types.Relationship, uint64, error) {
+	return c.ListRelationshipsFilteredContext(context.Background(), filter, cursor, limit)
+}
+
+// ListRelationshipsFilteredContext is like ListRelationshipsFiltered but honors ctx cancellation and deadline
+func (c *Client) ListRelationshipsFilteredContext(ctx context.Context, filter types.RelationshipFilter, cursor uint64, limit int) ([]*types.Relationship, uint64, error) {
 	req := &pb.ListRelationshipsRequest{
-		Cursor: cursor,
-		Limit:  int32(limit),
+		Cursor:   cursor,
+		Limit:    int32(limit),
+		Type:     filter.Type,
+		SourceId: filter.SourceID,
+		TargetId: filter.TargetID,
 	}
-	resp, err := c.send(pb.CommandType_CMD_LIST_RELATIONSHIPS, req)
+	resp, err := c.send(ctx, pb.CommandType_CMD_LIST_RELATIONSHIPS, req)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -1434,14 +2797,24 @@ func (c *Client) ListRelationships(cursor uint64, limit int) ([]*types.Relations
 // =============================================================================
 
 func (c *Client) BGSave(path string) error {
+	return c.BGSaveContext(context.Background(), path)
+}
+
+// BGSaveContext is like BGSave but honors ctx cancellation and deadline
+func (c *Client) BGSaveContext(ctx context.Context, path string) error {
 	req := &pb.SaveRequest{Path: path}
-	_, err := c.send(pb.CommandType_CMD_BGSAVE, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_BGSAVE, req)
 	return err
 }
 
 func (c *Client) Save(path string) error {
+	return c.SaveContext(context.Background(), path)
+}
+
+// SaveContext is like Save but honors ctx cancellation and deadline
+func (c *Client) SaveContext(ctx context.Context, path string) error {
 	req := &pb.SaveRequest{Path: path}
-	_, err := c.send(pb.CommandType_CMD_SAVE, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_SAVE, req)
 	return err
 }
 
@@ -1451,7 +2824,12 @@ type LastSaveInfo struct {
 }
 
 func (c *Client) LastSave() (*LastSaveInfo, error) {
-	resp, err := c.send(pb.CommandType_CMD_LASTSAVE, nil)
+	return c.LastSaveContext(context.Background())
+}
+
+// LastSaveContext is like LastSave but honors ctx cancellation and deadline
+func (c *Client) LastSaveContext(ctx context.Context) (*LastSaveInfo, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_LASTSAVE, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1468,8 +2846,13 @@ func (c *Client) LastSave() (*LastSaveInfo, error) {
 }
 
 func (c *Client) BGRestore(path string) error {
+	return c.BGRestoreContext(context.Background(), path)
+}
+
+// BGRestoreContext is like BGRestore but honors ctx cancellation and deadline
+func (c *Client) BGRestoreContext(ctx context.Context, path string) error {
 	req := &pb.RestoreRequest{Path: path}
-	_, err := c.send(pb.CommandType_CMD_BGRESTORE, req)
+	_, err := c.send(ctx, pb.CommandType_CMD_BGRESTORE, req)
 	return err
 }
 
@@ -1482,7 +2865,12 @@ type BackupStatus struct {
 }
 
 func (c *Client) BackupStatus() (*BackupStatus, error) {
-	resp, err := c.send(pb.CommandType_CMD_BACKUP_STATUS, nil)
+	return c.BackupStatusContext(context.Background())
+}
+
+// BackupStatusContext is like BackupStatus but honors ctx cancellation and deadline
+func (c *Client) BackupStatusContext(ctx context.Context) (*BackupStatus, error) {
+	resp, err := c.send(ctx, pb.CommandType_CMD_BACKUP_STATUS, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1500,3 +2888,202 @@ func (c *Client) BackupStatus() (*BackupStatus, error) {
 		LastSavePath: bsResp.LastSavePath,
 	}, nil
 }
+
+// =============================================================================
+// Key Rotation
+// =============================================================================
+
+// RotateKeyResult describes a rotated API key
+type RotateKeyResult struct {
+	KeyID      string
+	APIKey     string // generated key; empty when the caller supplied one
+	KeyHash    string // bcrypt hash to add to key_hashes in the server config
+	ActiveKeys int    // keys the ID now accepts
+}
+
+// RotateKey adds a key to the API key keyID (requires admin). With newKey
+// empty the server generates one. Both keys authenticate until the old one
+// is retired with retireOldest on a later rotation. The server keeps
+// rotations in memory only; persist KeyHash in its config.
+func (c *Client) RotateKey(keyID, newKey string, retireOldest bool) (*RotateKeyResult, error) {
+	return c.RotateKeyContext(context.Background(), keyID, newKey, retireOldest)
+}
+
+// RotateKeyContext is like RotateKey but honors ctx cancellation and deadline
+func (c *Client) RotateKeyContext(ctx context.Context, keyID, newKey string, retireOldest bool) (*RotateKeyResult, error) {
+	req := &pb.RotateKeyRequest{KeyId: keyID, NewKey: newKey, RetireOldest: retireOldest}
+	resp, err := c.send(ctx, pb.CommandType_CMD_ROTATE_KEY, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var rotResp pb.RotateKeyResponse
+	if err := proto.Unmarshal(resp.Payload, &rotResp); err != nil {
+		return nil, err
+	}
+
+	return &RotateKeyResult{
+		KeyID:      rotResp.KeyId,
+		APIKey:     rotResp.ApiKey,
+		KeyHash:    rotResp.KeyHash,
+		ActiveKeys: int(rotResp.ActiveKeys),
+	}, nil
+}
+
+// =============================================================================
+// Snapshot Streaming
+// =============================================================================
+
+// SnapshotChunkSize is the data size of each uploaded snapshot chunk; it
+// stays well under the server's default 4MB frame limit
+const SnapshotChunkSize = 1024 * 1024
+
+// withConn runs fn on a single pooled connection. Multi-envelope transfers
+// need it because the server keeps their state per connection; they are not
+// retried, and the connection is dropped if fn fails part-way.
+func (c *Client) withConn(ctx context.Context, cmd pb.CommandType, fn func(pc *pooledConn) error) error {
+	b := c.lb.pickFor(cmd, nil)
+	pool, err := b.connect()
+	if err != nil {
+		return err
+	}
+	pc, err := pool.getConn(ctx)
+	if err != nil {
+		return err
+	}
+
+	b.inFlight.Add(1)
+	stop := abortOnDone(ctx, pc)
+	err = fn(pc)
+	stop()
+	b.inFlight.Add(-1)
+	if err != nil {
+		pool.closeConn(pc)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	pool.putConn(pc)
+	return nil
+}
+
+// DownloadSnapshot writes a gzip-compressed snapshot of the whole server
+// (all sessions) to w. The result can be restored with UploadSnapshot or
+// placed on the server for BGRESTORE. Requires admin permission.
+func (c *Client) DownloadSnapshot(w io.Writer) error {
+	return c.DownloadSnapshotContext(context.Background(), w)
+}
+
+// DownloadSnapshotContext is like DownloadSnapshot but honors ctx cancellation and deadline
+func (c *Client) DownloadSnapshotContext(ctx context.Context, w io.Writer) error {
+	return c.download(ctx, pb.CommandType_CMD_STREAM_SNAPSHOT, w)
+}
+
+// UploadSnapshot replaces the server's state (all sessions) with a snapshot
+// read from r, gzip-compressed or not, as produced by DownloadSnapshot.
+// Requires admin permission.
+func (c *Client) UploadSnapshot(r io.Reader) error {
+	return c.UploadSnapshotContext(context.Background(), r)
+}
+
+// UploadSnapshotContext is like UploadSnapshot but honors ctx cancellation and deadline
+func (c *Client) UploadSnapshotContext(ctx context.Context, r io.Reader) error {
+	return c.upload(ctx, pb.CommandType_CMD_UPLOAD_SNAPSHOT, r)
+}
+
+// ExportSession writes a gzip-compressed export of the current session's
+// documents, text units, entities, relationships and communities to w, for
+// ImportSession on this or another server
+func (c *Client) ExportSession(w io.Writer) error {
+	return c.ExportSessionContext(context.Background(), w)
+}
+
+// ExportSessionContext is like ExportSession but honors ctx cancellation and deadline
+func (c *Client) ExportSessionContext(ctx context.Context, w io.Writer) error {
+	return c.download(ctx, pb.CommandType_CMD_EXPORT_SESSION, w)
+}
+
+// ImportSession loads a session export read from r into the current session.
+// A new session takes the export's IDs; into an existing one the records are
+// added under new IDs, and the import fails without changes if any of their
+// external IDs or entity titles is already taken.
+func (c *Client) ImportSession(r io.Reader) error {
+	return c.ImportSessionContext(context.Background(), r)
+}
+
+// ImportSessionContext is like ImportSession but honors ctx cancellation and deadline
+func (c *Client) ImportSessionContext(ctx context.Context, r io.Reader) error {
+	return c.upload(ctx, pb.CommandType_CMD_IMPORT_SESSION, r)
+}
+
+// MergeSession folds session srcID's records into the current session under
+// new IDs; srcID is left unchanged. onConflict decides what happens to
+// records clashing with the current session's.
+func (c *Client) MergeSession(srcID string, onConflict types.ConflictPolicy) error {
+	return c.MergeSessionContext(context.Background(), srcID, onConflict)
+}
+
+// MergeSessionContext is like MergeSession but honors ctx cancellation and deadline
+func (c *Client) MergeSessionContext(ctx context.Context, srcID string, onConflict types.ConflictPolicy) error {
+	req := &pb.MergeSessionRequest{
+		SourceSessionId: srcID,
+		OnConflict:      pb.MergeConflictPolicy(onConflict),
+	}
+	_, err := c.send(ctx, pb.CommandType_CMD_MERGE_SESSION, req)
+	return err
+}
+
+// download sends cmd and writes the data of the SnapshotChunks it answers
+// with to w
+func (c *Client) download(ctx context.Context, cmd pb.CommandType, w io.Writer) error {
+	return c.withConn(ctx, cmd, func(pc *pooledConn) error {
+		resp, err := c.doSend(ctx, pc, cmd, nil)
+		for seq := uint64(0); ; seq++ {
+			if err != nil {
+				return err
+			}
+			if resp.CmdType != pb.CommandType_CMD_SNAPSHOT_CHUNK {
+				return fmt.Errorf("unexpected response: %v", resp.CmdType)
+			}
+
+			var chunk pb.SnapshotChunk
+			if err := proto.Unmarshal(resp.Payload, &chunk); err != nil {
+				return err
+			}
+			if chunk.Seq != seq {
+				return fmt.Errorf("snapshot chunk %d out of order (want %d)", chunk.Seq, seq)
+			}
+			if _, err := w.Write(chunk.Data); err != nil {
+				return err
+			}
+			if chunk.Last {
+				return nil
+			}
+
+			resp, err = c.readResponse(ctx, pc)
+		}
+	})
+}
+
+// upload sends r to the server as a sequence of cmd SnapshotChunks
+func (c *Client) upload(ctx context.Context, cmd pb.CommandType, r io.Reader) error {
+	return c.withConn(ctx, cmd, func(pc *pooledConn) error {
+		buf := make([]byte, SnapshotChunkSize)
+		for seq := uint64(0); ; seq++ {
+			n, err := io.ReadFull(r, buf)
+			last := err == io.EOF || err == io.ErrUnexpectedEOF
+			if err != nil && !last {
+				return err
+			}
+
+			chunk := &pb.SnapshotChunk{Seq: seq, Data: buf[:n], Last: last}
+			if _, err := c.doSend(ctx, pc, cmd, chunk); err != nil {
+				return err
+			}
+			if last {
+				return nil
+			}
+		}
+	})
+}
@@ -2,16 +2,32 @@
 package client
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
 	"net"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gibram-io/gibram/pkg/codec"
 	"github.com/gibram-io/gibram/pkg/config"
+	"github.com/gibram-io/gibram/pkg/embed"
 	"github.com/gibram-io/gibram/pkg/engine"
+	"github.com/gibram-io/gibram/pkg/metrics"
 	"github.com/gibram-io/gibram/pkg/server"
 	"github.com/gibram-io/gibram/pkg/types"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 // =============================================================================
@@ -58,8 +74,6 @@ func startTestServer(t *testing.T) *testServer {
 }
 
 func startTestServerWithAuth(t *testing.T) (*testServer, string) {
-	eng := engine.NewEngine(64)
-
 	// Create config with API key authentication
 	apiKey, err := config.GenerateAPIKey()
 	if err != nil {
@@ -82,7 +96,11 @@ func startTestServerWithAuth(t *testing.T) (*testServer, string) {
 		},
 	}
 
-	srv := server.NewServerWithConfig(eng, cfg)
+	return startTestServerWithConfig(t, cfg), apiKey
+}
+
+func startTestServerWithConfig(t *testing.T, cfg *config.Config) *testServer {
+	srv := server.NewServerWithConfig(engine.NewEngine(64), cfg)
 
 	// Find available port
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
@@ -101,7 +119,7 @@ func startTestServerWithAuth(t *testing.T) (*testServer, string) {
 	// Give server time to start
 	time.Sleep(50 * time.Millisecond)
 
-	return &testServer{srv: srv, addr: addr}, apiKey
+	return &testServer{srv: srv, addr: addr}
 }
 
 func (ts *testServer) Stop() {
@@ -247,6 +265,119 @@ func TestNewClient_InvalidAddress(t *testing.T) {
 	}
 }
 
+func TestNewClient_MultipleServers(t *testing.T) {
+	ts1 := startTestServer(t)
+	defer ts1.Stop()
+	ts2 := startTestServer(t)
+	defer ts2.Stop()
+
+	cfg := DefaultPoolConfig()
+	cfg.ConnTimeout = 500 * time.Millisecond
+	client, err := NewClientWithConfig(ts1.addr+","+ts2.addr, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	stats := client.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(stats))
+	}
+	for _, st := range stats {
+		if !st.Healthy {
+			t.Errorf("Server %s should be healthy", st.Addr)
+		}
+	}
+
+	for i := 0; i < 4; i++ {
+		if err := client.Ping(); err != nil {
+			t.Fatalf("Ping failed: %v", err)
+		}
+	}
+
+	for _, st := range client.Stats() {
+		if st.Active == 0 {
+			t.Errorf("Server %s received no connections", st.Addr)
+		}
+	}
+}
+
+func TestNewClient_Failover(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	// A server that accepts connections and drops them immediately
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	cfg := DefaultPoolConfig()
+	cfg.ConnTimeout = 500 * time.Millisecond
+	client, err := NewClientWithConfig(ln.Addr().String()+","+ts.addr, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	for i := 0; i < 4; i++ {
+		if err := client.Ping(); err != nil {
+			t.Fatalf("Ping failed despite a healthy server: %v", err)
+		}
+	}
+
+	stats := client.Stats()
+	if stats[0].Healthy {
+		t.Error("Dropping server should be marked unhealthy")
+	}
+	if !stats[1].Healthy {
+		t.Error("Working server should be healthy")
+	}
+}
+
+func TestNewClient_MultipleServersPartiallyDown(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	cfg := DefaultPoolConfig()
+	cfg.ConnTimeout = 100 * time.Millisecond
+	cfg.LoadBalance = LoadBalanceLeastConn
+	client, err := NewClientWithConfig("127.0.0.1:1,"+ts.addr, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Client should start with one reachable server: %v", err)
+	}
+	defer closeClient(t, client)
+
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	stats := client.Stats()
+	if stats[0].Healthy || !stats[1].Healthy {
+		t.Errorf("Unexpected health: %+v", stats)
+	}
+}
+
+func TestNewClient_UnknownLoadBalance(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	cfg := DefaultPoolConfig()
+	cfg.LoadBalance = "random"
+	if _, err := NewClientWithConfig(ts.addr, testSessionID, cfg); err == nil {
+		t.Error("Expected error for unknown load balance strategy")
+	}
+}
+
 // =============================================================================
 // Client Operation Tests - PING
 // =============================================================================
@@ -321,6 +452,35 @@ func TestClient_Info(t *testing.T) {
 	}
 }
 
+func TestClient_CommandStats(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	if _, err := client.Info(); err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+
+	stats, err := client.CommandStats()
+	if err != nil {
+		t.Fatalf("CommandStats failed: %v", err)
+	}
+	for _, cmd := range stats.Commands {
+		if cmd.Command == "info" {
+			if cmd.Count < 1 || cmd.P99LatencyMicros <= 0 {
+				t.Errorf("info stats = %+v, want count >= 1 and p99 > 0", cmd)
+			}
+			return
+		}
+	}
+	t.Errorf("CommandStats() has no info entry: %+v", stats.Commands)
+}
+
 // =============================================================================
 // Client Operation Tests - Health
 // =============================================================================
@@ -655,11 +815,12 @@ func TestClient_Query(t *testing.T) {
 	}
 }
 
-// =============================================================================
-// Client Operation Tests - TTL
-// =============================================================================
+func TestClient_QueryTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
 
-func TestClient_SetTTL(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -669,20 +830,68 @@ func TestClient_SetTTL(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	docID := mustAddDocument(t, client, "doc-001", "test.pdf")
+	embedding := make([]float32, 64)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
+	}
+	mustAddEntity(t, client, "ent-001", "Test Entity", "test", "Description", embedding)
+	exporter.Reset()
 
-	// DEPRECATED: Session-based architecture - TTL managed at session level
-	_ = docID
-	// err = client.SetTTL("document", docID, 3600)
-	// if err != nil {
-	//	t.Errorf("SetTTL failed: %v", err)
-	// }
+	ctx, root := provider.Tracer("test").Start(context.Background(), "request")
+	spec := types.QuerySpec{
+		QueryVector:    embedding,
+		TopK:           5,
+		KHops:          1,
+		MaxTextUnits:   10,
+		MaxEntities:    10,
+		MaxCommunities: 5,
+		SearchTypes:    []types.SearchType{types.SearchTypeTextUnit, types.SearchTypeEntity, types.SearchTypeCommunity},
+	}
+	if _, err := client.QueryContext(ctx, spec); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	root.End()
+
+	var clientSpan, serverSpan tracetest.SpanStub
+	children := make(map[string]int)
+	for _, span := range exporter.GetSpans() {
+		switch {
+		case span.Name == "gibram.query" && span.SpanKind == trace.SpanKindClient:
+			clientSpan = span
+		case span.Name == "gibram.query" && span.SpanKind == trace.SpanKindServer:
+			serverSpan = span
+		}
+	}
+	if clientSpan.Parent.SpanID() != root.SpanContext().SpanID() {
+		t.Fatalf("client span parent = %v, want the caller's span %v", clientSpan.Parent.SpanID(), root.SpanContext().SpanID())
+	}
+	if serverSpan.Parent.SpanID() != clientSpan.SpanContext.SpanID() || !serverSpan.Parent.IsRemote() {
+		t.Fatalf("server span parent = %v, want the client span %v", serverSpan.Parent.SpanID(), clientSpan.SpanContext.SpanID())
+	}
+	if serverSpan.SpanContext.TraceID() != root.SpanContext().TraceID() {
+		t.Error("server span is not in the caller's trace")
+	}
+
+	for _, span := range exporter.GetSpans() {
+		if span.Parent.SpanID() == serverSpan.SpanContext.SpanID() {
+			children[span.Name]++
+		}
+	}
+	want := map[string]int{
+		"engine.vector_search":    2, // text units and entities
+		"engine.community_lookup": 1,
+		"engine.traversal":        1,
+	}
+	for name, n := range want {
+		if children[name] != n {
+			t.Errorf("%s spans under the server span = %d, want %d (got %v)", name, children[name], n, children)
+		}
+	}
 }
 
-// DEPRECATED: Session-based architecture - TTL managed at session level
-/*
-func TestClient_GetTTL(t *testing.T) {
-	ts := startTestServer(t)
+func TestClient_QueryStream(t *testing.T) {
+	// A small frame limit makes the results span several chunks
+	ts := startTestServerWithConfig(t, &config.Config{Security: config.SecurityConfig{MaxFrameSize: 64 * 1024}})
 	defer ts.Stop()
 
 	client, err := NewClient(ts.addr, testSessionID)
@@ -691,113 +900,222 @@ func TestClient_GetTTL(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	docID := mustAddDocument(t, client, "doc-001", "test.pdf")
-	// client.SetTTL("document", docID, 3600)
+	const numEntities = 300
+	for start := 0; start < numEntities; start += 50 {
+		batch := make([]types.BulkEntityInput, 0, 50)
+		for i := start; i < start+50; i++ {
+			embedding := make([]float32, 64)
+			embedding[i%64] = 1
+			embedding[(i+1)%64] = float32(i) / numEntities
+			batch = append(batch, types.BulkEntityInput{
+				ExternalID:  fmt.Sprintf("ent-%03d", i),
+				Title:       fmt.Sprintf("Entity %d", i),
+				Type:        "test",
+				Description: strings.Repeat("d", 400),
+				Embedding:   embedding,
+			})
+		}
+		if _, err := client.MSetEntities(batch); err != nil {
+			t.Fatalf("MSetEntities failed: %v", err)
+		}
+	}
 
-	_ = docID
-	// ttl, err := client.GetTTL("document", docID)
-	// if err != nil {
-	//	t.Fatalf("GetTTL failed: %v", err)
-	// }
+	query := make([]float32, 64)
+	query[0] = 1
+	spec := types.QuerySpec{
+		QueryVector: query,
+		TopK:        numEntities,
+		EfSearch:    numEntities,
+		MaxEntities: numEntities,
+		SearchTypes: []types.SearchType{types.SearchTypeEntity},
+	}
+	want, err := client.Query(spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
 
-	// if ttl <= 0 {
-	//	t.Error("TTL should be positive after setting")
-	// }
-}
-*/
+	var chunks int
+	var queryID uint64
+	seen := make(map[uint64]bool)
+	for pack, err := range client.QueryStream(spec) {
+		if err != nil {
+			t.Fatalf("QueryStream failed: %v", err)
+		}
+		chunks++
+		for _, ent := range pack.Entities {
+			seen[ent.Entity.ID] = true
+		}
+		if pack.QueryID != 0 {
+			if queryID != 0 {
+				t.Error("Expected the query ID in the last chunk only")
+			}
+			queryID = pack.QueryID
+		}
+	}
+	if chunks < 2 {
+		t.Errorf("Expected several chunks, got %d", chunks)
+	}
+	if queryID == 0 {
+		t.Error("Expected the last chunk to carry the query ID")
+	}
+	if len(seen) != len(want.Entities) {
+		t.Errorf("Streamed %d entities, Query returned %d", len(seen), len(want.Entities))
+	}
+	for _, ent := range want.Entities {
+		if !seen[ent.Entity.ID] {
+			t.Errorf("Entity %d missing from the stream", ent.Entity.ID)
+		}
+	}
 
-// =============================================================================
-// Wire Protocol Tests
-// =============================================================================
+	// Stopping early leaves the client usable
+	for range client.QueryStream(spec) {
+		break
+	}
+	if err := client.Ping(); err != nil {
+		t.Errorf("Ping after an abandoned stream failed: %v", err)
+	}
 
-func TestCodecType(t *testing.T) {
-	if codec.CodecProtobuf != 1 {
-		t.Errorf("CodecProtobuf = %d, want 1", codec.CodecProtobuf)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var streamErr error
+	for _, err := range client.QueryStreamContext(ctx, spec) {
+		streamErr = err
+	}
+	if !errors.Is(streamErr, context.Canceled) {
+		t.Errorf("QueryStreamContext with a cancelled context = %v, want context.Canceled", streamErr)
 	}
 }
 
-// =============================================================================
-// Integration Tests - Full Workflow
-// =============================================================================
+// recordingEmbedder embeds with a Mock and keeps the vectors it returned
+type recordingEmbedder struct {
+	mock *embed.Mock
+	last [][]float32
+}
 
-func TestClient_FullWorkflow_ChatWithPDF(t *testing.T) {
+func (e *recordingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vecs, err := e.mock.Embed(ctx, texts)
+	e.last = vecs
+	return vecs, err
+}
+
+func TestClient_QueryText(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
-	client, err := NewClient(ts.addr, testSessionID)
+	plain, err := NewClient(ts.addr, testSessionID)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
-	defer closeClient(t, client)
+	defer closeClient(t, plain)
+	if _, err := plain.QueryText("central bank", types.DefaultQuerySpec()); !errors.Is(err, ErrNoEmbedder) {
+		t.Errorf("QueryText without an embedder error = %v, want ErrNoEmbedder", err)
+	}
 
-	// 1. Upload document
-	docID, err := client.AddDocument("pdf-001", "research_paper.pdf")
+	embedder := &recordingEmbedder{mock: embed.NewMock(64)}
+	cfg := DefaultPoolConfig()
+	cfg.Embedder = embedder
+	client, err := NewClientWithConfig(ts.addr, testSessionID, cfg)
 	if err != nil {
-		t.Fatalf("Failed to add document: %v", err)
+		t.Fatalf("Failed to create client: %v", err)
 	}
-	t.Logf("Created document with ID: %d", docID)
+	defer closeClient(t, client)
 
-	// 2. Add text chunks
-	embedding := make([]float32, 64)
-	for i := range embedding {
-		embedding[i] = float32(i) / 64.0
+	texts := []string{"central bank", "monetary policy", "archipelago"}
+	vecs, _ := embed.NewMock(64).Embed(context.Background(), texts)
+	for i, text := range texts {
+		if _, err := client.AddEntity(fmt.Sprintf("ent-%d", i), text, "concept", "", vecs[i]); err != nil {
+			t.Fatalf("AddEntity failed: %v", err)
+		}
 	}
 
-	tu1ID := mustAddTextUnit(t, client, "chunk-1", docID, "Machine learning is...", embedding, 10)
-	tu2ID := mustAddTextUnit(t, client, "chunk-2", docID, "Neural networks are...", embedding, 10)
-	t.Logf("Created text units: %d, %d", tu1ID, tu2ID)
+	spec := types.DefaultQuerySpec()
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	spec.TopK = 1
+	spec.KHops = 0
+	spec.QueryVector = []float32{1} // replaced by the text's embedding
+	result, err := client.QueryText("monetary policy", spec)
+	if err != nil {
+		t.Fatalf("QueryText failed: %v", err)
+	}
 
-	// 3. Add entities
-	ent1ID := mustAddEntity(t, client, "ent-ml", "Machine Learning", "concept", "ML description", embedding)
-	ent2ID := mustAddEntity(t, client, "ent-nn", "Neural Network", "concept", "NN description", embedding)
-	t.Logf("Created entities: %d, %d", ent1ID, ent2ID)
+	if len(embedder.last) != 1 || fmt.Sprint(embedder.last[0]) != fmt.Sprint(vecs[1]) {
+		t.Fatalf("embedder produced %v, want the mock embedding of the query text", embedder.last)
+	}
+	if len(result.Entities) == 0 || !strings.EqualFold(result.Entities[0].Entity.Title, "monetary policy") {
+		t.Fatalf("QueryText entities = %+v, want MONETARY POLICY first", result.Entities)
+	}
+	if sim := result.Entities[0].Similarity; sim < 0.99 {
+		t.Errorf("top similarity = %f, want about 1 for the forwarded vector", sim)
+	}
+}
 
-	// 4. Add relationship
-	relID := mustAddRelationship(t, client, "rel-1", ent1ID, ent2ID, "USES", "ML uses NNs", 0.9)
-	t.Logf("Created relationship: %d", relID)
+func TestClient_AddText(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
 
-	// 5. Link text units to entities
-	if err := client.LinkTextUnitToEntity(tu1ID, ent1ID); err != nil {
-		t.Fatalf("LinkTextUnitToEntity failed: %v", err)
+	plain, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
 	}
-	if err := client.LinkTextUnitToEntity(tu2ID, ent2ID); err != nil {
-		t.Fatalf("LinkTextUnitToEntity failed: %v", err)
+	defer closeClient(t, plain)
+	if _, err := plain.AddEntityText("ent-1", "Bank Indonesia", "organization", "central bank"); !errors.Is(err, ErrNoEmbedder) {
+		t.Errorf("AddEntityText without an embedder error = %v, want ErrNoEmbedder", err)
 	}
 
-	// 6. Query
-	spec := types.QuerySpec{
-		QueryVector:    embedding,
-		TopK:           5,
-		KHops:          1,
-		MaxTextUnits:   10,
-		MaxEntities:    10,
-		MaxCommunities: 5,
-		SearchTypes:    []types.SearchType{types.SearchTypeTextUnit, types.SearchTypeEntity},
+	mock := embed.NewMock(64)
+	cfg := DefaultPoolConfig()
+	cfg.Embedder = mock
+	client, err := NewClientWithConfig(ts.addr, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
 	}
-	result, err := client.Query(spec)
+	defer closeClient(t, client)
+
+	entID, err := client.AddEntityText("ent-1", "Bank Indonesia", "organization", "central bank")
 	if err != nil {
-		t.Fatalf("Query failed: %v", err)
+		t.Fatalf("AddEntityText failed: %v", err)
+	}
+	docID, err := client.AddDocument("doc-1", "report.pdf")
+	if err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	const content = "Bank Indonesia manages monetary policy"
+	tuID, err := client.AddTextUnitText("tu-1", docID, content)
+	if err != nil {
+		t.Fatalf("AddTextUnitText failed: %v", err)
 	}
 
-	t.Logf("Query returned %d text units, %d entities", len(result.TextUnits), len(result.Entities))
+	want, _ := mock.Embed(context.Background(), []string{"Bank Indonesia central bank", content})
+	ent, err := client.GetEntity(entID)
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	assertSameVector(t, "entity", ent.Embedding, want[0])
 
-	// 7. Check info
-	info, err := client.Info()
+	tu, err := client.GetTextUnit(tuID)
 	if err != nil {
-		t.Fatalf("Info failed: %v", err)
+		t.Fatalf("GetTextUnit failed: %v", err)
 	}
-	if info.DocumentCount != 1 {
-		t.Errorf("DocumentCount = %d, want 1", info.DocumentCount)
+	assertSameVector(t, "text unit", tu.Embedding, want[1])
+	if tu.TokenCount != len(content)/4 {
+		t.Errorf("text unit token count = %d, want %d", tu.TokenCount, len(content)/4)
 	}
-	if info.TextUnitCount != 2 {
-		t.Errorf("TextUnitCount = %d, want 2", info.TextUnitCount)
+}
+
+// assertSameVector checks got matches want up to float rounding
+func assertSameVector(t *testing.T, what string, got, want []float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s embedding has %d dimensions, want %d", what, len(got), len(want))
 	}
-	if info.EntityCount != 2 {
-		t.Errorf("EntityCount = %d, want 2", info.EntityCount)
+	for i := range want {
+		if d := got[i] - want[i]; d > 1e-5 || d < -1e-5 {
+			t.Fatalf("%s embedding[%d] = %f, want %f", what, i, got[i], want[i])
+		}
 	}
 }
 
-func TestClient_ConcurrentOperations(t *testing.T) {
+func TestClient_Query_Keyword(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -807,87 +1125,77 @@ func TestClient_ConcurrentOperations(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	var wg sync.WaitGroup
-	const numOps = 20
-	errCh := make(chan error, numOps*3)
-
-	// Concurrent document additions
-	for i := 0; i < numOps; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			if _, err := client.AddDocument("doc-"+itoa(id), "file.pdf"); err != nil {
-				errCh <- err
-			}
-		}(i)
+	embedding := make([]float32, 64)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
 	}
+	docID := mustAddDocument(t, client, "doc-kw", "payments.txt")
+	mustAddTextUnit(t, client, "tu-kw-1", docID, "Card payments overview", embedding, 3)
+	fastID := mustAddTextUnit(t, client, "tu-kw-2", docID, "Settlement over BI-FAST", embedding, 3)
 
-	// Concurrent entity additions
-	embedding := make([]float32, 64)
-	for i := 0; i < numOps; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			if _, err := client.AddEntity("ent-"+itoa(id), "Entity "+itoa(id), "test", "Desc", embedding); err != nil {
-				errCh <- err
-			}
-		}(i)
+	result, err := client.Query(types.QuerySpec{
+		QueryVector:  embedding,
+		TopK:         5,
+		KHops:        1,
+		MaxTextUnits: 10,
+		SearchTypes:  []types.SearchType{types.SearchTypeTextUnit, types.SearchTypeKeyword},
+		KeywordQuery: "bi-fast",
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.TextUnits) != 2 {
+		t.Fatalf("Expected 2 text units, got %d", len(result.TextUnits))
 	}
+	if result.TextUnits[0].TextUnit.ID != fastID || result.TextUnits[0].KeywordScore != 1 {
+		t.Errorf("Expected keyword hit %d first with score 1, got %d (%v)", fastID, result.TextUnits[0].TextUnit.ID, result.TextUnits[0].KeywordScore)
+	}
+}
 
-	// Concurrent queries
-	for i := 0; i < numOps/2; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			spec := types.QuerySpec{
-				QueryVector:    embedding,
-				TopK:           5,
-				KHops:          0,
-				MaxTextUnits:   10,
-				MaxEntities:    10,
-				MaxCommunities: 5,
-				SearchTypes:    []types.SearchType{types.SearchTypeEntity},
-			}
-			if _, err := client.Query(spec); err != nil {
-				errCh <- err
-			}
-		}()
+func TestClient_Query_Paged(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
 	}
+	defer closeClient(t, client)
 
-	wg.Wait()
-	close(errCh)
-	for err := range errCh {
-		t.Errorf("Concurrent query error: %v", err)
+	embedding := make([]float32, 64)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
+	}
+	docID := mustAddDocument(t, client, "doc-page", "rates.txt")
+	for i := 0; i < 5; i++ {
+		mustAddTextUnit(t, client, fmt.Sprintf("tu-page-%d", i), docID, "Policy rate decision", embedding, 3)
 	}
 
-	// Verify final state
-	info, err := client.Info()
+	result, err := client.Query(types.QuerySpec{
+		QueryVector:  embedding,
+		TopK:         5,
+		KHops:        1,
+		MaxTextUnits: 10,
+		SearchTypes:  []types.SearchType{types.SearchTypeTextUnit},
+		PageSize:     3,
+	})
 	if err != nil {
-		t.Fatalf("Info failed: %v", err)
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.TextUnits) != 3 || result.NextCursor == "" {
+		t.Fatalf("Expected 3 text units and a cursor, got %d and %q", len(result.TextUnits), result.NextCursor)
 	}
-	t.Logf("Final state: %d documents, %d entities", info.DocumentCount, info.EntityCount)
-}
 
-// Helper for number to string
-func itoa(i int) string {
-	if i == 0 {
-		return "0"
+	next, err := client.Query(types.QuerySpec{Cursor: result.NextCursor})
+	if err != nil {
+		t.Fatalf("Query with cursor failed: %v", err)
 	}
-	var buf [20]byte
-	pos := len(buf)
-	for i > 0 {
-		pos--
-		buf[pos] = byte(i%10) + '0'
-		i /= 10
+	if len(next.TextUnits) != 2 || next.NextCursor != "" || next.QueryID != result.QueryID {
+		t.Errorf("Expected last page of 2 for query %d, got %d (cursor %q, query %d)", result.QueryID, len(next.TextUnits), next.NextCursor, next.QueryID)
 	}
-	return string(buf[pos:])
 }
 
-// =============================================================================
-// Client Operation Tests - Delete Operations
-// =============================================================================
-
-func TestClient_DeleteDocument(t *testing.T) {
+func TestClient_Count(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -897,26 +1205,30 @@ func TestClient_DeleteDocument(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add document first
-	docID, err := client.AddDocument("doc-to-delete", "test.pdf")
+	mustAddEntity(t, client, "ent-ojk", "OJK", "regulator", "Financial services authority", nil)
+	bri := mustAddEntity(t, client, "ent-bri", "BRI", "bank", "State bank", nil)
+
+	count, err := client.Count(types.ItemTypeEntity)
 	if err != nil {
-		t.Fatalf("AddDocument failed: %v", err)
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 entities, got %d", count)
 	}
 
-	// Delete it
-	err = client.DeleteDocument(docID)
-	if err != nil {
-		t.Errorf("DeleteDocument failed: %v", err)
+	if err := client.DeleteEntity(bri); err != nil {
+		t.Fatalf("DeleteEntity failed: %v", err)
+	}
+	if count, err := client.Count(types.ItemTypeEntity); err != nil || count != 1 {
+		t.Errorf("Expected 1 entity after delete, got %d (%v)", count, err)
 	}
 
-	// Try to get the deleted document - should fail
-	_, err = client.GetDocument(docID)
-	if err == nil {
-		t.Error("Expected error getting deleted document")
+	if _, err := client.Count("widget"); err == nil {
+		t.Error("Expected error for unknown item type")
 	}
 }
 
-func TestClient_DeleteTextUnit(t *testing.T) {
+func TestClient_EntityStats(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -926,28 +1238,51 @@ func TestClient_DeleteTextUnit(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add document and text unit first
-	docID := mustAddDocument(t, client, "doc-001", "test.pdf")
-	embedding := make([]float32, 64)
-	tuID, err := client.AddTextUnit("tu-to-delete", docID, "Content to delete", embedding, 5)
+	ojk := mustAddEntity(t, client, "ent-ojk", "OJK", "regulator", "Financial services authority", nil)
+	bri := mustAddEntity(t, client, "ent-bri", "BRI", "organization", "State bank", nil)
+	bca := mustAddEntity(t, client, "ent-bca", "BCA", "organization", "Private bank", nil)
+	mustAddRelationship(t, client, "", ojk, bri, "SUPERVISES", "", 1.0)
+	mustAddRelationship(t, client, "", ojk, bca, "SUPERVISES", "", 1.0)
+
+	counts, err := client.EntityStats()
 	if err != nil {
-		t.Fatalf("AddTextUnit failed: %v", err)
+		t.Fatalf("EntityStats failed: %v", err)
+	}
+	if len(counts.EntityTypes) != 2 || counts.EntityTypes["organization"] != 2 || counts.EntityTypes["regulator"] != 1 {
+		t.Errorf("Unexpected entity types: %v", counts.EntityTypes)
+	}
+	if len(counts.RelationshipTypes) != 1 || counts.RelationshipTypes["SUPERVISES"] != 2 {
+		t.Errorf("Unexpected relationship types: %v", counts.RelationshipTypes)
 	}
+}
 
-	// Delete it
-	err = client.DeleteTextUnit(tuID)
+func TestClient_ListRelationshipsFiltered(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
 	if err != nil {
-		t.Errorf("DeleteTextUnit failed: %v", err)
+		t.Fatalf("Failed to create client: %v", err)
 	}
+	defer closeClient(t, client)
 
-	// Try to get the deleted text unit - should fail
-	_, err = client.GetTextUnit(tuID)
-	if err == nil {
-		t.Error("Expected error getting deleted text unit")
+	ojk := mustAddEntity(t, client, "ent-ojk", "OJK", "regulator", "Financial services authority", nil)
+	bri := mustAddEntity(t, client, "ent-bri", "BRI", "bank", "State bank", nil)
+	bca := mustAddEntity(t, client, "ent-bca", "BCA", "bank", "Private bank", nil)
+	mustAddRelationship(t, client, "", ojk, bri, "SUPERVISES", "", 1.0)
+	partners := mustAddRelationship(t, client, "", bri, bca, "PARTNERS_WITH", "", 1.0)
+	mustAddRelationship(t, client, "", ojk, bca, "SUPERVISES", "", 1.0)
+
+	rels, next, err := client.ListRelationshipsFiltered(types.RelationshipFilter{Type: "PARTNERS_WITH", TargetID: bca}, 0, 10)
+	if err != nil {
+		t.Fatalf("ListRelationshipsFiltered failed: %v", err)
+	}
+	if len(rels) != 1 || rels[0].ID != partners || next != 0 {
+		t.Errorf("Expected only relationship %d, got %d relationships (cursor %d)", partners, len(rels), next)
 	}
 }
 
-func TestClient_DeleteEntity(t *testing.T) {
+func TestClient_QuantizeIndex(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -957,27 +1292,38 @@ func TestClient_DeleteEntity(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add entity first
 	embedding := make([]float32, 64)
-	entityID, err := client.AddEntity("ent-to-delete", "Delete Test", "test", "Desc", embedding)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
+	}
+	mustAddEntity(t, client, "ent-bi", "Bank Indonesia", "central_bank", "Monetary authority", embedding)
+
+	before, after, err := client.QuantizeIndex("int8")
 	if err != nil {
-		t.Fatalf("AddEntity failed: %v", err)
+		t.Fatalf("QuantizeIndex failed: %v", err)
+	}
+	if before == 0 || after >= before {
+		t.Errorf("Vector bytes %d -> %d, want a reduction", before, after)
 	}
 
-	// Delete it
-	err = client.DeleteEntity(entityID)
+	result, err := client.Query(types.QuerySpec{
+		QueryVector: embedding,
+		TopK:        1,
+		SearchTypes: []types.SearchType{types.SearchTypeEntity},
+	})
 	if err != nil {
-		t.Errorf("DeleteEntity failed: %v", err)
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ExternalID != "ent-bi" {
+		t.Errorf("Expected ent-bi after quantizing, got %+v", result.Entities)
 	}
 
-	// Try to get the deleted entity - should fail
-	_, err = client.GetEntity(entityID)
-	if err == nil {
-		t.Error("Expected error getting deleted entity")
+	if _, _, err := client.QuantizeIndex("int4"); err == nil {
+		t.Error("Expected error for unknown precision")
 	}
 }
 
-func TestClient_DeleteRelationship(t *testing.T) {
+func TestClient_RebuildIndexAsync(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -987,29 +1333,42 @@ func TestClient_DeleteRelationship(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add entities and relationship first
 	embedding := make([]float32, 64)
-	ent1ID := mustAddEntity(t, client, "ent-1", "Entity 1", "test", "Desc", embedding)
-	ent2ID := mustAddEntity(t, client, "ent-2", "Entity 2", "test", "Desc", embedding)
-	relID, err := client.AddRelationship("rel-to-delete", ent1ID, ent2ID, "RELATED", "Desc", 1.0)
-	if err != nil {
-		t.Fatalf("AddRelationship failed: %v", err)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
 	}
+	mustAddEntity(t, client, "ent-bi", "Bank Indonesia", "central_bank", "Monetary authority", embedding)
 
-	// Delete it
-	err = client.DeleteRelationship(relID)
-	if err != nil {
-		t.Errorf("DeleteRelationship failed: %v", err)
+	if err := client.RebuildIndex(); err != nil {
+		t.Fatalf("RebuildIndex failed: %v", err)
+	}
+	if err := client.RebuildIndexAsync(); err != nil {
+		t.Fatalf("RebuildIndexAsync failed: %v", err)
 	}
 
-	// Try to get the deleted relationship - should fail
-	_, err = client.GetRelationship(relID)
-	if err == nil {
-		t.Error("Expected error getting deleted relationship")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, err := client.IndexStatus()
+		if err != nil {
+			t.Fatalf("IndexStatus failed: %v", err)
+		}
+		if status.State == types.IndexReady {
+			if status.Percent != 100 || status.VectorCount != 1 || status.StartedAt == 0 {
+				t.Errorf("Status after rebuild = %+v", status)
+			}
+			break
+		}
+		if status.State != types.IndexBuilding {
+			t.Fatalf("Unexpected state %q", status.State)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Rebuild did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
 	}
 }
 
-func TestClient_UpdateEntityDescription(t *testing.T) {
+func TestClient_GetReturnsEmbedding(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1019,39 +1378,37 @@ func TestClient_UpdateEntityDescription(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add entity first
 	embedding := make([]float32, 64)
-	entityID, err := client.AddEntity("ent-update", "Update Test", "test", "Original desc", embedding)
-	if err != nil {
-		t.Fatalf("AddEntity failed: %v", err)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
 	}
+	entID := mustAddEntity(t, client, "ent-bi", "Bank Indonesia", "central_bank", "Monetary authority", embedding)
+	locID := mustAddEntity(t, client, "ent-jkt", "Jakarta", "location", "Capital city", nil)
+	docID := mustAddDocument(t, client, "doc-1", "rates.txt")
+	tuID := mustAddTextUnit(t, client, "tu-1", docID, "Policy rate held", embedding, 3)
 
-	// Update the description with new embedding
-	newEmbedding := make([]float32, 64)
-	for i := range newEmbedding {
-		newEmbedding[i] = 0.5
-	}
-	err = client.UpdateEntityDescription(entityID, "Updated description", newEmbedding)
+	ent, err := client.GetEntity(entID)
 	if err != nil {
-		t.Errorf("UpdateEntityDescription failed: %v", err)
+		t.Fatalf("GetEntity failed: %v", err)
 	}
-
-	// Verify the update
-	entity, err := client.GetEntity(entityID)
+	if !slices.Equal(ent.Embedding, embedding) {
+		t.Errorf("Entity embedding = %v, want %v", ent.Embedding, embedding)
+	}
+	tu, err := client.GetTextUnit(tuID)
 	if err != nil {
-		t.Fatalf("GetEntity failed: %v", err)
+		t.Fatalf("GetTextUnit failed: %v", err)
+	}
+	if !slices.Equal(tu.Embedding, embedding) {
+		t.Errorf("Text unit embedding = %v, want %v", tu.Embedding, embedding)
 	}
 
-	if entity.Description != "Updated description" {
-		t.Errorf("Description = %q, want %q", entity.Description, "Updated description")
+	// Objects stored without an embedding return none
+	if loc, err := client.GetEntity(locID); err != nil || loc.Embedding != nil {
+		t.Errorf("Expected no embedding for ent-jkt, got %v (%v)", loc, err)
 	}
 }
 
-// =============================================================================
-// Client Operation Tests - Community Operations
-// =============================================================================
-
-func TestClient_AddCommunity(t *testing.T) {
+func TestClient_AddEntity_DimensionMismatch(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1061,18 +1418,13 @@ func TestClient_AddCommunity(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	embedding := make([]float32, 64)
-	communityID, err := client.AddCommunity("comm-001", "Test Community", "Summary of the community", "Full content", 0, nil, nil, embedding)
-	if err != nil {
-		t.Fatalf("AddCommunity failed: %v", err)
-	}
-
-	if communityID == 0 {
-		t.Error("Community ID should not be 0")
+	_, err = client.AddEntity("ent-short", "Short", "test", "Desc", []float32{0.1, 0.2, 0.3})
+	if !errors.Is(err, ErrServerError) || !strings.Contains(err.Error(), "embedding dimension mismatch: expected 64, got 3") {
+		t.Errorf("Expected dimension mismatch server error, got %v", err)
 	}
 }
 
-func TestClient_GetCommunity(t *testing.T) {
+func TestClient_GetNeighbors(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1082,27 +1434,34 @@ func TestClient_GetCommunity(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	embedding := make([]float32, 64)
-	communityID, err := client.AddCommunity("comm-001", "Test Community", "Summary", "Full content", 0, nil, nil, embedding)
+	ojk := mustAddEntity(t, client, "ent-ojk", "OJK", "regulator", "Financial services authority", nil)
+	bri := mustAddEntity(t, client, "ent-bri", "BRI", "bank", "State bank", nil)
+	bi := mustAddEntity(t, client, "ent-bi", "BI", "regulator", "Central bank", nil)
+	supervises := mustAddRelationship(t, client, "rel-1", ojk, bri, "SUPERVISES", "", 1.0)
+	mustAddRelationship(t, client, "rel-2", bi, ojk, "COORDINATES_WITH", "", 1.0)
+
+	rels, err := client.GetNeighbors(ojk, types.DirectionOutgoing, []string{"SUPERVISES"})
 	if err != nil {
-		t.Fatalf("AddCommunity failed: %v", err)
+		t.Fatalf("GetNeighbors failed: %v", err)
+	}
+	if len(rels) != 1 || rels[0].ID != supervises || rels[0].TargetID != bri {
+		t.Errorf("Expected the SUPERVISES edge to BRI, got %+v", rels)
 	}
 
-	community, err := client.GetCommunity(communityID)
+	rels, err = client.GetNeighbors(ojk, types.DirectionBoth, nil)
 	if err != nil {
-		t.Fatalf("GetCommunity failed: %v", err)
+		t.Fatalf("GetNeighbors failed: %v", err)
 	}
-
-	if community == nil {
-		t.Fatal("Community should not be nil")
+	if len(rels) != 2 {
+		t.Errorf("Expected 2 relationships, got %d", len(rels))
 	}
 
-	if community.Title != "Test Community" {
-		t.Errorf("Title = %q, want %q", community.Title, "Test Community")
+	if _, err := client.GetNeighbors(99999, types.DirectionBoth, nil); err == nil {
+		t.Error("Expected error for unknown entity")
 	}
 }
 
-func TestClient_DeleteCommunity(t *testing.T) {
+func TestClient_MergeEntities(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1112,25 +1471,32 @@ func TestClient_DeleteCommunity(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	embedding := make([]float32, 64)
-	communityID, err := client.AddCommunity("comm-del", "Delete Me", "Summary", "Full content", 0, nil, nil, embedding)
-	if err != nil {
-		t.Fatalf("AddCommunity failed: %v", err)
+	keep := mustAddEntity(t, client, "ent-bri", "BRI", "organization", "State bank", nil)
+	dup := mustAddEntity(t, client, "ent-bank-rakyat", "Bank Rakyat Indonesia", "organization", "", nil)
+	bi := mustAddEntity(t, client, "ent-bi", "BI", "regulator", "Central bank", nil)
+	rel := mustAddRelationship(t, client, "rel-1", dup, bi, "REPORTS_TO", "", 1.0)
+
+	if err := client.MergeEntities(keep, dup); err != nil {
+		t.Fatalf("MergeEntities failed: %v", err)
 	}
 
-	err = client.DeleteCommunity(communityID)
+	if _, err := client.GetEntity(dup); err == nil {
+		t.Error("Merged entity should be deleted")
+	}
+	moved, err := client.GetRelationship(rel)
 	if err != nil {
-		t.Errorf("DeleteCommunity failed: %v", err)
+		t.Fatalf("GetRelationship failed: %v", err)
+	}
+	if moved.SourceID != keep {
+		t.Errorf("Expected relationship source %d, got %d", keep, moved.SourceID)
 	}
 
-	// Try to get the deleted community - should fail
-	_, err = client.GetCommunity(communityID)
-	if err == nil {
-		t.Error("Expected error getting deleted community")
+	if err := client.MergeEntities(keep, 99999); err == nil {
+		t.Error("Expected error for unknown entity")
 	}
 }
 
-func TestClient_ComputeCommunities(t *testing.T) {
+func TestClient_UpdateEntityTitle(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1140,27 +1506,29 @@ func TestClient_ComputeCommunities(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add some entities and relationships for community detection
-	embedding := make([]float32, 64)
-	ent1ID := mustAddEntity(t, client, "ent-a", "Entity A", "test", "Desc", embedding)
-	ent2ID := mustAddEntity(t, client, "ent-b", "Entity B", "test", "Desc", embedding)
-	ent3ID := mustAddEntity(t, client, "ent-c", "Entity C", "test", "Desc", embedding)
-	mustAddRelationship(t, client, "rel-ab", ent1ID, ent2ID, "RELATED", "Desc", 1.0)
-	mustAddRelationship(t, client, "rel-bc", ent2ID, ent3ID, "RELATED", "Desc", 1.0)
+	bri := mustAddEntity(t, client, "ent-bri", "BRI", "organization", "State bank", nil)
+	mustAddEntity(t, client, "ent-bi", "BI", "regulator", "Central bank", nil)
 
-	result, err := client.ComputeCommunities(1.0, 10)
+	if err := client.UpdateEntityTitle(bri, "Bank Rakyat Indonesia"); err != nil {
+		t.Fatalf("UpdateEntityTitle failed: %v", err)
+	}
+	if _, err := client.GetEntityByTitle("BRI"); err == nil {
+		t.Error("Old title should no longer resolve")
+	}
+	ent, err := client.GetEntityByTitle("Bank Rakyat Indonesia")
 	if err != nil {
-		t.Fatalf("ComputeCommunities failed: %v", err)
+		t.Fatalf("GetEntityByTitle failed: %v", err)
 	}
-
-	if result == nil {
-		t.Fatal("Result should not be nil")
+	if ent.ID != bri {
+		t.Errorf("Expected entity %d, got %d", bri, ent.ID)
 	}
 
-	t.Logf("Computed %d communities", result.Count)
+	if err := client.UpdateEntityTitle(bri, "BI"); err == nil {
+		t.Error("Expected conflict renaming onto an existing title")
+	}
 }
 
-func TestClient_HierarchicalLeiden(t *testing.T) {
+func TestClient_UpdateRelationshipWeight(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1170,27 +1538,44 @@ func TestClient_HierarchicalLeiden(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add some entities and relationships for community detection
-	embedding := make([]float32, 64)
-	ent1ID := mustAddEntity(t, client, "ent-h1", "Entity H1", "test", "Desc", embedding)
-	ent2ID := mustAddEntity(t, client, "ent-h2", "Entity H2", "test", "Desc", embedding)
-	ent3ID := mustAddEntity(t, client, "ent-h3", "Entity H3", "test", "Desc", embedding)
-	mustAddRelationship(t, client, "rel-h12", ent1ID, ent2ID, "RELATED", "Desc", 1.0)
-	mustAddRelationship(t, client, "rel-h23", ent2ID, ent3ID, "RELATED", "Desc", 1.0)
+	ojk := mustAddEntity(t, client, "ent-ojk", "OJK", "regulator", "", nil)
+	bri := mustAddEntity(t, client, "ent-bri", "BRI", "organization", "", nil)
+	rel := mustAddRelationship(t, client, "rel-1", ojk, bri, "SUPERVISES", "", 1.0)
 
-	result, err := client.HierarchicalLeiden(3, 1.0)
+	if err := client.UpdateRelationshipWeight(rel, 2.5); err != nil {
+		t.Fatalf("UpdateRelationshipWeight failed: %v", err)
+	}
+	got, err := client.GetRelationship(rel)
 	if err != nil {
-		t.Fatalf("HierarchicalLeiden failed: %v", err)
+		t.Fatalf("GetRelationship failed: %v", err)
+	}
+	if got.Weight != 2.5 {
+		t.Errorf("Expected weight 2.5, got %v", got.Weight)
 	}
 
-	if result == nil {
-		t.Fatal("Result should not be nil")
+	sessions, err := client.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	found := false
+	for _, sess := range sessions {
+		if sess.ID == testSessionID {
+			found = true
+			if !sess.CommunitiesDirty {
+				t.Error("Session communities should be dirty after a weight change")
+			}
+		}
+	}
+	if !found {
+		t.Errorf("ListSessions() did not include %s", testSessionID)
 	}
 
-	t.Logf("Hierarchical Leiden found %d total communities", result.TotalCommunities)
+	if err := client.UpdateRelationshipWeight(rel, -1); err == nil {
+		t.Error("Expected error for negative weight")
+	}
 }
 
-func TestClient_HierarchicalLeidenDefault(t *testing.T) {
+func TestClient_EntityMetadata(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1200,48 +1585,53 @@ func TestClient_HierarchicalLeidenDefault(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add some entities and relationships
 	embedding := make([]float32, 64)
-	ent1ID := mustAddEntity(t, client, "ent-d1", "Entity D1", "test", "Desc", embedding)
-	ent2ID := mustAddEntity(t, client, "ent-d2", "Entity D2", "test", "Desc", embedding)
-	mustAddRelationship(t, client, "rel-d12", ent1ID, ent2ID, "RELATED", "Desc", 1.0)
+	embedding[0] = 1
 
-	result, err := client.HierarchicalLeidenDefault()
+	bri, err := client.AddEntityWithMetadata("ent-bri", "BRI", "organization", "State bank", embedding, map[string]string{"ticker": "BBRI", "founded": "1895"})
 	if err != nil {
-		t.Fatalf("HierarchicalLeidenDefault failed: %v", err)
+		t.Fatalf("AddEntityWithMetadata failed: %v", err)
 	}
-
-	if result == nil {
-		t.Fatal("Result should not be nil")
+	if _, err := client.AddEntityWithMetadata("ent-bca", "BCA", "organization", "Private bank", embedding, map[string]string{"ticker": "BBCA"}); err != nil {
+		t.Fatalf("AddEntityWithMetadata failed: %v", err)
 	}
-}
 
-// =============================================================================
-// Client Operation Tests - Pool Stats
-// =============================================================================
-
-func TestClient_PoolStats(t *testing.T) {
-	ts := startTestServer(t)
-	defer ts.Stop()
+	ent, err := client.GetEntity(bri)
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	if ent.Metadata["ticker"] != "BBRI" || ent.Metadata["founded"] != "1895" {
+		t.Errorf("Unexpected metadata %v", ent.Metadata)
+	}
 
-	client, err := NewClient(ts.addr, testSessionID)
+	if err := client.UpdateEntityWithMetadata(bri, "State-owned bank", nil, map[string]string{"founded": ""}); err != nil {
+		t.Fatalf("UpdateEntityWithMetadata failed: %v", err)
+	}
+	ent, err = client.GetEntity(bri)
 	if err != nil {
-		t.Fatalf("Failed to create client: %v", err)
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	if _, ok := ent.Metadata["founded"]; ok || ent.Description != "State-owned bank" {
+		t.Errorf("Update not applied: %q %v", ent.Description, ent.Metadata)
 	}
-	defer closeClient(t, client)
 
-	active, available := client.PoolStats()
-	if active < 0 {
-		t.Errorf("Active connections should not be negative: %d", active)
+	spec := types.QuerySpec{
+		QueryVector:     embedding,
+		TopK:            5,
+		MaxEntities:     10,
+		SearchTypes:     []types.SearchType{types.SearchTypeEntity},
+		MetadataFilters: map[string]string{"ticker": "BBRI"},
+	}
+	result, err := client.Query(spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ExternalID != "ent-bri" {
+		t.Errorf("Expected only BRI, got %d entities", len(result.Entities))
 	}
-	t.Logf("Pool stats: active=%d, available=%d", active, available)
 }
 
-// =============================================================================
-// Client Operation Tests - IdleTTL
-// =============================================================================
-
-func TestClient_SetIdleTTL(t *testing.T) {
+func TestClient_Subgraph(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1251,26 +1641,30 @@ func TestClient_SetIdleTTL(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add entity
-	embedding := make([]float32, 64)
-	entityID, err := client.AddEntity("ent-idle", "Idle Test", "test", "Desc", embedding)
+	bi := mustAddEntity(t, client, "ent-bi", "Bank Indonesia", "organization", "Central bank", nil)
+	ojk := mustAddEntity(t, client, "ent-ojk", "OJK", "organization", "Regulator", nil)
+	bri := mustAddEntity(t, client, "ent-bri", "BRI", "organization", "State bank", nil)
+	mustAddRelationship(t, client, "rel-1", bi, ojk, "COORDINATES_WITH", "", 1.0)
+	mustAddRelationship(t, client, "rel-2", ojk, bri, "SUPERVISES", "", 1.0)
+
+	ents, rels, err := client.Subgraph([]uint64{bi}, 1, 0)
 	if err != nil {
-		t.Fatalf("AddEntity failed: %v", err)
+		t.Fatalf("Subgraph failed: %v", err)
+	}
+	if len(ents) != 2 || len(rels) != 1 {
+		t.Errorf("Expected 2 entities and 1 relationship, got %d and %d", len(ents), len(rels))
 	}
 
-	// DEPRECATED: Session-based architecture - idle TTL managed at session level
-	_ = entityID
-	// err = client.SetIdleTTL("entity", entityID, 7200)
-	// if err != nil {
-	//	t.Errorf("SetIdleTTL failed: %v", err)
-	// }
+	ents, rels, err = client.Subgraph([]uint64{bi}, 2, 0)
+	if err != nil {
+		t.Fatalf("Subgraph failed: %v", err)
+	}
+	if len(ents) != 3 || len(rels) != 2 {
+		t.Errorf("Expected 3 entities and 2 relationships, got %d and %d", len(ents), len(rels))
+	}
 }
 
-// =============================================================================
-// Client Operation Tests - Explain
-// =============================================================================
-
-func TestClient_Explain(t *testing.T) {
+func TestClient_Query_EntityTypes(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1280,44 +1674,53 @@ func TestClient_Explain(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Add data first
 	embedding := make([]float32, 64)
 	for i := range embedding {
 		embedding[i] = float32(i) / 64.0
 	}
-	mustAddEntity(t, client, "ent-exp", "Explain Entity", "test", "Desc", embedding)
+	other := make([]float32, 64)
+	other[0] = 1
+
+	alice := mustAddEntity(t, client, "ent-alice", "Alice", "person", "Analyst", embedding)
+	acme := mustAddEntity(t, client, "ent-acme", "Acme", "organization", "Bank", other)
+	risk := mustAddEntity(t, client, "ent-risk", "Credit Risk", "concept", "Topic", other)
+	mustAddEntity(t, client, "ent-globex", "Globex", "organization", "Fund", embedding)
+	mustAddRelationship(t, client, "rel-1", alice, acme, "WORKS_AT", "", 1.0)
+	mustAddRelationship(t, client, "rel-2", alice, risk, "STUDIES", "", 1.0)
 
-	// First run a query
 	spec := types.QuerySpec{
 		QueryVector:    embedding,
-		TopK:           5,
-		KHops:          0,
+		TopK:           2,
+		KHops:          1,
 		MaxTextUnits:   10,
 		MaxEntities:    10,
 		MaxCommunities: 5,
 		SearchTypes:    []types.SearchType{types.SearchTypeEntity},
+		EntityTypes:    []string{"organization"},
 	}
 	result, err := client.Query(spec)
 	if err != nil {
 		t.Fatalf("Query failed: %v", err)
 	}
 
-	// Explain the query
-	explanation, err := client.Explain(result.QueryID)
-	if err != nil {
-		t.Logf("Explain failed (may be expected): %v", err)
-	} else {
-		if explanation == nil {
-			t.Error("Explanation should not be nil")
+	// Globex is a seed, Acme is reached through the (filtered) person seed
+	found := make(map[string]bool)
+	for _, er := range result.Entities {
+		if er.Entity.Type != "organization" {
+			t.Errorf("Unexpected %s entity %q in results", er.Entity.Type, er.Entity.Title)
 		}
+		found[er.Entity.ExternalID] = true
+	}
+	if len(found) != 2 || !found["ent-acme"] || !found["ent-globex"] {
+		t.Errorf("Expected Acme and Globex, got %v", found)
 	}
 }
 
 // =============================================================================
-// Client Operation Tests - Bulk Operations (MSet/MGet)
+// Client Operation Tests - TTL
 // =============================================================================
 
-func TestClient_MSetEntities(t *testing.T) {
+func TestClient_SetTTL(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1327,24 +1730,19 @@ func TestClient_MSetEntities(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	embedding := make([]float32, 64)
-	entities := []types.BulkEntityInput{
-		{ExternalID: "bulk-ent-1", Title: "Bulk Entity 1", Type: "test", Description: "Desc 1", Embedding: embedding},
-		{ExternalID: "bulk-ent-2", Title: "Bulk Entity 2", Type: "test", Description: "Desc 2", Embedding: embedding},
-		{ExternalID: "bulk-ent-3", Title: "Bulk Entity 3", Type: "test", Description: "Desc 3", Embedding: embedding},
-	}
-
-	ids, err := client.MSetEntities(entities)
-	if err != nil {
-		t.Fatalf("MSetEntities failed: %v", err)
-	}
+	docID := mustAddDocument(t, client, "doc-001", "test.pdf")
 
-	if len(ids) != 3 {
-		t.Errorf("Expected 3 IDs, got %d", len(ids))
-	}
+	// DEPRECATED: Session-based architecture - TTL managed at session level
+	_ = docID
+	// err = client.SetTTL("document", docID, 3600)
+	// if err != nil {
+	//	t.Errorf("SetTTL failed: %v", err)
+	// }
 }
 
-func TestClient_MGetEntities(t *testing.T) {
+// DEPRECATED: Session-based architecture - TTL managed at session level
+/*
+func TestClient_GetTTL(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1354,21 +1752,36 @@ func TestClient_MGetEntities(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	embedding := make([]float32, 64)
-	ent1ID := mustAddEntity(t, client, "mget-ent-1", "MGet Entity 1", "test", "Desc", embedding)
-	ent2ID := mustAddEntity(t, client, "mget-ent-2", "MGet Entity 2", "test", "Desc", embedding)
+	docID := mustAddDocument(t, client, "doc-001", "test.pdf")
+	// client.SetTTL("document", docID, 3600)
 
-	entities, err := client.MGetEntities([]uint64{ent1ID, ent2ID})
-	if err != nil {
-		t.Fatalf("MGetEntities failed: %v", err)
-	}
+	_ = docID
+	// ttl, err := client.GetTTL("document", docID)
+	// if err != nil {
+	//	t.Fatalf("GetTTL failed: %v", err)
+	// }
 
-	if len(entities) != 2 {
-		t.Errorf("Expected 2 entities, got %d", len(entities))
+	// if ttl <= 0 {
+	//	t.Error("TTL should be positive after setting")
+	// }
+}
+*/
+
+// =============================================================================
+// Wire Protocol Tests
+// =============================================================================
+
+func TestCodecType(t *testing.T) {
+	if codec.CodecProtobuf != 1 {
+		t.Errorf("CodecProtobuf = %d, want 1", codec.CodecProtobuf)
 	}
 }
 
-func TestClient_MSetDocuments(t *testing.T) {
+// =============================================================================
+// Integration Tests - Full Workflow
+// =============================================================================
+
+func TestClient_FullWorkflow_ChatWithPDF(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1378,45 +1791,74 @@ func TestClient_MSetDocuments(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	docs := []types.BulkDocumentInput{
-		{ExternalID: "bulk-doc-1", Filename: "file1.pdf"},
-		{ExternalID: "bulk-doc-2", Filename: "file2.pdf"},
-	}
-
-	ids, err := client.MSetDocuments(docs)
+	// 1. Upload document
+	docID, err := client.AddDocument("pdf-001", "research_paper.pdf")
 	if err != nil {
-		t.Fatalf("MSetDocuments failed: %v", err)
+		t.Fatalf("Failed to add document: %v", err)
 	}
+	t.Logf("Created document with ID: %d", docID)
 
-	if len(ids) != 2 {
-		t.Errorf("Expected 2 IDs, got %d", len(ids))
+	// 2. Add text chunks
+	embedding := make([]float32, 64)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
 	}
-}
 
-func TestClient_MGetDocuments(t *testing.T) {
-	ts := startTestServer(t)
-	defer ts.Stop()
+	tu1ID := mustAddTextUnit(t, client, "chunk-1", docID, "Machine learning is...", embedding, 10)
+	tu2ID := mustAddTextUnit(t, client, "chunk-2", docID, "Neural networks are...", embedding, 10)
+	t.Logf("Created text units: %d, %d", tu1ID, tu2ID)
 
-	client, err := NewClient(ts.addr, testSessionID)
+	// 3. Add entities
+	ent1ID := mustAddEntity(t, client, "ent-ml", "Machine Learning", "concept", "ML description", embedding)
+	ent2ID := mustAddEntity(t, client, "ent-nn", "Neural Network", "concept", "NN description", embedding)
+	t.Logf("Created entities: %d, %d", ent1ID, ent2ID)
+
+	// 4. Add relationship
+	relID := mustAddRelationship(t, client, "rel-1", ent1ID, ent2ID, "USES", "ML uses NNs", 0.9)
+	t.Logf("Created relationship: %d", relID)
+
+	// 5. Link text units to entities
+	if err := client.LinkTextUnitToEntity(tu1ID, ent1ID); err != nil {
+		t.Fatalf("LinkTextUnitToEntity failed: %v", err)
+	}
+	if err := client.LinkTextUnitToEntity(tu2ID, ent2ID); err != nil {
+		t.Fatalf("LinkTextUnitToEntity failed: %v", err)
+	}
+
+	// 6. Query
+	spec := types.QuerySpec{
+		QueryVector:    embedding,
+		TopK:           5,
+		KHops:          1,
+		MaxTextUnits:   10,
+		MaxEntities:    10,
+		MaxCommunities: 5,
+		SearchTypes:    []types.SearchType{types.SearchTypeTextUnit, types.SearchTypeEntity},
+	}
+	result, err := client.Query(spec)
 	if err != nil {
-		t.Fatalf("Failed to create client: %v", err)
+		t.Fatalf("Query failed: %v", err)
 	}
-	defer closeClient(t, client)
 
-	doc1ID := mustAddDocument(t, client, "mget-doc-1", "file1.pdf")
-	doc2ID := mustAddDocument(t, client, "mget-doc-2", "file2.pdf")
+	t.Logf("Query returned %d text units, %d entities", len(result.TextUnits), len(result.Entities))
 
-	docs, err := client.MGetDocuments([]uint64{doc1ID, doc2ID})
+	// 7. Check info
+	info, err := client.Info()
 	if err != nil {
-		t.Fatalf("MGetDocuments failed: %v", err)
+		t.Fatalf("Info failed: %v", err)
 	}
-
-	if len(docs) != 2 {
-		t.Errorf("Expected 2 documents, got %d", len(docs))
+	if info.DocumentCount != 1 {
+		t.Errorf("DocumentCount = %d, want 1", info.DocumentCount)
+	}
+	if info.TextUnitCount != 2 {
+		t.Errorf("TextUnitCount = %d, want 2", info.TextUnitCount)
+	}
+	if info.EntityCount != 2 {
+		t.Errorf("EntityCount = %d, want 2", info.EntityCount)
 	}
 }
 
-func TestClient_MSetTextUnits(t *testing.T) {
+func TestClient_ConcurrentOperations(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1426,25 +1868,93 @@ func TestClient_MSetTextUnits(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	docID := mustAddDocument(t, client, "mset-tu-doc", "test.pdf")
-	embedding := make([]float32, 64)
-
-	tus := []types.BulkTextUnitInput{
-		{ExternalID: "bulk-tu-1", DocumentID: docID, Content: "Content 1", Embedding: embedding, TokenCount: 5},
-		{ExternalID: "bulk-tu-2", DocumentID: docID, Content: "Content 2", Embedding: embedding, TokenCount: 5},
+	// Server errors are not retried, so the session must exist before the
+	// queries race the first writes
+	if _, err := client.AddDocument("doc-seed", "seed.pdf"); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
 	}
 
-	ids, err := client.MSetTextUnits(tus)
-	if err != nil {
-		t.Fatalf("MSetTextUnits failed: %v", err)
-	}
+	var wg sync.WaitGroup
+	const numOps = 20
+	errCh := make(chan error, numOps*3)
 
-	if len(ids) != 2 {
-		t.Errorf("Expected 2 IDs, got %d", len(ids))
+	// Concurrent document additions
+	for i := 0; i < numOps; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if _, err := client.AddDocument("doc-"+itoa(id), "file.pdf"); err != nil {
+				errCh <- err
+			}
+		}(i)
+	}
+
+	// Concurrent entity additions
+	embedding := make([]float32, 64)
+	for i := 0; i < numOps; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if _, err := client.AddEntity("ent-"+itoa(id), "Entity "+itoa(id), "test", "Desc", embedding); err != nil {
+				errCh <- err
+			}
+		}(i)
+	}
+
+	// Concurrent queries
+	for i := 0; i < numOps/2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			spec := types.QuerySpec{
+				QueryVector:    embedding,
+				TopK:           5,
+				KHops:          0,
+				MaxTextUnits:   10,
+				MaxEntities:    10,
+				MaxCommunities: 5,
+				SearchTypes:    []types.SearchType{types.SearchTypeEntity},
+			}
+			if _, err := client.Query(spec); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		t.Errorf("Concurrent query error: %v", err)
+	}
+
+	// Verify final state
+	info, err := client.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
 	}
+	t.Logf("Final state: %d documents, %d entities", info.DocumentCount, info.EntityCount)
 }
 
-func TestClient_MGetTextUnits(t *testing.T) {
+// Helper for number to string
+func itoa(i int) string {
+	if i == 0 {
+		return "0"
+	}
+	var buf [20]byte
+	pos := len(buf)
+	for i > 0 {
+		pos--
+		buf[pos] = byte(i%10) + '0'
+		i /= 10
+	}
+	return string(buf[pos:])
+}
+
+// =============================================================================
+// Client Operation Tests - Delete Operations
+// =============================================================================
+
+func TestClient_DeleteDocument(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1454,22 +1964,26 @@ func TestClient_MGetTextUnits(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	docID := mustAddDocument(t, client, "mget-tu-doc", "test.pdf")
-	embedding := make([]float32, 64)
-	tu1ID := mustAddTextUnit(t, client, "mget-tu-1", docID, "Content 1", embedding, 5)
-	tu2ID := mustAddTextUnit(t, client, "mget-tu-2", docID, "Content 2", embedding, 5)
+	// Add document first
+	docID, err := client.AddDocument("doc-to-delete", "test.pdf")
+	if err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
 
-	tus, err := client.MGetTextUnits([]uint64{tu1ID, tu2ID})
+	// Delete it
+	err = client.DeleteDocument(docID)
 	if err != nil {
-		t.Fatalf("MGetTextUnits failed: %v", err)
+		t.Errorf("DeleteDocument failed: %v", err)
 	}
 
-	if len(tus) != 2 {
-		t.Errorf("Expected 2 text units, got %d", len(tus))
+	// Try to get the deleted document - should fail
+	_, err = client.GetDocument(docID)
+	if err == nil {
+		t.Error("Expected error getting deleted document")
 	}
 }
 
-func TestClient_MSetRelationships(t *testing.T) {
+func TestClient_DeleteTextUnit(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1479,27 +1993,28 @@ func TestClient_MSetRelationships(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
+	// Add document and text unit first
+	docID := mustAddDocument(t, client, "doc-001", "test.pdf")
 	embedding := make([]float32, 64)
-	ent1ID := mustAddEntity(t, client, "mset-rel-1", "Entity 1", "test", "Desc", embedding)
-	ent2ID := mustAddEntity(t, client, "mset-rel-2", "Entity 2", "test", "Desc", embedding)
-	ent3ID := mustAddEntity(t, client, "mset-rel-3", "Entity 3", "test", "Desc", embedding)
-
-	rels := []types.BulkRelationshipInput{
-		{ExternalID: "bulk-rel-1", SourceID: ent1ID, TargetID: ent2ID, Type: "RELATED", Description: "Desc", Weight: 1.0},
-		{ExternalID: "bulk-rel-2", SourceID: ent2ID, TargetID: ent3ID, Type: "RELATED", Description: "Desc", Weight: 1.0},
+	tuID, err := client.AddTextUnit("tu-to-delete", docID, "Content to delete", embedding, 5)
+	if err != nil {
+		t.Fatalf("AddTextUnit failed: %v", err)
 	}
 
-	ids, err := client.MSetRelationships(rels)
+	// Delete it
+	err = client.DeleteTextUnit(tuID)
 	if err != nil {
-		t.Fatalf("MSetRelationships failed: %v", err)
+		t.Errorf("DeleteTextUnit failed: %v", err)
 	}
 
-	if len(ids) != 2 {
-		t.Errorf("Expected 2 IDs, got %d", len(ids))
+	// Try to get the deleted text unit - should fail
+	_, err = client.GetTextUnit(tuID)
+	if err == nil {
+		t.Error("Expected error getting deleted text unit")
 	}
 }
 
-func TestClient_MGetRelationships(t *testing.T) {
+func TestClient_DeleteEntity(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1509,27 +2024,27 @@ func TestClient_MGetRelationships(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
+	// Add entity first
 	embedding := make([]float32, 64)
-	ent1ID := mustAddEntity(t, client, "mget-rel-1", "Entity 1", "test", "Desc", embedding)
-	ent2ID := mustAddEntity(t, client, "mget-rel-2", "Entity 2", "test", "Desc", embedding)
-	rel1ID := mustAddRelationship(t, client, "mget-rel-a", ent1ID, ent2ID, "TYPE_A", "Desc", 1.0)
-	rel2ID := mustAddRelationship(t, client, "mget-rel-b", ent2ID, ent1ID, "TYPE_B", "Desc", 1.0)
+	entityID, err := client.AddEntity("ent-to-delete", "Delete Test", "test", "Desc", embedding)
+	if err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
 
-	rels, err := client.MGetRelationships([]uint64{rel1ID, rel2ID})
+	// Delete it
+	err = client.DeleteEntity(entityID)
 	if err != nil {
-		t.Fatalf("MGetRelationships failed: %v", err)
+		t.Errorf("DeleteEntity failed: %v", err)
 	}
 
-	if len(rels) != 2 {
-		t.Errorf("Expected 2 relationships, got %d", len(rels))
+	// Try to get the deleted entity - should fail
+	_, err = client.GetEntity(entityID)
+	if err == nil {
+		t.Error("Expected error getting deleted entity")
 	}
 }
 
-// =============================================================================
-// Client Operation Tests - Backup Operations
-// =============================================================================
-
-func TestClient_BGSave(t *testing.T) {
+func TestClient_DeleteRelationship(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1539,14 +2054,29 @@ func TestClient_BGSave(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// BGSave starts a background save to a path
-	err = client.BGSave("/tmp/gibram_test_save")
+	// Add entities and relationship first
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "ent-1", "Entity 1", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "ent-2", "Entity 2", "test", "Desc", embedding)
+	relID, err := client.AddRelationship("rel-to-delete", ent1ID, ent2ID, "RELATED", "Desc", 1.0)
 	if err != nil {
-		t.Logf("BGSave failed (may be expected if datadir not configured): %v", err)
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+
+	// Delete it
+	err = client.DeleteRelationship(relID)
+	if err != nil {
+		t.Errorf("DeleteRelationship failed: %v", err)
+	}
+
+	// Try to get the deleted relationship - should fail
+	_, err = client.GetRelationship(relID)
+	if err == nil {
+		t.Error("Expected error getting deleted relationship")
 	}
 }
 
-func TestClient_Save(t *testing.T) {
+func TestClient_UpdateEntityDescription(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1556,14 +2086,39 @@ func TestClient_Save(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Save performs a blocking save to a path
-	err = client.Save("/tmp/gibram_test_save")
+	// Add entity first
+	embedding := make([]float32, 64)
+	entityID, err := client.AddEntity("ent-update", "Update Test", "test", "Original desc", embedding)
 	if err != nil {
-		t.Logf("Save failed (may be expected if datadir not configured): %v", err)
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+
+	// Update the description with new embedding
+	newEmbedding := make([]float32, 64)
+	for i := range newEmbedding {
+		newEmbedding[i] = 0.5
+	}
+	err = client.UpdateEntityDescription(entityID, "Updated description", newEmbedding)
+	if err != nil {
+		t.Errorf("UpdateEntityDescription failed: %v", err)
+	}
+
+	// Verify the update
+	entity, err := client.GetEntity(entityID)
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+
+	if entity.Description != "Updated description" {
+		t.Errorf("Description = %q, want %q", entity.Description, "Updated description")
 	}
 }
 
-func TestClient_LastSave(t *testing.T) {
+// =============================================================================
+// Client Operation Tests - Community Operations
+// =============================================================================
+
+func TestClient_AddCommunity(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1573,15 +2128,18 @@ func TestClient_LastSave(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	info, err := client.LastSave()
+	embedding := make([]float32, 64)
+	communityID, err := client.AddCommunity("comm-001", "Test Community", "Summary of the community", "Full content", 0, nil, nil, embedding)
 	if err != nil {
-		t.Logf("LastSave failed (may be expected if never saved): %v", err)
-	} else {
-		t.Logf("Last save at %d", info.Timestamp)
+		t.Fatalf("AddCommunity failed: %v", err)
+	}
+
+	if communityID == 0 {
+		t.Error("Community ID should not be 0")
 	}
 }
 
-func TestClient_BGRestore(t *testing.T) {
+func TestClient_GetCommunity(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1591,14 +2149,27 @@ func TestClient_BGRestore(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// BGRestore tries to restore from a path
-	err = client.BGRestore("/nonexistent/path")
+	embedding := make([]float32, 64)
+	communityID, err := client.AddCommunity("comm-001", "Test Community", "Summary", "Full content", 0, nil, nil, embedding)
 	if err != nil {
-		t.Logf("BGRestore failed (expected for nonexistent path): %v", err)
+		t.Fatalf("AddCommunity failed: %v", err)
+	}
+
+	community, err := client.GetCommunity(communityID)
+	if err != nil {
+		t.Fatalf("GetCommunity failed: %v", err)
+	}
+
+	if community == nil {
+		t.Fatal("Community should not be nil")
+	}
+
+	if community.Title != "Test Community" {
+		t.Errorf("Title = %q, want %q", community.Title, "Test Community")
 	}
 }
 
-func TestClient_BackupStatus(t *testing.T) {
+func TestClient_DeleteCommunity(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1608,117 +2179,98 @@ func TestClient_BackupStatus(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	status, err := client.BackupStatus()
+	embedding := make([]float32, 64)
+	communityID, err := client.AddCommunity("comm-del", "Delete Me", "Summary", "Full content", 0, nil, nil, embedding)
 	if err != nil {
-		t.Logf("BackupStatus failed (may be expected): %v", err)
-	} else {
-		t.Logf("Backup status: in_progress=%v, type=%s", status.InProgress, status.Type)
+		t.Fatalf("AddCommunity failed: %v", err)
 	}
-}
 
-// =============================================================================
-// Client Operation Tests - Connection Pool Edge Cases
-// =============================================================================
+	err = client.DeleteCommunity(communityID)
+	if err != nil {
+		t.Errorf("DeleteCommunity failed: %v", err)
+	}
 
-func TestConnPool_MultipleClients(t *testing.T) {
-	ts := startTestServer(t)
-	defer ts.Stop()
+	// Try to get the deleted community - should fail
+	_, err = client.GetCommunity(communityID)
+	if err == nil {
+		t.Error("Expected error getting deleted community")
+	}
+}
 
-	// Create multiple clients to test pool management
-	cfg := DefaultPoolConfig()
-	cfg.MaxConnections = 3
+func TestClient_ComputeCommunities(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
 
-	pool, err := NewConnPool(ts.addr, cfg)
+	client, err := NewClient(ts.addr, testSessionID)
 	if err != nil {
-		t.Fatalf("Failed to create pool: %v", err)
+		t.Fatalf("Failed to create client: %v", err)
 	}
-	defer pool.Close()
+	defer closeClient(t, client)
 
-	// Perform multiple concurrent operations to test pool usage
-	var wg sync.WaitGroup
-	errCh := make(chan error, 10)
-	for i := 0; i < 10; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			// Use pool via Client
-			client := &Client{pool: pool}
-			if err := client.Ping(); err != nil {
-				errCh <- err
-			}
-		}()
+	// Add some entities and relationships for community detection
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "ent-a", "Entity A", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "ent-b", "Entity B", "test", "Desc", embedding)
+	ent3ID := mustAddEntity(t, client, "ent-c", "Entity C", "test", "Desc", embedding)
+	mustAddRelationship(t, client, "rel-ab", ent1ID, ent2ID, "RELATED", "Desc", 1.0)
+	mustAddRelationship(t, client, "rel-bc", ent2ID, ent3ID, "RELATED", "Desc", 1.0)
+
+	result, err := client.ComputeCommunities(1.0, 10)
+	if err != nil {
+		t.Fatalf("ComputeCommunities failed: %v", err)
 	}
-	wg.Wait()
-	close(errCh)
-	for err := range errCh {
-		t.Errorf("Ping failed: %v", err)
+
+	if result == nil {
+		t.Fatal("Result should not be nil")
 	}
 
-	active, available := pool.Stats()
-	t.Logf("After concurrent ops: active=%d, available=%d", active, available)
+	t.Logf("Computed %d communities (modularity %.4f)", result.Count, result.Modularity)
 }
 
-func TestConnPool_IdleConnectionCleanup(t *testing.T) {
+func TestClient_ComputeCommunitiesWithAlgorithm(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
-	// Create pool with short idle timeout
-	cfg := DefaultPoolConfig()
-	cfg.IdleTimeout = 100 * time.Millisecond
-
-	pool, err := NewConnPool(ts.addr, cfg)
+	client, err := NewClient(ts.addr, testSessionID)
 	if err != nil {
-		t.Fatalf("Failed to create pool: %v", err)
+		t.Fatalf("Failed to create client: %v", err)
 	}
-	defer pool.Close()
+	defer closeClient(t, client)
 
-	// Do something to create a connection
-	client := &Client{pool: pool}
-	if err := client.Ping(); err != nil {
-		t.Fatalf("Ping failed: %v", err)
+	// Two triangles joined by a single edge
+	embedding := make([]float32, 64)
+	ids := make([]uint64, 6)
+	for i := range ids {
+		ids[i] = mustAddEntity(t, client, fmt.Sprintf("ent-lv%d", i), fmt.Sprintf("Louvain %d", i), "test", "Desc", embedding)
 	}
-
-	// Wait for idle connection to expire
-	time.Sleep(150 * time.Millisecond)
-
-	active, _ := pool.Stats()
-	t.Logf("After idle wait: active=%d", active)
-}
-
-func TestClient_WithTLS(t *testing.T) {
-	// Test TLS config creation (without actual TLS server)
-	cfg := DefaultPoolConfig()
-	cfg.TLSEnabled = true
-	cfg.TLSSkipVerify = true
-	cfg.ConnTimeout = 100 * time.Millisecond
-
-	// This will fail to connect but tests the TLS path
-	_, err := NewConnPool("127.0.0.1:59999", cfg)
-	if err == nil {
-		t.Error("Expected error connecting to invalid TLS endpoint")
+	edges := [][2]int{{0, 1}, {1, 2}, {0, 2}, {3, 4}, {4, 5}, {3, 5}, {2, 3}}
+	for i, e := range edges {
+		mustAddRelationship(t, client, fmt.Sprintf("rel-lv%d", i), ids[e[0]], ids[e[1]], "RELATED", "Desc", 1.0)
 	}
-}
 
-func TestClient_WithAPIKey(t *testing.T) {
-	// Test API key config (requires server with auth)
-	cfg := DefaultPoolConfig()
-	cfg.APIKey = "test-api-key"
-	cfg.ConnTimeout = 100 * time.Millisecond
+	for _, algorithm := range []string{"leiden", "louvain"} {
+		result, err := client.ComputeCommunitiesWithAlgorithm(algorithm, 1.0, 10)
+		if err != nil {
+			t.Fatalf("ComputeCommunitiesWithAlgorithm(%q) failed: %v", algorithm, err)
+		}
+		seen := 0
+		for _, comm := range result.Communities {
+			seen += len(comm.EntityIDs)
+		}
+		if seen != len(ids) {
+			t.Errorf("%s: communities cover %d entities, want %d", algorithm, seen, len(ids))
+		}
+		if result.Modularity <= 0 {
+			t.Errorf("%s: modularity = %v, want > 0", algorithm, result.Modularity)
+		}
+	}
 
-	// This will fail but tests the API key config path
-	_, err := NewConnPool("127.0.0.1:59998", cfg)
-	if err == nil {
-		t.Error("Expected error connecting to invalid endpoint")
+	if _, err := client.ComputeCommunitiesWithAlgorithm("spectral", 1.0, 10); err == nil {
+		t.Error("expected error for unknown algorithm")
 	}
 }
 
-// =============================================================================
-// Wire Protocol Tests
-// =============================================================================
-
-func TestWriteEnvelope(t *testing.T) {
-	// Test envelope serialization - this is internal but affects coverage
-	// We can test it indirectly through client operations
+func TestClient_GetEntityCommunities(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1728,34 +2280,42 @@ func TestWriteEnvelope(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// This exercises writeEnvelope and readEnvelope
-	if err := client.Ping(); err != nil {
-		t.Errorf("Ping failed: %v", err)
-	}
-}
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "ent-m1", "Member 1", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "ent-m2", "Member 2", "test", "Desc", embedding)
+	loneID := mustAddEntity(t, client, "ent-m3", "Loner", "test", "Desc", embedding)
 
-// =============================================================================
-// Error Handling Tests
-// =============================================================================
+	low, err := client.AddCommunity("comm-m0", "Low", "Summary", "Content", 0, []uint64{ent1ID, ent2ID}, nil, embedding)
+	if err != nil {
+		t.Fatalf("AddCommunity failed: %v", err)
+	}
+	high, err := client.AddCommunity("comm-m1", "High", "Summary", "Content", 1, []uint64{ent1ID}, nil, embedding)
+	if err != nil {
+		t.Fatalf("AddCommunity failed: %v", err)
+	}
 
-func TestClient_ErrorHandling_InvalidEntity(t *testing.T) {
-	ts := startTestServer(t)
-	defer ts.Stop()
+	got, err := client.GetEntityCommunities(ent1ID)
+	if err != nil {
+		t.Fatalf("GetEntityCommunities failed: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != low || got[1].ID != high {
+		t.Errorf("Expected communities [%d %d] by level, got %v", low, high, got)
+	}
 
-	client, err := NewClient(ts.addr, testSessionID)
+	got, err = client.GetEntityCommunities(loneID)
 	if err != nil {
-		t.Fatalf("Failed to create client: %v", err)
+		t.Fatalf("GetEntityCommunities failed: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Expected no communities, got %d", len(got))
 	}
-	defer closeClient(t, client)
 
-	// Try to get non-existent entity
-	_, err = client.GetEntity(999999999)
-	if err == nil {
-		t.Error("Expected error for non-existent entity")
+	if _, err := client.GetEntityCommunities(99999); err == nil {
+		t.Error("Expected error for unknown entity")
 	}
 }
 
-func TestClient_ErrorHandling_InvalidRelationship(t *testing.T) {
+func TestClient_ComputePageRank(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1765,31 +2325,36 @@ func TestClient_ErrorHandling_InvalidRelationship(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Try to get non-existent relationship
-	_, err = client.GetRelationship(999999999)
-	if err == nil {
-		t.Error("Expected error for non-existent relationship")
+	embedding := make([]float32, 64)
+	hubID := mustAddEntity(t, client, "ent-pr-hub", "PageRank Hub", "test", "Desc", embedding)
+	for i := 0; i < 3; i++ {
+		spokeID := mustAddEntity(t, client, fmt.Sprintf("ent-pr-%d", i), fmt.Sprintf("PageRank Spoke %d", i), "test", "Desc", embedding)
+		mustAddRelationship(t, client, fmt.Sprintf("rel-pr-%d", i), spokeID, hubID, "RELATED", "Desc", 1.0)
 	}
-}
-
-func TestClient_ErrorHandling_InvalidTextUnit(t *testing.T) {
-	ts := startTestServer(t)
-	defer ts.Stop()
 
-	client, err := NewClient(ts.addr, testSessionID)
+	scores, err := client.ComputePageRank()
 	if err != nil {
-		t.Fatalf("Failed to create client: %v", err)
+		t.Fatalf("ComputePageRank failed: %v", err)
+	}
+	if len(scores) != 4 {
+		t.Fatalf("Expected 4 scores, got %d", len(scores))
 	}
-	defer closeClient(t, client)
 
-	// Try to get non-existent text unit
-	_, err = client.GetTextUnit(999999999)
-	if err == nil {
-		t.Error("Expected error for non-existent text unit")
+	ent, err := client.GetEntity(hubID)
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	if ent.PageRank != scores[hubID] {
+		t.Errorf("Entity PageRank = %v, want %v", ent.PageRank, scores[hubID])
+	}
+	for id, score := range scores {
+		if id != hubID && score >= scores[hubID] {
+			t.Errorf("Spoke %d score %v should be below hub %v", id, score, scores[hubID])
+		}
 	}
 }
 
-func TestClient_ErrorHandling_InvalidCommunity(t *testing.T) {
+func TestClient_HierarchicalLeiden(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1799,14 +2364,27 @@ func TestClient_ErrorHandling_InvalidCommunity(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Try to get non-existent community
-	_, err = client.GetCommunity(999999999)
-	if err == nil {
-		t.Error("Expected error for non-existent community")
+	// Add some entities and relationships for community detection
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "ent-h1", "Entity H1", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "ent-h2", "Entity H2", "test", "Desc", embedding)
+	ent3ID := mustAddEntity(t, client, "ent-h3", "Entity H3", "test", "Desc", embedding)
+	mustAddRelationship(t, client, "rel-h12", ent1ID, ent2ID, "RELATED", "Desc", 1.0)
+	mustAddRelationship(t, client, "rel-h23", ent2ID, ent3ID, "RELATED", "Desc", 1.0)
+
+	result, err := client.HierarchicalLeiden(3, 1.0)
+	if err != nil {
+		t.Fatalf("HierarchicalLeiden failed: %v", err)
 	}
+
+	if result == nil {
+		t.Fatal("Result should not be nil")
+	}
+
+	t.Logf("Hierarchical Leiden found %d total communities", result.TotalCommunities)
 }
 
-func TestClient_MGetEntities_EmptyIDs(t *testing.T) {
+func TestClient_HierarchicalLeidenDefault(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1816,15 +2394,27 @@ func TestClient_MGetEntities_EmptyIDs(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	// Get with empty IDs
-	entities, err := client.MGetEntities([]uint64{})
+	// Add some entities and relationships
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "ent-d1", "Entity D1", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "ent-d2", "Entity D2", "test", "Desc", embedding)
+	mustAddRelationship(t, client, "rel-d12", ent1ID, ent2ID, "RELATED", "Desc", 1.0)
+
+	result, err := client.HierarchicalLeidenDefault()
 	if err != nil {
-		t.Logf("MGetEntities with empty IDs: %v", err)
+		t.Fatalf("HierarchicalLeidenDefault failed: %v", err)
+	}
+
+	if result == nil {
+		t.Fatal("Result should not be nil")
 	}
-	t.Logf("Returned %d entities for empty IDs", len(entities))
 }
 
-func TestClient_MGetDocuments_EmptyIDs(t *testing.T) {
+// =============================================================================
+// Client Operation Tests - Pool Stats
+// =============================================================================
+
+func TestClient_PoolStats(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1834,18 +2424,18 @@ func TestClient_MGetDocuments_EmptyIDs(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	docs, err := client.MGetDocuments([]uint64{})
-	if err != nil {
-		t.Logf("MGetDocuments with empty IDs: %v", err)
+	active, available := client.PoolStats()
+	if active < 0 {
+		t.Errorf("Active connections should not be negative: %d", active)
 	}
-	t.Logf("Returned %d documents for empty IDs", len(docs))
+	t.Logf("Pool stats: active=%d, available=%d", active, available)
 }
 
 // =============================================================================
-// Additional Entity Operations
+// Client Operation Tests - IdleTTL
 // =============================================================================
 
-func TestClient_GetEntityByTitle_NotFound(t *testing.T) {
+func TestClient_SetIdleTTL(t *testing.T) {
 	ts := startTestServer(t)
 	defer ts.Stop()
 
@@ -1855,36 +2445,731 @@ func TestClient_GetEntityByTitle_NotFound(t *testing.T) {
 	}
 	defer closeClient(t, client)
 
-	_, err = client.GetEntityByTitle("nonexistent entity title")
-	if err == nil {
-		t.Error("Expected error for non-existent entity title")
+	// Add entity
+	embedding := make([]float32, 64)
+	entityID, err := client.AddEntity("ent-idle", "Idle Test", "test", "Desc", embedding)
+	if err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
 	}
+
+	// DEPRECATED: Session-based architecture - idle TTL managed at session level
+	_ = entityID
+	// err = client.SetIdleTTL("entity", entityID, 7200)
+	// if err != nil {
+	//	t.Errorf("SetIdleTTL failed: %v", err)
+	// }
 }
 
 // =============================================================================
-// Authentication Tests
+// Client Operation Tests - Explain
 // =============================================================================
 
-func TestClient_WithAuthentication(t *testing.T) {
-	ts, apiKey := startTestServerWithAuth(t)
+func TestClient_Explain(t *testing.T) {
+	ts := startTestServer(t)
 	defer ts.Stop()
 
-	// Create client with API key
-	cfg := DefaultPoolConfig()
-	cfg.APIKey = apiKey
-
-	client, err := NewClientWithConfig(ts.addr, testSessionID, cfg)
+	client, err := NewClient(ts.addr, testSessionID)
 	if err != nil {
-		t.Fatalf("Failed to create authenticated client: %v", err)
+		t.Fatalf("Failed to create client: %v", err)
 	}
 	defer closeClient(t, client)
 
-	// Verify client can operate
-	if err := client.Ping(); err != nil {
-		t.Errorf("Ping failed: %v", err)
+	// Add data first
+	embedding := make([]float32, 64)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
 	}
+	mustAddEntity(t, client, "ent-exp", "Explain Entity", "test", "Desc", embedding)
 
-	// Get info
+	// First run a query
+	spec := types.QuerySpec{
+		QueryVector:    embedding,
+		TopK:           5,
+		KHops:          0,
+		MaxTextUnits:   10,
+		MaxEntities:    10,
+		MaxCommunities: 5,
+		SearchTypes:    []types.SearchType{types.SearchTypeEntity},
+	}
+	result, err := client.Query(spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// Explain the query
+	explanation, err := client.Explain(result.QueryID)
+	if err != nil {
+		t.Logf("Explain failed (may be expected): %v", err)
+	} else {
+		if explanation == nil {
+			t.Error("Explanation should not be nil")
+		}
+	}
+}
+
+func TestClient_QueryHistory(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	embedding := make([]float32, 64)
+	for i := range embedding {
+		embedding[i] = float32(i) / 64.0
+	}
+	mustAddEntity(t, client, "ent-hist", "History Entity", "test", "Desc", embedding)
+
+	spec := types.QuerySpec{
+		QueryVector:  embedding,
+		TopK:         5,
+		KeywordQuery: "history",
+		SearchTypes:  []types.SearchType{types.SearchTypeEntity},
+	}
+	var last *types.ContextPack
+	for range 3 {
+		if last, err = client.Query(spec); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+	}
+
+	records, err := client.ListQueries(2, false)
+	if err != nil {
+		t.Fatalf("ListQueries failed: %v", err)
+	}
+	if len(records) != 2 || records[0].QueryID != last.QueryID || records[1].QueryID >= last.QueryID {
+		t.Fatalf("ListQueries = %+v", records)
+	}
+	if got := records[0].Spec; got.TopK != 5 || got.KeywordQuery != "history" || got.QueryVector != nil || records[0].Entities != 1 {
+		t.Errorf("Record = %+v", records[0])
+	}
+	if records, err := client.ListQueries(1, true); err != nil || len(records[0].Spec.QueryVector) != 64 {
+		t.Errorf("ListQueries with vectors = %+v, %v", records, err)
+	}
+
+	result, err := client.GetQueryResult(last.QueryID)
+	if err != nil {
+		t.Fatalf("GetQueryResult failed: %v", err)
+	}
+	if result.QueryID != last.QueryID || len(result.Entities) != 1 || result.Entities[0].Entity.ExternalID != "ent-hist" {
+		t.Errorf("GetQueryResult = %+v", result)
+	}
+	if _, err := client.GetQueryResult(last.QueryID + 100); err == nil {
+		t.Error("Expected error for an unknown query")
+	}
+}
+
+// =============================================================================
+// Client Operation Tests - Bulk Operations (MSet/MGet)
+// =============================================================================
+
+func TestClient_MSetEntities(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	embedding := make([]float32, 64)
+	entities := []types.BulkEntityInput{
+		{ExternalID: "bulk-ent-1", Title: "Bulk Entity 1", Type: "test", Description: "Desc 1", Embedding: embedding},
+		{ExternalID: "bulk-ent-2", Title: "Bulk Entity 2", Type: "test", Description: "Desc 2", Embedding: embedding},
+		{ExternalID: "bulk-ent-3", Title: "Bulk Entity 3", Type: "test", Description: "Desc 3", Embedding: embedding},
+	}
+
+	ids, err := client.MSetEntities(entities)
+	if err != nil {
+		t.Fatalf("MSetEntities failed: %v", err)
+	}
+
+	if len(ids) != 3 {
+		t.Errorf("Expected 3 IDs, got %d", len(ids))
+	}
+}
+
+func TestClient_MGetEntities(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "mget-ent-1", "MGet Entity 1", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "mget-ent-2", "MGet Entity 2", "test", "Desc", embedding)
+
+	entities, err := client.MGetEntities([]uint64{ent1ID, ent2ID})
+	if err != nil {
+		t.Fatalf("MGetEntities failed: %v", err)
+	}
+
+	if len(entities) != 2 {
+		t.Errorf("Expected 2 entities, got %d", len(entities))
+	}
+}
+
+func TestClient_MSetDocuments(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	docs := []types.BulkDocumentInput{
+		{ExternalID: "bulk-doc-1", Filename: "file1.pdf"},
+		{ExternalID: "bulk-doc-2", Filename: "file2.pdf"},
+	}
+
+	ids, err := client.MSetDocuments(docs)
+	if err != nil {
+		t.Fatalf("MSetDocuments failed: %v", err)
+	}
+
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 IDs, got %d", len(ids))
+	}
+}
+
+func TestClient_MGetDocuments(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	doc1ID := mustAddDocument(t, client, "mget-doc-1", "file1.pdf")
+	doc2ID := mustAddDocument(t, client, "mget-doc-2", "file2.pdf")
+
+	docs, err := client.MGetDocuments([]uint64{doc1ID, doc2ID})
+	if err != nil {
+		t.Fatalf("MGetDocuments failed: %v", err)
+	}
+
+	if len(docs) != 2 {
+		t.Errorf("Expected 2 documents, got %d", len(docs))
+	}
+}
+
+func TestClient_MSetTextUnits(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	docID := mustAddDocument(t, client, "mset-tu-doc", "test.pdf")
+	embedding := make([]float32, 64)
+
+	tus := []types.BulkTextUnitInput{
+		{ExternalID: "bulk-tu-1", DocumentID: docID, Content: "Content 1", Embedding: embedding, TokenCount: 5},
+		{ExternalID: "bulk-tu-2", DocumentID: docID, Content: "Content 2", Embedding: embedding, TokenCount: 5},
+	}
+
+	ids, err := client.MSetTextUnits(tus)
+	if err != nil {
+		t.Fatalf("MSetTextUnits failed: %v", err)
+	}
+
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 IDs, got %d", len(ids))
+	}
+}
+
+func TestClient_MGetTextUnits(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	docID := mustAddDocument(t, client, "mget-tu-doc", "test.pdf")
+	embedding := make([]float32, 64)
+	tu1ID := mustAddTextUnit(t, client, "mget-tu-1", docID, "Content 1", embedding, 5)
+	tu2ID := mustAddTextUnit(t, client, "mget-tu-2", docID, "Content 2", embedding, 5)
+
+	tus, err := client.MGetTextUnits([]uint64{tu1ID, tu2ID})
+	if err != nil {
+		t.Fatalf("MGetTextUnits failed: %v", err)
+	}
+
+	if len(tus) != 2 {
+		t.Errorf("Expected 2 text units, got %d", len(tus))
+	}
+}
+
+func TestClient_MSetRelationships(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "mset-rel-1", "Entity 1", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "mset-rel-2", "Entity 2", "test", "Desc", embedding)
+	ent3ID := mustAddEntity(t, client, "mset-rel-3", "Entity 3", "test", "Desc", embedding)
+
+	rels := []types.BulkRelationshipInput{
+		{ExternalID: "bulk-rel-1", SourceID: ent1ID, TargetID: ent2ID, Type: "RELATED", Description: "Desc", Weight: 1.0},
+		{ExternalID: "bulk-rel-2", SourceID: ent2ID, TargetID: ent3ID, Type: "RELATED", Description: "Desc", Weight: 1.0},
+	}
+
+	ids, err := client.MSetRelationships(rels)
+	if err != nil {
+		t.Fatalf("MSetRelationships failed: %v", err)
+	}
+
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 IDs, got %d", len(ids))
+	}
+}
+
+func TestClient_MGetRelationships(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "mget-rel-1", "Entity 1", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "mget-rel-2", "Entity 2", "test", "Desc", embedding)
+	rel1ID := mustAddRelationship(t, client, "mget-rel-a", ent1ID, ent2ID, "TYPE_A", "Desc", 1.0)
+	rel2ID := mustAddRelationship(t, client, "mget-rel-b", ent2ID, ent1ID, "TYPE_B", "Desc", 1.0)
+
+	rels, err := client.MGetRelationships([]uint64{rel1ID, rel2ID})
+	if err != nil {
+		t.Fatalf("MGetRelationships failed: %v", err)
+	}
+
+	if len(rels) != 2 {
+		t.Errorf("Expected 2 relationships, got %d", len(rels))
+	}
+}
+
+func TestClient_MDeleteEntities(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	embedding := make([]float32, 64)
+	ent1ID := mustAddEntity(t, client, "mdel-1", "Entity 1", "test", "Desc", embedding)
+	ent2ID := mustAddEntity(t, client, "mdel-2", "Entity 2", "test", "Desc", embedding)
+	ent3ID := mustAddEntity(t, client, "mdel-3", "Entity 3", "test", "Desc", embedding)
+	relID := mustAddRelationship(t, client, "mdel-rel", ent1ID, ent3ID, "TYPE_A", "Desc", 1.0)
+
+	deleted, err := client.MDeleteEntities([]uint64{ent1ID, ent2ID, 9999})
+	if err != nil {
+		t.Fatalf("MDeleteEntities failed: %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("Expected 2 deleted, got %d", deleted)
+	}
+	if _, err := client.GetRelationship(relID); err == nil {
+		t.Error("Relationship of a deleted entity should be gone")
+	}
+	if _, err := client.GetEntity(ent3ID); err != nil {
+		t.Errorf("Entity 3 should remain: %v", err)
+	}
+
+	deleted, err = client.MDeleteRelationships([]uint64{relID})
+	if err != nil || deleted != 0 {
+		t.Errorf("MDeleteRelationships = %d, %v; want 0", deleted, err)
+	}
+}
+
+// =============================================================================
+// Client Operation Tests - Backup Operations
+// =============================================================================
+
+func TestClient_BGSave(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// BGSave starts a background save to a path
+	err = client.BGSave("/tmp/gibram_test_save")
+	if err != nil {
+		t.Logf("BGSave failed (may be expected if datadir not configured): %v", err)
+	}
+}
+
+func TestClient_Save(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// Save performs a blocking save to a path
+	err = client.Save("/tmp/gibram_test_save")
+	if err != nil {
+		t.Logf("Save failed (may be expected if datadir not configured): %v", err)
+	}
+}
+
+func TestClient_LastSave(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	info, err := client.LastSave()
+	if err != nil {
+		t.Logf("LastSave failed (may be expected if never saved): %v", err)
+	} else {
+		t.Logf("Last save at %d", info.Timestamp)
+	}
+}
+
+func TestClient_BGRestore(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// BGRestore tries to restore from a path
+	err = client.BGRestore("/nonexistent/path")
+	if err != nil {
+		t.Logf("BGRestore failed (expected for nonexistent path): %v", err)
+	}
+}
+
+func TestClient_BackupStatus(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	status, err := client.BackupStatus()
+	if err != nil {
+		t.Logf("BackupStatus failed (may be expected): %v", err)
+	} else {
+		t.Logf("Backup status: in_progress=%v, type=%s", status.InProgress, status.Type)
+	}
+}
+
+// =============================================================================
+// Client Operation Tests - Connection Pool Edge Cases
+// =============================================================================
+
+func TestConnPool_MultipleClients(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	// Create multiple clients to test pool management
+	cfg := DefaultPoolConfig()
+	cfg.MaxConnections = 3
+
+	pool, err := NewConnPool(ts.addr, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	// Perform multiple concurrent operations to test pool usage
+	var wg sync.WaitGroup
+	errCh := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Use pool via Client
+			client := newPoolClient(pool)
+			if err := client.Ping(); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		t.Errorf("Ping failed: %v", err)
+	}
+
+	active, available := pool.Stats()
+	t.Logf("After concurrent ops: active=%d, available=%d", active, available)
+}
+
+func TestConnPool_IdleConnectionCleanup(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	// Create pool with short idle timeout
+	cfg := DefaultPoolConfig()
+	cfg.IdleTimeout = 100 * time.Millisecond
+
+	pool, err := NewConnPool(ts.addr, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	// Do something to create a connection
+	client := newPoolClient(pool)
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	// Wait for idle connection to expire
+	time.Sleep(150 * time.Millisecond)
+
+	active, _ := pool.Stats()
+	t.Logf("After idle wait: active=%d", active)
+}
+
+func TestClient_WithTLS(t *testing.T) {
+	// Test TLS config creation (without actual TLS server)
+	cfg := DefaultPoolConfig()
+	cfg.TLSEnabled = true
+	cfg.TLSSkipVerify = true
+	cfg.ConnTimeout = 100 * time.Millisecond
+
+	// This will fail to connect but tests the TLS path
+	_, err := NewConnPool("127.0.0.1:59999", cfg)
+	if err == nil {
+		t.Error("Expected error connecting to invalid TLS endpoint")
+	}
+}
+
+func TestClient_WithAPIKey(t *testing.T) {
+	// Test API key config (requires server with auth)
+	cfg := DefaultPoolConfig()
+	cfg.APIKey = "test-api-key"
+	cfg.ConnTimeout = 100 * time.Millisecond
+
+	// This will fail but tests the API key config path
+	_, err := NewConnPool("127.0.0.1:59998", cfg)
+	if err == nil {
+		t.Error("Expected error connecting to invalid endpoint")
+	}
+}
+
+// =============================================================================
+// Wire Protocol Tests
+// =============================================================================
+
+func TestWriteEnvelope(t *testing.T) {
+	// Test envelope serialization - this is internal but affects coverage
+	// We can test it indirectly through client operations
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// This exercises writeEnvelope and readEnvelope
+	if err := client.Ping(); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+}
+
+// =============================================================================
+// Error Handling Tests
+// =============================================================================
+
+func TestClient_ErrorHandling_InvalidEntity(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// Try to get non-existent entity
+	_, err = client.GetEntity(999999999)
+	if err == nil {
+		t.Error("Expected error for non-existent entity")
+	}
+}
+
+func TestClient_ErrorHandling_InvalidRelationship(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// Try to get non-existent relationship
+	_, err = client.GetRelationship(999999999)
+	if err == nil {
+		t.Error("Expected error for non-existent relationship")
+	}
+}
+
+func TestClient_ErrorHandling_InvalidTextUnit(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// Try to get non-existent text unit
+	_, err = client.GetTextUnit(999999999)
+	if err == nil {
+		t.Error("Expected error for non-existent text unit")
+	}
+}
+
+func TestClient_ErrorHandling_InvalidCommunity(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// Try to get non-existent community
+	_, err = client.GetCommunity(999999999)
+	if err == nil {
+		t.Error("Expected error for non-existent community")
+	}
+}
+
+func TestClient_MGetEntities_EmptyIDs(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// Get with empty IDs
+	entities, err := client.MGetEntities([]uint64{})
+	if err != nil {
+		t.Logf("MGetEntities with empty IDs: %v", err)
+	}
+	t.Logf("Returned %d entities for empty IDs", len(entities))
+}
+
+func TestClient_MGetDocuments_EmptyIDs(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	docs, err := client.MGetDocuments([]uint64{})
+	if err != nil {
+		t.Logf("MGetDocuments with empty IDs: %v", err)
+	}
+	t.Logf("Returned %d documents for empty IDs", len(docs))
+}
+
+// =============================================================================
+// Additional Entity Operations
+// =============================================================================
+
+func TestClient_GetEntityByTitle_NotFound(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	client, err := NewClient(ts.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	_, err = client.GetEntityByTitle("nonexistent entity title")
+	if err == nil {
+		t.Error("Expected error for non-existent entity title")
+	}
+}
+
+// =============================================================================
+// Authentication Tests
+// =============================================================================
+
+func TestClient_WithAuthentication(t *testing.T) {
+	ts, apiKey := startTestServerWithAuth(t)
+	defer ts.Stop()
+
+	// Create client with API key
+	cfg := DefaultPoolConfig()
+	cfg.APIKey = apiKey
+
+	client, err := NewClientWithConfig(ts.addr, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create authenticated client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// Verify client can operate
+	if err := client.Ping(); err != nil {
+		t.Errorf("Ping failed: %v", err)
+	}
+
+	// Get info
 	info, err := client.Info()
 	if err != nil {
 		t.Errorf("Info failed: %v", err)
@@ -1903,9 +3188,68 @@ func TestClient_WithWrongAPIKey(t *testing.T) {
 	cfg.APIKey = "wrong-api-key"
 	cfg.ConnTimeout = 1 * time.Second
 
-	_, err := NewClientWithConfig(ts.addr, testSessionID, cfg)
-	if err == nil {
-		t.Error("Expected error for wrong API key")
+	_, err := NewClientWithConfig(ts.addr, testSessionID, cfg)
+	if err == nil {
+		t.Error("Expected error for wrong API key")
+	}
+}
+
+func TestClient_RotateKey(t *testing.T) {
+	ts, apiKey := startTestServerWithAuth(t)
+	defer ts.Stop()
+
+	// connect opens a client authenticated with key
+	connect := func(key string) (*Client, error) {
+		cfg := DefaultPoolConfig()
+		cfg.APIKey = key
+		cfg.ConnTimeout = 1 * time.Second
+		return NewClientWithConfig(ts.addr, testSessionID, cfg)
+	}
+
+	admin, err := connect(apiKey)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, admin)
+
+	result, err := admin.RotateKey("test-key", "", false)
+	if err != nil {
+		t.Fatalf("RotateKey failed: %v", err)
+	}
+	if result.APIKey == "" || result.KeyHash == "" || result.ActiveKeys != 2 {
+		t.Fatalf("Unexpected rotation result: %+v", result)
+	}
+
+	// Both keys authenticate during the rollover
+	for _, key := range []string{apiKey, result.APIKey} {
+		c, err := connect(key)
+		if err != nil {
+			t.Fatalf("Expected key to authenticate during rollover: %v", err)
+		}
+		closeClient(t, c)
+	}
+
+	// Retiring the oldest stops the original key; open connections stay up
+	if _, err := admin.RotateKey("test-key", "gibram_third_key", true); err != nil {
+		t.Fatalf("RotateKey with retire failed: %v", err)
+	}
+	if c, err := connect(apiKey); err == nil {
+		closeClient(t, c)
+		t.Error("Expected the retired key to stop authenticating")
+	}
+	for _, key := range []string{result.APIKey, "gibram_third_key"} {
+		c, err := connect(key)
+		if err != nil {
+			t.Fatalf("Expected key to authenticate after retirement: %v", err)
+		}
+		closeClient(t, c)
+	}
+	if err := admin.Ping(); err != nil {
+		t.Errorf("Ping on the existing connection failed: %v", err)
+	}
+
+	if _, err := admin.RotateKey("no-such-key", "", false); err == nil {
+		t.Error("Expected error for unknown key id")
 	}
 }
 
@@ -1985,3 +3329,604 @@ func TestClient_AuthenticatedOperations(t *testing.T) {
 		t.Error("Health status should not be empty")
 	}
 }
+
+func TestClient_SnapshotDownloadUploadRoundTrip(t *testing.T) {
+	src := startTestServer(t)
+	defer src.Stop()
+	dst := startTestServer(t)
+	defer dst.Stop()
+
+	srcClient, err := NewClient(src.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, srcClient)
+
+	// Random descriptions barely compress, so the snapshot spans several chunks
+	const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 ."
+	rng := rand.New(rand.NewSource(1))
+	desc := make([]byte, 2048)
+	for batch := 0; batch < 5; batch++ {
+		inputs := make([]types.BulkEntityInput, 200)
+		for i := range inputs {
+			for j := range desc {
+				desc[j] = alphabet[rng.Intn(len(alphabet))]
+			}
+			n := batch*len(inputs) + i
+			inputs[i] = types.BulkEntityInput{
+				ExternalID:  fmt.Sprintf("ent-%d", n),
+				Title:       fmt.Sprintf("Entity %d", n),
+				Type:        "organization",
+				Description: string(desc),
+			}
+		}
+		if _, err := srcClient.MSetEntities(inputs); err != nil {
+			t.Fatalf("MSetEntities failed: %v", err)
+		}
+	}
+	relID := mustAddRelationship(t, srcClient, "rel-1", 1, 2, "OWNS", "", 1.0)
+
+	var snap bytes.Buffer
+	if err := srcClient.DownloadSnapshot(&snap); err != nil {
+		t.Fatalf("DownloadSnapshot failed: %v", err)
+	}
+	if snap.Len() <= SnapshotChunkSize {
+		t.Fatalf("snapshot is %d bytes, want more than one %d-byte chunk", snap.Len(), SnapshotChunkSize)
+	}
+
+	dstClient, err := NewClient(dst.addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, dstClient)
+
+	if err := dstClient.UploadSnapshot(&snap); err != nil {
+		t.Fatalf("UploadSnapshot failed: %v", err)
+	}
+
+	srcInfo, err := srcClient.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	dstInfo, err := dstClient.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if dstInfo.EntityCount != srcInfo.EntityCount || dstInfo.RelationshipCount != srcInfo.RelationshipCount {
+		t.Errorf("restored counts = %d entities, %d relationships; want %d, %d",
+			dstInfo.EntityCount, dstInfo.RelationshipCount, srcInfo.EntityCount, srcInfo.RelationshipCount)
+	}
+
+	ent, err := dstClient.GetEntityByTitle("Entity 987")
+	if err != nil {
+		t.Fatalf("GetEntityByTitle failed: %v", err)
+	}
+	if ent.ExternalID != "ent-987" {
+		t.Errorf("ExternalID = %q, want %q", ent.ExternalID, "ent-987")
+	}
+	rel, err := dstClient.GetRelationship(relID)
+	if err != nil {
+		t.Fatalf("GetRelationship failed: %v", err)
+	}
+	if rel.SourceID != 1 || rel.TargetID != 2 {
+		t.Errorf("relationship = %d -> %d, want 1 -> 2", rel.SourceID, rel.TargetID)
+	}
+}
+
+func TestClient_SessionExportImport(t *testing.T) {
+	src := startTestServer(t)
+	defer src.Stop()
+	dst := startTestServer(t)
+	defer dst.Stop()
+
+	srcClient, err := NewClient(src.addr, "tenant-a")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, srcClient)
+	embedding := make([]float32, 64)
+	embedding[0] = 1
+	bankID := mustAddEntity(t, srcClient, "ent-bank", "Bank Indonesia", "organization", "Central bank", embedding)
+	govID := mustAddEntity(t, srcClient, "ent-gov", "Perry Warjiyo", "person", "Governor", embedding)
+	mustAddRelationship(t, srcClient, "rel-1", govID, bankID, "GOVERNOR_OF", "", 1.0)
+
+	var export bytes.Buffer
+	if err := srcClient.ExportSession(&export); err != nil {
+		t.Fatalf("ExportSession failed: %v", err)
+	}
+
+	// The target session already holds an entity, so the import is renumbered
+	dstClient, err := NewClient(dst.addr, "tenant-b")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, dstClient)
+	mustAddEntity(t, dstClient, "ent-local", "Local", "concept", "Already here", embedding)
+
+	if err := dstClient.ImportSession(bytes.NewReader(export.Bytes())); err != nil {
+		t.Fatalf("ImportSession failed: %v", err)
+	}
+	bank, err := dstClient.GetEntityByTitle("Bank Indonesia")
+	if err != nil {
+		t.Fatalf("GetEntityByTitle failed: %v", err)
+	}
+	if bank.ID == bankID {
+		t.Errorf("imported entity kept ID %d, want it renumbered after the existing entity", bank.ID)
+	}
+	rels, err := dstClient.GetNeighbors(bank.ID, types.DirectionIncoming, nil)
+	if err != nil || len(rels) != 1 {
+		t.Fatalf("GetNeighbors = %v, %v; want one relationship", rels, err)
+	}
+
+	if err := dstClient.ImportSession(bytes.NewReader(export.Bytes())); err == nil {
+		t.Error("expected importing the same records twice to fail")
+	}
+}
+
+func TestClient_MergeSession(t *testing.T) {
+	srv := startTestServer(t)
+	defer srv.Stop()
+
+	embedding := make([]float32, 64)
+	embedding[0] = 1
+	src, err := NewClient(srv.addr, "desk-b")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, src)
+	mustAddEntity(t, src, "b-bank", "Bank Indonesia", "organization", "Monetary authority", embedding)
+
+	dst, err := NewClient(srv.addr, "desk-a")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, dst)
+	bankID := mustAddEntity(t, dst, "a-bank", "Bank Indonesia", "organization", "Central bank", embedding)
+
+	if err := dst.MergeSession("desk-b", types.ConflictReject); err == nil {
+		t.Fatal("expected merge with a shared title to be rejected")
+	}
+	if err := dst.MergeSession("desk-b", types.ConflictDedupTitle); err != nil {
+		t.Fatalf("MergeSession failed: %v", err)
+	}
+	bank, err := dst.GetEntityByTitle("Bank Indonesia")
+	if err != nil || bank.ID != bankID {
+		t.Fatalf("GetEntityByTitle = %+v, %v; want entity %d", bank, err, bankID)
+	}
+	if err := dst.MergeSession("", types.ConflictSkip); err == nil {
+		t.Error("expected merge without a source session to fail")
+	}
+}
+
+func TestClient_GetByExternalID(t *testing.T) {
+	srv := startTestServer(t)
+	defer srv.Stop()
+
+	embedding := make([]float32, 64)
+	embedding[0] = 1
+	a, err := NewClient(srv.addr, "ext-a")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, a)
+
+	docID := mustAddDocument(t, a, "doc-1", "report.pdf")
+	tuID := mustAddTextUnit(t, a, "tu-1", docID, "BI raised rates", embedding, 3)
+	bankID := mustAddEntity(t, a, "ent-bank", "Bank Indonesia", "organization", "Central bank", embedding)
+	rateID := mustAddEntity(t, a, "ent-rate", "BI Rate", "concept", "Policy rate", embedding)
+	relID := mustAddRelationship(t, a, "rel-1", bankID, rateID, "SETS", "Bank sets rate", 1)
+
+	doc, err := a.GetDocumentByExternalID("doc-1")
+	if err != nil || doc.ID != docID || doc.Filename != "report.pdf" {
+		t.Errorf("GetDocumentByExternalID = %+v, %v; want document %d", doc, err, docID)
+	}
+	tu, err := a.GetTextUnitByExternalID("tu-1")
+	if err != nil || tu.ID != tuID || tu.Content != "BI raised rates" || len(tu.Embedding) != 64 {
+		t.Errorf("GetTextUnitByExternalID = %+v, %v; want text unit %d with embedding", tu, err, tuID)
+	}
+	ent, err := a.GetEntityByExternalID("ent-bank")
+	if err != nil || ent.ID != bankID || ent.Title != "BANK INDONESIA" {
+		t.Errorf("GetEntityByExternalID = %+v, %v; want entity %d", ent, err, bankID)
+	}
+	rel, err := a.GetRelationshipByExternalID("rel-1")
+	if err != nil || rel.ID != relID || rel.SourceID != bankID || rel.TargetID != rateID {
+		t.Errorf("GetRelationshipByExternalID = %+v, %v; want relationship %d", rel, err, relID)
+	}
+
+	if _, err := a.GetEntityByExternalID("missing"); err == nil {
+		t.Error("expected lookup of an unknown external ID to fail")
+	}
+	if _, err := a.GetRelationshipByExternalID(""); err == nil {
+		t.Error("expected lookup of an empty external ID to fail")
+	}
+
+	// Another session with the same external IDs sees only its own records
+	b, err := NewClient(srv.addr, "ext-b")
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, b)
+
+	if _, err := b.GetDocumentByExternalID("doc-1"); err == nil {
+		t.Error("session ext-b should not see ext-a's document")
+	}
+	if _, err := b.GetTextUnitByExternalID("tu-1"); err == nil {
+		t.Error("session ext-b should not see ext-a's text unit")
+	}
+	mustAddEntity(t, b, "ent-bank", "Federal Reserve", "organization", "Central bank", embedding)
+	ent, err = b.GetEntityByExternalID("ent-bank")
+	if err != nil || ent.Title != "FEDERAL RESERVE" {
+		t.Errorf("session ext-b GetEntityByExternalID = %+v, %v; want FEDERAL RESERVE", ent, err)
+	}
+	if _, err := b.GetRelationshipByExternalID("rel-1"); err == nil {
+		t.Error("session ext-b should not see ext-a's relationship")
+	}
+}
+
+func TestClient_Compression(t *testing.T) {
+	plain := startTestServer(t)
+	defer plain.Stop()
+	authed, apiKey := startTestServerWithAuth(t)
+	defer authed.Stop()
+
+	for _, tc := range []struct {
+		name   string
+		addr   string
+		apiKey string
+	}{
+		{"NoAuth", plain.addr, ""},
+		{"Auth", authed.addr, apiKey},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := DefaultPoolConfig()
+			cfg.APIKey = tc.apiKey
+			cfg.Compression = true
+
+			client, err := NewClientWithConfig(tc.addr, testSessionID, cfg)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			defer closeClient(t, client)
+
+			pc := client.lb.backends[0].pool.connections[0]
+			if pc.compressAbove != codec.DefaultCompressThreshold || pc.compression != codec.CompressionZstd {
+				t.Fatalf("compression = (%q, %d), want (%q, %d)", pc.compression, pc.compressAbove, codec.CompressionZstd, codec.DefaultCompressThreshold)
+			}
+
+			// Large enough that both the request and the response are compressed
+			desc := strings.Repeat("Supervises retail lending and payment systems. ", 100)
+			id := mustAddEntity(t, client, "ent-1", "Bank Indonesia", "organization", desc, nil)
+			ent, err := client.GetEntity(id)
+			if err != nil {
+				t.Fatalf("GetEntity failed: %v", err)
+			}
+			if ent.Description != desc {
+				t.Errorf("Description round trip mismatch: got %d bytes, want %d", len(ent.Description), len(desc))
+			}
+		})
+	}
+}
+
+// startHungServer accepts connections and reads requests but never answers
+func startHungServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+			go func() { _, _ = io.Copy(io.Discard, conn) }()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClient_QueryContext_Cancel(t *testing.T) {
+	addr := startHungServer(t)
+
+	client, err := NewClient(addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = make([]float32, 64)
+
+	// The default spec sets no deadline, so only the cancel ends the call
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = client.QueryContext(ctx, spec)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("QueryContext error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("QueryContext returned after %v, want prompt return on cancel", elapsed)
+	}
+}
+
+func TestClient_QueryContext_DeadlineMs(t *testing.T) {
+	addr := startHungServer(t)
+
+	client, err := NewClient(addr, testSessionID)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = make([]float32, 64)
+	spec.DeadlineMs = 50
+
+	// spec.DeadlineMs is sooner than the context deadline, so it wins
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.QueryContext(ctx, spec)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("QueryContext error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("QueryContext returned after %v, want about 50ms", elapsed)
+	}
+}
+
+// startFlakyProxy forwards connections to addr, except that it closes the
+// first drop connections as soon as they are accepted
+func startFlakyProxy(t *testing.T, addr string, drop int32) (string, *atomic.Int32) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	var accepted atomic.Int32
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if accepted.Add(1) <= drop {
+				_ = conn.Close()
+				continue
+			}
+			upstream, err := net.Dial("tcp", addr)
+			if err != nil {
+				_ = conn.Close()
+				continue
+			}
+			go func() { _, _ = io.Copy(upstream, conn); _ = upstream.Close() }()
+			go func() { _, _ = io.Copy(conn, upstream); _ = conn.Close() }()
+		}
+	}()
+	return ln.Addr().String(), &accepted
+}
+
+func TestClient_RetryAfterDroppedConnection(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	cfg := DefaultPoolConfig()
+	cfg.Retry = RetryConfig{MaxRetries: 3, BaseDelay: 5 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+
+	// The first connection (opened by NewClient) is dropped by the proxy
+	addr, accepted := startFlakyProxy(t, ts.addr, 1)
+	client, err := NewClientWithConfig(addr, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Ping should be retried on a fresh connection: %v", err)
+	}
+	if n := accepted.Load(); n != 2 {
+		t.Errorf("proxy accepted %d connections, want 2", n)
+	}
+}
+
+func TestClient_WritesNotRetriedByDefault(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+
+	cfg := DefaultPoolConfig()
+	cfg.Retry = RetryConfig{MaxRetries: 3, BaseDelay: 5 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+
+	addr, _ := startFlakyProxy(t, ts.addr, 1)
+	client, err := NewClientWithConfig(addr, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	if _, err := client.AddDocument("doc-1", "a.txt"); err == nil {
+		t.Fatal("AddDocument on a dropped connection should fail without opt-in")
+	}
+
+	// Opted in per call, the write goes through on a new connection
+	addr, _ = startFlakyProxy(t, ts.addr, 1)
+	optIn, err := NewClientWithConfig(addr, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, optIn)
+
+	if _, err := optIn.AddDocumentContext(WithWriteRetry(context.Background()), "doc-2", "b.txt"); err != nil {
+		t.Fatalf("AddDocumentContext with WithWriteRetry failed: %v", err)
+	}
+}
+
+func TestClient_ServerErrorsNotRetried(t *testing.T) {
+	ts := startTestServer(t)
+	defer ts.Stop()
+	collector := metrics.NewCollector()
+	ts.srv.SetMetricsCollector(collector)
+
+	cfg := DefaultPoolConfig()
+	cfg.Retry = RetryConfig{MaxRetries: 3, BaseDelay: 5 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+	client, err := NewClientWithConfig(ts.addr, "missing-session", cfg)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	// The server answered, so the error comes back after a single attempt
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = make([]float32, 64)
+	if _, err := client.Query(spec); !errors.Is(err, ErrServerError) {
+		t.Fatalf("Query error = %v, want ErrServerError", err)
+	}
+	var queries int64
+	for _, st := range collector.CommandStats() {
+		if st.Command == "query" {
+			queries = st.Count
+		}
+	}
+	if queries != 1 {
+		t.Errorf("Server saw %d queries, want 1", queries)
+	}
+
+	// The connection stays usable
+	if err := client.Ping(); err != nil {
+		t.Errorf("Ping after a server error failed: %v", err)
+	}
+}
+
+// switchProxy forwards to a server and can be taken down and brought back
+// on the same address; while down it drops every connection
+type switchProxy struct {
+	addr   string
+	down   atomic.Bool
+	mu     sync.Mutex
+	conns  []net.Conn
+	target string
+}
+
+func startSwitchProxy(t *testing.T, target string) *switchProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	p := &switchProxy{addr: ln.Addr().String(), target: target}
+	t.Cleanup(func() {
+		_ = ln.Close()
+		p.setDown(true)
+	})
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if p.down.Load() {
+				_ = conn.Close()
+				continue
+			}
+			upstream, err := net.Dial("tcp", p.target)
+			if err != nil {
+				_ = conn.Close()
+				continue
+			}
+			p.mu.Lock()
+			p.conns = append(p.conns, conn, upstream)
+			p.mu.Unlock()
+			go func() { _, _ = io.Copy(upstream, conn); _ = upstream.Close() }()
+			go func() { _, _ = io.Copy(conn, upstream); _ = conn.Close() }()
+		}
+	}()
+	return p
+}
+
+func (p *switchProxy) setDown(down bool) {
+	p.down.Store(down)
+	if !down {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		_ = c.Close()
+	}
+	p.conns = nil
+}
+
+func TestClusterClient_FailoverAndRecovery(t *testing.T) {
+	ts1 := startTestServer(t)
+	defer ts1.Stop()
+	ts2 := startTestServer(t)
+	defer ts2.Stop()
+	p1 := startSwitchProxy(t, ts1.addr)
+	p2 := startSwitchProxy(t, ts2.addr)
+
+	cfg := DefaultPoolConfig()
+	cfg.ConnTimeout = 500 * time.Millisecond
+	cfg.HealthCheckInterval = 20 * time.Millisecond
+	cfg.Retry = RetryConfig{MaxRetries: 3, BaseDelay: 5 * time.Millisecond, MaxDelay: 20 * time.Millisecond}
+	cfg.Primary = p1.addr
+
+	client, err := NewClusterClient([]string{p1.addr, p2.addr}, testSessionID, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create cluster client: %v", err)
+	}
+	defer closeClient(t, client)
+
+	stats := client.Stats()
+	if len(stats) != 2 || !stats[0].Primary || stats[1].Primary {
+		t.Fatalf("Stats() = %+v, want the first server as primary", stats)
+	}
+
+	// Writes go to the primary only
+	for i := 0; i < 4; i++ {
+		mustAddDocument(t, client, "doc-"+itoa(i), "a.txt")
+	}
+	for i, ts := range []*testServer{ts1, ts2} {
+		direct, err := NewClient(ts.addr, testSessionID)
+		if err != nil {
+			t.Fatalf("Failed to create client: %v", err)
+		}
+		info, err := direct.Info()
+		closeClient(t, direct)
+		if err != nil {
+			t.Fatalf("Info failed: %v", err)
+		}
+		if want := []int{4, 0}[i]; info.DocumentCount != want {
+			t.Errorf("server %d has %d documents, want %d", i+1, info.DocumentCount, want)
+		}
+	}
+
+	// The second server goes away mid-run; reads continue on the survivor
+	p2.setDown(true)
+	for i := 0; i < 10; i++ {
+		if err := client.Ping(); err != nil {
+			t.Fatalf("Ping %d failed with a healthy server left: %v", i, err)
+		}
+	}
+	if client.Stats()[1].Healthy {
+		t.Error("Stopped server should be marked unhealthy")
+	}
+
+	// Once it is back, the health check brings it back into rotation
+	p2.setDown(false)
+	deadline := time.Now().Add(2 * time.Second)
+	for !client.Stats()[1].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("Recovered server was not marked healthy by the health check")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -208,3 +208,33 @@ func TestBackupStatus(t *testing.T) {
 		t.Errorf("Type = %q, want %q", status.Type, "save")
 	}
 }
+
+// =============================================================================
+// Retry Policy Tests
+// =============================================================================
+
+func TestRetryConfig_Backoff(t *testing.T) {
+	r := RetryConfig{BaseDelay: 10 * time.Millisecond, MaxDelay: 100 * time.Millisecond}
+
+	for n, want := range []time.Duration{10, 20, 40, 80, 100, 100} {
+		want *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := r.backoff(n); d < want/2 || d > want {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", n, d, want/2, want)
+			}
+		}
+	}
+	if d := r.backoff(100); d > r.MaxDelay {
+		t.Errorf("backoff(100) = %v, want at most %v", d, r.MaxDelay)
+	}
+}
+
+func TestRetryConfig_Defaults(t *testing.T) {
+	r := RetryConfig{}.withDefaults(5)
+	if r.MaxRetries != 5 || r.BaseDelay != DefaultRetryBaseDelay || r.MaxDelay != DefaultRetryMaxDelay {
+		t.Errorf("withDefaults = %+v", r)
+	}
+	if r := (RetryConfig{MaxRetries: -1}).withDefaults(5); r.MaxRetries != 0 {
+		t.Errorf("MaxRetries = %d, want 0 for a negative setting", r.MaxRetries)
+	}
+}
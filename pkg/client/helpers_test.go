@@ -37,3 +37,13 @@ func mustAddRelationship(tb testing.TB, c *Client, extID string, sourceID, targe
 	}
 	return id
 }
+
+// newPoolClient wraps an existing pool in a single-server client
+func newPoolClient(pool *ConnPool) *Client {
+	b := &backend{addr: pool.addr, config: pool.config, pool: pool}
+	b.markUp()
+	return &Client{
+		lb:     &balancer{backends: []*backend{b}, strategy: LoadBalanceRoundRobin},
+		config: pool.config,
+	}
+}
@@ -0,0 +1,146 @@
+// Package client - retries with exponential backoff
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	pb "github.com/gibram-io/gibram/proto/gibrampb"
+)
+
+// =============================================================================
+// Retry Policy
+// =============================================================================
+
+const (
+	DefaultRetryBaseDelay = 50 * time.Millisecond
+	DefaultRetryMaxDelay  = 2 * time.Second
+)
+
+// RetryConfig controls how requests that hit a connection error are retried.
+// Failures before the request is sent (dial, pool) are retried for every
+// command. Failures after it is sent are retried only for idempotent reads,
+// or for writes when the call's context comes from WithWriteRetry.
+type RetryConfig struct {
+	MaxRetries int           // Retries after the first attempt (default: PoolConfig.MaxRetries, negative = none)
+	BaseDelay  time.Duration // Delay before the first retry, doubled each time (default: 50ms)
+	MaxDelay   time.Duration // Upper bound on the delay (default: 2s)
+}
+
+// DefaultRetryConfig returns the default retry policy
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultRetryBaseDelay,
+		MaxDelay:   DefaultRetryMaxDelay,
+	}
+}
+
+// withDefaults fills zero-valued settings; legacyRetries is PoolConfig.MaxRetries
+func (r RetryConfig) withDefaults(legacyRetries int) RetryConfig {
+	if r.MaxRetries == 0 {
+		r.MaxRetries = legacyRetries
+	}
+	if r.MaxRetries < 0 {
+		r.MaxRetries = 0
+	}
+	if r.BaseDelay <= 0 {
+		r.BaseDelay = DefaultRetryBaseDelay
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = DefaultRetryMaxDelay
+	}
+	if r.MaxDelay < r.BaseDelay {
+		r.MaxDelay = r.BaseDelay
+	}
+	return r
+}
+
+// backoff returns the delay before retry n (0-based): BaseDelay doubled n
+// times, capped at MaxDelay, with the upper half jittered
+func (r RetryConfig) backoff(n int) time.Duration {
+	d := r.MaxDelay
+	if n < 32 {
+		if exp := r.BaseDelay << n; exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type writeRetryKey struct{}
+
+// WithWriteRetry returns a context under which writes are retried like reads
+// after a connection error. A write that reached the server before the
+// connection broke may then be applied twice, so use it for writes that are
+// safe to repeat.
+func WithWriteRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writeRetryKey{}, true)
+}
+
+// idempotentCommands are the reads that are safe to resend after a
+// connection error
+var idempotentCommands = map[pb.CommandType]bool{
+	pb.CommandType_CMD_PING:                            true,
+	pb.CommandType_CMD_INFO:                            true,
+	pb.CommandType_CMD_HEALTH:                          true,
+	pb.CommandType_CMD_LIST_SESSIONS:                   true,
+	pb.CommandType_CMD_SESSION_INFO:                    true,
+	pb.CommandType_CMD_GET_SESSION_METADATA:            true,
+	pb.CommandType_CMD_GRAPH_DIFF:                      true,
+	pb.CommandType_CMD_GET_DOCUMENT:                    true,
+	pb.CommandType_CMD_GET_DOCUMENT_BY_EXTERNAL_ID:     true,
+	pb.CommandType_CMD_GET_TEXTUNIT:                    true,
+	pb.CommandType_CMD_GET_TEXTUNIT_BY_EXTERNAL_ID:     true,
+	pb.CommandType_CMD_GET_ENTITY:                      true,
+	pb.CommandType_CMD_GET_ENTITY_BY_EXTERNAL_ID:       true,
+	pb.CommandType_CMD_GET_ENTITY_BY_TITLE:             true,
+	pb.CommandType_CMD_GET_ENTITY_COMMUNITIES:          true,
+	pb.CommandType_CMD_GET_RELATIONSHIP:                true,
+	pb.CommandType_CMD_GET_RELATIONSHIP_BY_EXTERNAL_ID: true,
+	pb.CommandType_CMD_GET_COMMUNITY:                   true,
+	pb.CommandType_CMD_GET_NEIGHBORS:                   true,
+	pb.CommandType_CMD_SUBGRAPH:                        true,
+	pb.CommandType_CMD_RELATIONSHIP_TYPE_STATS:         true,
+	pb.CommandType_CMD_ENTITY_STATS:                    true,
+	pb.CommandType_CMD_COUNT:                           true,
+	pb.CommandType_CMD_QUERY:                           true,
+	pb.CommandType_CMD_EXPLAIN:                         true,
+	pb.CommandType_CMD_QUERY_STATS_SUMMARY:             true,
+	pb.CommandType_CMD_LIST_QUERIES:                    true,
+	pb.CommandType_CMD_GET_QUERY_RESULT:                true,
+	pb.CommandType_CMD_STATS:                           true,
+	pb.CommandType_CMD_MGET_DOCUMENTS:                  true,
+	pb.CommandType_CMD_MGET_TEXTUNITS:                  true,
+	pb.CommandType_CMD_MGET_ENTITIES:                   true,
+	pb.CommandType_CMD_MGET_RELATIONSHIPS:              true,
+	pb.CommandType_CMD_LIST_ENTITIES:                   true,
+	pb.CommandType_CMD_LIST_RELATIONSHIPS:              true,
+	pb.CommandType_CMD_LASTSAVE:                        true,
+	pb.CommandType_CMD_BACKUP_STATUS:                   true,
+	pb.CommandType_CMD_INDEX_STATUS:                    true,
+}
+
+// resendable reports whether cmd may be sent again after a connection error
+// that happened once it was on the wire
+func resendable(ctx context.Context, cmd pb.CommandType) bool {
+	if idempotentCommands[cmd] {
+		return true
+	}
+	opted, _ := ctx.Value(writeRetryKey{}).(bool)
+	return opted
+}
@@ -2,13 +2,18 @@
 package codec
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"sync"
 
 	"github.com/gibram-io/gibram/pkg/types"
 	pb "github.com/gibram-io/gibram/proto/gibrampb"
+	"github.com/klauspost/compress/zstd"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -16,10 +21,29 @@ import (
 type CodecType byte
 
 const (
-	CodecJSON     CodecType = 0x00 // JSON encoding (legacy, default)
-	CodecProtobuf CodecType = 0x01 // Protobuf encoding (new)
+	CodecJSON         CodecType = 0x00 // JSON encoding, opt-in for debugging and tooling (see json.go)
+	CodecProtobuf     CodecType = 0x01 // Protobuf encoding (new)
+	CodecProtobufGzip CodecType = 0x02 // Protobuf encoding, gzip-compressed
+	CodecProtobufZstd CodecType = 0x03 // Protobuf encoding, zstd-compressed
 )
 
+// MaxEnvelopeSize is the largest frame payload DecodeEnvelope accepts,
+// before and after decompression
+const MaxEnvelopeSize = 64 * 1024 * 1024
+
+// DefaultCompressThreshold is the encoded envelope size above which
+// compression pays for itself; smaller envelopes are sent as CodecProtobuf
+const DefaultCompressThreshold = 1024
+
+// Frame compressions named in the AUTH handshake
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// ErrUnsupportedCodec is returned for frames with an unknown codec byte
+var ErrUnsupportedCodec = errors.New("unsupported codec")
+
 // Frame represents a wire frame
 type Frame struct {
 	CodecType CodecType
@@ -31,16 +55,64 @@ type Frame struct {
 // Protobuf Encoder
 // =============================================================================
 
-// EncodeEnvelope encodes an envelope to wire format
-func EncodeEnvelope(env *pb.Envelope) ([]byte, error) {
+var gzipWriters = sync.Pool{
+	New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.BestSpeed)
+		return w
+	},
+}
+
+// zstd encoders and decoders are safe for concurrent EncodeAll calls and
+// pooled for streaming decodes respectively
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest), zstd.WithEncoderConcurrency(1))
+	zstdDecoders   = sync.Pool{
+		New: func() any {
+			d, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+			return d
+		},
+	}
+)
+
+// EncodeEnvelope encodes an envelope to wire format. Envelopes larger than
+// compressAbove bytes are gzip-compressed (CodecProtobufGzip) when that
+// makes them smaller; compressAbove <= 0 disables compression.
+func EncodeEnvelope(env *pb.Envelope, compressAbove int) ([]byte, error) {
+	return EncodeEnvelopeCompressed(env, CompressionGzip, compressAbove)
+}
+
+// EncodeEnvelopeCompressed is like EncodeEnvelope but compresses with the
+// named compression, CompressionGzip or CompressionZstd ("" = none)
+func EncodeEnvelopeCompressed(env *pb.Envelope, compression string, compressAbove int) ([]byte, error) {
 	data, err := proto.Marshal(env)
 	if err != nil {
 		return nil, err
 	}
 
+	codecType := CodecProtobuf
+	if compressAbove > 0 && len(data) > compressAbove && compression != "" {
+		var compressed []byte
+		compressedType := CodecProtobufGzip
+		switch compression {
+		case CompressionGzip:
+			if compressed, err = gzipCompress(data); err != nil {
+				return nil, err
+			}
+		case CompressionZstd:
+			compressedType = CodecProtobufZstd
+			compressed = zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2))
+		default:
+			return nil, fmt.Errorf("unsupported compression: %s", compression)
+		}
+		if len(compressed) < len(data) {
+			codecType = compressedType
+			data = compressed
+		}
+	}
+
 	// Frame: [1 byte codec][4 bytes length][payload]
 	frame := make([]byte, 1+4+len(data))
-	frame[0] = byte(CodecProtobuf)
+	frame[0] = byte(codecType)
 	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
 	copy(frame[5:], data)
 
@@ -63,7 +135,7 @@ func DecodeEnvelope(r io.Reader) (*pb.Envelope, CodecType, error) {
 		return nil, codecType, err
 	}
 
-	if length > 64*1024*1024 { // 64MB max
+	if length > MaxEnvelopeSize {
 		return nil, codecType, errors.New("frame too large")
 	}
 
@@ -73,16 +145,108 @@ func DecodeEnvelope(r io.Reader) (*pb.Envelope, CodecType, error) {
 		return nil, codecType, err
 	}
 
-	if codecType == CodecProtobuf {
-		var env pb.Envelope
-		if err := proto.Unmarshal(payload, &env); err != nil {
-			return nil, codecType, err
+	if codecType == CodecJSON {
+		// Decoding a reply's payload needs its request; see UnmarshalJSONEnvelope
+		return nil, codecType, errors.New("JSON frames not supported in this decoder")
+	}
+
+	env, err := UnmarshalEnvelope(codecType, payload, MaxEnvelopeSize)
+	return env, codecType, err
+}
+
+// UnmarshalEnvelope decodes a frame payload according to its codec byte.
+// Compressed payloads may expand to at most maxSize bytes.
+func UnmarshalEnvelope(codecType CodecType, payload []byte, maxSize int) (*pb.Envelope, error) {
+	switch codecType {
+	case CodecProtobuf:
+	case CodecProtobufGzip:
+		data, err := gzipDecompress(payload, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		payload = data
+	case CodecProtobufZstd:
+		data, err := zstdDecompress(payload, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		payload = data
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedCodec, codecType)
+	}
+
+	var env pb.Envelope
+	if err := proto.Unmarshal(payload, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+// NegotiateCompression returns the first offered compression this codec
+// supports, or "" for none
+func NegotiateCompression(offered []string) string {
+	for _, name := range offered {
+		if name == CompressionGzip || name == CompressionZstd {
+			return name
 		}
-		return &env, codecType, nil
 	}
+	return ""
+}
 
-	// Legacy JSON - convert to envelope
-	return nil, codecType, errors.New("legacy JSON codec not supported in this decoder")
+// CompressedCodec reports whether codecType is a compressed Protobuf codec
+func CompressedCodec(codecType CodecType) bool {
+	return codecType == CodecProtobufGzip || codecType == CodecProtobufZstd
+}
+
+func gzipCompress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data) / 2)
+
+	w := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(w)
+	w.Reset(&buf)
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func gzipDecompress(data []byte, maxSize int) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+
+	out, err := io.ReadAll(io.LimitReader(r, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxSize {
+		return nil, fmt.Errorf("decompressed frame too large (max: %d)", maxSize)
+	}
+	return out, nil
+}
+
+func zstdDecompress(data []byte, maxSize int) ([]byte, error) {
+	d := zstdDecoders.Get().(*zstd.Decoder)
+	defer zstdDecoders.Put(d)
+	if err := d.Reset(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	out, err := io.ReadAll(io.LimitReader(d, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > maxSize {
+		return nil, fmt.Errorf("decompressed frame too large (max: %d)", maxSize)
+	}
+	return out, nil
 }
 
 // =============================================================================
@@ -134,6 +298,7 @@ func ProtoToTextUnit(tu *pb.TextUnit) *types.TextUnit {
 		TokenCount: int(tu.TokenCount),
 		EntityIDs:  tu.EntityIds,
 		CreatedAt:  tu.CreatedAt,
+		Embedding:  tu.Embedding,
 	}
 }
 
@@ -147,6 +312,10 @@ func EntityToProto(ent *types.Entity) *pb.Entity {
 		Description: ent.Description,
 		TextunitIds: ent.TextUnitIDs,
 		CreatedAt:   ent.CreatedAt,
+		UpdatedAt:   ent.UpdatedAt,
+		Popularity:  ent.Popularity,
+		Metadata:    ent.Metadata,
+		Pagerank:    ent.PageRank,
 	}
 }
 
@@ -160,6 +329,11 @@ func ProtoToEntity(ent *pb.Entity) *types.Entity {
 		Description: ent.Description,
 		TextUnitIDs: ent.TextunitIds,
 		CreatedAt:   ent.CreatedAt,
+		UpdatedAt:   ent.UpdatedAt,
+		Popularity:  ent.Popularity,
+		Metadata:    ent.Metadata,
+		PageRank:    ent.Pagerank,
+		Embedding:   ent.Embedding,
 	}
 }
 
@@ -174,6 +348,10 @@ func RelationshipToProto(rel *types.Relationship) *pb.Relationship {
 		Description: rel.Description,
 		Weight:      rel.Weight,
 		CreatedAt:   rel.CreatedAt,
+		UpdatedAt:   rel.UpdatedAt,
+		ValidFrom:   rel.ValidFrom,
+		ValidUntil:  rel.ValidUntil,
+		Directed:    rel.Directed,
 	}
 }
 
@@ -188,6 +366,10 @@ func ProtoToRelationship(rel *pb.Relationship) *types.Relationship {
 		Description: rel.Description,
 		Weight:      rel.Weight,
 		CreatedAt:   rel.CreatedAt,
+		UpdatedAt:   rel.UpdatedAt,
+		ValidFrom:   rel.ValidFrom,
+		ValidUntil:  rel.ValidUntil,
+		Directed:    rel.Directed,
 	}
 }
 
@@ -221,6 +403,181 @@ func ProtoToCommunity(comm *pb.Community) *types.Community {
 	}
 }
 
+// QuerySpecToProto converts types.QuerySpec to pb.QueryRequest
+func QuerySpecToProto(spec types.QuerySpec) *pb.QueryRequest {
+	var searchTypes []string
+	for _, st := range spec.SearchTypes {
+		searchTypes = append(searchTypes, string(st))
+	}
+
+	return &pb.QueryRequest{
+		QueryVector:        spec.QueryVector,
+		TopK:               int32(spec.TopK),
+		KHops:              int32(spec.KHops),
+		MaxEntities:        int32(spec.MaxEntities),
+		MaxTextunits:       int32(spec.MaxTextUnits),
+		MaxCommunities:     int32(spec.MaxCommunities),
+		SearchTypes:        searchTypes,
+		AsOf:               spec.AsOf,
+		CreatedAfter:       spec.CreatedAfter,
+		CreatedBefore:      spec.CreatedBefore,
+		IncludeTextStats:   spec.IncludeTextStats,
+		HubPenalty:         spec.HubPenalty,
+		MinResults:         int32(spec.MinResults),
+		PopularityBoost:    spec.PopularityBoost,
+		MaxExpansionPerHop: int32(spec.MaxExpansionPerHop),
+		TraversalDirection: string(spec.TraversalDirection),
+		DecayFactor:        spec.DecayFactor,
+		DeadlineMs:         int32(spec.DeadlineMs),
+		FilterEntityTypes:  spec.EntityTypes,
+		TitleWeight:        spec.TitleWeight,
+		DescriptionWeight:  spec.DescriptionWeight,
+		MinSimilarity:      spec.MinSimilarity,
+		EfSearch:           int32(spec.EfSearch),
+		MetadataFilters:    spec.MetadataFilters,
+		PagerankWeight:     spec.PageRankWeight,
+		KeywordQuery:       spec.KeywordQuery,
+		KeywordWeight:      spec.KeywordWeight,
+		FusionMethod:       string(spec.FusionMethod),
+		RrfK:               int32(spec.RRFK),
+		Diversity:          spec.Diversity,
+		PageSize:           int32(spec.PageSize),
+		Cursor:             spec.Cursor,
+	}
+}
+
+// ProtoToQuerySpec converts pb.QueryRequest to types.QuerySpec. Unset
+// fields stay zero; no defaults are applied.
+func ProtoToQuerySpec(req *pb.QueryRequest) types.QuerySpec {
+	spec := types.QuerySpec{
+		QueryVector:        req.QueryVector,
+		TopK:               int(req.TopK),
+		KHops:              int(req.KHops),
+		MaxEntities:        int(req.MaxEntities),
+		MaxTextUnits:       int(req.MaxTextunits),
+		MaxCommunities:     int(req.MaxCommunities),
+		AsOf:               req.AsOf,
+		CreatedAfter:       req.CreatedAfter,
+		CreatedBefore:      req.CreatedBefore,
+		IncludeTextStats:   req.IncludeTextStats,
+		HubPenalty:         req.HubPenalty,
+		MinResults:         int(req.MinResults),
+		PopularityBoost:    req.PopularityBoost,
+		MaxExpansionPerHop: int(req.MaxExpansionPerHop),
+		TraversalDirection: types.TraversalDirection(req.TraversalDirection),
+		DecayFactor:        req.DecayFactor,
+		DeadlineMs:         int(req.DeadlineMs),
+		EntityTypes:        req.FilterEntityTypes,
+		TitleWeight:        req.TitleWeight,
+		DescriptionWeight:  req.DescriptionWeight,
+		MinSimilarity:      req.MinSimilarity,
+		EfSearch:           int(req.EfSearch),
+		MetadataFilters:    req.MetadataFilters,
+		PageRankWeight:     req.PagerankWeight,
+		KeywordQuery:       req.KeywordQuery,
+		KeywordWeight:      req.KeywordWeight,
+		FusionMethod:       types.FusionMethod(req.FusionMethod),
+		RRFK:               int(req.RrfK),
+		Diversity:          req.Diversity,
+		PageSize:           int(req.PageSize),
+		Cursor:             req.Cursor,
+	}
+	for _, st := range req.SearchTypes {
+		spec.SearchTypes = append(spec.SearchTypes, types.SearchType(st))
+	}
+	return spec
+}
+
+// =============================================================================
+// Bulk Request Conversion
+// =============================================================================
+
+// ProtoToBulkDocuments converts MSET_DOCUMENTS items to bulk inputs
+func ProtoToBulkDocuments(docs []*pb.AddDocumentRequest) []types.BulkDocumentInput {
+	inputs := make([]types.BulkDocumentInput, len(docs))
+	for i, d := range docs {
+		inputs[i] = types.BulkDocumentInput{
+			ExternalID: d.ExternalId,
+			Filename:   d.Filename,
+		}
+	}
+	return inputs
+}
+
+// ProtoToBulkTextUnits converts MSET_TEXTUNITS items to bulk inputs
+func ProtoToBulkTextUnits(tus []*pb.AddTextUnitRequest) []types.BulkTextUnitInput {
+	inputs := make([]types.BulkTextUnitInput, len(tus))
+	for i, t := range tus {
+		inputs[i] = types.BulkTextUnitInput{
+			ExternalID: t.ExternalId,
+			DocumentID: t.DocumentId,
+			Content:    t.Content,
+			Embedding:  t.Embedding,
+			TokenCount: int(t.TokenCount),
+		}
+	}
+	return inputs
+}
+
+// ProtoToBulkEntities converts MSET_ENTITIES items to bulk inputs
+func ProtoToBulkEntities(ents []*pb.AddEntityRequest) []types.BulkEntityInput {
+	inputs := make([]types.BulkEntityInput, len(ents))
+	for i, e := range ents {
+		inputs[i] = types.BulkEntityInput{
+			ExternalID:     e.ExternalId,
+			Title:          e.Title,
+			Type:           e.Type,
+			Description:    e.Description,
+			Embedding:      e.Embedding,
+			TitleEmbedding: e.TitleEmbedding,
+			Metadata:       e.Metadata,
+		}
+	}
+	return inputs
+}
+
+// ProtoToBulkRelationships converts MSET_RELATIONSHIPS items to bulk inputs
+func ProtoToBulkRelationships(rels []*pb.AddRelationshipRequest) []types.BulkRelationshipInput {
+	inputs := make([]types.BulkRelationshipInput, len(rels))
+	for i, r := range rels {
+		inputs[i] = types.BulkRelationshipInput{
+			ExternalID:  r.ExternalId,
+			SourceID:    r.SourceId,
+			TargetID:    r.TargetId,
+			Type:        r.Type,
+			Description: r.Description,
+			Weight:      r.Weight,
+			ValidFrom:   r.ValidFrom,
+			ValidUntil:  r.ValidUntil,
+			Directed:    r.Directed,
+		}
+	}
+	return inputs
+}
+
+// ProtoToBulkLinks converts MLINK_TEXTUNIT_ENTITY pairs to bulk inputs
+func ProtoToBulkLinks(links []*pb.LinkTextUnitEntityRequest) []types.BulkLinkInput {
+	inputs := make([]types.BulkLinkInput, len(links))
+	for i, l := range links {
+		inputs[i] = types.BulkLinkInput{TextUnitID: l.TextunitId, EntityID: l.EntityId}
+	}
+	return inputs
+}
+
+// ProtoToConflictPolicy converts a merge conflict policy
+func ProtoToConflictPolicy(policy pb.MergeConflictPolicy) (types.ConflictPolicy, error) {
+	switch policy {
+	case pb.MergeConflictPolicy_MERGE_CONFLICT_REJECT:
+		return types.ConflictReject, nil
+	case pb.MergeConflictPolicy_MERGE_CONFLICT_SKIP:
+		return types.ConflictSkip, nil
+	case pb.MergeConflictPolicy_MERGE_CONFLICT_DEDUP_TITLE:
+		return types.ConflictDedupTitle, nil
+	default:
+		return 0, fmt.Errorf("unknown conflict policy %d", policy)
+	}
+}
+
 // =============================================================================
 // Binary WAL Encoding (more compact than JSON)
 // =============================================================================
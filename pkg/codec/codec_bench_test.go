@@ -0,0 +1,67 @@
+// Package codec provides wire encoding benchmarks
+package codec
+
+import (
+	"fmt"
+	"testing"
+
+	pb "github.com/gibram-io/gibram/proto/gibrampb"
+	"google.golang.org/protobuf/proto"
+)
+
+// benchQueryResponse builds a QUERY response envelope with n entities
+func benchQueryResponse(b *testing.B, n int) *pb.Envelope {
+	b.Helper()
+	resp := &pb.QueryResponse{QueryId: 1}
+	for i := 0; i < n; i++ {
+		resp.Entities = append(resp.Entities, &pb.EntityResult{
+			Entity: &pb.Entity{
+				Id:          uint64(i + 1),
+				ExternalId:  fmt.Sprintf("ent-%d", i),
+				Title:       fmt.Sprintf("ORGANIZATION %d", i),
+				Type:        "organization",
+				Description: "A financial institution supervised by the central bank, active in retail lending and payments.",
+				TextunitIds: []uint64{uint64(i), uint64(i + 1), uint64(i + 2)},
+				CreatedAt:   1700000000 + int64(i),
+			},
+			Similarity: 0.9 - float32(i)/1000,
+			Hop:        int32(i % 3),
+		})
+	}
+	payload, err := proto.Marshal(resp)
+	if err != nil {
+		b.Fatalf("Marshal() error: %v", err)
+	}
+	return &pb.Envelope{
+		Version:   1,
+		RequestId: 1,
+		CmdType:   pb.CommandType_CMD_QUERY_RESPONSE,
+		Payload:   payload,
+	}
+}
+
+// BenchmarkEncodeEnvelope_Query100 compares wire size (wire-bytes) and
+// encoding cost of a 100-entity query response with and without gzip
+func BenchmarkEncodeEnvelope_Query100(b *testing.B) {
+	env := benchQueryResponse(b, 100)
+
+	for _, bc := range []struct {
+		name          string
+		compressAbove int
+	}{
+		{"Protobuf", 0},
+		{"ProtobufGzip", DefaultCompressThreshold},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				frame, err := EncodeEnvelope(env, bc.compressAbove)
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(frame)
+			}
+			b.ReportMetric(float64(size), "wire-bytes")
+		})
+	}
+}
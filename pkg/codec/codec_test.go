@@ -2,11 +2,14 @@ package codec
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/gibram-io/gibram/pkg/types"
 	pb "github.com/gibram-io/gibram/proto/gibrampb"
+	"google.golang.org/protobuf/proto"
 )
 
 // =============================================================================
@@ -320,7 +323,7 @@ func TestEncodeDecodeEnvelope(t *testing.T) {
 	}
 
 	// Encode
-	data, err := EncodeEnvelope(env)
+	data, err := EncodeEnvelope(env, 0)
 	if err != nil {
 		t.Fatalf("failed to encode envelope: %v", err)
 	}
@@ -385,6 +388,265 @@ func TestDecodeEnvelope_LegacyJSON(t *testing.T) {
 	}
 }
 
+func TestJSONEnvelope_RoundTrip(t *testing.T) {
+	mustPayload := func(msg proto.Message) []byte {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		env     *pb.Envelope
+		replyTo pb.CommandType
+		msg     proto.Message
+		want    string // substring of the JSON
+	}{
+		{
+			name:    "request",
+			env:     &pb.Envelope{Version: 1, RequestId: 7, CmdType: pb.CommandType_CMD_ADD_ENTITY, SessionId: "s1"},
+			replyTo: pb.CommandType_CMD_UNKNOWN,
+			msg:     &pb.AddEntityRequest{ExternalId: "e1", Title: "ALICE", Type: "person"},
+			want:    `"external_id":"e1"`,
+		},
+		{
+			name:    "typed reply",
+			env:     &pb.Envelope{Version: 1, RequestId: 8, CmdType: pb.CommandType_CMD_ENTITY_RESPONSE},
+			replyTo: pb.CommandType_CMD_GET_ENTITY,
+			msg:     &pb.Entity{Id: 42, Title: "ALICE"},
+			want:    `"id":"42"`,
+		},
+		{
+			name:    "shared CMD_OK reply",
+			env:     &pb.Envelope{Version: 1, RequestId: 9, CmdType: pb.CommandType_CMD_OK},
+			replyTo: pb.CommandType_CMD_LIST_SESSIONS,
+			msg:     &pb.ListSessionsResponse{Sessions: []*pb.SessionInfo{{SessionId: "s1"}}},
+			want:    `"session_id":"s1"`,
+		},
+		{
+			name:    "default CMD_OK reply",
+			env:     &pb.Envelope{Version: 1, RequestId: 10, CmdType: pb.CommandType_CMD_OK},
+			replyTo: pb.CommandType_CMD_ADD_ENTITY,
+			msg:     &pb.OkWithID{Id: 3},
+			want:    `"id":"3"`,
+		},
+		{
+			name:    "error",
+			env:     &pb.Envelope{Version: 1, RequestId: 11, CmdType: pb.CommandType_CMD_ERROR},
+			replyTo: pb.CommandType_CMD_GET_ENTITY,
+			msg:     &pb.Error{Code: 404, Message: "not found"},
+			want:    `"message":"not found"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.env.Payload = mustPayload(tt.msg)
+			data, err := MarshalJSONEnvelope(tt.env, tt.replyTo)
+			if err != nil {
+				t.Fatalf("MarshalJSONEnvelope error: %v", err)
+			}
+			if !bytes.Contains(data, []byte(tt.want)) {
+				t.Errorf("JSON %s does not contain %s", data, tt.want)
+			}
+
+			got, err := UnmarshalJSONEnvelope(data, tt.replyTo)
+			if err != nil {
+				t.Fatalf("UnmarshalJSONEnvelope error: %v", err)
+			}
+			if got.RequestId != tt.env.RequestId || got.CmdType != tt.env.CmdType || got.SessionId != tt.env.SessionId {
+				t.Errorf("envelope = %v, want %v", got, tt.env)
+			}
+			decoded := tt.msg.ProtoReflect().New().Interface()
+			if err := proto.Unmarshal(got.Payload, decoded); err != nil {
+				t.Fatalf("Unmarshal payload error: %v", err)
+			}
+			if !proto.Equal(decoded, tt.msg) {
+				t.Errorf("payload = %v, want %v", decoded, tt.msg)
+			}
+		})
+	}
+}
+
+func TestJSONEnvelope_Base64Payload(t *testing.T) {
+	// A payload with no known message travels as base64 protobuf
+	env := &pb.Envelope{Version: 1, RequestId: 1, CmdType: pb.CommandType_CMD_PING, Payload: []byte{1, 2, 3}}
+	data, err := MarshalJSONEnvelope(env, pb.CommandType_CMD_UNKNOWN)
+	if err != nil {
+		t.Fatalf("MarshalJSONEnvelope error: %v", err)
+	}
+	if !bytes.Contains(data, []byte(`"payload":"AQID"`)) {
+		t.Errorf("JSON %s, want base64 payload", data)
+	}
+	got, err := UnmarshalJSONEnvelope(data, pb.CommandType_CMD_UNKNOWN)
+	if err != nil {
+		t.Fatalf("UnmarshalJSONEnvelope error: %v", err)
+	}
+	if !bytes.Equal(got.Payload, env.Payload) {
+		t.Errorf("payload = %v, want %v", got.Payload, env.Payload)
+	}
+
+	// Object payloads need a known message
+	if _, err := UnmarshalJSONEnvelope([]byte(`{"version":1,"cmd_type":"CMD_PING","payload":{"x":1}}`), pb.CommandType_CMD_UNKNOWN); err == nil {
+		t.Error("expected error for object payload without a known message")
+	}
+}
+
+func TestJSONEnvelope_Invalid(t *testing.T) {
+	for _, data := range []string{
+		`not json`,
+		`{"version":1,"cmd_type":"CMD_NOPE"}`,
+		`{"version":1,"cmd_type":"CMD_ADD_ENTITY","payload":{"no_such_field":1}}`,
+	} {
+		if _, err := UnmarshalJSONEnvelope([]byte(data), pb.CommandType_CMD_UNKNOWN); err == nil {
+			t.Errorf("UnmarshalJSONEnvelope(%s) should fail", data)
+		}
+	}
+}
+
+func TestEncodeJSONEnvelope(t *testing.T) {
+	env := &pb.Envelope{Version: 1, RequestId: 5, CmdType: pb.CommandType_CMD_PING}
+	frame, err := EncodeJSONEnvelope(env, pb.CommandType_CMD_UNKNOWN)
+	if err != nil {
+		t.Fatalf("EncodeJSONEnvelope error: %v", err)
+	}
+	if frame[0] != byte(CodecJSON) {
+		t.Errorf("codec byte = %d, want %d", frame[0], CodecJSON)
+	}
+	if n := binary.BigEndian.Uint32(frame[1:5]); int(n) != len(frame)-5 {
+		t.Errorf("length = %d, want %d", n, len(frame)-5)
+	}
+	got, err := UnmarshalJSONEnvelope(frame[5:], pb.CommandType_CMD_UNKNOWN)
+	if err != nil {
+		t.Fatalf("UnmarshalJSONEnvelope error: %v", err)
+	}
+	if got.RequestId != 5 || got.CmdType != pb.CommandType_CMD_PING {
+		t.Errorf("envelope = %v", got)
+	}
+}
+
+func TestEncodeDecodeEnvelope_Gzip(t *testing.T) {
+	env := &pb.Envelope{
+		RequestId: 7,
+		Version:   2,
+		CmdType:   pb.CommandType_CMD_QUERY_RESPONSE,
+		Payload:   bytes.Repeat([]byte("central bank monetary policy "), 200),
+	}
+
+	data, err := EncodeEnvelope(env, DefaultCompressThreshold)
+	if err != nil {
+		t.Fatalf("failed to encode envelope: %v", err)
+	}
+	if data[0] != byte(CodecProtobufGzip) {
+		t.Fatalf("expected codec type %d, got %d", CodecProtobufGzip, data[0])
+	}
+	if len(data) >= len(env.Payload) {
+		t.Errorf("compressed frame is %d bytes, payload alone is %d", len(data), len(env.Payload))
+	}
+
+	decoded, codecType, err := DecodeEnvelope(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode envelope: %v", err)
+	}
+	if codecType != CodecProtobufGzip {
+		t.Errorf("expected codec type %d, got %d", CodecProtobufGzip, codecType)
+	}
+	if decoded.RequestId != env.RequestId || !bytes.Equal(decoded.Payload, env.Payload) {
+		t.Error("decoded envelope does not match the original")
+	}
+}
+
+func TestEncodeDecodeEnvelope_Zstd(t *testing.T) {
+	env := &pb.Envelope{
+		RequestId: 7,
+		Version:   2,
+		CmdType:   pb.CommandType_CMD_QUERY_RESPONSE,
+		Payload:   bytes.Repeat([]byte("central bank monetary policy "), 200),
+	}
+
+	data, err := EncodeEnvelopeCompressed(env, CompressionZstd, DefaultCompressThreshold)
+	if err != nil {
+		t.Fatalf("failed to encode envelope: %v", err)
+	}
+	if data[0] != byte(CodecProtobufZstd) {
+		t.Fatalf("expected codec type %d, got %d", CodecProtobufZstd, data[0])
+	}
+	if len(data) >= len(env.Payload) {
+		t.Errorf("compressed frame is %d bytes, payload alone is %d", len(data), len(env.Payload))
+	}
+
+	decoded, codecType, err := DecodeEnvelope(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to decode envelope: %v", err)
+	}
+	if codecType != CodecProtobufZstd {
+		t.Errorf("expected codec type %d, got %d", CodecProtobufZstd, codecType)
+	}
+	if decoded.RequestId != env.RequestId || !bytes.Equal(decoded.Payload, env.Payload) {
+		t.Error("decoded envelope does not match the original")
+	}
+
+	if _, err := EncodeEnvelopeCompressed(env, "brotli", DefaultCompressThreshold); err == nil {
+		t.Error("expected error for unsupported compression")
+	}
+}
+
+func TestEncodeEnvelope_BelowThreshold(t *testing.T) {
+	env := &pb.Envelope{RequestId: 1, Payload: []byte("small")}
+
+	data, err := EncodeEnvelope(env, DefaultCompressThreshold)
+	if err != nil {
+		t.Fatalf("failed to encode envelope: %v", err)
+	}
+	if data[0] != byte(CodecProtobuf) {
+		t.Errorf("expected small envelope to stay uncompressed, got codec %d", data[0])
+	}
+}
+
+func TestUnmarshalEnvelope_DecompressedTooLarge(t *testing.T) {
+	env := &pb.Envelope{Payload: make([]byte, 4096)}
+	data, err := EncodeEnvelope(env, 1)
+	if err != nil {
+		t.Fatalf("failed to encode envelope: %v", err)
+	}
+	if data[0] != byte(CodecProtobufGzip) {
+		t.Fatalf("expected codec type %d, got %d", CodecProtobufGzip, data[0])
+	}
+
+	if _, err := UnmarshalEnvelope(CodecProtobufGzip, data[5:], 1024); err == nil {
+		t.Error("expected error for frame that decompresses past the limit")
+	}
+	zstdData, err := EncodeEnvelopeCompressed(env, CompressionZstd, 1)
+	if err != nil {
+		t.Fatalf("failed to encode envelope: %v", err)
+	}
+	if _, err := UnmarshalEnvelope(CodecProtobufZstd, zstdData[5:], 1024); err == nil {
+		t.Error("expected error for zstd frame that decompresses past the limit")
+	}
+	if _, err := UnmarshalEnvelope(CodecType(0x7f), data[5:], MaxEnvelopeSize); !errors.Is(err, ErrUnsupportedCodec) {
+		t.Errorf("expected ErrUnsupportedCodec, got %v", err)
+	}
+}
+
+func TestNegotiateCompression(t *testing.T) {
+	tests := []struct {
+		offered []string
+		want    string
+	}{
+		{nil, ""},
+		{[]string{"brotli"}, ""},
+		{[]string{"brotli", CompressionGzip}, CompressionGzip},
+		{[]string{CompressionZstd, CompressionGzip}, CompressionZstd},
+	}
+	for _, tt := range tests {
+		if got := NegotiateCompression(tt.offered); got != tt.want {
+			t.Errorf("NegotiateCompression(%v) = %q, want %q", tt.offered, got, tt.want)
+		}
+	}
+}
+
 // =============================================================================
 // Test WAL Entry Encoding/Decoding
 // =============================================================================
@@ -547,6 +809,9 @@ func TestCodecConstants(t *testing.T) {
 	if CodecProtobuf != 0x01 {
 		t.Errorf("expected CodecProtobuf = 0x01, got %x", CodecProtobuf)
 	}
+	if CodecProtobufGzip != 0x02 {
+		t.Errorf("expected CodecProtobufGzip = 0x02, got %x", CodecProtobufGzip)
+	}
 	if SnapshotMagic != 0x47494232 {
 		t.Errorf("expected SnapshotMagic = 0x47494232 (GIB2), got %x", SnapshotMagic)
 	}
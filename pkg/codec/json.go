@@ -0,0 +1,298 @@
+// Package codec - JSON frames for debugging and tooling
+package codec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+
+	pb "github.com/gibram-io/gibram/proto/gibrampb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// A CodecJSON frame carries the envelope as a JSON object whose payload is
+// the protojson form of the command's message, with proto field names:
+//
+//	{"version":1,"request_id":7,"cmd_type":"CMD_GET_ENTITY","session_id":"s1","payload":{"id":"42"}}
+//
+// 64-bit integers are strings, as protojson writes them; numbers are accepted
+// too. Payloads without a known message, such as the envelopes nested in a
+// PIPELINE, travel as base64-encoded protobuf.
+type jsonEnvelope struct {
+	Version   uint32          `json:"version"`
+	RequestID uint64          `json:"request_id"`
+	CmdType   string          `json:"cmd_type"`
+	SessionID string          `json:"session_id,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+var (
+	protojsonMarshal   = protojson.MarshalOptions{UseProtoNames: true}
+	protojsonUnmarshal = protojson.UnmarshalOptions{}
+)
+
+// requestMessages maps each command to the message its request carries.
+// Commands not listed take no payload.
+var requestMessages = map[pb.CommandType]func() proto.Message{
+	pb.CommandType_CMD_AUTH:                            func() proto.Message { return &pb.AuthRequest{} },
+	pb.CommandType_CMD_ROTATE_KEY:                      func() proto.Message { return &pb.RotateKeyRequest{} },
+	pb.CommandType_CMD_LIST_SESSIONS:                   func() proto.Message { return &pb.ListSessionsRequest{} },
+	pb.CommandType_CMD_SET_SESSION_TTL:                 func() proto.Message { return &pb.SetSessionTTLRequest{} },
+	pb.CommandType_CMD_SET_SESSION_METADATA:            func() proto.Message { return &pb.SetSessionMetadataRequest{} },
+	pb.CommandType_CMD_GRAPH_DIFF:                      func() proto.Message { return &pb.GraphDiffRequest{} },
+	pb.CommandType_CMD_ADD_DOCUMENT:                    func() proto.Message { return &pb.AddDocumentRequest{} },
+	pb.CommandType_CMD_GET_DOCUMENT:                    func() proto.Message { return &pb.GetByIDRequest{} },
+	pb.CommandType_CMD_GET_DOCUMENT_BY_EXTERNAL_ID:     func() proto.Message { return &pb.GetByExternalIDRequest{} },
+	pb.CommandType_CMD_DELETE_DOCUMENT:                 func() proto.Message { return &pb.DeleteByIDRequest{} },
+	pb.CommandType_CMD_ADD_TEXTUNIT:                    func() proto.Message { return &pb.AddTextUnitRequest{} },
+	pb.CommandType_CMD_GET_TEXTUNIT:                    func() proto.Message { return &pb.GetByIDRequest{} },
+	pb.CommandType_CMD_GET_TEXTUNIT_BY_EXTERNAL_ID:     func() proto.Message { return &pb.GetByExternalIDRequest{} },
+	pb.CommandType_CMD_DELETE_TEXTUNIT:                 func() proto.Message { return &pb.DeleteByIDRequest{} },
+	pb.CommandType_CMD_LINK_TEXTUNIT_ENTITY:            func() proto.Message { return &pb.LinkTextUnitEntityRequest{} },
+	pb.CommandType_CMD_ADD_ENTITY:                      func() proto.Message { return &pb.AddEntityRequest{} },
+	pb.CommandType_CMD_GET_ENTITY:                      func() proto.Message { return &pb.GetByIDRequest{} },
+	pb.CommandType_CMD_GET_ENTITY_BY_EXTERNAL_ID:       func() proto.Message { return &pb.GetByExternalIDRequest{} },
+	pb.CommandType_CMD_GET_ENTITY_BY_TITLE:             func() proto.Message { return &pb.GetEntityByTitleRequest{} },
+	pb.CommandType_CMD_GET_ENTITY_COMMUNITIES:          func() proto.Message { return &pb.GetByIDRequest{} },
+	pb.CommandType_CMD_UPDATE_ENTITY_DESC:              func() proto.Message { return &pb.UpdateEntityDescRequest{} },
+	pb.CommandType_CMD_UPDATE_ENTITY_TITLE:             func() proto.Message { return &pb.UpdateEntityTitleRequest{} },
+	pb.CommandType_CMD_DELETE_ENTITY:                   func() proto.Message { return &pb.DeleteByIDRequest{} },
+	pb.CommandType_CMD_MERGE_ENTITIES:                  func() proto.Message { return &pb.MergeEntitiesRequest{} },
+	pb.CommandType_CMD_ADD_RELATIONSHIP:                func() proto.Message { return &pb.AddRelationshipRequest{} },
+	pb.CommandType_CMD_GET_RELATIONSHIP:                func() proto.Message { return &pb.GetByIDRequest{} },
+	pb.CommandType_CMD_GET_RELATIONSHIP_BY_EXTERNAL_ID: func() proto.Message { return &pb.GetByExternalIDRequest{} },
+	pb.CommandType_CMD_UPDATE_RELATIONSHIP_WEIGHT:      func() proto.Message { return &pb.UpdateRelationshipWeightRequest{} },
+	pb.CommandType_CMD_DELETE_RELATIONSHIP:             func() proto.Message { return &pb.DeleteByIDRequest{} },
+	pb.CommandType_CMD_RELATIONSHIP_TYPE_STATS:         func() proto.Message { return &pb.RelationshipTypeStatsRequest{} },
+	pb.CommandType_CMD_COUNT:                           func() proto.Message { return &pb.CountRequest{} },
+	pb.CommandType_CMD_GET_NEIGHBORS:                   func() proto.Message { return &pb.GetNeighborsRequest{} },
+	pb.CommandType_CMD_SUBGRAPH:                        func() proto.Message { return &pb.SubgraphRequest{} },
+	pb.CommandType_CMD_ADD_COMMUNITY:                   func() proto.Message { return &pb.AddCommunityRequest{} },
+	pb.CommandType_CMD_GET_COMMUNITY:                   func() proto.Message { return &pb.GetByIDRequest{} },
+	pb.CommandType_CMD_DELETE_COMMUNITY:                func() proto.Message { return &pb.DeleteByIDRequest{} },
+	pb.CommandType_CMD_COMPUTE_COMMUNITIES:             func() proto.Message { return &pb.ComputeCommunitiesRequest{} },
+	pb.CommandType_CMD_HIERARCHICAL_LEIDEN:             func() proto.Message { return &pb.HierarchicalLeidenRequest{} },
+	pb.CommandType_CMD_QUERY:                           func() proto.Message { return &pb.QueryRequest{} },
+	pb.CommandType_CMD_QUERY_STREAM:                    func() proto.Message { return &pb.QueryRequest{} },
+	pb.CommandType_CMD_EXPLAIN:                         func() proto.Message { return &pb.ExplainRequest{} },
+	pb.CommandType_CMD_QUERY_STATS_SUMMARY:             func() proto.Message { return &pb.QueryStatsSummaryRequest{} },
+	pb.CommandType_CMD_LIST_QUERIES:                    func() proto.Message { return &pb.ListQueriesRequest{} },
+	pb.CommandType_CMD_GET_QUERY_RESULT:                func() proto.Message { return &pb.GetByIDRequest{} },
+	pb.CommandType_CMD_MSET_ENTITIES:                   func() proto.Message { return &pb.MSetEntitiesRequest{} },
+	pb.CommandType_CMD_MGET_ENTITIES:                   func() proto.Message { return &pb.MGetEntitiesRequest{} },
+	pb.CommandType_CMD_MSET_DOCUMENTS:                  func() proto.Message { return &pb.MSetDocumentsRequest{} },
+	pb.CommandType_CMD_MGET_DOCUMENTS:                  func() proto.Message { return &pb.MGetDocumentsRequest{} },
+	pb.CommandType_CMD_MSET_TEXTUNITS:                  func() proto.Message { return &pb.MSetTextUnitsRequest{} },
+	pb.CommandType_CMD_MGET_TEXTUNITS:                  func() proto.Message { return &pb.MGetTextUnitsRequest{} },
+	pb.CommandType_CMD_MSET_RELATIONSHIPS:              func() proto.Message { return &pb.MSetRelationshipsRequest{} },
+	pb.CommandType_CMD_MGET_RELATIONSHIPS:              func() proto.Message { return &pb.MGetRelationshipsRequest{} },
+	pb.CommandType_CMD_LIST_ENTITIES:                   func() proto.Message { return &pb.ListEntitiesRequest{} },
+	pb.CommandType_CMD_LIST_RELATIONSHIPS:              func() proto.Message { return &pb.ListRelationshipsRequest{} },
+	pb.CommandType_CMD_MLINK_TEXTUNIT_ENTITY:           func() proto.Message { return &pb.MLinkTextUnitEntityRequest{} },
+	pb.CommandType_CMD_MDELETE_TEXTUNITS:               func() proto.Message { return &pb.MDeleteRequest{} },
+	pb.CommandType_CMD_MDELETE_ENTITIES:                func() proto.Message { return &pb.MDeleteRequest{} },
+	pb.CommandType_CMD_MDELETE_RELATIONSHIPS:           func() proto.Message { return &pb.MDeleteRequest{} },
+	pb.CommandType_CMD_PIPELINE:                        func() proto.Message { return &pb.PipelineRequest{} },
+	pb.CommandType_CMD_SAVE:                            func() proto.Message { return &pb.SaveRequest{} },
+	pb.CommandType_CMD_BGSAVE:                          func() proto.Message { return &pb.SaveRequest{} },
+	pb.CommandType_CMD_BGRESTORE:                       func() proto.Message { return &pb.RestoreRequest{} },
+	pb.CommandType_CMD_QUANTIZE_INDEX:                  func() proto.Message { return &pb.QuantizeIndexRequest{} },
+	pb.CommandType_CMD_REBUILD_INDEX:                   func() proto.Message { return &pb.RebuildIndexRequest{} },
+	pb.CommandType_CMD_UPLOAD_SNAPSHOT:                 func() proto.Message { return &pb.SnapshotChunk{} },
+	pb.CommandType_CMD_IMPORT_SESSION:                  func() proto.Message { return &pb.SnapshotChunk{} },
+	pb.CommandType_CMD_MERGE_SESSION:                   func() proto.Message { return &pb.MergeSessionRequest{} },
+}
+
+// replyMessages maps each command to the message of its successful reply,
+// keyed by the request since several reply types are shared (CMD_OK,
+// CMD_COMMUNITIES_RESPONSE, CMD_BACKUP_RESPONSE). Commands not listed reply
+// CMD_OK with an OkWithID, or with no payload.
+var replyMessages = map[pb.CommandType]func() proto.Message{
+	pb.CommandType_CMD_INFO:                            func() proto.Message { return &pb.InfoResponse{} },
+	pb.CommandType_CMD_HEALTH:                          func() proto.Message { return &pb.HealthResponse{} },
+	pb.CommandType_CMD_AUTH:                            func() proto.Message { return &pb.AuthResponse{} },
+	pb.CommandType_CMD_ROTATE_KEY:                      func() proto.Message { return &pb.RotateKeyResponse{} },
+	pb.CommandType_CMD_STATS:                           func() proto.Message { return &pb.StatsResponse{} },
+	pb.CommandType_CMD_LIST_SESSIONS:                   func() proto.Message { return &pb.ListSessionsResponse{} },
+	pb.CommandType_CMD_SESSION_INFO:                    func() proto.Message { return &pb.SessionInfo{} },
+	pb.CommandType_CMD_GET_SESSION_METADATA:            func() proto.Message { return &pb.SessionMetadataResponse{} },
+	pb.CommandType_CMD_GRAPH_DIFF:                      func() proto.Message { return &pb.GraphDiffResponse{} },
+	pb.CommandType_CMD_GET_DOCUMENT:                    func() proto.Message { return &pb.Document{} },
+	pb.CommandType_CMD_GET_DOCUMENT_BY_EXTERNAL_ID:     func() proto.Message { return &pb.Document{} },
+	pb.CommandType_CMD_GET_TEXTUNIT:                    func() proto.Message { return &pb.TextUnit{} },
+	pb.CommandType_CMD_GET_TEXTUNIT_BY_EXTERNAL_ID:     func() proto.Message { return &pb.TextUnit{} },
+	pb.CommandType_CMD_GET_ENTITY:                      func() proto.Message { return &pb.Entity{} },
+	pb.CommandType_CMD_GET_ENTITY_BY_EXTERNAL_ID:       func() proto.Message { return &pb.Entity{} },
+	pb.CommandType_CMD_GET_ENTITY_BY_TITLE:             func() proto.Message { return &pb.Entity{} },
+	pb.CommandType_CMD_GET_ENTITY_COMMUNITIES:          func() proto.Message { return &pb.CommunitiesResponse{} },
+	pb.CommandType_CMD_GET_RELATIONSHIP:                func() proto.Message { return &pb.Relationship{} },
+	pb.CommandType_CMD_GET_RELATIONSHIP_BY_EXTERNAL_ID: func() proto.Message { return &pb.Relationship{} },
+	pb.CommandType_CMD_RELATIONSHIP_TYPE_STATS:         func() proto.Message { return &pb.RelationshipTypeStatsResponse{} },
+	pb.CommandType_CMD_ENTITY_STATS:                    func() proto.Message { return &pb.EntityStatsResponse{} },
+	pb.CommandType_CMD_COUNT:                           func() proto.Message { return &pb.CountResponse{} },
+	pb.CommandType_CMD_GET_NEIGHBORS:                   func() proto.Message { return &pb.RelationshipsResponse{} },
+	pb.CommandType_CMD_SUBGRAPH:                        func() proto.Message { return &pb.SubgraphResponse{} },
+	pb.CommandType_CMD_GET_COMMUNITY:                   func() proto.Message { return &pb.Community{} },
+	pb.CommandType_CMD_COMPUTE_COMMUNITIES:             func() proto.Message { return &pb.ComputeCommunitiesResponse{} },
+	pb.CommandType_CMD_HIERARCHICAL_LEIDEN:             func() proto.Message { return &pb.HierarchicalLeidenResponse{} },
+	pb.CommandType_CMD_COMPUTE_PAGERANK:                func() proto.Message { return &pb.PageRankResponse{} },
+	pb.CommandType_CMD_QUERY:                           func() proto.Message { return &pb.QueryResponse{} },
+	pb.CommandType_CMD_QUERY_STREAM:                    func() proto.Message { return &pb.QueryStreamChunk{} },
+	pb.CommandType_CMD_EXPLAIN:                         func() proto.Message { return &pb.ExplainResponse{} },
+	pb.CommandType_CMD_QUERY_STATS_SUMMARY:             func() proto.Message { return &pb.QueryStatsSummaryResponse{} },
+	pb.CommandType_CMD_LIST_QUERIES:                    func() proto.Message { return &pb.ListQueriesResponse{} },
+	pb.CommandType_CMD_GET_QUERY_RESULT:                func() proto.Message { return &pb.QueryResponse{} },
+	pb.CommandType_CMD_MSET_ENTITIES:                   func() proto.Message { return &pb.EntitiesResponse{} },
+	pb.CommandType_CMD_MGET_ENTITIES:                   func() proto.Message { return &pb.EntitiesResponse{} },
+	pb.CommandType_CMD_LIST_ENTITIES:                   func() proto.Message { return &pb.EntitiesResponse{} },
+	pb.CommandType_CMD_MSET_DOCUMENTS:                  func() proto.Message { return &pb.DocumentsResponse{} },
+	pb.CommandType_CMD_MGET_DOCUMENTS:                  func() proto.Message { return &pb.DocumentsResponse{} },
+	pb.CommandType_CMD_MSET_TEXTUNITS:                  func() proto.Message { return &pb.TextUnitsResponse{} },
+	pb.CommandType_CMD_MGET_TEXTUNITS:                  func() proto.Message { return &pb.TextUnitsResponse{} },
+	pb.CommandType_CMD_MSET_RELATIONSHIPS:              func() proto.Message { return &pb.RelationshipsResponse{} },
+	pb.CommandType_CMD_MGET_RELATIONSHIPS:              func() proto.Message { return &pb.RelationshipsResponse{} },
+	pb.CommandType_CMD_LIST_RELATIONSHIPS:              func() proto.Message { return &pb.RelationshipsResponse{} },
+	pb.CommandType_CMD_MLINK_TEXTUNIT_ENTITY:           func() proto.Message { return &pb.MLinkTextUnitEntityResponse{} },
+	pb.CommandType_CMD_MDELETE_TEXTUNITS:               func() proto.Message { return &pb.MDeleteResponse{} },
+	pb.CommandType_CMD_MDELETE_ENTITIES:                func() proto.Message { return &pb.MDeleteResponse{} },
+	pb.CommandType_CMD_MDELETE_RELATIONSHIPS:           func() proto.Message { return &pb.MDeleteResponse{} },
+	pb.CommandType_CMD_PIPELINE:                        func() proto.Message { return &pb.PipelineResponse{} },
+	pb.CommandType_CMD_LASTSAVE:                        func() proto.Message { return &pb.LastSaveResponse{} },
+	pb.CommandType_CMD_BACKUP_STATUS:                   func() proto.Message { return &pb.BackupStatusResponse{} },
+	pb.CommandType_CMD_WAL_STATUS:                      func() proto.Message { return &pb.WALStatusResponse{} },
+	pb.CommandType_CMD_QUANTIZE_INDEX:                  func() proto.Message { return &pb.QuantizeIndexResponse{} },
+	pb.CommandType_CMD_INDEX_STATUS:                    func() proto.Message { return &pb.IndexStatusResponse{} },
+}
+
+// PayloadMessage returns an empty message for the payload of an envelope of
+// type cmd, or nil when it has no known message. replyTo is the command the
+// envelope answers, or CMD_UNKNOWN for a request.
+func PayloadMessage(cmd, replyTo pb.CommandType) proto.Message {
+	if replyTo == pb.CommandType_CMD_UNKNOWN {
+		if newMsg, ok := requestMessages[cmd]; ok {
+			return newMsg()
+		}
+		return nil
+	}
+
+	switch cmd {
+	case pb.CommandType_CMD_ERROR:
+		return &pb.Error{}
+	case pb.CommandType_CMD_SNAPSHOT_CHUNK:
+		return &pb.SnapshotChunk{}
+	}
+	if newMsg, ok := replyMessages[replyTo]; ok {
+		return newMsg()
+	}
+	if cmd == pb.CommandType_CMD_OK {
+		return &pb.OkWithID{}
+	}
+	return nil
+}
+
+// PayloadToJSON renders the protobuf payload of an envelope of type cmd as
+// protojson with proto field names. It fails when the payload has no known
+// message; see PayloadMessage for replyTo.
+func PayloadToJSON(cmd, replyTo pb.CommandType, payload []byte) ([]byte, error) {
+	msg := PayloadMessage(cmd, replyTo)
+	if msg == nil {
+		return nil, fmt.Errorf("%s payload: no known message", cmd)
+	}
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("%s payload: %w", cmd, err)
+	}
+	return protojsonMarshal.Marshal(msg)
+}
+
+// PayloadFromJSON is the inverse of PayloadToJSON, encoding the protojson
+// data as the protobuf payload of an envelope of type cmd
+func PayloadFromJSON(cmd, replyTo pb.CommandType, data []byte) ([]byte, error) {
+	msg := PayloadMessage(cmd, replyTo)
+	if msg == nil {
+		return nil, fmt.Errorf("%s payload: no known message", cmd)
+	}
+	if err := protojsonUnmarshal.Unmarshal(data, msg); err != nil {
+		return nil, fmt.Errorf("%s payload: %w", cmd, err)
+	}
+	return proto.Marshal(msg)
+}
+
+// MarshalJSONEnvelope renders env as JSON. replyTo is the command env
+// answers, or CMD_UNKNOWN when env is a request.
+func MarshalJSONEnvelope(env *pb.Envelope, replyTo pb.CommandType) ([]byte, error) {
+	out := jsonEnvelope{
+		Version:   env.Version,
+		RequestID: env.RequestId,
+		CmdType:   env.CmdType.String(),
+		SessionID: env.SessionId,
+	}
+
+	if len(env.Payload) > 0 {
+		var err error
+		if PayloadMessage(env.CmdType, replyTo) != nil {
+			out.Payload, err = PayloadToJSON(env.CmdType, replyTo, env.Payload)
+		} else {
+			out.Payload, err = json.Marshal(env.Payload)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSONEnvelope parses a JSON envelope, re-encoding its payload as
+// protobuf. replyTo is as for MarshalJSONEnvelope.
+func UnmarshalJSONEnvelope(data []byte, replyTo pb.CommandType) (*pb.Envelope, error) {
+	var in jsonEnvelope
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid JSON envelope: %w", err)
+	}
+	cmd, ok := pb.CommandType_value[in.CmdType]
+	if !ok {
+		return nil, fmt.Errorf("invalid JSON envelope: unknown cmd_type %q", in.CmdType)
+	}
+
+	env := &pb.Envelope{
+		Version:   in.Version,
+		RequestId: in.RequestID,
+		CmdType:   pb.CommandType(cmd),
+		SessionId: in.SessionID,
+	}
+
+	raw := bytes.TrimSpace(in.Payload)
+	switch {
+	case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+	case raw[0] == '"':
+		if err := json.Unmarshal(raw, &env.Payload); err != nil {
+			return nil, fmt.Errorf("%s payload: %w", env.CmdType, err)
+		}
+	default:
+		if PayloadMessage(env.CmdType, replyTo) == nil {
+			return nil, fmt.Errorf("%s payload: no known message; send base64 protobuf", env.CmdType)
+		}
+		payload, err := PayloadFromJSON(env.CmdType, replyTo, raw)
+		if err != nil {
+			return nil, err
+		}
+		env.Payload = payload
+	}
+	return env, nil
+}
+
+// EncodeJSONEnvelope encodes env as a CodecJSON frame; see
+// MarshalJSONEnvelope for replyTo
+func EncodeJSONEnvelope(env *pb.Envelope, replyTo pb.CommandType) ([]byte, error) {
+	data, err := MarshalJSONEnvelope(env, replyTo)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, 1+4+len(data))
+	frame[0] = byte(CodecJSON)
+	binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
+	copy(frame[5:], data)
+	return frame, nil
+}
@@ -7,7 +7,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -109,6 +111,12 @@ type Config struct {
 	Auth     AuthConfig     `yaml:"auth"`
 	Security SecurityConfig `yaml:"security"`
 	Logging  LoggingConfig  `yaml:"logging"`
+	Backup   BackupConfig   `yaml:"backup"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+	HTTP     HTTPConfig     `yaml:"http"`
+	Audit    AuditConfig    `yaml:"audit"`
+	Session  SessionConfig  `yaml:"session"`
 }
 
 // ServerConfig contains server settings
@@ -116,6 +124,51 @@ type ServerConfig struct {
 	Addr      string `yaml:"addr"`
 	DataDir   string `yaml:"data_dir"`
 	VectorDim int    `yaml:"vector_dim"`
+
+	// Reject embeddings whose L2 norm falls outside this range (0 = no bound).
+	// Catches all-zero or mis-scaled vectors from broken embedding pipelines.
+	EmbeddingMinNorm float64 `yaml:"embedding_min_norm"`
+	EmbeddingMaxNorm float64 `yaml:"embedding_max_norm"`
+
+	// Scale ingested embeddings to unit L2 norm before storing them, for
+	// clients that forget to; cosine scores are then plain dot products.
+	NormalizeOnIngest bool `yaml:"normalize_on_ingest"`
+
+	// Merge an added entity into an existing one of the same type whose
+	// embedding has at least this cosine similarity (0 = disabled)
+	DedupThreshold float32 `yaml:"dedup_threshold"`
+
+	// Half-life of per-entity access counters used for popularity features
+	// (0 = tracking disabled; it adds a write on every entity read).
+	PopularityHalfLife time.Duration `yaml:"popularity_half_life"`
+
+	// Cache up to QueryCacheSize query results, reused by identical queries
+	// until their session changes or QueryCacheTTL passes (0 = no cache)
+	QueryCacheSize int           `yaml:"query_cache_size"`
+	QueryCacheTTL  time.Duration `yaml:"query_cache_ttl"`
+
+	// Recent queries each session keeps for LIST_QUERIES and
+	// GET_QUERY_RESULT (0 = the default of 100, negative = no history)
+	QueryHistorySize int `yaml:"query_history_size"`
+
+	// Writes to an expired session start a fresh session under the same ID
+	// instead of failing with "session expired".
+	RecreateExpiredSessions bool `yaml:"recreate_expired_sessions"`
+
+	// Vector search metric for new sessions: cosine (default), dot, euclidean
+	DistanceMetric string `yaml:"distance_metric"`
+
+	// HNSW index parameters for new sessions (0 = default)
+	HNSWM              int `yaml:"hnsw_m"`
+	HNSWEfConstruction int `yaml:"hnsw_ef_construction"`
+	HNSWEfSearch       int `yaml:"hnsw_ef_search"`
+
+	// Vector storage precision for new sessions: float32 (default), float16
+	// or int8, which halve and quarter vector memory at a small recall cost
+	VectorPrecision string `yaml:"vector_precision"`
+
+	// When WAL appends reach disk: always, periodic (default, every second), never
+	WALSync string `yaml:"wal_sync"`
 }
 
 // TLSConfig contains TLS settings
@@ -132,11 +185,15 @@ type AuthConfig struct {
 
 // APIKeyConfig represents an API key
 type APIKeyConfig struct {
-	ID          string   `yaml:"id"`
-	Key         string   `yaml:"key"`          // Plain text in config
-	KeyHash     string   `yaml:"key_hash"`     // Or bcrypt hash (if Key is empty)
-	Permissions []string `yaml:"permissions"`  // admin, write, read
-	ExpiresAt   string   `yaml:"expires_at"`   // Optional: RFC3339 format
+	ID              string   `yaml:"id"`
+	Key             string   `yaml:"key"`              // Plain text in config
+	KeyHash         string   `yaml:"key_hash"`         // Or bcrypt hash (if Key is empty)
+	KeyHashes       []string `yaml:"key_hashes"`       // Optional: older hashes still accepted while rotating, oldest first
+	Permissions     []string `yaml:"permissions"`      // admin, write, read
+	ExpiresAt       string   `yaml:"expires_at"`       // Optional: RFC3339 format
+	SessionPrefixes []string `yaml:"session_prefixes"` // Optional: sessions this key may use, by ID prefix (admin keys ignore it)
+	RateLimit       int      `yaml:"rate_limit"`       // Optional: requests per second, overrides security.rate_limit
+	RateBurst       int      `yaml:"rate_burst"`       // Optional: burst allowance, overrides security.rate_burst
 }
 
 // SecurityConfig contains security settings
@@ -147,6 +204,28 @@ type SecurityConfig struct {
 	IdleTimeout    time.Duration `yaml:"idle_timeout"`     // Idle connection timeout
 	UnauthTimeout  time.Duration `yaml:"unauth_timeout"`   // Timeout for unauthenticated
 	MaxConnsPerIP  int           `yaml:"max_conns_per_ip"` // Max connections per IP
+
+	// MaxConnections caps open client connections; connections beyond it
+	// are closed as soon as they are accepted. 0 = unlimited.
+	MaxConnections int `yaml:"max_connections"`
+
+	// CommandTimeout bounds how long a query or community computation may
+	// run before it fails with a timeout error. 0 = unlimited.
+	CommandTimeout time.Duration `yaml:"command_timeout"`
+
+	// DisabledCommands lists commands rejected regardless of permission,
+	// e.g. ["CMD_DELETE_SESSION"]. The "CMD_" prefix is optional.
+	DisabledCommands []string `yaml:"disabled_commands"`
+
+	// AllowJSONCodec accepts JSON frames (codec byte 0x00) for debugging and
+	// for clients without protobuf. Off by default; JSON is slow and large.
+	AllowJSONCodec bool `yaml:"allow_json_codec"`
+
+	// AllowedCIDRs, if set, limits connections to client addresses in these
+	// ranges; DeniedCIDRs refuses addresses in its ranges and takes
+	// precedence. Entries are CIDRs ("10.0.0.0/8") or single addresses.
+	AllowedCIDRs []string `yaml:"allowed_cidrs"`
+	DeniedCIDRs  []string `yaml:"denied_cidrs"`
 }
 
 // LoggingConfig contains logging settings
@@ -157,6 +236,54 @@ type LoggingConfig struct {
 	File   string `yaml:"file"`   // Log file path if output=file
 }
 
+// BackupConfig contains automatic snapshot settings
+type BackupConfig struct {
+	SnapshotInterval  time.Duration `yaml:"snapshot_interval"`  // 0 = snapshots only on SAVE/BGSAVE
+	SnapshotRetention int           `yaml:"snapshot_retention"` // scheduled snapshots kept (0 = all)
+
+	// Persist changed sessions to <data_dir>/state on this interval, as an
+	// append log compacted into a base file once it reaches
+	// DurableCompactSize bytes (0 = engine default). 0 disables it.
+	DurableInterval    time.Duration `yaml:"durable_interval"`
+	DurableCompactSize int64         `yaml:"durable_compact_size"`
+}
+
+// MetricsConfig contains the Prometheus endpoint and slow-query log settings
+type MetricsConfig struct {
+	Addr string `yaml:"addr"` // HTTP listen address for /metrics ("" = disabled)
+
+	// Commands slower than this are logged with session, command and
+	// duration (0 = disabled)
+	SlowQueryThresholdMs int `yaml:"slow_query_threshold_ms"`
+}
+
+// TracingConfig contains the OpenTelemetry trace export settings
+type TracingConfig struct {
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/HTTP collector host:port ("" = disabled)
+	Insecure    bool    `yaml:"insecure"`     // export over plain HTTP instead of HTTPS
+	SampleRatio float64 `yaml:"sample_ratio"` // fraction of traces started here that are recorded
+}
+
+// HTTPConfig contains the JSON over HTTP gateway settings
+type HTTPConfig struct {
+	Addr string `yaml:"addr"` // HTTP listen address ("" = disabled)
+}
+
+// AuditConfig contains the audit trail settings
+type AuditConfig struct {
+	File         string `yaml:"file"`          // JSON lines audit log ("" = disabled)
+	IncludeReads bool   `yaml:"include_reads"` // also record read commands, not just writes and admin
+}
+
+// SessionConfig contains per-session resource limits
+type SessionConfig struct {
+	// Approximate bytes of text and vectors one session may hold; adds to a
+	// session at or over it fail (0 = unlimited)
+	MaxBytesPerSession int64 `yaml:"max_bytes_per_session"`
+	// Directory where expired sessions are exported before removal ("" = discard)
+	EvictionExportDir string `yaml:"eviction_export_dir"`
+}
+
 // =============================================================================
 // Default Configuration
 // =============================================================================
@@ -184,6 +311,7 @@ func DefaultConfig() *Config {
 			IdleTimeout:    300 * time.Second,
 			UnauthTimeout:  10 * time.Second,
 			MaxConnsPerIP:  50,
+			MaxConnections: 10000,
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -191,6 +319,9 @@ func DefaultConfig() *Config {
 			Output: "stdout",
 			File:   "",
 		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
 	}
 }
 
@@ -267,32 +398,52 @@ const (
 
 // APIKeyStore manages API keys in memory
 type APIKeyStore struct {
-	keys map[string]*APIKey // key hash -> APIKey
+	mu      sync.RWMutex
+	keys    map[string]*APIKey      // key hash -> APIKey
+	rotated map[string]*keyRotation // key ID -> changes made by Rotate
+}
+
+// keyRotation is what Rotate changed on a key, so a store rebuilt from the
+// config file can apply it again
+type keyRotation struct {
+	added   []string
+	retired []string
 }
 
 // APIKey represents a validated API key
 type APIKey struct {
-	ID          string
-	Hash        string
-	Permissions map[string]bool
-	ExpiresAt   time.Time
+	ID              string
+	Hashes          []string // accepted hashes, oldest first
+	Permissions     map[string]bool
+	ExpiresAt       time.Time
+	SessionPrefixes []string // empty = all sessions
+	RateLimit       int      // 0 = server default
+	RateBurst       int      // 0 = server default
 }
 
 // NewAPIKeyStore creates a new API key store from config
 func NewAPIKeyStore(cfg *AuthConfig) (*APIKeyStore, error) {
 	store := &APIKeyStore{
-		keys: make(map[string]*APIKey),
+		keys:    make(map[string]*APIKey),
+		rotated: make(map[string]*keyRotation),
 	}
 
 	for _, keyCfg := range cfg.Keys {
-		if keyCfg.KeyHash == "" {
+		hashes := keyCfg.KeyHashes
+		if keyCfg.KeyHash != "" {
+			hashes = append(hashes[:len(hashes):len(hashes)], keyCfg.KeyHash)
+		}
+		if len(hashes) == 0 {
 			continue
 		}
 
 		apiKey := &APIKey{
-			ID:          keyCfg.ID,
-			Hash:        keyCfg.KeyHash,
-			Permissions: make(map[string]bool),
+			ID:              keyCfg.ID,
+			Hashes:          hashes,
+			Permissions:     make(map[string]bool),
+			SessionPrefixes: keyCfg.SessionPrefixes,
+			RateLimit:       keyCfg.RateLimit,
+			RateBurst:       keyCfg.RateBurst,
 		}
 
 		for _, perm := range keyCfg.Permissions {
@@ -307,7 +458,9 @@ func NewAPIKeyStore(cfg *AuthConfig) (*APIKeyStore, error) {
 			apiKey.ExpiresAt = t
 		}
 
-		store.keys[keyCfg.KeyHash] = apiKey
+		for _, hash := range hashes {
+			store.keys[hash] = apiKey
+		}
 	}
 
 	return store, nil
@@ -315,9 +468,12 @@ func NewAPIKeyStore(cfg *AuthConfig) (*APIKeyStore, error) {
 
 // Validate validates an API key and returns the key info if valid
 func (s *APIKeyStore) Validate(plainKey string) (*APIKey, error) {
-	// Check each stored key hash
-	for _, apiKey := range s.keys {
-		if err := bcrypt.CompareHashAndPassword([]byte(apiKey.Hash), []byte(plainKey)); err == nil {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Check each stored key hash, including every hash of a key being rotated
+	for hash, apiKey := range s.keys {
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(plainKey)); err == nil {
 			// Key matches
 			if !apiKey.ExpiresAt.IsZero() && time.Now().After(apiKey.ExpiresAt) {
 				return nil, fmt.Errorf("api key expired")
@@ -328,6 +484,102 @@ func (s *APIKeyStore) Validate(plainKey string) (*APIKey, error) {
 	return nil, fmt.Errorf("invalid api key")
 }
 
+// Lookup returns the key with id
+func (s *APIKeyStore) Lookup(id string) (*APIKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.keys {
+		if k.ID == id {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// Rotate adds newHash to the key with id, so the new and existing keys both
+// authenticate. With retireOldest, the oldest hash stops being accepted. It
+// returns the number of hashes the key now accepts. Rotation is in memory
+// and carried across reloads by CarryRotations; add the hash to key_hashes
+// in the config file to keep it across restarts.
+func (s *APIKeyStore) Rotate(id, newHash string, retireOldest bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var apiKey *APIKey
+	for _, k := range s.keys {
+		if k.ID == id {
+			apiKey = k
+			break
+		}
+	}
+	if apiKey == nil {
+		return 0, fmt.Errorf("unknown api key id %q", id)
+	}
+	if _, exists := s.keys[newHash]; exists {
+		return 0, fmt.Errorf("hash already in use")
+	}
+
+	rot := s.rotated[id]
+	if rot == nil {
+		rot = &keyRotation{}
+		s.rotated[id] = rot
+	}
+	apiKey.Hashes = append(apiKey.Hashes, newHash)
+	s.keys[newHash] = apiKey
+	rot.added = append(rot.added, newHash)
+	if retireOldest {
+		retired := apiKey.Hashes[0]
+		delete(s.keys, retired)
+		apiKey.Hashes = apiKey.Hashes[1:]
+		rot.retired = append(rot.retired, retired)
+	}
+	return len(apiKey.Hashes), nil
+}
+
+// CarryRotations applies the rotations made on from to s, which was just
+// built from the config file, so a reload neither drops a new key nor
+// brings back a retired one. Rotations of keys no longer in the file are
+// dropped. It returns the number of keys whose rotations were carried.
+func (s *APIKeyStore) CarryRotations(from *APIKeyStore) int {
+	from.mu.RLock()
+	rotated := make(map[string]keyRotation, len(from.rotated))
+	for id, rot := range from.rotated {
+		rotated[id] = keyRotation{added: slices.Clone(rot.added), retired: slices.Clone(rot.retired)}
+	}
+	from.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	carried := 0
+	for id, rot := range rotated {
+		var apiKey *APIKey
+		for _, k := range s.keys {
+			if k.ID == id {
+				apiKey = k
+				break
+			}
+		}
+		if apiKey == nil {
+			continue
+		}
+		for _, hash := range rot.added {
+			if _, exists := s.keys[hash]; !exists {
+				apiKey.Hashes = append(apiKey.Hashes, hash)
+				s.keys[hash] = apiKey
+			}
+		}
+		for _, hash := range rot.retired {
+			if s.keys[hash] == apiKey {
+				delete(s.keys, hash)
+				apiKey.Hashes = slices.DeleteFunc(apiKey.Hashes, func(h string) bool { return h == hash })
+			}
+		}
+		s.rotated[id] = &rot
+		carried++
+	}
+	return carried
+}
+
 // HasPermission checks if a key has a specific permission
 func (k *APIKey) HasPermission(perm string) bool {
 	// Admin has all permissions
@@ -341,6 +593,20 @@ func (k *APIKey) HasPermission(perm string) bool {
 	return k.Permissions[perm]
 }
 
+// CanAccessSession checks if a key may use a session. Keys without
+// SessionPrefixes and admin keys may use any session.
+func (k *APIKey) CanAccessSession(sessionID string) bool {
+	if len(k.SessionPrefixes) == 0 || k.Permissions[PermAdmin] {
+		return true
+	}
+	for _, prefix := range k.SessionPrefixes {
+		if strings.HasPrefix(sessionID, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 // =============================================================================
 // Key Generation Utilities
 // =============================================================================
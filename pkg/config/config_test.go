@@ -419,6 +419,113 @@ func TestAPIKeyStore_Validate_ExpiredKey(t *testing.T) {
 	}
 }
 
+func TestAPIKeyStore_Rotate(t *testing.T) {
+	oldHash, _ := HashAPIKey("old-key")
+	midHash, _ := HashAPIKey("mid-key")
+	newHash, _ := HashAPIKey("new-key")
+
+	store, err := NewAPIKeyStore(&AuthConfig{
+		Keys: []APIKeyConfig{
+			{
+				ID:          "app",
+				KeyHashes:   []string{oldHash},
+				KeyHash:     midHash,
+				Permissions: []string{PermWrite},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to create API key store: %v", err)
+	}
+
+	// key_hashes and key_hash all authenticate as the same key
+	for _, key := range []string{"old-key", "mid-key"} {
+		if apiKey, err := store.Validate(key); err != nil || apiKey.ID != "app" {
+			t.Errorf("Validate(%s) = %v, %v; want key app", key, apiKey, err)
+		}
+	}
+
+	// Rotating with retirement drops the oldest hash and keeps the rest
+	active, err := store.Rotate("app", newHash, true)
+	if err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+	if active != 2 {
+		t.Errorf("expected 2 active hashes, got %d", active)
+	}
+	if _, err := store.Validate("old-key"); err == nil {
+		t.Error("expected retired key to stop working")
+	}
+	for _, key := range []string{"mid-key", "new-key"} {
+		if _, err := store.Validate(key); err != nil {
+			t.Errorf("Validate(%s) error: %v", key, err)
+		}
+	}
+
+	if _, err := store.Rotate("missing", oldHash, false); err == nil {
+		t.Error("expected error for unknown key id")
+	}
+	if _, err := store.Rotate("app", newHash, false); err == nil {
+		t.Error("expected error for a hash already in use")
+	}
+}
+
+func TestAPIKeyStore_CarryRotations(t *testing.T) {
+	oldHash, _ := HashAPIKey("old-key")
+	newHash, _ := HashAPIKey("new-key")
+	otherHash, _ := HashAPIKey("other-key")
+	auth := &AuthConfig{
+		Keys: []APIKeyConfig{
+			{ID: "app", KeyHash: oldHash, Permissions: []string{PermWrite}},
+			{ID: "other", KeyHash: otherHash, Permissions: []string{PermRead}},
+		},
+	}
+
+	store, err := NewAPIKeyStore(auth)
+	if err != nil {
+		t.Fatalf("failed to create API key store: %v", err)
+	}
+	if _, err := store.Rotate("app", newHash, true); err != nil {
+		t.Fatalf("Rotate() error: %v", err)
+	}
+
+	// A reload rebuilds the store from the unchanged file: the retired key
+	// must stay retired and the new one keep working
+	reloaded, err := NewAPIKeyStore(auth)
+	if err != nil {
+		t.Fatalf("failed to create API key store: %v", err)
+	}
+	if n := reloaded.CarryRotations(store); n != 1 {
+		t.Errorf("CarryRotations() = %d, want 1", n)
+	}
+	if _, err := reloaded.Validate("old-key"); err == nil {
+		t.Error("retired key works again after reload")
+	}
+	for _, key := range []string{"new-key", "other-key"} {
+		if _, err := reloaded.Validate(key); err != nil {
+			t.Errorf("Validate(%s) after reload error: %v", key, err)
+		}
+	}
+
+	// Carried rotations are carried again by the next reload
+	again, _ := NewAPIKeyStore(auth)
+	if n := again.CarryRotations(reloaded); n != 1 {
+		t.Errorf("second CarryRotations() = %d, want 1", n)
+	}
+	if _, err := again.Validate("old-key"); err == nil {
+		t.Error("retired key works again after a second reload")
+	}
+
+	// Rotations of a key removed from the file are dropped
+	removed, _ := NewAPIKeyStore(&AuthConfig{Keys: auth.Keys[1:]})
+	if n := removed.CarryRotations(store); n != 0 {
+		t.Errorf("CarryRotations() for a removed key = %d, want 0", n)
+	}
+	if _, err := removed.Validate("new-key"); err == nil {
+		t.Error("rotated key of a removed ID still works")
+	}
+}
+
 func TestAPIKey_HasPermission(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -474,6 +581,30 @@ func TestAPIKey_HasPermission(t *testing.T) {
 	}
 }
 
+func TestAPIKey_CanAccessSession(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      *APIKey
+		session  string
+		expected bool
+	}{
+		{"no prefixes", &APIKey{Permissions: map[string]bool{PermRead: true}}, "anything", true},
+		{"prefix match", &APIKey{Permissions: map[string]bool{PermWrite: true}, SessionPrefixes: []string{"tenant-a/"}}, "tenant-a/docs", true},
+		{"second prefix", &APIKey{Permissions: map[string]bool{PermWrite: true}, SessionPrefixes: []string{"tenant-a/", "shared/"}}, "shared/x", true},
+		{"other tenant", &APIKey{Permissions: map[string]bool{PermWrite: true}, SessionPrefixes: []string{"tenant-a/"}}, "tenant-b/docs", false},
+		{"prefix without separator", &APIKey{Permissions: map[string]bool{PermWrite: true}, SessionPrefixes: []string{"tenant-a/"}}, "tenant-a", false},
+		{"admin bypass", &APIKey{Permissions: map[string]bool{PermAdmin: true}, SessionPrefixes: []string{"tenant-a/"}}, "tenant-b/docs", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.CanAccessSession(tt.session); got != tt.expected {
+				t.Errorf("CanAccessSession(%q) = %v, want %v", tt.session, got, tt.expected)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // Test Key Generation
 // =============================================================================
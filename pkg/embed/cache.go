@@ -0,0 +1,245 @@
+package embed
+
+import (
+	"bufio"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// cacheFileMagic starts a cache file written by SaveFile
+const cacheFileMagic = "GIBEMB01"
+
+// maxCachedDim bounds the embedding size read from a cache file
+const maxCachedDim = 1 << 16
+
+// cacheKey is the SHA-256 hash of a text
+type cacheKey [sha256.Size]byte
+
+type cacheEntry struct {
+	key cacheKey
+	vec []float32 // never modified once cached
+}
+
+// Cache wraps an Embedder and keeps the vectors it returns in an LRU keyed
+// by a hash of each text, so embedding the same text again costs no call.
+// Misses are sent to the wrapped Embedder in one call per Embed. A cache
+// only holds one Embedder's vectors; cache files should not be shared
+// between providers or models.
+type Cache struct {
+	next Embedder
+
+	mu       sync.Mutex
+	capacity int
+	items    map[cacheKey]*list.Element
+	order    *list.List // front = most recent, back = least recent
+	hits     uint64
+	misses   uint64
+}
+
+// CacheStats counts lookups since the cache was created
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	Len    int
+}
+
+// NewCache returns a Cache of up to capacity vectors in front of next
+func NewCache(next Embedder, capacity int) *Cache {
+	return &Cache{
+		next:     next,
+		capacity: capacity,
+		items:    make(map[cacheKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Embed implements Embedder. The same text repeated within texts is sent to
+// the wrapped Embedder once.
+func (c *Cache) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vecs := make([][]float32, len(texts))
+	keys := make([]cacheKey, len(texts))
+	var missTexts []string
+	missAt := make(map[cacheKey][]int)
+
+	c.mu.Lock()
+	for i, text := range texts {
+		keys[i] = sha256.Sum256([]byte(text))
+		if elem, ok := c.items[keys[i]]; ok {
+			c.order.MoveToFront(elem)
+			c.hits++
+			vecs[i] = slices.Clone(elem.Value.(*cacheEntry).vec)
+			continue
+		}
+		c.misses++
+		if _, ok := missAt[keys[i]]; !ok {
+			missTexts = append(missTexts, text)
+		}
+		missAt[keys[i]] = append(missAt[keys[i]], i)
+	}
+	c.mu.Unlock()
+
+	if len(missTexts) == 0 {
+		return vecs, nil
+	}
+	computed, err := c.next.Embed(ctx, missTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(computed) != len(missTexts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(computed), len(missTexts))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for j, text := range missTexts {
+		key := sha256.Sum256([]byte(text))
+		c.put(key, computed[j])
+		for n, i := range missAt[key] {
+			if n == 0 {
+				vecs[i] = computed[j]
+			} else {
+				vecs[i] = slices.Clone(computed[j])
+			}
+		}
+	}
+	return vecs, nil
+}
+
+// put stores a copy of vec under key, evicting the least recently used
+// vectors beyond capacity. Callers hold c.mu.
+func (c *Cache) put(key cacheKey, vec []float32) {
+	if c.capacity <= 0 {
+		return
+	}
+	if elem, ok := c.items[key]; ok {
+		elem.Value.(*cacheEntry).vec = slices.Clone(vec)
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, vec: slices.Clone(vec)})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// Stats returns the cache's hit and miss counts and its size
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Len: c.order.Len()}
+}
+
+// LoadFile adds the vectors SaveFile wrote to path. A missing file is not
+// an error, so a cache can be loaded before its first save.
+func (c *Cache) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if err := c.load(bufio.NewReader(f)); err != nil {
+		return fmt.Errorf("load embedding cache %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Cache) load(r io.Reader) error {
+	magic := make([]byte, len(cacheFileMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return err
+	}
+	if string(magic) != cacheFileMagic {
+		return errors.New("not an embedding cache file")
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Entries run from least to most recently used, so putting them in
+	// order restores the recency order
+	for range count {
+		var key cacheKey
+		var dim uint32
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &dim); err != nil {
+			return err
+		}
+		if dim > maxCachedDim {
+			return fmt.Errorf("embedding of %d dimensions", dim)
+		}
+		vec := make([]float32, dim)
+		if err := binary.Read(r, binary.LittleEndian, vec); err != nil {
+			return err
+		}
+		c.put(key, vec)
+	}
+	return nil
+}
+
+// SaveFile writes the cached vectors to path, replacing it atomically
+func (c *Cache) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	w := bufio.NewWriter(tmp)
+	if err := c.save(w); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (c *Cache) save(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := io.WriteString(w, cacheFileMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(c.order.Len())); err != nil {
+		return err
+	}
+	for elem := c.order.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*cacheEntry)
+		if _, err := w.Write(entry.key[:]); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(entry.vec))); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, entry.vec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package embed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Cohere defaults
+const (
+	DefaultCohereURL   = "https://api.cohere.com"
+	DefaultCohereModel = "embed-english-v3.0" // 1024 dimensions
+)
+
+// cohereMaxTexts is the most texts the embed endpoint takes at once
+const cohereMaxTexts = 96
+
+// Cohere embeds texts with the Cohere v2 embed API
+type Cohere struct {
+	APIKey string
+	Model  string
+
+	// InputType tells v3 models how the text will be used:
+	// "search_document" for stored objects, "search_query" for queries
+	InputType string
+
+	// MaxBatch caps the texts per request below the API's own limit, and
+	// Concurrency is how many requests run at once (default 1)
+	MaxBatch    int
+	Concurrency int
+	Retry       RetryConfig // zero fields take the defaults
+
+	BaseURL    string
+	HTTPClient *http.Client // nil uses a client with a 60s timeout
+}
+
+// NewCohere returns a Cohere embedder for stored documents; an empty model
+// means DefaultCohereModel
+func NewCohere(apiKey, model string) *Cohere {
+	if model == "" {
+		model = DefaultCohereModel
+	}
+	return &Cohere{APIKey: apiKey, Model: model, InputType: "search_document", BaseURL: DefaultCohereURL}
+}
+
+type cohereRequest struct {
+	Model          string   `json:"model"`
+	Texts          []string `json:"texts"`
+	InputType      string   `json:"input_type"`
+	EmbeddingTypes []string `json:"embedding_types"`
+}
+
+type cohereResponse struct {
+	Embeddings struct {
+		Float [][]float32 `json:"float"`
+	} `json:"embeddings"`
+}
+
+// Embed implements Embedder
+func (c *Cohere) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return inBatches(ctx, texts, batchSize(c.MaxBatch, cohereMaxTexts), c.Concurrency, c.embedBatch)
+}
+
+func (c *Cohere) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	req := cohereRequest{Model: c.Model, Texts: texts, InputType: c.InputType, EmbeddingTypes: []string{"float"}}
+	var resp cohereResponse
+	url := strings.TrimSuffix(c.BaseURL, "/") + "/v2/embed"
+	if err := postJSON(ctx, c.HTTPClient, c.Retry, "cohere", url, c.APIKey, req, &resp, cohereErrorMessage); err != nil {
+		return nil, err
+	}
+	return resp.Embeddings.Float, nil
+}
+
+// cohereErrorMessage reads {"message":...}
+func cohereErrorMessage(body []byte) string {
+	var e struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &e) != nil {
+		return ""
+	}
+	return e.Message
+}
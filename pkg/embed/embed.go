@@ -0,0 +1,131 @@
+// Package embed computes text embeddings on the client side, through an
+// embedding API or a deterministic mock for tests and demos
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Embedder turns texts into embeddings. Implementations must be safe for
+// concurrent use.
+type Embedder interface {
+	// Embed returns one embedding per text, in the order of texts
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// defaultTimeout bounds one API request when no HTTPClient is set
+const defaultTimeout = 60 * time.Second
+
+// maxErrorBody bounds how much of an error response is read
+const maxErrorBody = 64 * 1024
+
+// APIError is a non-2xx response from an embedding API
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header, 0 when absent
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s embeddings: status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// Mock derives each embedding from a hash of its text: the same text always
+// gets the same unit vector and different texts get unrelated ones. It makes
+// no calls, so similarity between mock embeddings carries no meaning.
+type Mock struct {
+	Dim int
+}
+
+// NewMock returns a Mock producing dim-dimensional embeddings
+func NewMock(dim int) *Mock {
+	return &Mock{Dim: dim}
+}
+
+// Embed implements Embedder
+func (m *Mock) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(text))
+		rng := rand.New(rand.NewSource(int64(h.Sum64())))
+
+		vec := make([]float32, m.Dim)
+		var sum float64
+		for j := range vec {
+			v := rng.NormFloat64()
+			vec[j] = float32(v)
+			sum += v * v
+		}
+		if sum > 0 {
+			norm := float32(1 / math.Sqrt(sum))
+			for j := range vec {
+				vec[j] *= norm
+			}
+		}
+		vecs[i] = vec
+	}
+	return vecs, nil
+}
+
+// postJSON sends body as JSON to url with a bearer token and decodes a 2xx
+// response into out, retrying under policy. errorMessage extracts the
+// message from an error body.
+func postJSON(ctx context.Context, client *http.Client, policy RetryConfig, provider, url, apiKey string, body, out any, errorMessage func([]byte) string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	return withRetry(ctx, policy, func() error {
+		return postOnce(ctx, client, provider, url, apiKey, data, out, errorMessage)
+	})
+}
+
+func postOnce(ctx context.Context, client *http.Client, provider, url, apiKey string, data []byte, out any, errorMessage func([]byte) string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s embeddings: %w", provider, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		msg := errorMessage(raw)
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{
+			Provider:   provider,
+			StatusCode: resp.StatusCode,
+			Message:    msg,
+			RetryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s embeddings: decode response: %w", provider, err)
+	}
+	return nil
+}
@@ -0,0 +1,453 @@
+package embed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMock_Deterministic(t *testing.T) {
+	m := NewMock(64)
+	vecs, err := m.Embed(context.Background(), []string{"alpha", "beta", "alpha"})
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if len(vecs) != 3 || len(vecs[0]) != 64 {
+		t.Fatalf("Embed() = %d vectors of %d dims, want 3 of 64", len(vecs), len(vecs[0]))
+	}
+
+	var norm float64
+	for _, v := range vecs[0] {
+		norm += float64(v) * float64(v)
+	}
+	if math.Abs(norm-1) > 1e-4 {
+		t.Errorf("squared norm = %f, want 1", norm)
+	}
+
+	if fmt.Sprint(vecs[0]) != fmt.Sprint(vecs[2]) {
+		t.Error("same text gave different embeddings")
+	}
+	if fmt.Sprint(vecs[0]) == fmt.Sprint(vecs[1]) {
+		t.Error("different texts gave the same embedding")
+	}
+
+	again, _ := NewMock(64).Embed(context.Background(), []string{"alpha"})
+	if fmt.Sprint(again[0]) != fmt.Sprint(vecs[0]) {
+		t.Error("embedding changed between Mock instances")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.Embed(ctx, []string{"alpha"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Embed() with canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestOpenAI_Embed(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("request to %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Model != "text-embedding-3-small" || req.Dimensions != 4 || len(req.Input) != 2 {
+			t.Errorf("request = %+v", req)
+		}
+
+		// Answer out of order; the adapter must sort by index
+		_, _ = fmt.Fprintf(w, `{"object":"list","data":[
+			{"object":"embedding","index":1,"embedding":[0,1,0,0]},
+			{"object":"embedding","index":0,"embedding":[1,0,0,0]}
+		],"model":%q}`, req.Model)
+	}))
+	defer ts.Close()
+
+	o := NewOpenAI("sk-test", "")
+	o.BaseURL = ts.URL + "/v1/"
+	o.Dimensions = 4
+	vecs, err := o.Embed(context.Background(), []string{"first", "second"})
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if calls != 1 || len(vecs) != 2 || vecs[0][0] != 1 || vecs[1][1] != 1 {
+		t.Errorf("Embed() = %v after %d calls, want first and second in order", vecs, calls)
+	}
+}
+
+func TestOpenAI_APIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`))
+	}))
+	defer ts.Close()
+
+	o := NewOpenAI("bad", "")
+	o.BaseURL = ts.URL
+	_, err := o.Embed(context.Background(), []string{"text"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Embed() error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "Incorrect API key provided" {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+}
+
+func TestOpenAI_MissingEmbedding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":[{"index":0,"embedding":[1]}]}`))
+	}))
+	defer ts.Close()
+
+	o := NewOpenAI("sk-test", "")
+	o.BaseURL = ts.URL
+	if _, err := o.Embed(context.Background(), []string{"a", "b"}); err == nil {
+		t.Error("Embed() with one embedding for two texts succeeded")
+	}
+}
+
+func TestCohere_Embed(t *testing.T) {
+	var batches []int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/embed" || r.Header.Get("Authorization") != "Bearer co-test" {
+			t.Errorf("request to %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req cohereRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Model != DefaultCohereModel || req.InputType != "search_document" || len(req.EmbeddingTypes) != 1 || req.EmbeddingTypes[0] != "float" {
+			t.Errorf("request = %+v", req)
+		}
+		batches = append(batches, len(req.Texts))
+
+		var resp cohereResponse
+		for range req.Texts {
+			resp.Embeddings.Float = append(resp.Embeddings.Float, []float32{float32(len(batches)), 0})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	c := NewCohere("co-test", "")
+	c.BaseURL = ts.URL
+	texts := make([]string, cohereMaxTexts+4)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+	vecs, err := c.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if fmt.Sprint(batches) != fmt.Sprint([]int{cohereMaxTexts, 4}) {
+		t.Errorf("batch sizes = %v, want [%d 4]", batches, cohereMaxTexts)
+	}
+	if len(vecs) != len(texts) || vecs[0][0] != 1 || vecs[len(vecs)-1][0] != 2 {
+		t.Errorf("Embed() returned %d vectors, want %d from two batches in order", len(vecs), len(texts))
+	}
+}
+
+func TestCohere_APIError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"id":"abc","message":"You are using a Trial key, which is limited"}`))
+	}))
+	defer ts.Close()
+
+	c := NewCohere("co-test", "")
+	c.BaseURL = ts.URL
+	c.Retry.MaxRetries = -1
+	_, err := c.Embed(context.Background(), []string{"text"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Provider != "cohere" {
+		t.Fatalf("Embed() error = %v, want a cohere 429 *APIError", err)
+	}
+}
+
+func TestOpenAI_RetriesRateLimit(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"error":{"message":"Rate limit reached"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"index":0,"embedding":[1,2]}]}`))
+	}))
+	defer ts.Close()
+
+	o := NewOpenAI("sk-test", "")
+	o.BaseURL = ts.URL
+	o.Retry = RetryConfig{BaseDelay: time.Millisecond}
+	vecs, err := o.Embed(context.Background(), []string{"text"})
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if calls.Load() != 2 || fmt.Sprint(vecs) != "[[1 2]]" {
+		t.Errorf("Embed() = %v after %d calls, want [[1 2]] after 2", vecs, calls.Load())
+	}
+}
+
+func TestOpenAI_RetriesExhausted(t *testing.T) {
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	o := NewOpenAI("sk-test", "")
+	o.BaseURL = ts.URL
+	o.Retry = RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond}
+	_, err := o.Embed(context.Background(), []string{"text"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Embed() error = %v, want a 503 *APIError", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("server called %d times, want the first attempt and 2 retries", calls.Load())
+	}
+}
+
+func TestOpenAI_MaxBatch(t *testing.T) {
+	const maxBatch = 3
+	var requests, inFlight, peak atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		if len(req.Input) > maxBatch {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, `{"error":{"message":"%d inputs, at most %d allowed"}}`, len(req.Input), maxBatch)
+			return
+		}
+
+		// Each embedding is the number in its text
+		var resp openAIResponse
+		for i, text := range req.Input {
+			var n float32
+			_, _ = fmt.Sscanf(text, "text %g", &n)
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{i, []float32{n}})
+		}
+		time.Sleep(10 * time.Millisecond)
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer ts.Close()
+
+	texts := make([]string, 10)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("text %d", i)
+	}
+
+	o := NewOpenAI("sk-test", "")
+	o.BaseURL = ts.URL
+	if _, err := o.Embed(context.Background(), texts); err == nil {
+		t.Fatal("Embed() over the server's batch limit succeeded")
+	}
+
+	requests.Store(0)
+	o.MaxBatch = maxBatch
+	o.Concurrency = 2
+	vecs, err := o.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	for i, vec := range vecs {
+		if len(vec) != 1 || vec[0] != float32(i) {
+			t.Errorf("vector %d = %v, want [%d]", i, vec, i)
+		}
+	}
+	if requests.Load() != 4 {
+		t.Errorf("%d requests, want 4 batches of at most %d", requests.Load(), maxBatch)
+	}
+	if peak.Load() > 2 {
+		t.Errorf("%d requests in flight at once, want at most 2", peak.Load())
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{&APIError{StatusCode: http.StatusBadGateway}, true},
+		{&APIError{StatusCode: http.StatusBadRequest}, false},
+		{fmt.Errorf("openai embeddings: %w", &url.Error{Op: "Post", Err: errors.New("connection reset")}), true},
+		{fmt.Errorf("openai embeddings: %w", &url.Error{Op: "Post", Err: context.Canceled}), false},
+		{errors.New("decode response: unexpected EOF"), false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.err); got != tt.want {
+			t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+	if got := retryAfter("7"); got != 7*time.Second {
+		t.Errorf("retryAfter(7) = %v, want 7s", got)
+	}
+	if got := retryAfter("Wed, 21 Oct 2015 07:28:00 GMT"); got != 0 {
+		t.Errorf("retryAfter(date) = %v, want 0", got)
+	}
+}
+
+// countingEmbedder records the texts of each call and embeds with a Mock
+type countingEmbedder struct {
+	calls [][]string
+	fail  error
+}
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls = append(e.calls, slices.Clone(texts))
+	if e.fail != nil {
+		return nil, e.fail
+	}
+	return NewMock(8).Embed(ctx, texts)
+}
+
+func TestCache_Hits(t *testing.T) {
+	next := &countingEmbedder{}
+	c := NewCache(next, 10)
+	ctx := context.Background()
+
+	first, err := c.Embed(ctx, []string{"alpha", "beta"})
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	second, err := c.Embed(ctx, []string{"beta", "alpha"})
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if len(next.calls) != 1 {
+		t.Errorf("wrapped embedder called %d times, want 1", len(next.calls))
+	}
+	if fmt.Sprint(first[0]) != fmt.Sprint(second[1]) || fmt.Sprint(first[1]) != fmt.Sprint(second[0]) {
+		t.Error("cached vectors came back in the wrong order")
+	}
+
+	// Callers may modify what they get without changing the cache
+	second[0][0] = 42
+	third, _ := c.Embed(ctx, []string{"beta"})
+	if third[0][0] == 42 {
+		t.Error("modifying a returned vector changed the cache")
+	}
+
+	if stats := c.Stats(); stats.Hits != 3 || stats.Misses != 2 || stats.Len != 2 {
+		t.Errorf("Stats() = %+v, want 3 hits, 2 misses, 2 cached", stats)
+	}
+}
+
+func TestCache_PartialMiss(t *testing.T) {
+	next := &countingEmbedder{}
+	c := NewCache(next, 10)
+	ctx := context.Background()
+	if _, err := c.Embed(ctx, []string{"alpha", "gamma"}); err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+
+	texts := []string{"alpha", "beta", "gamma", "delta", "beta"}
+	vecs, err := c.Embed(ctx, texts)
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if len(next.calls) != 2 || fmt.Sprint(next.calls[1]) != "[beta delta]" {
+		t.Errorf("wrapped embedder calls = %v, want the misses [beta delta] once each", next.calls)
+	}
+
+	want, _ := NewMock(8).Embed(ctx, texts)
+	for i := range texts {
+		if fmt.Sprint(vecs[i]) != fmt.Sprint(want[i]) {
+			t.Errorf("vector %d (%s) out of place", i, texts[i])
+		}
+	}
+
+	next.fail = errors.New("rate limited")
+	if _, err := c.Embed(ctx, []string{"alpha", "epsilon"}); err == nil {
+		t.Error("Embed() succeeded although the wrapped embedder failed")
+	}
+	if stats := c.Stats(); stats.Len != 4 {
+		t.Errorf("cache holds %d vectors after a failed call, want 4", stats.Len)
+	}
+}
+
+func TestCache_Eviction(t *testing.T) {
+	next := &countingEmbedder{}
+	c := NewCache(next, 2)
+	ctx := context.Background()
+
+	_, _ = c.Embed(ctx, []string{"a", "b"})
+	_, _ = c.Embed(ctx, []string{"a"})      // a is now more recent than b
+	_, _ = c.Embed(ctx, []string{"c"})      // evicts b
+	_, _ = c.Embed(ctx, []string{"a", "b"}) // b misses again and evicts c
+
+	if len(next.calls) != 3 || fmt.Sprint(next.calls[2]) != "[b]" {
+		t.Errorf("wrapped embedder calls = %v, want b re-embedded after eviction", next.calls)
+	}
+	if stats := c.Stats(); stats.Len != 2 {
+		t.Errorf("cache holds %d vectors, want capacity 2", stats.Len)
+	}
+}
+
+func TestCache_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.cache")
+	ctx := context.Background()
+
+	empty := NewCache(&countingEmbedder{}, 10)
+	if err := empty.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() of a missing file error: %v", err)
+	}
+
+	c := NewCache(&countingEmbedder{}, 10)
+	want, _ := c.Embed(ctx, []string{"alpha", "beta", "gamma"})
+	if err := c.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile() error: %v", err)
+	}
+
+	// A smaller cache keeps the most recently used vectors
+	next := &countingEmbedder{}
+	loaded := NewCache(next, 2)
+	if err := loaded.LoadFile(path); err != nil {
+		t.Fatalf("LoadFile() error: %v", err)
+	}
+	got, err := loaded.Embed(ctx, []string{"beta", "gamma"})
+	if err != nil {
+		t.Fatalf("Embed() error: %v", err)
+	}
+	if len(next.calls) != 0 {
+		t.Errorf("wrapped embedder called %v after loading the cache", next.calls)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want[1:]) {
+		t.Error("loaded vectors differ from the saved ones")
+	}
+
+	if err := os.WriteFile(path, []byte("not a cache"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.LoadFile(path); err == nil {
+		t.Error("LoadFile() of a foreign file succeeded")
+	}
+}
@@ -0,0 +1,103 @@
+package embed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// OpenAI defaults
+const (
+	DefaultOpenAIURL   = "https://api.openai.com/v1"
+	DefaultOpenAIModel = "text-embedding-3-small" // 1536 dimensions
+)
+
+// openAIMaxInputs is the most texts the embeddings endpoint takes at once
+const openAIMaxInputs = 2048
+
+// OpenAI embeds texts with the OpenAI embeddings API, or any server
+// implementing it at BaseURL
+type OpenAI struct {
+	APIKey string
+	Model  string
+
+	// Dimensions asks text-embedding-3 models for shorter embeddings; 0
+	// keeps the model's own size
+	Dimensions int
+
+	// MaxBatch caps the texts per request below the API's own limit, and
+	// Concurrency is how many requests run at once (default 1)
+	MaxBatch    int
+	Concurrency int
+	Retry       RetryConfig // zero fields take the defaults
+
+	BaseURL    string
+	HTTPClient *http.Client // nil uses a client with a 60s timeout
+}
+
+// NewOpenAI returns an OpenAI embedder; an empty model means
+// DefaultOpenAIModel
+func NewOpenAI(apiKey, model string) *OpenAI {
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	return &OpenAI{APIKey: apiKey, Model: model, BaseURL: DefaultOpenAIURL}
+}
+
+type openAIRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format"`
+}
+
+type openAIResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed implements Embedder
+func (o *OpenAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return inBatches(ctx, texts, batchSize(o.MaxBatch, openAIMaxInputs), o.Concurrency, o.embedBatch)
+}
+
+func (o *OpenAI) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	req := openAIRequest{Model: o.Model, Input: texts, Dimensions: o.Dimensions, EncodingFormat: "float"}
+	var resp openAIResponse
+	url := strings.TrimSuffix(o.BaseURL, "/") + "/embeddings"
+	if err := postJSON(ctx, o.HTTPClient, o.Retry, "openai", url, o.APIKey, req, &resp, openAIErrorMessage); err != nil {
+		return nil, err
+	}
+
+	// Results carry their input index and need not arrive in order
+	vecs := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, fmt.Errorf("openai embeddings: index %d out of range", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	for i, vec := range vecs {
+		if vec == nil {
+			return nil, fmt.Errorf("openai embeddings: no embedding for input %d", i)
+		}
+	}
+	return vecs, nil
+}
+
+// openAIErrorMessage reads {"error":{"message":...}}
+func openAIErrorMessage(body []byte) string {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &e) != nil {
+		return ""
+	}
+	return e.Error.Message
+}
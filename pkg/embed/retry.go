@@ -0,0 +1,190 @@
+package embed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultMaxRetries     = 3
+	DefaultRetryBaseDelay = 500 * time.Millisecond
+	DefaultRetryMaxDelay  = 30 * time.Second
+)
+
+// RetryConfig controls how API requests are retried after a rate limit
+// (429), a server error (5xx) or a network error. A Retry-After header on
+// the response lengthens the wait to what the API asks for.
+type RetryConfig struct {
+	MaxRetries int           // Retries after the first attempt (default: 3, negative = none)
+	BaseDelay  time.Duration // Delay before the first retry, doubled each time (default: 500ms)
+	MaxDelay   time.Duration // Upper bound on the backoff delay (default: 30s)
+}
+
+// DefaultRetryConfig returns the default retry policy
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultRetryBaseDelay,
+		MaxDelay:   DefaultRetryMaxDelay,
+	}
+}
+
+// withDefaults fills zero-valued settings
+func (r RetryConfig) withDefaults() RetryConfig {
+	if r.MaxRetries == 0 {
+		r.MaxRetries = DefaultMaxRetries
+	}
+	if r.MaxRetries < 0 {
+		r.MaxRetries = 0
+	}
+	if r.BaseDelay <= 0 {
+		r.BaseDelay = DefaultRetryBaseDelay
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = DefaultRetryMaxDelay
+	}
+	if r.MaxDelay < r.BaseDelay {
+		r.MaxDelay = r.BaseDelay
+	}
+	return r
+}
+
+// backoff returns the delay before retry n (0-based): BaseDelay doubled n
+// times, capped at MaxDelay, with the upper half jittered
+func (r RetryConfig) backoff(n int) time.Duration {
+	d := r.MaxDelay
+	if n < 32 {
+		if exp := r.BaseDelay << n; exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// retryable reports whether a request that failed with err may succeed if
+// sent again
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	// A *url.Error is a network failure before any response arrived
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// retryAfter parses a Retry-After header given in seconds
+func retryAfter(header string) time.Duration {
+	secs, err := strconv.Atoi(header)
+	if err != nil || secs <= 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// withRetry calls attempt until it succeeds, fails for good or runs out of
+// retries
+func withRetry(ctx context.Context, policy RetryConfig, attempt func() error) error {
+	policy = policy.withDefaults()
+	for n := 0; ; n++ {
+		err := attempt()
+		if err == nil || n >= policy.MaxRetries || !retryable(err) {
+			return err
+		}
+
+		delay := policy.backoff(n)
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > delay {
+			delay = apiErr.RetryAfter
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// batchSize returns the configured batch size, bounded by the API's limit
+func batchSize(configured, limit int) int {
+	if configured <= 0 || configured > limit {
+		return limit
+	}
+	return configured
+}
+
+// inBatches calls embed on consecutive slices of at most size texts, up to
+// concurrency at a time, and joins the results in the order of texts. The
+// first failure cancels the batches still running.
+func inBatches(ctx context.Context, texts []string, size, concurrency int, embed func(context.Context, []string) ([][]float32, error)) ([][]float32, error) {
+	concurrency = max(concurrency, 1)
+	vecs := make([][]float32, len(texts))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	sem := make(chan struct{}, concurrency)
+	for start := 0; start < len(texts); start += size {
+		end := min(start+size, len(texts))
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			batch, err := embed(ctx, texts[start:end])
+			if err == nil && len(batch) != end-start {
+				err = fmt.Errorf("got %d embeddings for %d texts", len(batch), end-start)
+			}
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			copy(vecs[start:end], batch)
+		}(start, end)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return vecs, nil
+}
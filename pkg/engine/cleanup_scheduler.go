@@ -129,16 +129,15 @@ func (s *SessionCleanupScheduler) cleanup() {
 	}
 	s.mu.Unlock()
 
-	// Remove from engine
-	if len(toRemove) > 0 {
-		s.engine.mu.Lock()
-		for _, sessionID := range toRemove {
-			// Re-check expiry in case session was touched
-			if sess, ok := s.engine.sessions[sessionID]; ok && sess.IsExpired() {
-				delete(s.engine.sessions, sessionID)
-			}
+	// Remove from engine; evictExpired re-checks expiry in case the session
+	// was touched
+	for _, sessionID := range toRemove {
+		s.engine.mu.RLock()
+		sess, ok := s.engine.sessions[sessionID]
+		s.engine.mu.RUnlock()
+		if ok {
+			s.engine.evictExpired(sessionID, sess)
 		}
-		s.engine.mu.Unlock()
 	}
 }
 
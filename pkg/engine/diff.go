@@ -0,0 +1,182 @@
+// Package engine - Graph diff between snapshots and live sessions
+package engine
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/gibram-io/gibram/pkg/store"
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// ReadSnapshot decodes an engine snapshot of any known format version,
+// transparently handling gzip
+func ReadSnapshot(r io.Reader) (*EngineSnapshot, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip snapshot: %w", err)
+		}
+		defer func() { _ = gr.Close() }()
+		r = gr
+	} else {
+		r = br
+	}
+
+	return decodeSnapshot(r)
+}
+
+// SessionGraph returns the entities and relationships of a live session as a
+// snapshot suitable for diffing (vectors and text are not included).
+func (e *Engine) SessionGraph(sessionID string) (*store.SessionSnapshot, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &store.SessionSnapshot{
+		SessionID:     sessionID,
+		Entities:      sess.GetAllEntities(),
+		Relationships: sess.GetAllRelationships(),
+	}, nil
+}
+
+// DiffSessionSnapshots reports entity and relationship changes from one session
+// state to another. Either side may be nil (treated as empty). Changes are
+// ordered entities first, then relationships, each by ID, and paged with an
+// offset cursor.
+func DiffSessionSnapshots(from, to *store.SessionSnapshot, cursor uint64, limit int) *types.GraphDiff {
+	if from == nil {
+		from = &store.SessionSnapshot{}
+	}
+	if to == nil {
+		to = &store.SessionSnapshot{}
+	}
+
+	var changes []types.GraphChange
+
+	oldEnts := make(map[uint64]*types.Entity, len(from.Entities))
+	for _, ent := range from.Entities {
+		oldEnts[ent.ID] = ent
+	}
+	newEnts := make(map[uint64]*types.Entity, len(to.Entities))
+	for _, ent := range to.Entities {
+		newEnts[ent.ID] = ent
+	}
+	entIDs := make([]uint64, 0, len(newEnts))
+	for id := range oldEnts {
+		entIDs = append(entIDs, id)
+	}
+	for id := range newEnts {
+		if _, ok := oldEnts[id]; !ok {
+			entIDs = append(entIDs, id)
+		}
+	}
+	sortIDs(entIDs)
+	for _, id := range entIDs {
+		oldEnt, newEnt := oldEnts[id], newEnts[id]
+		switch {
+		case oldEnt == nil:
+			changes = append(changes, types.GraphChange{Op: types.DiffAdded, Entity: newEnt})
+		case newEnt == nil:
+			changes = append(changes, types.GraphChange{Op: types.DiffRemoved, Entity: oldEnt})
+		case !entitiesEqual(oldEnt, newEnt):
+			changes = append(changes, types.GraphChange{Op: types.DiffModified, Entity: newEnt})
+		}
+	}
+
+	oldRels := make(map[uint64]*types.Relationship, len(from.Relationships))
+	for _, rel := range from.Relationships {
+		oldRels[rel.ID] = rel
+	}
+	newRels := make(map[uint64]*types.Relationship, len(to.Relationships))
+	for _, rel := range to.Relationships {
+		newRels[rel.ID] = rel
+	}
+	relIDs := make([]uint64, 0, len(newRels))
+	for id := range oldRels {
+		relIDs = append(relIDs, id)
+	}
+	for id := range newRels {
+		if _, ok := oldRels[id]; !ok {
+			relIDs = append(relIDs, id)
+		}
+	}
+	sortIDs(relIDs)
+	for _, id := range relIDs {
+		oldRel, newRel := oldRels[id], newRels[id]
+		switch {
+		case oldRel == nil:
+			changes = append(changes, types.GraphChange{Op: types.DiffAdded, Relationship: newRel})
+		case newRel == nil:
+			changes = append(changes, types.GraphChange{Op: types.DiffRemoved, Relationship: oldRel})
+		case !relationshipsEqual(oldRel, newRel):
+			changes = append(changes, types.GraphChange{Op: types.DiffModified, Relationship: newRel})
+		}
+	}
+
+	diff := &types.GraphDiff{Total: len(changes)}
+	if cursor >= uint64(len(changes)) {
+		return diff
+	}
+	end := len(changes)
+	if limit > 0 && int(cursor)+limit < end {
+		end = int(cursor) + limit
+		diff.NextCursor = uint64(end)
+	}
+	diff.Changes = changes[cursor:end]
+	return diff
+}
+
+func sortIDs(ids []uint64) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+func idsEqual(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func attrsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func entitiesEqual(a, b *types.Entity) bool {
+	return a.ExternalID == b.ExternalID &&
+		a.Title == b.Title &&
+		a.Type == b.Type &&
+		a.Description == b.Description &&
+		attrsEqual(a.Metadata, b.Metadata) &&
+		idsEqual(a.TextUnitIDs, b.TextUnitIDs)
+}
+
+func relationshipsEqual(a, b *types.Relationship) bool {
+	return a.ExternalID == b.ExternalID &&
+		a.SourceID == b.SourceID &&
+		a.TargetID == b.TargetID &&
+		a.Type == b.Type &&
+		a.Description == b.Description &&
+		a.Weight == b.Weight &&
+		a.ValidFrom == b.ValidFrom &&
+		a.ValidUntil == b.ValidUntil &&
+		a.Directed == b.Directed &&
+		idsEqual(a.TextUnitIDs, b.TextUnitIDs)
+}
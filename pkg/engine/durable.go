@@ -0,0 +1,732 @@
+// Package engine - Durable engine state: compacted base plus append log
+package engine
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/gibram-io/gibram/pkg/store"
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// DefaultDurableCompactSize is the append log size at which Sync compacts
+// the log into a new base
+const DefaultDurableCompactSize = 64 << 20
+
+// Durable state file names: base-<gen>.json.gz holds an engine snapshot in
+// the Snapshot format, append-<gen>.log the records appended since it
+const (
+	durableBasePrefix = "base-"
+	durableBaseSuffix = ".json.gz"
+	durableLogPrefix  = "append-"
+	durableLogSuffix  = ".log"
+)
+
+// durableRecord is one line of the append log: a session's full state, a
+// session deletion, the upsert or deletion of one of a session's records,
+// or the commit marker that ends each Sync
+type durableRecord struct {
+	Session    string                 `json:"session,omitempty"`
+	Deleted    bool                   `json:"deleted,omitempty"`
+	State      *store.SessionSnapshot `json:"state,omitempty"`
+	Collection string                 `json:"collection,omitempty"`
+	ID         uint64                 `json:"id,omitempty"`
+	Value      json.RawMessage        `json:"value,omitempty"`
+	Commit     bool                   `json:"commit,omitempty"`
+	LSN        uint64                 `json:"lsn,omitempty"`
+}
+
+// Collections a session's state is split into for delta records. The
+// session collection holds a single record, ID 0, with the session itself
+// and its ID generator state.
+const (
+	durableSessionColl      = "session"
+	durableDocumentColl     = "document"
+	durableTextUnitColl     = "text_unit"
+	durableEntityColl       = "entity"
+	durableRelationshipColl = "relationship"
+	durableCommunityColl    = "community"
+)
+
+// durableKey identifies one record of a session
+type durableKey struct {
+	collection string
+	id         uint64
+}
+
+// durableValue is the value of a delta record; only the fields of its
+// collection are set. Items carry their own vectors.
+type durableValue struct {
+	Session      *types.Session      `json:"session,omitempty"`
+	IDState      map[string]uint64   `json:"id_state,omitempty"`
+	Document     *types.Document     `json:"document,omitempty"`
+	TextUnit     *types.TextUnit     `json:"text_unit,omitempty"`
+	Entity       *types.Entity       `json:"entity,omitempty"`
+	Relationship *types.Relationship `json:"relationship,omitempty"`
+	Community    *types.Community    `json:"community,omitempty"`
+	Vector       []float32           `json:"vector,omitempty"`
+	TitleVector  []float32           `json:"title_vector,omitempty"`
+}
+
+// durableSession is what the log last recorded for a session
+type durableSession struct {
+	sess     *store.SessionStore
+	version  uint64
+	settings string
+}
+
+// DurableStore keeps the engine's materialized state on disk in dir. Sync
+// appends the records of each session changed since the previous Sync, and
+// the deletion of each session removed since, to an append log; once the
+// log outgrows the compact size it is folded into a new base file. Unlike
+// the WAL, which records commands, the log records their result, so loading
+// restores sessions without re-running anything.
+//
+// A session is split into records keyed by collection and ID. The first
+// time a session is appended after opening or compacting, its full state is
+// written; after that only the records that changed or went away are, found
+// by comparing each record's hash with the one last written. Finding them
+// still encodes the whole changed session, but the log grows with the
+// change rather than the session.
+//
+// Each Sync ends with a commit marker carrying the caller's WAL LSN; a Sync
+// cut short by a crash is dropped on load. Callers pause writes around Sync
+// and Compact so the LSN matches the state written.
+type DurableStore struct {
+	mu          sync.Mutex
+	eng         *Engine
+	dir         string
+	gen         uint64
+	log         *os.File
+	logSize     int64
+	lsn         uint64
+	compactSize int64
+	synced      map[string]durableSession
+	hashes      map[string]map[durableKey]uint64 // of the records last appended
+}
+
+// OpenDurableStore loads the state in dir, creating dir if needed, into eng
+// and returns a store that keeps it up to date. Loaded reports whether dir
+// held any state. When it held none, the sessions already in eng are
+// written by the first Sync, which migrates a restored snapshot.
+func OpenDurableStore(eng *Engine, dir string) (d *DurableStore, loaded bool, err error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, false, err
+	}
+	d = &DurableStore{
+		eng:         eng,
+		dir:         dir,
+		compactSize: DefaultDurableCompactSize,
+		synced:      make(map[string]durableSession),
+		hashes:      make(map[string]map[durableKey]uint64),
+	}
+
+	d.gen, loaded, err = latestDurableGen(dir)
+	if err != nil {
+		return nil, false, err
+	}
+	if loaded {
+		if err := d.loadBase(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	logPath := d.logPath(d.gen)
+	f, err := os.OpenFile(logPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, false, err
+	}
+	committed, err := d.replayLog(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, false, fmt.Errorf("replay %s: %w", logPath, err)
+	}
+	// Drop a torn trailing Sync so new records follow the last commit
+	if err := f.Truncate(committed); err != nil {
+		_ = f.Close()
+		return nil, false, err
+	}
+	if _, err := f.Seek(committed, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, false, err
+	}
+	d.log = f
+	d.logSize = committed
+	if committed > 0 {
+		loaded = true
+	}
+	if loaded {
+		d.synced = d.currentSessions()
+	}
+	d.removeOtherGens()
+	return d, loaded, nil
+}
+
+// SetCompactSize sets the append log size in bytes at which Sync compacts
+// (<= 0 = DefaultDurableCompactSize)
+func (d *DurableStore) SetCompactSize(size int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if size <= 0 {
+		size = DefaultDurableCompactSize
+	}
+	d.compactSize = size
+}
+
+// LSN returns the WAL LSN of the last Sync or Compact, or of the loaded
+// state; the WAL is replayed from there on startup
+func (d *DurableStore) LSN() uint64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.lsn
+}
+
+// Sync appends the sessions changed since the last Sync, then compacts if
+// the log has outgrown the compact size. lsn is the WAL position the
+// engine state reflects.
+func (d *DurableStore) Sync(lsn uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := d.currentSessions()
+	var records []durableRecord
+	hashes := make(map[string]map[durableKey]uint64)
+	ids := make([]string, 0, len(current))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if prev, ok := d.synced[id]; ok && prev == current[id] {
+			continue
+		}
+		snapshot := current[id].sess.Snapshot()
+		prevHashes, ok := d.hashes[id]
+		if !ok {
+			records = append(records, durableRecord{Session: id, State: snapshot})
+		}
+		values, err := splitSessionSnapshot(snapshot)
+		if err != nil {
+			return fmt.Errorf("encode session %s: %w", id, err)
+		}
+		keys := make([]durableKey, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sortDurableKeys(keys)
+		sessHashes := make(map[durableKey]uint64, len(values))
+		for _, key := range keys {
+			sessHashes[key] = xxhash.Sum64(values[key])
+			if ok && prevHashes[key] != sessHashes[key] {
+				records = append(records, durableRecord{Session: id, Collection: key.collection, ID: key.id, Value: values[key]})
+			}
+		}
+		if ok {
+			var gone []durableKey
+			for key := range prevHashes {
+				if _, live := values[key]; !live {
+					gone = append(gone, key)
+				}
+			}
+			sortDurableKeys(gone)
+			for _, key := range gone {
+				records = append(records, durableRecord{Session: id, Collection: key.collection, ID: key.id, Deleted: true})
+			}
+		}
+		hashes[id] = sessHashes
+	}
+	for id := range d.synced {
+		if _, ok := current[id]; !ok {
+			records = append(records, durableRecord{Session: id, Deleted: true})
+		}
+	}
+	if len(records) == 0 && lsn == d.lsn {
+		return nil
+	}
+	records = append(records, durableRecord{Commit: true, LSN: lsn})
+
+	if err := d.append(records); err != nil {
+		return err
+	}
+	for id := range d.hashes {
+		if _, ok := current[id]; !ok {
+			delete(d.hashes, id)
+		}
+	}
+	for id, sessHashes := range hashes {
+		d.hashes[id] = sessHashes
+	}
+	d.synced = current
+	d.lsn = lsn
+
+	if d.logSize >= d.compactSize {
+		return d.compactLocked(lsn)
+	}
+	return nil
+}
+
+// Compact writes the engine's current state to a new base file and starts
+// an empty append log. It is also the migration path from snapshots:
+// restore a snapshot into the engine, then Compact.
+func (d *DurableStore) Compact(lsn uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.compactLocked(lsn)
+}
+
+// Close closes the append log
+func (d *DurableStore) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.log.Close()
+}
+
+func (d *DurableStore) compactLocked(lsn uint64) error {
+	// Versions are read before the snapshot, so a write racing it is
+	// appended again by the next Sync
+	current := d.currentSessions()
+	gen := d.gen + 1
+
+	// The new log, holding only the base's LSN, exists before the base:
+	// loading picks the newest base, which then always has its log
+	logPath := d.logPath(gen)
+	f, err := os.OpenFile(logPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	commit, err := encodeDurableRecords([]durableRecord{{Commit: true, LSN: lsn}})
+	if err == nil {
+		_, err = f.Write(commit)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(logPath)
+		return err
+	}
+
+	if err := d.writeBase(gen); err != nil {
+		_ = f.Close()
+		_ = os.Remove(logPath)
+		return err
+	}
+
+	_ = d.log.Close()
+	d.log = f
+	d.logSize = int64(len(commit))
+	d.gen = gen
+	d.lsn = lsn
+	d.synced = current
+	// The base holds whole sessions: each one's next change is written whole
+	d.hashes = make(map[string]map[durableKey]uint64)
+	d.removeOtherGens()
+	return nil
+}
+
+// writeBase writes the engine snapshot to base-<gen> through a temp file
+func (d *DurableStore) writeBase(gen uint64) error {
+	path := d.basePath(gen)
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(f)
+	err = d.eng.Snapshot(gw)
+	if closeErr := gw.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write base %s: %w", path, err)
+	}
+	return nil
+}
+
+// append writes records and syncs the log. On failure the log is cut back
+// so a partial Sync never sits in front of later ones.
+func (d *DurableStore) append(records []durableRecord) error {
+	data, err := encodeDurableRecords(records)
+	if err != nil {
+		return err
+	}
+	if _, err = d.log.Write(data); err == nil {
+		err = d.log.Sync()
+	}
+	if err != nil {
+		if truncErr := d.log.Truncate(d.logSize); truncErr == nil {
+			_, _ = d.log.Seek(d.logSize, io.SeekStart)
+		}
+		return fmt.Errorf("append to %s: %w", d.logPath(d.gen), err)
+	}
+	d.logSize += int64(len(data))
+	return nil
+}
+
+func encodeDurableRecords(records []durableRecord) ([]byte, error) {
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return nil, err
+		}
+	}
+	return []byte(buf.String()), nil
+}
+
+func (d *DurableStore) loadBase() error {
+	path := d.basePath(d.gen)
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("open base %s: %w", path, err)
+	}
+	defer func() { _ = gr.Close() }()
+	if err := d.eng.Restore(gr); err != nil {
+		return fmt.Errorf("load base %s: %w", path, err)
+	}
+	return nil
+}
+
+// replayLog applies every committed Sync in f and returns the offset just
+// past the last commit marker. Records are applied to the sessions' split
+// state, which replaces the engine's sessions once the log is read.
+func (d *DurableStore) replayLog(f *os.File) (int64, error) {
+	dec := json.NewDecoder(bufio.NewReader(f))
+	replayed := make(map[string]map[durableKey]json.RawMessage) // nil = deleted
+	var pending []durableRecord
+	var committed int64
+	for {
+		var rec durableRecord
+		if err := dec.Decode(&rec); err != nil {
+			// EOF, or a record torn by a crash: stop at the last commit
+			break
+		}
+		if !rec.Commit {
+			pending = append(pending, rec)
+			continue
+		}
+		for _, p := range pending {
+			if err := d.replayRecord(replayed, p); err != nil {
+				return 0, err
+			}
+		}
+		pending = pending[:0]
+		d.lsn = rec.LSN
+		committed = dec.InputOffset()
+	}
+
+	ids := make([]string, 0, len(replayed))
+	for id := range replayed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		values := replayed[id]
+		if values == nil {
+			if err := d.eng.replaceSession(id, nil, true); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		snapshot, err := joinSessionSnapshot(id, values)
+		if err != nil {
+			return 0, fmt.Errorf("session %s: %w", id, err)
+		}
+		if err := d.eng.replaceSession(id, snapshot, false); err != nil {
+			return 0, err
+		}
+	}
+	return committed, nil
+}
+
+// replayRecord applies one committed record to the replayed sessions,
+// starting a session from its loaded state on its first delta
+func (d *DurableStore) replayRecord(replayed map[string]map[durableKey]json.RawMessage, rec durableRecord) error {
+	switch {
+	case rec.Collection == "" && rec.Deleted:
+		replayed[rec.Session] = nil
+		return nil
+	case rec.Collection == "":
+		if rec.State == nil {
+			return errors.New("session record without state")
+		}
+		values, err := splitSessionSnapshot(rec.State)
+		if err != nil {
+			return fmt.Errorf("session %s: %w", rec.Session, err)
+		}
+		replayed[rec.Session] = values
+		return nil
+	}
+
+	values, ok := replayed[rec.Session]
+	if !ok {
+		sess, err := d.eng.getSession(rec.Session)
+		if err != nil {
+			return fmt.Errorf("delta for unknown session %s", rec.Session)
+		}
+		if values, err = splitSessionSnapshot(sess.Snapshot()); err != nil {
+			return fmt.Errorf("session %s: %w", rec.Session, err)
+		}
+		replayed[rec.Session] = values
+	} else if values == nil {
+		return fmt.Errorf("delta for deleted session %s", rec.Session)
+	}
+	key := durableKey{rec.Collection, rec.ID}
+	if rec.Deleted {
+		delete(values, key)
+	} else {
+		values[key] = rec.Value
+	}
+	return nil
+}
+
+// splitSessionSnapshot encodes each record of a session
+func splitSessionSnapshot(snapshot *store.SessionSnapshot) (map[durableKey]json.RawMessage, error) {
+	values := make(map[durableKey]json.RawMessage)
+	put := func(collection string, id uint64, v durableValue) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		values[durableKey{collection, id}] = data
+		return nil
+	}
+
+	if err := put(durableSessionColl, 0, durableValue{Session: snapshot.Session, IDState: snapshot.IDGeneratorState}); err != nil {
+		return nil, err
+	}
+	for _, doc := range snapshot.Documents {
+		if err := put(durableDocumentColl, doc.ID, durableValue{Document: doc}); err != nil {
+			return nil, err
+		}
+	}
+	for _, tu := range snapshot.TextUnits {
+		if err := put(durableTextUnitColl, tu.ID, durableValue{TextUnit: tu, Vector: snapshot.TextUnitVectors[tu.ID]}); err != nil {
+			return nil, err
+		}
+	}
+	for _, ent := range snapshot.Entities {
+		v := durableValue{Entity: ent, Vector: snapshot.EntityVectors[ent.ID], TitleVector: snapshot.EntityTitleVectors[ent.ID]}
+		if err := put(durableEntityColl, ent.ID, v); err != nil {
+			return nil, err
+		}
+	}
+	for _, rel := range snapshot.Relationships {
+		if err := put(durableRelationshipColl, rel.ID, durableValue{Relationship: rel}); err != nil {
+			return nil, err
+		}
+	}
+	for _, comm := range snapshot.Communities {
+		if err := put(durableCommunityColl, comm.ID, durableValue{Community: comm, Vector: snapshot.CommunityVectors[comm.ID]}); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// joinSessionSnapshot rebuilds a session snapshot from its records
+func joinSessionSnapshot(sessionID string, values map[durableKey]json.RawMessage) (*store.SessionSnapshot, error) {
+	snapshot := &store.SessionSnapshot{
+		SessionID:          sessionID,
+		TextUnitVectors:    make(map[uint64][]float32),
+		EntityVectors:      make(map[uint64][]float32),
+		CommunityVectors:   make(map[uint64][]float32),
+		EntityTitleVectors: make(map[uint64][]float32),
+	}
+	keys := make([]durableKey, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sortDurableKeys(keys)
+	for _, key := range keys {
+		var v durableValue
+		if err := json.Unmarshal(values[key], &v); err != nil {
+			return nil, fmt.Errorf("%s %d: %w", key.collection, key.id, err)
+		}
+		var present bool
+		switch key.collection {
+		case durableSessionColl:
+			snapshot.Session, snapshot.IDGeneratorState = v.Session, v.IDState
+			present = v.Session != nil
+		case durableDocumentColl:
+			snapshot.Documents = append(snapshot.Documents, v.Document)
+			present = v.Document != nil
+		case durableTextUnitColl:
+			snapshot.TextUnits = append(snapshot.TextUnits, v.TextUnit)
+			present = v.TextUnit != nil
+			if v.Vector != nil {
+				snapshot.TextUnitVectors[key.id] = v.Vector
+			}
+		case durableEntityColl:
+			snapshot.Entities = append(snapshot.Entities, v.Entity)
+			present = v.Entity != nil
+			if v.Vector != nil {
+				snapshot.EntityVectors[key.id] = v.Vector
+			}
+			if v.TitleVector != nil {
+				snapshot.EntityTitleVectors[key.id] = v.TitleVector
+			}
+		case durableRelationshipColl:
+			snapshot.Relationships = append(snapshot.Relationships, v.Relationship)
+			present = v.Relationship != nil
+		case durableCommunityColl:
+			snapshot.Communities = append(snapshot.Communities, v.Community)
+			present = v.Community != nil
+			if v.Vector != nil {
+				snapshot.CommunityVectors[key.id] = v.Vector
+			}
+		default:
+			return nil, fmt.Errorf("unknown collection %q", key.collection)
+		}
+		if !present {
+			return nil, fmt.Errorf("%s %d: empty record", key.collection, key.id)
+		}
+	}
+	if snapshot.Session == nil {
+		return nil, errors.New("no session record")
+	}
+	return snapshot, nil
+}
+
+// sortDurableKeys orders keys by collection, then ID
+func sortDurableKeys(keys []durableKey) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].collection != keys[j].collection {
+			return keys[i].collection < keys[j].collection
+		}
+		return keys[i].id < keys[j].id
+	})
+}
+
+// currentSessions returns what the log should hold for each live session
+func (d *DurableStore) currentSessions() map[string]durableSession {
+	d.eng.mu.RLock()
+	sessions := make(map[string]*store.SessionStore, len(d.eng.sessions))
+	for id, sess := range d.eng.sessions {
+		if !sess.IsExpired() {
+			sessions[id] = sess
+		}
+	}
+	d.eng.mu.RUnlock()
+
+	current := make(map[string]durableSession, len(sessions))
+	for id, sess := range sessions {
+		info := sess.GetInfo()
+		// Settings do not move the data version
+		settings, _ := json.Marshal(struct {
+			TTL      int64
+			IdleTTL  int64
+			Metadata map[string]string
+		}{info.TTL, info.IdleTTL, info.Metadata})
+		current[id] = durableSession{sess: sess, version: sess.DataVersion(), settings: string(settings)}
+	}
+	return current
+}
+
+func (d *DurableStore) basePath(gen uint64) string {
+	return filepath.Join(d.dir, durableBasePrefix+strconv.FormatUint(gen, 10)+durableBaseSuffix)
+}
+
+func (d *DurableStore) logPath(gen uint64) string {
+	return filepath.Join(d.dir, durableLogPrefix+strconv.FormatUint(gen, 10)+durableLogSuffix)
+}
+
+// removeOtherGens deletes base and log files of generations other than the
+// current one, left by compaction or by a crash during it
+func (d *DurableStore) removeOtherGens() {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if gen, ok := parseDurableGen(name); ok && gen != d.gen {
+			_ = os.Remove(filepath.Join(d.dir, name))
+		} else if strings.HasSuffix(name, durableBaseSuffix+".tmp") {
+			_ = os.Remove(filepath.Join(d.dir, name))
+		}
+	}
+}
+
+// latestDurableGen returns the newest generation with a base file
+func latestDurableGen(dir string) (uint64, bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, false, err
+	}
+	var latest uint64
+	found := false
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, durableBasePrefix) {
+			continue
+		}
+		if gen, ok := parseDurableGen(name); ok && (!found || gen > latest) {
+			latest, found = gen, true
+		}
+	}
+	return latest, found, nil
+}
+
+// parseDurableGen returns the generation of a base or log file name
+func parseDurableGen(name string) (uint64, bool) {
+	var num string
+	switch {
+	case strings.HasPrefix(name, durableBasePrefix) && strings.HasSuffix(name, durableBaseSuffix):
+		num = strings.TrimSuffix(strings.TrimPrefix(name, durableBasePrefix), durableBaseSuffix)
+	case strings.HasPrefix(name, durableLogPrefix) && strings.HasSuffix(name, durableLogSuffix):
+		num = strings.TrimSuffix(strings.TrimPrefix(name, durableLogPrefix), durableLogSuffix)
+	default:
+		return 0, false
+	}
+	gen, err := strconv.ParseUint(num, 10, 64)
+	return gen, err == nil
+}
+
+// replaceSession swaps in a session restored from snapshot, or removes the
+// session when deleted is set
+func (e *Engine) replaceSession(sessionID string, snapshot *store.SessionSnapshot, deleted bool) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if deleted {
+		delete(e.sessions, sessionID)
+		return nil
+	}
+	if snapshot == nil {
+		return errors.New("session record without state")
+	}
+	sess := store.NewSessionStore(sessionID, e.vectorDim)
+	if err := sess.RestoreFromSnapshot(snapshot); err != nil {
+		return fmt.Errorf("restore session %s: %w", sessionID, err)
+	}
+	e.sessions[sessionID] = sess
+	return nil
+}
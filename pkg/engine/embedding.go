@@ -0,0 +1,91 @@
+// Package engine - Server-side embedding generation hook
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDimensionMismatch is matched by errors.Is for every DimensionMismatchError
+var ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+// DimensionMismatchError rejects an embedding whose length is not the
+// engine's vector dimension
+type DimensionMismatchError struct {
+	Expected int
+	Got      int
+}
+
+func (e *DimensionMismatchError) Error() string {
+	return fmt.Sprintf("%v: expected %d, got %d", ErrDimensionMismatch, e.Expected, e.Got)
+}
+
+func (e *DimensionMismatchError) Unwrap() error {
+	return ErrDimensionMismatch
+}
+
+// EmbeddingProvider generates embeddings for entities and text units that
+// are submitted without one, so thin clients can send raw text. Embed must
+// return vectors of the engine's dimension and be safe for concurrent use.
+type EmbeddingProvider interface {
+	Embed(text string) ([]float32, error)
+}
+
+// NoopEmbeddingProvider never generates embeddings; objects submitted
+// without one are stored unindexed. It is the engine default.
+type NoopEmbeddingProvider struct{}
+
+// Embed returns no embedding
+func (NoopEmbeddingProvider) Embed(string) ([]float32, error) {
+	return nil, nil
+}
+
+// SetEmbeddingProvider installs the provider used for objects submitted
+// without an embedding. nil restores the no-op default.
+func (e *Engine) SetEmbeddingProvider(p EmbeddingProvider) {
+	if p == nil {
+		p = NoopEmbeddingProvider{}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.embedder = p
+}
+
+// autoEmbed returns embedding unchanged when present, otherwise asks the
+// configured provider to embed text.
+func (e *Engine) autoEmbed(embedding []float32, text string) ([]float32, error) {
+	if len(embedding) > 0 || strings.TrimSpace(text) == "" {
+		return embedding, nil
+	}
+
+	e.mu.RLock()
+	provider := e.embedder
+	e.mu.RUnlock()
+
+	generated, err := provider.Embed(text)
+	if err != nil {
+		return nil, fmt.Errorf("embedding provider: %w", err)
+	}
+	if err := e.checkDimension(generated); err != nil {
+		return nil, fmt.Errorf("embedding provider: %w", err)
+	}
+	return generated, nil
+}
+
+// checkDimension rejects a non-empty embedding of the wrong length. Empty
+// embeddings are allowed (the object is simply not indexed).
+func (e *Engine) checkDimension(embedding []float32) error {
+	if len(embedding) > 0 && len(embedding) != e.vectorDim {
+		return &DimensionMismatchError{Expected: e.vectorDim, Got: len(embedding)}
+	}
+	return nil
+}
+
+// entityEmbeddingText is the text embedded for an entity without an embedding
+func entityEmbeddingText(title, description string) string {
+	if description == "" {
+		return title
+	}
+	return title + ": " + description
+}
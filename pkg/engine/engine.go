@@ -3,19 +3,27 @@ package engine
 
 import (
 	"container/list"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gibram-io/gibram/pkg/graph"
+	"github.com/gibram-io/gibram/pkg/simd"
 	"github.com/gibram-io/gibram/pkg/store"
+	"github.com/gibram-io/gibram/pkg/textsearch"
+	"github.com/gibram-io/gibram/pkg/tracing"
 	"github.com/gibram-io/gibram/pkg/types"
+	"github.com/gibram-io/gibram/pkg/vector"
 	"github.com/gibram-io/gibram/pkg/version"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // =============================================================================
@@ -26,6 +34,16 @@ var (
 	ErrSessionRequired = errors.New("session_id is required")
 	ErrSessionNotFound = errors.New("session not found")
 	ErrSessionExpired  = errors.New("session expired")
+	ErrSessionExists   = errors.New("session already exists")
+	ErrEntityNotFound  = errors.New("entity not found")
+
+	// ErrRebuildInProgress rejects a rebuild of a session whose indices are
+	// already being rebuilt
+	ErrRebuildInProgress = errors.New("index rebuild already in progress")
+
+	// ErrSessionQuotaExceeded is returned for adds to a session holding
+	// SetMaxSessionBytes or more
+	ErrSessionQuotaExceeded = types.ErrMemoryQuotaExceeded
 )
 
 // =============================================================================
@@ -35,6 +53,7 @@ var (
 const (
 	MaxQueryLogEntries = 10000
 	MaxSessions        = 10000 // Maximum concurrent sessions (DoS protection)
+	MaxSubgraphNodes   = 1000  // Subgraph node cap when none is given
 )
 
 type queryLogLRU struct {
@@ -139,15 +158,72 @@ type Engine struct {
 	// Query logs for explain (LRU cache)
 	queryLogs *queryLogLRU
 
+	// Recent query samples for QueryStatsSummary
+	querySamples *querySampleRing
+
+	// Ranked results of paged queries, served by cursor
+	queryPages *queryPageLRU
+
+	// Recent queries of each session with their results
+	queryHistory *queryHistory
+
+	// Results of repeated queries (nil = caching disabled)
+	queryResults *queryCache
+
 	// Config
 	vectorDim int
 
+	// Default vector search metric for new sessions (empty = cosine)
+	distanceMetric types.DistanceMetric
+
+	// HNSW graph parameters for new sessions
+	indexConfig vector.HNSWConfig
+
+	// Embedding L2-norm bounds enforced on ingest (0 = unbounded)
+	minEmbeddingNorm float64
+	maxEmbeddingNorm float64
+
+	// Scale ingested embeddings to unit L2 norm before storing them
+	normalizeOnIngest bool
+
+	// Merge added entities into an existing one of the same type with at
+	// least this cosine similarity (0 = disabled)
+	dedupThreshold float32
+
+	// Entity access tracking half-life (0 = tracking disabled)
+	popularityHalfLife time.Duration
+
+	// Embeds entities/text units submitted without an embedding
+	embedder EmbeddingProvider
+
+	// Replace expired sessions on write instead of failing the write
+	recreateExpiredSessions bool
+
+	// Called with each expired session before it is removed; evictMu
+	// serializes evictions
+	evictionCallback func(sessionID string, info types.SessionInfo)
+	evictMu          sync.Mutex
+
+	// Bytes one session may hold before adds fail (0 = unlimited)
+	maxSessionBytes int64
+
+	// Latest background index rebuild per session; rebuildMu guards the map
+	// and the statuses in it
+	rebuilds  map[string]*indexRebuild
+	rebuildMu sync.Mutex
+
 	// Session cleanup
 	cleanupInterval time.Duration
 	stopCleanup     chan struct{}
 	cleanupWg       sync.WaitGroup
 }
 
+// indexRebuild is a background index rebuild of one session
+type indexRebuild struct {
+	sess   *store.SessionStore // a session recreated under the same ID does not inherit the status
+	status types.IndexStatus
+}
+
 type queryLog struct {
 	sessionID string
 	spec      types.QuerySpec
@@ -160,7 +236,12 @@ func NewEngine(vectorDim int) *Engine {
 	e := &Engine{
 		sessions:        make(map[string]*store.SessionStore),
 		queryLogs:       newQueryLogLRU(MaxQueryLogEntries),
+		querySamples:    newQuerySampleRing(MaxQuerySamples),
+		queryPages:      newQueryPageLRU(MaxQueryPageEntries),
+		queryHistory:    newQueryHistory(DefaultQueryHistorySize),
 		vectorDim:       vectorDim,
+		indexConfig:     vector.DefaultHNSWConfig(),
+		embedder:        NoopEmbeddingProvider{},
 		cleanupInterval: 60 * time.Second,
 		stopCleanup:     make(chan struct{}),
 	}
@@ -168,29 +249,264 @@ func NewEngine(vectorDim int) *Engine {
 	return e
 }
 
+// SetEmbeddingNormBounds rejects ingested embeddings whose L2 norm falls
+// outside [minNorm, maxNorm]. A zero bound disables that side of the check.
+func (e *Engine) SetEmbeddingNormBounds(minNorm, maxNorm float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.minEmbeddingNorm = minNorm
+	e.maxEmbeddingNorm = maxNorm
+}
+
+// SetNormalizeOnIngest makes ingest scale every embedding to unit L2 norm
+// before it is stored, for clients that forget to. Norm bounds still apply
+// to the embedding as sent.
+func (e *Engine) SetNormalizeOnIngest(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.normalizeOnIngest = enabled
+}
+
+// SetDedupThreshold makes AddEntity merge a new entity into an existing one
+// of the same type whose embedding has a cosine similarity of at least
+// threshold, returning the existing entity instead of a near-duplicate (see
+// SessionStore.AddEntityDedup). Bulk adds are not deduplicated. 0 disables
+// it, the default.
+func (e *Engine) SetDedupThreshold(threshold float32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dedupThreshold = threshold
+}
+
+// SetDistanceMetric sets the vector search metric for sessions created
+// from now on. Existing sessions keep theirs (see SetSessionDistanceMetric).
+func (e *Engine) SetDistanceMetric(metric types.DistanceMetric) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.distanceMetric = metric
+}
+
+// SetIndexConfig sets the HNSW graph parameters (M, efConstruction and the
+// default efSearch) for sessions created from now on. RebuildVectorIndices
+// applies it to an existing session.
+func (e *Engine) SetIndexConfig(config vector.HNSWConfig) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.indexConfig = config
+}
+
+// SetSessionDistanceMetric changes one session's vector search metric,
+// rebuilding its indices. The choice is persisted with the session.
+func (e *Engine) SetSessionDistanceMetric(sessionID string, metric types.DistanceMetric) error {
+	if _, err := types.ParseDistanceMetric(string(metric)); err != nil {
+		return err
+	}
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return sess.SetDistanceMetric(metric)
+}
+
+// SetRecreateExpiredSessions controls writes to an expired session. By
+// default the first such write fails with ErrSessionExpired and the session
+// is dropped; when enabled, the write transparently starts a fresh session
+// under the same ID, so concurrent writers all see the same outcome.
+func (e *Engine) SetRecreateExpiredSessions(enabled bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recreateExpiredSessions = enabled
+}
+
+// SetMaxSessionBytes caps the memory each session may hold, as reported in
+// SessionInfo.MemoryBytes (0 = unlimited). Once a session reaches it, adds
+// to that session fail with ErrSessionQuotaExceeded until data is deleted;
+// the add that crosses the cap, or a bulk add, may overshoot it.
+func (e *Engine) SetMaxSessionBytes(maxBytes int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxSessionBytes = maxBytes
+}
+
+// checkSessionQuota fails once sess holds the per-session byte cap
+func (e *Engine) checkSessionQuota(sess *store.SessionStore) error {
+	e.mu.RLock()
+	maxBytes := e.maxSessionBytes
+	e.mu.RUnlock()
+	if maxBytes > 0 && sess.MemoryBytes() >= maxBytes {
+		return ErrSessionQuotaExceeded
+	}
+	return nil
+}
+
+// SetSessionEvictionCallback registers fn to be called synchronously with
+// each expired session before it is removed, whether by the cleanup loop or
+// by a write finding it expired. The session's data can still be read with
+// ExportSession during the call. nil removes the callback.
+func (e *Engine) SetSessionEvictionCallback(fn func(sessionID string, info types.SessionInfo)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.evictionCallback = fn
+}
+
+// SetPopularityTracking enables per-entity access counters that decay with the
+// given half-life. Entities are counted when fetched directly or returned by a
+// query. Tracking adds a write on every read, so it is off by default; a zero
+// half-life disables it.
+func (e *Engine) SetPopularityTracking(halfLife time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.popularityHalfLife = halfLife
+}
+
+// SetQueryCache caches up to size query results, each reused by identical
+// queries until its session changes or ttl passes (ttl 0 = no expiry).
+// size 0 disables the cache.
+func (e *Engine) SetQueryCache(size int, ttl time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if size <= 0 {
+		e.queryResults = nil
+		return
+	}
+	e.queryResults = newQueryCache(size, ttl)
+}
+
+// queryCacheFor returns the query cache to use for spec, or nil when spec
+// must be computed fresh. PopularityBoost results change with every read, so
+// they are not cached while popularity tracking is on.
+func (e *Engine) queryCacheFor(spec types.QuerySpec) *queryCache {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if spec.PopularityBoost > 0 && e.popularityHalfLife > 0 {
+		return nil
+	}
+	return e.queryResults
+}
+
+// popularityTracking returns the tracking half-life (0 = disabled)
+func (e *Engine) popularityTracking() time.Duration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.popularityHalfLife
+}
+
+// recordEntityAccess counts an access for each entity when tracking is enabled
+func (e *Engine) recordEntityAccess(sess *store.SessionStore, ids ...uint64) {
+	if halfLife := e.popularityTracking(); halfLife > 0 {
+		sess.RecordEntityAccess(ids, halfLife)
+	}
+}
+
+// EntityPopularity returns the decayed access count of an entity
+// (0 when tracking is disabled or the entity was never accessed)
+func (e *Engine) EntityPopularity(sessionID string, id uint64) float64 {
+	halfLife := e.popularityTracking()
+	if halfLife <= 0 {
+		return 0
+	}
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return 0
+	}
+	return sess.EntityPopularity(id, halfLife)
+}
+
+// checkEmbedding validates an embedding's dimension and its norm against the
+// configured bounds. Empty embeddings are allowed (the object is simply not
+// indexed).
+func (e *Engine) checkEmbedding(embedding []float32) error {
+	if len(embedding) == 0 {
+		return nil
+	}
+	if err := e.checkDimension(embedding); err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	minNorm, maxNorm := e.minEmbeddingNorm, e.maxEmbeddingNorm
+	e.mu.RUnlock()
+
+	if minNorm == 0 && maxNorm == 0 {
+		return nil
+	}
+
+	norm := float64(simd.L2Norm(embedding))
+	if norm < minNorm || (maxNorm > 0 && norm > maxNorm) {
+		return fmt.Errorf("%w: norm=%g (allowed %g..%g)", types.ErrSuspiciousEmbedding, norm, minNorm, maxNorm)
+	}
+	return nil
+}
+
+// unitNormTolerance is how far from 1 an L2 norm may be for normalization
+// to leave the embedding as is
+const unitNormTolerance = 1e-6
+
+// normalizeEmbedding returns embedding scaled to unit L2 norm when
+// normalization on ingest is enabled. The caller's slice is not modified;
+// empty, zero and already-unit embeddings are returned unchanged.
+func (e *Engine) normalizeEmbedding(embedding []float32) []float32 {
+	e.mu.RLock()
+	enabled := e.normalizeOnIngest
+	e.mu.RUnlock()
+
+	if !enabled || len(embedding) == 0 {
+		return embedding
+	}
+	norm := simd.L2Norm(embedding)
+	if norm == 0 || math.Abs(float64(norm)-1) <= unitNormTolerance {
+		return embedding
+	}
+	normalized := make([]float32, len(embedding))
+	for i, v := range embedding {
+		normalized[i] = v / norm
+	}
+	return normalized
+}
+
 // =============================================================================
 // Session Management
 // =============================================================================
 
-// getOrCreateSession gets or creates a session store
+// getOrCreateSession gets or creates a session store. Creation is
+// single-flight: concurrent first writes to a new session ID all receive the
+// same store.
 func (e *Engine) getOrCreateSession(sessionID string) (*store.SessionStore, error) {
 	if sessionID == "" {
 		return nil, ErrSessionRequired
 	}
 
-	e.mu.Lock()
-	defer e.mu.Unlock()
+	for {
+		// Fast path: existing live session under the read lock
+		e.mu.RLock()
+		sess, ok := e.sessions[sessionID]
+		e.mu.RUnlock()
+		if ok && !sess.IsExpired() {
+			sess.Touch()
+			return sess, nil
+		}
+		if ok {
+			if e.evictExpired(sessionID, sess) && !e.recreateExpiredSessionsEnabled() {
+				return nil, ErrSessionExpired
+			}
+			continue
+		}
 
-	// Check if session exists
-	if sess, ok := e.sessions[sessionID]; ok {
-		if sess.IsExpired() {
-			delete(e.sessions, sessionID)
-			return nil, ErrSessionExpired
+		e.mu.Lock()
+		if _, ok := e.sessions[sessionID]; ok {
+			// Another writer created it meanwhile
+			e.mu.Unlock()
+			continue
 		}
-		sess.Touch()
-		return sess, nil
+		sess, err := e.newSessionLocked(sessionID)
+		e.mu.Unlock()
+		return sess, err
 	}
+}
 
+// newSessionLocked creates and registers an empty session. Caller must hold
+// e.mu for writing.
+func (e *Engine) newSessionLocked(sessionID string) (*store.SessionStore, error) {
 	// Enforce max session limit (DoS protection)
 	if len(e.sessions) >= MaxSessions {
 		return nil, fmt.Errorf("max sessions limit reached (%d)", MaxSessions)
@@ -198,10 +514,53 @@ func (e *Engine) getOrCreateSession(sessionID string) (*store.SessionStore, erro
 
 	// Create new session (auto-create on first write)
 	sess := store.NewSessionStore(sessionID, e.vectorDim)
+	sess.SetIndexConfig(e.indexConfig)
+	if e.distanceMetric != "" {
+		if err := sess.SetDistanceMetric(e.distanceMetric); err != nil {
+			return nil, err
+		}
+	}
 	e.sessions[sessionID] = sess
 	return sess, nil
 }
 
+// recreateExpiredSessionsEnabled reports the SetRecreateExpiredSessions setting
+func (e *Engine) recreateExpiredSessionsEnabled() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.recreateExpiredSessions
+}
+
+// evictExpired removes sess, found expired under sessionID, after passing it
+// to the eviction callback. It reports false, removing nothing, when the
+// session was replaced, evicted or revived meanwhile. Evictions run one at a
+// time, so the callback sees each session once.
+func (e *Engine) evictExpired(sessionID string, sess *store.SessionStore) bool {
+	e.evictMu.Lock()
+	defer e.evictMu.Unlock()
+
+	e.mu.RLock()
+	current, ok := e.sessions[sessionID]
+	callback := e.evictionCallback
+	e.mu.RUnlock()
+	if !ok || current != sess || !sess.IsExpired() {
+		return false
+	}
+
+	// Called without e.mu so the callback can use the engine
+	if callback != nil {
+		callback(sessionID, sess.GetInfo())
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.sessions[sessionID] == sess {
+		delete(e.sessions, sessionID)
+		e.forgetSession(sessionID)
+	}
+	return true
+}
+
 // getSession gets an existing session (does not auto-create)
 func (e *Engine) getSession(sessionID string) (*store.SessionStore, error) {
 	if sessionID == "" {
@@ -238,6 +597,39 @@ func (e *Engine) ListSessions() []types.SessionInfo {
 	return result
 }
 
+// ListSessionsFiltered returns the active sessions matching filter with IDs
+// after cursor, up to limit (0 = all), in ID order. The returned cursor is
+// the last ID of the page when more sessions match, or "" when none do.
+func (e *Engine) ListSessionsFiltered(filter types.SessionFilter, cursor string, limit int) ([]types.SessionInfo, string) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	ids := make([]string, 0, len(e.sessions))
+	for id := range e.sessions {
+		if id > cursor && filter.MatchesID(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	var result []types.SessionInfo
+	for _, id := range ids {
+		sess := e.sessions[id]
+		if sess.IsExpired() {
+			continue
+		}
+		info := sess.GetInfo()
+		if info.CreatedAt <= filter.CreatedAfter {
+			continue
+		}
+		if limit > 0 && len(result) == limit {
+			return result, result[limit-1].ID
+		}
+		result = append(result, info)
+	}
+	return result, ""
+}
+
 // DeleteSession deletes a session and all its data
 func (e *Engine) DeleteSession(sessionID string) bool {
 	e.mu.Lock()
@@ -248,9 +640,79 @@ func (e *Engine) DeleteSession(sessionID string) bool {
 	}
 
 	delete(e.sessions, sessionID)
+	e.forgetSession(sessionID)
 	return true
 }
 
+// FlushSession removes every record, vector and community from a session.
+// The session itself stays, keeping its TTL, metadata and index settings,
+// and new records get IDs starting from 1 again.
+func (e *Engine) FlushSession(sessionID string) error {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	sess.Clear()
+	sess.Touch()
+	return nil
+}
+
+// CloneSession copies session srcID to the new session dstID: records, IDs,
+// vectors, communities and settings. The copy shares no state with the
+// original, so either can be changed without affecting the other.
+func (e *Engine) CloneSession(srcID, dstID string) error {
+	if dstID == "" {
+		return ErrSessionRequired
+	}
+	src, err := e.getSession(srcID)
+	if err != nil {
+		return err
+	}
+	if _, err := e.getSession(dstID); err == nil {
+		return ErrSessionExists
+	}
+
+	// Copy outside e.mu so a large session does not block other sessions
+	clone, err := src.Clone(dstID)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if existing, ok := e.sessions[dstID]; ok && !existing.IsExpired() {
+		return ErrSessionExists
+	}
+	if len(e.sessions) >= MaxSessions {
+		return fmt.Errorf("max sessions limit reached (%d)", MaxSessions)
+	}
+	e.sessions[dstID] = clone
+	return nil
+}
+
+// MergeSession folds session srcID's records into session dstID under new
+// IDs, rewiring relationships, links and communities to match. onConflict
+// decides what happens to records clashing with dstID's; with
+// ConflictDedupTitle, entities sharing a title with one of dstID's are
+// merged into it. srcID is left unchanged.
+func (e *Engine) MergeSession(dstID, srcID string, onConflict types.ConflictPolicy) error {
+	if dstID == srcID {
+		return fmt.Errorf("cannot merge session %s into itself", srcID)
+	}
+	src, err := e.getSession(srcID)
+	if err != nil {
+		return err
+	}
+	dst, err := e.getOrCreateSession(dstID)
+	if err != nil {
+		return err
+	}
+	if err := e.checkSessionQuota(dst); err != nil {
+		return err
+	}
+	return dst.Import(src.CopySnapshot(), onConflict)
+}
+
 // GetSessionInfo returns info for a specific session
 func (e *Engine) GetSessionInfo(sessionID string) (types.SessionInfo, error) {
 	sess, err := e.getSession(sessionID)
@@ -275,6 +737,24 @@ func (e *Engine) SetSessionTTL(sessionID string, ttl, idleTTL int64) error {
 	return nil
 }
 
+// SetSessionMetadata updates session key-value metadata
+func (e *Engine) SetSessionMetadata(sessionID string, updates map[string]string, replace bool) error {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return sess.SetMetadata(updates, replace)
+}
+
+// GetSessionMetadata returns session key-value metadata
+func (e *Engine) GetSessionMetadata(sessionID string) (map[string]string, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return sess.GetMetadata(), nil
+}
+
 // TouchSession updates session last access time
 func (e *Engine) TouchSession(sessionID string) error {
 	sess, err := e.getSession(sessionID)
@@ -326,26 +806,18 @@ func (e *Engine) StopSessionCleanup() {
 }
 
 func (e *Engine) cleanupExpiredSessions() {
-	// Use RLock first to collect expired sessions (avoids long write lock)
+	// Collect under the read lock; evictExpired re-checks each one
 	e.mu.RLock()
-	var expired []string
+	expired := make(map[string]*store.SessionStore)
 	for id, sess := range e.sessions {
 		if sess.IsExpired() {
-			expired = append(expired, id)
+			expired[id] = sess
 		}
 	}
 	e.mu.RUnlock()
 
-	// Only acquire write lock if there are sessions to delete
-	if len(expired) > 0 {
-		e.mu.Lock()
-		for _, id := range expired {
-			// Re-check expiry in case session was touched between locks
-			if sess, ok := e.sessions[id]; ok && sess.IsExpired() {
-				delete(e.sessions, id)
-			}
-		}
-		e.mu.Unlock()
+	for id, sess := range expired {
+		e.evictExpired(id, sess)
 	}
 }
 
@@ -358,6 +830,9 @@ func (e *Engine) AddDocument(sessionID, extID, filename string) (*types.Document
 	if err != nil {
 		return nil, err
 	}
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
 	return sess.AddDocument(extID, filename)
 }
 
@@ -369,6 +844,15 @@ func (e *Engine) GetDocument(sessionID string, id uint64) (*types.Document, bool
 	return sess.GetDocument(id)
 }
 
+// GetDocumentByExternalID looks a document up by its external ID
+func (e *Engine) GetDocumentByExternalID(sessionID, extID string) (*types.Document, bool) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, false
+	}
+	return sess.GetDocumentByExternalID(extID)
+}
+
 func (e *Engine) DeleteDocument(sessionID string, id uint64) bool {
 	sess, err := e.getSession(sessionID)
 	if err != nil {
@@ -395,11 +879,21 @@ func (e *Engine) UpdateDocumentStatus(sessionID string, id uint64, status types.
 // =============================================================================
 
 func (e *Engine) AddTextUnit(sessionID, extID string, docID uint64, content string, embedding []float32, tokenCount int) (*types.TextUnit, error) {
+	embedding, err := e.autoEmbed(embedding, content)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkEmbedding(embedding); err != nil {
+		return nil, err
+	}
 	sess, err := e.getOrCreateSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
-	return sess.AddTextUnit(extID, docID, content, embedding, tokenCount)
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
+	return sess.AddTextUnit(extID, docID, content, e.normalizeEmbedding(embedding), tokenCount)
 }
 
 func (e *Engine) GetTextUnit(sessionID string, id uint64) (*types.TextUnit, bool) {
@@ -410,6 +904,25 @@ func (e *Engine) GetTextUnit(sessionID string, id uint64) (*types.TextUnit, bool
 	return sess.GetTextUnit(id)
 }
 
+// GetTextUnitByExternalID looks a text unit up by its external ID
+func (e *Engine) GetTextUnitByExternalID(sessionID, extID string) (*types.TextUnit, bool) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, false
+	}
+	return sess.GetTextUnitByExternalID(extID)
+}
+
+// TextUnitEmbedding returns a copy of a text unit's stored embedding, as
+// normalized on ingest and decoded from the index precision
+func (e *Engine) TextUnitEmbedding(sessionID string, id uint64) ([]float32, bool) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, false
+	}
+	return sess.TextUnitEmbedding(id)
+}
+
 func (e *Engine) DeleteTextUnit(sessionID string, id uint64) bool {
 	sess, err := e.getSession(sessionID)
 	if err != nil {
@@ -431,19 +944,80 @@ func (e *Engine) LinkTextUnitToEntity(sessionID string, tuID, entityID uint64) b
 // =============================================================================
 
 func (e *Engine) AddEntity(sessionID, extID, title, entType, description string, embedding []float32) (*types.Entity, error) {
-	sess, err := e.getOrCreateSession(sessionID)
-	if err != nil {
-		return nil, err
-	}
-	return sess.AddEntity(extID, title, entType, description, embedding)
+	return e.AddEntityWithTitleEmbedding(sessionID, extID, title, entType, description, embedding, nil)
 }
 
-func (e *Engine) GetEntity(sessionID string, id uint64) (*types.Entity, bool) {
-	sess, err := e.getSession(sessionID)
-	if err != nil {
-		return nil, false
-	}
-	return sess.GetEntity(id)
+// AddEntityWithTitleEmbedding adds an entity that also carries a separate
+// title embedding, used when a query sets QuerySpec.TitleWeight. Sessions
+// only pay for the title index once they store a title embedding.
+func (e *Engine) AddEntityWithTitleEmbedding(sessionID, extID, title, entType, description string, embedding, titleEmbedding []float32) (*types.Entity, error) {
+	return e.AddEntityWithMetadata(sessionID, extID, title, entType, description, embedding, titleEmbedding, nil)
+}
+
+// AddEntityWithMetadata adds an entity with structured metadata, which
+// QuerySpec.MetadataFilters can match on. titleEmbedding may be nil.
+func (e *Engine) AddEntityWithMetadata(sessionID, extID, title, entType, description string, embedding, titleEmbedding []float32, metadata map[string]string) (*types.Entity, error) {
+	embedding, err := e.autoEmbed(embedding, entityEmbeddingText(title, description))
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkEmbedding(embedding); err != nil {
+		return nil, err
+	}
+	if err := e.checkEmbedding(titleEmbedding); err != nil {
+		return nil, err
+	}
+	sess, err := e.getOrCreateSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
+
+	e.mu.RLock()
+	threshold := e.dedupThreshold
+	e.mu.RUnlock()
+	if threshold > 0 {
+		ent, _, err := sess.AddEntityDedup(threshold, extID, title, entType, description, e.normalizeEmbedding(embedding), e.normalizeEmbedding(titleEmbedding), metadata)
+		return ent, err
+	}
+	return sess.AddEntityWithMetadata(extID, title, entType, description, e.normalizeEmbedding(embedding), e.normalizeEmbedding(titleEmbedding), metadata)
+}
+
+func (e *Engine) GetEntity(sessionID string, id uint64) (*types.Entity, bool) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, false
+	}
+	ent, ok := sess.GetEntity(id)
+	if ok {
+		e.recordEntityAccess(sess, ent.ID)
+	}
+	return ent, ok
+}
+
+// GetEntityByExternalID looks an entity up by its external ID
+func (e *Engine) GetEntityByExternalID(sessionID, extID string) (*types.Entity, bool) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, false
+	}
+	ent, ok := sess.GetEntityByExternalID(extID)
+	if ok {
+		e.recordEntityAccess(sess, ent.ID)
+	}
+	return ent, ok
+}
+
+// EntityEmbedding returns a copy of an entity's stored embedding, as
+// normalized on ingest and decoded from the index precision
+func (e *Engine) EntityEmbedding(sessionID string, id uint64) ([]float32, bool) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, false
+	}
+	return sess.EntityEmbedding(id)
 }
 
 func (e *Engine) GetEntityByTitle(sessionID, title string) (*types.Entity, bool) {
@@ -451,15 +1025,42 @@ func (e *Engine) GetEntityByTitle(sessionID, title string) (*types.Entity, bool)
 	if err != nil {
 		return nil, false
 	}
-	return sess.GetEntityByTitle(title)
+	ent, ok := sess.GetEntityByTitle(title)
+	if ok {
+		e.recordEntityAccess(sess, ent.ID)
+	}
+	return ent, ok
 }
 
 func (e *Engine) UpdateEntityDescription(sessionID string, id uint64, description string, embedding []float32) bool {
+	if e.checkEmbedding(embedding) != nil {
+		return false
+	}
 	sess, err := e.getSession(sessionID)
 	if err != nil {
 		return false
 	}
-	return sess.UpdateEntityDescription(id, description, embedding)
+	return sess.UpdateEntityDescription(id, description, e.normalizeEmbedding(embedding))
+}
+
+// UpdateEntityTitle renames an entity; it fails if another entity in the
+// session already has newTitle
+func (e *Engine) UpdateEntityTitle(sessionID string, id uint64, newTitle string) error {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return sess.UpdateEntityTitle(id, newTitle)
+}
+
+// UpdateEntityMetadata merges metadata into an entity's metadata; an empty
+// value removes the key
+func (e *Engine) UpdateEntityMetadata(sessionID string, id uint64, metadata map[string]string) bool {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return false
+	}
+	return sess.UpdateEntityMetadata(id, metadata)
 }
 
 func (e *Engine) DeleteEntity(sessionID string, id uint64) bool {
@@ -470,16 +1071,73 @@ func (e *Engine) DeleteEntity(sessionID string, id uint64) bool {
 	return sess.DeleteEntity(id)
 }
 
+// MergeEntities folds the duplicate mergeID into keepID and deletes it.
+// See SessionStore.MergeEntities for how edges and links are combined.
+func (e *Engine) MergeEntities(sessionID string, keepID, mergeID uint64) error {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return sess.MergeEntities(keepID, mergeID)
+}
+
 // =============================================================================
 // Relationship Operations
 // =============================================================================
 
 func (e *Engine) AddRelationship(sessionID, extID string, sourceID, targetID uint64, relType, description string, weight float32) (*types.Relationship, error) {
+	return e.AddRelationshipWithOptions(sessionID, extID, sourceID, targetID, relType, description, weight, types.RelationshipOptions{})
+}
+
+// AddRelationshipWithOptions adds a relationship created with a validity
+// window and direction in one step
+func (e *Engine) AddRelationshipWithOptions(sessionID, extID string, sourceID, targetID uint64, relType, description string, weight float32, opts types.RelationshipOptions) (*types.Relationship, error) {
 	sess, err := e.getOrCreateSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
-	return sess.AddRelationship(extID, sourceID, targetID, relType, description, weight)
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
+	return sess.AddRelationshipWithOptions(extID, sourceID, targetID, relType, description, weight, opts)
+}
+
+// SetRelationshipValidity sets the temporal validity window of a relationship
+func (e *Engine) SetRelationshipValidity(sessionID string, id uint64, validFrom, validUntil int64) error {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return sess.SetRelationshipValidity(id, validFrom, validUntil)
+}
+
+// SetRelationshipDirected marks a relationship directed or undirected
+func (e *Engine) SetRelationshipDirected(sessionID string, id uint64, directed bool) error {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return sess.SetRelationshipDirected(id, directed)
+}
+
+// UpdateRelationshipWeight sets a relationship's weight. The session's
+// communities are marked dirty until the next ComputeCommunities.
+func (e *Engine) UpdateRelationshipWeight(sessionID string, id uint64, weight float32) error {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return sess.UpdateRelationshipWeight(id, weight)
+}
+
+// CommunitiesDirty reports whether a session's edges changed since its
+// communities were last computed
+func (e *Engine) CommunitiesDirty(sessionID string) (bool, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return false, err
+	}
+	return sess.CommunitiesDirty(), nil
 }
 
 func (e *Engine) GetRelationship(sessionID string, id uint64) (*types.Relationship, bool) {
@@ -490,6 +1148,15 @@ func (e *Engine) GetRelationship(sessionID string, id uint64) (*types.Relationsh
 	return sess.GetRelationship(id)
 }
 
+// GetRelationshipByExternalID looks a relationship up by its external ID
+func (e *Engine) GetRelationshipByExternalID(sessionID, extID string) (*types.Relationship, bool) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, false
+	}
+	return sess.GetRelationshipByExternalID(extID)
+}
+
 func (e *Engine) GetRelationshipByEntities(sessionID string, sourceID, targetID uint64) (*types.Relationship, bool) {
 	sess, err := e.getSession(sessionID)
 	if err != nil {
@@ -506,16 +1173,179 @@ func (e *Engine) DeleteRelationship(sessionID string, id uint64) bool {
 	return sess.DeleteRelationship(id)
 }
 
+// RelationshipTypeStats returns the relationship type histogram of a session
+func (e *Engine) RelationshipTypeStats(sessionID string) ([]types.RelationshipTypeStat, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return sess.RelationshipTypeStats(), nil
+}
+
+// Count returns the number of items of one type in a session. It only reads
+// the size of that collection, so it is cheap enough to poll.
+func (e *Engine) Count(sessionID string, itemType types.ItemType) (int, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	switch itemType {
+	case types.ItemTypeDocument:
+		return sess.DocumentCount(), nil
+	case types.ItemTypeTextUnit:
+		return sess.TextUnitCount(), nil
+	case types.ItemTypeEntity:
+		return sess.EntityCount(), nil
+	case types.ItemTypeRelationship:
+		return sess.RelationshipCount(), nil
+	case types.ItemTypeCommunity:
+		return sess.CommunityCount(), nil
+	default:
+		return 0, fmt.Errorf("unknown item type %q", itemType)
+	}
+}
+
+// EntityTypeCounts returns the number of entities of each type in a session
+func (e *Engine) EntityTypeCounts(sessionID string) (map[string]int, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return sess.EntityTypeCounts(), nil
+}
+
+// RelationshipTypeCounts returns the number of relationships of each type
+// in a session
+func (e *Engine) RelationshipTypeCounts(sessionID string) (map[string]int, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return sess.RelationshipTypeCounts(), nil
+}
+
+// GetNeighbors returns an entity's direct relationships in the given
+// direction, ordered by ID. relTypes restricts the result to those types;
+// empty returns every type.
+func (e *Engine) GetNeighbors(sessionID string, entityID uint64, direction types.Direction, relTypes []string) ([]*types.Relationship, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := sess.GetEntity(entityID); !ok {
+		return nil, ErrEntityNotFound
+	}
+
+	var candidates []*types.Relationship
+	if direction != types.DirectionIncoming {
+		candidates = append(candidates, sess.GetOutgoingRelationships(entityID)...)
+	}
+	if direction != types.DirectionOutgoing {
+		candidates = append(candidates, sess.GetIncomingRelationships(entityID)...)
+	}
+
+	allowed := make(map[string]bool, len(relTypes))
+	for _, t := range relTypes {
+		allowed[t] = true
+	}
+
+	seen := make(map[uint64]bool, len(candidates))
+	result := make([]*types.Relationship, 0, len(candidates))
+	for _, rel := range candidates {
+		if seen[rel.ID] { // self-loops appear in both directions
+			continue
+		}
+		seen[rel.ID] = true
+		if len(allowed) > 0 && !allowed[rel.Type] {
+			continue
+		}
+		result = append(result, rel)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// Subgraph returns the entities within hops of the seeds (in either
+// direction) and every relationship among them. hops 0 returns just the
+// seeds. Entities are in BFS order and at most maxNodes are returned
+// (<= 0 uses MaxSubgraphNodes); unknown seeds are skipped.
+func (e *Engine) Subgraph(sessionID string, seedIDs []uint64, hops, maxNodes int) ([]*types.Entity, []*types.Relationship, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if maxNodes <= 0 {
+		maxNodes = MaxSubgraphNodes
+	}
+
+	visited := make(map[uint64]bool)
+	entities := make([]*types.Entity, 0)
+	visit := func(id uint64) bool {
+		if visited[id] || len(entities) >= maxNodes {
+			return false
+		}
+		ent, ok := sess.GetEntity(id)
+		if !ok {
+			return false
+		}
+		visited[id] = true
+		entities = append(entities, ent)
+		return true
+	}
+
+	var frontier []uint64
+	for _, id := range seedIDs {
+		if visit(id) {
+			frontier = append(frontier, id)
+		}
+	}
+	for hop := 0; hop < hops && len(frontier) > 0 && len(entities) < maxNodes; hop++ {
+		var next []uint64
+		for _, id := range frontier {
+			neighbors := sess.GetNeighbors(id)
+			sort.Slice(neighbors, func(i, j int) bool { return neighbors[i] < neighbors[j] })
+			for _, nid := range neighbors {
+				if visit(nid) {
+					next = append(next, nid)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	rels := make([]*types.Relationship, 0)
+	for _, ent := range entities {
+		for _, rel := range sess.GetOutgoingRelationships(ent.ID) {
+			if visited[rel.TargetID] {
+				rels = append(rels, rel)
+			}
+		}
+	}
+	sort.Slice(rels, func(i, j int) bool { return rels[i].ID < rels[j].ID })
+
+	return entities, rels, nil
+}
+
 // =============================================================================
 // Community Operations
 // =============================================================================
 
 func (e *Engine) AddCommunity(sessionID, extID, title, summary, fullContent string, level int, entityIDs, relIDs []uint64, embedding []float32) (*types.Community, error) {
+	if err := e.checkEmbedding(embedding); err != nil {
+		return nil, err
+	}
 	sess, err := e.getOrCreateSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
-	return sess.AddCommunity(extID, title, summary, fullContent, level, entityIDs, relIDs, embedding)
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
+	return sess.AddCommunity(extID, title, summary, fullContent, level, entityIDs, relIDs, e.normalizeEmbedding(embedding))
 }
 
 func (e *Engine) GetCommunity(sessionID string, id uint64) (*types.Community, bool) {
@@ -534,14 +1364,40 @@ func (e *Engine) DeleteCommunity(sessionID string, id uint64) bool {
 	return sess.DeleteCommunity(id)
 }
 
+// GetEntityCommunities returns the communities containing an entity at every
+// hierarchy level, ordered by level
+func (e *Engine) GetEntityCommunities(sessionID string, entityID uint64) ([]*types.Community, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return sess.GetEntityCommunities(entityID)
+}
+
 // ComputeCommunities runs Leiden clustering and creates communities
 func (e *Engine) ComputeCommunities(sessionID string, config graph.LeidenConfig) ([]*types.Community, error) {
+	communities, _, err := e.ComputeCommunitiesWithModularity(sessionID, graph.AlgorithmLeiden, config)
+	return communities, err
+}
+
+// ComputeCommunitiesWithModularity clusters with the named algorithm
+// (graph.AlgorithmLeiden or graph.AlgorithmLouvain; "" means Leiden) and also
+// returns the modularity of the resulting partition, for tuning the resolution
+func (e *Engine) ComputeCommunitiesWithModularity(sessionID, algorithm string, config graph.LeidenConfig) ([]*types.Community, float64, error) {
+	return e.ComputeCommunitiesWithModularityContext(context.Background(), sessionID, algorithm, config)
+}
+
+// ComputeCommunitiesWithModularityContext is like
+// ComputeCommunitiesWithModularity but gives up with ctx.Err() once ctx is
+// done, leaving the session's communities unchanged
+func (e *Engine) ComputeCommunitiesWithModularityContext(ctx context.Context, sessionID, algorithm string, config graph.LeidenConfig) ([]*types.Community, float64, error) {
 	sess, err := e.getSession(sessionID)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	// Create adapter for Leiden algorithm
+	// Create adapter for the community algorithm
+	edgeVersion := sess.EdgeVersion()
 	entities := sess.GetAllEntities()
 	relationships := sess.GetAllRelationships()
 	idGen := sess.GetIDGenerator()
@@ -558,8 +1414,14 @@ func (e *Engine) ComputeCommunities(sessionID string, config graph.LeidenConfig)
 		relStore.inEdges[rel.TargetID] = append(relStore.inEdges[rel.TargetID], rel)
 	}
 
-	leiden := graph.NewLeiden(entStore, relStore, config)
-	clusters := leiden.ComputeCommunities()
+	detector, err := graph.NewCommunityDetector(algorithm, entStore, relStore, config)
+	if err != nil {
+		return nil, 0, err
+	}
+	clusters, err := detector.ComputeCommunitiesContext(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
 
 	// Clear existing communities
 	sess.ClearCommunities()
@@ -569,82 +1431,529 @@ func (e *Engine) ComputeCommunities(sessionID string, config graph.LeidenConfig)
 
 	for _, comm := range communities {
 		if _, err := sess.AddCommunity(comm.ExternalID, comm.Title, comm.Summary, comm.FullContent, comm.Level, comm.EntityIDs, comm.RelationshipIDs, nil); err != nil {
+			return nil, 0, err
+		}
+	}
+	sess.MarkCommunitiesComputed(edgeVersion)
+
+	return communities, detector.Modularity(), nil
+}
+
+// ComputeHierarchicalCommunities runs hierarchical Leiden clustering
+func (e *Engine) ComputeHierarchicalCommunities(sessionID string, config graph.LeidenConfig) ([]*types.Community, error) {
+	return e.ComputeHierarchicalCommunitiesContext(context.Background(), sessionID, config)
+}
+
+// ComputeHierarchicalCommunitiesContext is like
+// ComputeHierarchicalCommunities but gives up with ctx.Err() once ctx is
+// done, leaving the session's communities unchanged
+func (e *Engine) ComputeHierarchicalCommunitiesContext(ctx context.Context, sessionID string, config graph.LeidenConfig) ([]*types.Community, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Enforce max 5 levels
+	if config.MaxLevels > 5 {
+		config.MaxLevels = 5
+	}
+	if config.MaxLevels < 1 {
+		config.MaxLevels = 1
+	}
+	if config.MinCommunitySize < 2 {
+		config.MinCommunitySize = 2
+	}
+	if config.LevelResolution <= 0 || config.LevelResolution >= 1 {
+		config.LevelResolution = 0.7
+	}
+
+	edgeVersion := sess.EdgeVersion()
+	entities := sess.GetAllEntities()
+	relationships := sess.GetAllRelationships()
+	idGen := sess.GetIDGenerator()
+
+	entStore := &entityStoreAdapter{entities: entities}
+	relStore := &relationshipStoreAdapter{
+		relationships: relationships,
+		outEdges:      make(map[uint64][]*types.Relationship),
+		inEdges:       make(map[uint64][]*types.Relationship),
+	}
+	for _, rel := range relationships {
+		relStore.outEdges[rel.SourceID] = append(relStore.outEdges[rel.SourceID], rel)
+		relStore.inEdges[rel.TargetID] = append(relStore.inEdges[rel.TargetID], rel)
+	}
+
+	leiden := graph.NewLeiden(entStore, relStore, config)
+	hierarchical, err := leiden.ComputeHierarchicalCommunitiesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clear existing communities
+	sess.ClearCommunities()
+
+	// Build community objects from hierarchical results
+	communities := graph.BuildHierarchicalCommunities(hierarchical, entStore, relStore, idGen)
+
+	for _, comm := range communities {
+		if _, err := sess.AddCommunity(comm.ExternalID, comm.Title, comm.Summary, comm.FullContent, comm.Level, comm.EntityIDs, comm.RelationshipIDs, nil); err != nil {
+			return nil, err
+		}
+	}
+	sess.MarkCommunitiesComputed(edgeVersion)
+
+	return communities, nil
+}
+
+// ComputePageRank scores every entity by PageRank over the directed
+// relationship graph and stores the score on the entity
+func (e *Engine) ComputePageRank(sessionID string) (map[uint64]float64, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	entities := sess.GetAllEntities()
+	entityIDs := make([]uint64, len(entities))
+	for i, ent := range entities {
+		entityIDs[i] = ent.ID
+	}
+
+	scores := graph.PageRank(entityIDs, &sessionRelAdapter{sess: sess}, graph.DefaultPageRankDamping, graph.DefaultPageRankIterations)
+	sess.SetEntityPageRanks(scores)
+	return scores, nil
+}
+
+// =============================================================================
+// Query - Main Query Pipeline
+// =============================================================================
+
+func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPack, error) {
+	return e.QueryContext(context.Background(), sessionID, spec)
+}
+
+// QueryContext is like Query but gives up with ctx.Err() once ctx is done,
+// checking between stages and during graph traversal
+func (e *Engine) QueryContext(ctx context.Context, sessionID string, spec types.QuerySpec) (*types.ContextPack, error) {
+	if spec.Cursor != "" {
+		return e.queryPage(sessionID, spec.Cursor)
+	}
+
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := types.ParseFusionMethod(string(spec.FusionMethod)); err != nil {
+		return nil, err
+	}
+	if _, err := types.ParseTraversalDirection(string(spec.TraversalDirection)); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+
+	// Atomically increment query ID without global lock
+	queryID := atomic.AddUint64(&e.queryIDGen, 1)
+
+	// Read the version before running, so a write during the query leaves
+	// the cached result already stale
+	cache := e.queryCacheFor(spec)
+	var cacheKey queryCacheKey
+	var version uint64
+	if cache != nil {
+		version = sess.DataVersion()
+		if cacheKey, err = queryCacheKeyFor(sessionID, spec); err != nil {
+			cache = nil
+		} else if entry, ok := cache.Get(cacheKey, sess, version); ok {
+			cached := entry.qlog.spec // includes relaxations
+			cached.PageSize = spec.PageSize
+			return e.finishQuery(sess, sessionID, queryID, cached, entry.pack, entry.qlog, startTime, true), nil
+		}
+	}
+
+	pack, qlog, err := e.runQuery(ctx, sess, sessionID, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relax the query until it yields MinResults or runs out of steps
+	var relaxations []string
+	steps := queryRelaxations(spec)
+	for step := 0; step < maxQueryRelaxations && resultCount(pack) < spec.MinResults; step++ {
+		desc, ok := steps[step%len(steps)](&spec)
+		if !ok {
+			continue
+		}
+		relaxations = append(relaxations, desc)
+		if pack, qlog, err = e.runQuery(ctx, sess, sessionID, spec); err != nil {
 			return nil, err
 		}
 	}
-
-	return communities, nil
+
+	pack.Stats.Relaxations = relaxations
+	if cache != nil {
+		cache.Set(cacheKey, sess, version, pack, qlog)
+	}
+	return e.finishQuery(sess, sessionID, queryID, spec, *pack, qlog, startTime, false), nil
+}
+
+// finishQuery stamps a computed or cached result with its query ID and
+// timing, records it for EXPLAIN, stats and paging, and cuts the first page.
+// pack is a copy, so stamping it leaves a cached result untouched.
+func (e *Engine) finishQuery(sess *store.SessionStore, sessionID string, queryID uint64, spec types.QuerySpec, pack types.ContextPack, qlog *queryLog, startTime time.Time, cacheHit bool) *types.ContextPack {
+	entityIDs := make([]uint64, len(pack.Entities))
+	for i, er := range pack.Entities {
+		entityIDs[i] = er.Entity.ID
+	}
+	e.recordEntityAccess(sess, entityIDs...)
+
+	pack.QueryID = queryID
+	pack.Stats.CacheHit = cacheHit
+	pack.Stats.DurationMicros = time.Since(startTime).Microseconds()
+
+	// Save query log
+	e.queryLogs.Set(queryID, qlog)
+	e.recordQuerySample(sessionID, spec, &pack)
+	e.queryHistory.add(sessionID, spec, &pack)
+
+	if spec.PageSize > 0 {
+		e.queryPages.Set(queryID, sessionID, &pack)
+		return pageOf(&pack, 0, min(spec.PageSize, MaxQueryPageSize))
+	}
+	return &pack
+}
+
+// Query relaxation bounds
+const (
+	maxQueryRelaxations   = 4    // relaxation steps tried for QuerySpec.MinResults
+	maxRelaxedKHops       = 5    // relaxation never traverses deeper than this
+	maxRelaxedTopK        = 1000 // relaxation never widens the vector search past this
+	relaxedSimilarityStep = 0.1  // how far each step lowers MinSimilarity
+)
+
+// queryRelaxation loosens one constraint of spec, returning a description of
+// the change, or ok false when that constraint cannot be loosened further
+type queryRelaxation func(spec *types.QuerySpec) (desc string, ok bool)
+
+// queryRelaxations returns the relaxation steps for spec, tried in turn: a
+// lower similarity threshold (when it has one), a wider vector search and
+// one more hop
+func queryRelaxations(spec types.QuerySpec) []queryRelaxation {
+	steps := []queryRelaxation{relaxTopK, relaxKHops}
+	if spec.MinSimilarity > 0 {
+		steps = append([]queryRelaxation{relaxMinSimilarity}, steps...)
+	}
+	return steps
+}
+
+func relaxMinSimilarity(spec *types.QuerySpec) (string, bool) {
+	if spec.MinSimilarity <= 0 {
+		return "", false
+	}
+	// Round to keep descriptions readable despite float32 steps
+	lowered := float32(math.Round(float64(spec.MinSimilarity-relaxedSimilarityStep)*100) / 100)
+	lowered = max(lowered, 0)
+	desc := fmt.Sprintf("min_similarity:%g->%g", spec.MinSimilarity, lowered)
+	spec.MinSimilarity = lowered
+	return desc, true
+}
+
+func relaxTopK(spec *types.QuerySpec) (string, bool) {
+	if spec.TopK >= maxRelaxedTopK {
+		return "", false
+	}
+	topK := min(spec.TopK*2, maxRelaxedTopK)
+	if topK <= 0 {
+		topK = 10
+	}
+	desc := fmt.Sprintf("top_k:%d->%d", spec.TopK, topK)
+	spec.TopK = topK
+	return desc, true
+}
+
+func relaxKHops(spec *types.QuerySpec) (string, bool) {
+	if spec.KHops >= maxRelaxedKHops {
+		return "", false
+	}
+	desc := fmt.Sprintf("k_hops:%d->%d", spec.KHops, spec.KHops+1)
+	spec.KHops++
+	return desc, true
+}
+
+// resultCount is the number of context items (text units, entities, communities) in a pack
+func resultCount(pack *types.ContextPack) int {
+	return len(pack.TextUnits) + len(pack.Entities) + len(pack.Communities)
+}
+
+// searchEntities runs the entity vector search. With a TitleWeight and a
+// session that stores title embeddings, candidates from both indices are
+// rescored as the weighted mean of their title and description similarity.
+func searchEntities(descIndex, titleIndex vector.Index, spec types.QuerySpec) []vector.SearchResult {
+	if spec.TitleWeight <= 0 || titleIndex == nil || titleIndex.Count() == 0 {
+		return descIndex.SearchWithEf(spec.QueryVector, spec.TopK, spec.EfSearch)
+	}
+
+	candidates := make(map[uint64]bool)
+	for _, r := range descIndex.SearchWithEf(spec.QueryVector, spec.TopK, spec.EfSearch) {
+		candidates[r.ID] = true
+	}
+	for _, r := range titleIndex.SearchWithEf(spec.QueryVector, spec.TopK, spec.EfSearch) {
+		candidates[r.ID] = true
+	}
+
+	results := make([]vector.SearchResult, 0, len(candidates))
+	for id := range candidates {
+		var sum, weights float32
+		descSim, hasDesc := descIndex.Similarity(id, spec.QueryVector)
+		titleSim, hasTitle := titleIndex.Similarity(id, spec.QueryVector)
+		if hasDesc && spec.DescriptionWeight > 0 {
+			sum += spec.DescriptionWeight * descSim
+			weights += spec.DescriptionWeight
+		}
+		if hasTitle {
+			sum += spec.TitleWeight * titleSim
+			weights += spec.TitleWeight
+		}
+
+		score := descSim
+		if weights > 0 {
+			score = sum / weights
+		}
+		results = append(results, vector.SearchResult{ID: id, Similarity: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Similarity != results[j].Similarity {
+			return results[i].Similarity > results[j].Similarity
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > spec.TopK {
+		results = results[:spec.TopK]
+	}
+	return results
+}
+
+// queryEntityFilter returns whether an entity passes the spec's EntityTypes
+// and MetadataFilters, or nil when the spec has neither
+func queryEntityFilter(spec types.QuerySpec) func(*types.Entity) bool {
+	if len(spec.EntityTypes) == 0 && len(spec.MetadataFilters) == 0 {
+		return nil
+	}
+	var allowed map[string]bool
+	if len(spec.EntityTypes) > 0 {
+		allowed = make(map[string]bool, len(spec.EntityTypes))
+		for _, t := range spec.EntityTypes {
+			allowed[t] = true
+		}
+	}
+	return func(ent *types.Entity) bool {
+		if allowed != nil && !allowed[ent.Type] {
+			return false
+		}
+		return metadataMatches(ent.Metadata, spec.MetadataFilters)
+	}
+}
+
+// metadataMatches reports whether metadata has every filter key with an equal value
+func metadataMatches(metadata, filters map[string]string) bool {
+	for k, v := range filters {
+		if got, ok := metadata[k]; !ok || got != v {
+			return false
+		}
+	}
+	return true
+}
+
+// filterBySimilarity drops search results below minSimilarity (0 = keep all)
+func filterBySimilarity(results []vector.SearchResult, minSimilarity float32) []vector.SearchResult {
+	if minSimilarity <= 0 {
+		return results
+	}
+	kept := results[:0]
+	for _, r := range results {
+		if r.Similarity >= minSimilarity {
+			kept = append(kept, r)
+		}
+	}
+	return kept
 }
 
-// ComputeHierarchicalCommunities runs hierarchical Leiden clustering
-func (e *Engine) ComputeHierarchicalCommunities(sessionID string, config graph.LeidenConfig) ([]*types.Community, error) {
-	sess, err := e.getSession(sessionID)
-	if err != nil {
-		return nil, err
+// Hybrid search defaults
+const (
+	// DefaultKeywordWeight is the share of the keyword score in weighted
+	// fusion when QuerySpec.KeywordWeight is unset
+	DefaultKeywordWeight = 0.5
+	// DefaultRRFK is the rank offset k of reciprocal rank fusion when
+	// QuerySpec.RRFK is unset
+	DefaultRRFK = 60
+)
+
+// mergeKeywordHits adds BM25 hits to the text unit seeds and rescores every
+// seed with spec.FusionMethod. Weighted fusion scores
+// (1-weight)*similarity + weight*keyword score, with keyword scores scaled
+// so the best hit is 1. RRF scores sum 1/(k+rank) over the vector and
+// keyword rankings, scaled so a unit ranked first in both scores 1.
+func mergeKeywordHits(sess *store.SessionStore, results map[uint64]*types.TextUnitResult, hits []textsearch.Result, spec types.QuerySpec, qlog *queryLog) {
+	// Everything in results so far came from vector search
+	vectorRanking := make([]uint64, 0, len(results))
+	for id := range results {
+		vectorRanking = append(vectorRanking, id)
+	}
+	sort.Slice(vectorRanking, func(i, j int) bool {
+		a, b := results[vectorRanking[i]], results[vectorRanking[j]]
+		if a.Similarity != b.Similarity {
+			return a.Similarity > b.Similarity
+		}
+		return a.TextUnit.ID < b.TextUnit.ID
+	})
+
+	keywordRanking := make([]uint64, 0, len(hits))
+	best := hits[0].Score
+	for _, hit := range hits {
+		keywordScore := float32(hit.Score / best)
+		r, ok := results[hit.ID]
+		if !ok {
+			tu, ok := sess.GetTextUnit(hit.ID)
+			if !ok {
+				continue
+			}
+			r = &types.TextUnitResult{TextUnit: tu, Hop: 0}
+			results[hit.ID] = r
+
+			qlog.seeds = append(qlog.seeds, types.SeedInfo{
+				Type:       types.SearchTypeKeyword,
+				ID:         hit.ID,
+				ExternalID: tu.ExternalID,
+				Similarity: keywordScore,
+				LinkedIDs:  tu.EntityIDs,
+			})
+		}
+		r.KeywordScore = keywordScore
+		keywordRanking = append(keywordRanking, hit.ID)
 	}
 
-	// Enforce max 5 levels
-	if config.MaxLevels > 5 {
-		config.MaxLevels = 5
+	if spec.FusionMethod == types.FusionRRF {
+		k := spec.RRFK
+		if k <= 0 {
+			k = DefaultRRFK
+		}
+		fused := reciprocalRankFusion([][]uint64{vectorRanking, keywordRanking}, k)
+		maxScore := 2 / float64(k+1)
+		for id, r := range results {
+			r.Score = float32(fused[id] / maxScore)
+		}
+		return
 	}
-	if config.MaxLevels < 1 {
-		config.MaxLevels = 1
+
+	weight := spec.KeywordWeight
+	if weight <= 0 {
+		weight = DefaultKeywordWeight
 	}
-	if config.MinCommunitySize < 2 {
-		config.MinCommunitySize = 2
+	if weight > 1 {
+		weight = 1
 	}
-	if config.LevelResolution <= 0 || config.LevelResolution >= 1 {
-		config.LevelResolution = 0.7
+	for _, r := range results {
+		r.Score = (1-weight)*r.Similarity + weight*r.KeywordScore
 	}
+}
 
-	entities := sess.GetAllEntities()
-	relationships := sess.GetAllRelationships()
-	idGen := sess.GetIDGenerator()
-
-	entStore := &entityStoreAdapter{entities: entities}
-	relStore := &relationshipStoreAdapter{
-		relationships: relationships,
-		outEdges:      make(map[uint64][]*types.Relationship),
-		inEdges:       make(map[uint64][]*types.Relationship),
+// mmrRerank reorders score-sorted text units by Maximal Marginal Relevance:
+// each pick maximizes (1-diversity)*score - diversity*(highest similarity
+// to a unit already picked), comparing stored embeddings. Units without an
+// embedding count as unlike every other unit.
+func mmrRerank(list []types.TextUnitResult, index vector.Index, diversity float32) []types.TextUnitResult {
+	if index == nil || len(list) < 2 {
+		return list
 	}
-	for _, rel := range relationships {
-		relStore.outEdges[rel.SourceID] = append(relStore.outEdges[rel.SourceID], rel)
-		relStore.inEdges[rel.TargetID] = append(relStore.inEdges[rel.TargetID], rel)
+	if diversity > 1 {
+		diversity = 1
 	}
 
-	leiden := graph.NewLeiden(entStore, relStore, config)
-	hierarchical := leiden.ComputeHierarchicalCommunities()
-
-	// Clear existing communities
-	sess.ClearCommunities()
-
-	// Build community objects from hierarchical results
-	communities := graph.BuildHierarchicalCommunities(hierarchical, entStore, relStore, idGen)
+	maxSim := make([]float32, len(list))
+	picked := make([]bool, len(list))
+	reranked := make([]types.TextUnitResult, 0, len(list))
+	for len(reranked) < len(list) {
+		best := -1
+		var bestValue float32
+		for i := range list {
+			if picked[i] {
+				continue
+			}
+			value := (1-diversity)*list[i].Score - diversity*maxSim[i]
+			if best < 0 || value > bestValue {
+				best, bestValue = i, value
+			}
+		}
+		picked[best] = true
+		reranked = append(reranked, list[best])
 
-	for _, comm := range communities {
-		if _, err := sess.AddCommunity(comm.ExternalID, comm.Title, comm.Summary, comm.FullContent, comm.Level, comm.EntityIDs, comm.RelationshipIDs, nil); err != nil {
-			return nil, err
+		vec, ok := index.Vector(list[best].TextUnit.ID)
+		if !ok {
+			continue
+		}
+		for i := range list {
+			if picked[i] {
+				continue
+			}
+			if sim, ok := index.Similarity(list[i].TextUnit.ID, vec); ok && sim > maxSim[i] {
+				maxSim[i] = sim
+			}
 		}
 	}
-
-	return communities, nil
+	return reranked
 }
 
-// =============================================================================
-// Query - Main Query Pipeline
-// =============================================================================
-
-func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPack, error) {
-	sess, err := e.getSession(sessionID)
-	if err != nil {
-		return nil, err
+// reciprocalRankFusion scores each ID by the sum of 1/(k+rank) over the
+// rankings (best first, ranks from 1) that contain it
+func reciprocalRankFusion(rankings [][]uint64, k int) map[uint64]float64 {
+	scores := make(map[uint64]float64)
+	for _, ranking := range rankings {
+		for i, id := range ranking {
+			scores[id] += 1 / float64(k+i+1)
+		}
 	}
+	return scores
+}
 
-	startTime := time.Now()
+// propagateTraversalScores scores traversed entities by the best path from a
+// seed: seedScore * product(edge weights) * decay^hop. Paths follow edges
+// between consecutive hop layers, so each entity keeps its BFS hop.
+func propagateTraversalScores(seedScores map[uint64]float32, visitedIDs []uint64, hopMap map[uint64]int, rels graph.RelationshipStore, decay float32) map[uint64]float32 {
+	scores := make(map[uint64]float32, len(visitedIDs))
+	for id, score := range seedScores {
+		scores[id] = score
+	}
 
-	// Atomically increment query ID without global lock
-	queryID := atomic.AddUint64(&e.queryIDGen, 1)
+	// visitedIDs is sorted by hop, so every entity is final before it
+	// propagates to the next layer
+	for _, id := range visitedIDs {
+		score, ok := scores[id]
+		if !ok {
+			continue
+		}
+		hop := hopMap[id]
+		propagate := func(rel *types.Relationship, neighbor uint64) {
+			if h, ok := hopMap[neighbor]; !ok || h != hop+1 {
+				return
+			}
+			candidate := score * rel.Weight * decay
+			if best, ok := scores[neighbor]; !ok || candidate > best {
+				scores[neighbor] = candidate
+			}
+		}
+		for _, rel := range rels.GetOutgoing(id) {
+			propagate(rel, rel.TargetID)
+		}
+		for _, rel := range rels.GetIncoming(id) {
+			propagate(rel, rel.SourceID)
+		}
+	}
+	return scores
+}
 
+// runQuery executes one pass of vector search, graph expansion and ranking
+func (e *Engine) runQuery(ctx context.Context, sess *store.SessionStore, sessionID string, spec types.QuerySpec) (*types.ContextPack, *queryLog, error) {
 	// Initialize query log
 	qlog := &queryLog{
 		sessionID: sessionID,
@@ -665,11 +1974,13 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 	communityIndex := sess.GetCommunityIndex()
 
 	// Phase 1: Vector search on selected indices
+	var keywordHits []textsearch.Result
 	for _, searchType := range spec.SearchTypes {
+		span := startSearchSpan(ctx, searchType)
 		switch searchType {
 		case types.SearchTypeTextUnit:
 			if textUnitIndex != nil {
-				results := textUnitIndex.Search(spec.QueryVector, spec.TopK)
+				results := filterBySimilarity(textUnitIndex.SearchWithEf(spec.QueryVector, spec.TopK, spec.EfSearch), spec.MinSimilarity)
 				stats.TextUnitsSearched = textUnitIndex.Count()
 
 				for _, r := range results {
@@ -694,11 +2005,11 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 
 		case types.SearchTypeEntity:
 			if entityIndex != nil {
-				results := entityIndex.Search(spec.QueryVector, spec.TopK)
+				results := filterBySimilarity(searchEntities(entityIndex, sess.GetEntityTitleIndex(), spec), spec.MinSimilarity)
 				stats.EntitiesSearched = entityIndex.Count()
 
 				for _, r := range results {
-					if ent, ok := sess.GetEntity(r.ID); ok {
+					if ent, ok := sess.GetEntity(r.ID); ok && createdWithin(spec, ent.CreatedAt) {
 						entityResults[r.ID] = &types.EntityResult{
 							Entity:     ent,
 							Score:      r.Similarity,
@@ -719,7 +2030,7 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 
 		case types.SearchTypeCommunity:
 			if communityIndex != nil {
-				results := communityIndex.Search(spec.QueryVector, spec.TopK)
+				results := filterBySimilarity(communityIndex.SearchWithEf(spec.QueryVector, spec.TopK, spec.EfSearch), spec.MinSimilarity)
 				stats.CommunitiesSearched = communityIndex.Count()
 
 				for _, r := range results {
@@ -740,8 +2051,25 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 					}
 				}
 			}
+
+		case types.SearchTypeKeyword:
+			if spec.KeywordQuery != "" {
+				keywordHits = sess.SearchTextUnitsByKeyword(spec.KeywordQuery, spec.TopK)
+			}
 		}
+		span.End()
 	}
+	if len(keywordHits) > 0 {
+		mergeKeywordHits(sess, textUnitResults, keywordHits, spec, qlog)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	// Entity type and metadata filters decide which entities are returned;
+	// filtered entities still connect the graph during traversal
+	entityFilter := queryEntityFilter(spec)
 
 	// Phase 2: Graph expansion from entity seeds
 	if spec.KHops > 0 {
@@ -763,14 +2091,79 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 			seedEntityIDs = append(seedEntityIDs, cr.Community.EntityIDs...)
 		}
 
+		// Linked seeds must fall in the creation window too
+		if spec.CreatedAfter != 0 || spec.CreatedBefore != 0 {
+			kept := seedEntityIDs[:0]
+			for _, eid := range seedEntityIDs {
+				if ent, ok := sess.GetEntity(eid); ok && createdWithin(spec, ent.CreatedAt) {
+					kept = append(kept, eid)
+				}
+			}
+			seedEntityIDs = kept
+		}
+
 		// BFS traversal using session's relationship store
-		relAdapter := &sessionRelAdapter{sess: sess}
-		visitedIDs, hopMap, traversal := graph.BFSTraversal(
+		// With decay the MaxEntities cutoff ranks by propagated score, and
+		// with entity filters it counts only entities that pass them, so
+		// traversal only stops at KHops
+		maxNodes := spec.MaxEntities
+		if spec.DecayFactor > 0 || entityFilter != nil {
+			maxNodes = math.MaxInt
+		}
+		direction, _ := types.ParseTraversalDirection(string(spec.TraversalDirection))
+		relAdapter := &sessionRelAdapter{
+			sess:          sess,
+			asOf:          spec.AsOf,
+			createdAfter:  spec.CreatedAfter,
+			createdBefore: spec.CreatedBefore,
+			direction:     direction,
+		}
+		_, span := tracing.Tracer().Start(ctx, "engine.traversal", trace.WithAttributes(
+			attribute.Int("gibram.seeds", len(seedEntityIDs)),
+			attribute.Int("gibram.k_hops", spec.KHops),
+		))
+		visitedIDs, hopMap, traversal, err := graph.BFSTraversalContext(
+			ctx,
 			seedEntityIDs,
 			relAdapter,
 			spec.KHops,
-			spec.MaxEntities,
+			maxNodes,
+			spec.MaxExpansionPerHop,
 		)
+		if err != nil {
+			tracing.End(span, err)
+			return nil, nil, err
+		}
+		span.SetAttributes(attribute.Int("gibram.visited", len(visitedIDs)))
+		span.End()
+
+		// Edge-weighted decay: seeds start at their search score
+		var propagated map[uint64]float32
+		if spec.DecayFactor > 0 {
+			seedScores := make(map[uint64]float32, len(seedEntityIDs))
+			setSeed := func(eid uint64, score float32) {
+				if best, ok := seedScores[eid]; !ok || score > best {
+					seedScores[eid] = score
+				}
+			}
+			for eid, er := range entityResults {
+				setSeed(eid, er.Score)
+			}
+			for _, tur := range textUnitResults {
+				for _, eid := range tur.TextUnit.EntityIDs {
+					setSeed(eid, tur.Score)
+				}
+			}
+			for _, cr := range communityResults {
+				for _, eid := range cr.Community.EntityIDs {
+					setSeed(eid, cr.Score)
+				}
+			}
+			propagated = propagateTraversalScores(seedScores, visitedIDs, hopMap, relAdapter, spec.DecayFactor)
+			for i := range traversal {
+				traversal[i].Cumulative = propagated[traversal[i].ToEntityID]
+			}
+		}
 
 		stats.EdgesScanned = len(traversal)
 		qlog.traversal = traversal
@@ -778,9 +2171,12 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 		// Add discovered entities
 		for _, eid := range visitedIDs {
 			if _, exists := entityResults[eid]; !exists {
-				if ent, ok := sess.GetEntity(eid); ok {
+				if ent, ok := sess.GetEntity(eid); ok && (entityFilter == nil || entityFilter(ent)) {
 					hop := hopMap[eid]
 					score := float32(1.0 / float64(1+hop))
+					if propagated != nil {
+						score = propagated[eid]
+					}
 
 					entityResults[eid] = &types.EntityResult{
 						Entity:     ent,
@@ -792,8 +2188,12 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 			}
 		}
 
-		// Collect text units from discovered entities
+		// Collect text units from discovered entities, skipping seeds the
+		// filters drop
 		for _, er := range entityResults {
+			if entityFilter != nil && !entityFilter(er.Entity) {
+				continue
+			}
 			for _, tuID := range er.Entity.TextUnitIDs {
 				if _, exists := textUnitResults[tuID]; !exists {
 					if tu, ok := sess.GetTextUnit(tuID); ok {
@@ -812,6 +2212,19 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 		}
 	}
 
+	// Drop filtered search hits and seeds once traversal is done
+	if entityFilter != nil {
+		for eid, er := range entityResults {
+			if !entityFilter(er.Entity) {
+				delete(entityResults, eid)
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	// Phase 3: Collect relationships between found entities
 	relationshipResults := make([]types.RelationshipResult, 0)
 	entitySet := make(map[uint64]bool)
@@ -822,7 +2235,7 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 	for eid := range entityResults {
 		rels := sess.GetOutgoingRelationships(eid)
 		for _, rel := range rels {
-			if entitySet[rel.TargetID] {
+			if entitySet[rel.TargetID] && rel.IsValidAt(spec.AsOf) && createdWithin(spec, rel.CreatedAt) {
 				sourceEnt, _ := sess.GetEntity(rel.SourceID)
 				targetEnt, _ := sess.GetEntity(rel.TargetID)
 
@@ -852,12 +2265,38 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 	sort.Slice(textUnitList, func(i, j int) bool {
 		return textUnitList[i].Score > textUnitList[j].Score
 	})
+	if spec.Diversity > 0 {
+		textUnitList = mmrRerank(textUnitList, textUnitIndex, spec.Diversity)
+	}
 	if len(textUnitList) > spec.MaxTextUnits {
 		textUnitList = textUnitList[:spec.MaxTextUnits]
 	}
+	if spec.IncludeTextStats {
+		for i := range textUnitList {
+			tu := textUnitList[i].TextUnit
+			textUnitList[i].TokenCount = tu.TokenCount
+			textUnitList[i].ContentLength = utf8.RuneCountInString(tu.Content)
+		}
+	}
 
+	popularityHalfLife := e.popularityTracking()
+	entityCount := 0
+	if spec.PageRankWeight > 0 {
+		entityCount = sess.EntityCount()
+	}
 	entityList := make([]types.EntityResult, 0, len(entityResults))
 	for _, er := range entityResults {
+		if spec.HubPenalty > 0 {
+			degree := sess.Degree(er.Entity.ID)
+			er.Score *= float32(math.Pow(float64(1+degree), -float64(spec.HubPenalty)))
+		}
+		if spec.PopularityBoost > 0 && popularityHalfLife > 0 {
+			popularity := sess.EntityPopularity(er.Entity.ID, popularityHalfLife)
+			er.Score *= float32(1 + float64(spec.PopularityBoost)*math.Log1p(popularity))
+		}
+		if spec.PageRankWeight > 0 {
+			er.Score *= float32(1 + float64(spec.PageRankWeight)*er.Entity.PageRank*float64(entityCount))
+		}
 		entityList = append(entityList, *er)
 	}
 	sort.Slice(entityList, func(i, j int) bool {
@@ -878,28 +2317,37 @@ func (e *Engine) Query(sessionID string, spec types.QuerySpec) (*types.ContextPa
 		communityList = communityList[:spec.MaxCommunities]
 	}
 
-	stats.DurationMicros = time.Since(startTime).Microseconds()
-
-	// Save query log
-	e.queryLogs.Set(queryID, qlog)
-
 	return &types.ContextPack{
-		QueryID:       queryID,
 		TextUnits:     textUnitList,
 		Entities:      entityList,
 		Communities:   communityList,
 		Relationships: relationshipResults,
 		Stats:         stats,
-	}, nil
+	}, qlog, nil
+}
+
+// startSearchSpan starts the span of one phase 1 search
+func startSearchSpan(ctx context.Context, searchType types.SearchType) trace.Span {
+	name := "engine.vector_search"
+	switch searchType {
+	case types.SearchTypeCommunity:
+		name = "engine.community_lookup"
+	case types.SearchTypeKeyword:
+		name = "engine.keyword_search"
+	}
+	_, span := tracing.Tracer().Start(ctx, name, trace.WithAttributes(attribute.String("gibram.search_type", string(searchType))))
+	return span
 }
 
 // =============================================================================
 // Explain - Query Explanation
 // =============================================================================
 
-func (e *Engine) Explain(queryID uint64) (*types.ExplainPack, bool) {
+// Explain returns the seeds and traversal of a recent query. Query IDs are
+// engine-wide, so a query run in another session is reported as not found.
+func (e *Engine) Explain(sessionID string, queryID uint64) (*types.ExplainPack, bool) {
 	qlog, ok := e.queryLogs.Get(queryID)
-	if !ok {
+	if !ok || qlog.sessionID != sessionID {
 		return nil, false
 	}
 
@@ -964,47 +2412,126 @@ func (e *Engine) InfoForSession(sessionID string) (types.ServerInfo, error) {
 // Index Operations
 // =============================================================================
 
-// RebuildVectorIndices rebuilds all vector indices for a session
+// RebuildVectorIndices rebuilds the HNSW graphs of a session from their
+// stored vectors, applying the engine's current index config. Use it after
+// heavy deletion or a config change; the session's data is left as is.
 func (e *Engine) RebuildVectorIndices(sessionID string) error {
 	sess, err := e.getSession(sessionID)
 	if err != nil {
 		return err
 	}
 
-	// Get vectors from current indices before recreating
-	tuIdx := sess.GetTextUnitIndex()
-	entIdx := sess.GetEntityIndex()
-	commIdx := sess.GetCommunityIndex()
+	e.mu.RLock()
+	config := e.indexConfig
+	e.mu.RUnlock()
+
+	sess.SetIndexConfig(config)
+	return sess.RebuildIndices()
+}
 
-	textUnitVectors := tuIdx.GetAllVectors()
-	entityVectors := entIdx.GetAllVectors()
-	communityVectors := commIdx.GetAllVectors()
+// RebuildVectorIndicesAsync starts rebuilding a session's HNSW graphs like
+// RebuildVectorIndices and returns without waiting. Queries and writes keep
+// using the old graphs until the new ones replace them; IndexStatus reports
+// the progress.
+func (e *Engine) RebuildVectorIndicesAsync(sessionID string) error {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return err
+	}
+	count := sess.VectorCount()
 
-	// Clear and rebuild
-	sess.Clear()
+	e.rebuildMu.Lock()
+	if prev, ok := e.rebuilds[sessionID]; ok && prev.sess == sess && prev.status.State == types.IndexBuilding {
+		e.rebuildMu.Unlock()
+		return ErrRebuildInProgress
+	}
+	if e.rebuilds == nil {
+		e.rebuilds = make(map[string]*indexRebuild)
+	}
+	rebuild := &indexRebuild{sess: sess, status: types.IndexStatus{
+		State:       types.IndexBuilding,
+		VectorCount: count,
+		StartedAt:   time.Now().Unix(),
+	}}
+	e.rebuilds[sessionID] = rebuild
+	e.rebuildMu.Unlock()
 
-	// Re-add vectors
-	newTuIdx := sess.GetTextUnitIndex()
-	newEntIdx := sess.GetEntityIndex()
-	newCommIdx := sess.GetCommunityIndex()
+	e.mu.RLock()
+	config := e.indexConfig
+	e.mu.RUnlock()
+	sess.SetIndexConfig(config)
 
-	for id, vec := range textUnitVectors {
-		if err := newTuIdx.Add(id, vec); err != nil {
-			return err
+	go func() {
+		err := sess.RebuildIndicesOnline(func(done, total int) {
+			e.rebuildMu.Lock()
+			defer e.rebuildMu.Unlock()
+			rebuild.status.VectorCount = total
+			rebuild.status.Percent = 100 * float64(done) / float64(total)
+		})
+
+		e.rebuildMu.Lock()
+		defer e.rebuildMu.Unlock()
+		rebuild.status.State = types.IndexReady
+		rebuild.status.FinishedAt = time.Now().Unix()
+		if err != nil {
+			rebuild.status.Error = err.Error()
+		} else {
+			rebuild.status.Percent = 100
 		}
+	}()
+	return nil
+}
+
+// IndexStatus reports whether a session's indices are being rebuilt and how
+// far the rebuild has got. A session never rebuilt in the background is
+// ready.
+func (e *Engine) IndexStatus(sessionID string) (types.IndexStatus, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return types.IndexStatus{}, err
 	}
-	for id, vec := range entityVectors {
-		if err := newEntIdx.Add(id, vec); err != nil {
-			return err
-		}
+	count := sess.VectorCount()
+
+	e.rebuildMu.Lock()
+	defer e.rebuildMu.Unlock()
+	status := types.IndexStatus{State: types.IndexReady, Percent: 100}
+	if rebuild, ok := e.rebuilds[sessionID]; ok && rebuild.sess == sess {
+		status = rebuild.status
 	}
-	for id, vec := range communityVectors {
-		if err := newCommIdx.Add(id, vec); err != nil {
-			return err
-		}
+	if status.State == types.IndexReady {
+		status.VectorCount = count
 	}
+	return status, nil
+}
 
-	return nil
+// forgetSession drops the rebuild status and query history of a removed
+// session
+func (e *Engine) forgetSession(sessionID string) {
+	e.rebuildMu.Lock()
+	delete(e.rebuilds, sessionID)
+	e.rebuildMu.Unlock()
+	e.queryHistory.forget(sessionID)
+}
+
+// QuantizeVectorIndex converts a session's stored vectors to precision in
+// place, keeping its HNSW graphs, and persists the choice with the session
+// so vectors added later are stored the same way. It returns the vector
+// memory before and after the conversion.
+func (e *Engine) QuantizeVectorIndex(sessionID string, precision vector.Precision) (before, after int64, err error) {
+	precision, err = vector.ParsePrecision(string(precision))
+	if err != nil {
+		return 0, 0, err
+	}
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	before = sess.VectorBytes()
+	if err := sess.SetVectorPrecision(precision); err != nil {
+		return 0, 0, err
+	}
+	return before, sess.VectorBytes(), nil
 }
 
 // =============================================================================
@@ -1017,6 +2544,9 @@ func (e *Engine) MSetDocuments(sessionID string, inputs []types.BulkDocumentInpu
 	if err != nil {
 		return nil, err
 	}
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
 
 	ids := make([]uint64, 0, len(inputs))
 	for _, input := range inputs {
@@ -1047,14 +2577,28 @@ func (e *Engine) MGetDocuments(sessionID string, ids []uint64) []*types.Document
 
 // MSetTextUnits adds multiple text units
 func (e *Engine) MSetTextUnits(sessionID string, inputs []types.BulkTextUnitInput) ([]uint64, error) {
+	// A wrong dimension is a client bug, not bad data: reject the whole batch
+	for _, input := range inputs {
+		if err := e.checkDimension(input.Embedding); err != nil {
+			return nil, fmt.Errorf("text unit %s: %w", input.ExternalID, err)
+		}
+	}
+
 	sess, err := e.getOrCreateSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
 
 	ids := make([]uint64, 0, len(inputs))
 	for _, input := range inputs {
-		tu, err := sess.AddTextUnit(input.ExternalID, input.DocumentID, input.Content, input.Embedding, input.TokenCount)
+		embedding, err := e.autoEmbed(input.Embedding, input.Content)
+		if err != nil || e.checkEmbedding(embedding) != nil {
+			continue
+		}
+		tu, err := sess.AddTextUnit(input.ExternalID, input.DocumentID, input.Content, e.normalizeEmbedding(embedding), input.TokenCount)
 		if err != nil {
 			continue
 		}
@@ -1081,14 +2625,31 @@ func (e *Engine) MGetTextUnits(sessionID string, ids []uint64) []*types.TextUnit
 
 // MSetEntities adds multiple entities
 func (e *Engine) MSetEntities(sessionID string, inputs []types.BulkEntityInput) ([]uint64, error) {
+	// Reject the whole batch on a wrong dimension, as MSetTextUnits does
+	for _, input := range inputs {
+		if err := e.checkDimension(input.Embedding); err != nil {
+			return nil, fmt.Errorf("entity %s: %w", input.ExternalID, err)
+		}
+		if err := e.checkDimension(input.TitleEmbedding); err != nil {
+			return nil, fmt.Errorf("entity %s title: %w", input.ExternalID, err)
+		}
+	}
+
 	sess, err := e.getOrCreateSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
 
 	ids := make([]uint64, 0, len(inputs))
 	for _, input := range inputs {
-		ent, err := sess.AddEntity(input.ExternalID, input.Title, input.Type, input.Description, input.Embedding)
+		embedding, err := e.autoEmbed(input.Embedding, entityEmbeddingText(input.Title, input.Description))
+		if err != nil || e.checkEmbedding(embedding) != nil || e.checkEmbedding(input.TitleEmbedding) != nil {
+			continue
+		}
+		ent, err := sess.AddEntityWithMetadata(input.ExternalID, input.Title, input.Type, input.Description, e.normalizeEmbedding(embedding), e.normalizeEmbedding(input.TitleEmbedding), input.Metadata)
 		if err != nil {
 			continue
 		}
@@ -1105,11 +2666,14 @@ func (e *Engine) MGetEntities(sessionID string, ids []uint64) []*types.Entity {
 	}
 
 	result := make([]*types.Entity, 0, len(ids))
+	found := make([]uint64, 0, len(ids))
 	for _, id := range ids {
 		if ent, ok := sess.GetEntity(id); ok {
 			result = append(result, ent)
+			found = append(found, id)
 		}
 	}
+	e.recordEntityAccess(sess, found...)
 	return result
 }
 
@@ -1122,16 +2686,33 @@ func (e *Engine) ListEntities(sessionID string, cursor uint64, limit int) ([]*ty
 	return sess.ListEntities(cursor, limit)
 }
 
+// ListEntitiesByType is ListEntities restricted to entities of entType
+// (empty = all). See SessionStore.ListEntitiesByType for the scan budget.
+func (e *Engine) ListEntitiesByType(sessionID, entType string, cursor uint64, limit int) ([]*types.Entity, uint64) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, 0
+	}
+	return sess.ListEntitiesByType(cursor, limit, entType)
+}
+
 // MSetRelationships adds multiple relationships
 func (e *Engine) MSetRelationships(sessionID string, inputs []types.BulkRelationshipInput) ([]uint64, error) {
 	sess, err := e.getOrCreateSession(sessionID)
 	if err != nil {
 		return nil, err
 	}
+	if err := e.checkSessionQuota(sess); err != nil {
+		return nil, err
+	}
 
 	ids := make([]uint64, 0, len(inputs))
 	for _, input := range inputs {
-		rel, err := sess.AddRelationship(input.ExternalID, input.SourceID, input.TargetID, input.Type, input.Description, input.Weight)
+		rel, err := sess.AddRelationshipWithOptions(input.ExternalID, input.SourceID, input.TargetID, input.Type, input.Description, input.Weight, types.RelationshipOptions{
+			ValidFrom:  input.ValidFrom,
+			ValidUntil: input.ValidUntil,
+			Directed:   input.Directed,
+		})
 		if err != nil {
 			continue
 		}
@@ -1165,6 +2746,54 @@ func (e *Engine) ListRelationships(sessionID string, cursor uint64, limit int) (
 	return sess.ListRelationships(cursor, limit)
 }
 
+// ListRelationshipsFiltered is ListRelationships restricted to relationships
+// matching filter
+func (e *Engine) ListRelationshipsFiltered(sessionID string, filter types.RelationshipFilter, cursor uint64, limit int) ([]*types.Relationship, uint64) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, 0
+	}
+	return sess.ListRelationshipsFiltered(cursor, limit, filter)
+}
+
+// MLinkTextUnitsToEntities links many text unit -> entity pairs in one call
+func (e *Engine) MLinkTextUnitsToEntities(sessionID string, links []types.BulkLinkInput, continueOnError bool) ([]types.BulkLinkResult, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return sess.LinkTextUnitsToEntities(links, continueOnError), nil
+}
+
+// MDeleteTextUnits deletes multiple text units and returns how many existed
+func (e *Engine) MDeleteTextUnits(sessionID string, ids []uint64) (int, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	return sess.DeleteTextUnits(ids), nil
+}
+
+// MDeleteEntities deletes multiple entities, with their relationships and
+// text unit links, and returns how many existed
+func (e *Engine) MDeleteEntities(sessionID string, ids []uint64) (int, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	return sess.DeleteEntities(ids), nil
+}
+
+// MDeleteRelationships deletes multiple relationships and returns how many
+// existed
+func (e *Engine) MDeleteRelationships(sessionID string, ids []uint64) (int, error) {
+	sess, err := e.getSession(sessionID)
+	if err != nil {
+		return 0, err
+	}
+	return sess.DeleteRelationships(ids), nil
+}
+
 // =============================================================================
 // Snapshot/Restore
 // =============================================================================
@@ -1193,21 +2822,22 @@ func (e *Engine) Snapshot(w io.Writer) error {
 		}
 	}
 
-	encoder := json.NewEncoder(w)
-	return encoder.Encode(snapshot)
+	return writeSnapshot(w, &snapshot)
 }
 
-// Restore deserializes engine state from a reader
+// Restore deserializes engine state from a reader. Snapshots of older
+// format versions are migrated; unknown versions fail with
+// ErrSnapshotVersion and a checksum mismatch with ErrSnapshotCorrupt, both
+// leaving the engine unchanged.
 func (e *Engine) Restore(r io.Reader) error {
+	snapshot, err := decodeSnapshot(r)
+	if err != nil {
+		return err
+	}
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	var snapshot EngineSnapshot
-	decoder := json.NewDecoder(r)
-	if err := decoder.Decode(&snapshot); err != nil {
-		return fmt.Errorf("decode snapshot: %w", err)
-	}
-
 	// Validate snapshot
 	if snapshot.VectorDim != e.vectorDim {
 		return fmt.Errorf("vector dimension mismatch: snapshot=%d, engine=%d", snapshot.VectorDim, e.vectorDim)
@@ -1309,31 +2939,95 @@ func (a *relationshipStoreAdapter) GetNeighbors(entityID uint64) []*types.Relati
 	return result
 }
 
-// sessionRelAdapter adapts SessionStore for graph traversal
+// sessionRelAdapter adapts SessionStore for graph traversal.
+// A non-zero asOf hides relationships not valid at that unix time; a
+// creation window hides relationships, and edges to entities, created
+// outside it. A forward or reverse direction hides directed relationships
+// from the endpoint they do not lead away from; empty means both.
 type sessionRelAdapter struct {
-	sess *store.SessionStore
+	sess          *store.SessionStore
+	asOf          int64
+	createdAfter  int64
+	createdBefore int64
+	direction     types.TraversalDirection
 }
 
 func (a *sessionRelAdapter) GetAll() []*types.Relationship {
-	return a.sess.GetAllRelationships()
+	return a.filter(a.sess.GetAllRelationships(), true)
 }
 
 func (a *sessionRelAdapter) Get(id uint64) (*types.Relationship, bool) {
-	return a.sess.GetRelationship(id)
+	rel, ok := a.sess.GetRelationship(id)
+	if !ok || !a.keep(rel) {
+		return nil, false
+	}
+	return rel, true
 }
 
 func (a *sessionRelAdapter) GetOutgoing(entityID uint64) []*types.Relationship {
-	return a.sess.GetOutgoingRelationships(entityID)
+	return a.filter(a.sess.GetOutgoingRelationships(entityID), a.direction != types.TraversalReverse)
 }
 
 func (a *sessionRelAdapter) GetIncoming(entityID uint64) []*types.Relationship {
-	return a.sess.GetIncomingRelationships(entityID)
+	return a.filter(a.sess.GetIncomingRelationships(entityID), a.direction != types.TraversalForward)
 }
 
 func (a *sessionRelAdapter) GetNeighbors(entityID uint64) []*types.Relationship {
 	// Return both outgoing and incoming
 	result := make([]*types.Relationship, 0)
-	result = append(result, a.sess.GetOutgoingRelationships(entityID)...)
-	result = append(result, a.sess.GetIncomingRelationships(entityID)...)
+	result = append(result, a.GetOutgoing(entityID)...)
+	result = append(result, a.GetIncoming(entityID)...)
+	return result
+}
+
+// filter drops relationships outside the as-of validity window or the
+// creation window, and directed ones unless followDirected
+func (a *sessionRelAdapter) filter(rels []*types.Relationship, followDirected bool) []*types.Relationship {
+	if a.asOf == 0 && a.createdAfter == 0 && a.createdBefore == 0 && followDirected {
+		return rels
+	}
+	result := make([]*types.Relationship, 0, len(rels))
+	for _, rel := range rels {
+		if (followDirected || !rel.Directed) && a.keep(rel) {
+			result = append(result, rel)
+		}
+	}
 	return result
 }
+
+// keep reports whether rel is visible to the traversal
+func (a *sessionRelAdapter) keep(rel *types.Relationship) bool {
+	if !rel.IsValidAt(a.asOf) {
+		return false
+	}
+	if a.createdAfter == 0 && a.createdBefore == 0 {
+		return true
+	}
+	if !inCreatedWindow(rel.CreatedAt, a.createdAfter, a.createdBefore) {
+		return false
+	}
+	for _, id := range []uint64{rel.SourceID, rel.TargetID} {
+		ent, ok := a.sess.GetEntity(id)
+		if !ok || !inCreatedWindow(ent.CreatedAt, a.createdAfter, a.createdBefore) {
+			return false
+		}
+	}
+	return true
+}
+
+// createdWithin reports whether createdAt falls in the spec's creation window
+func createdWithin(spec types.QuerySpec, createdAt int64) bool {
+	return inCreatedWindow(createdAt, spec.CreatedAfter, spec.CreatedBefore)
+}
+
+// inCreatedWindow reports whether createdAt lies strictly between after and
+// before; a zero bound is open
+func inCreatedWindow(createdAt, after, before int64) bool {
+	if after != 0 && createdAt <= after {
+		return false
+	}
+	if before != 0 && createdAt >= before {
+		return false
+	}
+	return true
+}
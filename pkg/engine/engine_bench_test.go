@@ -268,7 +268,7 @@ func BenchmarkEngine_Explain(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		e.Explain(result.QueryID)
+		e.Explain(benchSessionID, result.QueryID)
 	}
 }
 
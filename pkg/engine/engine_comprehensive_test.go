@@ -3,11 +3,22 @@ package engine
 
 import (
 	"bytes"
+	"cmp"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gibram-io/gibram/pkg/graph"
+	"github.com/gibram-io/gibram/pkg/store"
 	"github.com/gibram-io/gibram/pkg/types"
 )
 
@@ -111,7 +122,7 @@ func TestScenario_ChatWithPDF(t *testing.T) {
 	}
 
 	// User can explain how results were found
-	explain, ok := e.Explain(result.QueryID)
+	explain, ok := e.Explain(testSessionID, result.QueryID)
 	if !ok {
 		t.Error("Explain should work for recent query")
 	}
@@ -296,10 +307,128 @@ func TestScenario_CommunityDetection(t *testing.T) {
 // Real-World Scenario: TTL-Based Session Cleanup
 // =============================================================================
 
+// =============================================================================
+// Real-World Scenario: Diff Between Extraction Runs
+// =============================================================================
+
+func TestScenario_GraphDiffBetweenRuns(t *testing.T) {
+	e := NewEngine(testVectorDim)
+
+	embedding := randomVector(testVectorDim)
+	ent1 := mustAddEntity(t, e, testSessionID, "ent-1", "Entity One", "test", "Description", embedding)
+	ent2 := mustAddEntity(t, e, testSessionID, "ent-2", "Entity Two", "test", "Description", embedding)
+	rel := mustAddRelationship(t, e, testSessionID, "rel-1", ent1.ID, ent2.ID, "RELATED", "Desc", 1.0)
+
+	// First run: gzip snapshot like the server writes
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if err := e.Snapshot(gw); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatalf("gzip close failed: %v", err)
+	}
+	snap, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("ReadSnapshot failed: %v", err)
+	}
+	before := snap.Sessions[testSessionID]
+
+	// Second run: one entity modified, one removed, one added
+	e.UpdateEntityDescription(testSessionID, ent1.ID, "Refined description", nil)
+	e.DeleteRelationship(testSessionID, rel.ID)
+	e.DeleteEntity(testSessionID, ent2.ID)
+	ent3 := mustAddEntity(t, e, testSessionID, "ent-3", "Entity Three", "test", "New", embedding)
+
+	after, err := e.SessionGraph(testSessionID)
+	if err != nil {
+		t.Fatalf("SessionGraph failed: %v", err)
+	}
+
+	diff := DiffSessionSnapshots(before, after, 0, 0)
+	if diff.Total != 4 || len(diff.Changes) != 4 {
+		t.Fatalf("Expected 4 changes, got total=%d len=%d", diff.Total, len(diff.Changes))
+	}
+	want := []struct {
+		op types.DiffOp
+		id uint64
+	}{
+		{types.DiffModified, ent1.ID},
+		{types.DiffRemoved, ent2.ID},
+		{types.DiffAdded, ent3.ID},
+	}
+	for i, w := range want {
+		c := diff.Changes[i]
+		if c.Op != w.op || c.Entity == nil || c.Entity.ID != w.id {
+			t.Errorf("Change %d: expected %s entity %d, got %+v", i, w.op, w.id, c)
+		}
+	}
+	if c := diff.Changes[3]; c.Op != types.DiffRemoved || c.Relationship == nil || c.Relationship.ID != rel.ID {
+		t.Errorf("Expected removed relationship %d, got %+v", rel.ID, c)
+	}
+
+	// Paging
+	page := DiffSessionSnapshots(before, after, 0, 3)
+	if len(page.Changes) != 3 || page.NextCursor != 3 {
+		t.Errorf("Expected 3 changes and cursor 3, got %d / %d", len(page.Changes), page.NextCursor)
+	}
+	page = DiffSessionSnapshots(before, after, page.NextCursor, 3)
+	if len(page.Changes) != 1 || page.NextCursor != 0 {
+		t.Errorf("Expected last change and cursor 0, got %d / %d", len(page.Changes), page.NextCursor)
+	}
+
+	// Identical states produce no changes
+	if d := DiffSessionSnapshots(after, after, 0, 0); d.Total != 0 {
+		t.Errorf("Expected no changes, got %d", d.Total)
+	}
+}
+
 // =============================================================================
 // Real-World Scenario: Snapshot and Restore (Debugging)
 // =============================================================================
 
+func TestScenario_SessionMetadataSurvivesSnapshot(t *testing.T) {
+	e := NewEngine(testVectorDim)
+	mustAddDocument(t, e, testSessionID, "doc-1", "file.pdf")
+
+	if err := e.SetSessionMetadata(testSessionID, map[string]string{"tenant": "acme", "corpus": "2024-q4"}, false); err != nil {
+		t.Fatalf("SetSessionMetadata failed: %v", err)
+	}
+	if err := e.SetSessionMetadata("missing-session", map[string]string{"k": "v"}, false); err == nil {
+		t.Error("Expected error for missing session")
+	}
+
+	var buf bytes.Buffer
+	if err := e.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	e2 := NewEngine(testVectorDim)
+	if err := e2.Restore(&buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	md, err := e2.GetSessionMetadata(testSessionID)
+	if err != nil {
+		t.Fatalf("GetSessionMetadata failed: %v", err)
+	}
+	if md["tenant"] != "acme" || md["corpus"] != "2024-q4" {
+		t.Errorf("Metadata not restored: %v", md)
+	}
+
+	found := false
+	for _, info := range e2.ListSessions() {
+		if info.ID == testSessionID {
+			found = true
+			if info.Metadata["tenant"] != "acme" {
+				t.Errorf("ListSessions should include metadata, got %v", info.Metadata)
+			}
+		}
+	}
+	if !found {
+		t.Error("Restored session missing from ListSessions")
+	}
+}
+
 func TestScenario_SnapshotRestore(t *testing.T) {
 	e := NewEngine(testVectorDim)
 
@@ -354,6 +483,691 @@ func TestScenario_SnapshotRestore(t *testing.T) {
 	}
 }
 
+func TestScenario_SnapshotFormatVersions(t *testing.T) {
+	e := NewEngine(testVectorDim)
+	populateTransferSession(t, e, testSessionID, "v")
+
+	var buf bytes.Buffer
+	if err := e.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, append(snapshotMagic[:], SnapshotFormatVersion)) {
+		t.Fatalf("Snapshot should start with the format header, got %q", data[:8])
+	}
+
+	// Version 1 snapshots are the JSON body alone, version 2 adds the header
+	body := data[len(snapshotMagic)+1 : len(data)-snapshotChecksumSize]
+	legacy := map[string][]byte{
+		"v1": body,
+		"v2": append(append(snapshotMagic[:], 2), body...),
+	}
+	for name, old := range legacy {
+		e2 := NewEngine(testVectorDim)
+		if err := e2.Restore(bytes.NewReader(old)); err != nil {
+			t.Fatalf("Restore of %s snapshot failed: %v", name, err)
+		}
+		if got, want := e2.Info().EntityCount, e.Info().EntityCount; got != want {
+			t.Errorf("%s restore entity count = %d, want %d", name, got, want)
+		}
+	}
+
+	// Gzipped snapshots carry the header inside the compressed stream
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write(data)
+	_ = gw.Close()
+	snap, err := ReadSnapshot(&gz)
+	if err != nil {
+		t.Fatalf("ReadSnapshot of gzipped snapshot failed: %v", err)
+	}
+	if _, ok := snap.Sessions[testSessionID]; !ok {
+		t.Error("Gzipped snapshot should contain the session")
+	}
+
+	flipped := bytes.Clone(data)
+	flipped[len(flipped)/2] ^= 0x01
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"old magic", append([]byte("GRAM\x03"), data[len(snapshotMagic)+1:]...), ErrSnapshotFormat},
+		{"corrupted magic", append([]byte("GSNX\x03"), data[len(snapshotMagic)+1:]...), ErrSnapshotFormat},
+		{"future version", append(append(snapshotMagic[:], SnapshotFormatVersion+1), data[len(snapshotMagic)+1:]...), ErrSnapshotVersion},
+		{"empty", nil, ErrSnapshotFormat},
+		{"flipped byte", flipped, ErrSnapshotCorrupt},
+		{"truncated", data[:len(data)-10], ErrSnapshotCorrupt},
+		{"missing trailer", data[:len(data)-snapshotChecksumSize], ErrSnapshotCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e2 := NewEngine(testVectorDim)
+			mustAddEntity(t, e2, "keep", "ent-keep", "Keep", "test", "Desc", randomVector(testVectorDim))
+			err := e2.Restore(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Restore error = %v, want %v", err, tt.want)
+			}
+			if e2.Info().EntityCount != 1 {
+				t.Error("Rejected restore should leave the engine unchanged")
+			}
+		})
+	}
+}
+
+// openDurable opens dir for a fresh engine and reports whether it held state
+func openDurable(t *testing.T, dir string) (*Engine, *DurableStore, bool) {
+	t.Helper()
+	e := NewEngine(testVectorDim)
+	d, loaded, err := OpenDurableStore(e, dir)
+	if err != nil {
+		t.Fatalf("OpenDurableStore failed: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return e, d, loaded
+}
+
+// durableLogRecords describes each non-commit record in dir's append log:
+// the session for whole-session records, plus collection and ID for deltas
+func durableLogRecords(t *testing.T, dir string) []string {
+	t.Helper()
+	logs, err := filepath.Glob(filepath.Join(dir, "append-*.log"))
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("Expected one append log, got %v (%v)", logs, err)
+	}
+	data, err := os.ReadFile(logs[0])
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	var records []string
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var rec durableRecord
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatalf("Decode log record failed: %v", err)
+		}
+		switch {
+		case rec.Commit:
+		case rec.Collection == "":
+			records = append(records, rec.Session)
+		case rec.Deleted:
+			records = append(records, fmt.Sprintf("%s -%s %d", rec.Session, rec.Collection, rec.ID))
+		default:
+			records = append(records, fmt.Sprintf("%s %s %d", rec.Session, rec.Collection, rec.ID))
+		}
+	}
+	return records
+}
+
+func TestScenario_DurableStoreAppendAndReload(t *testing.T) {
+	dir := t.TempDir()
+	e, d, loaded := openDurable(t, dir)
+	if loaded {
+		t.Fatal("Empty dir should not report loaded state")
+	}
+
+	ent1, _ := populateTransferSession(t, e, "tenant-a", "")
+	populateTransferSession(t, e, "tenant-b", "")
+	mustAddDocument(t, e, "tenant-c", "doc-c", "c.pdf")
+	if err := d.Sync(5); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	// Only the changed records of changed sessions are appended
+	ent3 := mustAddEntity(t, e, "tenant-a", "ent-3", "Third", "person", "desc", randomVector(testVectorDim))
+	ent4 := mustAddEntity(t, e, "tenant-a", "ent-4", "Fourth", "person", "desc", randomVector(testVectorDim))
+	if err := e.SetSessionMetadata("tenant-c", map[string]string{"owner": "ops"}, false); err != nil {
+		t.Fatalf("SetSessionMetadata failed: %v", err)
+	}
+	e.DeleteSession("tenant-b")
+	if err := d.Sync(6); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if !e.DeleteEntity("tenant-a", ent4.ID) {
+		t.Fatal("DeleteEntity failed")
+	}
+	if err := d.Sync(7); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	got := durableLogRecords(t, dir)
+	want := []string{
+		"tenant-a", "tenant-b", "tenant-c",
+		fmt.Sprintf("tenant-a entity %d", ent3.ID),
+		fmt.Sprintf("tenant-a entity %d", ent4.ID),
+		"tenant-a session 0", "tenant-c session 0", "tenant-b",
+		"tenant-a session 0", fmt.Sprintf("tenant-a -entity %d", ent4.ID),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Log records = %v, want %v", got, want)
+	}
+
+	// An unchanged engine appends nothing
+	if err := d.Sync(7); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if n := len(durableLogRecords(t, dir)); n != len(want) {
+		t.Errorf("Idle sync appended records: %d, want %d", n, len(want))
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	e2, d2, loaded := openDurable(t, dir)
+	if !loaded || d2.LSN() != 7 {
+		t.Fatalf("Reload: loaded=%v lsn=%d, want true/7", loaded, d2.LSN())
+	}
+	assertSameState(t, e, e2)
+	if _, err := e2.GetSessionInfo("tenant-b"); err == nil {
+		t.Error("Deleted session came back on reload")
+	}
+	if md, _ := e2.GetSessionMetadata("tenant-c"); md["owner"] != "ops" {
+		t.Errorf("Session metadata not reloaded: %v", md)
+	}
+	if got, ok := e2.GetEntity("tenant-a", ent1.ID); !ok || got.Title != ent1.Title {
+		t.Errorf("Entity %d not reloaded: %+v", ent1.ID, got)
+	}
+}
+
+func TestScenario_DurableStoreCompaction(t *testing.T) {
+	dir := t.TempDir()
+	e, d, _ := openDurable(t, dir)
+	d.SetCompactSize(1) // every Sync compacts
+
+	populateTransferSession(t, e, "tenant-a", "")
+	if err := d.Sync(1); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	populateTransferSession(t, e, "tenant-b", "")
+	if err := d.Sync(2); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"append-2.log", "base-2.json.gz"}) {
+		t.Errorf("Files after compaction = %v", names)
+	}
+	if recs := durableLogRecords(t, dir); len(recs) != 0 {
+		t.Errorf("Compacted log should hold no session records, got %v", recs)
+	}
+
+	// Appends after a compaction land on the new base
+	d.SetCompactSize(0)
+	mustAddDocument(t, e, "tenant-c", "doc-c", "c.pdf")
+	if err := d.Sync(3); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	e2, d2, loaded := openDurable(t, dir)
+	if !loaded || d2.LSN() != 3 {
+		t.Fatalf("Reload: loaded=%v lsn=%d, want true/3", loaded, d2.LSN())
+	}
+	assertSameState(t, e, e2)
+}
+
+func TestScenario_DurableStoreTornSync(t *testing.T) {
+	dir := t.TempDir()
+	e, d, _ := openDurable(t, dir)
+	populateTransferSession(t, e, "tenant-a", "")
+	if err := d.Sync(4); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// A crash mid-Sync leaves a record without its commit marker
+	logPath := filepath.Join(dir, "append-0.log")
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	if _, err := f.WriteString(`{"session":"tenant-z","state":{"session_id":"ten`); err != nil {
+		t.Fatalf("WriteString failed: %v", err)
+	}
+	_ = f.Close()
+
+	e2, d2, loaded := openDurable(t, dir)
+	if !loaded || d2.LSN() != 4 {
+		t.Fatalf("Reload: loaded=%v lsn=%d, want true/4", loaded, d2.LSN())
+	}
+	assertSameState(t, e, e2)
+
+	// The torn tail is cut, so later syncs reload cleanly
+	mustAddDocument(t, e2, "tenant-b", "doc-b", "b.pdf")
+	if err := d2.Sync(5); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	_ = d2.Close()
+	e3, d3, _ := openDurable(t, dir)
+	if d3.LSN() != 5 {
+		t.Errorf("LSN after torn tail = %d, want 5", d3.LSN())
+	}
+	assertSameState(t, e2, e3)
+}
+
+func TestScenario_DurableStoreMigrateSnapshot(t *testing.T) {
+	src := NewEngine(testVectorDim)
+	populateTransferSession(t, src, "tenant-a", "")
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+
+	// First start: restore the old snapshot, then seed the durable state
+	dir := t.TempDir()
+	e, d, loaded := openDurable(t, dir)
+	if loaded {
+		t.Fatal("Empty dir should not report loaded state")
+	}
+	if err := e.Restore(&buf); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if err := d.Compact(9); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	_ = d.Close()
+
+	e2, d2, loaded := openDurable(t, dir)
+	if !loaded || d2.LSN() != 9 {
+		t.Fatalf("Reload: loaded=%v lsn=%d, want true/9", loaded, d2.LSN())
+	}
+	assertSameState(t, src, e2)
+}
+
+// assertSameState compares two engines' snapshots, ignoring record order
+func assertSameState(t *testing.T, want, got *Engine) {
+	t.Helper()
+	sessions := func(e *Engine) map[string]*store.SessionSnapshot {
+		var buf bytes.Buffer
+		if err := e.Snapshot(&buf); err != nil {
+			t.Fatalf("Snapshot failed: %v", err)
+		}
+		snap, err := ReadSnapshot(&buf)
+		if err != nil {
+			t.Fatalf("ReadSnapshot failed: %v", err)
+		}
+		for _, ss := range snap.Sessions {
+			slices.SortFunc(ss.Documents, func(a, b *types.Document) int { return cmp.Compare(a.ID, b.ID) })
+			slices.SortFunc(ss.TextUnits, func(a, b *types.TextUnit) int { return cmp.Compare(a.ID, b.ID) })
+			slices.SortFunc(ss.Entities, func(a, b *types.Entity) int { return cmp.Compare(a.ID, b.ID) })
+			slices.SortFunc(ss.Relationships, func(a, b *types.Relationship) int { return cmp.Compare(a.ID, b.ID) })
+			slices.SortFunc(ss.Communities, func(a, b *types.Community) int { return cmp.Compare(a.ID, b.ID) })
+		}
+		return snap.Sessions
+	}
+	w, g := sessions(want), sessions(got)
+	if len(w) != len(g) {
+		t.Errorf("Reloaded %d sessions, want %d", len(g), len(w))
+	}
+	for id, ws := range w {
+		if !reflect.DeepEqual(ws, g[id]) {
+			t.Errorf("Reloaded session %s differs from the original", id)
+		}
+	}
+}
+
+// populateTransferSession fills sessionID with a small linked graph and
+// returns its two entities
+func populateTransferSession(t *testing.T, e *Engine, sessionID, prefix string) (*types.Entity, *types.Entity) {
+	t.Helper()
+	embedding := randomVector(testVectorDim)
+	doc := mustAddDocument(t, e, sessionID, prefix+"doc-1", prefix+"file.pdf")
+	tu := mustAddTextUnit(t, e, sessionID, prefix+"tu-1", doc.ID, "Bank Indonesia raised rates", embedding, 10)
+	ent1 := mustAddEntity(t, e, sessionID, prefix+"ent-1", prefix+"Bank Indonesia", "organization", "Central bank", embedding)
+	ent2 := mustAddEntity(t, e, sessionID, prefix+"ent-2", prefix+"Perry Warjiyo", "person", "Governor", embedding)
+	e.LinkTextUnitToEntity(sessionID, tu.ID, ent1.ID)
+	rel := mustAddRelationship(t, e, sessionID, prefix+"rel-1", ent2.ID, ent1.ID, "GOVERNOR_OF", "Leads", 1.0)
+	mustAddCommunity(t, e, sessionID, prefix+"comm-1", "Monetary policy", "Summary", "Full", 0, []uint64{ent1.ID, ent2.ID}, []uint64{rel.ID}, embedding)
+	return ent1, ent2
+}
+
+func TestScenario_ExportImportSession(t *testing.T) {
+	src := NewEngine(testVectorDim)
+	ent1, ent2 := populateTransferSession(t, src, "tenant-a", "")
+	mustAddDocument(t, src, "tenant-b", "other-doc", "other.pdf")
+	if err := src.SetSessionMetadata("tenant-a", map[string]string{"tenant": "a"}, false); err != nil {
+		t.Fatalf("SetSessionMetadata failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.ExportSession("tenant-a", &buf); err != nil {
+		t.Fatalf("ExportSession failed: %v", err)
+	}
+
+	dst := NewEngine(testVectorDim)
+	if err := dst.ImportSession("tenant-a-moved", &buf); err != nil {
+		t.Fatalf("ImportSession failed: %v", err)
+	}
+	if dst.SessionCount() != 1 {
+		t.Errorf("Expected only the exported session, got %d sessions", dst.SessionCount())
+	}
+
+	info, err := dst.GetSessionInfo("tenant-a-moved")
+	if err != nil {
+		t.Fatalf("GetSessionInfo failed: %v", err)
+	}
+	if info.DocumentCount != 1 || info.TextUnitCount != 1 || info.EntityCount != 2 || info.RelationshipCount != 1 || info.CommunityCount != 1 {
+		t.Errorf("Unexpected counts after import: %+v", info)
+	}
+	if info.Metadata["tenant"] != "a" {
+		t.Errorf("Session metadata not imported: %v", info.Metadata)
+	}
+	if info.CommunitiesDirty {
+		t.Error("Imported communities should be current")
+	}
+
+	// A new session keeps the exported IDs
+	got, ok := dst.GetEntity("tenant-a-moved", ent1.ID)
+	if !ok || got.Title != ent1.Title || len(got.TextUnitIDs) != 1 {
+		t.Errorf("Entity %d not imported intact: %+v", ent1.ID, got)
+	}
+	rels, err := dst.GetNeighbors("tenant-a-moved", ent2.ID, types.DirectionOutgoing, nil)
+	if err != nil || len(rels) != 1 || rels[0].TargetID != ent1.ID {
+		t.Errorf("Relationship not imported: %v, %v", rels, err)
+	}
+	if _, ok := dst.EntityEmbedding("tenant-a-moved", ent1.ID); !ok {
+		t.Error("Entity embedding not imported")
+	}
+}
+
+func TestEngine_ImportSessionIntoExisting(t *testing.T) {
+	src := NewEngine(testVectorDim)
+	populateTransferSession(t, src, "tenant-a", "")
+	var export bytes.Buffer
+	if err := src.ExportSession("tenant-a", &export); err != nil {
+		t.Fatalf("ExportSession failed: %v", err)
+	}
+
+	dst := NewEngine(testVectorDim)
+	populateTransferSession(t, dst, "target", "local-")
+	if err := dst.ImportSession("target", bytes.NewReader(export.Bytes())); err != nil {
+		t.Fatalf("ImportSession failed: %v", err)
+	}
+
+	info, err := dst.GetSessionInfo("target")
+	if err != nil {
+		t.Fatalf("GetSessionInfo failed: %v", err)
+	}
+	if info.EntityCount != 4 || info.RelationshipCount != 2 || info.CommunityCount != 2 {
+		t.Errorf("Unexpected counts after import: %+v", info)
+	}
+
+	// Imported records are renumbered after the existing ones and their
+	// references follow
+	bank, ok := dst.GetEntityByTitle("target", "Bank Indonesia")
+	if !ok || bank.ID != 3 {
+		t.Fatalf("Expected imported entity with ID 3, got %+v", bank)
+	}
+	rels, err := dst.GetNeighbors("target", bank.ID, types.DirectionIncoming, nil)
+	if err != nil || len(rels) != 1 {
+		t.Fatalf("Expected one incoming relationship, got %v, %v", rels, err)
+	}
+	if governor, ok := dst.GetEntity("target", rels[0].SourceID); !ok || governor.Title != "PERRY WARJIYO" {
+		t.Errorf("Relationship not rewired to the imported entity: %+v", governor)
+	}
+	if len(bank.TextUnitIDs) != 1 {
+		t.Fatalf("Expected one linked text unit, got %v", bank.TextUnitIDs)
+	}
+	if tu, ok := dst.GetTextUnit("target", bank.TextUnitIDs[0]); !ok || tu.ExternalID != "tu-1" {
+		t.Errorf("Text unit link not rewired: %+v", tu)
+	}
+	comms, err := dst.GetEntityCommunities("target", bank.ID)
+	if err != nil || len(comms) != 1 || comms[0].RelationshipIDs[0] != rels[0].ID {
+		t.Errorf("Community not rewired: %v, %v", comms, err)
+	}
+
+	// Importing the same records again collides on external IDs
+	if err := dst.ImportSession("target", bytes.NewReader(export.Bytes())); err == nil {
+		t.Error("Expected conflicting import to fail")
+	}
+	if info, _ := dst.GetSessionInfo("target"); info.EntityCount != 4 {
+		t.Errorf("Rejected import changed the session: %d entities", info.EntityCount)
+	}
+
+	if err := NewEngine(testVectorDim+1).ImportSession("target", bytes.NewReader(export.Bytes())); err == nil {
+		t.Error("Expected vector dimension mismatch error")
+	}
+	if err := src.ExportSession("missing", &bytes.Buffer{}); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestScenario_CloneSessionIsIndependent(t *testing.T) {
+	e := NewEngine(testVectorDim)
+	bank, governor := populateTransferSession(t, e, "baseline", "")
+	if err := e.SetSessionMetadata("baseline", map[string]string{"variant": "a"}, false); err != nil {
+		t.Fatalf("SetSessionMetadata failed: %v", err)
+	}
+	originalEmbedding, _ := e.EntityEmbedding("baseline", bank.ID)
+	if _, err := e.ComputeCommunities("baseline", graph.DefaultLeidenConfig()); err != nil {
+		t.Fatalf("ComputeCommunities failed: %v", err)
+	}
+	baselineComms, err := e.GetEntityCommunities("baseline", governor.ID)
+	if err != nil || len(baselineComms) != 1 {
+		t.Fatalf("Expected one community for entity %d, got %v, %v", governor.ID, baselineComms, err)
+	}
+
+	if err := e.CloneSession("baseline", "experiment"); err != nil {
+		t.Fatalf("CloneSession failed: %v", err)
+	}
+
+	// The clone starts identical, IDs included
+	clonedBank, ok := e.GetEntity("experiment", bank.ID)
+	if !ok || clonedBank.Title != bank.Title {
+		t.Fatalf("Cloned entity %d missing: %+v", bank.ID, clonedBank)
+	}
+	if clonedBank == bank {
+		t.Error("Clone shares entity records with the original")
+	}
+	info, err := e.GetSessionInfo("experiment")
+	if err != nil {
+		t.Fatalf("GetSessionInfo failed: %v", err)
+	}
+	if info.EntityCount != 2 || info.RelationshipCount != 1 || info.CommunityCount != 1 || info.CommunitiesDirty || info.Metadata["variant"] != "a" {
+		t.Errorf("Unexpected clone info: %+v", info)
+	}
+
+	// Mutating the clone leaves the original alone
+	newEmbedding := randomVector(testVectorDim)
+	if !e.UpdateEntityDescription("experiment", bank.ID, "Rewritten", newEmbedding) {
+		t.Fatal("UpdateEntityDescription on clone failed")
+	}
+	if got, _ := e.GetEntity("baseline", bank.ID); got.Description != "Central bank" {
+		t.Errorf("Original description changed to %q", got.Description)
+	}
+	if got, _ := e.EntityEmbedding("baseline", bank.ID); !slices.Equal(got, originalEmbedding) {
+		t.Error("Original embedding changed with the clone's")
+	}
+	third := mustAddEntity(t, e, "experiment", "ent-3", "Jakarta", "location", "Capital", newEmbedding)
+	mustAddRelationship(t, e, "experiment", "rel-2", bank.ID, third.ID, "LOCATED_IN", "HQ", 1.0)
+	if rels, _ := e.GetNeighbors("baseline", bank.ID, types.DirectionBoth, nil); len(rels) != 1 {
+		t.Errorf("Original has %d relationships on entity %d, want 1", len(rels), bank.ID)
+	}
+
+	// Community caches are per session
+	if dirty, _ := e.CommunitiesDirty("baseline"); dirty {
+		t.Error("Original communities marked dirty by the clone's edits")
+	}
+	if dirty, _ := e.CommunitiesDirty("experiment"); !dirty {
+		t.Error("Clone communities should be dirty after adding an edge")
+	}
+	if _, err := e.ComputeCommunities("experiment", graph.DefaultLeidenConfig()); err != nil {
+		t.Fatalf("ComputeCommunities failed: %v", err)
+	}
+	comms, err := e.GetEntityCommunities("baseline", governor.ID)
+	if err != nil || len(comms) != 1 || comms[0].ID != baselineComms[0].ID || len(comms[0].EntityIDs) != 2 {
+		t.Errorf("Original communities changed: %v, %v", comms, err)
+	}
+	if thirdComms, _ := e.GetEntityCommunities("baseline", third.ID); len(thirdComms) != 0 {
+		t.Errorf("Clone's communities leaked into the original: %v", thirdComms)
+	}
+
+	if err := e.CloneSession("baseline", "experiment"); err != ErrSessionExists {
+		t.Errorf("Expected ErrSessionExists, got %v", err)
+	}
+	if err := e.CloneSession("missing", "other"); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestScenario_StageRollback(t *testing.T) {
+	e := NewEngine(testVectorDim)
+	bank, governor := populateTransferSession(t, e, "baseline", "")
+	originalEmbedding, _ := e.EntityEmbedding("baseline", bank.ID)
+
+	stage := e.BeginStage()
+	stage.Add("baseline")
+	stage.Add("scratch")
+
+	if !e.UpdateEntityDescription("baseline", bank.ID, "Rewritten", randomVector(testVectorDim)) {
+		t.Fatal("UpdateEntityDescription failed")
+	}
+	third := mustAddEntity(t, e, "baseline", "ent-3", "Jakarta", "location", "Capital", randomVector(testVectorDim))
+	mustAddRelationship(t, e, "baseline", "rel-2", bank.ID, third.ID, "LOCATED_IN", "HQ", 1.0)
+	mustAddEntity(t, e, "scratch", "ent-1", "Scratch", "test", "Temporary", randomVector(testVectorDim))
+
+	if err := stage.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	if got, ok := e.GetEntity("baseline", bank.ID); !ok || got.Description != "Central bank" {
+		t.Errorf("Description after rollback = %+v, want the original", got)
+	}
+	if got, _ := e.EntityEmbedding("baseline", bank.ID); !slices.Equal(got, originalEmbedding) {
+		t.Error("Embedding not rolled back")
+	}
+	if _, ok := e.GetEntityByExternalID("baseline", "ent-3"); ok {
+		t.Error("Entity added after Add survived the rollback")
+	}
+	info, err := e.GetSessionInfo("baseline")
+	if err != nil || info.EntityCount != 2 || info.RelationshipCount != 1 {
+		t.Errorf("Session info after rollback = %+v, %v", info, err)
+	}
+	if _, err := e.GetSessionInfo("scratch"); err != ErrSessionNotFound {
+		t.Errorf("Session created after Add should be gone, got %v", err)
+	}
+
+	// IDs restart where they were, and a deleted session comes back
+	if again := mustAddEntity(t, e, "baseline", "ent-3", "Jakarta", "location", "Capital", randomVector(testVectorDim)); again.ID != third.ID {
+		t.Errorf("Entity ID after rollback = %d, want %d", again.ID, third.ID)
+	}
+	stage = e.BeginStage()
+	stage.Add("baseline")
+	e.DeleteSession("baseline")
+	if err := stage.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if _, ok := e.GetEntity("baseline", governor.ID); !ok {
+		t.Error("Deleted session not restored by rollback")
+	}
+}
+
+func TestScenario_MergeSessionDedupTitle(t *testing.T) {
+	e := NewEngine(testVectorDim)
+	embedding := randomVector(testVectorDim)
+
+	// Two analysts extracted the same central bank from different reports
+	mustAddDocument(t, e, "desk-a", "a-doc", "a.pdf")
+	bank := mustAddEntity(t, e, "desk-a", "a-bi", "Bank Indonesia", "organization", "Central bank", embedding)
+	governor := mustAddEntity(t, e, "desk-a", "a-pw", "Perry Warjiyo", "person", "Governor", embedding)
+	mustAddRelationship(t, e, "desk-a", "a-rel", governor.ID, bank.ID, "GOVERNOR_OF", "Leads", 1.0)
+
+	doc := mustAddDocument(t, e, "desk-b", "b-doc", "b.pdf")
+	tu := mustAddTextUnit(t, e, "desk-b", "b-tu", doc.ID, "The finance ministry met Bank Indonesia", embedding, 8)
+	otherBank := mustAddEntity(t, e, "desk-b", "b-bi", "Bank Indonesia", "organization", "Monetary authority", embedding)
+	minister := mustAddEntity(t, e, "desk-b", "b-sm", "Sri Mulyani", "person", "Finance minister", embedding)
+	e.LinkTextUnitToEntity("desk-b", tu.ID, otherBank.ID)
+	mustAddRelationship(t, e, "desk-b", "b-rel", minister.ID, otherBank.ID, "COORDINATES_WITH", "Policy mix", 1.0)
+
+	if err := e.MergeSession("desk-a", "desk-b", types.ConflictDedupTitle); err != nil {
+		t.Fatalf("MergeSession failed: %v", err)
+	}
+
+	info, err := e.GetSessionInfo("desk-a")
+	if err != nil {
+		t.Fatalf("GetSessionInfo failed: %v", err)
+	}
+	if info.DocumentCount != 2 || info.TextUnitCount != 1 || info.EntityCount != 3 || info.RelationshipCount != 2 {
+		t.Errorf("Unexpected counts after merge: %+v", info)
+	}
+
+	// One BANK INDONESIA, the destination's, carrying both sides' edges and links
+	merged, ok := e.GetEntityByTitle("desk-a", "Bank Indonesia")
+	if !ok || merged.ID != bank.ID || merged.Description != "Central bank" {
+		t.Fatalf("Expected entity %d to survive the merge, got %+v", bank.ID, merged)
+	}
+	rels, err := e.GetNeighbors("desk-a", bank.ID, types.DirectionIncoming, nil)
+	if err != nil || len(rels) != 2 {
+		t.Fatalf("Expected 2 relationships into entity %d, got %v, %v", bank.ID, rels, err)
+	}
+	mergedMinister, ok := e.GetEntityByTitle("desk-a", "Sri Mulyani")
+	if !ok {
+		t.Fatal("Merged entity SRI MULYANI missing")
+	}
+	for _, rel := range rels {
+		if rel.Type == "COORDINATES_WITH" && rel.SourceID != mergedMinister.ID {
+			t.Errorf("COORDINATES_WITH starts at %d, want %d", rel.SourceID, mergedMinister.ID)
+		}
+	}
+	if len(merged.TextUnitIDs) != 1 {
+		t.Fatalf("Expected the merged text unit linked to entity %d, got %v", bank.ID, merged.TextUnitIDs)
+	}
+	mergedTU, ok := e.GetTextUnit("desk-a", merged.TextUnitIDs[0])
+	if !ok || !slices.Contains(mergedTU.EntityIDs, bank.ID) {
+		t.Errorf("Merged text unit not linked back to entity %d: %+v", bank.ID, mergedTU)
+	}
+
+	// The source is untouched
+	if srcInfo, _ := e.GetSessionInfo("desk-b"); srcInfo.EntityCount != 2 || srcInfo.RelationshipCount != 1 {
+		t.Errorf("Source session changed by the merge: %+v", srcInfo)
+	}
+}
+
+func TestEngine_MergeSessionConflicts(t *testing.T) {
+	e := NewEngine(testVectorDim)
+	bank, _ := populateTransferSession(t, e, "dst", "")
+	populateTransferSession(t, e, "src", "x-")
+	// Clashes with the destination's BANK INDONESIA on external ID only
+	mustAddEntity(t, e, "src", "ent-1", "Jakarta", "location", "Capital", randomVector(testVectorDim))
+
+	if err := e.MergeSession("dst", "src", types.ConflictReject); err == nil {
+		t.Fatal("Expected merge with a taken external ID to be rejected")
+	}
+	info, _ := e.GetSessionInfo("dst")
+	if info.EntityCount != 2 || info.DocumentCount != 1 {
+		t.Fatalf("Rejected merge changed the destination: %+v", info)
+	}
+
+	if err := e.MergeSession("dst", "src", types.ConflictSkip); err != nil {
+		t.Fatalf("MergeSession failed: %v", err)
+	}
+	info, _ = e.GetSessionInfo("dst")
+	if info.DocumentCount != 2 || info.TextUnitCount != 2 || info.EntityCount != 4 || info.RelationshipCount != 2 || info.CommunityCount != 2 {
+		t.Errorf("Unexpected counts after merge: %+v", info)
+	}
+	if got, ok := e.GetEntity("dst", bank.ID); !ok || got.ExternalID != "ent-1" || got.Title != bank.Title {
+		t.Errorf("Destination entity %d replaced: %+v", bank.ID, got)
+	}
+	if _, ok := e.GetEntityByTitle("dst", "Jakarta"); ok {
+		t.Error("Entity with a taken external ID should have been skipped")
+	}
+
+	if err := e.MergeSession("dst", "dst", types.ConflictSkip); err == nil {
+		t.Error("Expected merging a session into itself to fail")
+	}
+	if err := e.MergeSession("dst", "missing", types.ConflictSkip); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
 // =============================================================================
 // Real-World Scenario: Concurrent Access (Multi-user)
 // =============================================================================
@@ -522,6 +1336,71 @@ func TestEngine_RebuildVectorIndices(t *testing.T) {
 	if result.QueryID == 0 {
 		t.Error("Should get valid query result after rebuild")
 	}
+	if len(result.Entities) == 0 || len(result.TextUnits) == 0 {
+		t.Error("Rebuild should keep session data searchable")
+	}
+	if info, _ := e.InfoForSession(testSessionID); info.EntityCount != 1 || info.CommunityCount != 1 {
+		t.Errorf("Rebuild should not drop data, got %+v", info)
+	}
+}
+
+func TestEngine_RebuildVectorIndicesAsync(t *testing.T) {
+	e := NewEngine(testVectorDim)
+
+	vecs := make([][]float32, 1000)
+	for i := range vecs {
+		vecs[i] = randomVector(testVectorDim)
+		mustAddEntity(t, e, testSessionID, fmt.Sprintf("ent-%d", i), fmt.Sprintf("Entity %d", i), "test", "Desc", vecs[i])
+	}
+
+	status, err := e.IndexStatus(testSessionID)
+	if err != nil {
+		t.Fatalf("IndexStatus failed: %v", err)
+	}
+	if status.State != types.IndexReady || status.VectorCount != len(vecs) {
+		t.Errorf("Status before rebuild = %+v", status)
+	}
+
+	if err := e.RebuildVectorIndicesAsync(testSessionID); err != nil {
+		t.Fatalf("RebuildVectorIndicesAsync failed: %v", err)
+	}
+	status, _ = e.IndexStatus(testSessionID)
+	if status.State != types.IndexBuilding || status.StartedAt == 0 {
+		t.Fatalf("Status after start = %+v, want building", status)
+	}
+
+	// Queries keep working while the new graphs are built
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = vecs[0]
+	deadline := time.Now().Add(30 * time.Second)
+	for status.State == types.IndexBuilding {
+		if time.Now().After(deadline) {
+			t.Fatal("Rebuild did not finish")
+		}
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query during rebuild failed: %v", err)
+		}
+		if len(result.Entities) == 0 {
+			t.Fatal("Query during rebuild found no entities")
+		}
+		if status.Percent < 0 || status.Percent > 100 {
+			t.Errorf("Percent = %v", status.Percent)
+		}
+		status, _ = e.IndexStatus(testSessionID)
+	}
+
+	if status.Percent != 100 || status.VectorCount != len(vecs) || status.FinishedAt == 0 || status.Error != "" {
+		t.Errorf("Status after rebuild = %+v", status)
+	}
+	result, err := e.Query(testSessionID, spec)
+	if err != nil || len(result.Entities) == 0 {
+		t.Errorf("Query after rebuild = %v, %v", result, err)
+	}
+
+	if err := e.RebuildVectorIndicesAsync("missing"); err == nil {
+		t.Error("Expected error for a missing session")
+	}
 }
 
 func TestEngine_Clear(t *testing.T) {
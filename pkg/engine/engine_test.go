@@ -2,11 +2,23 @@
 package engine
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/gibram-io/gibram/pkg/graph"
+	"github.com/gibram-io/gibram/pkg/simd"
 	"github.com/gibram-io/gibram/pkg/types"
+	"github.com/gibram-io/gibram/pkg/vector"
 )
 
 // =============================================================================
@@ -295,150 +307,1815 @@ func TestEngine_AddRelationship(t *testing.T) {
 	}
 }
 
-func TestEngine_GetRelationship(t *testing.T) {
+func TestEngine_EmbeddingNormBounds(t *testing.T) {
 	e := createTestEngine()
+	e.SetEmbeddingNormBounds(0.5, 2.0)
 
-	embedding := randomVector(testVectorDim)
-	ent1 := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc 1", embedding)
-	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc 2", embedding)
+	unit := make([]float32, testVectorDim)
+	unit[0] = 1
+	zero := make([]float32, testVectorDim)
+	huge := make([]float32, testVectorDim)
+	huge[0] = 10
 
-	rel := mustAddRelationship(t, e, testSessionID, "ext-rel-1", ent1.ID, ent2.ID, "RELATED_TO", "Desc", 1.0)
+	if _, err := e.AddEntity(testSessionID, "ent-ok", "Unit", "test", "Desc", unit); err != nil {
+		t.Fatalf("Unit vector should be accepted: %v", err)
+	}
+	if _, err := e.AddEntity(testSessionID, "ent-none", "No Embedding", "test", "Desc", nil); err != nil {
+		t.Fatalf("Missing embedding should be accepted: %v", err)
+	}
 
-	retrieved, ok := e.GetRelationship(testSessionID, rel.ID)
+	for name, vec := range map[string][]float32{"zero": zero, "huge": huge} {
+		_, err := e.AddEntity(testSessionID, "ent-"+name, name, "test", "Desc", vec)
+		if !errors.Is(err, types.ErrSuspiciousEmbedding) {
+			t.Errorf("%s vector: expected ErrSuspiciousEmbedding, got %v", name, err)
+		}
+		doc := mustAddDocument(t, e, testSessionID, "doc-"+name, name+".txt")
+		if _, err := e.AddTextUnit(testSessionID, "tu-"+name, doc.ID, "Content", vec, 1); !errors.Is(err, types.ErrSuspiciousEmbedding) {
+			t.Errorf("%s text unit: expected ErrSuspiciousEmbedding, got %v", name, err)
+		}
+	}
+
+	// Disabling bounds accepts anything again
+	e.SetEmbeddingNormBounds(0, 0)
+	if _, err := e.AddEntity(testSessionID, "ent-zero", "zero", "test", "Desc", zero); err != nil {
+		t.Errorf("Zero vector should be accepted without bounds: %v", err)
+	}
+}
+
+func TestEngine_NormalizeOnIngest(t *testing.T) {
+	e := createTestEngine()
+	e.SetNormalizeOnIngest(true)
+	e.SetDistanceMetric(types.DistanceDotProduct)
+
+	raw := make([]float32, testVectorDim)
+	raw[0], raw[1] = 3, 4
+	ent := mustAddEntity(t, e, testSessionID, "ent-raw", "Raw", "test", "Desc", raw)
+	if raw[0] != 3 || raw[1] != 4 {
+		t.Errorf("Caller's embedding was modified: %v", raw[:2])
+	}
+
+	stored, ok := e.EntityEmbedding(testSessionID, ent.ID)
 	if !ok {
-		t.Error("GetRelationship should return true")
+		t.Fatal("EntityEmbedding not found")
 	}
-	if retrieved.Type != "RELATED_TO" {
-		t.Errorf("Expected type 'RELATED_TO', got '%s'", retrieved.Type)
+	if math.Abs(float64(stored[0])-0.6) > 1e-6 || math.Abs(float64(stored[1])-0.8) > 1e-6 {
+		t.Errorf("Stored embedding = %v, want [0.6 0.8 ...]", stored[:2])
+	}
+
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "doc.txt")
+	tu := mustAddTextUnit(t, e, testSessionID, "tu-raw", doc.ID, "Content", raw, 1)
+	if stored, _ := e.TextUnitEmbedding(testSessionID, tu.ID); math.Abs(float64(simd.L2Norm(stored))-1) > 1e-6 {
+		t.Errorf("Stored text unit norm = %v, want 1", simd.L2Norm(stored))
+	}
+
+	// With unit vectors stored, the dot product of a unit query is its cosine
+	query := make([]float32, testVectorDim)
+	query[0] = 1
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = query
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 || math.Abs(float64(result.Entities[0].Similarity)-0.6) > 1e-5 {
+		t.Errorf("Expected one entity scored 0.6, got %+v", result.Entities)
+	}
+
+	// Unit and zero vectors are stored as sent
+	unit := make([]float32, testVectorDim)
+	unit[2] = 1
+	if got := e.normalizeEmbedding(unit); &got[0] != &unit[0] {
+		t.Error("Unit vector should be returned unchanged")
+	}
+	zero := make([]float32, testVectorDim)
+	if got := e.normalizeEmbedding(zero); &got[0] != &zero[0] {
+		t.Error("Zero vector should be returned unchanged")
 	}
 }
 
-func TestEngine_GetRelationshipByEntities(t *testing.T) {
+func TestEngine_DedupThreshold(t *testing.T) {
+	// vec returns a unit vector at cosine cos to the first axis
+	vec := func(cos float64) []float32 {
+		v := make([]float32, testVectorDim)
+		v[0], v[1] = float32(cos), float32(math.Sqrt(1-cos*cos))
+		return v
+	}
+
 	e := createTestEngine()
+	bank := mustAddEntity(t, e, testSessionID, "ent-bca", "Bank Central Asia", "bank", "Largest private bank", vec(1))
 
-	embedding := randomVector(testVectorDim)
-	ent1 := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc 1", embedding)
-	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc 2", embedding)
+	// Off by default: a near-duplicate is a new entity
+	other := mustAddEntity(t, e, testSessionID, "ent-bca-2", "Bank BCA", "bank", "", vec(0.99))
+	if other.ID == bank.ID {
+		t.Fatal("Near-duplicate merged with dedup disabled")
+	}
+	e.DeleteEntity(testSessionID, other.ID)
 
-	mustAddRelationship(t, e, testSessionID, "ext-rel-1", ent1.ID, ent2.ID, "RELATED_TO", "Desc", 1.0)
+	e.SetDedupThreshold(0.95)
+	merged, err := e.AddEntityWithMetadata(testSessionID, "ent-bca-3", "Bank BCA", "bank", "Also known as BCA", vec(0.99), nil, map[string]string{"ticker": "BBCA"})
+	if err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+	if merged.ID != bank.ID {
+		t.Fatalf("Near-duplicate got ID %d, want existing %d", merged.ID, bank.ID)
+	}
+	got, _ := e.GetEntity(testSessionID, bank.ID)
+	if got.Title != "BANK CENTRAL ASIA" || got.Description != "Largest private bank\nAlso known as BCA" || got.Metadata["ticker"] != "BBCA" {
+		t.Errorf("Merged entity = %+v", got)
+	}
+	if _, ok := e.GetEntityByExternalID(testSessionID, "ent-bca-3"); ok {
+		t.Error("Merged entity's external ID should not be registered")
+	}
 
-	retrieved, ok := e.GetRelationshipByEntities(testSessionID, ent1.ID, ent2.ID)
-	if !ok {
-		t.Error("GetRelationshipByEntities should return true")
+	// Below the threshold, or of another type, entities are independent
+	below := mustAddEntity(t, e, testSessionID, "ent-bri", "Bank Rakyat Indonesia", "bank", "", vec(0.9))
+	if below.ID == bank.ID {
+		t.Error("Entity below the threshold was merged")
 	}
-	if retrieved.SourceID != ent1.ID || retrieved.TargetID != ent2.ID {
-		t.Error("Retrieved wrong relationship")
+	person := mustAddEntity(t, e, testSessionID, "ent-person", "BCA Founder", "person", "", vec(1))
+	if person.ID == bank.ID {
+		t.Error("Entity of another type was merged")
+	}
+	if info, _ := e.InfoForSession(testSessionID); info.EntityCount != 3 {
+		t.Errorf("EntityCount = %d, want 3", info.EntityCount)
 	}
 }
 
-// =============================================================================
-// Community Operations Tests
-// =============================================================================
+func TestEngine_EmbeddingDimension(t *testing.T) {
+	e := createTestEngine()
+	short := make([]float32, testVectorDim/2)
+	short[0] = 1
+
+	// checkMismatch asserts err reports the expected and got dimensions
+	checkMismatch := func(what string, err error) {
+		t.Helper()
+		var dimErr *DimensionMismatchError
+		if !errors.As(err, &dimErr) {
+			t.Fatalf("%s: expected DimensionMismatchError, got %v", what, err)
+		}
+		if dimErr.Expected != testVectorDim || dimErr.Got != len(short) {
+			t.Errorf("%s: got expected=%d got=%d, want %d and %d", what, dimErr.Expected, dimErr.Got, testVectorDim, len(short))
+		}
+		if !errors.Is(err, ErrDimensionMismatch) {
+			t.Errorf("%s: error does not match ErrDimensionMismatch", what)
+		}
+	}
 
-func TestEngine_AddCommunity(t *testing.T) {
+	_, err := e.AddEntity(testSessionID, "ent-short", "Short", "test", "Desc", short)
+	checkMismatch("AddEntity", err)
+	_, err = e.AddEntityWithTitleEmbedding(testSessionID, "ent-short-title", "Short Title", "test", "Desc", nil, short)
+	checkMismatch("AddEntity title embedding", err)
+
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "doc.txt")
+	_, err = e.AddTextUnit(testSessionID, "tu-short", doc.ID, "Content", short, 1)
+	checkMismatch("AddTextUnit", err)
+
+	// A bad item fails the whole batch, so nothing is stored
+	good := randomVector(testVectorDim)
+	_, err = e.MSetEntities(testSessionID, []types.BulkEntityInput{
+		{ExternalID: "ent-good", Title: "Good", Type: "test", Embedding: good},
+		{ExternalID: "ent-bad", Title: "Bad", Type: "test", Embedding: short},
+	})
+	checkMismatch("MSetEntities", err)
+	_, err = e.MSetTextUnits(testSessionID, []types.BulkTextUnitInput{
+		{ExternalID: "tu-good", DocumentID: doc.ID, Content: "Good", Embedding: good},
+		{ExternalID: "tu-bad", DocumentID: doc.ID, Content: "Bad", Embedding: short},
+	})
+	checkMismatch("MSetTextUnits", err)
+	if count, _ := e.Count(testSessionID, types.ItemTypeEntity); count != 0 {
+		t.Errorf("Expected no entities stored, got %d", count)
+	}
+	if count, _ := e.Count(testSessionID, types.ItemTypeTextUnit); count != 0 {
+		t.Errorf("Expected no text units stored, got %d", count)
+	}
+
+	// Objects without an embedding are still accepted, unindexed
+	if _, err := e.AddEntity(testSessionID, "ent-location", "Jakarta", "location", "Capital", nil); err != nil {
+		t.Errorf("Entity without embedding should be accepted: %v", err)
+	}
+}
+
+// fakeEmbedder returns a fixed vector and records the texts it was asked to embed
+type fakeEmbedder struct {
+	mu    sync.Mutex
+	vec   []float32
+	err   error
+	texts []string
+}
+
+func (f *fakeEmbedder) Embed(text string) ([]float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.texts = append(f.texts, text)
+	return f.vec, f.err
+}
+
+func TestEngine_EmbeddingProvider(t *testing.T) {
 	e := createTestEngine()
 
-	embedding := randomVector(testVectorDim)
-	comm, err := e.AddCommunity(testSessionID, "ext-comm-1", "Test Community", "Summary", "Full content", 0, []uint64{1, 2, 3}, []uint64{1, 2}, embedding)
+	vec := randomVector(testVectorDim)
+	embedder := &fakeEmbedder{vec: vec}
+	e.SetEmbeddingProvider(embedder)
+
+	ent := mustAddEntity(t, e, testSessionID, "ent-1", "Acme", "organization", "A bank", nil)
+	given := make([]float32, testVectorDim)
+	given[0] = 1
+	mustAddEntity(t, e, testSessionID, "ent-2", "Given", "organization", "Has vector", given)
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "doc.txt")
+	mustAddTextUnit(t, e, testSessionID, "tu-1", doc.ID, "Some content", nil, 2)
+
+	want := []string{"Acme: A bank", "Some content"}
+	if fmt.Sprint(embedder.texts) != fmt.Sprint(want) {
+		t.Errorf("Embedded texts = %q, want %q", embedder.texts, want)
+	}
+
+	// The generated embedding is indexed
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = vec
+	spec.TopK = 1
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	result, err := e.Query(testSessionID, spec)
 	if err != nil {
-		t.Fatalf("AddCommunity failed: %v", err)
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ID != ent.ID {
+		t.Errorf("Expected auto-embedded entity as top hit, got %v", result.Entities)
 	}
 
-	if comm.ID == 0 {
-		t.Error("Community ID should not be 0")
+	// Provider failures and wrong dimensions are rejected
+	embedder.err = errors.New("service down")
+	if _, err := e.AddEntity(testSessionID, "ent-3", "Fail", "test", "Desc", nil); err == nil {
+		t.Error("Expected provider error")
 	}
-	if comm.Title != "Test Community" {
-		t.Errorf("Expected title 'Test Community', got '%s'", comm.Title)
+	embedder.err = nil
+	embedder.vec = []float32{1, 2}
+	if _, err := e.AddEntity(testSessionID, "ent-4", "Short", "test", "Desc", nil); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch, got %v", err)
+	}
+
+	// nil restores the no-op default
+	e.SetEmbeddingProvider(nil)
+	if _, err := e.AddEntity(testSessionID, "ent-5", "Plain", "test", "Desc", nil); err != nil {
+		t.Errorf("No-op provider should accept missing embedding: %v", err)
 	}
 }
 
-func TestEngine_GetCommunity(t *testing.T) {
+func TestEngine_Query_TitleWeight(t *testing.T) {
+	e := createTestEngine()
+
+	e0 := make([]float32, testVectorDim)
+	e0[0] = 1
+	e1 := make([]float32, testVectorDim)
+	e1[1] = 1
+
+	// Name matches the query for A, the description does for B
+	a, err := e.AddEntityWithTitleEmbedding(testSessionID, "ent-a", "A", "test", "Desc A", e1, e0)
+	if err != nil {
+		t.Fatalf("AddEntityWithTitleEmbedding failed: %v", err)
+	}
+	b, err := e.AddEntityWithTitleEmbedding(testSessionID, "ent-b", "B", "test", "Desc B", e0, e1)
+	if err != nil {
+		t.Fatalf("AddEntityWithTitleEmbedding failed: %v", err)
+	}
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = e0
+	spec.TopK = 1
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+
+	top := func() types.EntityResult {
+		t.Helper()
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(result.Entities) != 1 {
+			t.Fatalf("Expected 1 entity, got %d", len(result.Entities))
+		}
+		return result.Entities[0]
+	}
+
+	if got := top(); got.Entity.ID != b.ID {
+		t.Errorf("Description-only scoring should pick B, got %s", got.Entity.Title)
+	}
+
+	spec.TitleWeight = 1
+	if got := top(); got.Entity.ID != a.ID {
+		t.Errorf("Title-only scoring should pick A, got %s", got.Entity.Title)
+	}
+
+	spec.DescriptionWeight = 3
+	if got := top(); got.Entity.ID != b.ID || math.Abs(float64(got.Similarity)-0.75) > 1e-5 {
+		t.Errorf("Expected B with blended similarity 0.75, got %s %v", got.Entity.Title, got.Similarity)
+	}
+}
+
+func TestEngine_EntityMetadata(t *testing.T) {
+	e := createTestEngine()
+
+	meta := map[string]string{"ticker": "BBRI", "founded": "1895"}
+	ent, err := e.AddEntityWithMetadata(testSessionID, "ent-bri", "BRI", "organization", "State bank", randomVector(testVectorDim), nil, meta)
+	if err != nil {
+		t.Fatalf("AddEntityWithMetadata failed: %v", err)
+	}
+	meta["ticker"] = "changed" // stored metadata is a copy
+
+	got, _ := e.GetEntity(testSessionID, ent.ID)
+	if !reflect.DeepEqual(got.Metadata, map[string]string{"ticker": "BBRI", "founded": "1895"}) {
+		t.Errorf("Unexpected metadata %v", got.Metadata)
+	}
+
+	if !e.UpdateEntityMetadata(testSessionID, ent.ID, map[string]string{"sector": "banking", "founded": ""}) {
+		t.Fatal("UpdateEntityMetadata failed")
+	}
+	got, _ = e.GetEntity(testSessionID, ent.ID)
+	if !reflect.DeepEqual(got.Metadata, map[string]string{"ticker": "BBRI", "sector": "banking"}) {
+		t.Errorf("Unexpected metadata after update %v", got.Metadata)
+	}
+
+	if e.UpdateEntityMetadata(testSessionID, 99999, map[string]string{"a": "b"}) {
+		t.Error("Expected false for unknown entity")
+	}
+}
+
+func TestEngine_Query_MetadataFilters(t *testing.T) {
 	e := createTestEngine()
 
 	embedding := randomVector(testVectorDim)
-	comm, _ := e.AddCommunity(testSessionID, "ext-comm-1", "Test Community", "Summary", "Full content", 0, []uint64{1, 2, 3}, []uint64{1, 2}, embedding)
+	bri, err := e.AddEntityWithMetadata(testSessionID, "ent-bri", "BRI", "organization", "Bank", embedding, nil, map[string]string{"ticker": "BBRI"})
+	if err != nil {
+		t.Fatalf("AddEntityWithMetadata failed: %v", err)
+	}
+	if _, err := e.AddEntityWithMetadata(testSessionID, "ent-bca", "BCA", "organization", "Bank", embedding, nil, map[string]string{"ticker": "BBCA"}); err != nil {
+		t.Fatalf("AddEntityWithMetadata failed: %v", err)
+	}
+	mustAddEntity(t, e, testSessionID, "ent-ojk", "OJK", "regulator", "Regulator", embedding)
 
-	retrieved, ok := e.GetCommunity(testSessionID, comm.ID)
-	if !ok {
-		t.Error("GetCommunity should return true")
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = embedding
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
 	}
-	if retrieved.Summary != "Summary" {
-		t.Errorf("Expected summary 'Summary', got '%s'", retrieved.Summary)
+	if len(result.Entities) != 3 {
+		t.Fatalf("Expected 3 entities without filters, got %d", len(result.Entities))
+	}
+
+	spec.MetadataFilters = map[string]string{"ticker": "BBRI"}
+	result, err = e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ID != bri.ID {
+		t.Errorf("Expected only BRI, got %d entities", len(result.Entities))
 	}
 }
 
-// =============================================================================
-// Query Pipeline Tests
-// =============================================================================
+func TestEngine_Query_MetadataFiltersTraversal(t *testing.T) {
+	e := createTestEngine()
 
-func TestEngine_Query_Basic(t *testing.T) {
+	axis := func(i int) []float32 {
+		v := make([]float32, testVectorDim)
+		v[i] = 1
+		return v
+	}
+
+	// seed -> three unlisted banks -> a listed bank two hops out
+	seed := mustAddEntity(t, e, testSessionID, "ent-seed", "Seed", "organization", "desc", axis(0))
+	listed, err := e.AddEntityWithMetadata(testSessionID, "ent-bri", "BRI", "organization", "Bank", axis(1), nil, map[string]string{"ticker": "BBRI"})
+	if err != nil {
+		t.Fatalf("AddEntityWithMetadata failed: %v", err)
+	}
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "doc.txt")
+	var unlisted []*types.Entity
+	for i := 0; i < 3; i++ {
+		bank := mustAddEntity(t, e, testSessionID, fmt.Sprintf("ent-b%d", i), fmt.Sprintf("Bank %d", i), "organization", "desc", axis(2+i))
+		mustAddRelationship(t, e, testSessionID, fmt.Sprintf("rel-b%d", i), seed.ID, bank.ID, "OWNS", "desc", 1)
+		unlisted = append(unlisted, bank)
+	}
+	mustAddRelationship(t, e, testSessionID, "rel-bri", unlisted[2].ID, listed.ID, "OWNS", "desc", 1)
+	unlistedTU := mustAddTextUnit(t, e, testSessionID, "tu-unlisted", doc.ID, "About an unlisted bank", axis(10), 3)
+	listedTU := mustAddTextUnit(t, e, testSessionID, "tu-listed", doc.ID, "About BRI", axis(11), 3)
+	e.LinkTextUnitToEntity(testSessionID, unlistedTU.ID, unlisted[0].ID)
+	e.LinkTextUnitToEntity(testSessionID, listedTU.ID, listed.ID)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = axis(0)
+	spec.TopK = 1
+	spec.KHops = 2
+	spec.MaxEntities = 2
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	spec.MetadataFilters = map[string]string{"ticker": "BBRI"}
+
+	// Entities failing the filter do not use up MaxEntities
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ID != listed.ID {
+		t.Fatalf("Expected only BRI, got %d entities", len(result.Entities))
+	}
+	if len(result.TextUnits) != 1 || result.TextUnits[0].TextUnit.ID != listedTU.ID {
+		t.Errorf("Expected only BRI's text unit, got %d text units", len(result.TextUnits))
+	}
+}
+
+func TestEngine_Query_EntityTypesTraversal(t *testing.T) {
 	e := createTestEngine()
 
-	// Add test data
-	embedding1 := randomVector(testVectorDim)
-	embedding2 := randomVector(testVectorDim)
+	axis := func(i int) []float32 {
+		v := make([]float32, testVectorDim)
+		v[i] = 1
+		return v
+	}
 
-	doc := mustAddDocument(t, e, testSessionID, "ext-doc-1", "test.txt")
-	mustAddTextUnit(t, e, testSessionID, "ext-tu-1", doc.ID, "Test content 1", embedding1, 10)
-	mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Description 1", embedding1)
-	mustAddCommunity(t, e, testSessionID, "ext-comm-1", "Community 1", "Summary", "Full", 0, []uint64{1}, []uint64{}, embedding2)
+	// seed -> three people -> bank; only the bank is an organization
+	seed := mustAddEntity(t, e, testSessionID, "ent-seed", "Seed", "person", "desc", axis(0))
+	bank := mustAddEntity(t, e, testSessionID, "ent-bank", "Bank", "organization", "desc", axis(1))
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "doc.txt")
+	var people []*types.Entity
+	for i := 0; i < 3; i++ {
+		person := mustAddEntity(t, e, testSessionID, fmt.Sprintf("ent-p%d", i), fmt.Sprintf("Person %d", i), "person", "desc", axis(2+i))
+		mustAddRelationship(t, e, testSessionID, fmt.Sprintf("rel-p%d", i), seed.ID, person.ID, "KNOWS", "desc", 1)
+		people = append(people, person)
+	}
+	mustAddRelationship(t, e, testSessionID, "rel-bank", people[2].ID, bank.ID, "WORKS_AT", "desc", 1)
+	personTU := mustAddTextUnit(t, e, testSessionID, "tu-person", doc.ID, "About a person", axis(10), 3)
+	bankTU := mustAddTextUnit(t, e, testSessionID, "tu-bank", doc.ID, "About the bank", axis(11), 3)
+	e.LinkTextUnitToEntity(testSessionID, personTU.ID, people[0].ID)
+	e.LinkTextUnitToEntity(testSessionID, bankTU.ID, bank.ID)
 
-	// Query
 	spec := types.DefaultQuerySpec()
-	spec.QueryVector = embedding1
+	spec.QueryVector = axis(0)
+	spec.TopK = 1
+	spec.KHops = 2
+	spec.MaxEntities = 2
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	spec.EntityTypes = []string{"organization"}
+
+	// Filtered people do not use up MaxEntities, so the bank two hops out is found
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ID != bank.ID {
+		t.Fatalf("Expected only the bank, got %d entities", len(result.Entities))
+	}
+
+	// Text units reached only through filtered entities are dropped
+	if len(result.TextUnits) != 1 || result.TextUnits[0].TextUnit.ID != bankTU.ID {
+		t.Errorf("Expected only the bank's text unit, got %d text units", len(result.TextUnits))
+	}
+}
+
+func TestEngine_Query_MinSimilarity(t *testing.T) {
+	e := createTestEngine()
+
+	// Two tight clusters around orthogonal axes
+	cluster := func(axis int) []float32 {
+		v := randomVector(testVectorDim)
+		for i := range v {
+			v[i] *= 0.05
+		}
+		v[axis] = 1
+		return v
+	}
+
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "doc.txt")
+	for _, c := range []struct {
+		name string
+		axis int
+	}{{"near", 0}, {"far", 1}} {
+		for i := 0; i < 3; i++ {
+			mustAddEntity(t, e, testSessionID, fmt.Sprintf("ent-%s-%d", c.name, i), fmt.Sprintf("%s %d", c.name, i), c.name, "desc", cluster(c.axis))
+			mustAddTextUnit(t, e, testSessionID, fmt.Sprintf("tu-%s-%d", c.name, i), doc.ID, c.name, cluster(c.axis), 1)
+		}
+		if _, err := e.AddCommunity(testSessionID, "comm-"+c.name, c.name, "summary", "content", 0, nil, nil, cluster(c.axis)); err != nil {
+			t.Fatalf("AddCommunity failed: %v", err)
+		}
+	}
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = cluster(0)
+	spec.TopK = 10
+	spec.KHops = 0
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 6 || len(result.TextUnits) != 6 || len(result.Communities) != 2 {
+		t.Fatalf("Expected both clusters without threshold, got %d/%d/%d",
+			len(result.Entities), len(result.TextUnits), len(result.Communities))
+	}
+
+	spec.MinSimilarity = 0.8
+	result, err = e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 3 || len(result.TextUnits) != 3 || len(result.Communities) != 1 {
+		t.Fatalf("Expected only the near cluster, got %d/%d/%d",
+			len(result.Entities), len(result.TextUnits), len(result.Communities))
+	}
+	for _, er := range result.Entities {
+		if er.Entity.Type != "near" || er.Similarity < spec.MinSimilarity {
+			t.Errorf("Unexpected entity %s (similarity %v)", er.Entity.Title, er.Similarity)
+		}
+	}
+	for _, tur := range result.TextUnits {
+		if tur.TextUnit.Content != "near" {
+			t.Errorf("Unexpected text unit %s", tur.TextUnit.ExternalID)
+		}
+	}
+	if result.Communities[0].Community.ExternalID != "comm-near" {
+		t.Errorf("Unexpected community %s", result.Communities[0].Community.ExternalID)
+	}
+}
 
+func TestEngine_DistanceMetric(t *testing.T) {
+	e := createTestEngine()
+	e.SetDistanceMetric(types.DistanceEuclidean)
+
+	query := make([]float32, testVectorDim)
+	query[0] = 1
+	far := make([]float32, testVectorDim)
+	far[0] = 3
+	mustAddEntity(t, e, testSessionID, "ent-far", "Far", "test", "Desc", far)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = query
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+
+	similarity := func() float32 {
+		t.Helper()
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(result.Entities) != 1 {
+			t.Fatalf("Expected 1 entity, got %d", len(result.Entities))
+		}
+		return result.Entities[0].Similarity
+	}
+
+	// New sessions use the engine default: 1/(1+distance 2)
+	if got := similarity(); math.Abs(float64(got)-1.0/3) > 1e-5 {
+		t.Errorf("Euclidean similarity = %v, want 1/3", got)
+	}
+
+	if err := e.SetSessionDistanceMetric(testSessionID, types.DistanceDotProduct); err != nil {
+		t.Fatalf("SetSessionDistanceMetric failed: %v", err)
+	}
+	if got := similarity(); math.Abs(float64(got)-3) > 1e-5 {
+		t.Errorf("Dot product similarity = %v, want 3", got)
+	}
+
+	if err := e.SetSessionDistanceMetric(testSessionID, "manhattan"); err == nil {
+		t.Error("Expected error for unknown metric")
+	}
+}
+
+func TestEngine_QuantizeVectorIndex(t *testing.T) {
+	e := createTestEngine()
+
+	rng := rand.New(rand.NewSource(7))
+	embeddings := make([][]float32, 20)
+	for i := range embeddings {
+		embeddings[i] = make([]float32, testVectorDim)
+		for j := range embeddings[i] {
+			embeddings[i][j] = rng.Float32()*2 - 1
+		}
+		mustAddEntity(t, e, testSessionID, fmt.Sprintf("ent-%d", i), fmt.Sprintf("Entity %d", i), "test", "Desc", embeddings[i])
+	}
+
+	before, after, err := e.QuantizeVectorIndex(testSessionID, vector.PrecisionInt8)
+	if err != nil {
+		t.Fatalf("QuantizeVectorIndex failed: %v", err)
+	}
+	if before == 0 || after*3 > before {
+		t.Errorf("Vector bytes %d -> %d, want at least 3x smaller", before, after)
+	}
+
+	// Quantized vectors still find themselves
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = embeddings[3]
+	spec.KHops = 0
+	spec.TopK = 1
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
 	result, err := e.Query(testSessionID, spec)
 	if err != nil {
 		t.Fatalf("Query failed: %v", err)
 	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ExternalID != "ent-3" {
+		t.Fatalf("Expected ent-3 as top hit, got %+v", result.Entities)
+	}
+	if sim := result.Entities[0].Similarity; sim < 0.99 {
+		t.Errorf("Self similarity = %v, want ~1", sim)
+	}
+
+	// The precision sticks to the session across a rebuild
+	if err := e.RebuildVectorIndices(testSessionID); err != nil {
+		t.Fatalf("RebuildVectorIndices failed: %v", err)
+	}
+	if again, _, _ := e.QuantizeVectorIndex(testSessionID, vector.PrecisionInt8); again != after {
+		t.Errorf("Vector bytes after rebuild = %d, want %d", again, after)
+	}
+
+	if _, _, err := e.QuantizeVectorIndex(testSessionID, "int4"); err == nil {
+		t.Error("Expected error for unknown precision")
+	}
+	if _, _, err := e.QuantizeVectorIndex("missing", vector.PrecisionInt8); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
+
+func TestEngine_GetRelationship(t *testing.T) {
+	e := createTestEngine()
+
+	embedding := randomVector(testVectorDim)
+	ent1 := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc 1", embedding)
+	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc 2", embedding)
+
+	rel := mustAddRelationship(t, e, testSessionID, "ext-rel-1", ent1.ID, ent2.ID, "RELATED_TO", "Desc", 1.0)
+
+	retrieved, ok := e.GetRelationship(testSessionID, rel.ID)
+	if !ok {
+		t.Error("GetRelationship should return true")
+	}
+	if retrieved.Type != "RELATED_TO" {
+		t.Errorf("Expected type 'RELATED_TO', got '%s'", retrieved.Type)
+	}
+}
+
+func TestEngine_GetRelationshipByEntities(t *testing.T) {
+	e := createTestEngine()
+
+	embedding := randomVector(testVectorDim)
+	ent1 := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc 1", embedding)
+	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc 2", embedding)
+
+	mustAddRelationship(t, e, testSessionID, "ext-rel-1", ent1.ID, ent2.ID, "RELATED_TO", "Desc", 1.0)
+
+	retrieved, ok := e.GetRelationshipByEntities(testSessionID, ent1.ID, ent2.ID)
+	if !ok {
+		t.Error("GetRelationshipByEntities should return true")
+	}
+	if retrieved.SourceID != ent1.ID || retrieved.TargetID != ent2.ID {
+		t.Error("Retrieved wrong relationship")
+	}
+}
+
+// =============================================================================
+// Community Operations Tests
+// =============================================================================
+
+func TestEngine_AddCommunity(t *testing.T) {
+	e := createTestEngine()
+
+	embedding := randomVector(testVectorDim)
+	comm, err := e.AddCommunity(testSessionID, "ext-comm-1", "Test Community", "Summary", "Full content", 0, []uint64{1, 2, 3}, []uint64{1, 2}, embedding)
+	if err != nil {
+		t.Fatalf("AddCommunity failed: %v", err)
+	}
+
+	if comm.ID == 0 {
+		t.Error("Community ID should not be 0")
+	}
+	if comm.Title != "Test Community" {
+		t.Errorf("Expected title 'Test Community', got '%s'", comm.Title)
+	}
+}
+
+func TestEngine_GetCommunity(t *testing.T) {
+	e := createTestEngine()
+
+	embedding := randomVector(testVectorDim)
+	comm, _ := e.AddCommunity(testSessionID, "ext-comm-1", "Test Community", "Summary", "Full content", 0, []uint64{1, 2, 3}, []uint64{1, 2}, embedding)
+
+	retrieved, ok := e.GetCommunity(testSessionID, comm.ID)
+	if !ok {
+		t.Error("GetCommunity should return true")
+	}
+	if retrieved.Summary != "Summary" {
+		t.Errorf("Expected summary 'Summary', got '%s'", retrieved.Summary)
+	}
+}
+
+// =============================================================================
+// Query Pipeline Tests
+// =============================================================================
+
+func TestEngine_Query_Basic(t *testing.T) {
+	e := createTestEngine()
+
+	// Add test data
+	embedding1 := randomVector(testVectorDim)
+	embedding2 := randomVector(testVectorDim)
+
+	doc := mustAddDocument(t, e, testSessionID, "ext-doc-1", "test.txt")
+	mustAddTextUnit(t, e, testSessionID, "ext-tu-1", doc.ID, "Test content 1", embedding1, 10)
+	mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Description 1", embedding1)
+	mustAddCommunity(t, e, testSessionID, "ext-comm-1", "Community 1", "Summary", "Full", 0, []uint64{1}, []uint64{}, embedding2)
+
+	// Query
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = embedding1
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if result.QueryID == 0 {
+		t.Error("QueryID should not be 0")
+	}
+}
+
+func TestEngine_Query_EmptyIndex(t *testing.T) {
+	e := createTestEngine()
+
+	// Add a document to ensure session exists (even though indices are empty)
+	mustAddDocument(t, e, testSessionID, "doc-1", "test.pdf")
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = randomVector(testVectorDim)
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query on empty index should not error: %v", err)
+	}
+
+	if len(result.TextUnits) != 0 {
+		t.Error("TextUnits should be empty on empty index")
+	}
+}
+
+func TestEngine_Query_WithGraphExpansion(t *testing.T) {
+	e := createTestEngine()
+
+	// Setup: entities with relationships
+	embedding := randomVector(testVectorDim)
+
+	ent1 := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc 1", embedding)
+	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc 2", randomVector(testVectorDim))
+	mustAddRelationship(t, e, testSessionID, "rel-1", ent1.ID, ent2.ID, "RELATED", "Desc", 1.0)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = embedding
+	spec.KHops = 2
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query with graph expansion failed: %v", err)
+	}
+
+	// Should find entities via graph traversal
+	if result.QueryID == 0 {
+		t.Error("QueryID should not be 0")
+	}
+}
+
+func TestEngine_Query_AsOf(t *testing.T) {
+	e := createTestEngine()
+
+	// Only ent1 is indexed, so ent2 can only be reached by traversal
+	v1 := randomVector(testVectorDim)
+	ent1 := mustAddEntity(t, e, testSessionID, "per-sunarso", "Sunarso", "person", "Banker", v1)
+	ent2 := mustAddEntity(t, e, testSessionID, "org-bri", "BRI", "organization", "Bank", nil)
+	rel := mustAddRelationship(t, e, testSessionID, "", ent1.ID, ent2.ID, "CEO_OF", "2019-2024", 1.0)
+
+	from := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	until := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	if err := e.SetRelationshipValidity(testSessionID, rel.ID, until, from); err != types.ErrInvalidValidityWindow {
+		t.Errorf("Expected ErrInvalidValidityWindow, got %v", err)
+	}
+	if err := e.SetRelationshipValidity(testSessionID, rel.ID, from, until); err != nil {
+		t.Fatalf("SetRelationshipValidity failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		asOf    int64
+		wantRel bool
+	}{
+		{"no filter", 0, true},
+		{"inside window", time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC).Unix(), true},
+		{"before window", time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC).Unix(), false},
+		{"at end (exclusive)", until, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := types.DefaultQuerySpec()
+			spec.QueryVector = v1
+			spec.TopK = 1
+			spec.KHops = 1
+			spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+			spec.AsOf = tt.asOf
+
+			result, err := e.Query(testSessionID, spec)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+
+			foundEnt2 := false
+			for _, er := range result.Entities {
+				if er.Entity.ID == ent2.ID {
+					foundEnt2 = true
+				}
+			}
+			if foundEnt2 != tt.wantRel {
+				t.Errorf("ent2 reached = %v, want %v", foundEnt2, tt.wantRel)
+			}
+			if (len(result.Relationships) == 1) != tt.wantRel {
+				t.Errorf("Expected relationship present = %v, got %d relationships", tt.wantRel, len(result.Relationships))
+			}
+		})
+	}
+}
+
+func TestEngine_Query_CreatedWindow(t *testing.T) {
+	e := createTestEngine()
+
+	v := randomVector(testVectorDim)
+	seed := mustAddEntity(t, e, testSessionID, "seed", "Seed", "person", "desc", v)
+	early := mustAddEntity(t, e, testSessionID, "early", "Early", "org", "desc", nil)
+	late := mustAddEntity(t, e, testSessionID, "late", "Late", "org", "desc", nil)
+	toEarly := mustAddRelationship(t, e, testSessionID, "", seed.ID, early.ID, "RELATED", "desc", 1.0)
+	toLate := mustAddRelationship(t, e, testSessionID, "", seed.ID, late.ID, "RELATED", "desc", 1.0)
+
+	// Backdate inserts: seed and early in 2020, late in 2023. The
+	// seed-early relationship was only recorded in 2022.
+	ts := func(year int) int64 { return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC).Unix() }
+	seed.CreatedAt = ts(2020)
+	early.CreatedAt = ts(2020)
+	late.CreatedAt = ts(2023)
+	toEarly.CreatedAt = ts(2022)
+	toLate.CreatedAt = ts(2023)
+
+	tests := []struct {
+		name          string
+		after, before int64
+		want          []uint64
+	}{
+		{"no window", 0, 0, []uint64{seed.ID, early.ID, late.ID}},
+		{"before 2021", 0, ts(2021), []uint64{seed.ID}},
+		{"before 2023", 0, ts(2023), []uint64{seed.ID, early.ID}},
+		{"after 2021", ts(2021), 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := types.DefaultQuerySpec()
+			spec.QueryVector = v
+			spec.TopK = 1
+			spec.KHops = 1
+			spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+			spec.CreatedAfter = tt.after
+			spec.CreatedBefore = tt.before
+
+			result, err := e.Query(testSessionID, spec)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			got := make(map[uint64]bool)
+			for _, er := range result.Entities {
+				got[er.Entity.ID] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("Got %d entities, want %d: %v", len(got), len(tt.want), got)
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("Entity %d missing from %v", id, got)
+				}
+			}
+			for _, rr := range result.Relationships {
+				if !got[rr.Relationship.SourceID] || !got[rr.Relationship.TargetID] {
+					t.Errorf("Relationship %d links entities outside the window", rr.Relationship.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestEngine_Query_TraversalDirection(t *testing.T) {
+	e := createTestEngine()
+
+	v := randomVector(testVectorDim)
+	worker := mustAddEntity(t, e, testSessionID, "worker", "Worker", "person", "desc", v)
+	boss := mustAddEntity(t, e, testSessionID, "boss", "Boss", "person", "desc", nil)
+	intern := mustAddEntity(t, e, testSessionID, "intern", "Intern", "person", "desc", nil)
+	peer := mustAddEntity(t, e, testSessionID, "peer", "Peer", "person", "desc", nil)
+	for _, rel := range []*types.Relationship{
+		mustAddRelationship(t, e, testSessionID, "", boss.ID, worker.ID, "SUPERVISES", "desc", 1.0),
+		mustAddRelationship(t, e, testSessionID, "", worker.ID, intern.ID, "SUPERVISES", "desc", 1.0),
+	} {
+		if err := e.SetRelationshipDirected(testSessionID, rel.ID, true); err != nil {
+			t.Fatalf("SetRelationshipDirected failed: %v", err)
+		}
+	}
+	// Undirected, pointing at the seed
+	mustAddRelationship(t, e, testSessionID, "", peer.ID, worker.ID, "KNOWS", "desc", 1.0)
+
+	tests := []struct {
+		direction types.TraversalDirection
+		want      []uint64
+	}{
+		{"", []uint64{worker.ID, intern.ID, peer.ID}},
+		{types.TraversalForward, []uint64{worker.ID, intern.ID, peer.ID}},
+		{types.TraversalReverse, []uint64{worker.ID, boss.ID, peer.ID}},
+		{types.TraversalBoth, []uint64{worker.ID, boss.ID, intern.ID, peer.ID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.direction), func(t *testing.T) {
+			spec := types.DefaultQuerySpec()
+			spec.QueryVector = v
+			spec.TopK = 1
+			spec.KHops = 1
+			spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+			spec.TraversalDirection = tt.direction
+
+			result, err := e.Query(testSessionID, spec)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			got := make(map[uint64]bool)
+			for _, er := range result.Entities {
+				got[er.Entity.ID] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("Got %d entities, want %d: %v", len(got), len(tt.want), got)
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("Entity %d not reached, got %v", id, got)
+				}
+			}
+		})
+	}
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = v
+	spec.TraversalDirection = "sideways"
+	if _, err := e.Query(testSessionID, spec); err == nil {
+		t.Error("Expected error for unknown traversal direction")
+	}
+}
+
+func TestEngine_ContextDeadline(t *testing.T) {
+	e := createTestEngine()
+
+	v := randomVector(testVectorDim)
+	var prev *types.Entity
+	for i := 0; i < 20; i++ {
+		ent := mustAddEntity(t, e, testSessionID, fmt.Sprintf("e%d", i), fmt.Sprintf("Entity %d", i), "test", "desc", v)
+		if prev != nil {
+			mustAddRelationship(t, e, testSessionID, "", prev.ID, ent.ID, "NEXT", "desc", 1.0)
+		}
+		prev = ent
+	}
+	mustAddCommunity(t, e, testSessionID, "c1", "Existing", "summary", "full", 0, []uint64{prev.ID}, nil, v)
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = v
+	if _, err := e.QueryContext(expired, testSessionID, spec); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("QueryContext error = %v, want deadline exceeded", err)
+	}
+	if _, _, err := e.ComputeCommunitiesWithModularityContext(expired, testSessionID, graph.AlgorithmLouvain, graph.DefaultLeidenConfig()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ComputeCommunitiesWithModularityContext error = %v, want deadline exceeded", err)
+	}
+	if _, err := e.ComputeHierarchicalCommunitiesContext(expired, testSessionID, graph.DefaultLeidenConfig()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("ComputeHierarchicalCommunitiesContext error = %v, want deadline exceeded", err)
+	}
+	if info := e.Info(); info.CommunityCount != 1 {
+		t.Errorf("Timed-out computations should keep existing communities, have %d", info.CommunityCount)
+	}
+
+	// Without a deadline the same calls succeed
+	if _, err := e.QueryContext(context.Background(), testSessionID, spec); err != nil {
+		t.Errorf("QueryContext error = %v", err)
+	}
+	if _, _, err := e.ComputeCommunitiesWithModularityContext(context.Background(), testSessionID, "", graph.DefaultLeidenConfig()); err != nil {
+		t.Errorf("ComputeCommunitiesWithModularityContext error = %v", err)
+	}
+}
+
+func TestEngine_Query_HubPenalty(t *testing.T) {
+	e := createTestEngine()
+
+	v := randomVector(testVectorDim)
+	seed := mustAddEntity(t, e, testSessionID, "seed", "Seed", "person", "desc", v)
+	hub := mustAddEntity(t, e, testSessionID, "hub", "Bank Indonesia", "organization", "desc", nil)
+	specific := mustAddEntity(t, e, testSessionID, "specific", "Small Bank", "organization", "desc", nil)
+	mustAddRelationship(t, e, testSessionID, "", seed.ID, hub.ID, "RELATED", "desc", 1.0)
+	mustAddRelationship(t, e, testSessionID, "", seed.ID, specific.ID, "RELATED", "desc", 1.0)
+	for i := 0; i < 5; i++ {
+		leaf := mustAddEntity(t, e, testSessionID, fmt.Sprintf("leaf-%d", i), fmt.Sprintf("Leaf %d", i), "org", "desc", nil)
+		mustAddRelationship(t, e, testSessionID, "", hub.ID, leaf.ID, "REGULATES", "desc", 1.0)
+	}
+
+	scores := func(penalty float32) map[uint64]float32 {
+		spec := types.DefaultQuerySpec()
+		spec.QueryVector = v
+		spec.TopK = 1
+		spec.KHops = 1
+		spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+		spec.HubPenalty = penalty
+
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		out := make(map[uint64]float32)
+		for _, er := range result.Entities {
+			out[er.Entity.ID] = er.Score
+		}
+		return out
+	}
+
+	plain := scores(0)
+	if plain[hub.ID] != plain[specific.ID] {
+		t.Errorf("Without penalty hub and specific should tie, got %v vs %v", plain[hub.ID], plain[specific.ID])
+	}
+
+	penalized := scores(1)
+	if penalized[hub.ID] >= penalized[specific.ID] {
+		t.Errorf("Hub should score below specific entity, got %v vs %v", penalized[hub.ID], penalized[specific.ID])
+	}
+	// degree 6 -> 0.5/7, degree 1 -> 0.5/2
+	if math.Abs(float64(penalized[hub.ID])-0.5/7) > 1e-6 || math.Abs(float64(penalized[specific.ID])-0.25) > 1e-6 {
+		t.Errorf("Unexpected penalized scores: hub=%v specific=%v", penalized[hub.ID], penalized[specific.ID])
+	}
+}
+
+func TestEngine_Query_DecayFactor(t *testing.T) {
+	e := createTestEngine()
+
+	v := randomVector(testVectorDim)
+	seed := mustAddEntity(t, e, testSessionID, "seed", "Seed", "person", "desc", v)
+	strong := mustAddEntity(t, e, testSessionID, "strong", "Strong", "org", "desc", nil)
+	weak := mustAddEntity(t, e, testSessionID, "weak", "Weak", "org", "desc", nil)
+	strongLeaf := mustAddEntity(t, e, testSessionID, "strong-leaf", "Strong Leaf", "org", "desc", nil)
+	weakLeaf := mustAddEntity(t, e, testSessionID, "weak-leaf", "Weak Leaf", "org", "desc", nil)
+	mustAddRelationship(t, e, testSessionID, "", weak.ID, seed.ID, "RELATED", "desc", 0.2)
+	mustAddRelationship(t, e, testSessionID, "", seed.ID, strong.ID, "RELATED", "desc", 0.9)
+	mustAddRelationship(t, e, testSessionID, "", strong.ID, strongLeaf.ID, "RELATED", "desc", 1.0)
+	mustAddRelationship(t, e, testSessionID, "", weak.ID, weakLeaf.ID, "RELATED", "desc", 1.0)
+
+	query := func(decay float32, maxEntities int) *types.ContextPack {
+		spec := types.DefaultQuerySpec()
+		spec.QueryVector = v
+		spec.TopK = 1
+		spec.KHops = 2
+		spec.MaxEntities = maxEntities
+		spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+		spec.DecayFactor = decay
+
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		return result
+	}
+	scores := func(result *types.ContextPack) map[uint64]float32 {
+		out := make(map[uint64]float32)
+		for _, er := range result.Entities {
+			out[er.Entity.ID] = er.Score
+		}
+		return out
+	}
+
+	plain := scores(query(0, 50))
+	if plain[strong.ID] != plain[weak.ID] || plain[strongLeaf.ID] != plain[weakLeaf.ID] {
+		t.Errorf("Without decay entities at the same hop should tie, got %v", plain)
+	}
+
+	result := query(0.5, 50)
+	decayed := scores(result)
+	seedScore := decayed[seed.ID]
+	// hop 1: seed*w*0.5, hop 2: seed*w*1.0*0.25
+	want := map[uint64]float32{
+		strong.ID:     seedScore * 0.9 * 0.5,
+		weak.ID:       seedScore * 0.2 * 0.5,
+		strongLeaf.ID: seedScore * 0.9 * 0.25,
+		weakLeaf.ID:   seedScore * 0.2 * 0.25,
+	}
+	for id, w := range want {
+		if math.Abs(float64(decayed[id]-w)) > 1e-5 {
+			t.Errorf("Entity %d score = %v, want %v", id, decayed[id], w)
+		}
+	}
+	if decayed[strong.ID] <= decayed[weak.ID] || decayed[strongLeaf.ID] <= decayed[weakLeaf.ID] {
+		t.Errorf("Strong paths should outrank weak ones at the same hop, got %v", decayed)
+	}
+	// The strong leaf (two hops) still beats the weakly linked neighbor
+	if decayed[strongLeaf.ID] <= decayed[weak.ID] {
+		t.Errorf("Strong leaf should outrank weak neighbor, got %v vs %v", decayed[strongLeaf.ID], decayed[weak.ID])
+	}
+
+	// The MaxEntities cutoff keeps the highest propagated scores
+	cut := scores(query(0.5, 3))
+	if len(cut) != 3 {
+		t.Fatalf("Expected 3 entities, got %d", len(cut))
+	}
+	for _, id := range []uint64{seed.ID, strong.ID, strongLeaf.ID} {
+		if _, ok := cut[id]; !ok {
+			t.Errorf("Entity %d should survive the cutoff, got %v", id, cut)
+		}
+	}
+}
+
+func TestEngine_Query_MinResults(t *testing.T) {
+	e := createTestEngine()
+
+	v := randomVector(testVectorDim)
+	mustAddEntity(t, e, testSessionID, "ent-1", "Entity 1", "person", "desc", v)
+	mustAddEntity(t, e, testSessionID, "ent-2", "Entity 2", "person", "desc", randomVector(testVectorDim))
+	mustAddEntity(t, e, testSessionID, "ent-3", "Entity 3", "person", "desc", randomVector(testVectorDim))
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = v
+	spec.TopK = 1
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 || len(result.Stats.Relaxations) != 0 {
+		t.Fatalf("Expected 1 entity and no relaxations, got %d / %v", len(result.Entities), result.Stats.Relaxations)
+	}
+
+	spec.MinResults = 3
+	result, err = e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 3 {
+		t.Errorf("Expected relaxation to reach 3 entities, got %d", len(result.Entities))
+	}
+	want := []string{"top_k:1->2", "k_hops:0->1", "top_k:2->4"}
+	if fmt.Sprint(result.Stats.Relaxations) != fmt.Sprint(want) {
+		t.Errorf("Relaxations = %v, want %v", result.Stats.Relaxations, want)
+	}
+
+	// Unreachable minimum stops after the bounded number of steps
+	spec.MinResults = 100
+	result, err = e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Stats.Relaxations) != maxQueryRelaxations {
+		t.Errorf("Expected %d relaxations, got %v", maxQueryRelaxations, result.Stats.Relaxations)
+	}
+
+	// The vector search is never widened past maxRelaxedTopK
+	spec.TopK = maxRelaxedTopK
+	result, err = e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	want = []string{"k_hops:0->1", "k_hops:1->2"}
+	if fmt.Sprint(result.Stats.Relaxations) != fmt.Sprint(want) {
+		t.Errorf("Relaxations at the top_k cap = %v, want %v", result.Stats.Relaxations, want)
+	}
+}
+
+func TestEngine_Query_MinResultsLowersMinSimilarity(t *testing.T) {
+	e := createTestEngine()
+
+	// The entity's cosine similarity to the query is 0.75
+	query := make([]float32, testVectorDim)
+	query[0] = 1
+	vec := make([]float32, testVectorDim)
+	vec[0], vec[1] = 0.75, float32(math.Sqrt(1-0.75*0.75))
+	mustAddEntity(t, e, testSessionID, "ent-1", "Entity 1", "person", "desc", vec)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = query
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	spec.MinSimilarity = 0.8
+	spec.MinResults = 1
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Entities) != 1 {
+		t.Fatalf("Expected relaxation to reach the entity, got %d entities", len(result.Entities))
+	}
+	want := []string{"min_similarity:0.8->0.7"}
+	if fmt.Sprint(result.Stats.Relaxations) != fmt.Sprint(want) {
+		t.Errorf("Relaxations = %v, want %v", result.Stats.Relaxations, want)
+	}
+}
+
+func TestEngine_QueryStatsSummary(t *testing.T) {
+	e := createTestEngine()
+
+	if summary := e.QueryStatsSummary(0, ""); summary.Count != 0 || summary.WindowSeconds != int64(DefaultQueryStatsWindow/time.Second) {
+		t.Fatalf("Expected empty summary over default window, got %+v", summary)
+	}
+
+	v := randomVector(testVectorDim)
+	mustAddEntity(t, e, testSessionID, "ent-1", "Entity 1", "person", "desc", v)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = v
+	spec.KHops = 1
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	for i := 0; i < 3; i++ {
+		if _, err := e.Query(testSessionID, spec); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+	}
+
+	// Query against a session without entities
+	mustAddDocument(t, e, "other-session", "doc-1", "doc.txt")
+	if _, err := e.Query("other-session", spec); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	summary := e.QueryStatsSummary(time.Minute, "")
+	if summary.Count != 4 {
+		t.Fatalf("Expected 4 queries, got %d", summary.Count)
+	}
+	if summary.EmptyResults != 1 {
+		t.Errorf("Expected 1 empty query, got %d", summary.EmptyResults)
+	}
+	if summary.AvgEntities != 0.75 || summary.AvgKHops != 1 {
+		t.Errorf("Unexpected averages: entities=%v k_hops=%v", summary.AvgEntities, summary.AvgKHops)
+	}
+	if summary.P99LatencyMicros < summary.P50LatencyMicros {
+		t.Errorf("p99 %v below p50 %v", summary.P99LatencyMicros, summary.P50LatencyMicros)
+	}
+
+	if summary := e.QueryStatsSummary(time.Minute, testSessionID); summary.Count != 3 || summary.EmptyResults != 0 {
+		t.Errorf("Expected 3 non-empty queries for session, got %+v", summary)
+	}
+}
+
+func TestEngine_QueryHistory(t *testing.T) {
+	e := createTestEngine()
+	e.SetQueryHistorySize(3)
+
+	v := randomVector(testVectorDim)
+	ent := mustAddEntity(t, e, testSessionID, "ent-1", "Entity 1", "person", "desc", v)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = v
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	var ids []uint64
+	for topK := 1; topK <= 5; topK++ {
+		spec.TopK = topK
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		ids = append(ids, result.QueryID)
+	}
+
+	// Only the last three are kept, newest first
+	records, err := e.ListQueries(testSessionID, 0)
+	if err != nil {
+		t.Fatalf("ListQueries failed: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 queries, got %d", len(records))
+	}
+	for i, rec := range records {
+		want := ids[len(ids)-1-i]
+		if rec.QueryID != want || rec.Spec.TopK != 5-i || rec.Entities != 1 || rec.At == 0 {
+			t.Errorf("Record %d = %+v, want query %d with top_k %d", i, rec, want, 5-i)
+		}
+	}
+	if records, _ := e.ListQueries(testSessionID, 1); len(records) != 1 || records[0].QueryID != ids[4] {
+		t.Errorf("ListQueries(limit 1) = %+v", records)
+	}
+
+	result, ok := e.GetQueryResult(testSessionID, ids[4])
+	if !ok || result.QueryID != ids[4] || len(result.Entities) != 1 || result.Entities[0].Entity.ID != ent.ID {
+		t.Errorf("GetQueryResult = %+v, %v", result, ok)
+	}
+	if _, ok := e.GetQueryResult(testSessionID, ids[0]); ok {
+		t.Error("Evicted query should not be found")
+	}
+	if _, ok := e.GetQueryResult("other-session", ids[4]); ok {
+		t.Error("Query should not be found from another session")
+	}
+
+	// Shrinking trims, 0 turns the history off
+	e.SetQueryHistorySize(1)
+	if records, _ := e.ListQueries(testSessionID, 0); len(records) != 1 || records[0].QueryID != ids[4] {
+		t.Errorf("After shrinking = %+v", records)
+	}
+	e.SetQueryHistorySize(0)
+	if _, err := e.Query(testSessionID, spec); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if records, _ := e.ListQueries(testSessionID, 0); len(records) != 0 {
+		t.Errorf("Expected no history, got %d", len(records))
+	}
+
+	e.SetQueryHistorySize(DefaultQueryHistorySize)
+	if _, err := e.Query(testSessionID, spec); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	e.DeleteSession(testSessionID)
+	if _, err := e.ListQueries(testSessionID, 0); err == nil {
+		t.Error("Expected error for a deleted session")
+	}
+	if len(e.queryHistory.sessions) != 0 {
+		t.Error("Deleting a session should drop its history")
+	}
+}
+
+func TestEngine_PopularityTracking(t *testing.T) {
+	e := createTestEngine()
+
+	v := randomVector(testVectorDim)
+	seed := mustAddEntity(t, e, testSessionID, "seed", "Seed", "person", "desc", v)
+	popular := mustAddEntity(t, e, testSessionID, "popular", "Popular", "org", "desc", nil)
+	other := mustAddEntity(t, e, testSessionID, "other", "Other", "org", "desc", nil)
+	mustAddRelationship(t, e, testSessionID, "", seed.ID, popular.ID, "RELATED", "desc", 1.0)
+	mustAddRelationship(t, e, testSessionID, "", seed.ID, other.ID, "RELATED", "desc", 1.0)
+
+	// Disabled by default: reads are not counted
+	e.GetEntity(testSessionID, popular.ID)
+	if got := e.EntityPopularity(testSessionID, popular.ID); got != 0 {
+		t.Errorf("Expected 0 with tracking disabled, got %v", got)
+	}
+
+	e.SetPopularityTracking(time.Hour)
+	for i := 0; i < 5; i++ {
+		e.GetEntity(testSessionID, popular.ID)
+	}
+	if got := e.EntityPopularity(testSessionID, popular.ID); got < 4.99 {
+		t.Errorf("Expected popularity ~5, got %v", got)
+	}
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = v
+	spec.TopK = 1
+	spec.KHops = 1
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	spec.PopularityBoost = 1
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	scores := make(map[uint64]float32)
+	for _, er := range result.Entities {
+		scores[er.Entity.ID] = er.Score
+	}
+	if scores[popular.ID] <= scores[other.ID] {
+		t.Errorf("Popular entity should outrank other, got %v vs %v", scores[popular.ID], scores[other.ID])
+	}
+
+	// Query results count as accesses too
+	if got := e.EntityPopularity(testSessionID, other.ID); got < 0.99 {
+		t.Errorf("Expected query result to be counted, got %v", got)
+	}
+}
+
+func TestEngine_ComputePageRank(t *testing.T) {
+	e := createTestEngine()
+
+	hub := mustAddEntity(t, e, testSessionID, "ojk", "OJK", "organization", "desc", nil)
+	var spokes []*types.Entity
+	for i := 0; i < 4; i++ {
+		spoke := mustAddEntity(t, e, testSessionID, fmt.Sprintf("bank-%d", i), fmt.Sprintf("Bank %d", i), "organization", "desc", nil)
+		mustAddRelationship(t, e, testSessionID, "", spoke.ID, hub.ID, "REGULATED_BY", "desc", 1.0)
+		spokes = append(spokes, spoke)
+	}
+
+	scores, err := e.ComputePageRank(testSessionID)
+	if err != nil {
+		t.Fatalf("ComputePageRank failed: %v", err)
+	}
+	if len(scores) != 5 {
+		t.Fatalf("Expected 5 scores, got %d", len(scores))
+	}
+
+	hubEnt, _ := e.GetEntity(testSessionID, hub.ID)
+	if hubEnt.PageRank != scores[hub.ID] || hubEnt.PageRank == 0 {
+		t.Errorf("Hub PageRank = %v, want stored score %v", hubEnt.PageRank, scores[hub.ID])
+	}
+	for _, spoke := range spokes {
+		ent, _ := e.GetEntity(testSessionID, spoke.ID)
+		if ent.PageRank >= hubEnt.PageRank {
+			t.Errorf("Spoke %d PageRank %v should be below hub %v", spoke.ID, ent.PageRank, hubEnt.PageRank)
+		}
+	}
+
+	if _, err := e.ComputePageRank("missing-session"); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
+
+func TestEngine_Query_PageRankWeight(t *testing.T) {
+	e := createTestEngine()
+
+	v := randomVector(testVectorDim)
+	seed := mustAddEntity(t, e, testSessionID, "seed", "Seed", "person", "desc", v)
+	near := mustAddEntity(t, e, testSessionID, "near", "Near", "org", "desc", nil)
+	mid := mustAddEntity(t, e, testSessionID, "mid", "Mid", "org", "desc", nil)
+	central := mustAddEntity(t, e, testSessionID, "central", "Central", "org", "desc", nil)
+	mustAddRelationship(t, e, testSessionID, "", seed.ID, near.ID, "RELATED", "desc", 1.0)
+	mustAddRelationship(t, e, testSessionID, "", seed.ID, mid.ID, "RELATED", "desc", 1.0)
+	mustAddRelationship(t, e, testSessionID, "", mid.ID, central.ID, "RELATED", "desc", 1.0)
+	for i := 0; i < 6; i++ {
+		leaf := mustAddEntity(t, e, testSessionID, fmt.Sprintf("leaf-%d", i), fmt.Sprintf("Leaf %d", i), "org", "desc", nil)
+		mustAddRelationship(t, e, testSessionID, "", leaf.ID, central.ID, "REFERS_TO", "desc", 1.0)
+	}
+	if _, err := e.ComputePageRank(testSessionID); err != nil {
+		t.Fatalf("ComputePageRank failed: %v", err)
+	}
+
+	rank := func(weight float32) map[uint64]int {
+		spec := types.DefaultQuerySpec()
+		spec.QueryVector = v
+		spec.TopK = 1
+		spec.KHops = 2
+		spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+		spec.PageRankWeight = weight
+
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		out := make(map[uint64]int)
+		for i, er := range result.Entities {
+			out[er.Entity.ID] = i
+		}
+		return out
+	}
+
+	plain := rank(0)
+	if _, ok := plain[central.ID]; !ok {
+		t.Fatalf("Central entity missing from results: %v", plain)
+	}
+	if plain[near.ID] >= plain[central.ID] {
+		t.Errorf("Without boost the 1-hop entity should rank first, got near=%d central=%d", plain[near.ID], plain[central.ID])
+	}
+	boosted := rank(1)
+	if boosted[central.ID] >= boosted[near.ID] {
+		t.Errorf("With boost the central entity should rank first, got near=%d central=%d", boosted[near.ID], boosted[central.ID])
+	}
+}
+
+func TestEngine_Query_KeywordHybrid(t *testing.T) {
+	e := createTestEngine()
+
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "payments.txt")
+	v := randomVector(testVectorDim)
+	near := func() []float32 {
+		out := make([]float32, len(v))
+		for i := range v {
+			out[i] = v[i] + 0.05*(rand.Float32()-0.5)
+		}
+		return out
+	}
+	for i := 0; i < 4; i++ {
+		mustAddTextUnit(t, e, testSessionID, fmt.Sprintf("tu-%d", i), doc.ID, "Interbank transfers and payment systems overview", near(), 6)
+	}
+	// Vector search ranks this one last, but it holds the exact term
+	negated := make([]float32, len(v))
+	for i := range v {
+		negated[i] = -v[i]
+	}
+	exact := mustAddTextUnit(t, e, testSessionID, "tu-exact", doc.ID, "Retail settlement moved to BI-FAST in 2021", negated, 8)
+
+	query := func(searchTypes ...types.SearchType) []types.TextUnitResult {
+		spec := types.DefaultQuerySpec()
+		spec.QueryVector = v
+		spec.TopK = 3
+		spec.KHops = 0
+		spec.MaxTextUnits = 3
+		spec.SearchTypes = searchTypes
+		spec.KeywordQuery = "BI-FAST"
+		spec.KeywordWeight = 0.7
+
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		return result.TextUnits
+	}
+
+	for _, tur := range query(types.SearchTypeTextUnit) {
+		if tur.TextUnit.ID == exact.ID {
+			t.Fatal("Vector search alone should not surface the exact-term unit")
+		}
+	}
+
+	hybrid := query(types.SearchTypeTextUnit, types.SearchTypeKeyword)
+	if len(hybrid) == 0 || hybrid[0].TextUnit.ID != exact.ID {
+		t.Fatalf("Hybrid query should rank the exact-term unit first, got %v", hybrid)
+	}
+	if hybrid[0].KeywordScore != 1 || math.Abs(float64(hybrid[0].Score)-0.7) > 1e-6 {
+		t.Errorf("Expected keyword score 1 and blended score 0.7, got %v and %v", hybrid[0].KeywordScore, hybrid[0].Score)
+	}
+	for _, tur := range hybrid[1:] {
+		if tur.KeywordScore != 0 || tur.Score > 0.3+1e-6 {
+			t.Errorf("Vector-only unit %d should score at most 0.3*similarity, got %v", tur.TextUnit.ID, tur.Score)
+		}
+	}
+
+	// Keyword search works without a query vector
+	spec := types.DefaultQuerySpec()
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeKeyword}
+	spec.KeywordQuery = "settlement"
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.TextUnits) != 1 || result.TextUnits[0].TextUnit.ID != exact.ID {
+		t.Errorf("Keyword-only query should return the matching unit, got %v", result.TextUnits)
+	}
+}
+
+func TestReciprocalRankFusion(t *testing.T) {
+	// 1 tops the vector ranking only, 2 tops the keyword ranking only, and
+	// 3 is second in both
+	vectorRanking := []uint64{1, 3, 4}
+	keywordRanking := []uint64{2, 3}
+
+	scores := reciprocalRankFusion([][]uint64{vectorRanking, keywordRanking}, DefaultRRFK)
+	if scores[3] <= scores[1] || scores[3] <= scores[2] {
+		t.Errorf("Item ranked second in both should beat items ranked first in one, got %v", scores)
+	}
+	if want := 2.0 / 62; math.Abs(scores[3]-want) > 1e-12 {
+		t.Errorf("scores[3] = %v, want %v", scores[3], want)
+	}
+	if want := 1.0 / 63; math.Abs(scores[4]-want) > 1e-12 {
+		t.Errorf("scores[4] = %v, want %v", scores[4], want)
+	}
+
+	// k is configurable: with k=1, 2/3 for the item second in both still
+	// beats 1/2 for the item first in one
+	small := reciprocalRankFusion([][]uint64{vectorRanking, keywordRanking}, 1)
+	if math.Abs(small[3]-2.0/3) > 1e-12 || math.Abs(small[1]-0.5) > 1e-12 {
+		t.Errorf("k=1: got %v", small)
+	}
+}
+
+func TestEngine_Query_FusionRRF(t *testing.T) {
+	e := createTestEngine()
+
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "payments.txt")
+	v := randomVector(testVectorDim)
+	nearby := make([]float32, len(v))
+	negated := make([]float32, len(v))
+	for i := range v {
+		nearby[i] = v[i] + 0.1*(rand.Float32()-0.5)
+		negated[i] = -v[i]
+	}
+	vectorTop := mustAddTextUnit(t, e, testSessionID, "tu-vector", doc.ID, "Card networks and interchange", v, 4)
+	both := mustAddTextUnit(t, e, testSessionID, "tu-both", doc.ID, "Merchants also take QRIS alongside cards and cash", nearby, 8)
+	keywordTop := mustAddTextUnit(t, e, testSessionID, "tu-keyword", doc.ID, "QRIS QRIS QRIS", negated, 3)
+
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = v
+	spec.TopK = 2
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeTextUnit, types.SearchTypeKeyword}
+	spec.KeywordQuery = "qris"
+	spec.FusionMethod = types.FusionRRF
+
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.TextUnits) != 3 {
+		t.Fatalf("Expected 3 text units, got %d", len(result.TextUnits))
+	}
+	if result.TextUnits[0].TextUnit.ID != both.ID {
+		t.Errorf("Unit ranked second by both searches should lead, got %d", result.TextUnits[0].TextUnit.ID)
+	}
+	// Ranked first in both would score 1; second in both scores (k+1)/(k+2)
+	if want := float32(61.0 / 62.0); math.Abs(float64(result.TextUnits[0].Score-want)) > 1e-6 {
+		t.Errorf("Fused score = %v, want %v", result.TextUnits[0].Score, want)
+	}
+	tail := map[uint64]float32{
+		result.TextUnits[1].TextUnit.ID: result.TextUnits[1].Score,
+		result.TextUnits[2].TextUnit.ID: result.TextUnits[2].Score,
+	}
+	if tail[vectorTop.ID] == 0 || tail[vectorTop.ID] != tail[keywordTop.ID] {
+		t.Errorf("Units ranked first in one search should tie behind, got %v", tail)
+	}
+
+	spec.FusionMethod = "sum"
+	if _, err := e.Query(testSessionID, spec); err == nil {
+		t.Error("Expected error for unknown fusion method")
+	}
+}
+
+func TestEngine_Query_Diversity(t *testing.T) {
+	e := createTestEngine()
+
+	// Unit query vector q and a unit vector u orthogonal to it
+	q := randomVector(testVectorDim)
+	u := randomVector(testVectorDim)
+	var qNorm, dot float64
+	for i := range q {
+		qNorm += float64(q[i] * q[i])
+	}
+	for i := range q {
+		q[i] /= float32(math.Sqrt(qNorm))
+	}
+	for i := range q {
+		dot += float64(q[i] * u[i])
+	}
+	var uNorm float64
+	for i := range u {
+		u[i] -= float32(dot) * q[i]
+		uNorm += float64(u[i] * u[i])
+	}
+	for i := range u {
+		u[i] /= float32(math.Sqrt(uNorm))
+	}
+
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "rates.txt")
+	for i := 0; i < 3; i++ {
+		dup := make([]float32, testVectorDim)
+		for j := range dup {
+			dup[j] = q[j] + 0.01*float32(i)*u[j]
+		}
+		mustAddTextUnit(t, e, testSessionID, fmt.Sprintf("tu-dup-%d", i), doc.ID, "BI raised the policy rate by 25bp", dup, 8)
+	}
+	distinctVec := make([]float32, testVectorDim)
+	for j := range distinctVec {
+		distinctVec[j] = 0.6*q[j] + 0.8*u[j]
+	}
+	distinct := mustAddTextUnit(t, e, testSessionID, "tu-distinct", doc.ID, "The rupiah strengthened after the decision", distinctVec, 7)
+
+	query := func(diversity float32) []types.TextUnitResult {
+		spec := types.DefaultQuerySpec()
+		spec.QueryVector = q
+		spec.TopK = 4
+		spec.KHops = 0
+		spec.MaxTextUnits = 2
+		spec.SearchTypes = []types.SearchType{types.SearchTypeTextUnit}
+		spec.Diversity = diversity
+
+		result, err := e.Query(testSessionID, spec)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		return result.TextUnits
+	}
+
+	for _, tur := range query(0) {
+		if tur.TextUnit.ID == distinct.ID {
+			t.Fatal("Without diversity the distinct chunk should fall below the cap")
+		}
+	}
 
-	if result.QueryID == 0 {
-		t.Error("QueryID should not be 0")
+	diverse := query(0.7)
+	if len(diverse) != 2 {
+		t.Fatalf("Expected 2 text units, got %d", len(diverse))
+	}
+	if diverse[0].TextUnit.ID == distinct.ID {
+		t.Error("The most relevant chunk should still come first")
+	}
+	if diverse[1].TextUnit.ID != distinct.ID {
+		t.Errorf("High diversity should promote the distinct chunk, got %d", diverse[1].TextUnit.ID)
 	}
 }
 
-func TestEngine_Query_EmptyIndex(t *testing.T) {
+func TestEngine_Query_Pagination(t *testing.T) {
 	e := createTestEngine()
 
-	// Add a document to ensure session exists (even though indices are empty)
-	mustAddDocument(t, e, testSessionID, "doc-1", "test.pdf")
+	rng := rand.New(rand.NewSource(7))
+	q := randomVector(testVectorDim)
+	for i := 0; i < 25; i++ {
+		vec := make([]float32, testVectorDim)
+		for j := range vec {
+			vec[j] = q[j] + 0.05*float32(i)*(rng.Float32()-0.5)
+		}
+		mustAddEntity(t, e, testSessionID, fmt.Sprintf("ent-%d", i), fmt.Sprintf("Bank %d", i), "organization", "a bank", vec)
+	}
 
 	spec := types.DefaultQuerySpec()
-	spec.QueryVector = randomVector(testVectorDim)
+	spec.QueryVector = q
+	spec.TopK = 25
+	spec.KHops = 0
+	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
 
-	result, err := e.Query(testSessionID, spec)
+	full, err := e.Query(testSessionID, spec)
 	if err != nil {
-		t.Fatalf("Query on empty index should not error: %v", err)
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(full.Entities) != 25 || full.NextCursor != "" {
+		t.Fatalf("Unpaged query returned %d entities, cursor %q; want 25 and none", len(full.Entities), full.NextCursor)
 	}
 
-	if len(result.TextUnits) != 0 {
-		t.Error("TextUnits should be empty on empty index")
+	spec.PageSize = 10
+	page, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// A better match added mid-walk must not shift the remaining pages
+	mustAddEntity(t, e, testSessionID, "ent-late", "Late Bank", "organization", "a bank", q)
+
+	var walked []uint64
+	var sizes []int
+	for {
+		sizes = append(sizes, len(page.Entities))
+		for _, er := range page.Entities {
+			walked = append(walked, er.Entity.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		page, err = e.Query(testSessionID, types.QuerySpec{Cursor: page.NextCursor})
+		if err != nil {
+			t.Fatalf("Query with cursor failed: %v", err)
+		}
+	}
+
+	if !slices.Equal(sizes, []int{10, 10, 5}) {
+		t.Errorf("Page sizes = %v, want [10 10 5]", sizes)
+	}
+	for i, er := range full.Entities {
+		if i >= len(walked) || walked[i] != er.Entity.ID {
+			t.Fatalf("Paged order %v differs from unpaged ranking at %d", walked, i)
+		}
+	}
+
+	for _, cursor := range []string{
+		"not-a-cursor",
+		fmt.Sprintf("%d:1:9223372036854775807", page.QueryID),
+		fmt.Sprintf("%d:9223372036854775807:10", page.QueryID),
+		fmt.Sprintf("%d:26:10", page.QueryID),
+		fmt.Sprintf("%d:0:%d", page.QueryID, MaxQueryPageSize+1),
+		fmt.Sprintf("%d:-1:10", page.QueryID),
+		fmt.Sprintf("%d:0:99999999999999999999", page.QueryID),
+	} {
+		if _, err := e.Query(testSessionID, types.QuerySpec{Cursor: cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Cursor %q error = %v, want ErrInvalidCursor", cursor, err)
+		}
+	}
+	if _, err := e.Query(testSessionID, types.QuerySpec{Cursor: encodeCursor(full.QueryID, 10, 10)}); !errors.Is(err, ErrCursorExpired) {
+		t.Errorf("Cursor for an unpaged query error = %v, want ErrCursorExpired", err)
 	}
 }
 
-func TestEngine_Query_WithGraphExpansion(t *testing.T) {
+func TestEngine_Query_IncludeTextStats(t *testing.T) {
 	e := createTestEngine()
 
-	// Setup: entities with relationships
+	doc := mustAddDocument(t, e, testSessionID, "ext-doc-1", "test.txt")
 	embedding := randomVector(testVectorDim)
-
-	ent1 := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc 1", embedding)
-	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc 2", randomVector(testVectorDim))
-	mustAddRelationship(t, e, testSessionID, "rel-1", ent1.ID, ent2.ID, "RELATED", "Desc", 1.0)
+	content := "Suku bunga acuan naik"
+	mustAddTextUnit(t, e, testSessionID, "ext-tu-1", doc.ID, content, embedding, len(content)/4)
 
 	spec := types.DefaultQuerySpec()
 	spec.QueryVector = embedding
-	spec.KHops = 2
-	spec.SearchTypes = []types.SearchType{types.SearchTypeEntity}
+	spec.SearchTypes = []types.SearchType{types.SearchTypeTextUnit}
 
 	result, err := e.Query(testSessionID, spec)
 	if err != nil {
-		t.Fatalf("Query with graph expansion failed: %v", err)
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.TextUnits) != 1 {
+		t.Fatalf("Expected 1 text unit, got %d", len(result.TextUnits))
+	}
+	if result.TextUnits[0].TokenCount != 0 || result.TextUnits[0].ContentLength != 0 {
+		t.Error("Text stats should be omitted by default")
 	}
 
-	// Should find entities via graph traversal
-	if result.QueryID == 0 {
-		t.Error("QueryID should not be 0")
+	spec.IncludeTextStats = true
+	result, err = e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	tur := result.TextUnits[0]
+	if tur.TokenCount != len(content)/4 {
+		t.Errorf("Expected TokenCount %d, got %d", len(content)/4, tur.TokenCount)
+	}
+	if tur.ContentLength != len(content) {
+		t.Errorf("Expected ContentLength %d, got %d", len(content), tur.ContentLength)
 	}
 }
 
@@ -460,7 +2137,7 @@ func TestEngine_Explain(t *testing.T) {
 		t.Fatalf("Query failed: %v", err)
 	}
 
-	explain, ok := e.Explain(result.QueryID)
+	explain, ok := e.Explain(testSessionID, result.QueryID)
 	if !ok {
 		t.Error("Explain should return true for valid QueryID")
 	}
@@ -473,12 +2150,179 @@ func TestEngine_Explain(t *testing.T) {
 func TestEngine_Explain_NotFound(t *testing.T) {
 	e := createTestEngine()
 
-	_, ok := e.Explain(99999)
+	_, ok := e.Explain(testSessionID, 99999)
 	if ok {
 		t.Error("Explain should return false for non-existent QueryID")
 	}
 }
 
+func TestEngine_QueryCache(t *testing.T) {
+	e := createTestEngine()
+	e.SetQueryCache(10, time.Minute)
+
+	embedding := randomVector(testVectorDim)
+	ent := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc 1", embedding)
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = embedding
+
+	first, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if first.Stats.CacheHit {
+		t.Error("First query should not be a cache hit")
+	}
+
+	// Same vector and spec; the deadline does not change the result
+	spec.DeadlineMs = 500
+	second, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if !second.Stats.CacheHit {
+		t.Error("Repeated query should be a cache hit")
+	}
+	if second.QueryID == first.QueryID || len(second.Entities) != 1 || second.Entities[0].Entity.ID != ent.ID {
+		t.Errorf("Unexpected cached result: %+v", second)
+	}
+	if first.Stats.CacheHit {
+		t.Error("Serving a cache hit should not modify the earlier result")
+	}
+	if _, ok := e.Explain(testSessionID, second.QueryID); !ok {
+		t.Error("Cached query should be explainable")
+	}
+	if summary := e.QueryStatsSummary(0, testSessionID); summary.CacheHits != 1 || summary.CacheHitRate != 0.5 {
+		t.Errorf("QueryStatsSummary cache hits = %d (rate %v), want 1 (0.5)", summary.CacheHits, summary.CacheHitRate)
+	}
+
+	// Another session with the same query is computed separately
+	if other, err := e.Query("other-session", spec); err == nil && other.Stats.CacheHit {
+		t.Error("Query in another session should not hit the cache")
+	}
+
+	// A write to the session invalidates its cached results
+	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc 2", embedding)
+	third, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if third.Stats.CacheHit || len(third.Entities) != 2 {
+		t.Errorf("Query after a write should be recomputed, got hit=%v with %d entities", third.Stats.CacheHit, len(third.Entities))
+	}
+	e.DeleteEntity(testSessionID, ent2.ID)
+	if fourth, _ := e.Query(testSessionID, spec); fourth.Stats.CacheHit {
+		t.Error("Query after a delete should be recomputed")
+	}
+
+	// Entries expire after the TTL
+	e.SetQueryCache(10, 20*time.Millisecond)
+	if _, err := e.Query(testSessionID, spec); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if hit, _ := e.Query(testSessionID, spec); !hit.Stats.CacheHit {
+		t.Error("Query within the TTL should be a cache hit")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if expired, _ := e.Query(testSessionID, spec); expired.Stats.CacheHit {
+		t.Error("Query after the TTL should be recomputed")
+	}
+
+	// Disabled cache never hits
+	e.SetQueryCache(0, 0)
+	if _, err := e.Query(testSessionID, spec); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if again, _ := e.Query(testSessionID, spec); again.Stats.CacheHit {
+		t.Error("Query with the cache disabled should not hit")
+	}
+}
+
+func TestEngine_SessionIsolation(t *testing.T) {
+	e := createTestEngine()
+	const sessA, sessB = "isolation-a", "isolation-b"
+
+	// Same external IDs in both sessions; A gets one more entity and a
+	// relationship, so its highest IDs do not exist in B
+	docA := mustAddDocument(t, e, sessA, "doc-1", "a.txt")
+	docB := mustAddDocument(t, e, sessB, "doc-1", "b.txt")
+	tuA := mustAddTextUnit(t, e, sessA, "tu-1", docA.ID, "content of A", randomVector(testVectorDim), 3)
+	tuB := mustAddTextUnit(t, e, sessB, "tu-1", docB.ID, "content of B", randomVector(testVectorDim), 3)
+	entA := mustAddEntity(t, e, sessA, "ent-1", "Alpha", "test", "only in A", randomVector(testVectorDim))
+	entB := mustAddEntity(t, e, sessB, "ent-1", "Beta", "test", "only in B", randomVector(testVectorDim))
+	entA2 := mustAddEntity(t, e, sessA, "ent-2", "Gamma", "test", "second in A", randomVector(testVectorDim))
+	relA := mustAddRelationship(t, e, sessA, "rel-1", entA.ID, entA2.ID, "RELATED", "", 1)
+
+	if docA.ID != docB.ID || tuA.ID != tuB.ID || entA.ID != entB.ID {
+		t.Fatalf("sessions should allocate IDs independently: doc %d/%d, tu %d/%d, entity %d/%d",
+			docA.ID, docB.ID, tuA.ID, tuB.ID, entA.ID, entB.ID)
+	}
+
+	// A colliding ID resolves to each session's own record
+	if doc, ok := e.GetDocument(sessB, docA.ID); !ok || doc.Filename != "b.txt" {
+		t.Errorf("session B document = %+v, want b.txt", doc)
+	}
+	if tu, ok := e.GetTextUnit(sessB, tuA.ID); !ok || tu.Content != "content of B" {
+		t.Errorf("session B text unit = %+v, want B's content", tu)
+	}
+	if ent, ok := e.GetEntity(sessA, entB.ID); !ok || ent.Title != "ALPHA" {
+		t.Errorf("session A entity = %+v, want ALPHA", ent)
+	}
+	if ent, ok := e.GetEntity(sessB, entA.ID); !ok || ent.Title != "BETA" {
+		t.Errorf("session B entity = %+v, want BETA", ent)
+	}
+	if _, ok := e.GetEntityByTitle(sessB, "Alpha"); ok {
+		t.Error("session B should not find session A's entity title")
+	}
+
+	// IDs that exist only in A are not found through B
+	if _, ok := e.GetEntity(sessB, entA2.ID); ok {
+		t.Error("session B should not see session A's entity")
+	}
+	if _, ok := e.GetRelationship(sessB, relA.ID); ok {
+		t.Error("session B should not see session A's relationship")
+	}
+	if _, err := e.GetNeighbors(sessB, entA2.ID, types.DirectionBoth, nil); err == nil {
+		t.Error("session B neighbors of session A's entity should fail")
+	}
+	if e.DeleteEntity(sessB, entA2.ID) {
+		t.Error("session B should not delete session A's entity")
+	}
+	if e.DeleteRelationship(sessB, relA.ID) {
+		t.Error("session B should not delete session A's relationship")
+	}
+	if e.LinkTextUnitToEntity(sessB, tuB.ID, entA2.ID) {
+		t.Error("session B should not link to session A's entity")
+	}
+	if _, ok := e.GetEntity(sessA, entA2.ID); !ok {
+		t.Error("session A entity should survive session B's delete")
+	}
+	if _, ok := e.GetRelationship(sessA, relA.ID); !ok {
+		t.Error("session A relationship should survive session B's delete")
+	}
+
+	// Deleting a colliding ID in B leaves A's record alone
+	if !e.DeleteEntity(sessB, entB.ID) {
+		t.Fatal("session B should delete its own entity")
+	}
+	if ent, ok := e.GetEntity(sessA, entA.ID); !ok || ent.Title != "ALPHA" {
+		t.Errorf("session A entity after B's delete = %+v, want ALPHA", ent)
+	}
+
+	// Query IDs are engine-wide, so explain must check the session
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = randomVector(testVectorDim)
+	result, err := e.Query(sessA, spec)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if _, ok := e.Explain(sessA, result.QueryID); !ok {
+		t.Error("session A should explain its own query")
+	}
+	if _, ok := e.Explain(sessB, result.QueryID); ok {
+		t.Error("session B should not explain session A's query")
+	}
+}
+
 // =============================================================================
 // Info Tests
 // =============================================================================
@@ -614,43 +2458,330 @@ func TestQueryLogLRU_Len(t *testing.T) {
 	}
 }
 
-// =============================================================================
-// Concurrent Tests
-// =============================================================================
-
-func TestEngine_ConcurrentAccess(t *testing.T) {
+// =============================================================================
+// Concurrent Tests
+// =============================================================================
+
+func TestEngine_ConcurrentAccess(t *testing.T) {
+	e := createTestEngine()
+
+	var wg sync.WaitGroup
+	const n = 20
+
+	// Concurrent document additions
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			mustAddDocument(t, e, testSessionID, "doc-"+itoa(id), "file.txt")
+		}(i)
+	}
+
+	// Concurrent entity additions
+	embedding := randomVector(testVectorDim)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			mustAddEntity(t, e, testSessionID, "ent-"+itoa(id), "Entity "+itoa(id), "test", "Desc", embedding)
+		}(i)
+	}
+
+	wg.Wait()
+
+	info := e.Info()
+	if info.DocumentCount != n {
+		t.Errorf("Expected %d documents, got %d", n, info.DocumentCount)
+	}
+	if info.EntityCount != n {
+		t.Errorf("Expected %d entities, got %d", n, info.EntityCount)
+	}
+}
+
+func TestEngine_ConcurrentSessionCreation(t *testing.T) {
+	e := createTestEngine()
+
+	const writers = 64
+	const perWriter = 25
+	sessionID := "fresh-session"
+
+	start := make(chan struct{})
+	errs := make(chan error, writers*perWriter)
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			<-start
+			for i := 0; i < perWriter; i++ {
+				if _, err := e.AddDocument(sessionID, fmt.Sprintf("doc-%d-%d", w, i), "file.txt"); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Concurrent write error: %v", err)
+	}
+
+	sessions := e.ListSessions()
+	if len(sessions) != 1 || sessions[0].ID != sessionID {
+		t.Fatalf("Expected exactly one session, got %v", sessions)
+	}
+	if sessions[0].DocumentCount != writers*perWriter {
+		t.Errorf("Expected %d documents, got %d", writers*perWriter, sessions[0].DocumentCount)
+	}
+}
+
+func TestEngine_ListSessionsFiltered(t *testing.T) {
+	e := createTestEngine()
+	for i := 0; i < 25; i++ {
+		mustAddDocument(t, e, fmt.Sprintf("tenant-a-%02d", i), "doc", "file.txt")
+		mustAddDocument(t, e, fmt.Sprintf("tenant-b-%02d", i), "doc", "file.txt")
+	}
+
+	filter := types.SessionFilter{Prefix: "tenant-a-"}
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("Paging did not terminate")
+		}
+		page, next := e.ListSessionsFiltered(filter, cursor, 10)
+		for _, info := range page {
+			if info.DocumentCount != 1 {
+				t.Errorf("Session %s has %d documents, want 1", info.ID, info.DocumentCount)
+			}
+			seen = append(seen, info.ID)
+		}
+		if next == "" {
+			break
+		}
+		if len(page) != 10 {
+			t.Errorf("Expected a full page before the last, got %d", len(page))
+		}
+		cursor = next
+	}
+	if len(seen) != 25 || !slices.IsSorted(seen) || seen[0] != "tenant-a-00" || seen[24] != "tenant-a-24" {
+		t.Errorf("Unexpected sessions: %v", seen)
+	}
+
+	// A page that ends exactly at the last match has no next cursor
+	if page, next := e.ListSessionsFiltered(filter, "tenant-a-14", 10); len(page) != 10 || next != "" {
+		t.Errorf("Expected a final page of 10, got %d with cursor %q", len(page), next)
+	}
+
+	cutoff := time.Now().UnixNano()
+	time.Sleep(time.Millisecond)
+	mustAddDocument(t, e, "tenant-a-late", "doc", "file.txt")
+	page, _ := e.ListSessionsFiltered(types.SessionFilter{Prefix: "tenant-", CreatedAfter: cutoff}, "", 0)
+	if len(page) != 1 || page[0].ID != "tenant-a-late" {
+		t.Errorf("Expected only tenant-a-late created after the cutoff, got %v", page)
+	}
+
+	visible := types.SessionFilter{Visible: func(id string) bool { return strings.HasSuffix(id, "-07") }}
+	if page, _ := e.ListSessionsFiltered(visible, "", 0); len(page) != 2 {
+		t.Errorf("Expected 2 visible sessions, got %d", len(page))
+	}
+}
+
+func TestEngine_SessionQuota(t *testing.T) {
+	e := createTestEngine()
+	e.SetMaxSessionBytes(16 * 1024)
+	embedding := randomVector(testVectorDim)
+
+	var ids []uint64
+	var err error
+	for len(ids) < 1000 {
+		var tu *types.TextUnit
+		tu, err = e.AddTextUnit("noisy", fmt.Sprintf("tu-%d", len(ids)), 0, strings.Repeat("x", 512), embedding, 100)
+		if err != nil {
+			break
+		}
+		ids = append(ids, tu.ID)
+	}
+	if !errors.Is(err, ErrSessionQuotaExceeded) {
+		t.Fatalf("Expected ErrSessionQuotaExceeded, got %v after %d adds", err, len(ids))
+	}
+	info, err := e.GetSessionInfo("noisy")
+	if err != nil {
+		t.Fatalf("GetSessionInfo failed: %v", err)
+	}
+	if info.MemoryBytes < 16*1024 || info.TextUnitCount != len(ids) {
+		t.Errorf("Unexpected info at quota: %+v", info)
+	}
+	if _, err := e.AddEntity("noisy", "ent-1", "Bank Indonesia", "organization", "", embedding); !errors.Is(err, ErrSessionQuotaExceeded) {
+		t.Errorf("Expected entity add to fail too, got %v", err)
+	}
+	if _, err := e.MSetDocuments("noisy", []types.BulkDocumentInput{{ExternalID: "doc", Filename: "a.txt"}}); !errors.Is(err, ErrSessionQuotaExceeded) {
+		t.Errorf("Expected bulk add to fail too, got %v", err)
+	}
+
+	// Other sessions have their own budget
+	if _, err := e.AddTextUnit("quiet", "tu-0", 0, strings.Repeat("x", 512), embedding, 100); err != nil {
+		t.Errorf("Add to another session failed: %v", err)
+	}
+
+	// Deleting data frees room again
+	for _, id := range ids {
+		e.DeleteTextUnit("noisy", id)
+	}
+	if _, err := e.AddEntity("noisy", "ent-1", "Bank Indonesia", "organization", "", embedding); err != nil {
+		t.Errorf("Add after freeing room failed: %v", err)
+	}
+}
+
+func TestEngine_FlushSession(t *testing.T) {
+	e := createTestEngine()
+	embedding := randomVector(testVectorDim)
+
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "a.txt")
+	tu := mustAddTextUnit(t, e, testSessionID, "tu-1", doc.ID, "Content", embedding, 10)
+	ent1 := mustAddEntity(t, e, testSessionID, "ent-1", "Entity 1", "test", "Desc", embedding)
+	ent2 := mustAddEntity(t, e, testSessionID, "ent-2", "Entity 2", "test", "Desc", embedding)
+	mustAddRelationship(t, e, testSessionID, "rel-1", ent1.ID, ent2.ID, "RELATED", "", 1)
+	mustAddCommunity(t, e, testSessionID, "comm-1", "Community", "Summary", "Full", 0, []uint64{ent1.ID, ent2.ID}, nil, embedding)
+	e.LinkTextUnitToEntity(testSessionID, tu.ID, ent1.ID)
+	if err := e.SetSessionTTL(testSessionID, int64(time.Hour), int64(10*time.Minute)); err != nil {
+		t.Fatalf("SetSessionTTL failed: %v", err)
+	}
+	if err := e.SetSessionMetadata(testSessionID, map[string]string{"tenant": "acme"}, false); err != nil {
+		t.Fatalf("SetSessionMetadata failed: %v", err)
+	}
+
+	if err := e.FlushSession(testSessionID); err != nil {
+		t.Fatalf("FlushSession failed: %v", err)
+	}
+
+	info, err := e.GetSessionInfo(testSessionID)
+	if err != nil {
+		t.Fatalf("Session should still exist after flush: %v", err)
+	}
+	if info.DocumentCount != 0 || info.TextUnitCount != 0 || info.EntityCount != 0 ||
+		info.RelationshipCount != 0 || info.CommunityCount != 0 || info.MemoryBytes != 0 {
+		t.Errorf("Expected an empty session, got %+v", info)
+	}
+	if info.TTL != int64(time.Hour) || info.IdleTTL != int64(10*time.Minute) || info.Metadata["tenant"] != "acme" {
+		t.Errorf("Flush should keep TTL and metadata, got %+v", info)
+	}
+	if _, ok := e.GetEntityByTitle(testSessionID, "Entity 1"); ok {
+		t.Error("Flushed entity should be gone")
+	}
+
+	// The session accepts writes, and its indices are rebuilt on demand
+	ent := mustAddEntity(t, e, testSessionID, "ent-1", "Entity 1", "test", "Desc", embedding)
+	spec := types.DefaultQuerySpec()
+	spec.QueryVector = embedding
+	result, err := e.Query(testSessionID, spec)
+	if err != nil {
+		t.Fatalf("Query after flush failed: %v", err)
+	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ID != ent.ID {
+		t.Errorf("Expected the new entity from query, got %+v", result.Entities)
+	}
+
+	if err := e.FlushSession("missing-session"); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestEngine_SessionEvictionCallback(t *testing.T) {
+	e := createTestEngine()
+	var mu sync.Mutex
+	var evicted []string
+	var exported bytes.Buffer
+	e.SetSessionEvictionCallback(func(sessionID string, info types.SessionInfo) {
+		mu.Lock()
+		defer mu.Unlock()
+		evicted = append(evicted, sessionID)
+		if info.ID != sessionID || info.DocumentCount != 1 {
+			t.Errorf("Unexpected info for %s: %+v", sessionID, info)
+		}
+		// The session is still there to be saved
+		if err := e.ExportSession(sessionID, &exported); err != nil {
+			t.Errorf("ExportSession during eviction failed: %v", err)
+		}
+	})
+	evictedSoFar := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(evicted)
+	}
+	expireSoon := func(sessionID string) {
+		t.Helper()
+		mustAddDocument(t, e, sessionID, "doc-1", "a.txt")
+		if err := e.SetSessionTTL(sessionID, int64(time.Millisecond), 0); err != nil {
+			t.Fatalf("SetSessionTTL failed: %v", err)
+		}
+	}
+
+	// A write finding the session expired evicts it
+	expireSoon("written")
+	time.Sleep(5 * time.Millisecond)
+	if _, err := e.AddDocument("written", "doc-2", "b.txt"); !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("Expected ErrSessionExpired, got %v", err)
+	}
+	if got := evictedSoFar(); !slices.Equal(got, []string{"written"}) {
+		t.Fatalf("Expected written to be evicted by the write, got %v", got)
+	}
+
+	// So does the cleanup loop, leaving live sessions alone
+	expireSoon("short-lived")
+	mustAddDocument(t, e, "long-lived", "doc-1", "a.txt")
+	e.StartSessionCleanup(5 * time.Millisecond)
+	defer e.StopSessionCleanup()
+	deadline := time.Now().Add(2 * time.Second)
+	for (len(evictedSoFar()) < 2 || e.SessionCount() != 1) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := evictedSoFar(); !slices.Equal(got, []string{"written", "short-lived"}) {
+		t.Fatalf("Expected short-lived to be evicted by the cleanup loop, got %v", got)
+	}
+	if _, err := e.GetSessionInfo("short-lived"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected short-lived to be gone, got %v", err)
+	}
+	if _, err := e.GetSessionInfo("long-lived"); err != nil {
+		t.Errorf("long-lived should survive cleanup: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if exported.Len() == 0 {
+		t.Error("Nothing exported during eviction")
+	}
+}
+
+func TestEngine_RecreateExpiredSessions(t *testing.T) {
 	e := createTestEngine()
 
-	var wg sync.WaitGroup
-	const n = 20
-
-	// Concurrent document additions
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			mustAddDocument(t, e, testSessionID, "doc-"+itoa(id), "file.txt")
-		}(i)
+	expire := func() {
+		t.Helper()
+		mustAddDocument(t, e, testSessionID, "doc-old", "old.txt")
+		if err := e.SetSessionTTL(testSessionID, int64(time.Millisecond), 0); err != nil {
+			t.Fatalf("SetSessionTTL failed: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
 	}
 
-	// Concurrent entity additions
-	embedding := randomVector(testVectorDim)
-	for i := 0; i < n; i++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			mustAddEntity(t, e, testSessionID, "ent-"+itoa(id), "Entity "+itoa(id), "test", "Desc", embedding)
-		}(i)
+	expire()
+	if _, err := e.AddDocument(testSessionID, "doc-new", "new.txt"); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("Expected ErrSessionExpired by default, got %v", err)
 	}
 
-	wg.Wait()
-
-	info := e.Info()
-	if info.DocumentCount != n {
-		t.Errorf("Expected %d documents, got %d", n, info.DocumentCount)
+	e.SetRecreateExpiredSessions(true)
+	expire()
+	if _, err := e.AddDocument(testSessionID, "doc-new", "new.txt"); err != nil {
+		t.Fatalf("Expected write to recreate the session, got %v", err)
 	}
-	if info.EntityCount != n {
-		t.Errorf("Expected %d entities, got %d", n, info.EntityCount)
+	info, err := e.GetSessionInfo(testSessionID)
+	if err != nil {
+		t.Fatalf("GetSessionInfo failed: %v", err)
+	}
+	if info.DocumentCount != 1 {
+		t.Errorf("Expected fresh session with 1 document, got %d", info.DocumentCount)
 	}
 }
 
@@ -809,6 +2940,112 @@ func TestEngine_AddCommunity_Duplicate(t *testing.T) {
 	}
 }
 
+func TestEngine_MLinkTextUnitsToEntities(t *testing.T) {
+	e := createTestEngine()
+
+	doc := mustAddDocument(t, e, testSessionID, "ext-doc-1", "test.txt")
+	embedding := randomVector(testVectorDim)
+	tu1 := mustAddTextUnit(t, e, testSessionID, "ext-tu-1", doc.ID, "Content 1", embedding, 10)
+	tu2 := mustAddTextUnit(t, e, testSessionID, "ext-tu-2", doc.ID, "Content 2", embedding, 10)
+	ent1 := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc", embedding)
+	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc", embedding)
+
+	links := []types.BulkLinkInput{
+		{TextUnitID: tu1.ID, EntityID: ent1.ID},
+		{TextUnitID: tu1.ID, EntityID: 9999},
+		{TextUnitID: tu2.ID, EntityID: ent2.ID},
+	}
+
+	// Stop at first failure
+	results, err := e.MLinkTextUnitsToEntities(testSessionID, links, false)
+	if err != nil {
+		t.Fatalf("MLinkTextUnitsToEntities failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 attempted pairs, got %d", len(results))
+	}
+	if results[0].Error != "" || results[1].Error == "" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+	if ent, _ := e.GetEntity(testSessionID, ent2.ID); len(ent.TextUnitIDs) != 0 {
+		t.Error("Pair after failure should not be applied")
+	}
+
+	// Continue past failures
+	results, err = e.MLinkTextUnitsToEntities(testSessionID, links, true)
+	if err != nil {
+		t.Fatalf("MLinkTextUnitsToEntities failed: %v", err)
+	}
+	if len(results) != 3 || results[2].Error != "" {
+		t.Errorf("Unexpected results: %+v", results)
+	}
+	if ent, _ := e.GetEntity(testSessionID, ent2.ID); len(ent.TextUnitIDs) != 1 || ent.TextUnitIDs[0] != tu2.ID {
+		t.Errorf("Expected ent2 linked to tu2, got %v", ent.TextUnitIDs)
+	}
+
+	if _, err := e.MLinkTextUnitsToEntities("missing-session", links, true); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestEngine_MDelete(t *testing.T) {
+	e := createTestEngine()
+
+	doc := mustAddDocument(t, e, testSessionID, "ext-doc-1", "test.txt")
+	embedding := randomVector(testVectorDim)
+	tu1 := mustAddTextUnit(t, e, testSessionID, "ext-tu-1", doc.ID, "Content 1", embedding, 10)
+	tu2 := mustAddTextUnit(t, e, testSessionID, "ext-tu-2", doc.ID, "Content 2", embedding, 10)
+	ent1 := mustAddEntity(t, e, testSessionID, "ext-ent-1", "Entity 1", "test", "Desc", embedding)
+	ent2 := mustAddEntity(t, e, testSessionID, "ext-ent-2", "Entity 2", "test", "Desc", embedding)
+	ent3 := mustAddEntity(t, e, testSessionID, "ext-ent-3", "Entity 3", "test", "Desc", embedding)
+	rel12 := mustAddRelationship(t, e, testSessionID, "ext-rel-12", ent1.ID, ent2.ID, "RELATED", "", 1)
+	rel23 := mustAddRelationship(t, e, testSessionID, "ext-rel-23", ent2.ID, ent3.ID, "RELATED", "", 1)
+	rel31 := mustAddRelationship(t, e, testSessionID, "ext-rel-31", ent3.ID, ent1.ID, "RELATED", "", 1)
+	e.LinkTextUnitToEntity(testSessionID, tu1.ID, ent1.ID)
+	e.LinkTextUnitToEntity(testSessionID, tu1.ID, ent3.ID)
+
+	// Unknown IDs are skipped and not counted
+	deleted, err := e.MDeleteRelationships(testSessionID, []uint64{rel31.ID, 9999})
+	if err != nil || deleted != 1 {
+		t.Fatalf("MDeleteRelationships = %d, %v; want 1", deleted, err)
+	}
+	if _, ok := e.GetRelationship(testSessionID, rel31.ID); ok {
+		t.Error("rel31 should be deleted")
+	}
+
+	// Deleting entities cascades to their relationships and text unit links
+	deleted, err = e.MDeleteEntities(testSessionID, []uint64{ent1.ID, ent2.ID, ent1.ID, 9999})
+	if err != nil || deleted != 2 {
+		t.Fatalf("MDeleteEntities = %d, %v; want 2", deleted, err)
+	}
+	for _, id := range []uint64{rel12.ID, rel23.ID} {
+		if _, ok := e.GetRelationship(testSessionID, id); ok {
+			t.Errorf("relationship %d should be deleted with its entities", id)
+		}
+	}
+	if n, _ := e.Count(testSessionID, types.ItemTypeRelationship); n != 0 {
+		t.Errorf("relationship count = %d, want 0", n)
+	}
+	if rels, err := e.GetNeighbors(testSessionID, ent3.ID, types.DirectionBoth, nil); err != nil || len(rels) != 0 {
+		t.Errorf("ent3 neighbors = %v, %v; want none", rels, err)
+	}
+	if tu, _ := e.GetTextUnit(testSessionID, tu1.ID); !slices.Equal(tu.EntityIDs, []uint64{ent3.ID}) {
+		t.Errorf("tu1 entity links = %v, want [%d]", tu.EntityIDs, ent3.ID)
+	}
+
+	deleted, err = e.MDeleteTextUnits(testSessionID, []uint64{tu1.ID, tu2.ID})
+	if err != nil || deleted != 2 {
+		t.Fatalf("MDeleteTextUnits = %d, %v; want 2", deleted, err)
+	}
+	if n, _ := e.Count(testSessionID, types.ItemTypeTextUnit); n != 0 {
+		t.Errorf("text unit count = %d, want 0", n)
+	}
+
+	if _, err := e.MDeleteEntities("missing-session", []uint64{ent3.ID}); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
 func TestEngine_LinkTextUnitToEntity_NotFound(t *testing.T) {
 	e := createTestEngine()
 
@@ -967,6 +3204,55 @@ func TestEngine_ListEntitiesPagination(t *testing.T) {
 	}
 }
 
+func TestEngine_ListEntitiesByType(t *testing.T) {
+	e := createTestEngine()
+
+	// Every third entity is a concept, with a run of non-matches at the end
+	var concepts []uint64
+	for i := 0; i < 15; i++ {
+		entType := "person"
+		if i%3 == 0 && i < 12 {
+			entType = "concept"
+		}
+		ent := mustAddEntity(t, e, testSessionID, fmt.Sprintf("ent-%d", i), fmt.Sprintf("Entity %d", i), entType, "desc", nil)
+		if entType == "concept" {
+			concepts = append(concepts, ent.ID)
+		}
+	}
+
+	var listed []uint64
+	var pages int
+	cursor := uint64(0)
+	for {
+		entities, next := e.ListEntitiesByType(testSessionID, "concept", cursor, 3)
+		pages++
+		for _, ent := range entities {
+			if ent.Type != "concept" {
+				t.Errorf("Listed entity %d of type %s", ent.ID, ent.Type)
+			}
+			listed = append(listed, ent.ID)
+		}
+		if next == 0 {
+			break
+		}
+		if next <= cursor {
+			t.Fatalf("Cursor did not advance: %d -> %d", cursor, next)
+		}
+		cursor = next
+	}
+
+	if !slices.Equal(listed, concepts) {
+		t.Errorf("Listed %v, want %v", listed, concepts)
+	}
+	if pages != 2 {
+		t.Errorf("Listed concepts in %d pages, want 2", pages)
+	}
+
+	if entities, next := e.ListEntitiesByType(testSessionID, "place", 0, 3); len(entities) != 0 || next != 0 {
+		t.Errorf("Expected no places, got %d (cursor %d)", len(entities), next)
+	}
+}
+
 func TestEngine_ListRelationshipsPagination(t *testing.T) {
 	e := createTestEngine()
 
@@ -998,6 +3284,470 @@ func TestEngine_ListRelationshipsPagination(t *testing.T) {
 	}
 }
 
+func TestEngine_ListRelationshipsFiltered(t *testing.T) {
+	e := createTestEngine()
+
+	ojk := mustAddEntity(t, e, testSessionID, "ent-ojk", "OJK", "regulator", "desc", nil)
+	bi := mustAddEntity(t, e, testSessionID, "ent-bi", "BI", "regulator", "desc", nil)
+	bri := mustAddEntity(t, e, testSessionID, "ent-bri", "BRI", "bank", "desc", nil)
+	bca := mustAddEntity(t, e, testSessionID, "ent-bca", "BCA", "bank", "desc", nil)
+	mandiri := mustAddEntity(t, e, testSessionID, "ent-mandiri", "Mandiri", "bank", "desc", nil)
+
+	r1 := mustAddRelationship(t, e, testSessionID, "", ojk.ID, bri.ID, "SUPERVISES", "desc", 1.0)
+	r2 := mustAddRelationship(t, e, testSessionID, "", ojk.ID, bca.ID, "SUPERVISES", "desc", 1.0)
+	r3 := mustAddRelationship(t, e, testSessionID, "", bi.ID, bri.ID, "REGULATES", "desc", 1.0)
+	r4 := mustAddRelationship(t, e, testSessionID, "", ojk.ID, mandiri.ID, "SUPERVISES", "desc", 1.0)
+	r5 := mustAddRelationship(t, e, testSessionID, "", bri.ID, bca.ID, "PARTNERS_WITH", "desc", 1.0)
+	r6 := mustAddRelationship(t, e, testSessionID, "", ojk.ID, bi.ID, "COORDINATES_WITH", "desc", 1.0)
+
+	// listAll pages through with a small limit to exercise the cursor
+	listAll := func(filter types.RelationshipFilter) []uint64 {
+		t.Helper()
+		var ids []uint64
+		cursor := uint64(0)
+		for {
+			rels, next := e.ListRelationshipsFiltered(testSessionID, filter, cursor, 2)
+			for _, rel := range rels {
+				ids = append(ids, rel.ID)
+			}
+			if next == 0 {
+				return ids
+			}
+			cursor = next
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter types.RelationshipFilter
+		want   []uint64
+	}{
+		{"none", types.RelationshipFilter{}, []uint64{r1.ID, r2.ID, r3.ID, r4.ID, r5.ID, r6.ID}},
+		{"type", types.RelationshipFilter{Type: "SUPERVISES"}, []uint64{r1.ID, r2.ID, r4.ID}},
+		{"source", types.RelationshipFilter{SourceID: ojk.ID}, []uint64{r1.ID, r2.ID, r4.ID, r6.ID}},
+		{"target", types.RelationshipFilter{TargetID: bri.ID}, []uint64{r1.ID, r3.ID}},
+		{"type and source", types.RelationshipFilter{Type: "COORDINATES_WITH", SourceID: ojk.ID}, []uint64{r6.ID}},
+		{"source and target", types.RelationshipFilter{SourceID: bri.ID, TargetID: bca.ID}, []uint64{r5.ID}},
+		{"no match", types.RelationshipFilter{Type: "SUPERVISES", TargetID: bi.ID}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listAll(tt.filter); !slices.Equal(got, tt.want) {
+				t.Errorf("ListRelationshipsFiltered(%+v) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEngine_RelationshipTypeStats(t *testing.T) {
+	e := createTestEngine()
+
+	e1 := mustAddEntity(t, e, testSessionID, "ent-001", "Entity 1", "person", "desc", nil)
+	e2 := mustAddEntity(t, e, testSessionID, "ent-002", "Entity 2", "person", "desc", nil)
+	e3 := mustAddEntity(t, e, testSessionID, "ent-003", "Entity 3", "person", "desc", nil)
+
+	mustAddRelationship(t, e, testSessionID, "rel-001", e1.ID, e2.ID, "KNOWS", "desc", 1.0)
+	mustAddRelationship(t, e, testSessionID, "rel-002", e2.ID, e3.ID, "KNOWS", "desc", 0.5)
+	mustAddRelationship(t, e, testSessionID, "rel-003", e3.ID, e1.ID, "WORKS_WITH", "desc", 0.8)
+
+	stats, err := e.RelationshipTypeStats(testSessionID)
+	if err != nil {
+		t.Fatalf("RelationshipTypeStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 types, got %d", len(stats))
+	}
+	if stats[0].Type != "KNOWS" || stats[0].Count != 2 || stats[0].AvgWeight != 0.75 {
+		t.Errorf("Unexpected top stat: %+v", stats[0])
+	}
+	if stats[1].Type != "WORKS_WITH" || stats[1].Count != 1 {
+		t.Errorf("Unexpected second stat: %+v", stats[1])
+	}
+
+	if _, err := e.RelationshipTypeStats("missing-session"); err == nil {
+		t.Error("Expected error for missing session")
+	}
+}
+
+func TestEngine_Count(t *testing.T) {
+	e := createTestEngine()
+
+	assertCount := func(itemType types.ItemType, want int) {
+		t.Helper()
+		got, err := e.Count(testSessionID, itemType)
+		if err != nil {
+			t.Fatalf("Count(%s) failed: %v", itemType, err)
+		}
+		if got != want {
+			t.Errorf("Count(%s) = %d, want %d", itemType, got, want)
+		}
+	}
+
+	doc := mustAddDocument(t, e, testSessionID, "doc-1", "a.txt")
+	mustAddDocument(t, e, testSessionID, "doc-2", "b.txt")
+	tu := mustAddTextUnit(t, e, testSessionID, "tu-1", doc.ID, "content", nil, 1)
+	mustAddTextUnit(t, e, testSessionID, "tu-2", doc.ID, "content", nil, 1)
+	e1 := mustAddEntity(t, e, testSessionID, "ent-1", "Entity 1", "person", "desc", nil)
+	e2 := mustAddEntity(t, e, testSessionID, "ent-2", "Entity 2", "person", "desc", nil)
+	rel := mustAddRelationship(t, e, testSessionID, "rel-1", e1.ID, e2.ID, "KNOWS", "desc", 1.0)
+	comm := mustAddCommunity(t, e, testSessionID, "comm-1", "Community", "summary", "full", 0, []uint64{e1.ID, e2.ID}, []uint64{rel.ID}, nil)
+
+	assertCount(types.ItemTypeDocument, 2)
+	assertCount(types.ItemTypeTextUnit, 2)
+	assertCount(types.ItemTypeEntity, 2)
+	assertCount(types.ItemTypeRelationship, 1)
+	assertCount(types.ItemTypeCommunity, 1)
+
+	e.DeleteCommunity(testSessionID, comm.ID)
+	e.DeleteRelationship(testSessionID, rel.ID)
+	e.DeleteEntity(testSessionID, e2.ID)
+	e.DeleteTextUnit(testSessionID, tu.ID)
+	e.DeleteDocument(testSessionID, doc.ID)
+
+	assertCount(types.ItemTypeDocument, 1)
+	assertCount(types.ItemTypeTextUnit, 1)
+	assertCount(types.ItemTypeEntity, 1)
+	assertCount(types.ItemTypeRelationship, 0)
+	assertCount(types.ItemTypeCommunity, 0)
+
+	if _, err := e.Count(testSessionID, "widget"); err == nil {
+		t.Error("Expected error for unknown item type")
+	}
+	if _, err := e.Count("missing-session", types.ItemTypeEntity); err == nil {
+		t.Error("Expected error for missing session")
+	}
+}
+
+func TestEngine_TypeCounts(t *testing.T) {
+	e := createTestEngine()
+
+	ojk := mustAddEntity(t, e, testSessionID, "ent-ojk", "OJK", "regulator", "desc", nil)
+	bi := mustAddEntity(t, e, testSessionID, "ent-bi", "BI", "regulator", "desc", nil)
+	bri := mustAddEntity(t, e, testSessionID, "ent-bri", "BRI", "organization", "desc", nil)
+	bca := mustAddEntity(t, e, testSessionID, "ent-bca", "BCA", "organization", "desc", nil)
+	bca2 := mustAddEntity(t, e, testSessionID, "ent-bca2", "Bank Central Asia", "organization", "desc", nil)
+	mustAddEntity(t, e, testSessionID, "ent-perry", "Perry Warjiyo", "person", "desc", nil)
+	mustAddEntity(t, e, testSessionID, "ent-qris", "QRIS", "concept", "desc", nil)
+
+	mustAddRelationship(t, e, testSessionID, "", ojk.ID, bri.ID, "SUPERVISES", "desc", 1.0)
+	mustAddRelationship(t, e, testSessionID, "", ojk.ID, bca.ID, "SUPERVISES", "desc", 1.0)
+	mustAddRelationship(t, e, testSessionID, "", ojk.ID, bca2.ID, "SUPERVISES", "desc", 1.0)
+	rel := mustAddRelationship(t, e, testSessionID, "", bi.ID, bri.ID, "REGULATES", "desc", 1.0)
+
+	assertCounts := func(wantEntities, wantRels map[string]int) {
+		t.Helper()
+		entityTypes, err := e.EntityTypeCounts(testSessionID)
+		if err != nil {
+			t.Fatalf("EntityTypeCounts failed: %v", err)
+		}
+		relTypes, err := e.RelationshipTypeCounts(testSessionID)
+		if err != nil {
+			t.Fatalf("RelationshipTypeCounts failed: %v", err)
+		}
+		if !reflect.DeepEqual(entityTypes, wantEntities) {
+			t.Errorf("EntityTypeCounts = %v, want %v", entityTypes, wantEntities)
+		}
+		if !reflect.DeepEqual(relTypes, wantRels) {
+			t.Errorf("RelationshipTypeCounts = %v, want %v", relTypes, wantRels)
+		}
+	}
+
+	assertCounts(
+		map[string]int{"regulator": 2, "organization": 3, "person": 1, "concept": 1},
+		map[string]int{"SUPERVISES": 3, "REGULATES": 1},
+	)
+
+	// Merging drops the duplicate entity and its now-duplicate edge
+	if err := e.MergeEntities(testSessionID, bca.ID, bca2.ID); err != nil {
+		t.Fatalf("MergeEntities failed: %v", err)
+	}
+	e.DeleteRelationship(testSessionID, rel.ID)
+	e.DeleteEntity(testSessionID, bi.ID)
+	assertCounts(
+		map[string]int{"regulator": 1, "organization": 2, "person": 1, "concept": 1},
+		map[string]int{"SUPERVISES": 2},
+	)
+
+	if _, err := e.EntityTypeCounts("missing-session"); err == nil {
+		t.Error("Expected error for missing session")
+	}
+}
+
+func TestEngine_GetNeighbors(t *testing.T) {
+	e := createTestEngine()
+
+	ojk := mustAddEntity(t, e, testSessionID, "ent-ojk", "OJK", "regulator", "desc", nil)
+	bri := mustAddEntity(t, e, testSessionID, "ent-bri", "BRI", "bank", "desc", nil)
+	bca := mustAddEntity(t, e, testSessionID, "ent-bca", "BCA", "bank", "desc", nil)
+	bi := mustAddEntity(t, e, testSessionID, "ent-bi", "BI", "regulator", "desc", nil)
+	lonely := mustAddEntity(t, e, testSessionID, "ent-lonely", "Lonely", "bank", "desc", nil)
+
+	r1 := mustAddRelationship(t, e, testSessionID, "rel-001", ojk.ID, bri.ID, "SUPERVISES", "desc", 1.0)
+	r2 := mustAddRelationship(t, e, testSessionID, "rel-002", ojk.ID, bca.ID, "SUPERVISES", "desc", 1.0)
+	r3 := mustAddRelationship(t, e, testSessionID, "rel-003", bi.ID, ojk.ID, "COORDINATES_WITH", "desc", 1.0)
+	r4 := mustAddRelationship(t, e, testSessionID, "rel-004", ojk.ID, ojk.ID, "REVIEWS", "desc", 1.0)
+
+	relIDs := func(rels []*types.Relationship) []uint64 {
+		ids := make([]uint64, len(rels))
+		for i, rel := range rels {
+			ids[i] = rel.ID
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name      string
+		direction types.Direction
+		relTypes  []string
+		want      []uint64
+	}{
+		{"both", types.DirectionBoth, nil, []uint64{r1.ID, r2.ID, r3.ID, r4.ID}},
+		{"outgoing", types.DirectionOutgoing, nil, []uint64{r1.ID, r2.ID, r4.ID}},
+		{"incoming", types.DirectionIncoming, nil, []uint64{r3.ID, r4.ID}},
+		{"outgoing by type", types.DirectionOutgoing, []string{"SUPERVISES"}, []uint64{r1.ID, r2.ID}},
+		{"incoming by type", types.DirectionIncoming, []string{"SUPERVISES"}, []uint64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rels, err := e.GetNeighbors(testSessionID, ojk.ID, tt.direction, tt.relTypes)
+			if err != nil {
+				t.Fatalf("GetNeighbors failed: %v", err)
+			}
+			if got := relIDs(rels); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetNeighbors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	rels, err := e.GetNeighbors(testSessionID, lonely.ID, types.DirectionBoth, nil)
+	if err != nil {
+		t.Fatalf("GetNeighbors failed: %v", err)
+	}
+	if len(rels) != 0 {
+		t.Errorf("Expected no relationships for isolated entity, got %d", len(rels))
+	}
+
+	if _, err := e.GetNeighbors(testSessionID, 99999, types.DirectionBoth, nil); !errors.Is(err, ErrEntityNotFound) {
+		t.Errorf("Expected ErrEntityNotFound, got %v", err)
+	}
+	if _, err := e.GetNeighbors("missing-session", ojk.ID, types.DirectionBoth, nil); err == nil {
+		t.Error("Expected error for missing session")
+	}
+}
+
+func TestEngine_Subgraph(t *testing.T) {
+	e := createTestEngine()
+
+	bi := mustAddEntity(t, e, testSessionID, "ent-bi", "Bank Indonesia", "organization", "Central bank", nil)
+	rate := mustAddEntity(t, e, testSessionID, "ent-rate", "BI Rate", "concept", "Policy rate", nil)
+	perry := mustAddEntity(t, e, testSessionID, "ent-perry", "Perry Warjiyo", "person", "Governor", nil)
+	ojk := mustAddEntity(t, e, testSessionID, "ent-ojk", "OJK", "organization", "Regulator", nil)
+	bri := mustAddEntity(t, e, testSessionID, "ent-bri", "BRI", "organization", "State bank", nil)
+
+	sets := mustAddRelationship(t, e, testSessionID, "rel-1", bi.ID, rate.ID, "SETS", "", 1.0)
+	governs := mustAddRelationship(t, e, testSessionID, "rel-2", perry.ID, bi.ID, "GOVERNS", "", 1.0)
+	coordinates := mustAddRelationship(t, e, testSessionID, "rel-3", bi.ID, ojk.ID, "COORDINATES_WITH", "", 1.0)
+	mustAddRelationship(t, e, testSessionID, "rel-4", ojk.ID, bri.ID, "SUPERVISES", "", 1.0)
+
+	titles := func(ents []*types.Entity) map[string]bool {
+		m := make(map[string]bool, len(ents))
+		for _, ent := range ents {
+			m[ent.Title] = true
+		}
+		return m
+	}
+
+	// 1-hop neighborhood of BANK INDONESIA, in both directions
+	ents, rels, err := e.Subgraph(testSessionID, []uint64{bi.ID}, 1, 0)
+	if err != nil {
+		t.Fatalf("Subgraph failed: %v", err)
+	}
+	want := map[string]bool{"BANK INDONESIA": true, "BI RATE": true, "PERRY WARJIYO": true, "OJK": true}
+	if got := titles(ents); !reflect.DeepEqual(got, want) {
+		t.Errorf("Subgraph entities = %v, want %v", got, want)
+	}
+	if ents[0].ID != bi.ID {
+		t.Errorf("Expected the seed first, got %s", ents[0].Title)
+	}
+	gotRels := make([]uint64, len(rels))
+	for i, rel := range rels {
+		gotRels[i] = rel.ID
+	}
+	if wantRels := []uint64{sets.ID, governs.ID, coordinates.ID}; !reflect.DeepEqual(gotRels, wantRels) {
+		t.Errorf("Subgraph relationships = %v, want %v", gotRels, wantRels)
+	}
+
+	// hops=0 returns just the seeds (unknown seeds skipped)
+	ents, rels, err = e.Subgraph(testSessionID, []uint64{bi.ID, 99999}, 0, 0)
+	if err != nil {
+		t.Fatalf("Subgraph failed: %v", err)
+	}
+	if len(ents) != 1 || len(rels) != 0 {
+		t.Errorf("Expected only the seed, got %d entities, %d relationships", len(ents), len(rels))
+	}
+
+	// maxNodes caps the result; edges only among returned entities
+	ents, rels, err = e.Subgraph(testSessionID, []uint64{bi.ID}, 3, 2)
+	if err != nil {
+		t.Fatalf("Subgraph failed: %v", err)
+	}
+	if len(ents) != 2 {
+		t.Fatalf("Expected 2 entities, got %d", len(ents))
+	}
+	kept := map[uint64]bool{ents[0].ID: true, ents[1].ID: true}
+	for _, rel := range rels {
+		if !kept[rel.SourceID] || !kept[rel.TargetID] {
+			t.Errorf("Relationship %d leaves the subgraph", rel.ID)
+		}
+	}
+
+	if _, _, err := e.Subgraph("missing-session", []uint64{bi.ID}, 1, 0); err == nil {
+		t.Error("Expected error for missing session")
+	}
+}
+
+func TestUpdateRelationshipWeight_MarksCommunitiesDirty(t *testing.T) {
+	e := NewEngine(testVectorDim)
+
+	a := mustAddEntity(t, e, testSessionID, "ent-a", "Alpha", "org", "", nil)
+	b := mustAddEntity(t, e, testSessionID, "ent-b", "Beta", "org", "", nil)
+	c := mustAddEntity(t, e, testSessionID, "ent-c", "Gamma", "org", "", nil)
+	rel := mustAddRelationship(t, e, testSessionID, "rel-ab", a.ID, b.ID, "OWNS", "", 1.0)
+	mustAddRelationship(t, e, testSessionID, "rel-bc", b.ID, c.ID, "OWNS", "", 1.0)
+
+	if dirty, _ := e.CommunitiesDirty(testSessionID); !dirty {
+		t.Error("Communities should be dirty before the first computation")
+	}
+	if _, err := e.ComputeCommunities(testSessionID, graph.DefaultLeidenConfig()); err != nil {
+		t.Fatalf("ComputeCommunities failed: %v", err)
+	}
+	if dirty, _ := e.CommunitiesDirty(testSessionID); dirty {
+		t.Error("Communities should be clean after ComputeCommunities")
+	}
+
+	if err := e.UpdateRelationshipWeight(testSessionID, rel.ID, 0.25); err != nil {
+		t.Fatalf("UpdateRelationshipWeight failed: %v", err)
+	}
+	got, _ := e.GetRelationship(testSessionID, rel.ID)
+	if got.Weight != 0.25 {
+		t.Errorf("Expected weight 0.25, got %v", got.Weight)
+	}
+	info, _ := e.GetSessionInfo(testSessionID)
+	if !info.CommunitiesDirty {
+		t.Error("Weight change should mark communities dirty")
+	}
+
+	if _, err := e.ComputeHierarchicalCommunities(testSessionID, graph.DefaultLeidenConfig()); err != nil {
+		t.Fatalf("ComputeHierarchicalCommunities failed: %v", err)
+	}
+	if dirty, _ := e.CommunitiesDirty(testSessionID); dirty {
+		t.Error("Communities should be clean after recomputation")
+	}
+
+	// Setting the same weight is not a change
+	if err := e.UpdateRelationshipWeight(testSessionID, rel.ID, 0.25); err != nil {
+		t.Fatalf("UpdateRelationshipWeight failed: %v", err)
+	}
+	if dirty, _ := e.CommunitiesDirty(testSessionID); dirty {
+		t.Error("Unchanged weight should not mark communities dirty")
+	}
+
+	for _, w := range []float32{0, -1, float32(math.NaN()), float32(math.Inf(1))} {
+		if err := e.UpdateRelationshipWeight(testSessionID, rel.ID, w); err == nil {
+			t.Errorf("Expected error for weight %v", w)
+		}
+	}
+	if err := e.UpdateRelationshipWeight(testSessionID, 99999, 1); err == nil {
+		t.Error("Expected error for non-existent relationship")
+	}
+	if err := e.UpdateRelationshipWeight("missing-session", rel.ID, 1); err == nil {
+		t.Error("Expected error for missing session")
+	}
+}
+
+func TestEngine_GetEntityCommunities(t *testing.T) {
+	e := createTestEngine()
+
+	// Three seeded clusters of six, dense inside and sparse between
+	rng := rand.New(rand.NewSource(42))
+	var entities []*types.Entity
+	for i := 0; i < 18; i++ {
+		entities = append(entities, mustAddEntity(t, e, testSessionID, fmt.Sprintf("ent-%d", i), fmt.Sprintf("Entity %d", i), "org", "desc", nil))
+	}
+	for i := 0; i < 18; i++ {
+		for j := i + 1; j < 18; j++ {
+			if i/6 == j/6 && rng.Float64() < 0.8 {
+				mustAddRelationship(t, e, testSessionID, "", entities[i].ID, entities[j].ID, "RELATED", "desc", 1.0)
+			} else if i/6 != j/6 && rng.Float64() < 0.05 {
+				mustAddRelationship(t, e, testSessionID, "", entities[i].ID, entities[j].ID, "RELATED", "desc", 0.2)
+			}
+		}
+	}
+
+	config := graph.DefaultLeidenConfig()
+	config.MaxLevels = 3
+	config.MinCommunitySize = 2
+	communities, err := e.ComputeHierarchicalCommunities(testSessionID, config)
+	if err != nil {
+		t.Fatalf("ComputeHierarchicalCommunities failed: %v", err)
+	}
+
+	// Expected memberships by scanning every community
+	want := make(map[uint64][]uint64)
+	for _, comm := range communities {
+		for _, id := range comm.EntityIDs {
+			want[id] = append(want[id], comm.ID)
+		}
+	}
+
+	for _, ent := range entities {
+		got, err := e.GetEntityCommunities(testSessionID, ent.ID)
+		if err != nil {
+			t.Fatalf("GetEntityCommunities(%d) failed: %v", ent.ID, err)
+		}
+		if len(got) != len(want[ent.ID]) {
+			t.Errorf("Entity %d: got %d communities, want %d", ent.ID, len(got), len(want[ent.ID]))
+		}
+		seenLevel := make(map[int]bool)
+		for i, comm := range got {
+			if !slices.Contains(comm.EntityIDs, ent.ID) {
+				t.Errorf("Community %d returned for entity %d does not contain it", comm.ID, ent.ID)
+			}
+			if seenLevel[comm.Level] {
+				t.Errorf("Entity %d is in two communities at level %d", ent.ID, comm.Level)
+			}
+			if i > 0 && got[i-1].Level > comm.Level {
+				t.Errorf("Entity %d memberships not ordered by level", ent.ID)
+			}
+			seenLevel[comm.Level] = true
+		}
+		if !seenLevel[0] {
+			t.Errorf("Entity %d has no level 0 community", ent.ID)
+		}
+	}
+
+	// Deleting a community removes it from the lookup
+	first, _ := e.GetEntityCommunities(testSessionID, entities[0].ID)
+	e.DeleteCommunity(testSessionID, first[0].ID)
+	after, _ := e.GetEntityCommunities(testSessionID, entities[0].ID)
+	if len(after) != len(first)-1 {
+		t.Errorf("Expected %d memberships after delete, got %d", len(first)-1, len(after))
+	}
+
+	// Recomputing replaces the old memberships
+	if _, err := e.ComputeCommunities(testSessionID, graph.DefaultLeidenConfig()); err != nil {
+		t.Fatalf("ComputeCommunities failed: %v", err)
+	}
+	flat, _ := e.GetEntityCommunities(testSessionID, entities[0].ID)
+	if len(flat) != 1 || flat[0].Level != 0 {
+		t.Errorf("Expected one level 0 community after flat recompute, got %v", flat)
+	}
+
+	if _, err := e.GetEntityCommunities(testSessionID, 99999); err == nil {
+		t.Error("Expected error for unknown entity")
+	}
+}
+
 func TestQueryLogLRU_Update(t *testing.T) {
 	cache := newQueryLogLRU(3)
 
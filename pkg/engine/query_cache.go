@@ -0,0 +1,109 @@
+// Package engine - Query result cache
+package engine
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gibram-io/gibram/pkg/store"
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// queryCacheKey identifies a query by session and a hash of its spec
+type queryCacheKey struct {
+	sessionID string
+	specHash  [sha256.Size]byte
+}
+
+// queryCacheEntry is one cached query result. It is only served for the same
+// session store at the same data version, so a write, a flush or a session
+// recreated under the same ID all miss.
+type queryCacheEntry struct {
+	key     queryCacheKey
+	sess    *store.SessionStore
+	version uint64
+	expires time.Time
+	pack    types.ContextPack // never modified once cached
+	qlog    *queryLog
+}
+
+// queryCache is an LRU of query results with a per-entry TTL
+type queryCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[queryCacheKey]*list.Element
+	order    *list.List // front = most recent, back = least recent
+}
+
+func newQueryCache(capacity int, ttl time.Duration) *queryCache {
+	return &queryCache{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[queryCacheKey]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// queryCacheKeyFor hashes the spec fields that decide a query's results.
+// DeadlineMs, PageSize and Cursor only shape how results are delivered.
+func queryCacheKeyFor(sessionID string, spec types.QuerySpec) (queryCacheKey, error) {
+	spec.DeadlineMs = 0
+	spec.PageSize = 0
+	spec.Cursor = ""
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return queryCacheKey{}, err
+	}
+	return queryCacheKey{sessionID: sessionID, specHash: sha256.Sum256(data)}, nil
+}
+
+// Get returns the entry for key if it was computed from sess at version and
+// has not expired
+func (c *queryCache) Get(key queryCacheKey, sess *store.SessionStore, version uint64) (*queryCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*queryCacheEntry)
+	if entry.sess != sess || entry.version != version || (c.ttl > 0 && time.Now().After(entry.expires)) {
+		delete(c.items, key)
+		c.order.Remove(elem)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+// Set caches a result computed from sess at version
+func (c *queryCache) Set(key queryCacheKey, sess *store.SessionStore, version uint64, pack *types.ContextPack, qlog *queryLog) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &queryCacheEntry{
+		key:     key,
+		sess:    sess,
+		version: version,
+		expires: time.Now().Add(c.ttl),
+		pack:    *pack,
+		qlog:    qlog,
+	}
+	if elem, ok := c.items[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	for c.order.Len() >= c.capacity {
+		back := c.order.Back()
+		delete(c.items, back.Value.(*queryCacheEntry).key)
+		c.order.Remove(back)
+	}
+	c.items[key] = c.order.PushFront(entry)
+}
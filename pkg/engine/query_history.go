@@ -0,0 +1,140 @@
+// Package engine - Per-session history of recent queries
+package engine
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// DefaultQueryHistorySize is how many recent queries each session keeps
+// until SetQueryHistorySize changes it
+const DefaultQueryHistorySize = 100
+
+// queryHistory keeps the most recent queries of each session with their
+// full results
+type queryHistory struct {
+	mu       sync.Mutex
+	size     int
+	sessions map[string][]queryHistoryEntry // oldest first
+}
+
+type queryHistoryEntry struct {
+	record types.QueryRecord
+	pack   *types.ContextPack // never modified once stored
+}
+
+func newQueryHistory(size int) *queryHistory {
+	return &queryHistory{size: size, sessions: make(map[string][]queryHistoryEntry)}
+}
+
+// add records a finished query, dropping the session's oldest beyond size.
+// The caller keeps pack, so a copy is stored.
+func (h *queryHistory) add(sessionID string, spec types.QuerySpec, pack *types.ContextPack) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.size <= 0 {
+		return
+	}
+
+	stored := *pack
+	spec.Cursor = ""
+	entries := append(h.sessions[sessionID], queryHistoryEntry{
+		record: types.QueryRecord{
+			QueryID:     pack.QueryID,
+			Spec:        spec,
+			Stats:       pack.Stats,
+			At:          time.Now().Unix(),
+			TextUnits:   len(pack.TextUnits),
+			Entities:    len(pack.Entities),
+			Communities: len(pack.Communities),
+		},
+		pack: &stored,
+	})
+	if len(entries) > h.size {
+		entries = slices.Delete(entries, 0, len(entries)-h.size)
+	}
+	h.sessions[sessionID] = entries
+}
+
+// list returns up to limit of a session's queries, most recent first
+// (limit <= 0 = all)
+func (h *queryHistory) list(sessionID string, limit int) []types.QueryRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	entries := h.sessions[sessionID]
+	if limit <= 0 || limit > len(entries) {
+		limit = len(entries)
+	}
+	records := make([]types.QueryRecord, 0, limit)
+	for i := len(entries) - 1; i >= len(entries)-limit; i-- {
+		records = append(records, entries[i].record)
+	}
+	return records
+}
+
+// result returns the stored result of a session's query
+func (h *queryHistory) result(sessionID string, queryID uint64) (*types.ContextPack, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, entry := range h.sessions[sessionID] {
+		if entry.record.QueryID == queryID {
+			pack := *entry.pack
+			return &pack, true
+		}
+	}
+	return nil, false
+}
+
+// resize trims every session's history to size; 0 turns it off
+func (h *queryHistory) resize(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	size = max(size, 0)
+	h.size = size
+	for sessionID, entries := range h.sessions {
+		if len(entries) > size {
+			entries = slices.Delete(entries, 0, len(entries)-size)
+		}
+		if len(entries) == 0 {
+			delete(h.sessions, sessionID)
+		} else {
+			h.sessions[sessionID] = entries
+		}
+	}
+}
+
+// forget drops a removed session's history
+func (h *queryHistory) forget(sessionID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.sessions, sessionID)
+}
+
+// SetQueryHistorySize sets how many recent queries each session keeps for
+// ListQueries and GetQueryResult, trimming longer histories. 0 turns the
+// history off.
+func (e *Engine) SetQueryHistorySize(size int) {
+	e.queryHistory.resize(size)
+}
+
+// ListQueries returns up to limit of a session's recent queries, most
+// recent first, with the spec each ran with and its stats (limit <= 0 =
+// the whole history). Pages fetched by cursor are not listed.
+func (e *Engine) ListQueries(sessionID string, limit int) ([]types.QueryRecord, error) {
+	if _, err := e.getSession(sessionID); err != nil {
+		return nil, err
+	}
+	return e.queryHistory.list(sessionID, limit), nil
+}
+
+// GetQueryResult returns the full result of a query still in its session's
+// history. Paged queries return every result, not just the first page.
+func (e *Engine) GetQueryResult(sessionID string, queryID uint64) (*types.ContextPack, bool) {
+	return e.queryHistory.result(sessionID, queryID)
+}
@@ -0,0 +1,178 @@
+// Package engine - Cursor pagination over query results
+package engine
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// MaxQueryPageEntries bounds how many paged queries keep their ranked
+// results for follow-up cursors
+const MaxQueryPageEntries = 1000
+
+// MaxQueryPageSize caps the page size of paged queries; larger PageSize
+// values are served as pages of this size
+const MaxQueryPageSize = 10000
+
+var (
+	ErrInvalidCursor = errors.New("invalid query cursor")
+	ErrCursorExpired = errors.New("query cursor expired")
+)
+
+// queryPageLRU keeps the full ranked results of paged queries by query ID
+type queryPageLRU struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[uint64]*list.Element
+	order    *list.List // front = most recent, back = least recent
+}
+
+type queryPageEntry struct {
+	id        uint64
+	sessionID string
+	pack      *types.ContextPack
+}
+
+func newQueryPageLRU(capacity int) *queryPageLRU {
+	return &queryPageLRU{
+		capacity: capacity,
+		items:    make(map[uint64]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Set stores pack, which must not be modified afterwards
+func (c *queryPageLRU) Set(id uint64, sessionID string, pack *types.ContextPack) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[id]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*queryPageEntry)
+		entry.sessionID = sessionID
+		entry.pack = pack
+		return
+	}
+
+	for c.order.Len() >= c.capacity {
+		back := c.order.Back()
+		delete(c.items, back.Value.(*queryPageEntry).id)
+		c.order.Remove(back)
+	}
+
+	c.items[id] = c.order.PushFront(&queryPageEntry{id: id, sessionID: sessionID, pack: pack})
+}
+
+// Get returns the cached pack for id if it belongs to sessionID
+func (c *queryPageLRU) Get(id uint64, sessionID string) (*types.ContextPack, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[id]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*queryPageEntry)
+	if entry.sessionID != sessionID {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.pack, true
+}
+
+// encodeCursor and decodeCursor map a query ID, result offset and page size
+// to the opaque "<query_id>:<offset>:<page_size>" cursor handed to clients
+func encodeCursor(queryID uint64, offset, pageSize int) string {
+	return fmt.Sprintf("%d:%d:%d", queryID, offset, pageSize)
+}
+
+func decodeCursor(cursor string) (queryID uint64, offset, pageSize int, err error) {
+	parts := strings.Split(cursor, ":")
+	if len(parts) != 3 {
+		return 0, 0, 0, ErrInvalidCursor
+	}
+	queryID, err = strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, 0, ErrInvalidCursor
+	}
+	offset, err = strconv.Atoi(parts[1])
+	if err != nil || offset < 0 {
+		return 0, 0, 0, ErrInvalidCursor
+	}
+	pageSize, err = strconv.Atoi(parts[2])
+	if err != nil || pageSize <= 0 || pageSize > MaxQueryPageSize {
+		return 0, 0, 0, ErrInvalidCursor
+	}
+	return queryID, offset, pageSize, nil
+}
+
+// queryPage serves the page a cursor points at from the cached ranking
+func (e *Engine) queryPage(sessionID, cursor string) (*types.ContextPack, error) {
+	if _, err := e.getSession(sessionID); err != nil {
+		return nil, err
+	}
+	queryID, offset, pageSize, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	full, ok := e.queryPages.Get(queryID, sessionID)
+	if !ok {
+		return nil, ErrCursorExpired
+	}
+	if offset > max(len(full.TextUnits), len(full.Entities), len(full.Communities)) {
+		return nil, ErrInvalidCursor
+	}
+
+	page := pageOf(full, offset, pageSize)
+	page.Stats.DurationMicros = time.Since(startTime).Microseconds()
+	return page, nil
+}
+
+// pageOf cuts each result list of full to [offset, offset+pageSize) and sets
+// NextCursor while any list has items past the page. Relationships are kept
+// when they touch an entity on the page.
+func pageOf(full *types.ContextPack, offset, pageSize int) *types.ContextPack {
+	page := &types.ContextPack{
+		QueryID:     full.QueryID,
+		TextUnits:   pageSlice(full.TextUnits, offset, pageSize),
+		Entities:    pageSlice(full.Entities, offset, pageSize),
+		Communities: pageSlice(full.Communities, offset, pageSize),
+		Stats:       full.Stats,
+	}
+
+	onPage := make(map[uint64]bool, len(page.Entities))
+	for _, er := range page.Entities {
+		onPage[er.Entity.ID] = true
+	}
+	page.Relationships = make([]types.RelationshipResult, 0)
+	for _, rr := range full.Relationships {
+		if onPage[rr.Relationship.SourceID] || onPage[rr.Relationship.TargetID] {
+			page.Relationships = append(page.Relationships, rr)
+		}
+	}
+
+	end := offset + pageSize
+	if end < len(full.TextUnits) || end < len(full.Entities) || end < len(full.Communities) {
+		page.NextCursor = encodeCursor(full.QueryID, end, pageSize)
+	}
+	return page
+}
+
+func pageSlice[T any](items []T, offset, pageSize int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := offset + min(pageSize, len(items)-offset)
+	out := make([]T, end-offset)
+	copy(out, items[offset:end])
+	return out
+}
@@ -0,0 +1,140 @@
+// Package engine - Aggregated query workload statistics
+package engine
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gibram-io/gibram/pkg/metrics"
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+const (
+	MaxQuerySamples         = 10000            // recent queries kept for QueryStatsSummary
+	DefaultQueryStatsWindow = 15 * time.Minute // window used when none is given
+)
+
+// querySample is the per-query record kept for aggregation
+type querySample struct {
+	at            time.Time
+	sessionID     string
+	latencyMicros int64
+	textUnits     int
+	entities      int
+	communities   int
+	kHops         int
+	relaxed       bool
+	cacheHit      bool
+}
+
+// querySampleRing is a fixed-size ring of the most recent query samples
+type querySampleRing struct {
+	mu      sync.Mutex
+	samples []querySample
+	next    int
+	full    bool
+}
+
+func newQuerySampleRing(capacity int) *querySampleRing {
+	return &querySampleRing{samples: make([]querySample, capacity)}
+}
+
+func (r *querySampleRing) add(s querySample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.samples[r.next] = s
+	r.next++
+	if r.next == len(r.samples) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// since returns samples recorded at or after cutoff, optionally for one session
+func (r *querySampleRing) since(cutoff time.Time, sessionID string) []querySample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.next
+	if r.full {
+		n = len(r.samples)
+	}
+	result := make([]querySample, 0, n)
+	for i := 0; i < n; i++ {
+		s := r.samples[i]
+		if s.at.Before(cutoff) {
+			continue
+		}
+		if sessionID != "" && s.sessionID != sessionID {
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// recordQuerySample adds a finished query to the workload statistics
+func (e *Engine) recordQuerySample(sessionID string, spec types.QuerySpec, pack *types.ContextPack) {
+	e.querySamples.add(querySample{
+		at:            time.Now(),
+		sessionID:     sessionID,
+		latencyMicros: pack.Stats.DurationMicros,
+		textUnits:     len(pack.TextUnits),
+		entities:      len(pack.Entities),
+		communities:   len(pack.Communities),
+		kHops:         spec.KHops,
+		relaxed:       len(pack.Stats.Relaxations) > 0,
+		cacheHit:      pack.Stats.CacheHit,
+	})
+}
+
+// QueryStatsSummary aggregates queries from the last window (default 15m).
+// An empty sessionID covers all sessions. Only the most recent
+// MaxQuerySamples queries are retained.
+func (e *Engine) QueryStatsSummary(window time.Duration, sessionID string) *types.QueryStatsSummary {
+	if window <= 0 {
+		window = DefaultQueryStatsWindow
+	}
+	samples := e.querySamples.since(time.Now().Add(-window), sessionID)
+
+	summary := &types.QueryStatsSummary{
+		WindowSeconds: int64(window / time.Second),
+		Count:         len(samples),
+	}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	latency := metrics.NewHistogram()
+	var textUnits, entities, communities, kHops int
+	for _, s := range samples {
+		latency.Record(float64(s.latencyMicros))
+		textUnits += s.textUnits
+		entities += s.entities
+		communities += s.communities
+		kHops += s.kHops
+		if s.textUnits+s.entities+s.communities == 0 {
+			summary.EmptyResults++
+		}
+		if s.relaxed {
+			summary.Relaxed++
+		}
+		if s.cacheHit {
+			summary.CacheHits++
+		}
+	}
+
+	stats := latency.Stats()
+	n := float64(len(samples))
+	summary.AvgLatencyMicros = stats.Avg
+	summary.P50LatencyMicros = stats.P50
+	summary.P95LatencyMicros = stats.P95
+	summary.P99LatencyMicros = stats.P99
+	summary.AvgTextUnits = float64(textUnits) / n
+	summary.AvgEntities = float64(entities) / n
+	summary.AvgCommunities = float64(communities) / n
+	summary.AvgKHops = float64(kHops) / n
+	summary.CacheHitRate = float64(summary.CacheHits) / n
+	return summary
+}
@@ -0,0 +1,101 @@
+// Package engine - Export and import of single sessions
+package engine
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gibram-io/gibram/pkg/store"
+	"github.com/gibram-io/gibram/pkg/types"
+	"github.com/gibram-io/gibram/pkg/version"
+)
+
+// SessionExport is one session's state as written by ExportSession
+type SessionExport struct {
+	Version   string                 `json:"version"`
+	VectorDim int                    `json:"vector_dim"`
+	Session   *store.SessionSnapshot `json:"session"`
+}
+
+// ExportSession serializes one session's documents, text units, entities,
+// relationships, communities and vectors to w. An expired session can be
+// exported until it is removed, so an eviction callback can save it.
+func (e *Engine) ExportSession(sessionID string, w io.Writer) error {
+	if sessionID == "" {
+		return ErrSessionRequired
+	}
+	e.mu.RLock()
+	sess, ok := e.sessions[sessionID]
+	e.mu.RUnlock()
+	if !ok {
+		return ErrSessionNotFound
+	}
+
+	export := SessionExport{
+		Version:   version.Version,
+		VectorDim: e.vectorDim,
+		Session:   sess.Snapshot(),
+	}
+	return json.NewEncoder(w).Encode(export)
+}
+
+// ImportSession loads a session written by ExportSession, gzip-compressed or
+// not, into sessionID. A new session takes the export as is, keeping its IDs
+// and settings. An existing session keeps its own data and settings and gets
+// the imported records under new IDs; the import is rejected without changes
+// if one of their external IDs or entity titles is already taken.
+func (e *Engine) ImportSession(sessionID string, r io.Reader) error {
+	if sessionID == "" {
+		return ErrSessionRequired
+	}
+
+	br := bufio.NewReader(r)
+	r = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("open gzip export: %w", err)
+		}
+		defer func() { _ = gr.Close() }()
+		r = gr
+	}
+
+	var export SessionExport
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return fmt.Errorf("decode session export: %w", err)
+	}
+	if export.Session == nil {
+		return fmt.Errorf("decode session export: no session")
+	}
+	if export.VectorDim != e.vectorDim {
+		return fmt.Errorf("vector dimension mismatch: export=%d, engine=%d", export.VectorDim, e.vectorDim)
+	}
+
+	e.mu.Lock()
+	sess, ok := e.sessions[sessionID]
+	if ok && !sess.IsExpired() {
+		e.mu.Unlock()
+		return sess.Import(export.Session, types.ConflictReject)
+	}
+	defer e.mu.Unlock()
+
+	if !ok && len(e.sessions) >= MaxSessions {
+		return fmt.Errorf("max sessions limit reached (%d)", MaxSessions)
+	}
+	export.Session.SessionID = sessionID
+	if export.Session.Session == nil {
+		export.Session.Session = types.NewSession(sessionID)
+	}
+	export.Session.Session.ID = sessionID
+	sess = store.NewSessionStore(sessionID, e.vectorDim)
+	sess.SetIndexConfig(e.indexConfig)
+	if err := sess.RestoreFromSnapshot(export.Session); err != nil {
+		return fmt.Errorf("restore session %s: %w", sessionID, err)
+	}
+	sess.Touch()
+	e.sessions[sessionID] = sess
+	return nil
+}
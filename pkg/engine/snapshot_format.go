@@ -0,0 +1,143 @@
+// Package engine - Engine snapshot stream format and version migration
+package engine
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// SnapshotFormatVersion is the snapshot format Snapshot writes. Version 3
+// streams are snapshotMagic, a version byte, the JSON body and a big-endian
+// CRC32C of the body. Version 2 streams have no checksum, and version 1
+// streams are the bare JSON body.
+const SnapshotFormatVersion = 3
+
+// snapshotChecksumSize is the length of the version 3 checksum trailer
+const snapshotChecksumSize = 4
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// snapshotMagic opens every snapshot stream since version 2. When the
+// snapshot is gzipped it sits inside the compressed data.
+var snapshotMagic = [4]byte{'G', 'S', 'N', 'P'}
+
+var (
+	// ErrSnapshotFormat is returned for data that is not an engine snapshot
+	ErrSnapshotFormat = errors.New("not a gibram engine snapshot")
+	// ErrSnapshotVersion is returned for a snapshot version this build
+	// cannot read
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	// ErrSnapshotCorrupt is returned when a snapshot's checksum does not
+	// match its contents, typically after truncation or bit rot
+	ErrSnapshotCorrupt = errors.New("snapshot checksum mismatch")
+)
+
+// snapshotDecoders reads the body of each known snapshot version into the
+// current EngineSnapshot. A format change adds a version here whose decoder
+// reads the new layout, and turns the decoder of the version it replaces
+// into a migration from the old layout.
+var snapshotDecoders = map[byte]func(io.Reader) (*EngineSnapshot, error){
+	1: decodeSnapshotJSON,
+	2: decodeSnapshotJSON,
+	3: decodeChecksummedSnapshot,
+}
+
+// writeSnapshot writes snapshot in the current format
+func writeSnapshot(w io.Writer, snapshot *EngineSnapshot) error {
+	header := append(snapshotMagic[:], SnapshotFormatVersion)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	crc := crc32.New(crc32cTable)
+	if err := json.NewEncoder(io.MultiWriter(w, crc)).Encode(snapshot); err != nil {
+		return err
+	}
+	_, err := w.Write(binary.BigEndian.AppendUint32(nil, crc.Sum32()))
+	return err
+}
+
+// decodeSnapshot reads an uncompressed snapshot stream of any known version
+func decodeSnapshot(r io.Reader) (*EngineSnapshot, error) {
+	br := bufio.NewReader(r)
+	version := byte(1)
+	header, err := br.Peek(len(snapshotMagic) + 1)
+	switch {
+	case err == nil && [4]byte(header[:4]) == snapshotMagic:
+		version = header[4]
+		if _, err := br.Discard(len(header)); err != nil {
+			return nil, err
+		}
+	case len(header) > 0 && header[0] == '{':
+		// Version 1: JSON without a header
+	default:
+		return nil, ErrSnapshotFormat
+	}
+
+	decode, ok := snapshotDecoders[version]
+	if !ok {
+		return nil, fmt.Errorf("%w %d (this build reads up to %d)", ErrSnapshotVersion, version, SnapshotFormatVersion)
+	}
+	return decode(br)
+}
+
+func decodeSnapshotJSON(r io.Reader) (*EngineSnapshot, error) {
+	var snapshot EngineSnapshot
+	if err := json.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// decodeChecksummedSnapshot decodes a JSON body followed by its CRC32C. A
+// mismatch is reported as ErrSnapshotCorrupt even when the damage also broke
+// the JSON, so truncation is distinguishable from a malformed writer.
+func decodeChecksummedSnapshot(r io.Reader) (*EngineSnapshot, error) {
+	cr := &checksumReader{r: r, crc: crc32.New(crc32cTable)}
+	snapshot, decodeErr := decodeSnapshotJSON(cr)
+	if _, err := io.Copy(io.Discard, cr); err != nil {
+		return nil, err
+	}
+	if len(cr.held) != snapshotChecksumSize || binary.BigEndian.Uint32(cr.held) != cr.crc.Sum32() {
+		return nil, ErrSnapshotCorrupt
+	}
+	if decodeErr != nil {
+		return nil, decodeErr
+	}
+	return snapshot, nil
+}
+
+// checksumReader passes through everything but the last snapshotChecksumSize
+// bytes of r, hashing what it passes. At EOF held is the trailer.
+type checksumReader struct {
+	r    io.Reader
+	crc  hash.Hash32
+	held []byte
+	eof  bool
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	for len(c.held) <= snapshotChecksumSize {
+		if c.eof {
+			return 0, io.EOF
+		}
+		buf := make([]byte, max(len(p), 4096))
+		n, err := c.r.Read(buf)
+		c.held = append(c.held, buf[:n]...)
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.held[:len(c.held)-snapshotChecksumSize])
+	c.crc.Write(p[:n])
+	c.held = c.held[n:]
+	return n, nil
+}
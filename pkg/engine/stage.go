@@ -0,0 +1,68 @@
+// Package engine - Staging groups of session changes for rollback
+package engine
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gibram-io/gibram/pkg/store"
+)
+
+// Stage holds the state sessions had before a group of changes, so the
+// changes can be undone together when one of them fails. Changes go to the
+// sessions as usual; dropping the Stage keeps them.
+type Stage struct {
+	e           *Engine
+	checkpoints map[string]sessionCheckpoint
+}
+
+// sessionCheckpoint is a session's store and a copy of its state. sess is
+// nil when the session did not exist.
+type sessionCheckpoint struct {
+	sess     *store.SessionStore
+	snapshot *store.SessionSnapshot
+}
+
+// BeginStage returns an empty Stage. Sessions join it with Add before they
+// are changed.
+func (e *Engine) BeginStage() *Stage {
+	return &Stage{e: e, checkpoints: make(map[string]sessionCheckpoint)}
+}
+
+// Add checkpoints sessionID unless it already is. The checkpoint copies the
+// session's records and vectors, so it costs about as much as CloneSession.
+func (st *Stage) Add(sessionID string) {
+	if _, ok := st.checkpoints[sessionID]; ok {
+		return
+	}
+
+	st.e.mu.RLock()
+	sess, ok := st.e.sessions[sessionID]
+	st.e.mu.RUnlock()
+	if !ok {
+		st.checkpoints[sessionID] = sessionCheckpoint{}
+		return
+	}
+	st.checkpoints[sessionID] = sessionCheckpoint{sess: sess, snapshot: sess.Checkpoint()}
+}
+
+// Rollback returns every session added to the stage to its checkpoint.
+// Sessions created since are deleted and deleted ones are put back. Writes
+// other callers made to these sessions since they were added are undone
+// too, so callers should keep them out.
+func (st *Stage) Rollback() error {
+	var errs []error
+	for id, cp := range st.checkpoints {
+		if cp.sess == nil {
+			st.e.DeleteSession(id)
+			continue
+		}
+		if err := cp.sess.RestoreFromSnapshot(cp.snapshot); err != nil {
+			errs = append(errs, fmt.Errorf("roll back session %s: %w", id, err))
+		}
+		st.e.mu.Lock()
+		st.e.sessions[id] = cp.sess
+		st.e.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
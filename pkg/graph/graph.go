@@ -2,6 +2,7 @@
 package graph
 
 import (
+	"context"
 	"math"
 	"math/rand"
 	"sort"
@@ -69,7 +70,9 @@ type Leiden struct {
 	adjWeights   map[uint64]map[uint64]float64 // adjacency with weights
 	nodeStrength map[uint64]float64            // sum of edge weights per node
 	totalWeight  float64                       // total edge weight in graph
+	modularity   float64                       // modularity of the last result
 	rng          *rand.Rand
+	ctx          context.Context // stops a *Context run early (nil = never)
 }
 
 func NewLeiden(entities EntityStore, relationships RelationshipStore, config LeidenConfig) *Leiden {
@@ -89,6 +92,23 @@ type HierarchicalCommunity struct {
 	Children  []int    // indices of child communities
 }
 
+// ComputeHierarchicalCommunitiesContext is like
+// ComputeHierarchicalCommunities but gives up with ctx.Err() once ctx is done
+func (l *Leiden) ComputeHierarchicalCommunitiesContext(ctx context.Context) ([][]HierarchicalCommunity, error) {
+	l.ctx = ctx
+	defer func() { l.ctx = nil }()
+	result := l.ComputeHierarchicalCommunities()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// stopped reports whether the context of a *Context run is done
+func (l *Leiden) stopped() bool {
+	return l.ctx != nil && l.ctx.Err() != nil
+}
+
 // ComputeHierarchicalCommunities runs hierarchical Leiden up to MaxLevels
 // Returns communities organized by level: result[level] = [][]uint64
 func (l *Leiden) ComputeHierarchicalCommunities() [][]HierarchicalCommunity {
@@ -117,7 +137,7 @@ func (l *Leiden) ComputeHierarchicalCommunities() [][]HierarchicalCommunity {
 
 	queue := []splitTask{{entityIDs: allEntities, level: 0, parentIdx: -1}}
 
-	for len(queue) > 0 {
+	for len(queue) > 0 && !l.stopped() {
 		task := queue[0]
 		queue = queue[1:]
 
@@ -232,7 +252,7 @@ func (l *Leiden) leidenOnSubset(entityIDs []uint64, level int) [][]uint64 {
 	}
 
 	// Local moving phase
-	for iter := 0; iter < l.config.Iterations; iter++ {
+	for iter := 0; iter < l.config.Iterations && !l.stopped(); iter++ {
 		improved := false
 
 		// Shuffle nodes
@@ -332,6 +352,18 @@ func (l *Leiden) leidenOnSubset(entityIDs []uint64, level int) [][]uint64 {
 	return results
 }
 
+// ComputeCommunitiesContext is like ComputeCommunities but gives up with
+// ctx.Err() once ctx is done
+func (l *Leiden) ComputeCommunitiesContext(ctx context.Context) ([][]uint64, error) {
+	l.ctx = ctx
+	defer func() { l.ctx = nil }()
+	result := l.ComputeCommunities()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
 // ComputeCommunities runs Leiden and returns community assignments
 func (l *Leiden) ComputeCommunities() [][]uint64 {
 	l.buildGraph()
@@ -344,7 +376,7 @@ func (l *Leiden) ComputeCommunities() [][]uint64 {
 	l.initializeCommunities()
 
 	// Main Leiden loop
-	for iter := 0; iter < l.config.Iterations; iter++ {
+	for iter := 0; iter < l.config.Iterations && !l.stopped(); iter++ {
 		improved := l.moveNodes()
 		if !improved {
 			break
@@ -359,9 +391,56 @@ func (l *Leiden) ComputeCommunities() [][]uint64 {
 		}
 	}
 
+	l.modularity = Modularity(l.adjWeights, result, l.config.Resolution)
 	return result
 }
 
+// Modularity returns the modularity of the partition found by the last
+// ComputeCommunities call, at the configured resolution
+func (l *Leiden) Modularity() float64 {
+	return l.modularity
+}
+
+// Modularity scores a partition of the weighted undirected graph adj, where
+// adj[u][v] == adj[v][u] is the edge weight and every node appears in
+// exactly one cluster:
+//
+//	Q = sum over clusters c of in_c/2m - resolution * (tot_c/2m)^2
+//
+// in_c is the weight of edges inside c counted from both ends, tot_c the
+// summed strength of c's nodes and m the total edge weight. It returns 0
+// for a graph without edges.
+func Modularity(adj map[uint64]map[uint64]float64, clusters [][]uint64, resolution float64) float64 {
+	m2 := 0.0
+	for _, neighbors := range adj {
+		for _, w := range neighbors {
+			m2 += w
+		}
+	}
+	if m2 == 0 {
+		return 0
+	}
+
+	q := 0.0
+	for _, cluster := range clusters {
+		members := make(map[uint64]bool, len(cluster))
+		for _, id := range cluster {
+			members[id] = true
+		}
+		in, tot := 0.0, 0.0
+		for _, id := range cluster {
+			for neighbor, w := range adj[id] {
+				tot += w
+				if members[neighbor] {
+					in += w
+				}
+			}
+		}
+		q += in/m2 - resolution*(tot/m2)*(tot/m2)
+	}
+	return q
+}
+
 // buildGraph constructs adjacency from entity and relationship stores
 func (l *Leiden) buildGraph() {
 	l.adjWeights = make(map[uint64]map[uint64]float64)
@@ -381,7 +460,8 @@ func (l *Leiden) buildGraph() {
 			weight = 1.0
 		}
 
-		// Bidirectional
+		// Symmetric whether or not the relationship is directed; parallel
+		// and reciprocal edges add up, matching nodeStrength
 		if l.adjWeights[rel.SourceID] == nil {
 			l.adjWeights[rel.SourceID] = make(map[uint64]float64)
 		}
@@ -389,8 +469,8 @@ func (l *Leiden) buildGraph() {
 			l.adjWeights[rel.TargetID] = make(map[uint64]float64)
 		}
 
-		l.adjWeights[rel.SourceID][rel.TargetID] = weight
-		l.adjWeights[rel.TargetID][rel.SourceID] = weight
+		l.adjWeights[rel.SourceID][rel.TargetID] += weight
+		l.adjWeights[rel.TargetID][rel.SourceID] += weight
 
 		l.nodeStrength[rel.SourceID] += weight
 		l.nodeStrength[rel.TargetID] += weight
@@ -425,6 +505,9 @@ func (l *Leiden) moveNodes() bool {
 	})
 
 	for _, nodeID := range nodes {
+		if l.stopped() {
+			break
+		}
 		currentComm := l.nodeToComm[nodeID]
 
 		// Calculate modularity gain for moving to each neighbor's community
@@ -677,6 +760,43 @@ func BFSTraversal(
 	maxHops int,
 	maxNodes int,
 ) ([]uint64, map[uint64]int, []types.TraversalStep) {
+	return BFSTraversalWithExpansionLimit(seedIDs, relStore, maxHops, maxNodes, 0)
+}
+
+// neighborEdge is a relationship seen from one endpoint
+type neighborEdge struct {
+	rel      *types.Relationship
+	neighbor uint64
+}
+
+// BFSTraversalWithExpansionLimit is BFSTraversal where each node discovers at
+// most maxPerNode new neighbors, taking its highest-weight edges first. This
+// keeps a single hub from flooding the frontier. maxPerNode <= 0 means no cap.
+func BFSTraversalWithExpansionLimit(
+	seedIDs []uint64,
+	relStore RelationshipStore,
+	maxHops int,
+	maxNodes int,
+	maxPerNode int,
+) ([]uint64, map[uint64]int, []types.TraversalStep) {
+	nodeIDs, visited, traversal, _ := BFSTraversalContext(context.Background(), seedIDs, relStore, maxHops, maxNodes, maxPerNode)
+	return nodeIDs, visited, traversal
+}
+
+// bfsCheckInterval is how many nodes BFSTraversalContext expands between
+// checks of its context
+const bfsCheckInterval = 64
+
+// BFSTraversalContext is BFSTraversalWithExpansionLimit that gives up with
+// ctx.Err() once ctx is done
+func BFSTraversalContext(
+	ctx context.Context,
+	seedIDs []uint64,
+	relStore RelationshipStore,
+	maxHops int,
+	maxNodes int,
+	maxPerNode int,
+) ([]uint64, map[uint64]int, []types.TraversalStep, error) {
 	// Returns: visited node IDs, node -> hop distance, traversal steps
 
 	visited := make(map[uint64]int) // nodeID -> hop distance
@@ -691,7 +811,12 @@ func BFSTraversal(
 		}
 	}
 
-	for len(queue) > 0 && len(visited) < maxNodes {
+	for expandedNodes := 0; len(queue) > 0 && len(visited) < maxNodes; expandedNodes++ {
+		if expandedNodes%bfsCheckInterval == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, nil, nil, err
+			}
+		}
 		currentID := queue[0]
 		queue = queue[1:]
 
@@ -700,51 +825,46 @@ func BFSTraversal(
 			continue
 		}
 
-		// Get neighbors
+		// Get neighbors (outgoing first, then incoming)
 		outgoing := relStore.GetOutgoing(currentID)
 		incoming := relStore.GetIncoming(currentID)
-
+		edges := make([]neighborEdge, 0, len(outgoing)+len(incoming))
 		for _, rel := range outgoing {
-			if _, seen := visited[rel.TargetID]; !seen {
-				visited[rel.TargetID] = currentHop + 1
-				queue = append(queue, rel.TargetID)
-
-				traversal = append(traversal, types.TraversalStep{
-					FromEntityID:   currentID,
-					ToEntityID:     rel.TargetID,
-					RelationshipID: rel.ID,
-					RelType:        rel.Type,
-					Weight:         rel.Weight,
-					Hop:            currentHop + 1,
-				})
+			edges = append(edges, neighborEdge{rel: rel, neighbor: rel.TargetID})
+		}
+		for _, rel := range incoming {
+			edges = append(edges, neighborEdge{rel: rel, neighbor: rel.SourceID})
+		}
+		if maxPerNode > 0 && len(edges) > maxPerNode {
+			sort.SliceStable(edges, func(i, j int) bool {
+				return edges[i].rel.Weight > edges[j].rel.Weight
+			})
+		}
 
-				if len(visited) >= maxNodes {
-					break
-				}
+		expanded := 0
+		for _, edge := range edges {
+			if maxPerNode > 0 && expanded >= maxPerNode {
+				break
+			}
+			if _, seen := visited[edge.neighbor]; seen {
+				continue
 			}
-		}
 
-		if len(visited) >= maxNodes {
-			break
-		}
+			visited[edge.neighbor] = currentHop + 1
+			queue = append(queue, edge.neighbor)
+			expanded++
 
-		for _, rel := range incoming {
-			if _, seen := visited[rel.SourceID]; !seen {
-				visited[rel.SourceID] = currentHop + 1
-				queue = append(queue, rel.SourceID)
-
-				traversal = append(traversal, types.TraversalStep{
-					FromEntityID:   currentID,
-					ToEntityID:     rel.SourceID,
-					RelationshipID: rel.ID,
-					RelType:        rel.Type,
-					Weight:         rel.Weight,
-					Hop:            currentHop + 1,
-				})
+			traversal = append(traversal, types.TraversalStep{
+				FromEntityID:   currentID,
+				ToEntityID:     edge.neighbor,
+				RelationshipID: edge.rel.ID,
+				RelType:        edge.rel.Type,
+				Weight:         edge.rel.Weight,
+				Hop:            currentHop + 1,
+			})
 
-				if len(visited) >= maxNodes {
-					break
-				}
+			if len(visited) >= maxNodes {
+				break
 			}
 		}
 	}
@@ -758,9 +878,15 @@ func BFSTraversal(
 		return visited[nodeIDs[i]] < visited[nodeIDs[j]]
 	})
 
-	return nodeIDs, visited, traversal
+	return nodeIDs, visited, traversal, nil
 }
 
+// PageRank defaults
+const (
+	DefaultPageRankDamping    = 0.85
+	DefaultPageRankIterations = 20
+)
+
 // PageRank computes PageRank scores for entities
 func PageRank(
 	entityIDs []uint64,
@@ -2,6 +2,7 @@
 package graph
 
 import (
+	"math"
 	"sync"
 	"testing"
 
@@ -277,6 +278,36 @@ func TestLeiden_ComputeHierarchicalCommunities(t *testing.T) {
 	}
 }
 
+func TestLeiden_BuildGraph_Directed(t *testing.T) {
+	entityStore := newMockEntityStore()
+	for i := uint64(1); i <= 3; i++ {
+		entityStore.Add(types.NewEntity(i, "", "E"+itoa(int(i)), "test", ""))
+	}
+	relStore := newMockRelationshipStore()
+	// A reciprocal pair of directed edges between 1 and 2, one undirected
+	// edge between 2 and 3
+	forward := types.NewRelationship(1, "", 1, 2, "SUPERVISES", "", 1.0)
+	forward.Directed = true
+	backward := types.NewRelationship(2, "", 2, 1, "REPORTS_TO", "", 1.0)
+	backward.Directed = true
+	relStore.Add(forward)
+	relStore.Add(backward)
+	relStore.Add(types.NewRelationship(3, "", 3, 2, "KNOWS", "", 2.0))
+
+	leiden := NewLeiden(entityStore, relStore, DefaultLeidenConfig())
+	leiden.buildGraph()
+
+	for _, pair := range [][2]uint64{{1, 2}, {2, 3}} {
+		a, b := pair[0], pair[1]
+		if leiden.adjWeights[a][b] != 2.0 || leiden.adjWeights[b][a] != 2.0 {
+			t.Errorf("adjWeights between %d and %d = %v/%v, want 2 both ways", a, b, leiden.adjWeights[a][b], leiden.adjWeights[b][a])
+		}
+	}
+	if leiden.nodeStrength[2] != 4.0 {
+		t.Errorf("nodeStrength[2] = %v, want 4", leiden.nodeStrength[2])
+	}
+}
+
 func TestDefaultLeidenConfig(t *testing.T) {
 	config := DefaultLeidenConfig()
 
@@ -376,6 +407,35 @@ func TestBFSTraversal_Empty(t *testing.T) {
 	}
 }
 
+func TestBFSTraversal_ExpansionLimit(t *testing.T) {
+	relStore := newMockRelationshipStore()
+
+	// Hub 1 with 5 spokes of increasing weight
+	for i := uint64(2); i <= 6; i++ {
+		relStore.Add(&types.Relationship{ID: i, SourceID: 1, TargetID: i, Type: "LINK", Weight: float32(i)})
+	}
+
+	nodeIDs, distances, steps := BFSTraversalWithExpansionLimit([]uint64{1}, relStore, 2, 100, 2)
+	if len(nodeIDs) != 3 {
+		t.Fatalf("Expected seed + 2 neighbors, got %d nodes", len(nodeIDs))
+	}
+	// Highest-weight edges win
+	for _, id := range []uint64{5, 6} {
+		if d, ok := distances[id]; !ok || d != 1 {
+			t.Errorf("Expected node %d at hop 1, got %d (found=%v)", id, d, ok)
+		}
+	}
+	if len(steps) != 2 {
+		t.Errorf("Expected 2 traversal steps, got %d", len(steps))
+	}
+
+	// No cap expands every edge
+	nodeIDs, _, _ = BFSTraversalWithExpansionLimit([]uint64{1}, relStore, 2, 100, 0)
+	if len(nodeIDs) != 6 {
+		t.Errorf("Expected all 6 nodes without cap, got %d", len(nodeIDs))
+	}
+}
+
 // =============================================================================
 // PageRank Tests
 // =============================================================================
@@ -430,6 +490,25 @@ func TestPageRank_SingleNode(t *testing.T) {
 	}
 }
 
+func TestPageRank_StarHub(t *testing.T) {
+	relStore := newMockRelationshipStore()
+	entityIDs := []uint64{1, 2, 3, 4, 5, 6}
+	// Every spoke points at the hub
+	for i := uint64(2); i <= 6; i++ {
+		relStore.Add(&types.Relationship{ID: i, SourceID: i, TargetID: 1, Type: "REGULATED_BY", Weight: 1.0})
+	}
+
+	scores := PageRank(entityIDs, relStore, DefaultPageRankDamping, DefaultPageRankIterations)
+	for _, eid := range entityIDs[1:] {
+		if scores[1] <= scores[eid] {
+			t.Errorf("hub score %f should exceed spoke %d score %f", scores[1], eid, scores[eid])
+		}
+		if math.Abs(scores[eid]-scores[2]) > 1e-12 {
+			t.Errorf("spokes should tie, got %f vs %f", scores[eid], scores[2])
+		}
+	}
+}
+
 // =============================================================================
 // Connected Components Tests
 // =============================================================================
@@ -831,6 +910,64 @@ func TestLeiden_ComputeCommunities(t *testing.T) {
 	}
 }
 
+// createTwoTrianglesGraph builds two triangles {1,2,3} and {4,5,6} joined by
+// the single edge 3-4, all weights 1
+func createTwoTrianglesGraph() (*mockEntityStore, *mockRelationshipStore) {
+	entityStore := newMockEntityStore()
+	relStore := newMockRelationshipStore()
+	for i := uint64(1); i <= 6; i++ {
+		entityStore.Add(&types.Entity{ID: i, Title: "E" + itoa(int(i)), Type: "test"})
+	}
+	edges := [][2]uint64{{1, 2}, {2, 3}, {1, 3}, {4, 5}, {5, 6}, {4, 6}, {3, 4}}
+	for i, e := range edges {
+		relStore.Add(&types.Relationship{ID: uint64(i + 1), SourceID: e[0], TargetID: e[1], Type: "CONNECTED", Weight: 1.0})
+	}
+	return entityStore, relStore
+}
+
+func TestLeiden_Modularity_TwoClusters(t *testing.T) {
+	entityStore, relStore := createTwoTrianglesGraph()
+	config := DefaultLeidenConfig()
+
+	leiden := NewLeiden(entityStore, relStore, config)
+	result := leiden.ComputeCommunities()
+	if len(result) != 2 {
+		t.Fatalf("Expected the two triangles as communities, got %v", result)
+	}
+
+	// m = 7; each triangle has in_c = 6 and tot_c = 7:
+	// Q = 2 * (6/14 - (7/14)^2) = 5/14
+	want := 5.0 / 14.0
+	if got := leiden.Modularity(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Modularity() = %v, want %v", got, want)
+	}
+}
+
+func TestModularity(t *testing.T) {
+	adj := map[uint64]map[uint64]float64{
+		1: {2: 1},
+		2: {1: 1},
+		3: {4: 1},
+		4: {3: 1},
+	}
+
+	// Two disconnected edges split apart: 2 * (2/4 - (2/4)^2) = 0.5
+	if got := Modularity(adj, [][]uint64{{1, 2}, {3, 4}}, 1.0); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("Modularity(split) = %v, want 0.5", got)
+	}
+	// One community holding everything scores 1 - 1 = 0
+	if got := Modularity(adj, [][]uint64{{1, 2, 3, 4}}, 1.0); math.Abs(got) > 1e-9 {
+		t.Errorf("Modularity(single) = %v, want 0", got)
+	}
+	// Resolution scales the null-model term: 1 - 0.5 * 2 * 0.25 = 0.75
+	if got := Modularity(adj, [][]uint64{{1, 2}, {3, 4}}, 0.5); math.Abs(got-0.75) > 1e-9 {
+		t.Errorf("Modularity(resolution 0.5) = %v, want 0.75", got)
+	}
+	if got := Modularity(map[uint64]map[uint64]float64{1: {}}, [][]uint64{{1}}, 1.0); got != 0 {
+		t.Errorf("Modularity(no edges) = %v, want 0", got)
+	}
+}
+
 func TestLeiden_ComputeCommunities_Empty(t *testing.T) {
 	entityStore := newMockEntityStore()
 	relStore := newMockRelationshipStore()
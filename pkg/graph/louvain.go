@@ -0,0 +1,282 @@
+// Package graph - Louvain community detection
+package graph
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Community detection algorithms accepted by NewCommunityDetector
+const (
+	AlgorithmLeiden  = "leiden"
+	AlgorithmLouvain = "louvain"
+)
+
+// CommunityDetector partitions the entity graph into communities
+type CommunityDetector interface {
+	// ComputeCommunities returns the entity IDs of each community
+	ComputeCommunities() [][]uint64
+	// ComputeCommunitiesContext is like ComputeCommunities but gives up
+	// with ctx.Err() once ctx is done
+	ComputeCommunitiesContext(ctx context.Context) ([][]uint64, error)
+	// Modularity returns the modularity of the last result
+	Modularity() float64
+}
+
+// NewCommunityDetector returns the detector for algorithm ("" = leiden)
+func NewCommunityDetector(algorithm string, entities EntityStore, relationships RelationshipStore, config LeidenConfig) (CommunityDetector, error) {
+	switch strings.ToLower(algorithm) {
+	case "", AlgorithmLeiden:
+		return NewLeiden(entities, relationships, config), nil
+	case AlgorithmLouvain:
+		return NewLouvain(entities, relationships, config), nil
+	default:
+		return nil, fmt.Errorf("unknown community algorithm %q (want %s or %s)", algorithm, AlgorithmLeiden, AlgorithmLouvain)
+	}
+}
+
+// =============================================================================
+// Louvain Clustering Algorithm
+// =============================================================================
+
+// Louvain implements Louvain community detection: nodes move greedily
+// between neighboring communities, then each community is collapsed into a
+// single node and the process repeats on the smaller graph until no move
+// improves modularity. It uses Resolution, Iterations (local moving passes
+// per level), MinDelta and RandomSeed from the config.
+type Louvain struct {
+	config        LeidenConfig
+	entities      EntityStore
+	relationships RelationshipStore
+	modularity    float64
+	rng           *rand.Rand
+	ctx           context.Context // stops a *Context run early (nil = never)
+}
+
+// NewLouvain creates a Louvain detector
+func NewLouvain(entities EntityStore, relationships RelationshipStore, config LeidenConfig) *Louvain {
+	return &Louvain{
+		config:        config,
+		entities:      entities,
+		relationships: relationships,
+		rng:           rand.New(rand.NewSource(config.RandomSeed)),
+	}
+}
+
+// louvainLevel is the graph at one aggregation level. Node i stands for the
+// entities in members[i]; adj[i][i] holds twice the weight inside node i so
+// that strength[i] is the sum of row i.
+type louvainLevel struct {
+	adj      []map[int]float64
+	strength []float64
+	members  [][]uint64
+}
+
+// ComputeCommunitiesContext is like ComputeCommunities but gives up with
+// ctx.Err() once ctx is done
+func (l *Louvain) ComputeCommunitiesContext(ctx context.Context) ([][]uint64, error) {
+	l.ctx = ctx
+	defer func() { l.ctx = nil }()
+	result := l.ComputeCommunities()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// stopped reports whether the context of a *Context run is done
+func (l *Louvain) stopped() bool {
+	return l.ctx != nil && l.ctx.Err() != nil
+}
+
+// ComputeCommunities runs Louvain and returns community assignments
+func (l *Louvain) ComputeCommunities() [][]uint64 {
+	base := l.buildAdjacency()
+	if len(base) == 0 {
+		return nil
+	}
+
+	level := newLouvainLevel(base)
+	iterations := l.config.Iterations
+	if iterations <= 0 {
+		iterations = DefaultLeidenConfig().Iterations
+	}
+
+	for !l.stopped() {
+		comm, moved := l.moveNodes(level, iterations)
+		if !moved {
+			break
+		}
+		level = level.aggregate(comm)
+	}
+
+	result := make([][]uint64, 0, len(level.members))
+	for _, members := range level.members {
+		result = append(result, members)
+	}
+
+	l.modularity = Modularity(base, result, l.config.Resolution)
+	return result
+}
+
+// Modularity returns the modularity of the partition found by the last
+// ComputeCommunities call, at the configured resolution
+func (l *Louvain) Modularity() float64 {
+	return l.modularity
+}
+
+// buildAdjacency returns the symmetric weighted adjacency of the entity
+// graph in the form Modularity expects. Parallel edges add up.
+func (l *Louvain) buildAdjacency() map[uint64]map[uint64]float64 {
+	adj := make(map[uint64]map[uint64]float64)
+	for _, ent := range l.entities.GetAll() {
+		adj[ent.ID] = make(map[uint64]float64)
+	}
+
+	for _, rel := range l.relationships.GetAll() {
+		weight := float64(rel.Weight)
+		if weight == 0 {
+			weight = 1.0
+		}
+		if adj[rel.SourceID] == nil {
+			adj[rel.SourceID] = make(map[uint64]float64)
+		}
+		if adj[rel.TargetID] == nil {
+			adj[rel.TargetID] = make(map[uint64]float64)
+		}
+		if rel.SourceID == rel.TargetID {
+			adj[rel.SourceID][rel.SourceID] += 2 * weight
+			continue
+		}
+		adj[rel.SourceID][rel.TargetID] += weight
+		adj[rel.TargetID][rel.SourceID] += weight
+	}
+	return adj
+}
+
+// newLouvainLevel indexes the base graph, one node per entity in ID order
+func newLouvainLevel(base map[uint64]map[uint64]float64) *louvainLevel {
+	ids := make([]uint64, 0, len(base))
+	for id := range base {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	index := make(map[uint64]int, len(ids))
+	for i, id := range ids {
+		index[id] = i
+	}
+
+	level := &louvainLevel{
+		adj:      make([]map[int]float64, len(ids)),
+		strength: make([]float64, len(ids)),
+		members:  make([][]uint64, len(ids)),
+	}
+	for i, id := range ids {
+		level.adj[i] = make(map[int]float64, len(base[id]))
+		for neighbor, w := range base[id] {
+			level.adj[i][index[neighbor]] = w
+			level.strength[i] += w
+		}
+		level.members[i] = []uint64{id}
+	}
+	return level
+}
+
+// moveNodes runs the local moving phase and returns each node's community
+// and whether any node moved
+func (l *Louvain) moveNodes(level *louvainLevel, iterations int) ([]int, bool) {
+	n := len(level.adj)
+	comm := make([]int, n)
+	tot := make([]float64, n)
+	m2 := 0.0
+	for i := range comm {
+		comm[i] = i
+		tot[i] = level.strength[i]
+		m2 += level.strength[i]
+	}
+	if m2 == 0 {
+		return comm, false
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+
+	resolution := l.config.Resolution
+	movedAny := false
+	for iter := 0; iter < iterations && !l.stopped(); iter++ {
+		l.rng.Shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+
+		moved := false
+		for _, i := range order {
+			current := comm[i]
+			ki := level.strength[i]
+
+			// Weight from i to each neighboring community, self-loop excluded
+			links := make(map[int]float64)
+			for j, w := range level.adj[i] {
+				if j != i {
+					links[comm[j]] += w
+				}
+			}
+
+			tot[current] -= ki
+			best := current
+			bestGain := links[current] - resolution*tot[current]*ki/m2
+			for c, w := range links {
+				gain := w - resolution*tot[c]*ki/m2
+				if gain > bestGain || (gain == bestGain && c < best && c != current) {
+					best, bestGain = c, gain
+				}
+			}
+
+			// Modularity change of the move is 2*(gain difference)/m2
+			if best != current && 2*(bestGain-(links[current]-resolution*tot[current]*ki/m2))/m2 <= l.config.MinDelta {
+				best = current
+			}
+			tot[best] += ki
+			if best != current {
+				comm[i] = best
+				moved = true
+				movedAny = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	return comm, movedAny
+}
+
+// aggregate collapses each community of comm into one node
+func (level *louvainLevel) aggregate(comm []int) *louvainLevel {
+	renumber := make(map[int]int)
+	for _, c := range comm {
+		if _, ok := renumber[c]; !ok {
+			renumber[c] = len(renumber)
+		}
+	}
+
+	next := &louvainLevel{
+		adj:      make([]map[int]float64, len(renumber)),
+		strength: make([]float64, len(renumber)),
+		members:  make([][]uint64, len(renumber)),
+	}
+	for i := range next.adj {
+		next.adj[i] = make(map[int]float64)
+	}
+	for i, row := range level.adj {
+		ci := renumber[comm[i]]
+		next.members[ci] = append(next.members[ci], level.members[i]...)
+		next.strength[ci] += level.strength[i]
+		for j, w := range row {
+			next.adj[ci][renumber[comm[j]]] += w
+		}
+	}
+	return next
+}
@@ -0,0 +1,181 @@
+// Package graph - Louvain tests
+package graph
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// createFinancialGraph builds four sectors of an Indonesian financial graph
+// with dense, randomly weighted links inside each sector and a few weak
+// links between sectors. The seed keeps it reproducible.
+func createFinancialGraph() (*mockEntityStore, *mockRelationshipStore) {
+	sectors := [][]string{
+		{"BANK INDONESIA", "OJK", "LPS", "BI-FAST", "QRIS"},
+		{"BRI", "BCA", "MANDIRI", "BNI", "BTN"},
+		{"GOPAY", "OVO", "DANA", "SHOPEEPAY", "FLIP"},
+		{"AKULAKU", "KREDIVO", "AMARTHA", "MODALKU", "INVESTREE"},
+	}
+
+	entityStore := newMockEntityStore()
+	relStore := newMockRelationshipStore()
+	rng := rand.New(rand.NewSource(7))
+
+	var id uint64
+	ids := make([][]uint64, len(sectors))
+	for s, titles := range sectors {
+		for _, title := range titles {
+			id++
+			entityStore.Add(&types.Entity{ID: id, Title: title, Type: "organization"})
+			ids[s] = append(ids[s], id)
+		}
+	}
+
+	var relID uint64
+	link := func(src, dst uint64, weight float32) {
+		relID++
+		relStore.Add(&types.Relationship{ID: relID, SourceID: src, TargetID: dst, Type: "RELATED_TO", Weight: weight})
+	}
+	for _, members := range ids {
+		for i := 0; i < len(members); i++ {
+			for j := i + 1; j < len(members); j++ {
+				if rng.Float64() < 0.8 {
+					link(members[i], members[j], float32(1+rng.Intn(4)))
+				}
+			}
+		}
+		// Keep every sector connected whatever the dice said
+		for i := 1; i < len(members); i++ {
+			link(members[i-1], members[i], 1)
+		}
+	}
+	for s := range ids {
+		next := ids[(s+1)%len(ids)]
+		link(ids[s][rng.Intn(len(ids[s]))], next[rng.Intn(len(next))], 0.5)
+	}
+	return entityStore, relStore
+}
+
+// assertPartition fails unless result places every entity in exactly one
+// community
+func assertPartition(t *testing.T, entities EntityStore, result [][]uint64) {
+	t.Helper()
+	seen := make(map[uint64]int)
+	for i, members := range result {
+		if len(members) == 0 {
+			t.Errorf("community %d is empty", i)
+		}
+		for _, id := range members {
+			if prev, ok := seen[id]; ok {
+				t.Errorf("entity %d is in communities %d and %d", id, prev, i)
+			}
+			seen[id] = i
+		}
+	}
+	for _, ent := range entities.GetAll() {
+		if _, ok := seen[ent.ID]; !ok {
+			t.Errorf("entity %d (%s) is in no community", ent.ID, ent.Title)
+		}
+	}
+	if len(seen) != len(entities.GetAll()) {
+		t.Errorf("partition covers %d entities, want %d", len(seen), len(entities.GetAll()))
+	}
+}
+
+func TestLouvain_FinancialGraph(t *testing.T) {
+	entityStore, relStore := createFinancialGraph()
+	config := DefaultLeidenConfig()
+
+	louvain := NewLouvain(entityStore, relStore, config)
+	result := louvain.ComputeCommunities()
+	assertPartition(t, entityStore, result)
+
+	if len(result) != 4 {
+		t.Errorf("Louvain found %d communities, want the 4 sectors: %v", len(result), result)
+	}
+	for _, members := range result {
+		sector := (members[0] - 1) / 5
+		for _, id := range members {
+			if (id-1)/5 != sector {
+				t.Errorf("community %v mixes sectors", members)
+				break
+			}
+		}
+	}
+
+	// Reported modularity matches the partition it returned
+	if got, want := louvain.Modularity(), Modularity(louvain.buildAdjacency(), result, config.Resolution); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Modularity() = %v, want %v", got, want)
+	}
+	if louvain.Modularity() <= 0.5 {
+		t.Errorf("Modularity() = %v, want > 0.5 for four well-separated sectors", louvain.Modularity())
+	}
+}
+
+func TestCommunityDetectors_FinancialGraph(t *testing.T) {
+	entityStore, relStore := createFinancialGraph()
+	config := DefaultLeidenConfig()
+
+	for _, algorithm := range []string{AlgorithmLeiden, AlgorithmLouvain} {
+		detector, err := NewCommunityDetector(algorithm, entityStore, relStore, config)
+		if err != nil {
+			t.Fatalf("NewCommunityDetector(%q) error: %v", algorithm, err)
+		}
+		result := detector.ComputeCommunities()
+		assertPartition(t, entityStore, result)
+		if detector.Modularity() <= 0 {
+			t.Errorf("%s modularity = %v, want > 0", algorithm, detector.Modularity())
+		}
+	}
+
+	// Louvain is deterministic for a seed
+	first := NewLouvain(entityStore, relStore, config)
+	second := NewLouvain(entityStore, relStore, config)
+	if a, b := first.ComputeCommunities(), second.ComputeCommunities(); len(a) != len(b) || first.Modularity() != second.Modularity() {
+		t.Errorf("same seed gave different partitions: %v vs %v", a, b)
+	}
+}
+
+func TestLouvain_EdgeCases(t *testing.T) {
+	// No entities
+	louvain := NewLouvain(newMockEntityStore(), newMockRelationshipStore(), DefaultLeidenConfig())
+	if result := louvain.ComputeCommunities(); len(result) != 0 {
+		t.Errorf("empty graph gave %v", result)
+	}
+
+	// Isolated entities stay on their own
+	entityStore := newMockEntityStore()
+	for i := uint64(1); i <= 3; i++ {
+		entityStore.Add(&types.Entity{ID: i, Title: "E" + itoa(int(i)), Type: "test"})
+	}
+	louvain = NewLouvain(entityStore, newMockRelationshipStore(), DefaultLeidenConfig())
+	result := louvain.ComputeCommunities()
+	assertPartition(t, entityStore, result)
+	if len(result) != 3 {
+		t.Errorf("isolated entities gave %d communities, want 3", len(result))
+	}
+
+	// Two triangles joined by one edge split at the bridge
+	entityStore, relStore := createTwoTrianglesGraph()
+	louvain = NewLouvain(entityStore, relStore, DefaultLeidenConfig())
+	if result := louvain.ComputeCommunities(); len(result) != 2 {
+		t.Errorf("two triangles gave %v, want 2 communities", result)
+	}
+	if got, want := louvain.Modularity(), 5.0/14.0; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Modularity() = %v, want %v", got, want)
+	}
+}
+
+func TestNewCommunityDetector_Unknown(t *testing.T) {
+	if _, err := NewCommunityDetector("spectral", newMockEntityStore(), newMockRelationshipStore(), DefaultLeidenConfig()); err == nil {
+		t.Error("expected error for unknown algorithm")
+	}
+	if d, err := NewCommunityDetector("", newMockEntityStore(), newMockRelationshipStore(), DefaultLeidenConfig()); err != nil {
+		t.Errorf("empty algorithm error: %v", err)
+	} else if _, ok := d.(*Leiden); !ok {
+		t.Errorf("empty algorithm gave %T, want *Leiden", d)
+	}
+}
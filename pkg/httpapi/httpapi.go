@@ -0,0 +1,168 @@
+// Package httpapi serves the core GibRAM commands as JSON over HTTP
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gibram-io/gibram/pkg/codec"
+	"github.com/gibram-io/gibram/pkg/server"
+	pb "github.com/gibram-io/gibram/proto/gibrampb"
+	"google.golang.org/protobuf/proto"
+)
+
+// SessionHeader names the request header carrying the session ID
+const SessionHeader = "X-Gibram-Session"
+
+// maxBodySize bounds request bodies, matching the default protocol frame
+// limit
+const maxBodySize = 4 * 1024 * 1024
+
+// Handler translates HTTP requests into protocol commands run by a Server,
+// so they go through the same permission checks, rate limits, WAL and audit
+// log as commands over TCP. Bodies and responses are the protojson form of
+// the command's messages, with proto field names:
+//
+//	POST /v1/entities  {"external_id":"e1","title":"Alice","type":"person","embedding":[...]}
+//	-> 200             {"id":"1"}
+//
+// Clients authenticate with "Authorization: Bearer <api key>" and name the
+// session in the X-Gibram-Session header.
+type Handler struct {
+	srv *server.Server
+	mux *http.ServeMux
+}
+
+// NewHandler returns a Handler running commands on srv
+func NewHandler(srv *server.Server) *Handler {
+	h := &Handler{srv: srv, mux: http.NewServeMux()}
+
+	h.mux.Handle("GET /v1/info", h.command(pb.CommandType_CMD_INFO, noBody))
+	h.mux.Handle("POST /v1/query", h.command(pb.CommandType_CMD_QUERY, jsonBody))
+
+	collections := []struct {
+		path     string
+		add, get pb.CommandType
+	}{
+		{"documents", pb.CommandType_CMD_ADD_DOCUMENT, pb.CommandType_CMD_GET_DOCUMENT},
+		{"textunits", pb.CommandType_CMD_ADD_TEXTUNIT, pb.CommandType_CMD_GET_TEXTUNIT},
+		{"entities", pb.CommandType_CMD_ADD_ENTITY, pb.CommandType_CMD_GET_ENTITY},
+		{"relationships", pb.CommandType_CMD_ADD_RELATIONSHIP, pb.CommandType_CMD_GET_RELATIONSHIP},
+		{"communities", pb.CommandType_CMD_ADD_COMMUNITY, pb.CommandType_CMD_GET_COMMUNITY},
+	}
+	for _, c := range collections {
+		h.mux.Handle("POST /v1/"+c.path, h.command(c.add, jsonBody))
+		h.mux.Handle("GET /v1/"+c.path+"/{id}", h.command(c.get, pathID))
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// payloadFunc builds a command's protobuf payload from the request
+type payloadFunc func(cmd pb.CommandType, r *http.Request) ([]byte, error)
+
+func noBody(pb.CommandType, *http.Request) ([]byte, error) {
+	return nil, nil
+}
+
+func jsonBody(cmd pb.CommandType, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return codec.PayloadFromJSON(cmd, pb.CommandType_CMD_UNKNOWN, body)
+}
+
+func pathID(_ pb.CommandType, r *http.Request) ([]byte, error) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid id")
+	}
+	return proto.Marshal(&pb.GetByIDRequest{Id: id})
+}
+
+// command returns the handler running cmd with the payload from payload
+func (h *Handler) command(cmd pb.CommandType, payload payloadFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.srv.AddrAllowed(r.RemoteAddr) {
+			writeError(w, http.StatusForbidden, "address not allowed")
+			return
+		}
+
+		apiKey, err := h.srv.Authenticate(bearerToken(r))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+		data, err := payload(cmd, r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		resp := h.srv.Execute(r.Context(), apiKey, &pb.Envelope{
+			Version:   server.ProtocolVersion,
+			CmdType:   cmd,
+			Payload:   data,
+			SessionId: r.Header.Get(SessionHeader),
+		})
+		if resp.CmdType == pb.CommandType_CMD_ERROR {
+			var e pb.Error
+			_ = proto.Unmarshal(resp.Payload, &e)
+			writeError(w, errorStatus(e.Message), e.Message)
+			return
+		}
+
+		body := []byte("{}")
+		if codec.PayloadMessage(resp.CmdType, cmd) != nil {
+			if body, err = codec.PayloadToJSON(resp.CmdType, cmd, resp.Payload); err != nil {
+				writeError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	})
+}
+
+// bearerToken returns the API key from the Authorization header
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// errorStatus picks the HTTP status for a command error message
+func errorStatus(msg string) int {
+	switch {
+	case strings.HasPrefix(msg, "permission denied"), strings.HasPrefix(msg, server.ErrCommandDisabled.Error()):
+		return http.StatusForbidden
+	case strings.Contains(msg, "not found"):
+		return http.StatusNotFound
+	case msg == "rate limit exceeded":
+		return http.StatusTooManyRequests
+	case msg == server.ErrCommandTimeout.Error():
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,153 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gibram-io/gibram/pkg/config"
+	"github.com/gibram-io/gibram/pkg/engine"
+	"github.com/gibram-io/gibram/pkg/server"
+)
+
+const testSessionID = "http-session"
+
+// newTestGateway serves a Handler over a server with a read-write key and
+// a read-only key
+func newTestGateway(t *testing.T) (url, writeKey, readKey string) {
+	t.Helper()
+	writeKey, readKey = mustGenerateKey(t), mustGenerateKey(t)
+	cfg := &config.Config{
+		Auth: config.AuthConfig{
+			Keys: []config.APIKeyConfig{
+				{ID: "writer", KeyHash: mustHashKey(t, writeKey), Permissions: []string{config.PermRead, config.PermWrite}},
+				{ID: "reader", KeyHash: mustHashKey(t, readKey), Permissions: []string{config.PermRead}},
+			},
+		},
+	}
+	ts := httptest.NewServer(NewHandler(server.NewServerWithConfig(engine.NewEngine(4), cfg)))
+	t.Cleanup(ts.Close)
+	return ts.URL, writeKey, readKey
+}
+
+func mustGenerateKey(t *testing.T) string {
+	t.Helper()
+	key, err := config.GenerateAPIKey()
+	if err != nil {
+		t.Fatalf("GenerateAPIKey() error: %v", err)
+	}
+	return key
+}
+
+func mustHashKey(t *testing.T, key string) string {
+	t.Helper()
+	hash, err := config.HashAPIKey(key)
+	if err != nil {
+		t.Fatalf("HashAPIKey() error: %v", err)
+	}
+	return hash
+}
+
+// do sends a request with key as bearer token ("" = none) and decodes the
+// JSON response into out
+func do(t *testing.T, method, url, key, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error: %v", err)
+	}
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	req.Header.Set(SessionHeader, testSessionID)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("%s %s: decode response: %v", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestHandler_AddEntityAndQuery(t *testing.T) {
+	url, writeKey, readKey := newTestGateway(t)
+
+	var added struct {
+		ID string `json:"id"`
+	}
+	status := do(t, "POST", url+"/v1/entities", writeKey,
+		`{"external_id":"e1","title":"Alice","type":"person","embedding":[1,0,0,0]}`, &added)
+	if status != http.StatusOK || added.ID == "" {
+		t.Fatalf("POST /v1/entities = %d %+v, want 200 with an id", status, added)
+	}
+
+	var entity struct {
+		Title string `json:"title"`
+	}
+	if status := do(t, "GET", url+"/v1/entities/"+added.ID, readKey, "", &entity); status != http.StatusOK || !strings.EqualFold(entity.Title, "Alice") {
+		t.Fatalf("GET /v1/entities/%s = %d %+v, want Alice", added.ID, status, entity)
+	}
+
+	var result struct {
+		QueryID  string `json:"query_id"`
+		Entities []struct {
+			Entity struct {
+				ID    string `json:"id"`
+				Title string `json:"title"`
+			} `json:"entity"`
+		} `json:"entities"`
+	}
+	status = do(t, "POST", url+"/v1/query", readKey,
+		`{"query_vector":[1,0,0,0],"search_types":["entity"],"top_k":5,"max_entities":5}`, &result)
+	if status != http.StatusOK {
+		t.Fatalf("POST /v1/query = %d, want 200", status)
+	}
+	if len(result.Entities) != 1 || result.Entities[0].Entity.ID != added.ID || !strings.EqualFold(result.Entities[0].Entity.Title, "Alice") {
+		t.Fatalf("query entities = %+v, want Alice", result.Entities)
+	}
+
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if status := do(t, "GET", url+"/v1/entities/999", readKey, "", &errResp); status != http.StatusNotFound {
+		t.Errorf("GET missing entity = %d (%s), want 404", status, errResp.Error)
+	}
+	if status := do(t, "POST", url+"/v1/query", readKey, `{"top_k":"many"}`, &errResp); status != http.StatusBadRequest {
+		t.Errorf("POST malformed query = %d (%s), want 400", status, errResp.Error)
+	}
+}
+
+func TestHandler_AuthRejected(t *testing.T) {
+	url, _, readKey := newTestGateway(t)
+	entity := `{"external_id":"e1","title":"Alice","type":"person","embedding":[1,0,0,0]}`
+	query := `{"query_vector":[1,0,0,0],"search_types":["entity"],"top_k":5}`
+
+	tests := []struct {
+		name         string
+		path, body   string
+		key          string
+		wantStatus   int
+		wantErrorHas string
+	}{
+		{"query without token", "/v1/query", query, "", http.StatusUnauthorized, "invalid"},
+		{"query with unknown key", "/v1/query", query, "not-a-key", http.StatusUnauthorized, "invalid"},
+		{"add entity without token", "/v1/entities", entity, "", http.StatusUnauthorized, "invalid"},
+		{"add entity with read-only key", "/v1/entities", entity, readKey, http.StatusForbidden, "permission denied"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errResp struct {
+				Error string `json:"error"`
+			}
+			status := do(t, "POST", url+tt.path, tt.key, tt.body, &errResp)
+			if status != tt.wantStatus || !strings.Contains(errResp.Error, tt.wantErrorHas) {
+				t.Errorf("status = %d, error = %q; want %d with %q", status, errResp.Error, tt.wantStatus, tt.wantErrorHas)
+			}
+		})
+	}
+}
@@ -371,6 +371,29 @@ func TestTracker_Check_Warning(t *testing.T) {
 	}
 }
 
+func TestTracker_Usage(t *testing.T) {
+	tracker := NewTracker(1) // Any usage is over the limit
+
+	called := false
+	tracker.SetAlertCallback(func(level string, usedBytes, maxBytes int64) {
+		called = true
+	})
+
+	usedBytes, maxBytes, level := tracker.Usage()
+	if usedBytes <= 0 || maxBytes != 1 {
+		t.Errorf("Usage() = (%d, %d), want positive usage and max 1", usedBytes, maxBytes)
+	}
+	if level != "critical" {
+		t.Errorf("Level = %q, want 'critical'", level)
+	}
+	if called {
+		t.Error("Usage() should not raise alerts")
+	}
+
+	if _, _, level := NewTracker(0).Usage(); level != "ok" {
+		t.Errorf("Unlimited tracker level = %q, want 'ok'", level)
+	}
+}
 func TestTracker_SetAlertCallback(t *testing.T) {
 	tracker := NewTracker(100) // Very low limit
 
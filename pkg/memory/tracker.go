@@ -45,26 +45,37 @@ func (t *Tracker) Check() (usedBytes int64, level string) {
 	t.mu.Unlock()
 
 	usedBytes = int64(stats.Alloc)
-
-	if t.maxBytes > 0 {
-		if usedBytes >= t.maxBytes {
-			level = "critical"
-		} else if usedBytes >= t.warningBytes {
-			level = "warning"
-		} else {
-			level = "ok"
-		}
-
-		if cb != nil && level != "ok" {
-			cb(level, usedBytes, t.maxBytes)
-		}
-	} else {
-		level = "ok"
+	level = t.level(usedBytes)
+	if cb != nil && level != "ok" {
+		cb(level, usedBytes, t.maxBytes)
 	}
 
 	return usedBytes, level
 }
 
+// Usage reports current memory usage, the limit (0 = unlimited) and its
+// level like Check, without raising alerts
+func (t *Tracker) Usage() (usedBytes, maxBytes int64, level string) {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	usedBytes = int64(stats.Alloc)
+	return usedBytes, t.maxBytes, t.level(usedBytes)
+}
+
+// level classifies usedBytes against the limit and warning threshold
+func (t *Tracker) level(usedBytes int64) string {
+	switch {
+	case t.maxBytes <= 0:
+		return "ok"
+	case usedBytes >= t.maxBytes:
+		return "critical"
+	case usedBytes >= t.warningBytes:
+		return "warning"
+	default:
+		return "ok"
+	}
+}
+
 // GetStats returns last memory stats
 func (t *Tracker) GetStats() (stats runtime.MemStats, lastCheck time.Time) {
 	t.mu.RLock()
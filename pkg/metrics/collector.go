@@ -12,6 +12,7 @@ type Collector struct {
 	counters sync.Map // map[string]*atomic.Int64
 	gauges   sync.Map // map[string]*atomic.Int64
 	histos   sync.Map // map[string]*Histogram
+	commands sync.Map // map[string]*commandMetrics
 
 	startTime time.Time
 }
@@ -101,6 +102,7 @@ func (c *Collector) Reset() {
 	c.counters = sync.Map{}
 	c.gauges = sync.Map{}
 	c.histos = sync.Map{}
+	c.commands = sync.Map{}
 	c.startTime = time.Now()
 }
 
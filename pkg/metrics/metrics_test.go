@@ -3,6 +3,7 @@ package metrics
 
 import (
 	"math"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -710,3 +711,119 @@ func TestHistogram_InfNaN(t *testing.T) {
 	// Just verify it doesn't panic and returns something
 	_ = stats
 }
+
+// =============================================================================
+// Prometheus Tests
+// =============================================================================
+
+func TestBucketHistogram_Cumulative(t *testing.T) {
+	h := NewBucketHistogram([]float64{0.001, 0.01, 0.1})
+	h.Observe(500 * time.Microsecond)
+	h.Observe(time.Millisecond) // on a bound: counted in that bucket
+	h.Observe(5 * time.Millisecond)
+	h.Observe(time.Second)
+
+	bounds, counts := h.Buckets()
+	if len(counts) != len(bounds)+1 {
+		t.Fatalf("len(counts) = %d, want %d", len(counts), len(bounds)+1)
+	}
+	want := []uint64{2, 3, 3, 4}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("counts = %v, want %v", counts, want)
+			break
+		}
+	}
+	if h.Count() != 4 {
+		t.Errorf("Count() = %d, want 4", h.Count())
+	}
+	if want := time.Second + 6500*time.Microsecond; h.Sum() != want {
+		t.Errorf("Sum() = %v, want %v", h.Sum(), want)
+	}
+}
+
+func TestCollector_WritePrometheus(t *testing.T) {
+	c := NewCollector()
+	c.Counter("requests", 3)
+	c.Gauge("memory.alloc_bytes", 1024)
+	c.RecordCommand("query", 2*time.Millisecond, false)
+	c.RecordCommand("query", 3*time.Millisecond, true)
+
+	var buf strings.Builder
+	if err := c.WritePrometheus(&buf); err != nil {
+		t.Fatalf("WritePrometheus() error: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"gibram_requests_total 3",
+		"# TYPE gibram_memory_alloc_bytes gauge",
+		"gibram_memory_alloc_bytes 1024",
+		`gibram_command_total{command="query"} 2`,
+		`gibram_command_errors_total{command="query"} 1`,
+		`gibram_command_duration_seconds_bucket{command="query",le="0.0025"} 1`,
+		`gibram_command_duration_seconds_bucket{command="query",le="+Inf"} 2`,
+		`gibram_command_duration_seconds_sum{command="query"} 0.005`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	c.Reset()
+	buf.Reset()
+	if err := c.WritePrometheus(&buf); err != nil {
+		t.Fatalf("WritePrometheus() error: %v", err)
+	}
+	if strings.Contains(buf.String(), "gibram_command_total") {
+		t.Error("command metrics survived Reset()")
+	}
+}
+
+func TestBucketHistogram_Quantile(t *testing.T) {
+	h := NewBucketHistogram([]float64{0.001, 0.01, 0.1})
+	if got := h.Quantile(0.5); got != 0 {
+		t.Errorf("Quantile() on empty histogram = %v, want 0", got)
+	}
+
+	// 10 observations in (1ms, 10ms]: quantiles interpolate across the bucket
+	for i := 0; i < 10; i++ {
+		h.Observe(5 * time.Millisecond)
+	}
+	if got, want := h.Quantile(0.5), 5500*time.Microsecond; got != want {
+		t.Errorf("Quantile(0.5) = %v, want %v", got, want)
+	}
+	if got, want := h.Quantile(1), 10*time.Millisecond; got != want {
+		t.Errorf("Quantile(1) = %v, want %v", got, want)
+	}
+
+	// Above the last bound: reported as the last bound
+	for i := 0; i < 90; i++ {
+		h.Observe(time.Second)
+	}
+	if got, want := h.Quantile(0.99), 100*time.Millisecond; got != want {
+		t.Errorf("Quantile(0.99) = %v, want %v", got, want)
+	}
+}
+
+func TestCollector_CommandStats(t *testing.T) {
+	c := NewCollector()
+	c.RecordCommand("query", 2*time.Millisecond, false)
+	c.RecordCommand("query", 4*time.Millisecond, true)
+	c.RecordCommand("add_entity", time.Millisecond, false)
+
+	stats := c.CommandStats()
+	if len(stats) != 2 || stats[0].Command != "add_entity" || stats[1].Command != "query" {
+		t.Fatalf("CommandStats() = %+v, want add_entity then query", stats)
+	}
+	q := stats[1]
+	if q.Count != 2 || q.Errors != 1 {
+		t.Errorf("query count/errors = %d/%d, want 2/1", q.Count, q.Errors)
+	}
+	if q.Avg != 3*time.Millisecond {
+		t.Errorf("query Avg = %v, want 3ms", q.Avg)
+	}
+	if q.P50 <= 0 || q.P50 > q.P95 || q.P95 > q.P99 || q.P99 > 5*time.Millisecond {
+		t.Errorf("query percentiles p50=%v p95=%v p99=%v not ordered within the 5ms bucket", q.P50, q.P95, q.P99)
+	}
+}
@@ -0,0 +1,284 @@
+// Package metrics provides metrics collection for GibRAM
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLatencyBuckets are the command latency histogram bounds in seconds
+var DefaultLatencyBuckets = []float64{
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+}
+
+// BucketHistogram counts observations into fixed cumulative buckets, as
+// Prometheus histograms do. It is lock-free and never forgets observations.
+type BucketHistogram struct {
+	bounds []float64
+	counts []atomic.Uint64 // per bucket, non-cumulative; last is +Inf
+	count  atomic.Uint64
+	sumNs  atomic.Int64 // sum of observations in nanoseconds
+}
+
+// NewBucketHistogram creates a histogram with the given upper bounds in seconds
+func NewBucketHistogram(bounds []float64) *BucketHistogram {
+	return &BucketHistogram{
+		bounds: bounds,
+		counts: make([]atomic.Uint64, len(bounds)+1),
+	}
+}
+
+// Observe records one duration
+func (h *BucketHistogram) Observe(d time.Duration) {
+	sec := d.Seconds()
+	i := sort.SearchFloat64s(h.bounds, sec)
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sumNs.Add(int64(d))
+}
+
+// Buckets returns the upper bounds and cumulative counts; the final count
+// is the +Inf bucket
+func (h *BucketHistogram) Buckets() ([]float64, []uint64) {
+	cumulative := make([]uint64, len(h.counts))
+	var total uint64
+	for i := range h.counts {
+		total += h.counts[i].Load()
+		cumulative[i] = total
+	}
+	return h.bounds, cumulative
+}
+
+// Count returns the number of observations
+func (h *BucketHistogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// Sum returns the total of all observations
+func (h *BucketHistogram) Sum() time.Duration {
+	return time.Duration(h.sumNs.Load())
+}
+
+// Quantile estimates the q-th quantile (0 < q <= 1) by linear
+// interpolation within the bucket that holds it, as Prometheus'
+// histogram_quantile does. Observations above the last bound are reported
+// as that bound.
+func (h *BucketHistogram) Quantile(q float64) time.Duration {
+	bounds, counts := h.Buckets()
+	total := counts[len(counts)-1]
+	if total == 0 || len(bounds) == 0 {
+		return 0
+	}
+
+	rank := q * float64(total)
+	i := sort.Search(len(counts), func(i int) bool { return float64(counts[i]) >= rank })
+	if i >= len(bounds) {
+		return secondsToDuration(bounds[len(bounds)-1])
+	}
+
+	lower, prev := 0.0, uint64(0)
+	if i > 0 {
+		lower, prev = bounds[i-1], counts[i-1]
+	}
+	inBucket := counts[i] - prev
+	if inBucket == 0 {
+		return secondsToDuration(bounds[i])
+	}
+	return secondsToDuration(lower + (bounds[i]-lower)*(rank-float64(prev))/float64(inBucket))
+}
+
+func secondsToDuration(sec float64) time.Duration {
+	return time.Duration(sec * float64(time.Second))
+}
+
+// commandMetrics holds per-command counters and latency
+type commandMetrics struct {
+	total   atomic.Int64
+	errors  atomic.Int64
+	latency *BucketHistogram
+}
+
+// CommandStats summarizes one command's counters and latency
+type CommandStats struct {
+	Command string
+	Count   int64
+	Errors  int64
+	Avg     time.Duration
+	P50     time.Duration
+	P95     time.Duration
+	P99     time.Duration
+}
+
+// CommandStats returns stats for every recorded command, sorted by name
+func (c *Collector) CommandStats() []CommandStats {
+	var stats []CommandStats
+	c.commands.Range(func(key, value any) bool {
+		m := value.(*commandMetrics)
+		st := CommandStats{
+			Command: key.(string),
+			Count:   m.total.Load(),
+			Errors:  m.errors.Load(),
+			P50:     m.latency.Quantile(0.50),
+			P95:     m.latency.Quantile(0.95),
+			P99:     m.latency.Quantile(0.99),
+		}
+		if n := m.latency.Count(); n > 0 {
+			st.Avg = m.latency.Sum() / time.Duration(n)
+		}
+		stats = append(stats, st)
+		return true
+	})
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Command < stats[j].Command })
+	return stats
+}
+
+// RecordCommand records one processed protocol command
+func (c *Collector) RecordCommand(command string, duration time.Duration, failed bool) {
+	val, ok := c.commands.Load(command)
+	if !ok {
+		val, _ = c.commands.LoadOrStore(command, &commandMetrics{latency: NewBucketHistogram(DefaultLatencyBuckets)})
+	}
+	m := val.(*commandMetrics)
+	m.total.Add(1)
+	if failed {
+		m.errors.Add(1)
+	}
+	m.latency.Observe(duration)
+}
+
+// =============================================================================
+// Prometheus Exposition
+// =============================================================================
+
+// Handler serves the collector's metrics in the Prometheus text format
+func Handler(c *Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := c.WritePrometheus(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WritePrometheus writes all metrics in the Prometheus text format. Counter
+// and gauge names are prefixed with gibram_ and sanitized; windowed
+// histograms are written as summaries.
+func (c *Collector) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	snap := c.Snapshot()
+
+	writeFamily(bw, "gibram_uptime_seconds", "gauge", "Seconds since the collector started")
+	fmt.Fprintf(bw, "gibram_uptime_seconds %s\n", formatFloat(snap.Uptime.Seconds()))
+
+	for _, name := range sortedKeys(snap.Counters) {
+		metric := promName(name)
+		if !strings.HasSuffix(metric, "_total") {
+			metric += "_total"
+		}
+		writeFamily(bw, metric, "counter", "")
+		fmt.Fprintf(bw, "%s %d\n", metric, snap.Counters[name])
+	}
+
+	for _, name := range sortedKeys(snap.Gauges) {
+		metric := promName(name)
+		writeFamily(bw, metric, "gauge", "")
+		fmt.Fprintf(bw, "%s %d\n", metric, snap.Gauges[name])
+	}
+
+	for _, name := range sortedKeys(snap.Histograms) {
+		metric := promName(name)
+		h := snap.Histograms[name]
+		writeFamily(bw, metric, "summary", "")
+		for _, q := range []struct {
+			quantile string
+			value    float64
+		}{{"0.5", h.P50}, {"0.9", h.P90}, {"0.95", h.P95}, {"0.99", h.P99}} {
+			fmt.Fprintf(bw, "%s{quantile=%q} %s\n", metric, q.quantile, formatFloat(q.value))
+		}
+		fmt.Fprintf(bw, "%s_sum %s\n", metric, formatFloat(h.Sum))
+		fmt.Fprintf(bw, "%s_count %d\n", metric, h.Count)
+	}
+
+	c.writeCommandMetrics(bw)
+	return bw.Flush()
+}
+
+func (c *Collector) writeCommandMetrics(bw *bufio.Writer) {
+	commands := make(map[string]*commandMetrics)
+	c.commands.Range(func(key, value any) bool {
+		commands[key.(string)] = value.(*commandMetrics)
+		return true
+	})
+	if len(commands) == 0 {
+		return
+	}
+	names := sortedKeys(commands)
+
+	writeFamily(bw, "gibram_command_total", "counter", "Protocol commands processed")
+	for _, name := range names {
+		fmt.Fprintf(bw, "gibram_command_total{command=%q} %d\n", name, commands[name].total.Load())
+	}
+
+	writeFamily(bw, "gibram_command_errors_total", "counter", "Protocol commands that returned an error")
+	for _, name := range names {
+		fmt.Fprintf(bw, "gibram_command_errors_total{command=%q} %d\n", name, commands[name].errors.Load())
+	}
+
+	writeFamily(bw, "gibram_command_duration_seconds", "histogram", "Protocol command latency")
+	for _, name := range names {
+		h := commands[name].latency
+		bounds, counts := h.Buckets()
+		for i, bound := range bounds {
+			fmt.Fprintf(bw, "gibram_command_duration_seconds_bucket{command=%q,le=%q} %d\n", name, formatFloat(bound), counts[i])
+		}
+		fmt.Fprintf(bw, "gibram_command_duration_seconds_bucket{command=%q,le=\"+Inf\"} %d\n", name, counts[len(counts)-1])
+		fmt.Fprintf(bw, "gibram_command_duration_seconds_sum{command=%q} %s\n", name, formatFloat(h.Sum().Seconds()))
+		fmt.Fprintf(bw, "gibram_command_duration_seconds_count{command=%q} %d\n", name, h.Count())
+	}
+}
+
+func writeFamily(bw *bufio.Writer, name, kind, help string) {
+	if help != "" {
+		fmt.Fprintf(bw, "# HELP %s %s\n", name, help)
+	}
+	fmt.Fprintf(bw, "# TYPE %s %s\n", name, kind)
+}
+
+// promName turns a collector name like "memory.alloc_bytes" into a
+// Prometheus metric name like "gibram_memory_alloc_bytes"
+func promName(name string) string {
+	var b strings.Builder
+	b.WriteString("gibram_")
+	for _, r := range name {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
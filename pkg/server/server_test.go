@@ -2,23 +2,36 @@
 package server
 
 import (
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/gibram-io/gibram/pkg/audit"
 	"github.com/gibram-io/gibram/pkg/backup"
 	"github.com/gibram-io/gibram/pkg/codec"
 	"github.com/gibram-io/gibram/pkg/config"
 	"github.com/gibram-io/gibram/pkg/engine"
+	"github.com/gibram-io/gibram/pkg/logging"
+	"github.com/gibram-io/gibram/pkg/memory"
+	"github.com/gibram-io/gibram/pkg/metrics"
+	"github.com/gibram-io/gibram/pkg/shutdown"
+	"github.com/gibram-io/gibram/pkg/types"
 	pb "github.com/gibram-io/gibram/proto/gibrampb"
 	"google.golang.org/protobuf/proto"
 )
 
-var _ = backup.SyncNever // Suppress unused import (used in createTestServerWithWAL)
-
 // =============================================================================
 // Test Helpers
 // =============================================================================
@@ -47,6 +60,48 @@ func createTestServer(t *testing.T) (*Server, string) {
 	return srv, addr
 }
 
+// createTestServerWithConfig starts a test server with cfg
+func createTestServerWithConfig(t *testing.T, cfg *config.Config) (*Server, string) {
+	srv := NewServerWithConfig(engine.NewEngine(testVectorDim), cfg)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	closeSilently(ln)
+
+	if err := srv.Start(addr); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	return srv, addr
+}
+
+// createTestServerWithWAL starts a test server that logs writes to a WAL in walDir
+func createTestServerWithWAL(t *testing.T, walDir string) (*Server, *backup.WAL, string) {
+	wal, err := backup.NewWAL(walDir, backup.SyncNever)
+	if err != nil {
+		t.Fatalf("NewWAL() error: %v", err)
+	}
+	eng := engine.NewEngine(testVectorDim)
+	srv := NewServer(eng)
+	srv.SetWAL(wal)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	closeSilently(ln)
+
+	if err := srv.Start(addr); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+
+	return srv, wal, addr
+}
+
 // sendCommand sends a command using proper codec encoding and returns the response
 func sendCommand(conn net.Conn, cmdType pb.CommandType, payload proto.Message) (*pb.Envelope, error) {
 	// Marshal payload
@@ -69,7 +124,7 @@ func sendCommand(conn net.Conn, cmdType pb.CommandType, payload proto.Message) (
 	}
 
 	// Encode using codec (includes codec marker byte)
-	frameData, err := codec.EncodeEnvelope(env)
+	frameData, err := codec.EncodeEnvelope(env, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -582,6 +637,35 @@ func TestServerIntegration_Health(t *testing.T) {
 	}
 }
 
+func TestHandleHealth_MemoryAndWAL(t *testing.T) {
+	srv, _, _ := createTestServerWithWAL(t, t.TempDir())
+	defer srv.Stop()
+
+	health := func() *pb.HealthResponse {
+		var resp pb.HealthResponse
+		mustUnmarshal(t, srv.handleHealth(), &resp)
+		return &resp
+	}
+
+	srv.SetMemoryTracker(memory.NewTracker(1 << 40))
+	resp := health()
+	for _, key := range []string{"engine", "backup", "sessions", "memory", "memory_used_percent", "wal", "wal_lag"} {
+		if _, ok := resp.Components[key]; !ok {
+			t.Errorf("Components missing %q: %v", key, resp.Components)
+		}
+	}
+	if resp.Status != "ok" || resp.Components["memory"] != "ok" || resp.Components["wal"] != "ok" {
+		t.Errorf("Health = %s %v, want ok", resp.Status, resp.Components)
+	}
+
+	// Any usage is over a 1-byte limit
+	srv.SetMemoryTracker(memory.NewTracker(1))
+	resp = health()
+	if resp.Status != "degraded" || resp.Components["memory"] != "critical" {
+		t.Errorf("Health with high memory = %s %v, want degraded", resp.Status, resp.Components)
+	}
+}
+
 // =============================================================================
 // Entity Operations Integration Tests
 // =============================================================================
@@ -946,6 +1030,231 @@ func TestServerIntegration_SetTTL(t *testing.T) {
 }
 */
 
+// =============================================================================
+// Graph and Session Inspection Integration Tests
+// =============================================================================
+
+func TestServerIntegration_RelationshipTypeStats(t *testing.T) {
+	srv, addr := createTestServer(t)
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	embedding := make([]float32, testVectorDim)
+	var ids []uint64
+	for _, extID := range []string{"ent-rt-1", "ent-rt-2", "ent-rt-3"} {
+		resp := mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{
+			ExternalId: extID, Title: extID, Type: "test", Embedding: embedding,
+		})
+		var ok pb.OkWithID
+		mustUnmarshal(t, resp.Payload, &ok)
+		ids = append(ids, ok.Id)
+	}
+	for i, relType := range []string{"KNOWS", "KNOWS", "LIKES"} {
+		mustSendCommand(t, conn, pb.CommandType_CMD_ADD_RELATIONSHIP, &pb.AddRelationshipRequest{
+			ExternalId: fmt.Sprintf("rel-rt-%d", i), SourceId: ids[i], TargetId: ids[(i+1)%3],
+			Type: relType, Weight: 1.0,
+		})
+	}
+
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_RELATIONSHIP_TYPE_STATS, &pb.RelationshipTypeStatsRequest{Limit: 1})
+	if resp.CmdType != pb.CommandType_CMD_RELATIONSHIP_TYPE_STATS_RESPONSE {
+		t.Fatalf("Expected CMD_RELATIONSHIP_TYPE_STATS_RESPONSE, got %v", resp.CmdType)
+	}
+
+	var statsResp pb.RelationshipTypeStatsResponse
+	mustUnmarshal(t, resp.Payload, &statsResp)
+	if statsResp.TotalRelationships != 3 {
+		t.Errorf("Expected 3 total relationships, got %d", statsResp.TotalRelationships)
+	}
+	if len(statsResp.Stats) != 1 || statsResp.Stats[0].Type != "KNOWS" || statsResp.Stats[0].Count != 2 {
+		t.Errorf("Unexpected stats: %v", statsResp.Stats)
+	}
+}
+
+func TestServerIntegration_QueryStatsSummary(t *testing.T) {
+	srv, addr := createTestServer(t)
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	embedding := make([]float32, testVectorDim)
+	embedding[0] = 1
+	mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{
+		ExternalId: "ent-qs", Title: "ent-qs", Type: "test", Embedding: embedding,
+	})
+
+	query := &pb.QueryRequest{
+		QueryVector: embedding,
+		SearchTypes: []string{"entity"},
+		TopK:        5,
+		KHops:       2,
+	}
+	for i := 0; i < 2; i++ {
+		resp := mustSendCommand(t, conn, pb.CommandType_CMD_QUERY, query)
+		if resp.CmdType != pb.CommandType_CMD_QUERY_RESPONSE {
+			t.Fatalf("Expected CMD_QUERY_RESPONSE, got %v", resp.CmdType)
+		}
+	}
+
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_QUERY_STATS_SUMMARY, &pb.QueryStatsSummaryRequest{
+		WindowSeconds: 60,
+		SessionOnly:   true,
+	})
+	if resp.CmdType != pb.CommandType_CMD_QUERY_STATS_SUMMARY_RESPONSE {
+		t.Fatalf("Expected CMD_QUERY_STATS_SUMMARY_RESPONSE, got %v", resp.CmdType)
+	}
+
+	var summary pb.QueryStatsSummaryResponse
+	mustUnmarshal(t, resp.Payload, &summary)
+	if summary.WindowSeconds != 60 || summary.Count != 2 {
+		t.Errorf("Expected 2 queries over 60s, got %d over %ds", summary.Count, summary.WindowSeconds)
+	}
+	if summary.AvgKHops != 2 || summary.AvgEntities != 1 || summary.EmptyResults != 0 {
+		t.Errorf("Unexpected summary: %+v", &summary)
+	}
+}
+
+func TestServerIntegration_SessionMetadata(t *testing.T) {
+	srv, addr := createTestServer(t)
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	mustSendCommand(t, conn, pb.CommandType_CMD_ADD_DOCUMENT, &pb.AddDocumentRequest{
+		ExternalId: "doc-md", Filename: "md.pdf",
+	})
+
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_SET_SESSION_METADATA, &pb.SetSessionMetadataRequest{
+		Metadata: map[string]string{"tenant": "acme"},
+	})
+	if resp.CmdType != pb.CommandType_CMD_OK {
+		t.Fatalf("Expected CMD_OK, got %v", resp.CmdType)
+	}
+
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_GET_SESSION_METADATA, nil)
+	if resp.CmdType != pb.CommandType_CMD_SESSION_METADATA_RESPONSE {
+		t.Fatalf("Expected CMD_SESSION_METADATA_RESPONSE, got %v", resp.CmdType)
+	}
+	var mdResp pb.SessionMetadataResponse
+	mustUnmarshal(t, resp.Payload, &mdResp)
+	if mdResp.Metadata["tenant"] != "acme" {
+		t.Errorf("Unexpected metadata: %v", mdResp.Metadata)
+	}
+
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_SESSION_INFO, nil)
+	var info pb.SessionInfo
+	mustUnmarshal(t, resp.Payload, &info)
+	if info.Metadata["tenant"] != "acme" {
+		t.Errorf("SessionInfo should include metadata, got %v", info.Metadata)
+	}
+
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_SET_SESSION_METADATA, &pb.SetSessionMetadataRequest{
+		Metadata: map[string]string{"blob": strings.Repeat("x", types.MaxSessionMetadataBytes+1)},
+	})
+	if resp.CmdType != pb.CommandType_CMD_ERROR {
+		t.Errorf("Expected CMD_ERROR for oversized metadata, got %v", resp.CmdType)
+	}
+}
+
+func TestServerIntegration_GraphDiff(t *testing.T) {
+	dataDir := t.TempDir()
+	srv, addr := createTestServerWithConfig(t, &config.Config{Server: config.ServerConfig{DataDir: dataDir}})
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	embedding := make([]float32, testVectorDim)
+	mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{
+		ExternalId: "ent-diff-1", Title: "Diff One", Type: "test", Embedding: embedding,
+	})
+
+	// Snapshot the current state to disk
+	path := filepath.Join(dataDir, "before.gibram")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create snapshot file failed: %v", err)
+	}
+	if err := srv.engine.Snapshot(f); err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	closeSilently(f)
+
+	mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{
+		ExternalId: "ent-diff-2", Title: "Diff Two", Type: "test", Embedding: embedding,
+	})
+
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_GRAPH_DIFF, &pb.GraphDiffRequest{FromPath: path})
+	if resp.CmdType != pb.CommandType_CMD_GRAPH_DIFF_RESPONSE {
+		var errResp pb.Error
+		mustUnmarshal(t, resp.Payload, &errResp)
+		t.Fatalf("Expected CMD_GRAPH_DIFF_RESPONSE, got %v: %s", resp.CmdType, errResp.Message)
+	}
+
+	var diff pb.GraphDiffResponse
+	mustUnmarshal(t, resp.Payload, &diff)
+	if diff.Total != 1 || len(diff.Changes) != 1 {
+		t.Fatalf("Expected 1 change, got %d", diff.Total)
+	}
+	if diff.Changes[0].Op != "added" || diff.Changes[0].Entity.ExternalId != "ent-diff-2" {
+		t.Errorf("Unexpected change: %v", diff.Changes[0])
+	}
+
+	// Relative paths are taken from the data directory, and later pages
+	// come from the cached snapshot
+	mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{
+		ExternalId: "ent-diff-3", Title: "Diff Three", Type: "test", Embedding: embedding,
+	})
+	var added []string
+	for cursor := uint64(0); ; {
+		diff.Reset()
+		mustUnmarshal(t, mustSendCommand(t, conn, pb.CommandType_CMD_GRAPH_DIFF, &pb.GraphDiffRequest{FromPath: "before.gibram", Limit: 1, Cursor: cursor}).Payload, &diff)
+		for _, c := range diff.Changes {
+			added = append(added, c.Entity.GetExternalId())
+		}
+		if diff.NextCursor == 0 || len(added) > 2 {
+			break
+		}
+		cursor = diff.NextCursor
+	}
+	if len(added) != 2 {
+		t.Errorf("Paged diff returned %v, want ent-diff-2 and ent-diff-3", added)
+	}
+
+	// Snapshots outside the data directory are refused
+	outside := filepath.Join(t.TempDir(), "outside.gibram")
+	if err := os.Link(path, outside); err != nil {
+		t.Fatalf("Link snapshot failed: %v", err)
+	}
+	for _, p := range []string{outside, "../" + filepath.Base(filepath.Dir(outside)) + "/outside.gibram"} {
+		if resp := mustSendCommand(t, conn, pb.CommandType_CMD_GRAPH_DIFF, &pb.GraphDiffRequest{FromPath: p}); resp.CmdType != pb.CommandType_CMD_ERROR {
+			t.Errorf("GRAPH_DIFF from %s = %v, want CMD_ERROR", p, resp.CmdType)
+		}
+	}
+
+	// from_path is required
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_GRAPH_DIFF, &pb.GraphDiffRequest{})
+	if resp.CmdType != pb.CommandType_CMD_ERROR {
+		t.Errorf("Expected CMD_ERROR without from_path, got %v", resp.CmdType)
+	}
+}
+
 // =============================================================================
 // Error Handling Integration Tests
 // =============================================================================
@@ -960,15 +1269,831 @@ func TestServerIntegration_UnknownCommand(t *testing.T) {
 	}
 	defer closeSilently(conn)
 
-	// Send unknown command type
-	resp, err := sendCommand(conn, pb.CommandType(9999), nil)
-	if err != nil {
-		t.Fatalf("Send failed: %v", err)
+	// Send unknown command type
+	resp, err := sendCommand(conn, pb.CommandType(9999), nil)
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	// Should get error response
+	if resp.CmdType != pb.CommandType_CMD_ERROR {
+		t.Errorf("Expected CMD_ERROR for unknown command, got %v", resp.CmdType)
+	}
+}
+
+func TestServerIntegration_DisabledCommands(t *testing.T) {
+	eng := engine.NewEngine(testVectorDim)
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			DisabledCommands: []string{"CMD_DELETE_SESSION", "bgrestore", "NOT_A_COMMAND"},
+		},
+	}
+	srv := NewServerWithConfig(eng, cfg)
+	if len(srv.disabledCommands) != 2 {
+		t.Fatalf("Expected 2 disabled commands, got %d", len(srv.disabledCommands))
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	closeSilently(ln)
+	if err := srv.Start(addr); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_DELETE_SESSION, nil)
+	if resp.CmdType != pb.CommandType_CMD_ERROR {
+		t.Fatalf("Expected CMD_ERROR for disabled command, got %v", resp.CmdType)
+	}
+	var errResp pb.Error
+	mustUnmarshal(t, resp.Payload, &errResp)
+	if !strings.Contains(errResp.Message, ErrCommandDisabled.Error()) {
+		t.Errorf("Expected disabled error, got %q", errResp.Message)
+	}
+
+	// Disabled commands inside a pipeline are rejected too
+	sub := &pb.Envelope{Version: ProtocolVersion, CmdType: pb.CommandType_CMD_BGRESTORE, SessionId: testSessionID}
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_PIPELINE, &pb.PipelineRequest{Commands: []*pb.Envelope{sub}})
+	var pipeResp pb.PipelineResponse
+	mustUnmarshal(t, resp.Payload, &pipeResp)
+	if len(pipeResp.Responses) != 1 || pipeResp.Responses[0].CmdType != pb.CommandType_CMD_ERROR {
+		t.Errorf("Expected disabled command in pipeline to fail, got %v", pipeResp.Responses)
+	}
+
+	// Other commands still work and INFO reports the policy
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_INFO, nil)
+	var info pb.InfoResponse
+	mustUnmarshal(t, resp.Payload, &info)
+	want := []string{"CMD_BGRESTORE", "CMD_DELETE_SESSION"}
+	if fmt.Sprint(info.DisabledCommands) != fmt.Sprint(want) {
+		t.Errorf("Expected disabled commands %v, got %v", want, info.DisabledCommands)
+	}
+}
+
+func TestServerIntegration_JSONCodec(t *testing.T) {
+	eng := engine.NewEngine(testVectorDim)
+	srv := NewServerWithConfig(eng, &config.Config{
+		Security: config.SecurityConfig{AllowJSONCodec: true},
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	closeSilently(ln)
+	if err := srv.Start(addr); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	// send writes a JSON request and reads the JSON reply
+	send := func(cmd pb.CommandType, payload string) *pb.Envelope {
+		t.Helper()
+		data := fmt.Sprintf(`{"version":%d,"request_id":1,"cmd_type":%q,"session_id":%q,"payload":%s}`,
+			ProtocolVersion, cmd.String(), testSessionID, payload)
+		frame := make([]byte, 5+len(data))
+		frame[0] = byte(codec.CodecJSON)
+		binary.BigEndian.PutUint32(frame[1:5], uint32(len(data)))
+		copy(frame[5:], data)
+		if _, err := conn.Write(frame); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		if header[0] != byte(codec.CodecJSON) {
+			t.Fatalf("Expected a JSON reply, got codec %d", header[0])
+		}
+		body := make([]byte, binary.BigEndian.Uint32(header[1:5]))
+		if _, err := io.ReadFull(conn, body); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		resp, err := codec.UnmarshalJSONEnvelope(body, cmd)
+		if err != nil {
+			t.Fatalf("UnmarshalJSONEnvelope(%s) error: %v", body, err)
+		}
+		return resp
+	}
+
+	resp := send(pb.CommandType_CMD_ADD_ENTITY, `{"external_id":"e1","title":"ALICE","type":"person"}`)
+	if resp.CmdType != pb.CommandType_CMD_OK {
+		t.Fatalf("Expected CMD_OK, got %v", resp.CmdType)
+	}
+	var ok pb.OkWithID
+	mustUnmarshal(t, resp.Payload, &ok)
+
+	resp = send(pb.CommandType_CMD_GET_ENTITY, fmt.Sprintf(`{"id":"%d"}`, ok.Id))
+	var ent pb.Entity
+	mustUnmarshal(t, resp.Payload, &ent)
+	if ent.Title != "ALICE" {
+		t.Errorf("Expected title ALICE, got %q", ent.Title)
+	}
+
+	// The first frame fixed the codec; a protobuf frame now ends the connection
+	if _, err := sendCommand(conn, pb.CommandType_CMD_PING, nil); err == nil {
+		t.Error("Expected protobuf frame on a JSON connection to fail")
+	}
+}
+
+func TestServerIntegration_JSONCodecDisabled(t *testing.T) {
+	srv, addr := createTestServer(t)
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	frame, err := codec.EncodeJSONEnvelope(&pb.Envelope{Version: ProtocolVersion, CmdType: pb.CommandType_CMD_PING}, pb.CommandType_CMD_UNKNOWN)
+	if err != nil {
+		t.Fatalf("EncodeJSONEnvelope error: %v", err)
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("SetReadDeadline error: %v", err)
+	}
+	if _, _, err := codec.DecodeEnvelope(conn); err == nil {
+		t.Error("Expected the server to close a connection sending JSON by default")
+	}
+}
+
+func TestServerIntegration_SessionScopedKey(t *testing.T) {
+	tenantKey, adminKey := "tenant-key", "admin-key"
+	tenantHash, err := config.HashAPIKey(tenantKey)
+	if err != nil {
+		t.Fatalf("Failed to hash API key: %v", err)
+	}
+	adminHash, err := config.HashAPIKey(adminKey)
+	if err != nil {
+		t.Fatalf("Failed to hash API key: %v", err)
+	}
+	srv, addr := createTestServerWithConfig(t, &config.Config{
+		Auth: config.AuthConfig{
+			Keys: []config.APIKeyConfig{
+				{ID: "tenant-a", KeyHash: tenantHash, Permissions: []string{config.PermWrite}, SessionPrefixes: []string{"tenant-a/"}},
+				{ID: "admin", KeyHash: adminHash, Permissions: []string{config.PermAdmin}, SessionPrefixes: []string{"tenant-a/"}},
+			},
+		},
+	})
+	defer srv.Stop()
+
+	// connect authenticates a connection with key
+	connect := func(key string) net.Conn {
+		t.Helper()
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		var authResp pb.AuthResponse
+		mustUnmarshal(t, mustSendCommand(t, conn, pb.CommandType_CMD_AUTH, &pb.AuthRequest{ApiKey: key}).Payload, &authResp)
+		if !authResp.Success {
+			t.Fatalf("Auth failed: %s", authResp.Message)
+		}
+		return conn
+	}
+	// sendInSession sends a command for session and returns the response
+	sendInSession := func(conn net.Conn, cmdType pb.CommandType, session string, msg proto.Message) *pb.Envelope {
+		t.Helper()
+		payload, err := proto.Marshal(msg)
+		if err != nil {
+			t.Fatalf("Marshal error: %v", err)
+		}
+		frame, err := codec.EncodeEnvelope(&pb.Envelope{
+			Version:   ProtocolVersion,
+			CmdType:   cmdType,
+			SessionId: session,
+			Payload:   payload,
+		}, 0)
+		if err != nil {
+			t.Fatalf("EncodeEnvelope error: %v", err)
+		}
+		if _, err := conn.Write(frame); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		resp, _, err := codec.DecodeEnvelope(conn)
+		if err != nil {
+			t.Fatalf("DecodeEnvelope error: %v", err)
+		}
+		return resp
+	}
+	// addDocument adds a document to session and returns the response type
+	addDocument := func(conn net.Conn, session string) pb.CommandType {
+		t.Helper()
+		return sendInSession(conn, pb.CommandType_CMD_ADD_DOCUMENT, session, &pb.AddDocumentRequest{ExternalId: "doc", Filename: "doc.txt"}).CmdType
+	}
+
+	tenant := connect(tenantKey)
+	defer closeSilently(tenant)
+	admin := connect(adminKey)
+	defer closeSilently(admin)
+
+	// Sessions matching the prefix are allowed, others are denied
+	if got := addDocument(tenant, "tenant-a/docs"); got != pb.CommandType_CMD_OK {
+		t.Errorf("tenant-a/docs: expected CMD_OK, got %v", got)
+	}
+	for _, session := range []string{"tenant-b/docs", "tenant-a", "other"} {
+		if got := addDocument(tenant, session); got != pb.CommandType_CMD_ERROR {
+			t.Errorf("%s: expected CMD_ERROR, got %v", session, got)
+		}
+	}
+
+	// Commands that ignore the session work whatever session the client sets
+	if resp := mustSendCommand(t, tenant, pb.CommandType_CMD_PING, nil); resp.CmdType != pb.CommandType_CMD_PONG {
+		t.Errorf("tenant PING: expected PONG, got %v", resp.CmdType)
+	}
+
+	// Admin keys bypass the prefixes
+	if got := addDocument(admin, "tenant-b/docs"); got != pb.CommandType_CMD_OK {
+		t.Errorf("admin tenant-b/docs: expected CMD_OK, got %v", got)
+	}
+
+	// LIST_SESSIONS only shows the key's own sessions
+	var list pb.ListSessionsResponse
+	mustUnmarshal(t, mustSendCommand(t, tenant, pb.CommandType_CMD_LIST_SESSIONS, nil).Payload, &list)
+	if len(list.Sessions) != 1 || list.Sessions[0].SessionId != "tenant-a/docs" {
+		t.Errorf("tenant LIST_SESSIONS = %v, want only tenant-a/docs", list.Sessions)
+	}
+	mustUnmarshal(t, mustSendCommand(t, admin, pb.CommandType_CMD_LIST_SESSIONS, nil).Payload, &list)
+	if len(list.Sessions) != 2 {
+		t.Errorf("admin LIST_SESSIONS returned %d sessions, want 2", len(list.Sessions))
+	}
+
+	// Paging is applied after the key's scope, so scoped pages are never short
+	list.Reset()
+	mustUnmarshal(t, mustSendCommand(t, tenant, pb.CommandType_CMD_LIST_SESSIONS, &pb.ListSessionsRequest{Prefix: "tenant-", Limit: 1}).Payload, &list)
+	if len(list.Sessions) != 1 || list.Sessions[0].SessionId != "tenant-a/docs" || list.NextCursor != "" {
+		t.Errorf("tenant paged LIST_SESSIONS = %v (next %q), want only tenant-a/docs", list.Sessions, list.NextCursor)
+	}
+	var pages []string
+	for cursor := ""; ; {
+		list.Reset()
+		mustUnmarshal(t, mustSendCommand(t, admin, pb.CommandType_CMD_LIST_SESSIONS, &pb.ListSessionsRequest{Prefix: "tenant-", Limit: 1, Cursor: cursor}).Payload, &list)
+		for _, sess := range list.Sessions {
+			pages = append(pages, sess.SessionId)
+		}
+		if list.NextCursor == "" || len(pages) > 2 {
+			break
+		}
+		cursor = list.NextCursor
+	}
+	if !slices.Equal(pages, []string{"tenant-a/docs", "tenant-b/docs"}) {
+		t.Errorf("admin paged LIST_SESSIONS = %v, want tenant-a/docs then tenant-b/docs", pages)
+	}
+
+	// QUERY_STATS_SUMMARY is limited to the key's session even without session_only
+	if _, err := srv.engine.Query("tenant-b/docs", types.QuerySpec{QueryVector: make([]float32, 64), TopK: 1, KHops: 1}); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	var summary pb.QueryStatsSummaryResponse
+	mustUnmarshal(t, sendInSession(tenant, pb.CommandType_CMD_QUERY_STATS_SUMMARY, "tenant-a/docs", &pb.QueryStatsSummaryRequest{}).Payload, &summary)
+	if summary.Count != 0 {
+		t.Errorf("tenant QUERY_STATS_SUMMARY counted %d queries, want 0", summary.Count)
+	}
+	mustUnmarshal(t, sendInSession(admin, pb.CommandType_CMD_QUERY_STATS_SUMMARY, "tenant-a/docs", &pb.QueryStatsSummaryRequest{}).Payload, &summary)
+	if summary.Count != 1 {
+		t.Errorf("admin QUERY_STATS_SUMMARY counted %d queries, want 1", summary.Count)
+	}
+}
+
+func TestServerIntegration_PerKeyRateLimit(t *testing.T) {
+	defaultKey, loaderKey := "default-key", "loader-key"
+	defaultHash, err := config.HashAPIKey(defaultKey)
+	if err != nil {
+		t.Fatalf("Failed to hash API key: %v", err)
+	}
+	loaderHash, err := config.HashAPIKey(loaderKey)
+	if err != nil {
+		t.Fatalf("Failed to hash API key: %v", err)
+	}
+	srv, addr := createTestServerWithConfig(t, &config.Config{
+		Auth: config.AuthConfig{
+			Keys: []config.APIKeyConfig{
+				{ID: "default", KeyHash: defaultHash, Permissions: []string{config.PermRead}},
+				{ID: "loader", KeyHash: loaderHash, Permissions: []string{config.PermRead}, RateLimit: 1, RateBurst: 20},
+			},
+		},
+		Security: config.SecurityConfig{RateLimit: 1, RateBurst: 3},
+	})
+	defer srv.Stop()
+
+	// allowed authenticates with key and counts PINGs answered before the
+	// first rate limit error
+	allowed := func(key string) int {
+		t.Helper()
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer closeSilently(conn)
+		var authResp pb.AuthResponse
+		mustUnmarshal(t, mustSendCommand(t, conn, pb.CommandType_CMD_AUTH, &pb.AuthRequest{ApiKey: key}).Payload, &authResp)
+		if !authResp.Success {
+			t.Fatalf("Auth failed: %s", authResp.Message)
+		}
+		for i := 0; i < 30; i++ {
+			if resp := mustSendCommand(t, conn, pb.CommandType_CMD_PING, nil); resp.CmdType != pb.CommandType_CMD_PONG {
+				return i
+			}
+		}
+		return 30
+	}
+
+	def, loader := allowed(defaultKey), allowed(loaderKey)
+	if def < 3 || def > 4 {
+		t.Errorf("default key answered %d PINGs, want the server burst of 3", def)
+	}
+	if loader < 20 || loader > 21 {
+		t.Errorf("loader key answered %d PINGs, want its own burst of 20", loader)
+	}
+}
+
+func TestServerIntegration_ConfigReloadOnSIGHUP(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "gibram.yaml")
+	writeConfig := func(rateLimit, rateBurst int) {
+		t.Helper()
+		data := fmt.Sprintf(`tls:
+  auto_cert: false
+auth:
+  keys:
+    - id: reload
+      key: reload-key
+      permissions: [read]
+security:
+  rate_limit: %d
+  rate_burst: %d
+`, rateLimit, rateBurst)
+		if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+	}
+
+	writeConfig(1, 3)
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	srv, addr := createTestServerWithConfig(t, cfg)
+	defer srv.Stop()
+
+	handler := shutdown.NewHandler()
+	handler.OnReload(func() {
+		newCfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			t.Errorf("LoadConfig() on reload error: %v", err)
+			return
+		}
+		if err := srv.Reload(newCfg); err != nil {
+			t.Errorf("Reload() error: %v", err)
+		}
+	})
+	handler.Start()
+	defer handler.Shutdown()
+
+	// allowed authenticates and counts PINGs answered before the first rate
+	// limit error
+	allowed := func() int {
+		t.Helper()
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		defer closeSilently(conn)
+		var authResp pb.AuthResponse
+		mustUnmarshal(t, mustSendCommand(t, conn, pb.CommandType_CMD_AUTH, &pb.AuthRequest{ApiKey: "reload-key"}).Payload, &authResp)
+		if !authResp.Success {
+			t.Fatalf("Auth failed: %s", authResp.Message)
+		}
+		for i := 0; i < 30; i++ {
+			if resp := mustSendCommand(t, conn, pb.CommandType_CMD_PING, nil); resp.CmdType != pb.CommandType_CMD_PONG {
+				return i
+			}
+		}
+		return 30
+	}
+
+	if n := allowed(); n < 3 || n > 4 {
+		t.Fatalf("answered %d PINGs before reload, want the burst of 3", n)
+	}
+
+	writeConfig(1000, 50)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Failed to send SIGHUP: %v", err)
+	}
+
+	// The reload runs asynchronously; the limiter cached for the key must
+	// pick up the new limits
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n := allowed()
+		if n == 30 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("answered %d PINGs after reload, want all 30", n)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestServerIntegration_ReloadRevokesKeys(t *testing.T) {
+	writerHash, err := config.HashAPIKey("writer-key")
+	if err != nil {
+		t.Fatalf("Failed to hash API key: %v", err)
+	}
+	goneHash, err := config.HashAPIKey("gone-key")
+	if err != nil {
+		t.Fatalf("Failed to hash API key: %v", err)
+	}
+	authConfig := func(keys ...config.APIKeyConfig) *config.Config {
+		return &config.Config{Auth: config.AuthConfig{Keys: keys}}
+	}
+	srv, addr := createTestServerWithConfig(t, authConfig(
+		config.APIKeyConfig{ID: "writer", KeyHash: writerHash, Permissions: []string{config.PermWrite}},
+		config.APIKeyConfig{ID: "gone", KeyHash: goneHash, Permissions: []string{config.PermRead}},
+	))
+	defer srv.Stop()
+
+	connect := func(key string) net.Conn {
+		t.Helper()
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		var authResp pb.AuthResponse
+		mustUnmarshal(t, mustSendCommand(t, conn, pb.CommandType_CMD_AUTH, &pb.AuthRequest{ApiKey: key}).Payload, &authResp)
+		if !authResp.Success {
+			t.Fatalf("Auth failed: %s", authResp.Message)
+		}
+		return conn
+	}
+	writer := connect("writer-key")
+	defer closeSilently(writer)
+	gone := connect("gone-key")
+	defer closeSilently(gone)
+
+	addDoc := &pb.AddDocumentRequest{ExternalId: "doc-1", Filename: "doc.txt"}
+	if resp := mustSendCommand(t, writer, pb.CommandType_CMD_ADD_DOCUMENT, addDoc); resp.CmdType != pb.CommandType_CMD_OK {
+		t.Fatalf("ADD_DOCUMENT before reload = %v, want CMD_OK", resp.CmdType)
+	}
+
+	// writer is downgraded to read and scoped to other sessions; gone is removed
+	if err := srv.Reload(authConfig(
+		config.APIKeyConfig{ID: "writer", KeyHash: writerHash, Permissions: []string{config.PermRead}, SessionPrefixes: []string{"other/"}},
+	)); err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+
+	addDoc.ExternalId = "doc-2"
+	if resp := mustSendCommand(t, writer, pb.CommandType_CMD_ADD_DOCUMENT, addDoc); resp.CmdType != pb.CommandType_CMD_ERROR {
+		t.Errorf("ADD_DOCUMENT by a downgraded key = %v, want CMD_ERROR", resp.CmdType)
+	}
+	resp := mustSendCommand(t, writer, pb.CommandType_CMD_GET_DOCUMENT, &pb.GetByIDRequest{Id: 1})
+	var errResp pb.Error
+	mustUnmarshal(t, resp.Payload, &errResp)
+	if resp.CmdType != pb.CommandType_CMD_ERROR || !strings.Contains(errResp.Message, "not allowed") {
+		t.Errorf("GET_DOCUMENT outside the new prefixes = %v %q, want session denied", resp.CmdType, errResp.Message)
+	}
+
+	// The removed key's connection is refused, then closed
+	resp = mustSendCommand(t, gone, pb.CommandType_CMD_PING, nil)
+	mustUnmarshal(t, resp.Payload, &errResp)
+	if resp.CmdType != pb.CommandType_CMD_ERROR || !strings.Contains(errResp.Message, "revoked") {
+		t.Errorf("PING by a removed key = %v %q, want revoked", resp.CmdType, errResp.Message)
+	}
+	if resp, err := sendCommand(gone, pb.CommandType_CMD_PING, nil); err == nil && resp.CmdType != pb.CommandType_CMD_ERROR {
+		t.Errorf("PING after revocation = %v, want an error", resp.CmdType)
+	}
+	if _, err := sendCommand(gone, pb.CommandType_CMD_PING, nil); err == nil {
+		t.Error("Connection of a removed key is still open")
+	}
+}
+
+func TestServerIntegration_ClientAddressFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		accept  bool
+	}{
+		{"no lists", nil, nil, true},
+		{"allowlist includes loopback", []string{"10.0.0.0/8", "127.0.0.0/8"}, nil, true},
+		{"allowlist excludes loopback", []string{"10.0.0.0/8"}, nil, false},
+		{"denylist includes loopback", nil, []string{"127.0.0.1"}, false},
+		{"denylist excludes loopback", nil, []string{"192.168.0.0/16"}, true},
+		{"denylist wins", []string{"127.0.0.0/8"}, []string{"127.0.0.1/32"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, addr := createTestServerWithConfig(t, &config.Config{
+				Security: config.SecurityConfig{AllowedCIDRs: tt.allowed, DeniedCIDRs: tt.denied},
+			})
+			defer srv.Stop()
+
+			conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+			if err != nil {
+				t.Fatalf("Failed to connect: %v", err)
+			}
+			defer closeSilently(conn)
+			if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+				t.Fatalf("SetDeadline error: %v", err)
+			}
+
+			// A refused connection is closed before any frame is read
+			resp, err := sendCommand(conn, pb.CommandType_CMD_PING, nil)
+			if tt.accept && (err != nil || resp.CmdType != pb.CommandType_CMD_PONG) {
+				t.Errorf("Expected PONG, got %v, %v", resp, err)
+			}
+			if !tt.accept && err == nil {
+				t.Errorf("Expected the connection to be closed, got %v", resp.CmdType)
+			}
+		})
+	}
+
+	srv := NewServerWithConfig(engine.NewEngine(testVectorDim), &config.Config{
+		Security: config.SecurityConfig{AllowedCIDRs: []string{"127.0.0.0/33"}},
+	})
+	if err := srv.Start("127.0.0.1:0"); err == nil {
+		srv.Stop()
+		t.Error("Start with an invalid CIDR should fail")
+	}
+}
+
+func TestServerIntegration_MaxConnections(t *testing.T) {
+	const limit = 3
+	srv, addr := createTestServerWithConfig(t, &config.Config{
+		Security: config.SecurityConfig{MaxConnections: limit},
+	})
+	defer srv.Stop()
+
+	dial := func() net.Conn {
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			t.Fatalf("Failed to connect: %v", err)
+		}
+		if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+			t.Fatalf("SetDeadline error: %v", err)
+		}
+		return conn
+	}
+
+	var open []net.Conn
+	defer func() {
+		for _, conn := range open {
+			closeSilently(conn)
+		}
+	}()
+	accepted, refused := 0, 0
+	for i := 0; i < limit+2; i++ {
+		conn := dial()
+		open = append(open, conn)
+		// A refused connection is closed before any frame is read
+		if resp, err := sendCommand(conn, pb.CommandType_CMD_PING, nil); err == nil && resp.CmdType == pb.CommandType_CMD_PONG {
+			accepted++
+		} else {
+			refused++
+		}
+	}
+	if accepted != limit || refused != 2 {
+		t.Errorf("accepted %d, refused %d; want %d, 2", accepted, refused, limit)
+	}
+	if got := srv.metrics.GetCounter("connections_rejected"); got != 2 {
+		t.Errorf("connections_rejected = %d, want 2", got)
+	}
+
+	// Closing a connection frees its slot
+	closeSilently(open[0])
+	open = open[1:]
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn := dial()
+		open = append(open, conn)
+		if resp, err := sendCommand(conn, pb.CommandType_CMD_PING, nil); err == nil && resp.CmdType == pb.CommandType_CMD_PONG {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("A freed slot should accept a new connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServerIntegration_CommandTimeout(t *testing.T) {
+	// Every cancellable command outlives a 1ns timeout
+	srv, addr := createTestServerWithConfig(t, &config.Config{
+		Security: config.SecurityConfig{CommandTimeout: time.Nanosecond},
+	})
+	defer srv.Stop()
+
+	var prev *types.Entity
+	for i := 0; i < 50; i++ {
+		ent, err := srv.engine.AddEntity(testSessionID, "", fmt.Sprintf("Entity %d", i), "test", "desc", nil)
+		if err != nil {
+			t.Fatalf("AddEntity error: %v", err)
+		}
+		if prev != nil {
+			if _, err := srv.engine.AddRelationship(testSessionID, "", prev.ID, ent.ID, "NEXT", "desc", 1); err != nil {
+				t.Fatalf("AddRelationship error: %v", err)
+			}
+		}
+		prev = ent
+	}
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	for _, cmd := range []struct {
+		cmdType pb.CommandType
+		req     proto.Message
+	}{
+		{pb.CommandType_CMD_COMPUTE_COMMUNITIES, &pb.ComputeCommunitiesRequest{Resolution: 1, Iterations: 10}},
+		{pb.CommandType_CMD_HIERARCHICAL_LEIDEN, &pb.HierarchicalLeidenRequest{Resolution: 1, MaxLevels: 3}},
+		{pb.CommandType_CMD_QUERY, &pb.QueryRequest{QueryVector: make([]float32, testVectorDim), KHops: 3}},
+	} {
+		resp := mustSendCommand(t, conn, cmd.cmdType, cmd.req)
+		if resp.CmdType != pb.CommandType_CMD_ERROR {
+			t.Errorf("%s: expected a timeout error, got %s", cmd.cmdType, resp.CmdType)
+			continue
+		}
+		var errResp pb.Error
+		mustUnmarshal(t, resp.Payload, &errResp)
+		if errResp.Message != ErrCommandTimeout.Error() {
+			t.Errorf("%s: error = %q, want %q", cmd.cmdType, errResp.Message, ErrCommandTimeout)
+		}
+	}
+	if info := srv.engine.Info(); info.CommunityCount != 0 {
+		t.Errorf("A timed-out computation should not store communities, have %d", info.CommunityCount)
+	}
+
+	// Commands that cannot be cancelled are unaffected
+	if resp := mustSendCommand(t, conn, pb.CommandType_CMD_PING, nil); resp.CmdType != pb.CommandType_CMD_PONG {
+		t.Errorf("PING = %s, want PONG", resp.CmdType)
+	}
+}
+
+func TestQueryStreamChunks(t *testing.T) {
+	resp := &pb.QueryResponse{QueryId: 7, Stats: &pb.QueryStats{VectorSearches: 1}}
+	for i := range 10 {
+		resp.Textunits = append(resp.Textunits, &pb.TextUnitResult{Textunit: &pb.TextUnit{Id: uint64(i), Content: strings.Repeat("t", 100)}})
+	}
+	for i := range 40 {
+		resp.Entities = append(resp.Entities, &pb.EntityResult{Entity: &pb.Entity{Id: uint64(i), Description: strings.Repeat("e", 100)}})
+	}
+	for i := range 5 {
+		resp.Relationships = append(resp.Relationships, &pb.RelationshipResult{Relationship: &pb.Relationship{Id: uint64(i)}})
+	}
+
+	const budget = 1024
+	chunks := queryStreamChunks(resp, budget)
+	if len(chunks) < 5 {
+		t.Fatalf("Expected the results to span several chunks, got %d", len(chunks))
+	}
+
+	joined := &pb.QueryResponse{}
+	for i, data := range chunks {
+		var chunk pb.QueryStreamChunk
+		mustUnmarshal(t, data, &chunk)
+		if chunk.Seq != uint64(i) {
+			t.Errorf("chunk %d: seq = %d", i, chunk.Seq)
+		}
+		if last := i == len(chunks)-1; chunk.Last != last {
+			t.Errorf("chunk %d: last = %v, want %v", i, chunk.Last, last)
+		}
+		if size := proto.Size(chunk.Results); size > budget+queryStreamItemOverhead {
+			t.Errorf("chunk %d: %d bytes of results, budget %d", i, size, budget)
+		}
+		if !chunk.Last && (chunk.Results.QueryId != 0 || chunk.Results.Stats != nil) {
+			t.Errorf("chunk %d: query ID and stats belong in the last chunk", i)
+		}
+		// Text units come before entities, entities before relationships
+		if len(chunk.Results.Entities) > 0 && len(joined.Relationships) > 0 ||
+			len(chunk.Results.Textunits) > 0 && len(joined.Entities) > 0 {
+			t.Errorf("chunk %d: results out of order", i)
+		}
+		joined.Textunits = append(joined.Textunits, chunk.Results.Textunits...)
+		joined.Entities = append(joined.Entities, chunk.Results.Entities...)
+		joined.Relationships = append(joined.Relationships, chunk.Results.Relationships...)
+		joined.QueryId, joined.Stats = chunk.Results.QueryId, chunk.Results.Stats
+	}
+	if !proto.Equal(joined, resp) {
+		t.Error("Reassembled chunks differ from the query response")
+	}
+
+	// An empty result is a single last chunk
+	chunks = queryStreamChunks(&pb.QueryResponse{QueryId: 1}, budget)
+	var chunk pb.QueryStreamChunk
+	if len(chunks) != 1 {
+		t.Fatalf("Expected 1 chunk for an empty result, got %d", len(chunks))
+	}
+	mustUnmarshal(t, chunks[0], &chunk)
+	if !chunk.Last || chunk.Results.QueryId != 1 {
+		t.Errorf("Empty result chunk = %v", &chunk)
+	}
+}
+
+func TestServerIntegration_AuditLog(t *testing.T) {
+	writerKey := "writer-key"
+	writerHash, err := config.HashAPIKey(writerKey)
+	if err != nil {
+		t.Fatalf("Failed to hash API key: %v", err)
+	}
+	srv := NewServerWithConfig(engine.NewEngine(testVectorDim), &config.Config{
+		Auth: config.AuthConfig{
+			Keys: []config.APIKeyConfig{{ID: "writer", KeyHash: writerHash, Permissions: []string{config.PermWrite}}},
+		},
+	})
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := audit.Open(auditPath)
+	if err != nil {
+		t.Fatalf("audit.Open() error: %v", err)
+	}
+	defer closeSilently(auditLog)
+	srv.SetAuditLog(auditLog, false)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	closeSilently(ln)
+	if err := srv.Start(addr); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+	mustSendCommand(t, conn, pb.CommandType_CMD_AUTH, &pb.AuthRequest{ApiKey: writerKey})
+
+	embedding := make([]float32, testVectorDim)
+	embedding[0] = 1
+	var added pb.OkWithID
+	mustUnmarshal(t, mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{
+		ExternalId: "e1", Title: "ALICE", Type: "person", Embedding: embedding,
+	}).Payload, &added)
+	mustSendCommand(t, conn, pb.CommandType_CMD_GET_ENTITY, &pb.GetByIDRequest{Id: added.Id})                                    // read: not audited
+	mustSendCommand(t, conn, pb.CommandType_CMD_UPDATE_ENTITY_DESC, &pb.UpdateEntityDescRequest{Id: added.Id, Description: "x"}) // write
+	mustSendCommand(t, conn, pb.CommandType_CMD_DELETE_ENTITY, &pb.DeleteByIDRequest{Id: 999999})                                // failed write
+	mustSendCommand(t, conn, pb.CommandType_CMD_DELETE_SESSION, nil)                                                             // denied: not audited
+
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("ReadFile error: %v", err)
+	}
+	var records []audit.Record
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var r audit.Record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("Invalid audit line %q: %v", line, err)
+		}
+		records = append(records, r)
 	}
 
-	// Should get error response
-	if resp.CmdType != pb.CommandType_CMD_ERROR {
-		t.Errorf("Expected CMD_ERROR for unknown command, got %v", resp.CmdType)
+	want := []audit.Record{
+		{KeyID: "writer", SessionID: testSessionID, Command: "CMD_ADD_ENTITY", TargetID: added.Id, OK: true},
+		{KeyID: "writer", SessionID: testSessionID, Command: "CMD_UPDATE_ENTITY_DESC", TargetID: added.Id, OK: true},
+		{KeyID: "writer", SessionID: testSessionID, Command: "CMD_DELETE_ENTITY", TargetID: 999999, OK: false},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Expected %d audit records, got %d: %s", len(want), len(records), data)
+	}
+	for i, r := range records {
+		if r.Time.IsZero() {
+			t.Errorf("record %d has no time", i)
+		}
+		if !r.OK && r.Error == "" {
+			t.Errorf("record %d failed without an error message", i)
+		}
+		r.Time, r.Error = time.Time{}, ""
+		if r != want[i] {
+			t.Errorf("record %d = %+v, want %+v", i, r, want[i])
+		}
 	}
 }
 
@@ -1490,6 +2615,73 @@ func TestServerIntegration_LinkTextUnitToEntity(t *testing.T) {
 	}
 }
 
+func TestServerIntegration_MLinkTextUnitEntity(t *testing.T) {
+	srv, addr := createTestServer(t)
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	docResp := mustSendCommand(t, conn, pb.CommandType_CMD_ADD_DOCUMENT, &pb.AddDocumentRequest{ExternalId: "doc-mlink", Filename: "mlink.pdf"})
+	var docID pb.OkWithID
+	mustUnmarshal(t, docResp.Payload, &docID)
+
+	embedding := make([]float32, testVectorDim)
+	tuResp := mustSendCommand(t, conn, pb.CommandType_CMD_ADD_TEXTUNIT, &pb.AddTextUnitRequest{
+		ExternalId: "tu-mlink",
+		DocumentId: docID.Id,
+		Content:    "Content for bulk linking",
+		Embedding:  embedding,
+	})
+	var tuID pb.OkWithID
+	mustUnmarshal(t, tuResp.Payload, &tuID)
+
+	var entIDs []uint64
+	for i := 0; i < 2; i++ {
+		entResp := mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{
+			ExternalId: fmt.Sprintf("ent-mlink-%d", i),
+			Title:      fmt.Sprintf("MLink Entity %d", i),
+			Type:       "test",
+			Embedding:  embedding,
+		})
+		var entID pb.OkWithID
+		mustUnmarshal(t, entResp.Payload, &entID)
+		entIDs = append(entIDs, entID.Id)
+	}
+
+	req := &pb.MLinkTextUnitEntityRequest{
+		Links: []*pb.LinkTextUnitEntityRequest{
+			{TextunitId: tuID.Id, EntityId: entIDs[0]},
+			{TextunitId: 9999, EntityId: entIDs[0]},
+			{TextunitId: tuID.Id, EntityId: entIDs[1]},
+		},
+		ContinueOnError: true,
+	}
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_MLINK_TEXTUNIT_ENTITY, req)
+	if resp.CmdType != pb.CommandType_CMD_MLINK_RESPONSE {
+		t.Fatalf("Expected CMD_MLINK_RESPONSE, got %v", resp.CmdType)
+	}
+
+	var linkResp pb.MLinkTextUnitEntityResponse
+	mustUnmarshal(t, resp.Payload, &linkResp)
+	if linkResp.LinkedCount != 2 || linkResp.FailedCount != 1 {
+		t.Errorf("Expected 2 linked / 1 failed, got %d / %d", linkResp.LinkedCount, linkResp.FailedCount)
+	}
+	if len(linkResp.Results) != 3 || linkResp.Results[1].Ok {
+		t.Errorf("Expected second pair to fail, got %v", linkResp.Results)
+	}
+
+	tuGet := mustSendCommand(t, conn, pb.CommandType_CMD_GET_TEXTUNIT, &pb.GetByIDRequest{Id: tuID.Id})
+	var tu pb.TextUnit
+	mustUnmarshal(t, tuGet.Payload, &tu)
+	if len(tu.EntityIds) != 2 {
+		t.Errorf("Expected text unit linked to 2 entities, got %v", tu.EntityIds)
+	}
+}
+
 // =============================================================================
 // Update Entity Description Test
 // =============================================================================
@@ -3256,7 +4448,7 @@ func TestServerIntegration_InvalidPayload(t *testing.T) {
 		Payload:   []byte("invalid protobuf data"),
 	}
 
-	frameData, err := codec.EncodeEnvelope(env)
+	frameData, err := codec.EncodeEnvelope(env, 0)
 	if err != nil {
 		t.Fatalf("Failed to encode envelope: %v", err)
 	}
@@ -3824,3 +5016,498 @@ func TestServerIntegration_HierarchicalLeidenWithData(t *testing.T) {
 		t.Logf("Hierarchical Leiden found %d total communities", leidenResp.TotalCommunities)
 	}
 }
+
+func TestServerIntegration_WritesAppendToWAL(t *testing.T) {
+	walDir := t.TempDir()
+	srv, wal, addr := createTestServerWithWAL(t, walDir)
+	defer srv.Stop()
+	defer closeSilently(wal)
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	writes := []struct {
+		cmd     pb.CommandType
+		payload proto.Message
+	}{
+		{pb.CommandType_CMD_ADD_DOCUMENT, &pb.AddDocumentRequest{ExternalId: "doc-1", Filename: "a.txt"}},
+		{pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-1", Title: "Alpha", Type: "org"}},
+		{pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-2", Title: "Beta", Type: "org"}},
+		{pb.CommandType_CMD_ADD_RELATIONSHIP, &pb.AddRelationshipRequest{SourceId: 1, TargetId: 2, Type: "OWNS", Weight: 1}},
+		{pb.CommandType_CMD_MSET_ENTITIES, &pb.MSetEntitiesRequest{Entities: []*pb.AddEntityRequest{{Title: "Gamma"}, {Title: "Delta"}}}},
+		{pb.CommandType_CMD_DELETE_ENTITY, &pb.DeleteByIDRequest{Id: 4}},
+		{pb.CommandType_CMD_UPDATE_ENTITY_TITLE, &pb.UpdateEntityTitleRequest{Id: 2, Title: "Beta Holdings"}},
+	}
+	for i, w := range writes {
+		before := wal.CurrentLSN()
+		resp := mustSendCommand(t, conn, w.cmd, w.payload)
+		if resp.CmdType == pb.CommandType_CMD_ERROR {
+			var errResp pb.Error
+			mustUnmarshal(t, resp.Payload, &errResp)
+			t.Fatalf("write %d (%s) returned error: %s", i, w.cmd, errResp.Message)
+		}
+		if wal.CurrentLSN() != before+1 {
+			t.Fatalf("write %d (%s): CurrentLSN() = %d, want %d", i, w.cmd, wal.CurrentLSN(), before+1)
+		}
+	}
+
+	// Reads and failed writes are not logged
+	lsn := wal.CurrentLSN()
+	mustSendCommand(t, conn, pb.CommandType_CMD_GET_ENTITY, &pb.GetByIDRequest{Id: 1})
+	if resp := mustSendCommand(t, conn, pb.CommandType_CMD_DELETE_ENTITY, &pb.DeleteByIDRequest{Id: 99}); resp.CmdType != pb.CommandType_CMD_ERROR {
+		t.Fatalf("deleting a missing entity returned %s, want error", resp.CmdType)
+	}
+	if wal.CurrentLSN() != lsn {
+		t.Errorf("CurrentLSN() = %d after read and failed write, want %d", wal.CurrentLSN(), lsn)
+	}
+
+	if err := wal.Sync(); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	entries, err := backup.ReadEntries(walDir, 0)
+	if err != nil {
+		t.Fatalf("ReadEntries() error: %v", err)
+	}
+	if len(entries) != len(writes) {
+		t.Fatalf("ReadEntries() returned %d entries, want %d", len(entries), len(writes))
+	}
+	for i, entry := range entries {
+		cmd, payload, err := backup.DecodeCommand(entry.Data)
+		if err != nil {
+			t.Fatalf("entry %d: DecodeCommand() error: %v", i, err)
+		}
+		want, _ := proto.Marshal(writes[i].payload)
+		if cmd != writes[i].cmd || entry.Key != testSessionID || string(payload) != string(want) {
+			t.Errorf("entry %d = (%s, %q), want (%s, %q)", i, cmd, entry.Key, writes[i].cmd, testSessionID)
+		}
+	}
+
+	// The log alone rebuilds the same graph
+	restarted := engine.NewEngine(testVectorDim)
+	if err := backup.NewRecovery(t.TempDir()).ReplayWAL(restarted, walDir, 0); err != nil {
+		t.Fatalf("ReplayWAL() error: %v", err)
+	}
+	got, want := restarted.Info(), srv.engine.Info()
+	if got.DocumentCount != want.DocumentCount || got.EntityCount != want.EntityCount || got.RelationshipCount != want.RelationshipCount {
+		t.Errorf("replayed counts = %+v, want %+v", got, want)
+	}
+	if ent, ok := restarted.GetEntityByTitle(testSessionID, "Beta Holdings"); !ok || ent.ID != 2 {
+		t.Errorf("replayed rename: GetEntityByTitle() = (%v, %v), want entity 2", ent, ok)
+	}
+}
+
+func TestServerIntegration_AtomicPipeline(t *testing.T) {
+	srv, wal, addr := createTestServerWithWAL(t, t.TempDir())
+	defer srv.Stop()
+	defer closeSilently(wal)
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	sub := func(cmd pb.CommandType, payload proto.Message) *pb.Envelope {
+		data, _ := proto.Marshal(payload)
+		return &pb.Envelope{Version: ProtocolVersion, CmdType: cmd, SessionId: testSessionID, Payload: data}
+	}
+	runPipeline := func(commands ...*pb.Envelope) *pb.PipelineResponse {
+		t.Helper()
+		resp := mustSendCommand(t, conn, pb.CommandType_CMD_PIPELINE, &pb.PipelineRequest{Commands: commands, Atomic: true})
+		if resp.CmdType != pb.CommandType_CMD_PIPELINE_RESPONSE {
+			t.Fatalf("PIPELINE = %s, want PIPELINE_RESPONSE", resp.CmdType)
+		}
+		var pipeResp pb.PipelineResponse
+		mustUnmarshal(t, resp.Payload, &pipeResp)
+		return &pipeResp
+	}
+
+	// All commands succeed: every write is applied and logged
+	lsn := wal.CurrentLSN()
+	pipeResp := runPipeline(
+		sub(pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-1", Title: "Alpha", Type: "org"}),
+		sub(pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-2", Title: "Beta", Type: "org"}),
+		sub(pb.CommandType_CMD_ADD_RELATIONSHIP, &pb.AddRelationshipRequest{SourceId: 1, TargetId: 2, Type: "OWNS", Weight: 1}),
+	)
+	if pipeResp.RolledBack {
+		t.Fatalf("Successful pipeline rolled back: %v", pipeResp.Responses)
+	}
+	for i, r := range pipeResp.Responses {
+		if r.CmdType == pb.CommandType_CMD_ERROR {
+			t.Fatalf("command %d failed", i)
+		}
+	}
+	if got := wal.CurrentLSN(); got != lsn+3 {
+		t.Errorf("CurrentLSN() = %d after the pipeline, want %d", got, lsn+3)
+	}
+
+	// A failure midway leaves the session as it was and the WAL untouched
+	before := srv.engine.Info()
+	lsn = wal.CurrentLSN()
+	pipeResp = runPipeline(
+		sub(pb.CommandType_CMD_UPDATE_ENTITY_TITLE, &pb.UpdateEntityTitleRequest{Id: 1, Title: "Alpha Holdings"}),
+		sub(pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-3", Title: "Gamma", Type: "org"}),
+		sub(pb.CommandType_CMD_DELETE_ENTITY, &pb.DeleteByIDRequest{Id: 99}),
+		sub(pb.CommandType_CMD_DELETE_ENTITY, &pb.DeleteByIDRequest{Id: 2}),
+	)
+	if !pipeResp.RolledBack {
+		t.Fatal("Failed pipeline was not rolled back")
+	}
+	wantTypes := []pb.CommandType{pb.CommandType_CMD_OK, pb.CommandType_CMD_OK, pb.CommandType_CMD_ERROR, pb.CommandType_CMD_ERROR}
+	for i, r := range pipeResp.Responses {
+		if r.CmdType != wantTypes[i] {
+			t.Errorf("command %d = %s, want %s", i, r.CmdType, wantTypes[i])
+		}
+	}
+	var skipped pb.Error
+	mustUnmarshal(t, pipeResp.Responses[3].Payload, &skipped)
+	if !strings.Contains(skipped.Message, "not run") {
+		t.Errorf("Skipped command error = %q", skipped.Message)
+	}
+
+	if after := srv.engine.Info(); after.EntityCount != before.EntityCount || after.RelationshipCount != before.RelationshipCount {
+		t.Errorf("Counts after rollback = %d entities, %d relationships; want %d, %d",
+			after.EntityCount, after.RelationshipCount, before.EntityCount, before.RelationshipCount)
+	}
+	if ent, ok := srv.engine.GetEntity(testSessionID, 1); !ok || ent.Title != "ALPHA" {
+		t.Errorf("Entity 1 after rollback = %+v, want title ALPHA", ent)
+	}
+	if _, ok := srv.engine.GetEntityByExternalID(testSessionID, "e-3"); ok {
+		t.Error("Entity added by the failed pipeline is still there")
+	}
+	if got := wal.CurrentLSN(); got != lsn {
+		t.Errorf("CurrentLSN() = %d after the failed pipeline, want %d", got, lsn)
+	}
+
+	// Commands a rollback cannot undo are refused
+	pipeResp = runPipeline(sub(pb.CommandType_CMD_QUANTIZE_INDEX, &pb.QuantizeIndexRequest{}))
+	if !pipeResp.RolledBack || pipeResp.Responses[0].CmdType != pb.CommandType_CMD_ERROR {
+		t.Errorf("QUANTIZE_INDEX in an atomic pipeline = %v, want refused", pipeResp.Responses[0].CmdType)
+	}
+}
+
+func TestServerIntegration_WritesExcludedWithoutWAL(t *testing.T) {
+	srv, addr := createTestServer(t)
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	// While writeMu is held, as by an atomic pipeline, writes wait even
+	// though the server has no WAL
+	srv.writeMu.Lock()
+	done := make(chan *pb.Envelope, 1)
+	go func() {
+		resp, err := sendCommand(conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-1", Title: "Alpha", Type: "org"})
+		if err != nil {
+			resp = nil
+		}
+		done <- resp
+	}()
+	select {
+	case <-done:
+		srv.writeMu.Unlock()
+		t.Fatal("Write completed while writeMu was held")
+	case <-time.After(100 * time.Millisecond):
+	}
+	srv.writeMu.Unlock()
+
+	select {
+	case resp := <-done:
+		if resp == nil || resp.CmdType == pb.CommandType_CMD_ERROR {
+			t.Errorf("Write after writeMu was released failed: %v", resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Write did not complete after writeMu was released")
+	}
+}
+
+func TestServerIntegration_PipelineRefs(t *testing.T) {
+	walDir := t.TempDir()
+	srv, wal, addr := createTestServerWithWAL(t, walDir)
+	defer srv.Stop()
+	defer closeSilently(wal)
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	// An existing entity keeps the new IDs from being 1 and 2
+	mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-0", Title: "Zero", Type: "org"})
+
+	sub := func(cmd pb.CommandType, payload proto.Message) *pb.Envelope {
+		data, _ := proto.Marshal(payload)
+		return &pb.Envelope{Version: ProtocolVersion, CmdType: cmd, SessionId: testSessionID, Payload: data}
+	}
+	relationship := &pb.AddRelationshipRequest{ExternalId: "r-1", Type: "OWNS", Weight: 1}
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_PIPELINE, &pb.PipelineRequest{
+		Commands: []*pb.Envelope{
+			sub(pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-1", Title: "Alice", Type: "person"}),
+			sub(pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{ExternalId: "e-2", Title: "Acme", Type: "org"}),
+			sub(pb.CommandType_CMD_ADD_RELATIONSHIP, relationship),
+			sub(pb.CommandType_CMD_ADD_RELATIONSHIP, relationship),
+		},
+		Labels: []string{"alice", "acme"},
+		Refs: []*pb.PipelineRef{
+			{Command: 2, Field: "source_id", Ref: "$alice"},
+			{Command: 2, Field: "target_id", Ref: "$acme"},
+			{Command: 3, Field: "source_id", Ref: "$bob"},
+		},
+	})
+	var pipeResp pb.PipelineResponse
+	mustUnmarshal(t, resp.Payload, &pipeResp)
+	if len(pipeResp.Responses) != 4 {
+		t.Fatalf("Expected 4 responses, got %d", len(pipeResp.Responses))
+	}
+
+	var ids [3]uint64
+	for i := range ids {
+		if pipeResp.Responses[i].CmdType != pb.CommandType_CMD_OK {
+			var errResp pb.Error
+			mustUnmarshal(t, pipeResp.Responses[i].Payload, &errResp)
+			t.Fatalf("command %d failed: %s", i, errResp.Message)
+		}
+		var ok pb.OkWithID
+		mustUnmarshal(t, pipeResp.Responses[i].Payload, &ok)
+		ids[i] = ok.Id
+	}
+	rel, found := srv.engine.GetRelationship(testSessionID, ids[2])
+	if !found || rel.SourceID != ids[0] || rel.TargetID != ids[1] || ids[0] == 1 {
+		t.Errorf("Relationship = %+v, want %d -> %d", rel, ids[0], ids[1])
+	}
+
+	var errResp pb.Error
+	mustUnmarshal(t, pipeResp.Responses[3].Payload, &errResp)
+	if pipeResp.Responses[3].CmdType != pb.CommandType_CMD_ERROR || !strings.Contains(errResp.Message, "unresolved pipeline ref $bob") {
+		t.Errorf("Unknown ref = %s %q, want an unresolved ref error", pipeResp.Responses[3].CmdType, errResp.Message)
+	}
+
+	// The WAL holds the filled-in IDs, so replay does not depend on labels
+	if err := wal.Sync(); err != nil {
+		t.Fatalf("Sync() error: %v", err)
+	}
+	entries, err := backup.ReadEntries(walDir, 0)
+	if err != nil {
+		t.Fatalf("ReadEntries() error: %v", err)
+	}
+	_, payload, err := backup.DecodeCommand(entries[len(entries)-1].Data)
+	if err != nil {
+		t.Fatalf("DecodeCommand() error: %v", err)
+	}
+	var logged pb.AddRelationshipRequest
+	mustUnmarshal(t, payload, &logged)
+	if logged.SourceId != ids[0] || logged.TargetId != ids[1] {
+		t.Errorf("Logged relationship %d -> %d, want %d -> %d", logged.SourceId, logged.TargetId, ids[0], ids[1])
+	}
+
+	// Malformed refs fail the whole pipeline
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_PIPELINE, &pb.PipelineRequest{
+		Commands: []*pb.Envelope{sub(pb.CommandType_CMD_ADD_RELATIONSHIP, relationship)},
+		Refs:     []*pb.PipelineRef{{Command: 0, Field: "source_id", Ref: "alice"}},
+	})
+	if resp.CmdType != pb.CommandType_CMD_ERROR {
+		t.Errorf("Ref without $ = %s, want error", resp.CmdType)
+	}
+}
+
+func TestServerIntegration_HandshakeNegotiatesCompression(t *testing.T) {
+	srv, addr := createTestServer(t)
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_AUTH, &pb.AuthRequest{Compression: []string{"brotli", codec.CompressionGzip}})
+	if resp.CmdType != pb.CommandType_CMD_AUTH_RESPONSE {
+		t.Fatalf("Expected AUTH_RESPONSE, got %v", resp.CmdType)
+	}
+	var authResp pb.AuthResponse
+	mustUnmarshal(t, resp.Payload, &authResp)
+	if !authResp.Success || authResp.Compression != codec.CompressionGzip {
+		t.Fatalf("handshake = (%v, %q), want (true, %q)", authResp.Success, authResp.Compression, codec.CompressionGzip)
+	}
+
+	desc := strings.Repeat("Supervises retail lending and payment systems. ", 100)
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_ADD_ENTITY, &pb.AddEntityRequest{Title: "Bank", Type: "org", Description: desc})
+	var ok pb.OkWithID
+	mustUnmarshal(t, resp.Payload, &ok)
+
+	// Large responses now arrive compressed
+	payload, _ := proto.Marshal(&pb.GetByIDRequest{Id: ok.Id})
+	frame, err := codec.EncodeEnvelope(&pb.Envelope{
+		Version:   ProtocolVersion,
+		RequestId: 2,
+		CmdType:   pb.CommandType_CMD_GET_ENTITY,
+		Payload:   payload,
+		SessionId: testSessionID,
+	}, 0)
+	if err != nil {
+		t.Fatalf("EncodeEnvelope() error: %v", err)
+	}
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	resp, codecType, err := codec.DecodeEnvelope(conn)
+	if err != nil {
+		t.Fatalf("DecodeEnvelope() error: %v", err)
+	}
+	if codecType != codec.CodecProtobufGzip {
+		t.Errorf("response codec = %d, want %d", codecType, codec.CodecProtobufGzip)
+	}
+	var ent pb.Entity
+	mustUnmarshal(t, resp.Payload, &ent)
+	if ent.Description != desc {
+		t.Errorf("Description round trip mismatch: got %d bytes, want %d", len(ent.Description), len(desc))
+	}
+}
+
+func TestServerIntegration_PrometheusMetrics(t *testing.T) {
+	collector := metrics.NewCollector()
+	srv := NewServer(engine.NewEngine(testVectorDim))
+	srv.SetMetricsCollector(collector)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	closeSilently(ln)
+	if err := srv.Start(addr); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	mustSendCommand(t, conn, pb.CommandType_CMD_PING, nil)
+	mustSendCommand(t, conn, pb.CommandType_CMD_PING, nil)
+	mustSendCommand(t, conn, pb.CommandType_CMD_GET_ENTITY, &pb.GetByIDRequest{Id: 999})
+	// Commands outside the enum share one label however many values arrive
+	mustSendCommand(t, conn, pb.CommandType(9001), nil)
+	mustSendCommand(t, conn, pb.CommandType(9002), nil)
+
+	metricsSrv := httptest.NewServer(metrics.Handler(collector))
+	defer metricsSrv.Close()
+
+	resp, err := http.Get(metricsSrv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error: %v", err)
+	}
+	defer closeSilently(resp.Body)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ReadAll() error: %v", err)
+	}
+	scrape := string(body)
+
+	for _, want := range []string{
+		"# TYPE gibram_command_total counter",
+		`gibram_command_total{command="ping"} 2`,
+		`gibram_command_errors_total{command="get_entity"} 1`,
+		"# TYPE gibram_command_duration_seconds histogram",
+		`gibram_command_duration_seconds_bucket{command="ping",le="+Inf"} 2`,
+		`gibram_command_duration_seconds_count{command="get_entity"} 1`,
+		`gibram_command_total{command="unknown"} 2`,
+	} {
+		if !strings.Contains(scrape, want) {
+			t.Errorf("scrape missing %q:\n%s", want, scrape)
+		}
+	}
+	if strings.Contains(scrape, "9001") {
+		t.Errorf("scrape has a series for an unknown command value:\n%s", scrape)
+	}
+}
+
+func TestServerIntegration_SlowQueryLogAndStats(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "server.log")
+	if err := logging.Init(logging.Config{Level: "warn", Format: "json", Output: "file", File: logPath}); err != nil {
+		t.Fatalf("logging.Init() error: %v", err)
+	}
+	defer func() {
+		if err := logging.Init(logging.DefaultConfig()); err != nil {
+			t.Errorf("restore logger: %v", err)
+		}
+	}()
+
+	srv := NewServer(engine.NewEngine(testVectorDim))
+	srv.SetSlowQueryThreshold(10 * time.Millisecond)
+	// A deliberately slow handler: SAVE waits on the snapshot callback
+	srv.SetSnapshotCallback(func(path string) error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find available port: %v", err)
+	}
+	addr := ln.Addr().String()
+	closeSilently(ln)
+	if err := srv.Start(addr); err != nil {
+		t.Fatalf("Failed to start server: %v", err)
+	}
+	defer srv.Stop()
+
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer closeSilently(conn)
+
+	mustSendCommand(t, conn, pb.CommandType_CMD_PING, nil)
+	resp := mustSendCommand(t, conn, pb.CommandType_CMD_SAVE, &pb.SaveRequest{Path: "unused"})
+	if resp.CmdType != pb.CommandType_CMD_OK {
+		t.Fatalf("SAVE returned %v", resp.CmdType)
+	}
+
+	logData, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	logged := string(logData)
+	for _, want := range []string{`"command":"save"`, `"session_id":"` + testSessionID + `"`, `"duration_ms":`, `"level":"WARN"`} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("slow-query log missing %s:\n%s", want, logged)
+		}
+	}
+
+	resp = mustSendCommand(t, conn, pb.CommandType_CMD_STATS, &pb.Empty{})
+	if resp.CmdType != pb.CommandType_CMD_STATS_RESPONSE {
+		t.Fatalf("Expected STATS_RESPONSE, got %v", resp.CmdType)
+	}
+	var stats pb.StatsResponse
+	mustUnmarshal(t, resp.Payload, &stats)
+	if stats.SlowQueryThresholdMs != 10 {
+		t.Errorf("SlowQueryThresholdMs = %d, want 10", stats.SlowQueryThresholdMs)
+	}
+
+	byName := make(map[string]*pb.CommandStats)
+	for _, cmd := range stats.Commands {
+		byName[cmd.Command] = cmd
+	}
+	save := byName["save"]
+	if save == nil || save.Count != 1 {
+		t.Fatalf("save stats = %v, want count 1", save)
+	}
+	if save.P50LatencyMicros < 25000 || save.P99LatencyMicros < save.P50LatencyMicros {
+		t.Errorf("save latency p50=%.0fus p99=%.0fus, want p50 >= 25ms and p99 >= p50", save.P50LatencyMicros, save.P99LatencyMicros)
+	}
+	if ping := byName["ping"]; ping == nil || ping.Count != 1 {
+		t.Errorf("ping stats = %v, want count 1", ping)
+	}
+}
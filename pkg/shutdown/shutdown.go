@@ -19,6 +19,7 @@ type Handler struct {
 	signals  []os.Signal
 	done     chan struct{}
 	started  bool
+	reloads  []func()
 }
 
 // ShutdownHook is a function called during shutdown
@@ -71,7 +72,16 @@ func (h *Handler) Register(name string, priority int, fn func(ctx context.Contex
 	}
 }
 
-// Start starts listening for shutdown signals
+// OnReload registers a function run on SIGHUP, e.g. to re-read the config
+// file. Register before Start; without any, SIGHUP keeps its default action.
+func (h *Handler) OnReload(fn func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reloads = append(h.reloads, fn)
+}
+
+// Start starts listening for shutdown signals, and SIGHUP if reload
+// functions are registered
 func (h *Handler) Start() {
 	h.mu.Lock()
 	if h.started {
@@ -79,8 +89,28 @@ func (h *Handler) Start() {
 		return
 	}
 	h.started = true
+	reloads := h.reloads
 	h.mu.Unlock()
 
+	if len(reloads) > 0 {
+		hupCh := make(chan os.Signal, 1)
+		signal.Notify(hupCh, syscall.SIGHUP)
+		go func() {
+			defer signal.Stop(hupCh)
+			for {
+				select {
+				case <-hupCh:
+					log.Printf("Received signal: SIGHUP, reloading...")
+					for _, fn := range reloads {
+						fn()
+					}
+				case <-h.done:
+					return
+				}
+			}
+		}()
+	}
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, h.signals...)
 
@@ -252,6 +252,30 @@ func TestHandler_Start_Idempotent(t *testing.T) {
 	}
 }
 
+func TestHandler_OnReload(t *testing.T) {
+	h := NewHandler()
+	reloaded := make(chan struct{}, 1)
+	h.OnReload(func() { reloaded <- struct{}{} })
+	h.Start()
+	defer h.Shutdown()
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Failed to send SIGHUP: %v", err)
+	}
+
+	select {
+	case <-reloaded:
+	case <-time.After(1 * time.Second):
+		t.Fatal("reload function not called on SIGHUP")
+	}
+
+	select {
+	case <-h.Done():
+		t.Error("SIGHUP should not start a shutdown")
+	default:
+	}
+}
+
 // =============================================================================
 // Wait Tests
 // =============================================================================
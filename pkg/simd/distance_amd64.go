@@ -4,6 +4,8 @@
 package simd
 
 import (
+	"math"
+
 	"golang.org/x/sys/cpu"
 )
 
@@ -135,19 +137,12 @@ func float32Sqrt(x float32) float32 {
 	return float32(fastSqrt(float64(x)))
 }
 
-// fastSqrt provides a fast square root approximation
+// fastSqrt computes the square root for the AVX2 paths
 func fastSqrt(x float64) float64 {
-	// This could be optimized with assembly VSQRTSD
-	// For now, use standard library
+	// This could be optimized with assembly VSQRTSD; math.Sqrt already
+	// compiles to the hardware SQRTSD instruction on amd64.
 	if x <= 0 {
 		return 0
 	}
-	
-	// Fast inverse square root approximation (Quake III algorithm adapted)
-	// For production, we'd use hardware SQRT instruction
-	half := x * 0.5
-	i := int64(0x5fe6eb50c7b537a9 - (int64(x) >> 1))
-	y := float64(i)
-	y = y * (1.5 - (half * y * y))
-	return x * y
+	return math.Sqrt(x)
 }
@@ -2,11 +2,18 @@
 package store
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/gibram-io/gibram/pkg/simd"
+	"github.com/gibram-io/gibram/pkg/textsearch"
 	"github.com/gibram-io/gibram/pkg/types"
 	"github.com/gibram-io/gibram/pkg/vector"
 )
@@ -37,30 +44,72 @@ type SessionStore struct {
 	entities   map[uint64]*types.Entity
 	entByExtID map[string]uint64
 	entByTitle map[string]uint64
+	entByType  map[string]int // entity type -> count
 
 	relationships     map[uint64]*types.Relationship
 	relByExtID        map[string]uint64
 	relBySourceTarget map[string]uint64
 	outEdges          map[uint64][]uint64
 	inEdges           map[uint64][]uint64
+	relByType         map[string]int // relationship type -> count
 
-	communities map[uint64]*types.Community
-	commByExtID map[string]uint64
-	commByLevel map[int][]uint64
+	communities  map[uint64]*types.Community
+	commByExtID  map[string]uint64
+	commByLevel  map[int][]uint64
+	commByEntity map[uint64][]uint64 // entity ID -> IDs of communities containing it
+
+	// edgeVersion counts changes to edges and weights; communityVersion is
+	// the edgeVersion the current communities were computed from
+	edgeVersion      uint64
+	communityVersion uint64
+
+	// dataVersion counts every change that can alter query results
+	dataVersion uint64
+
+	// Bytes of record text (IDs, titles, descriptions, content), kept in
+	// step with the record maps; vector bytes come from the indices
+	contentBytes int64
 
 	// Vector indices (per-session, lazy initialized)
 	textUnitIndex  vector.Index
 	entityIndex    vector.Index
 	communityIndex vector.Index
 	vectorDim      int
+	indexConfig    vector.HNSWConfig // graph parameters for new/rebuilt indices
+
+	// Entity title embeddings; only created once a session stores one
+	entityTitleIndex vector.Index
+
+	// BM25 keyword index over text unit content
+	textUnitKeywords *textsearch.Index
+
+	// Entity access counters (own lock so reads don't contend on s.mu)
+	popMu      sync.Mutex
+	popularity map[uint64]*accessCounter
+}
+
+// accessCounter is an exponentially decaying access count
+type accessCounter struct {
+	value   float64
+	updated int64 // unix nanos of last decay
+}
+
+// decayed returns the counter value at now for the given half-life
+func (c *accessCounter) decayed(now int64, halfLife time.Duration) float64 {
+	elapsed := now - c.updated
+	if elapsed <= 0 || halfLife <= 0 {
+		return c.value
+	}
+	return c.value * math.Exp2(-float64(elapsed)/float64(halfLife))
 }
 
 // NewSessionStore creates a new session store
 func NewSessionStore(sessionID string, vectorDim int) *SessionStore {
 	return &SessionStore{
-		session:   types.NewSession(sessionID),
-		idGen:     types.NewIDGenerator(),
-		vectorDim: vectorDim,
+		session:     types.NewSession(sessionID),
+		idGen:       types.NewIDGenerator(),
+		vectorDim:   vectorDim,
+		indexConfig: vector.DefaultHNSWConfig(),
 
 		// Documents
 		documents:     make(map[uint64]*types.Document),
@@ -72,10 +121,13 @@ func NewSessionStore(sessionID string, vectorDim int) *SessionStore {
 		tuByExtID: make(map[string]uint64),
 		tuByDocID: make(map[uint64][]uint64),
 
+		textUnitKeywords: textsearch.NewIndex(),
+
 		// Entities
 		entities:   make(map[uint64]*types.Entity),
 		entByExtID: make(map[string]uint64),
 		entByTitle: make(map[string]uint64),
+		entByType:  make(map[string]int),
 
 		// Relationships
 		relationships:     make(map[uint64]*types.Relationship),
@@ -83,11 +135,13 @@ func NewSessionStore(sessionID string, vectorDim int) *SessionStore {
 		relBySourceTarget: make(map[string]uint64),
 		outEdges:          make(map[uint64][]uint64),
 		inEdges:           make(map[uint64][]uint64),
+		relByType:         make(map[string]int),
 
 		// Communities
-		communities: make(map[uint64]*types.Community),
-		commByExtID: make(map[string]uint64),
-		commByLevel: make(map[int][]uint64),
+		communities:  make(map[uint64]*types.Community),
+		commByExtID:  make(map[string]uint64),
+		commByLevel:  make(map[int][]uint64),
+		commByEntity: make(map[uint64][]uint64),
 	}
 }
 
@@ -125,6 +179,16 @@ func (s *SessionStore) SetIdleTTL(idleTTL int64) {
 	s.session.SetIdleTTL(idleTTL)
 }
 
+// SetMetadata updates session metadata (see types.Session.SetMetadata)
+func (s *SessionStore) SetMetadata(updates map[string]string, replace bool) error {
+	return s.session.SetMetadata(updates, replace)
+}
+
+// GetMetadata returns a copy of the session metadata
+func (s *SessionStore) GetMetadata() map[string]string {
+	return s.session.GetMetadata()
+}
+
 // GetInfo returns session info with counts
 func (s *SessionStore) GetInfo() types.SessionInfo {
 	s.mu.RLock()
@@ -136,6 +200,8 @@ func (s *SessionStore) GetInfo() types.SessionInfo {
 	info.EntityCount = len(s.entities)
 	info.RelationshipCount = len(s.relationships)
 	info.CommunityCount = len(s.communities)
+	info.CommunitiesDirty = s.edgeVersion != s.communityVersion
+	info.MemoryBytes = s.memoryBytesLocked()
 	return info
 }
 
@@ -148,27 +214,314 @@ func (s *SessionStore) GetIDGenerator() *types.IDGenerator {
 // Vector Index Management (lazy initialization)
 // =============================================================================
 
+// newIndex creates an empty vector index using the session's distance metric
+// and, when set, its vector precision
+func (s *SessionStore) newIndex() vector.Index {
+	return vector.NewHNSWIndex(s.vectorDim, s.effectiveIndexConfig())
+}
+
+// effectiveIndexConfig is the index config with the session's metric and
+// precision applied. Caller must hold s.mu.
+func (s *SessionStore) effectiveIndexConfig() vector.HNSWConfig {
+	config := s.indexConfig
+	config.Metric = s.session.GetDistanceMetric()
+	if precision := s.session.GetVectorPrecision(); precision != "" {
+		config.Precision = vector.Precision(precision)
+	}
+	return config
+}
+
+// indexSlots points at the session's four vector indices, any of which may
+// be nil. Caller must hold s.mu.
+func (s *SessionStore) indexSlots() []*vector.Index {
+	return []*vector.Index{&s.textUnitIndex, &s.entityIndex, &s.communityIndex, &s.entityTitleIndex}
+}
+
+// SetVectorPrecision switches the session's vector storage precision,
+// converting the vectors of existing indices in place. Their graphs are
+// kept, so no rebuild is needed. The choice is persisted with the session.
+func (s *SessionStore) SetVectorPrecision(precision vector.Precision) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	s.session.SetVectorPrecision(string(precision))
+	for _, idx := range []vector.Index{s.textUnitIndex, s.entityIndex, s.communityIndex, s.entityTitleIndex} {
+		if idx == nil {
+			continue
+		}
+		hnsw, ok := idx.(*vector.HNSWIndex)
+		if !ok {
+			return s.rebuildIndicesLocked()
+		}
+		hnsw.SetPrecision(precision)
+	}
+	return nil
+}
+
+// VectorBytes returns the memory held by vectors across the session's indices
+func (s *SessionStore) VectorBytes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var total int64
+	for _, idx := range []vector.Index{s.textUnitIndex, s.entityIndex, s.communityIndex, s.entityTitleIndex} {
+		if hnsw, ok := idx.(*vector.HNSWIndex); ok {
+			total += hnsw.VectorBytes()
+		}
+	}
+	return total
+}
+
+// MemoryBytes approximates the memory the session's data holds: the text of
+// its records plus its stored vectors at their index's precision
+func (s *SessionStore) MemoryBytes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.memoryBytesLocked()
+}
+
+// memoryBytesLocked is MemoryBytes for callers holding s.mu
+func (s *SessionStore) memoryBytesLocked() int64 {
+	total := s.contentBytes
+	for _, idx := range []vector.Index{s.textUnitIndex, s.entityIndex, s.communityIndex, s.entityTitleIndex} {
+		if hnsw, ok := idx.(*vector.HNSWIndex); ok {
+			total += int64(hnsw.Count()) * hnsw.Precision().VectorBytes(hnsw.Dimension())
+		}
+	}
+	return total
+}
+
+// countContentBytesLocked sums the text of every record. Caller must hold
+// s.mu.
+func (s *SessionStore) countContentBytesLocked() int64 {
+	var total int64
+	for _, doc := range s.documents {
+		total += documentBytes(doc)
+	}
+	for _, tu := range s.textUnits {
+		total += textUnitBytes(tu)
+	}
+	for _, ent := range s.entities {
+		total += entityBytes(ent)
+	}
+	for _, rel := range s.relationships {
+		total += relationshipBytes(rel)
+	}
+	for _, comm := range s.communities {
+		total += communityBytes(comm)
+	}
+	return total
+}
+
+func documentBytes(doc *types.Document) int64 {
+	return int64(len(doc.ExternalID) + len(doc.Filename))
+}
+
+func textUnitBytes(tu *types.TextUnit) int64 {
+	return int64(len(tu.ExternalID) + len(tu.Content))
+}
+
+func entityBytes(ent *types.Entity) int64 {
+	n := len(ent.ExternalID) + len(ent.Title) + len(ent.Type) + len(ent.Description)
+	for k, v := range ent.Metadata {
+		n += len(k) + len(v)
+	}
+	return int64(n)
+}
+
+func relationshipBytes(rel *types.Relationship) int64 {
+	return int64(len(rel.ExternalID) + len(rel.Type) + len(rel.Description))
+}
+
+func communityBytes(comm *types.Community) int64 {
+	return int64(len(comm.ExternalID) + len(comm.Title) + len(comm.Summary) + len(comm.FullContent))
+}
+
+// SetDistanceMetric switches the session's vector search metric, rebuilding
+// any existing indices so stored vectors are scored with the new metric.
+func (s *SessionStore) SetDistanceMetric(metric types.DistanceMetric) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	if metric == s.session.GetDistanceMetric() {
+		return nil
+	}
+	s.session.SetDistanceMetric(metric)
+	return s.rebuildIndicesLocked()
+}
+
+// SetIndexConfig sets the HNSW parameters used for indices created from now
+// on. Existing indices keep their graph until RebuildIndices is called.
+func (s *SessionStore) SetIndexConfig(config vector.HNSWConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+	s.indexConfig = config
+}
+
+// RebuildIndices rebuilds every vector index graph from its stored vectors
+// using the current index config. Entities and other records are untouched.
+func (s *SessionStore) RebuildIndices() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+	return s.rebuildIndicesLocked()
+}
+
+// rebuildIndicesLocked replaces each existing index with a fresh one.
+// Caller must hold s.mu.
+func (s *SessionStore) rebuildIndicesLocked() error {
+	for _, idx := range s.indexSlots() {
+		if *idx == nil {
+			continue
+		}
+		rebuilt := s.newIndex()
+		for id, vec := range (*idx).GetAllVectors() {
+			if err := rebuilt.Add(id, vec); err != nil {
+				return err
+			}
+		}
+		*idx = rebuilt
+	}
+	return nil
+}
+
+// rebuildProgressStep is how many vectors RebuildIndicesOnline adds between
+// progress reports
+const rebuildProgressStep = 256
+
+// RebuildIndicesOnline rebuilds the index graphs like RebuildIndices without
+// holding the session for the whole build. The new graphs are built from a
+// copy of the stored vectors while searches and writes keep using the old
+// ones; vectors written meanwhile are then applied to the new graphs, which
+// replace the old ones under the lock. progress, if not nil, is called with
+// the vectors added so far and the total.
+func (s *SessionStore) RebuildIndicesOnline(progress func(done, total int)) error {
+	s.mu.RLock()
+	config := s.effectiveIndexConfig()
+	slots := s.indexSlots()
+	snapshots := make([]map[uint64][]float32, len(slots))
+	total := 0
+	for i, idx := range slots {
+		if *idx != nil {
+			snapshots[i] = (*idx).GetAllVectors()
+			total += len(snapshots[i])
+		}
+	}
+	s.mu.RUnlock()
+
+	rebuilt := make([]vector.Index, len(snapshots))
+	done := 0
+	for i, vectors := range snapshots {
+		if vectors == nil {
+			continue
+		}
+		rebuilt[i] = vector.NewHNSWIndex(s.vectorDim, config)
+		for id, vec := range vectors {
+			if err := rebuilt[i].Add(id, vec); err != nil {
+				return err
+			}
+			done++
+			if progress != nil && done%rebuildProgressStep == 0 {
+				progress(done, total)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	// A metric, precision or config change during the build makes the new
+	// graphs stale, so rebuild them again, this time under the lock
+	if s.effectiveIndexConfig() != config {
+		return s.rebuildIndicesLocked()
+	}
+	for i, idx := range s.indexSlots() {
+		// An index created since the snapshot is already fresh
+		if *idx == nil || rebuilt[i] == nil {
+			continue
+		}
+		if err := applyVectorChanges(rebuilt[i], snapshots[i], (*idx).GetAllVectors()); err != nil {
+			return err
+		}
+		*idx = rebuilt[i]
+	}
+	return nil
+}
+
+// applyVectorChanges brings idx, built from the vectors in before, up to
+// date with now
+func applyVectorChanges(idx vector.Index, before, now map[uint64][]float32) error {
+	for id, vec := range now {
+		old, ok := before[id]
+		if ok && slices.Equal(old, vec) {
+			continue
+		}
+		if ok {
+			idx.Remove(id)
+		}
+		if err := idx.Add(id, vec); err != nil {
+			return err
+		}
+	}
+	for id := range before {
+		if _, ok := now[id]; !ok {
+			idx.Remove(id)
+		}
+	}
+	return nil
+}
+
+// VectorCount returns how many vectors the session's indices hold
+func (s *SessionStore) VectorCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	total := 0
+	for _, idx := range s.indexSlots() {
+		if *idx != nil {
+			total += (*idx).Count()
+		}
+	}
+	return total
+}
+
+// GetDistanceMetric returns the session's vector search metric
+func (s *SessionStore) GetDistanceMetric() types.DistanceMetric {
+	return s.session.GetDistanceMetric()
+}
+
 func (s *SessionStore) getTextUnitIndex() vector.Index {
 	if s.textUnitIndex == nil {
-		s.textUnitIndex = vector.NewHNSWIndex(s.vectorDim, vector.DefaultHNSWConfig())
+		s.textUnitIndex = s.newIndex()
 	}
 	return s.textUnitIndex
 }
 
 func (s *SessionStore) getEntityIndex() vector.Index {
 	if s.entityIndex == nil {
-		s.entityIndex = vector.NewHNSWIndex(s.vectorDim, vector.DefaultHNSWConfig())
+		s.entityIndex = s.newIndex()
 	}
 	return s.entityIndex
 }
 
 func (s *SessionStore) getCommunityIndex() vector.Index {
 	if s.communityIndex == nil {
-		s.communityIndex = vector.NewHNSWIndex(s.vectorDim, vector.DefaultHNSWConfig())
+		s.communityIndex = s.newIndex()
 	}
 	return s.communityIndex
 }
 
+func (s *SessionStore) getEntityTitleIndex() vector.Index {
+	if s.entityTitleIndex == nil {
+		s.entityTitleIndex = s.newIndex()
+	}
+	return s.entityTitleIndex
+}
+
 // GetTextUnitIndex returns the text unit vector index
 func (s *SessionStore) GetTextUnitIndex() vector.Index {
 	s.mu.Lock()
@@ -190,6 +543,14 @@ func (s *SessionStore) GetCommunityIndex() vector.Index {
 	return s.getCommunityIndex()
 }
 
+// GetEntityTitleIndex returns the entity title vector index, or nil when the
+// session has no title embeddings
+func (s *SessionStore) GetEntityTitleIndex() vector.Index {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.entityTitleIndex
+}
+
 // =============================================================================
 // Document Operations
 // =============================================================================
@@ -198,6 +559,7 @@ func (s *SessionStore) GetCommunityIndex() vector.Index {
 func (s *SessionStore) AddDocument(extID, filename string) (*types.Document, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	if _, exists := s.docByExtID[extID]; exists {
 		return nil, fmt.Errorf("document with external_id %s already exists", extID)
@@ -205,6 +567,7 @@ func (s *SessionStore) AddDocument(extID, filename string) (*types.Document, err
 
 	doc := types.NewDocument(s.idGen.NextDocumentID(), extID, filename)
 	s.documents[doc.ID] = doc
+	s.contentBytes += documentBytes(doc)
 	s.docByExtID[extID] = doc.ID
 	if filename != "" {
 		s.docByFilename[filename] = doc.ID
@@ -242,6 +605,7 @@ func (s *SessionStore) GetDocumentByExternalID(extID string) (*types.Document, b
 func (s *SessionStore) DeleteDocument(id uint64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	doc, ok := s.documents[id]
 	if !ok {
@@ -251,6 +615,7 @@ func (s *SessionStore) DeleteDocument(id uint64) bool {
 	delete(s.docByExtID, doc.ExternalID)
 	delete(s.docByFilename, doc.Filename)
 	delete(s.documents, id)
+	s.contentBytes -= documentBytes(doc)
 
 	s.session.Touch()
 	return true
@@ -283,6 +648,7 @@ func (s *SessionStore) DocumentCount() int {
 func (s *SessionStore) AddTextUnit(extID string, docID uint64, content string, embedding []float32, tokenCount int) (*types.TextUnit, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	if _, exists := s.tuByExtID[extID]; exists {
 		return nil, fmt.Errorf("textunit with external_id %s already exists", extID)
@@ -301,6 +667,8 @@ func (s *SessionStore) AddTextUnit(extID string, docID uint64, content string, e
 			return nil, err
 		}
 	}
+	s.textUnitKeywords.Add(tu.ID, content)
+	s.contentBytes += textUnitBytes(tu)
 
 	s.session.Touch()
 	return tu, nil
@@ -318,6 +686,30 @@ func (s *SessionStore) GetTextUnit(id uint64) (*types.TextUnit, bool) {
 	return tu, ok
 }
 
+// GetTextUnitByExternalID retrieves a text unit by external ID
+func (s *SessionStore) GetTextUnitByExternalID(extID string) (*types.TextUnit, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.tuByExtID[extID]
+	if !ok {
+		return nil, false
+	}
+	s.session.Touch()
+	return s.textUnits[id], true
+}
+
+// TextUnitEmbedding returns a copy of a text unit's indexed embedding
+func (s *SessionStore) TextUnitEmbedding(id uint64) ([]float32, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.textUnitIndex == nil {
+		return nil, false
+	}
+	return s.textUnitIndex.Vector(id)
+}
+
 // GetTextUnitsByDocumentID retrieves all text units for a document
 func (s *SessionStore) GetTextUnitsByDocumentID(docID uint64) []*types.TextUnit {
 	s.mu.RLock()
@@ -337,7 +729,38 @@ func (s *SessionStore) GetTextUnitsByDocumentID(docID uint64) []*types.TextUnit
 func (s *SessionStore) DeleteTextUnit(id uint64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
+	if !s.deleteTextUnitLocked(id) {
+		return false
+	}
+
+	s.session.Touch()
+	return true
+}
+
+// DeleteTextUnits removes many text units under one lock and returns how
+// many existed
+func (s *SessionStore) DeleteTextUnits(ids []uint64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	deleted := 0
+	for _, id := range ids {
+		if s.deleteTextUnitLocked(id) {
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		s.session.Touch()
+	}
+	return deleted
+}
+
+// deleteTextUnitLocked removes a text unit and its vector. Caller must hold
+// s.mu.
+func (s *SessionStore) deleteTextUnitLocked(id uint64) bool {
 	tu, ok := s.textUnits[id]
 	if !ok {
 		return false
@@ -355,19 +778,28 @@ func (s *SessionStore) DeleteTextUnit(id uint64) bool {
 	}
 
 	delete(s.textUnits, id)
+	s.contentBytes -= textUnitBytes(tu)
 
 	if s.textUnitIndex != nil {
 		s.textUnitIndex.Remove(id)
 	}
-
-	s.session.Touch()
+	s.textUnitKeywords.Remove(id)
 	return true
 }
 
+// SearchTextUnitsByKeyword returns the k text units whose content best
+// matches query by BM25, highest score first
+func (s *SessionStore) SearchTextUnitsByKeyword(query string, k int) []textsearch.Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.textUnitKeywords.Search(query, k)
+}
+
 // LinkTextUnitToEntity links a text unit to an entity
 func (s *SessionStore) LinkTextUnitToEntity(tuID, entityID uint64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	tu, ok := s.textUnits[tuID]
 	if !ok {
@@ -386,6 +818,40 @@ func (s *SessionStore) LinkTextUnitToEntity(tuID, entityID uint64) bool {
 	return true
 }
 
+// LinkTextUnitsToEntities applies many text unit -> entity links under one lock.
+// Unless continueOnError is set, processing stops at the first failing pair;
+// results cover only the pairs that were attempted.
+func (s *SessionStore) LinkTextUnitsToEntities(links []types.BulkLinkInput, continueOnError bool) []types.BulkLinkResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	results := make([]types.BulkLinkResult, 0, len(links))
+	for _, link := range links {
+		result := types.BulkLinkResult{TextUnitID: link.TextUnitID, EntityID: link.EntityID}
+
+		tu, tuOK := s.textUnits[link.TextUnitID]
+		ent, entOK := s.entities[link.EntityID]
+		switch {
+		case !tuOK:
+			result.Error = fmt.Sprintf("textunit %d not found", link.TextUnitID)
+		case !entOK:
+			result.Error = fmt.Sprintf("entity %d not found", link.EntityID)
+		default:
+			tu.AddEntityID(link.EntityID)
+			ent.AddTextUnitID(link.TextUnitID)
+		}
+
+		results = append(results, result)
+		if result.Error != "" && !continueOnError {
+			break
+		}
+	}
+
+	s.session.Touch()
+	return results
+}
+
 // GetAllTextUnits returns all text units
 func (s *SessionStore) GetAllTextUnits() []*types.TextUnit {
 	s.mu.RLock()
@@ -411,9 +877,78 @@ func (s *SessionStore) TextUnitCount() int {
 
 // AddEntity adds an entity to the session
 func (s *SessionStore) AddEntity(extID, title, entType, description string, embedding []float32) (*types.Entity, error) {
+	return s.AddEntityWithTitleEmbedding(extID, title, entType, description, embedding, nil)
+}
+
+// AddEntityWithTitleEmbedding adds an entity with separate description and
+// title embeddings. The title index is only allocated when titleEmbedding is set.
+func (s *SessionStore) AddEntityWithTitleEmbedding(extID, title, entType, description string, embedding, titleEmbedding []float32) (*types.Entity, error) {
+	return s.AddEntityWithMetadata(extID, title, entType, description, embedding, titleEmbedding, nil)
+}
+
+// AddEntityWithMetadata adds an entity carrying structured metadata. The
+// map is copied; titleEmbedding may be nil.
+func (s *SessionStore) AddEntityWithMetadata(extID, title, entType, description string, embedding, titleEmbedding []float32, metadata map[string]string) (*types.Entity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+	return s.addEntityLocked(extID, title, entType, description, embedding, titleEmbedding, metadata)
+}
+
+// dedupCandidates is how many nearest entities AddEntityDedup checks for one
+// of the same type
+const dedupCandidates = 10
+
+// AddEntityDedup adds an entity like AddEntityWithMetadata unless an
+// existing entity of the same type has an embedding with a cosine similarity
+// of at least threshold to embedding. The new entity is then merged into the
+// most similar one as MergeEntities would combine them: descriptions and
+// metadata are merged, the existing ID, title and embeddings are kept, and
+// merged is true. Only the nearest few entities are checked, and entities
+// without an embedding are always added.
+func (s *SessionStore) AddEntityDedup(threshold float32, extID, title, entType, description string, embedding, titleEmbedding []float32, metadata map[string]string) (ent *types.Entity, merged bool, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
+	if keep := s.similarEntityLocked(entType, embedding, threshold); keep != nil {
+		s.mergeEntityFieldsLocked(keep, description, metadata)
+		s.session.Touch()
+		return keep, true, nil
+	}
+	ent, err = s.addEntityLocked(extID, title, entType, description, embedding, titleEmbedding, metadata)
+	return ent, false, err
+}
+
+// similarEntityLocked returns the entity of type entType most similar to
+// embedding with a cosine similarity of at least threshold, or nil. Caller
+// must hold s.mu.
+func (s *SessionStore) similarEntityLocked(entType string, embedding []float32, threshold float32) *types.Entity {
+	if threshold <= 0 || len(embedding) == 0 || s.entityIndex == nil {
+		return nil
+	}
+	var best *types.Entity
+	bestSim := threshold
+	for _, result := range s.entityIndex.Search(embedding, dedupCandidates) {
+		ent, ok := s.entities[result.ID]
+		if !ok || ent.Type != entType {
+			continue
+		}
+		// Scored here rather than taken from the search, which uses the
+		// session's metric
+		vec, ok := s.entityIndex.Vector(result.ID)
+		if !ok {
+			continue
+		}
+		if sim := simd.CosineSimilarity(embedding, vec); sim >= bestSim {
+			best, bestSim = ent, sim
+		}
+	}
+	return best
+}
+
+// addEntityLocked is AddEntityWithMetadata for callers holding s.mu
+func (s *SessionStore) addEntityLocked(extID, title, entType, description string, embedding, titleEmbedding []float32, metadata map[string]string) (*types.Entity, error) {
 	normalizedTitle := strings.ToUpper(strings.TrimSpace(title))
 
 	if _, exists := s.entByTitle[normalizedTitle]; exists {
@@ -426,13 +961,19 @@ func (s *SessionStore) AddEntity(extID, title, entType, description string, embe
 	}
 
 	ent := types.NewEntity(s.idGen.NextEntityID(), extID, normalizedTitle, entType, description)
+	if len(metadata) > 0 {
+		ent.Metadata = make(map[string]string, len(metadata))
+		for k, v := range metadata {
+			ent.Metadata[k] = v
+		}
+	}
 	s.entities[ent.ID] = ent
 	s.entByTitle[normalizedTitle] = ent.ID
 	if extID != "" {
 		s.entByExtID[extID] = ent.ID
 	}
 
-	// Add to vector index
+	// Add to vector indices
 	if len(embedding) > 0 {
 		if err := s.getEntityIndex().Add(ent.ID, embedding); err != nil {
 			delete(s.entities, ent.ID)
@@ -441,6 +982,19 @@ func (s *SessionStore) AddEntity(extID, title, entType, description string, embe
 			return nil, err
 		}
 	}
+	if len(titleEmbedding) > 0 {
+		if err := s.getEntityTitleIndex().Add(ent.ID, titleEmbedding); err != nil {
+			if s.entityIndex != nil {
+				s.entityIndex.Remove(ent.ID)
+			}
+			delete(s.entities, ent.ID)
+			delete(s.entByTitle, normalizedTitle)
+			delete(s.entByExtID, extID)
+			return nil, err
+		}
+	}
+	s.entByType[ent.Type]++
+	s.contentBytes += entityBytes(ent)
 
 	s.session.Touch()
 	return ent, nil
@@ -458,6 +1012,30 @@ func (s *SessionStore) GetEntity(id uint64) (*types.Entity, bool) {
 	return ent, ok
 }
 
+// GetEntityByExternalID retrieves an entity by external ID
+func (s *SessionStore) GetEntityByExternalID(extID string) (*types.Entity, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.entByExtID[extID]
+	if !ok {
+		return nil, false
+	}
+	s.session.Touch()
+	return s.entities[id], true
+}
+
+// EntityEmbedding returns a copy of an entity's indexed embedding
+func (s *SessionStore) EntityEmbedding(id uint64) ([]float32, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.entityIndex == nil {
+		return nil, false
+	}
+	return s.entityIndex.Vector(id)
+}
+
 // GetEntityByTitle retrieves an entity by title
 func (s *SessionStore) GetEntityByTitle(title string) (*types.Entity, bool) {
 	s.mu.RLock()
@@ -475,13 +1053,16 @@ func (s *SessionStore) GetEntityByTitle(title string) (*types.Entity, bool) {
 func (s *SessionStore) UpdateEntityDescription(id uint64, description string, embedding []float32) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	ent, ok := s.entities[id]
 	if !ok {
 		return false
 	}
 
+	s.contentBytes += int64(len(description) - len(ent.Description))
 	ent.Description = description
+	ent.UpdatedAt = time.Now().Unix()
 
 	// Update vector index
 	if len(embedding) > 0 && s.entityIndex != nil {
@@ -495,11 +1076,136 @@ func (s *SessionStore) UpdateEntityDescription(id uint64, description string, em
 	return true
 }
 
-// DeleteEntity removes an entity
+// UpdateEntityTitle renames an entity and moves its title index entry. The
+// title is normalized as in AddEntity; renaming onto a title held by another
+// entity fails with types.ErrDuplicateTitle. The title embedding, if any, is
+// left unchanged.
+func (s *SessionStore) UpdateEntityTitle(id uint64, newTitle string) error {
+	normalizedTitle := strings.ToUpper(strings.TrimSpace(newTitle))
+	if normalizedTitle == "" {
+		return fmt.Errorf("entity title is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	ent, ok := s.entities[id]
+	if !ok {
+		return fmt.Errorf("entity %d not found", id)
+	}
+	if owner, exists := s.entByTitle[normalizedTitle]; exists && owner != id {
+		return fmt.Errorf("title %s belongs to entity %d: %w", normalizedTitle, owner, types.ErrDuplicateTitle)
+	}
+
+	delete(s.entByTitle, ent.Title)
+	s.contentBytes += int64(len(normalizedTitle) - len(ent.Title))
+	ent.Title = normalizedTitle
+	ent.UpdatedAt = time.Now().Unix()
+	s.entByTitle[normalizedTitle] = id
+
+	s.session.Touch()
+	return nil
+}
+
+// UpdateEntityMetadata merges metadata into an entity's metadata. An empty
+// value removes that key.
+func (s *SessionStore) UpdateEntityMetadata(id uint64, metadata map[string]string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	ent, ok := s.entities[id]
+	if !ok {
+		return false
+	}
+
+	// Readers hold ent.Metadata past the lock, so the map is replaced
+	// rather than changed in place
+	before := entityBytes(ent)
+	updated := maps.Clone(ent.Metadata)
+	if updated == nil {
+		updated = make(map[string]string, len(metadata))
+	}
+	for k, v := range metadata {
+		if v == "" {
+			delete(updated, k)
+			continue
+		}
+		updated[k] = v
+	}
+	if len(updated) == 0 {
+		updated = nil
+	}
+	ent.Metadata = updated
+	ent.UpdatedAt = time.Now().Unix()
+	s.contentBytes += entityBytes(ent) - before
+
+	s.session.Touch()
+	return true
+}
+
+// DeleteEntity removes an entity along with its relationships and its text
+// unit links
 func (s *SessionStore) DeleteEntity(id uint64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
+
+	if !s.deleteEntityCascadeLocked(id) {
+		return false
+	}
+
+	s.session.Touch()
+	return true
+}
+
+// DeleteEntities removes many entities under one lock, cascading as
+// DeleteEntity does, and returns how many existed
+func (s *SessionStore) DeleteEntities(ids []uint64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	deleted := 0
+	for _, id := range ids {
+		if s.deleteEntityCascadeLocked(id) {
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		s.session.Touch()
+	}
+	return deleted
+}
+
+// deleteEntityCascadeLocked removes an entity, the relationships touching it
+// and its links from text units. Caller must hold s.mu.
+func (s *SessionStore) deleteEntityCascadeLocked(id uint64) bool {
+	ent, ok := s.entities[id]
+	if !ok {
+		return false
+	}
 
+	// Concat copies: deleting a relationship edits the edge lists
+	for _, relID := range slices.Concat(s.outEdges[id], s.inEdges[id]) {
+		if rel, ok := s.relationships[relID]; ok {
+			s.deleteRelationshipLocked(rel)
+		}
+	}
+	delete(s.outEdges, id)
+	delete(s.inEdges, id)
+
+	for _, tuID := range ent.TextUnitIDs {
+		if tu, ok := s.textUnits[tuID]; ok {
+			tu.RemoveEntityID(id)
+		}
+	}
+	return s.deleteEntityLocked(id)
+}
+
+// deleteEntityLocked removes an entity and its vectors. Caller must hold s.mu.
+func (s *SessionStore) deleteEntityLocked(id uint64) bool {
 	ent, ok := s.entities[id]
 	if !ok {
 		return false
@@ -508,15 +1214,134 @@ func (s *SessionStore) DeleteEntity(id uint64) bool {
 	delete(s.entByTitle, ent.Title)
 	delete(s.entByExtID, ent.ExternalID)
 	delete(s.entities, id)
+	decrementTypeCount(s.entByType, ent.Type)
+	s.contentBytes -= entityBytes(ent)
 
 	if s.entityIndex != nil {
 		s.entityIndex.Remove(id)
 	}
+	if s.entityTitleIndex != nil {
+		s.entityTitleIndex.Remove(id)
+	}
 
-	s.session.Touch()
+	s.popMu.Lock()
+	delete(s.popularity, id)
+	s.popMu.Unlock()
 	return true
 }
 
+// MergeEntities folds mergeID into keepID: relationships and text unit
+// links move to keepID, descriptions and metadata are combined, and
+// mergeID is deleted. A moved relationship that would duplicate an
+// existing keepID edge is dropped (its provenance is added to the
+// surviving edge), as is one that would become a self-loop. keepID's
+// embedding is left as is.
+func (s *SessionStore) MergeEntities(keepID, mergeID uint64) error {
+	if keepID == mergeID {
+		return fmt.Errorf("cannot merge entity %d into itself", keepID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	keep, ok := s.entities[keepID]
+	if !ok {
+		return fmt.Errorf("entity %d not found", keepID)
+	}
+	merged, ok := s.entities[mergeID]
+	if !ok {
+		return fmt.Errorf("entity %d not found", mergeID)
+	}
+
+	// Collect first: relinking edits the edge lists being read
+	relIDs := make([]uint64, 0, len(s.outEdges[mergeID])+len(s.inEdges[mergeID]))
+	seen := make(map[uint64]bool)
+	for _, ids := range [][]uint64{s.outEdges[mergeID], s.inEdges[mergeID]} {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				relIDs = append(relIDs, id)
+			}
+		}
+	}
+
+	for _, id := range relIDs {
+		rel := s.relationships[id]
+		if rel == nil {
+			continue
+		}
+		s.unlinkRelationshipLocked(rel)
+
+		sourceID, targetID := rel.SourceID, rel.TargetID
+		if sourceID == mergeID {
+			sourceID = keepID
+		}
+		if targetID == mergeID {
+			targetID = keepID
+		}
+
+		existingID, duplicate := s.relBySourceTarget[s.makeRelKey(sourceID, targetID)]
+		if sourceID == targetID || duplicate {
+			if duplicate {
+				for _, tuID := range rel.TextUnitIDs {
+					s.relationships[existingID].AddTextUnitID(tuID)
+				}
+			}
+			delete(s.relByExtID, rel.ExternalID)
+			delete(s.relationships, id)
+			s.contentBytes -= relationshipBytes(rel)
+			continue
+		}
+
+		rel.SourceID, rel.TargetID = sourceID, targetID
+		s.linkRelationshipLocked(rel)
+	}
+
+	for _, tuID := range merged.TextUnitIDs {
+		if tu, ok := s.textUnits[tuID]; ok {
+			tu.RemoveEntityID(mergeID)
+			tu.AddEntityID(keepID)
+		}
+		keep.AddTextUnitID(tuID)
+	}
+
+	s.mergeEntityFieldsLocked(keep, merged.Description, merged.Metadata)
+	s.deleteEntityLocked(mergeID)
+	delete(s.outEdges, mergeID)
+	delete(s.inEdges, mergeID)
+
+	s.session.Touch()
+	return nil
+}
+
+// mergeEntityFieldsLocked appends description to keep's unless it is empty
+// or the same, and adds the metadata keys keep lacks. Caller must hold s.mu.
+func (s *SessionStore) mergeEntityFieldsLocked(keep *types.Entity, description string, metadata map[string]string) {
+	before := entityBytes(keep)
+	switch {
+	case keep.Description == "":
+		keep.Description = description
+	case description != "" && description != keep.Description:
+		keep.Description += "\n" + description
+	}
+	var merged map[string]string // copy-on-write, as in UpdateEntityMetadata
+	for k, v := range metadata {
+		if _, exists := keep.Metadata[k]; !exists {
+			if merged == nil {
+				merged = make(map[string]string, len(keep.Metadata)+len(metadata))
+				maps.Copy(merged, keep.Metadata)
+			}
+			merged[k] = v
+		}
+	}
+	if merged != nil {
+		keep.Metadata = merged
+	}
+	keep.UpdatedAt = time.Now().Unix()
+	s.contentBytes += entityBytes(keep) - before
+}
+
 // GetAllEntities returns all entities
 func (s *SessionStore) GetAllEntities() []*types.Entity {
 	s.mu.RLock()
@@ -529,8 +1354,20 @@ func (s *SessionStore) GetAllEntities() []*types.Entity {
 	return result
 }
 
+// ListScanBudget caps how many items one filtered list call examines, so a
+// rare filter value can't turn a page into a full scan
+const ListScanBudget = 50000
+
 // ListEntities returns entities after the given cursor, up to limit, in ID order.
 func (s *SessionStore) ListEntities(afterID uint64, limit int) ([]*types.Entity, uint64) {
+	return s.ListEntitiesByType(afterID, limit, "")
+}
+
+// ListEntitiesByType is ListEntities restricted to entities of entType
+// (empty = all). Pages keep scanning past non-matching entities until limit
+// matches or ListScanBudget entities were examined; a short or empty page
+// with a non-zero cursor means the budget ran out before the end.
+func (s *SessionStore) ListEntitiesByType(afterID uint64, limit int, entType string) ([]*types.Entity, uint64) {
 	if limit <= 0 {
 		limit = 1000
 	}
@@ -550,9 +1387,10 @@ func (s *SessionStore) ListEntities(afterID uint64, limit int) ([]*types.Entity,
 	var lastID uint64
 
 	s.mu.RLock()
-	for ; i < len(ids) && len(results) < limit; i++ {
+	for scanned := 0; i < len(ids) && len(results) < limit && scanned < ListScanBudget; i++ {
 		lastID = ids[i]
-		if ent, ok := s.entities[lastID]; ok {
+		scanned++
+		if ent, ok := s.entities[lastID]; ok && (entType == "" || ent.Type == entType) {
 			results = append(results, ent)
 		}
 	}
@@ -573,6 +1411,19 @@ func (s *SessionStore) EntityCount() int {
 	return len(s.entities)
 }
 
+// SetEntityPageRanks stores PageRank scores on the entities. Entities missing
+// from scores get 0.
+func (s *SessionStore) SetEntityPageRanks(scores map[uint64]float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	for id, ent := range s.entities {
+		ent.PageRank = scores[id]
+	}
+	s.session.Touch()
+}
+
 // =============================================================================
 // Relationship Operations
 // =============================================================================
@@ -583,8 +1434,19 @@ func (s *SessionStore) makeRelKey(sourceID, targetID uint64) string {
 
 // AddRelationship adds a relationship to the session
 func (s *SessionStore) AddRelationship(extID string, sourceID, targetID uint64, relType, description string, weight float32) (*types.Relationship, error) {
+	return s.AddRelationshipWithOptions(extID, sourceID, targetID, relType, description, weight, types.RelationshipOptions{})
+}
+
+// AddRelationshipWithOptions adds a relationship with its validity window
+// and direction already set, so readers never see it without them
+func (s *SessionStore) AddRelationshipWithOptions(extID string, sourceID, targetID uint64, relType, description string, weight float32, opts types.RelationshipOptions) (*types.Relationship, error) {
+	if opts.ValidUntil != 0 && opts.ValidUntil <= opts.ValidFrom {
+		return nil, types.ErrInvalidValidityWindow
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	key := s.makeRelKey(sourceID, targetID)
 	if _, exists := s.relBySourceTarget[key]; exists {
@@ -601,18 +1463,120 @@ func (s *SessionStore) AddRelationship(extID string, sourceID, targetID uint64,
 	}
 
 	rel := types.NewRelationship(s.idGen.NextRelationshipID(), extID, sourceID, targetID, relType, description, weight)
+	rel.ValidFrom, rel.ValidUntil, rel.Directed = opts.ValidFrom, opts.ValidUntil, opts.Directed
 	s.relationships[rel.ID] = rel
-	s.relBySourceTarget[key] = rel.ID
 	if extID != "" {
 		s.relByExtID[extID] = rel.ID
 	}
-	s.outEdges[sourceID] = append(s.outEdges[sourceID], rel.ID)
-	s.inEdges[targetID] = append(s.inEdges[targetID], rel.ID)
+	s.linkRelationshipLocked(rel)
+	s.contentBytes += relationshipBytes(rel)
 
 	s.session.Touch()
 	return rel, nil
 }
 
+// SetRelationshipValidity sets a relationship's temporal validity window
+func (s *SessionStore) SetRelationshipValidity(id uint64, validFrom, validUntil int64) error {
+	if validUntil != 0 && validUntil <= validFrom {
+		return types.ErrInvalidValidityWindow
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	rel, ok := s.relationships[id]
+	if !ok {
+		return fmt.Errorf("relationship %d not found", id)
+	}
+
+	rel.ValidFrom = validFrom
+	rel.ValidUntil = validUntil
+	rel.UpdatedAt = time.Now().Unix()
+
+	s.session.Touch()
+	return nil
+}
+
+// SetRelationshipDirected marks a relationship directed or undirected
+func (s *SessionStore) SetRelationshipDirected(id uint64, directed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	rel, ok := s.relationships[id]
+	if !ok {
+		return fmt.Errorf("relationship %d not found", id)
+	}
+
+	if rel.Directed != directed {
+		rel.Directed = directed
+		rel.UpdatedAt = time.Now().Unix()
+	}
+
+	s.session.Touch()
+	return nil
+}
+
+// UpdateRelationshipWeight sets a relationship's weight and marks the
+// session's communities dirty, since Leiden clusters on edge weights
+func (s *SessionStore) UpdateRelationshipWeight(id uint64, weight float32) error {
+	if !(weight > 0) || math.IsInf(float64(weight), 0) {
+		return fmt.Errorf("relationship weight must be positive and finite, got %v", weight)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	rel, ok := s.relationships[id]
+	if !ok {
+		return fmt.Errorf("relationship %d not found", id)
+	}
+
+	if rel.Weight != weight {
+		rel.Weight = weight
+		rel.UpdatedAt = time.Now().Unix()
+		s.edgeVersion++
+	}
+
+	s.session.Touch()
+	return nil
+}
+
+// DataVersion returns a counter that changes whenever the session's records,
+// vectors, index settings or PageRank scores change. Entity access counts
+// are not included.
+func (s *SessionStore) DataVersion() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dataVersion
+}
+
+// EdgeVersion returns a counter that changes whenever edges or their weights
+// change; pass it to MarkCommunitiesComputed after clustering
+func (s *SessionStore) EdgeVersion() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.edgeVersion
+}
+
+// MarkCommunitiesComputed records that the current communities were computed
+// from the graph at edgeVersion
+func (s *SessionStore) MarkCommunitiesComputed(edgeVersion uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.communityVersion = edgeVersion
+}
+
+// CommunitiesDirty reports whether edges changed since communities were
+// last computed
+func (s *SessionStore) CommunitiesDirty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.edgeVersion != s.communityVersion
+}
+
 // GetRelationship retrieves a relationship by ID
 func (s *SessionStore) GetRelationship(id uint64) (*types.Relationship, bool) {
 	s.mu.RLock()
@@ -622,7 +1586,20 @@ func (s *SessionStore) GetRelationship(id uint64) (*types.Relationship, bool) {
 	if ok {
 		s.session.Touch()
 	}
-	return rel, ok
+	return rel, ok
+}
+
+// GetRelationshipByExternalID retrieves a relationship by external ID
+func (s *SessionStore) GetRelationshipByExternalID(extID string) (*types.Relationship, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.relByExtID[extID]
+	if !ok {
+		return nil, false
+	}
+	s.session.Touch()
+	return s.relationships[id], true
 }
 
 // GetRelationshipBySourceTarget retrieves a relationship by source and target
@@ -668,6 +1645,49 @@ func (s *SessionStore) GetIncomingRelationships(entityID uint64) []*types.Relati
 	return result
 }
 
+// RecordEntityAccess bumps the decaying access counter of each entity by one
+func (s *SessionStore) RecordEntityAccess(ids []uint64, halfLife time.Duration) {
+	if len(ids) == 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+
+	s.popMu.Lock()
+	defer s.popMu.Unlock()
+
+	if s.popularity == nil {
+		s.popularity = make(map[uint64]*accessCounter)
+	}
+	for _, id := range ids {
+		c, ok := s.popularity[id]
+		if !ok {
+			c = &accessCounter{}
+			s.popularity[id] = c
+		}
+		c.value = c.decayed(now, halfLife) + 1
+		c.updated = now
+	}
+}
+
+// EntityPopularity returns the decayed access count of an entity
+func (s *SessionStore) EntityPopularity(id uint64, halfLife time.Duration) float64 {
+	s.popMu.Lock()
+	defer s.popMu.Unlock()
+
+	c, ok := s.popularity[id]
+	if !ok {
+		return 0
+	}
+	return c.decayed(time.Now().UnixNano(), halfLife)
+}
+
+// Degree returns the number of relationships (incoming + outgoing) of an entity
+func (s *SessionStore) Degree(entityID uint64) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.outEdges[entityID]) + len(s.inEdges[entityID])
+}
+
 // GetNeighbors returns all neighboring entity IDs
 func (s *SessionStore) GetNeighbors(entityID uint64) []uint64 {
 	s.mu.RLock()
@@ -697,20 +1717,68 @@ func (s *SessionStore) GetNeighbors(entityID uint64) []uint64 {
 func (s *SessionStore) DeleteRelationship(id uint64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	rel, ok := s.relationships[id]
 	if !ok {
 		return false
 	}
 
-	key := s.makeRelKey(rel.SourceID, rel.TargetID)
-	delete(s.relBySourceTarget, key)
+	s.deleteRelationshipLocked(rel)
+	s.session.Touch()
+	return true
+}
+
+// DeleteRelationships removes many relationships under one lock and returns
+// how many existed
+func (s *SessionStore) DeleteRelationships(ids []uint64) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	deleted := 0
+	for _, id := range ids {
+		if rel, ok := s.relationships[id]; ok {
+			s.deleteRelationshipLocked(rel)
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		s.session.Touch()
+	}
+	return deleted
+}
+
+// deleteRelationshipLocked removes rel from the store and its indexes.
+// Caller must hold s.mu.
+func (s *SessionStore) deleteRelationshipLocked(rel *types.Relationship) {
+	s.unlinkRelationshipLocked(rel)
 	delete(s.relByExtID, rel.ExternalID)
+	delete(s.relationships, rel.ID)
+	s.contentBytes -= relationshipBytes(rel)
+}
+
+// linkRelationshipLocked indexes rel by its endpoints and type. Caller must
+// hold s.mu.
+func (s *SessionStore) linkRelationshipLocked(rel *types.Relationship) {
+	s.edgeVersion++
+	s.relByType[rel.Type]++
+	s.relBySourceTarget[s.makeRelKey(rel.SourceID, rel.TargetID)] = rel.ID
+	s.outEdges[rel.SourceID] = append(s.outEdges[rel.SourceID], rel.ID)
+	s.inEdges[rel.TargetID] = append(s.inEdges[rel.TargetID], rel.ID)
+}
+
+// unlinkRelationshipLocked removes rel from the endpoint and type indexes.
+// Caller must hold s.mu.
+func (s *SessionStore) unlinkRelationshipLocked(rel *types.Relationship) {
+	s.edgeVersion++
+	decrementTypeCount(s.relByType, rel.Type)
+	delete(s.relBySourceTarget, s.makeRelKey(rel.SourceID, rel.TargetID))
 
 	// Remove from outEdges
 	outIDs := s.outEdges[rel.SourceID]
 	for i, rid := range outIDs {
-		if rid == id {
+		if rid == rel.ID {
 			s.outEdges[rel.SourceID] = append(outIDs[:i], outIDs[i+1:]...)
 			break
 		}
@@ -719,16 +1787,11 @@ func (s *SessionStore) DeleteRelationship(id uint64) bool {
 	// Remove from inEdges
 	inIDs := s.inEdges[rel.TargetID]
 	for i, rid := range inIDs {
-		if rid == id {
+		if rid == rel.ID {
 			s.inEdges[rel.TargetID] = append(inIDs[:i], inIDs[i+1:]...)
 			break
 		}
 	}
-
-	delete(s.relationships, id)
-
-	s.session.Touch()
-	return true
 }
 
 // GetAllRelationships returns all relationships
@@ -745,14 +1808,29 @@ func (s *SessionStore) GetAllRelationships() []*types.Relationship {
 
 // ListRelationships returns relationships after the given cursor, up to limit, in ID order.
 func (s *SessionStore) ListRelationships(afterID uint64, limit int) ([]*types.Relationship, uint64) {
+	return s.ListRelationshipsFiltered(afterID, limit, types.RelationshipFilter{})
+}
+
+// ListRelationshipsFiltered is ListRelationships restricted to relationships
+// matching filter. An endpoint filter reads only that entity's edges; other
+// filters scan in ID order within ListScanBudget, as in ListEntitiesByType.
+func (s *SessionStore) ListRelationshipsFiltered(afterID uint64, limit int, filter types.RelationshipFilter) ([]*types.Relationship, uint64) {
 	if limit <= 0 {
 		limit = 1000
 	}
 
 	s.mu.RLock()
-	ids := make([]uint64, 0, len(s.relationships))
-	for id := range s.relationships {
-		ids = append(ids, id)
+	var ids []uint64
+	switch {
+	case filter.SourceID != 0:
+		ids = slices.Clone(s.outEdges[filter.SourceID])
+	case filter.TargetID != 0:
+		ids = slices.Clone(s.inEdges[filter.TargetID])
+	default:
+		ids = make([]uint64, 0, len(s.relationships))
+		for id := range s.relationships {
+			ids = append(ids, id)
+		}
 	}
 	s.mu.RUnlock()
 
@@ -764,9 +1842,10 @@ func (s *SessionStore) ListRelationships(afterID uint64, limit int) ([]*types.Re
 	var lastID uint64
 
 	s.mu.RLock()
-	for ; i < len(ids) && len(results) < limit; i++ {
+	for scanned := 0; i < len(ids) && len(results) < limit && scanned < ListScanBudget; i++ {
 		lastID = ids[i]
-		if rel, ok := s.relationships[lastID]; ok {
+		scanned++
+		if rel, ok := s.relationships[lastID]; ok && filter.Matches(rel) {
 			results = append(results, rel)
 		}
 	}
@@ -787,6 +1866,61 @@ func (s *SessionStore) RelationshipCount() int {
 	return len(s.relationships)
 }
 
+// RelationshipTypeStats returns a histogram of relationship types ordered by
+// count descending (ties by type name)
+func (s *SessionStore) RelationshipTypeStats() []types.RelationshipTypeStat {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	counts := make(map[string]int)
+	weights := make(map[string]float64)
+	for _, rel := range s.relationships {
+		counts[rel.Type]++
+		weights[rel.Type] += float64(rel.Weight)
+	}
+
+	stats := make([]types.RelationshipTypeStat, 0, len(counts))
+	for relType, count := range counts {
+		stats = append(stats, types.RelationshipTypeStat{
+			Type:      relType,
+			Count:     count,
+			AvgWeight: float32(weights[relType] / float64(count)),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		return stats[i].Type < stats[j].Type
+	})
+
+	s.session.Touch()
+	return stats
+}
+
+// EntityTypeCounts returns the number of entities of each type
+func (s *SessionStore) EntityTypeCounts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.entByType)
+}
+
+// RelationshipTypeCounts returns the number of relationships of each type
+func (s *SessionStore) RelationshipTypeCounts() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return maps.Clone(s.relByType)
+}
+
+// decrementTypeCount lowers the count for typ, dropping it at zero
+func decrementTypeCount(counts map[string]int, typ string) {
+	if counts[typ] <= 1 {
+		delete(counts, typ)
+		return
+	}
+	counts[typ]--
+}
+
 // =============================================================================
 // Community Operations
 // =============================================================================
@@ -795,6 +1929,7 @@ func (s *SessionStore) RelationshipCount() int {
 func (s *SessionStore) AddCommunity(extID, title, summary, fullContent string, level int, entityIDs, relIDs []uint64, embedding []float32) (*types.Community, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	if extID != "" {
 		if _, exists := s.commByExtID[extID]; exists {
@@ -808,15 +1943,18 @@ func (s *SessionStore) AddCommunity(extID, title, summary, fullContent string, l
 		s.commByExtID[extID] = comm.ID
 	}
 	s.commByLevel[level] = append(s.commByLevel[level], comm.ID)
+	s.indexCommunityMembersLocked(comm)
 
 	// Add to vector index
 	if len(embedding) > 0 {
 		if err := s.getCommunityIndex().Add(comm.ID, embedding); err != nil {
 			delete(s.communities, comm.ID)
 			delete(s.commByExtID, extID)
+			s.unindexCommunityMembersLocked(comm)
 			return nil, err
 		}
 	}
+	s.contentBytes += communityBytes(comm)
 
 	s.session.Touch()
 	return comm, nil
@@ -853,6 +1991,7 @@ func (s *SessionStore) GetCommunitiesByLevel(level int) []*types.Community {
 func (s *SessionStore) DeleteCommunity(id uint64) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	comm, ok := s.communities[id]
 	if !ok {
@@ -869,8 +2008,10 @@ func (s *SessionStore) DeleteCommunity(id uint64) bool {
 			break
 		}
 	}
+	s.unindexCommunityMembersLocked(comm)
 
 	delete(s.communities, id)
+	s.contentBytes -= communityBytes(comm)
 
 	if s.communityIndex != nil {
 		s.communityIndex.Remove(id)
@@ -880,17 +2021,79 @@ func (s *SessionStore) DeleteCommunity(id uint64) bool {
 	return true
 }
 
+// GetEntityCommunities returns the communities containing an entity, across
+// all hierarchy levels, ordered by level then ID
+func (s *SessionStore) GetEntityCommunities(entityID uint64) ([]*types.Community, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.entities[entityID]; !ok {
+		return nil, fmt.Errorf("entity %d not found", entityID)
+	}
+
+	ids := s.commByEntity[entityID]
+	result := make([]*types.Community, 0, len(ids))
+	for _, id := range ids {
+		if comm, ok := s.communities[id]; ok {
+			result = append(result, comm)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Level != result[j].Level {
+			return result[i].Level < result[j].Level
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// indexCommunityMembersLocked adds comm to the reverse index of each of its
+// entities. Caller holds s.mu.
+func (s *SessionStore) indexCommunityMembersLocked(comm *types.Community) {
+	for _, entID := range comm.EntityIDs {
+		ids := s.commByEntity[entID]
+		if n := len(ids); n > 0 && ids[n-1] == comm.ID {
+			continue // entity listed twice
+		}
+		s.commByEntity[entID] = append(ids, comm.ID)
+	}
+}
+
+// unindexCommunityMembersLocked drops comm from the reverse index. Caller
+// holds s.mu.
+func (s *SessionStore) unindexCommunityMembersLocked(comm *types.Community) {
+	for _, entID := range comm.EntityIDs {
+		ids := s.commByEntity[entID]
+		for i, cid := range ids {
+			if cid == comm.ID {
+				ids = append(ids[:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(ids) == 0 {
+			delete(s.commByEntity, entID)
+		} else {
+			s.commByEntity[entID] = ids
+		}
+	}
+}
+
 // ClearCommunities removes all communities (useful before re-computing)
 func (s *SessionStore) ClearCommunities() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
+	for _, comm := range s.communities {
+		s.contentBytes -= communityBytes(comm)
+	}
 	s.communities = make(map[uint64]*types.Community)
 	s.commByExtID = make(map[string]uint64)
 	s.commByLevel = make(map[int][]uint64)
+	s.commByEntity = make(map[uint64][]uint64)
 
 	if s.communityIndex != nil {
-		s.communityIndex = vector.NewHNSWIndex(s.vectorDim, vector.DefaultHNSWConfig())
+		s.communityIndex = s.newIndex()
 	}
 }
 
@@ -917,10 +2120,12 @@ func (s *SessionStore) CommunityCount() int {
 // Bulk Operations
 // =============================================================================
 
-// Clear removes all data from the session store
+// Clear removes all data from the session store. Session settings (TTL,
+// metadata, metric, index configuration) are kept.
 func (s *SessionStore) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	s.documents = make(map[uint64]*types.Document)
 	s.docByExtID = make(map[string]uint64)
@@ -929,25 +2134,36 @@ func (s *SessionStore) Clear() {
 	s.textUnits = make(map[uint64]*types.TextUnit)
 	s.tuByExtID = make(map[string]uint64)
 	s.tuByDocID = make(map[uint64][]uint64)
+	s.textUnitKeywords = textsearch.NewIndex()
 
 	s.entities = make(map[uint64]*types.Entity)
 	s.entByExtID = make(map[string]uint64)
 	s.entByTitle = make(map[string]uint64)
+	s.entByType = make(map[string]int)
 
 	s.relationships = make(map[uint64]*types.Relationship)
 	s.relByExtID = make(map[string]uint64)
 	s.relBySourceTarget = make(map[string]uint64)
 	s.outEdges = make(map[uint64][]uint64)
 	s.inEdges = make(map[uint64][]uint64)
+	s.relByType = make(map[string]int)
 
 	s.communities = make(map[uint64]*types.Community)
 	s.commByExtID = make(map[string]uint64)
 	s.commByLevel = make(map[int][]uint64)
+	s.commByEntity = make(map[uint64][]uint64)
+	s.communityVersion = s.edgeVersion
 
 	// Reset vector indices
 	s.textUnitIndex = nil
 	s.entityIndex = nil
 	s.communityIndex = nil
+	s.entityTitleIndex = nil
+	s.contentBytes = 0
+
+	s.popMu.Lock()
+	s.popularity = nil
+	s.popMu.Unlock()
 
 	// Reset ID generator
 	s.idGen = types.NewIDGenerator()
@@ -970,6 +2186,8 @@ type SessionSnapshot struct {
 	TextUnitVectors  map[uint64][]float32  `json:"text_unit_vectors"`
 	EntityVectors    map[uint64][]float32  `json:"entity_vectors"`
 	CommunityVectors map[uint64][]float32  `json:"community_vectors"`
+
+	EntityTitleVectors map[uint64][]float32 `json:"entity_title_vectors,omitempty"`
 }
 
 // Snapshot creates a snapshot of the session
@@ -985,17 +2203,9 @@ func (s *SessionStore) Snapshot() *SessionSnapshot {
 		Entities:         s.GetAllEntities(),
 		Relationships:    s.GetAllRelationships(),
 		Communities:      s.GetAllCommunities(),
-		IDGeneratorState: make(map[string]uint64),
+		IDGeneratorState: s.idGeneratorState(),
 	}
 
-	// Save ID generator state
-	doc, tu, ent, rel, comm, _ := s.idGen.GetCounters()
-	snapshot.IDGeneratorState["document"] = doc
-	snapshot.IDGeneratorState["textunit"] = tu
-	snapshot.IDGeneratorState["entity"] = ent
-	snapshot.IDGeneratorState["relationship"] = rel
-	snapshot.IDGeneratorState["community"] = comm
-
 	// Save vector indices
 	if s.textUnitIndex != nil {
 		snapshot.TextUnitVectors = s.textUnitIndex.GetAllVectors()
@@ -1006,6 +2216,9 @@ func (s *SessionStore) Snapshot() *SessionSnapshot {
 	if s.communityIndex != nil {
 		snapshot.CommunityVectors = s.communityIndex.GetAllVectors()
 	}
+	if s.entityTitleIndex != nil {
+		snapshot.EntityTitleVectors = s.entityTitleIndex.GetAllVectors()
+	}
 
 	return snapshot
 }
@@ -1014,6 +2227,7 @@ func (s *SessionStore) Snapshot() *SessionSnapshot {
 func (s *SessionStore) RestoreFromSnapshot(snapshot *SessionSnapshot) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.dataVersion++
 
 	// Restore session metadata
 	s.session = snapshot.Session
@@ -1034,19 +2248,23 @@ func (s *SessionStore) RestoreFromSnapshot(snapshot *SessionSnapshot) error {
 	s.textUnits = make(map[uint64]*types.TextUnit)
 	s.tuByExtID = make(map[string]uint64)
 	s.tuByDocID = make(map[uint64][]uint64)
+	s.textUnitKeywords = textsearch.NewIndex()
 	for _, tu := range snapshot.TextUnits {
 		s.textUnits[tu.ID] = tu
 		s.tuByExtID[tu.ExternalID] = tu.ID
 		s.tuByDocID[tu.DocumentID] = append(s.tuByDocID[tu.DocumentID], tu.ID)
+		s.textUnitKeywords.Add(tu.ID, tu.Content)
 	}
 
 	// Clear and restore entities
 	s.entities = make(map[uint64]*types.Entity)
 	s.entByExtID = make(map[string]uint64)
 	s.entByTitle = make(map[string]uint64)
+	s.entByType = make(map[string]int)
 	for _, ent := range snapshot.Entities {
 		s.entities[ent.ID] = ent
 		s.entByTitle[ent.Title] = ent.ID
+		s.entByType[ent.Type]++
 		if ent.ExternalID != "" {
 			s.entByExtID[ent.ExternalID] = ent.ID
 		}
@@ -1058,8 +2276,10 @@ func (s *SessionStore) RestoreFromSnapshot(snapshot *SessionSnapshot) error {
 	s.relBySourceTarget = make(map[string]uint64)
 	s.outEdges = make(map[uint64][]uint64)
 	s.inEdges = make(map[uint64][]uint64)
+	s.relByType = make(map[string]int)
 	for _, rel := range snapshot.Relationships {
 		s.relationships[rel.ID] = rel
+		s.relByType[rel.Type]++
 		key := s.makeRelKey(rel.SourceID, rel.TargetID)
 		s.relBySourceTarget[key] = rel.ID
 		if rel.ExternalID != "" {
@@ -1073,13 +2293,18 @@ func (s *SessionStore) RestoreFromSnapshot(snapshot *SessionSnapshot) error {
 	s.communities = make(map[uint64]*types.Community)
 	s.commByExtID = make(map[string]uint64)
 	s.commByLevel = make(map[int][]uint64)
+	s.commByEntity = make(map[uint64][]uint64)
 	for _, comm := range snapshot.Communities {
 		s.communities[comm.ID] = comm
 		if comm.ExternalID != "" {
 			s.commByExtID[comm.ExternalID] = comm.ID
 		}
 		s.commByLevel[comm.Level] = append(s.commByLevel[comm.Level], comm.ID)
+		s.indexCommunityMembersLocked(comm)
 	}
+	// Restored communities are taken as current for the restored edges
+	s.communityVersion = s.edgeVersion
+	s.contentBytes = s.countContentBytesLocked()
 
 	// Restore ID generator
 	if snapshot.IDGeneratorState != nil {
@@ -1090,6 +2315,7 @@ func (s *SessionStore) RestoreFromSnapshot(snapshot *SessionSnapshot) error {
 	s.textUnitIndex = nil
 	s.entityIndex = nil
 	s.communityIndex = nil
+	s.entityTitleIndex = nil
 
 	if len(snapshot.TextUnitVectors) > 0 {
 		idx := s.getTextUnitIndex()
@@ -1115,6 +2341,430 @@ func (s *SessionStore) RestoreFromSnapshot(snapshot *SessionSnapshot) error {
 			}
 		}
 	}
+	if len(snapshot.EntityTitleVectors) > 0 {
+		idx := s.getEntityTitleIndex()
+		for id, vec := range snapshot.EntityTitleVectors {
+			if err := idx.Add(id, vec); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Import adds the records of a snapshot, typically another session's, to
+// this session. They get new IDs after the session's own, and every
+// reference between them is rewired to match. onConflict decides what
+// happens to records whose external ID, entity title or relationship
+// endpoints are already taken here; references to records left out are
+// dropped.
+func (s *SessionStore) Import(snapshot *SessionSnapshot, onConflict types.ConflictPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dataVersion++
+
+	if onConflict == types.ConflictReject {
+		if err := s.checkImportConflictsLocked(snapshot); err != nil {
+			return err
+		}
+	}
+	wasEmpty := len(s.entities) == 0 && len(s.relationships) == 0 && len(s.communities) == 0
+
+	// Assign new IDs in the snapshot's ID order
+	ids := idMapping{
+		docs:          make(map[uint64]uint64, len(snapshot.Documents)),
+		textUnits:     make(map[uint64]uint64, len(snapshot.TextUnits)),
+		entities:      make(map[uint64]uint64, len(snapshot.Entities)),
+		relationships: make(map[uint64]uint64, len(snapshot.Relationships)),
+		communities:   make(map[uint64]uint64, len(snapshot.Communities)),
+	}
+	for _, doc := range sortedByID(snapshot.Documents, func(d *types.Document) uint64 { return d.ID }) {
+		if _, taken := s.docByExtID[doc.ExternalID]; taken {
+			continue
+		}
+		ids.docs[doc.ID] = s.idGen.NextDocumentID()
+	}
+	for _, tu := range sortedByID(snapshot.TextUnits, func(t *types.TextUnit) uint64 { return t.ID }) {
+		if _, taken := s.tuByExtID[tu.ExternalID]; taken {
+			continue
+		}
+		ids.textUnits[tu.ID] = s.idGen.NextTextUnitID()
+	}
+	for _, ent := range sortedByID(snapshot.Entities, func(e *types.Entity) uint64 { return e.ID }) {
+		if existing, taken := s.entByTitle[ent.Title]; taken {
+			if onConflict == types.ConflictDedupTitle {
+				ids.entities[ent.ID] = existing
+			}
+			continue
+		}
+		if _, taken := s.entByExtID[ent.ExternalID]; taken && ent.ExternalID != "" {
+			continue
+		}
+		ids.entities[ent.ID] = s.idGen.NextEntityID()
+	}
+	pairs := make(map[string]bool, len(snapshot.Relationships))
+	for _, rel := range sortedByID(snapshot.Relationships, func(r *types.Relationship) uint64 { return r.ID }) {
+		sourceID, sourceOK := ids.entities[rel.SourceID]
+		targetID, targetOK := ids.entities[rel.TargetID]
+		if !sourceOK || !targetOK {
+			continue
+		}
+		key := s.makeRelKey(sourceID, targetID)
+		if _, taken := s.relBySourceTarget[key]; taken || pairs[key] {
+			continue
+		}
+		if _, taken := s.relByExtID[rel.ExternalID]; taken && rel.ExternalID != "" {
+			continue
+		}
+		pairs[key] = true
+		ids.relationships[rel.ID] = s.idGen.NextRelationshipID()
+	}
+	for _, comm := range sortedByID(snapshot.Communities, func(c *types.Community) uint64 { return c.ID }) {
+		if _, taken := s.commByExtID[comm.ExternalID]; taken && comm.ExternalID != "" {
+			continue
+		}
+		ids.communities[comm.ID] = s.idGen.NextCommunityID()
+	}
+
+	if err := s.addRecordsLocked(snapshot, ids); err != nil {
+		return err
+	}
+	// Imported communities only describe the imported edges, so they stay
+	// current only when nothing else was here
+	if wasEmpty {
+		s.communityVersion = s.edgeVersion
+	}
+
+	s.session.Touch()
+	return nil
+}
+
+// Clone returns an independent copy of the session under sessionID, with
+// the same records, IDs, vectors and settings. Records, indices and
+// community membership are copied, so changes to either session do not
+// show in the other.
+func (s *SessionStore) Clone(sessionID string) (*SessionStore, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := s.recordsLocked()
+
+	// IDs map to themselves
+	ids := idMapping{
+		docs:          identityIDs(s.documents),
+		textUnits:     identityIDs(s.textUnits),
+		entities:      identityIDs(s.entities),
+		relationships: identityIDs(s.relationships),
+		communities:   identityIDs(s.communities),
+	}
+
+	clone := NewSessionStore(sessionID, s.vectorDim)
+	clone.session = s.session.Clone(sessionID)
+	clone.indexConfig = s.indexConfig
+	clone.idGen.SetCounters(s.idGen.GetCounters())
+
+	clone.mu.Lock()
+	defer clone.mu.Unlock()
+	if err := clone.addRecordsLocked(snapshot, ids); err != nil {
+		return nil, err
+	}
+	if s.communityVersion == s.edgeVersion {
+		clone.communityVersion = clone.edgeVersion
+	}
+	return clone, nil
+}
+
+// CopySnapshot returns the session's records, vectors and settings as copies
+// taken under the session lock, so the result can be used while the session
+// keeps changing. Unlike Snapshot it leaves out the ID generator state.
+func (s *SessionStore) CopySnapshot() *SessionSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.copySnapshotLocked()
+}
+
+// Checkpoint is like CopySnapshot but also keeps the ID generator state, so
+// RestoreFromSnapshot can roll the session back to exactly this point
+func (s *SessionStore) Checkpoint() *SessionSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := s.copySnapshotLocked()
+	snapshot.IDGeneratorState = s.idGeneratorState()
+	return snapshot
+}
+
+// idGeneratorState returns the ID counters in the form snapshots store them
+func (s *SessionStore) idGeneratorState() map[string]uint64 {
+	doc, tu, ent, rel, comm, _ := s.idGen.GetCounters()
+	return map[string]uint64{
+		"document":     doc,
+		"textunit":     tu,
+		"entity":       ent,
+		"relationship": rel,
+		"community":    comm,
+	}
+}
+
+// copySnapshotLocked implements CopySnapshot. Caller must hold s.mu.
+func (s *SessionStore) copySnapshotLocked() *SessionSnapshot {
+	snapshot := s.recordsLocked()
+	snapshot.SessionID = s.session.ID
+	snapshot.Session = s.session.Clone(s.session.ID)
+	for i, doc := range snapshot.Documents {
+		c := *doc
+		c.Attrs = maps.Clone(doc.Attrs)
+		snapshot.Documents[i] = &c
+	}
+	for i, tu := range snapshot.TextUnits {
+		c := *tu
+		c.EntityIDs = slices.Clone(tu.EntityIDs)
+		snapshot.TextUnits[i] = &c
+	}
+	for i, ent := range snapshot.Entities {
+		c := *ent
+		c.Metadata = maps.Clone(ent.Metadata)
+		c.TextUnitIDs = slices.Clone(ent.TextUnitIDs)
+		snapshot.Entities[i] = &c
+	}
+	for i, rel := range snapshot.Relationships {
+		c := *rel
+		c.TextUnitIDs = slices.Clone(rel.TextUnitIDs)
+		snapshot.Relationships[i] = &c
+	}
+	for i, comm := range snapshot.Communities {
+		c := *comm
+		c.EntityIDs = slices.Clone(comm.EntityIDs)
+		c.RelationshipIDs = slices.Clone(comm.RelationshipIDs)
+		snapshot.Communities[i] = &c
+	}
+	return snapshot
+}
+
+// recordsLocked returns the session's records, shared with it, and copies of
+// its vectors. Caller must hold s.mu.
+func (s *SessionStore) recordsLocked() *SessionSnapshot {
+	snapshot := &SessionSnapshot{
+		Documents:     slices.Collect(maps.Values(s.documents)),
+		TextUnits:     slices.Collect(maps.Values(s.textUnits)),
+		Entities:      slices.Collect(maps.Values(s.entities)),
+		Relationships: slices.Collect(maps.Values(s.relationships)),
+		Communities:   slices.Collect(maps.Values(s.communities)),
+	}
+	if s.textUnitIndex != nil {
+		snapshot.TextUnitVectors = s.textUnitIndex.GetAllVectors()
+	}
+	if s.entityIndex != nil {
+		snapshot.EntityVectors = s.entityIndex.GetAllVectors()
+	}
+	if s.communityIndex != nil {
+		snapshot.CommunityVectors = s.communityIndex.GetAllVectors()
+	}
+	if s.entityTitleIndex != nil {
+		snapshot.EntityTitleVectors = s.entityTitleIndex.GetAllVectors()
+	}
+	return snapshot
+}
+
+// idMapping maps a snapshot's record IDs, per record type, to the IDs they
+// get in the session the records are added to
+type idMapping struct {
+	docs          map[uint64]uint64
+	textUnits     map[uint64]uint64
+	entities      map[uint64]uint64
+	relationships map[uint64]uint64
+	communities   map[uint64]uint64
+}
+
+// addRecordsLocked adds copies of the snapshot's records and vectors that
+// have an ID in ids, rewiring the references between them; references to
+// records not in ids are dropped. An entity mapped to an entity already here
+// is folded into it: only its text unit links are added. Caller must hold
+// s.mu.
+func (s *SessionStore) addRecordsLocked(snapshot *SessionSnapshot, ids idMapping) error {
+	for _, src := range snapshot.Documents {
+		id, ok := ids.docs[src.ID]
+		if !ok {
+			continue
+		}
+		doc := *src
+		doc.ID = id
+		doc.Attrs = maps.Clone(src.Attrs)
+		s.documents[doc.ID] = &doc
+		s.docByExtID[doc.ExternalID] = doc.ID
+		if doc.Filename != "" {
+			s.docByFilename[doc.Filename] = doc.ID
+		}
+	}
+
+	for _, src := range snapshot.TextUnits {
+		id, ok := ids.textUnits[src.ID]
+		if !ok {
+			continue
+		}
+		tu := *src
+		tu.ID = id
+		tu.DocumentID = ids.docs[src.DocumentID]
+		tu.EntityIDs = remapIDs(src.EntityIDs, ids.entities)
+		tu.Embedding = nil
+		s.textUnits[tu.ID] = &tu
+		s.tuByExtID[tu.ExternalID] = tu.ID
+		s.tuByDocID[tu.DocumentID] = append(s.tuByDocID[tu.DocumentID], tu.ID)
+		s.textUnitKeywords.Add(tu.ID, tu.Content)
+	}
+
+	// Folded entities keep this session's vectors
+	addedEntities := make(map[uint64]uint64, len(ids.entities))
+	for _, src := range snapshot.Entities {
+		id, ok := ids.entities[src.ID]
+		if !ok {
+			continue
+		}
+		if existing, ok := s.entities[id]; ok {
+			for _, tuID := range remapIDs(src.TextUnitIDs, ids.textUnits) {
+				existing.AddTextUnitID(tuID)
+			}
+			continue
+		}
+		addedEntities[src.ID] = id
+
+		ent := *src
+		ent.ID = id
+		ent.Metadata = maps.Clone(src.Metadata)
+		ent.TextUnitIDs = remapIDs(src.TextUnitIDs, ids.textUnits)
+		ent.Embedding = nil
+		s.entities[ent.ID] = &ent
+		s.entByTitle[ent.Title] = ent.ID
+		s.entByType[ent.Type]++
+		if ent.ExternalID != "" {
+			s.entByExtID[ent.ExternalID] = ent.ID
+		}
+	}
+
+	for _, src := range snapshot.Relationships {
+		id, ok := ids.relationships[src.ID]
+		if !ok {
+			continue
+		}
+		rel := *src
+		rel.ID = id
+		rel.SourceID = ids.entities[src.SourceID]
+		rel.TargetID = ids.entities[src.TargetID]
+		rel.TextUnitIDs = remapIDs(src.TextUnitIDs, ids.textUnits)
+		s.relationships[rel.ID] = &rel
+		if rel.ExternalID != "" {
+			s.relByExtID[rel.ExternalID] = rel.ID
+		}
+		s.linkRelationshipLocked(&rel)
+	}
+
+	for _, src := range snapshot.Communities {
+		id, ok := ids.communities[src.ID]
+		if !ok {
+			continue
+		}
+		comm := *src
+		comm.ID = id
+		comm.EntityIDs = remapIDs(src.EntityIDs, ids.entities)
+		comm.RelationshipIDs = remapIDs(src.RelationshipIDs, ids.relationships)
+		s.communities[comm.ID] = &comm
+		if comm.ExternalID != "" {
+			s.commByExtID[comm.ExternalID] = comm.ID
+		}
+		s.commByLevel[comm.Level] = append(s.commByLevel[comm.Level], comm.ID)
+		s.indexCommunityMembersLocked(&comm)
+	}
+	s.contentBytes = s.countContentBytesLocked()
+
+	if err := addRemappedVectors(s.getTextUnitIndex, snapshot.TextUnitVectors, ids.textUnits); err != nil {
+		return err
+	}
+	if err := addRemappedVectors(s.getEntityIndex, snapshot.EntityVectors, addedEntities); err != nil {
+		return err
+	}
+	if err := addRemappedVectors(s.getCommunityIndex, snapshot.CommunityVectors, ids.communities); err != nil {
+		return err
+	}
+	return addRemappedVectors(s.getEntityTitleIndex, snapshot.EntityTitleVectors, addedEntities)
+}
+
+// checkImportConflictsLocked reports the first external ID or entity title
+// of snapshot already taken in s. Caller must hold s.mu.
+func (s *SessionStore) checkImportConflictsLocked(snapshot *SessionSnapshot) error {
+	for _, doc := range snapshot.Documents {
+		if _, exists := s.docByExtID[doc.ExternalID]; exists {
+			return fmt.Errorf("document with external_id %s already exists", doc.ExternalID)
+		}
+	}
+	for _, tu := range snapshot.TextUnits {
+		if _, exists := s.tuByExtID[tu.ExternalID]; exists {
+			return fmt.Errorf("textunit with external_id %s already exists", tu.ExternalID)
+		}
+	}
+	for _, ent := range snapshot.Entities {
+		if _, exists := s.entByTitle[ent.Title]; exists {
+			return fmt.Errorf("entity with title %s already exists", ent.Title)
+		}
+		if _, exists := s.entByExtID[ent.ExternalID]; exists && ent.ExternalID != "" {
+			return fmt.Errorf("entity with external_id %s already exists", ent.ExternalID)
+		}
+	}
+	for _, rel := range snapshot.Relationships {
+		if _, exists := s.relByExtID[rel.ExternalID]; exists && rel.ExternalID != "" {
+			return fmt.Errorf("relationship with external_id %s already exists", rel.ExternalID)
+		}
+	}
+	for _, comm := range snapshot.Communities {
+		if _, exists := s.commByExtID[comm.ExternalID]; exists && comm.ExternalID != "" {
+			return fmt.Errorf("community with external_id %s already exists", comm.ExternalID)
+		}
+	}
+	return nil
+}
+
+// identityIDs maps each key of records to itself
+func identityIDs[T any](records map[uint64]T) map[uint64]uint64 {
+	ids := make(map[uint64]uint64, len(records))
+	for id := range records {
+		ids[id] = id
+	}
+	return ids
+}
+
+// sortedByID returns items ordered by the ID id extracts
+func sortedByID[T any](items []T, id func(T) uint64) []T {
+	sorted := slices.Clone(items)
+	slices.SortFunc(sorted, func(a, b T) int {
+		return cmp.Compare(id(a), id(b))
+	})
+	return sorted
+}
+
+// remapIDs maps each of ids through newIDs, dropping IDs not in it
+func remapIDs(ids []uint64, newIDs map[uint64]uint64) []uint64 {
+	if ids == nil {
+		return nil
+	}
+	result := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if newID, ok := newIDs[id]; ok {
+			result = append(result, newID)
+		}
+	}
+	return result
+}
 
+// addRemappedVectors adds vectors under their new IDs to the index from
+// getIndex, which is only called once there is a vector to add
+func addRemappedVectors(getIndex func() vector.Index, vectors map[uint64][]float32, newIDs map[uint64]uint64) error {
+	for id, vec := range vectors {
+		newID, ok := newIDs[id]
+		if !ok {
+			continue
+		}
+		if err := getIndex().Add(newID, slices.Clone(vec)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -2,9 +2,15 @@
 package store
 
 import (
+	"errors"
 	"fmt"
+	"math/rand"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/gibram-io/gibram/pkg/types"
+	"github.com/gibram-io/gibram/pkg/vector"
 )
 
 const testVectorDim = 64
@@ -305,6 +311,39 @@ func TestDeleteTextUnit(t *testing.T) {
 	}
 }
 
+func TestSearchTextUnitsByKeyword(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	doc := mustAddDocument(t, store, "doc-001", "test.pdf")
+	fast := mustAddTextUnit(t, store, "tu-001", doc.ID, "Transfers settle instantly over BI-FAST", nil, 6)
+	qris := mustAddTextUnit(t, store, "tu-002", doc.ID, "Merchants accept QRIS payments", nil, 4)
+
+	results := store.SearchTextUnitsByKeyword("bi-fast", 10)
+	if len(results) != 1 || results[0].ID != fast.ID {
+		t.Fatalf("Expected BI-FAST unit, got %v", results)
+	}
+
+	// Survives a snapshot round trip
+	restored := NewSessionStore("test-session", testVectorDim)
+	if err := restored.RestoreFromSnapshot(store.Snapshot()); err != nil {
+		t.Fatalf("RestoreFromSnapshot failed: %v", err)
+	}
+	if results := restored.SearchTextUnitsByKeyword("QRIS", 10); len(results) != 1 || results[0].ID != qris.ID {
+		t.Errorf("Expected QRIS unit after restore, got %v", results)
+	}
+
+	// Deleted units drop out of the index
+	store.DeleteTextUnit(fast.ID)
+	if results := store.SearchTextUnitsByKeyword("bi-fast", 10); len(results) != 0 {
+		t.Errorf("Deleted unit still matched: %v", results)
+	}
+
+	store.Clear()
+	if results := store.SearchTextUnitsByKeyword("qris", 10); len(results) != 0 {
+		t.Errorf("Cleared store still matched: %v", results)
+	}
+}
+
 // =============================================================================
 // Entity Operations Tests
 // =============================================================================
@@ -428,6 +467,46 @@ func TestUpdateEntityDescription(t *testing.T) {
 	}
 }
 
+func TestUpdateEntityMetadata_CopyOnWrite(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	keep, err := store.AddEntityWithMetadata("ent-bri", "BRI", "organization", "State bank", nil, nil, map[string]string{"ticker": "BBRI"})
+	if err != nil {
+		t.Fatalf("AddEntityWithMetadata failed: %v", err)
+	}
+	dup, err := store.AddEntityWithMetadata("ent-bank-rakyat", "Bank Rakyat", "organization", "", nil, nil, map[string]string{"founded": "1895"})
+	if err != nil {
+		t.Fatalf("AddEntityWithMetadata failed: %v", err)
+	}
+
+	// Readers encode the entity's map after the lock is released, so a map
+	// handed out earlier must never change
+	ent, _ := store.GetEntity(keep.ID)
+	handedOut := ent.Metadata
+	if !store.UpdateEntityMetadata(keep.ID, map[string]string{"sector": "banking", "ticker": ""}) {
+		t.Fatal("UpdateEntityMetadata failed")
+	}
+	if !reflect.DeepEqual(handedOut, map[string]string{"ticker": "BBRI"}) {
+		t.Errorf("Update changed a handed-out map: %v", handedOut)
+	}
+	ent, _ = store.GetEntity(keep.ID)
+	if !reflect.DeepEqual(ent.Metadata, map[string]string{"sector": "banking"}) {
+		t.Errorf("Unexpected metadata after update %v", ent.Metadata)
+	}
+
+	handedOut = ent.Metadata
+	if err := store.MergeEntities(keep.ID, dup.ID); err != nil {
+		t.Fatalf("MergeEntities failed: %v", err)
+	}
+	if !reflect.DeepEqual(handedOut, map[string]string{"sector": "banking"}) {
+		t.Errorf("Merge changed a handed-out map: %v", handedOut)
+	}
+	ent, _ = store.GetEntity(keep.ID)
+	if !reflect.DeepEqual(ent.Metadata, map[string]string{"sector": "banking", "founded": "1895"}) {
+		t.Errorf("Unexpected metadata after merge %v", ent.Metadata)
+	}
+}
+
 func TestDeleteEntity(t *testing.T) {
 	store := NewSessionStore("test-session", testVectorDim)
 
@@ -451,6 +530,130 @@ func TestDeleteEntity(t *testing.T) {
 	}
 }
 
+func TestMergeEntities(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	doc := mustAddDocument(t, store, "doc-001", "doc.txt")
+	tu1 := mustAddTextUnit(t, store, "tu-001", doc.ID, "BRI reports", nil, 10)
+	tu2 := mustAddTextUnit(t, store, "tu-002", doc.ID, "Bank Rakyat reports", nil, 10)
+
+	keep := mustAddEntity(t, store, "ent-bri", "BRI", "organization", "State bank", nil)
+	dup := mustAddEntity(t, store, "ent-bank-rakyat", "Bank Rakyat Indonesia", "organization", "Founded 1895", nil)
+	ojk := mustAddEntity(t, store, "ent-ojk", "OJK", "regulator", "Regulator", nil)
+	bi := mustAddEntity(t, store, "ent-bi", "BI", "regulator", "Central bank", nil)
+
+	store.LinkTextUnitToEntity(tu1.ID, keep.ID)
+	store.LinkTextUnitToEntity(tu2.ID, dup.ID)
+
+	supervisesKeep := mustAddRelationship(t, store, "rel-1", ojk.ID, keep.ID, "SUPERVISES", "", 1.0)
+	supervisesDup := mustAddRelationship(t, store, "rel-2", ojk.ID, dup.ID, "SUPERVISES", "", 1.0) // duplicate after merge
+	reports := mustAddRelationship(t, store, "rel-3", dup.ID, bi.ID, "REPORTS_TO", "", 1.0)        // reassigned
+	alias := mustAddRelationship(t, store, "rel-4", dup.ID, keep.ID, "ALIAS_OF", "", 1.0)          // self-loop after merge
+	store.relationships[supervisesDup.ID].AddTextUnitID(tu2.ID)
+
+	if err := store.MergeEntities(keep.ID, dup.ID); err != nil {
+		t.Fatalf("MergeEntities failed: %v", err)
+	}
+
+	if _, ok := store.GetEntity(dup.ID); ok {
+		t.Error("Merged entity should be deleted")
+	}
+	if store.EntityCount() != 3 {
+		t.Errorf("Expected 3 entities, got %d", store.EntityCount())
+	}
+
+	merged, _ := store.GetEntity(keep.ID)
+	if merged.Description != "State bank\nFounded 1895" {
+		t.Errorf("Unexpected combined description %q", merged.Description)
+	}
+	if len(merged.TextUnitIDs) != 2 {
+		t.Errorf("Expected 2 linked text units, got %v", merged.TextUnitIDs)
+	}
+	if tu, _ := store.GetTextUnit(tu2.ID); len(tu.EntityIDs) != 1 || tu.EntityIDs[0] != keep.ID {
+		t.Errorf("Text unit should link to the kept entity, got %v", tu.EntityIDs)
+	}
+
+	// Duplicate and self-loop edges are dropped; provenance moves over
+	if store.RelationshipCount() != 2 {
+		t.Errorf("Expected 2 relationships, got %d", store.RelationshipCount())
+	}
+	for _, id := range []uint64{supervisesDup.ID, alias.ID} {
+		if _, ok := store.GetRelationship(id); ok {
+			t.Errorf("Relationship %d should be dropped", id)
+		}
+	}
+	if rel, _ := store.GetRelationship(supervisesKeep.ID); len(rel.TextUnitIDs) != 1 || rel.TextUnitIDs[0] != tu2.ID {
+		t.Errorf("Expected provenance of the dropped edge, got %v", rel.TextUnitIDs)
+	}
+	if rel, ok := store.GetRelationshipBySourceTarget(keep.ID, bi.ID); !ok || rel.ID != reports.ID {
+		t.Error("REPORTS_TO should be reassigned to the kept entity")
+	}
+	if out := store.GetOutgoingRelationships(keep.ID); len(out) != 1 || out[0].ID != reports.ID {
+		t.Errorf("Unexpected outgoing edges %v", out)
+	}
+	if in := store.GetIncomingRelationships(keep.ID); len(in) != 1 || in[0].ID != supervisesKeep.ID {
+		t.Errorf("Unexpected incoming edges %v", in)
+	}
+	if out := store.GetOutgoingRelationships(ojk.ID); len(out) != 1 {
+		t.Errorf("Expected 1 outgoing edge from OJK, got %d", len(out))
+	}
+
+	if err := store.MergeEntities(keep.ID, keep.ID); err == nil {
+		t.Error("Expected error merging an entity into itself")
+	}
+	if err := store.MergeEntities(keep.ID, 99999); err == nil {
+		t.Error("Expected error for non-existent entity")
+	}
+}
+
+func TestUpdateEntityTitle(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	bri := mustAddEntity(t, store, "ent-bri", "BRI", "organization", "State bank", nil)
+	bi := mustAddEntity(t, store, "ent-bi", "BI", "regulator", "Central bank", nil)
+
+	if err := store.UpdateEntityTitle(bri.ID, "  Bank Rakyat Indonesia "); err != nil {
+		t.Fatalf("UpdateEntityTitle failed: %v", err)
+	}
+	if _, ok := store.GetEntityByTitle("BRI"); ok {
+		t.Error("Old title should no longer resolve")
+	}
+	ent, ok := store.GetEntityByTitle("bank rakyat indonesia")
+	if !ok || ent.ID != bri.ID {
+		t.Fatalf("New title should resolve to entity %d, got %v", bri.ID, ent)
+	}
+	if ent.Title != "BANK RAKYAT INDONESIA" {
+		t.Errorf("Expected normalized title, got %q", ent.Title)
+	}
+
+	// The freed title can be reused
+	mustAddEntity(t, store, "ent-bri-2", "BRI", "organization", "", nil)
+
+	// Renaming onto another entity's title is rejected and changes nothing
+	err := store.UpdateEntityTitle(bri.ID, "bi")
+	if !errors.Is(err, types.ErrDuplicateTitle) {
+		t.Errorf("Expected ErrDuplicateTitle, got %v", err)
+	}
+	if ent, _ := store.GetEntityByTitle("BI"); ent.ID != bi.ID {
+		t.Errorf("BI should still resolve to entity %d, got %d", bi.ID, ent.ID)
+	}
+	if ent, _ := store.GetEntityByTitle("Bank Rakyat Indonesia"); ent.ID != bri.ID {
+		t.Error("Rejected rename should keep the current title")
+	}
+
+	// Renaming to the entity's own title is a no-op
+	if err := store.UpdateEntityTitle(bi.ID, "Bi"); err != nil {
+		t.Errorf("Renaming to the same title failed: %v", err)
+	}
+
+	if err := store.UpdateEntityTitle(bri.ID, "  "); err == nil {
+		t.Error("Expected error for empty title")
+	}
+	if err := store.UpdateEntityTitle(99999, "Nobody"); err == nil {
+		t.Error("Expected error for non-existent entity")
+	}
+}
+
 func TestListEntitiesPagination(t *testing.T) {
 	store := NewSessionStore("test-session", testVectorDim)
 
@@ -545,6 +748,85 @@ func TestAddRelationshipDuplicate(t *testing.T) {
 	}
 }
 
+func TestEntityPopularity(t *testing.T) {
+	store := NewSessionStore("test", testVectorDim)
+	ent := mustAddEntity(t, store, "ent-001", "Entity", "person", "desc", nil)
+
+	halfLife := time.Hour
+	if got := store.EntityPopularity(ent.ID, halfLife); got != 0 {
+		t.Errorf("Expected 0 before any access, got %v", got)
+	}
+
+	store.RecordEntityAccess([]uint64{ent.ID, ent.ID, ent.ID}, halfLife)
+	if got := store.EntityPopularity(ent.ID, halfLife); got < 2.99 || got > 3 {
+		t.Errorf("Expected popularity ~3, got %v", got)
+	}
+
+	// Two half-lives quarter the count
+	c := &accessCounter{value: 4}
+	if got := c.decayed(int64(2*halfLife), halfLife); got != 1 {
+		t.Errorf("Expected decayed value 1, got %v", got)
+	}
+
+	store.DeleteEntity(ent.ID)
+	if got := store.EntityPopularity(ent.ID, halfLife); got != 0 {
+		t.Errorf("Expected counter removed with entity, got %v", got)
+	}
+}
+
+func TestAddRelationshipWithOptions(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	embedding := make([]float32, testVectorDim)
+	e1 := mustAddEntity(t, store, "ent-001", "Entity 1", "person", "Desc", embedding)
+	e2 := mustAddEntity(t, store, "ent-002", "Entity 2", "org", "Desc", embedding)
+
+	// An invalid window is rejected before anything is added
+	opts := types.RelationshipOptions{ValidFrom: 200, ValidUntil: 100, Directed: true}
+	if _, err := store.AddRelationshipWithOptions("rel-001", e1.ID, e2.ID, "CEO_OF", "Desc", 1.0, opts); err != types.ErrInvalidValidityWindow {
+		t.Errorf("Expected ErrInvalidValidityWindow, got %v", err)
+	}
+	if store.RelationshipCount() != 0 {
+		t.Fatalf("Rejected relationship was added")
+	}
+
+	opts.ValidFrom, opts.ValidUntil = 100, 200
+	rel, err := store.AddRelationshipWithOptions("rel-001", e1.ID, e2.ID, "CEO_OF", "Desc", 1.0, opts)
+	if err != nil {
+		t.Fatalf("AddRelationshipWithOptions failed: %v", err)
+	}
+	if rel.ValidFrom != 100 || rel.ValidUntil != 200 || !rel.Directed {
+		t.Errorf("Expected a directed relationship valid in [100, 200), got %+v", rel)
+	}
+}
+
+func TestSetRelationshipValidity(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	embedding := make([]float32, testVectorDim)
+	e1 := mustAddEntity(t, store, "ent-001", "Entity 1", "person", "Desc", embedding)
+	e2 := mustAddEntity(t, store, "ent-002", "Entity 2", "org", "Desc", embedding)
+	rel := mustAddRelationship(t, store, "rel-001", e1.ID, e2.ID, "CEO_OF", "Desc", 1.0)
+
+	if err := store.SetRelationshipValidity(rel.ID, 200, 100); err == nil {
+		t.Error("Expected error for inverted validity window")
+	}
+	if err := store.SetRelationshipValidity(9999, 100, 200); err == nil {
+		t.Error("Expected error for nonexistent relationship")
+	}
+	if err := store.SetRelationshipValidity(rel.ID, 100, 200); err != nil {
+		t.Fatalf("SetRelationshipValidity failed: %v", err)
+	}
+
+	got, _ := store.GetRelationship(rel.ID)
+	if got.ValidFrom != 100 || got.ValidUntil != 200 {
+		t.Errorf("Expected window [100, 200), got [%d, %d)", got.ValidFrom, got.ValidUntil)
+	}
+	if got.IsValidAt(99) || !got.IsValidAt(100) || got.IsValidAt(200) {
+		t.Error("IsValidAt does not honor the half-open window")
+	}
+}
+
 func TestGetRelationship(t *testing.T) {
 	store := NewSessionStore("test-session", testVectorDim)
 
@@ -670,6 +952,75 @@ func TestGetIncomingEdges(t *testing.T) {
 	}
 }
 
+func TestTypeCounts_SnapshotAndClear(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	e1 := mustAddEntity(t, store, "ent-001", "Entity 1", "person", "Desc", nil)
+	e2 := mustAddEntity(t, store, "ent-002", "Entity 2", "organization", "Desc", nil)
+	mustAddRelationship(t, store, "rel-001", e1.ID, e2.ID, "WORKS_AT", "Desc", 1.0)
+
+	restored := NewSessionStore("test-session", testVectorDim)
+	if err := restored.RestoreFromSnapshot(store.Snapshot()); err != nil {
+		t.Fatalf("RestoreFromSnapshot failed: %v", err)
+	}
+	if counts := restored.EntityTypeCounts(); counts["person"] != 1 || counts["organization"] != 1 {
+		t.Errorf("Restored entity type counts = %v", counts)
+	}
+	if counts := restored.RelationshipTypeCounts(); counts["WORKS_AT"] != 1 {
+		t.Errorf("Restored relationship type counts = %v", counts)
+	}
+
+	store.Clear()
+	if len(store.EntityTypeCounts()) != 0 || len(store.RelationshipTypeCounts()) != 0 {
+		t.Error("Cleared store still has type counts")
+	}
+}
+
+func TestTimestamps_UpdateAndSnapshot(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	e1 := mustAddEntity(t, store, "ent-001", "Entity 1", "person", "Desc", nil)
+	e2 := mustAddEntity(t, store, "ent-002", "Entity 2", "organization", "Desc", nil)
+	rel := mustAddRelationship(t, store, "rel-001", e1.ID, e2.ID, "WORKS_AT", "Desc", 1.0)
+	if e1.CreatedAt == 0 || e1.UpdatedAt != e1.CreatedAt || rel.UpdatedAt != rel.CreatedAt {
+		t.Fatalf("New records should start with UpdatedAt = CreatedAt, got entity %d/%d relationship %d/%d",
+			e1.CreatedAt, e1.UpdatedAt, rel.CreatedAt, rel.UpdatedAt)
+	}
+
+	// Backdate so updates are visible at second resolution
+	const past = 1_600_000_000
+	e1.CreatedAt, e1.UpdatedAt = past, past
+	e2.CreatedAt, e2.UpdatedAt = past, past
+	rel.CreatedAt, rel.UpdatedAt = past, past
+
+	store.UpdateEntityDescription(e1.ID, "New desc", nil)
+	if err := store.UpdateRelationshipWeight(rel.ID, 2.0); err != nil {
+		t.Fatalf("UpdateRelationshipWeight failed: %v", err)
+	}
+	if e1.UpdatedAt <= past || e1.CreatedAt != past {
+		t.Errorf("Entity update should only move UpdatedAt, got created=%d updated=%d", e1.CreatedAt, e1.UpdatedAt)
+	}
+	if rel.UpdatedAt <= past || rel.CreatedAt != past {
+		t.Errorf("Relationship update should only move UpdatedAt, got created=%d updated=%d", rel.CreatedAt, rel.UpdatedAt)
+	}
+
+	restored := NewSessionStore("test-session", testVectorDim)
+	if err := restored.RestoreFromSnapshot(store.Snapshot()); err != nil {
+		t.Fatalf("RestoreFromSnapshot failed: %v", err)
+	}
+	for _, id := range []uint64{e1.ID, e2.ID} {
+		orig, _ := store.GetEntity(id)
+		got, ok := restored.GetEntity(id)
+		if !ok || got.CreatedAt != orig.CreatedAt || got.UpdatedAt != orig.UpdatedAt {
+			t.Errorf("Entity %d timestamps not restored: got %+v, want created=%d updated=%d", id, got, orig.CreatedAt, orig.UpdatedAt)
+		}
+	}
+	gotRel, ok := restored.GetRelationship(rel.ID)
+	if !ok || gotRel.CreatedAt != rel.CreatedAt || gotRel.UpdatedAt != rel.UpdatedAt {
+		t.Errorf("Relationship timestamps not restored: got %+v", gotRel)
+	}
+}
+
 // =============================================================================
 // Community Operations Tests
 // =============================================================================
@@ -785,6 +1136,161 @@ func TestGetEntityIndex(t *testing.T) {
 	}
 }
 
+func TestEntityTitleIndex(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+
+	mustAddEntity(t, store, "ent-1", "Plain", "test", "Desc", make([]float32, testVectorDim))
+	if store.GetEntityTitleIndex() != nil {
+		t.Fatal("Title index should not exist before a title embedding is stored")
+	}
+
+	title := make([]float32, testVectorDim)
+	title[0] = 1
+	ent, err := store.AddEntityWithTitleEmbedding("ent-2", "Titled", "test", "Desc", nil, title)
+	if err != nil {
+		t.Fatalf("AddEntityWithTitleEmbedding failed: %v", err)
+	}
+	if idx := store.GetEntityTitleIndex(); idx == nil || idx.Count() != 1 {
+		t.Fatal("Expected title index with one vector")
+	}
+
+	// Title vectors survive a snapshot round-trip
+	restored := NewSessionStore("test-session", testVectorDim)
+	if err := restored.RestoreFromSnapshot(store.Snapshot()); err != nil {
+		t.Fatalf("RestoreFromSnapshot failed: %v", err)
+	}
+	if idx := restored.GetEntityTitleIndex(); idx == nil || idx.Count() != 1 {
+		t.Error("Expected title vector after restore")
+	}
+
+	// A bad title embedding rolls back the whole entity
+	if _, err := store.AddEntityWithTitleEmbedding("ent-3", "Bad", "test", "Desc", title, []float32{1}); err == nil {
+		t.Error("Expected dimension error for title embedding")
+	}
+	if _, ok := store.GetEntityByTitle("Bad"); ok {
+		t.Error("Entity should not exist after failed add")
+	}
+	if store.GetEntityIndex().Count() != 1 {
+		t.Errorf("Expected 1 description vector after rollback, got %d", store.GetEntityIndex().Count())
+	}
+
+	store.DeleteEntity(ent.ID)
+	if store.GetEntityTitleIndex().Count() != 0 {
+		t.Error("DeleteEntity should remove the title vector")
+	}
+}
+
+func TestRebuildIndicesOnline(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+	rng := rand.New(rand.NewSource(1))
+	randomVector := func() []float32 {
+		vec := make([]float32, testVectorDim)
+		for i := range vec {
+			vec[i] = rng.Float32()*2 - 1
+		}
+		return vec
+	}
+	topID := func(idx vector.Index, query []float32) uint64 {
+		results := idx.Search(query, 1)
+		if len(results) == 0 {
+			return 0
+		}
+		return results[0].ID
+	}
+
+	vecs := make([][]float32, 600)
+	ents := make([]*types.Entity, len(vecs))
+	for i := range vecs {
+		vecs[i] = randomVector()
+		ents[i] = mustAddEntity(t, store, fmt.Sprintf("ent-%d", i), fmt.Sprintf("Entity %d", i), "test", "Desc", vecs[i])
+	}
+	oldIndex := store.GetEntityIndex()
+
+	var added *types.Entity
+	addedVec := randomVector()
+	reports := 0
+	err := store.RebuildIndicesOnline(func(done, total int) {
+		reports++
+		if total != len(vecs) {
+			t.Errorf("total = %d, want %d", total, len(vecs))
+		}
+		if reports > 1 {
+			return
+		}
+		// Mid-build, searches use the old index and writes still go through
+		if store.GetEntityIndex() != oldIndex {
+			t.Error("Index replaced before the rebuild finished")
+		}
+		if got := topID(oldIndex, vecs[0]); got != ents[0].ID {
+			t.Errorf("Search during rebuild = %d, want %d", got, ents[0].ID)
+		}
+		added = mustAddEntity(t, store, "ent-new", "New Entity", "test", "Desc", addedVec)
+		store.DeleteEntity(ents[1].ID)
+	})
+	if err != nil {
+		t.Fatalf("RebuildIndicesOnline failed: %v", err)
+	}
+	if reports < 2 {
+		t.Errorf("Expected progress reports, got %d", reports)
+	}
+
+	idx := store.GetEntityIndex()
+	if idx == oldIndex {
+		t.Fatal("Index was not replaced")
+	}
+	if idx.Count() != len(vecs) {
+		t.Errorf("Count = %d, want %d", idx.Count(), len(vecs))
+	}
+	if got := topID(idx, addedVec); got != added.ID {
+		t.Errorf("Entity added during rebuild: top hit %d, want %d", got, added.ID)
+	}
+	if got := topID(idx, vecs[1]); got == ents[1].ID {
+		t.Error("Entity deleted during rebuild is still indexed")
+	}
+	if store.VectorCount() != len(vecs) {
+		t.Errorf("VectorCount = %d, want %d", store.VectorCount(), len(vecs))
+	}
+}
+
+func TestSetDistanceMetric(t *testing.T) {
+	store := NewSessionStore("test-session", 2)
+
+	mustAddEntity(t, store, "ent-long", "Long", "test", "Desc", []float32{4, 3})
+	mustAddEntity(t, store, "ent-short", "Short", "test", "Desc", []float32{1, 0.1})
+	query := []float32{1, 0}
+
+	top := func(s *SessionStore) string {
+		t.Helper()
+		results := s.GetEntityIndex().Search(query, 1)
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		ent, _ := s.GetEntity(results[0].ID)
+		return ent.ExternalID
+	}
+
+	if store.GetDistanceMetric() != types.DistanceCosine || top(store) != "ent-short" {
+		t.Fatal("Expected cosine by default")
+	}
+
+	// Switching rebuilds the existing index
+	if err := store.SetDistanceMetric(types.DistanceDotProduct); err != nil {
+		t.Fatalf("SetDistanceMetric failed: %v", err)
+	}
+	if got := top(store); got != "ent-long" {
+		t.Errorf("Expected dot product to favor the longer vector, got %s", got)
+	}
+
+	// The metric is restored with the session
+	restored := NewSessionStore("test-session", 2)
+	if err := restored.RestoreFromSnapshot(store.Snapshot()); err != nil {
+		t.Fatalf("RestoreFromSnapshot failed: %v", err)
+	}
+	if restored.GetDistanceMetric() != types.DistanceDotProduct || top(restored) != "ent-long" {
+		t.Error("Expected dot product metric after restore")
+	}
+}
+
 func TestGetCommunityIndex(t *testing.T) {
 	store := NewSessionStore("test-session", testVectorDim)
 
@@ -800,6 +1306,57 @@ func TestGetCommunityIndex(t *testing.T) {
 	}
 }
 
+func TestMemoryBytes(t *testing.T) {
+	store := NewSessionStore("test-session", testVectorDim)
+	embedding := make([]float32, testVectorDim)
+	embedding[0] = 1
+
+	doc := mustAddDocument(t, store, "doc-1", "report.pdf")
+	tu := mustAddTextUnit(t, store, "tu-1", doc.ID, "Bank Indonesia held rates", embedding, 5)
+	ent1 := mustAddEntity(t, store, "ent-1", "Bank Indonesia", "organization", "Central bank", embedding)
+	ent2 := mustAddEntity(t, store, "ent-2", "Rupiah", "currency", "", nil)
+	rel, err := store.AddRelationship("rel-1", ent1.ID, ent2.ID, "ISSUES", "Issuer", 1)
+	if err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+
+	// Text of every record plus two float32 vectors
+	text := int64(len("doc-1report.pdf") + len("tu-1Bank Indonesia held rates") +
+		len("ent-1BANK INDONESIAorganizationCentral bank") + len("ent-2RUPIAHcurrency") + len("rel-1ISSUESIssuer"))
+	want := text + 2*testVectorDim*4
+	if got := store.MemoryBytes(); got != want {
+		t.Fatalf("MemoryBytes() = %d, want %d", got, want)
+	}
+	if info := store.GetInfo(); info.MemoryBytes != want {
+		t.Errorf("GetInfo().MemoryBytes = %d, want %d", info.MemoryBytes, want)
+	}
+
+	if !store.UpdateEntityDescription(ent2.ID, "Legal tender", nil) {
+		t.Fatal("UpdateEntityDescription failed")
+	}
+	want += int64(len("Legal tender"))
+	if got := store.MemoryBytes(); got != want {
+		t.Errorf("MemoryBytes() after update = %d, want %d", got, want)
+	}
+
+	restored := NewSessionStore("test-session", testVectorDim)
+	if err := restored.RestoreFromSnapshot(store.Snapshot()); err != nil {
+		t.Fatalf("RestoreFromSnapshot failed: %v", err)
+	}
+	if got := restored.MemoryBytes(); got != want {
+		t.Errorf("MemoryBytes() after restore = %d, want %d", got, want)
+	}
+
+	store.DeleteRelationship(rel.ID)
+	store.DeleteEntity(ent1.ID)
+	store.DeleteEntity(ent2.ID)
+	store.DeleteTextUnit(tu.ID)
+	store.DeleteDocument(doc.ID)
+	if got := store.MemoryBytes(); got != 0 {
+		t.Errorf("MemoryBytes() of an emptied session = %d, want 0", got)
+	}
+}
+
 // =============================================================================
 // ID Generator Tests
 // =============================================================================
@@ -0,0 +1,202 @@
+// Package textsearch provides a BM25 keyword index for GibRAM
+package textsearch
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// BM25 defaults
+const (
+	DefaultK1 = 1.2  // term frequency saturation
+	DefaultB  = 0.75 // document length normalization
+)
+
+// Result is a scored keyword match
+type Result struct {
+	ID    uint64
+	Score float64
+}
+
+// =============================================================================
+// BM25 Index
+// =============================================================================
+
+// Index is an inverted index scored with Okapi BM25. It is safe for
+// concurrent use.
+type Index struct {
+	mu sync.RWMutex
+
+	k1 float64
+	b  float64
+
+	postings map[string]map[uint64]int // term -> document -> term frequency
+	docLen   map[uint64]int            // document -> token count
+	docTerms map[uint64][]string       // document -> distinct terms, for removal
+	totalLen int
+}
+
+// NewIndex creates an empty index with the default BM25 parameters
+func NewIndex() *Index {
+	return NewIndexWithParams(DefaultK1, DefaultB)
+}
+
+// NewIndexWithParams creates an empty index with the given k1 and b
+func NewIndexWithParams(k1, b float64) *Index {
+	return &Index{
+		k1:       k1,
+		b:        b,
+		postings: make(map[string]map[uint64]int),
+		docLen:   make(map[uint64]int),
+		docTerms: make(map[uint64][]string),
+	}
+}
+
+// Add indexes text under id, replacing any earlier text for id
+func (idx *Index) Add(id uint64, text string) {
+	tokens := Tokenize(text)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(id)
+
+	tf := make(map[string]int)
+	for _, tok := range tokens {
+		tf[tok]++
+	}
+	terms := make([]string, 0, len(tf))
+	for term, n := range tf {
+		docs, ok := idx.postings[term]
+		if !ok {
+			docs = make(map[uint64]int)
+			idx.postings[term] = docs
+		}
+		docs[id] = n
+		terms = append(terms, term)
+	}
+
+	idx.docLen[id] = len(tokens)
+	idx.docTerms[id] = terms
+	idx.totalLen += len(tokens)
+}
+
+// Remove drops id from the index
+func (idx *Index) Remove(id uint64) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	return idx.removeLocked(id)
+}
+
+func (idx *Index) removeLocked(id uint64) bool {
+	length, ok := idx.docLen[id]
+	if !ok {
+		return false
+	}
+	for _, term := range idx.docTerms[id] {
+		docs := idx.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.docLen, id)
+	delete(idx.docTerms, id)
+	idx.totalLen -= length
+	return true
+}
+
+// Count returns the number of indexed documents
+func (idx *Index) Count() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docLen)
+}
+
+// Search returns the k best BM25 matches for query, highest score first.
+// Documents that share no term with the query are not returned.
+func (idx *Index) Search(query string, k int) []Result {
+	if k <= 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	n := len(idx.docLen)
+	if n == 0 {
+		return nil
+	}
+	avgLen := float64(idx.totalLen) / float64(n)
+	if avgLen == 0 {
+		avgLen = 1
+	}
+
+	scores := make(map[uint64]float64)
+	seen := make(map[string]bool)
+	for _, term := range Tokenize(query) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		docs := idx.postings[term]
+		if len(docs) == 0 {
+			continue
+		}
+		df := float64(len(docs))
+		idf := math.Log(1 + (float64(n)-df+0.5)/(df+0.5))
+		for id, tf := range docs {
+			f := float64(tf)
+			norm := idx.k1 * (1 - idx.b + idx.b*float64(idx.docLen[id])/avgLen)
+			scores[id] += idf * f * (idx.k1 + 1) / (f + norm)
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		results = append(results, Result{ID: id, Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// =============================================================================
+// Tokenization
+// =============================================================================
+
+// Tokenize lowercases text and splits it into runs of letters and digits.
+// Hyphens and underscores inside a run are kept, so "BI-FAST" yields the
+// exact term "bi-fast" followed by its parts "bi" and "fast".
+func Tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.Trim(field, "-_")
+		if field == "" {
+			continue
+		}
+		tokens = append(tokens, field)
+		if !strings.ContainsAny(field, "-_") {
+			continue
+		}
+		for _, part := range strings.FieldsFunc(field, func(r rune) bool { return r == '-' || r == '_' }) {
+			tokens = append(tokens, part)
+		}
+	}
+	return tokens
+}
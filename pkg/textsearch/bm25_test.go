@@ -0,0 +1,128 @@
+// Package textsearch - BM25 index tests
+package textsearch
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Bank Indonesia raises rates", []string{"bank", "indonesia", "raises", "rates"}},
+		{"BI-FAST, QRIS & SNAP.", []string{"bi-fast", "bi", "fast", "qris", "snap"}},
+		{"  --leading_trailing--  ", []string{"leading_trailing", "leading", "trailing"}},
+		{"Rp 5.000 (2024)", []string{"rp", "5", "000", "2024"}},
+		{"Überweisung für Käufer", []string{"überweisung", "für", "käufer"}},
+		{"", []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := Tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestIndex_TermFrequency(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(1, "qris payment via qris and more qris")
+	idx.Add(2, "qris payment accepted here today")
+	idx.Add(3, "card payment accepted here today")
+
+	results := idx.Search("qris", 10)
+	if len(results) != 2 {
+		t.Fatalf("Search() returned %d results, want 2: %v", len(results), results)
+	}
+	if results[0].ID != 1 {
+		t.Errorf("Document with more occurrences should rank first, got %v", results)
+	}
+	if results[0].Score <= results[1].Score {
+		t.Errorf("Scores should be strictly ordered, got %v", results)
+	}
+
+	// A term in every document scores below a rare one
+	common := idx.Search("payment", 10)
+	rare := idx.Search("qris", 10)
+	if common[0].Score >= rare[0].Score {
+		t.Errorf("Common term score %v should be below rare term score %v", common[0].Score, rare[0].Score)
+	}
+}
+
+func TestIndex_LengthNormalization(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(1, "ojk")
+	idx.Add(2, "ojk issued a long regulation covering many unrelated topics at once")
+	idx.Add(3, "nothing relevant")
+
+	results := idx.Search("OJK", 10)
+	if len(results) != 2 || results[0].ID != 1 {
+		t.Errorf("Shorter document should rank first, got %v", results)
+	}
+
+	// b = 0 disables length normalization
+	flat := NewIndexWithParams(DefaultK1, 0)
+	flat.Add(1, "ojk")
+	flat.Add(2, "ojk issued a long regulation covering many unrelated topics at once")
+	flat.Add(3, "nothing relevant")
+	results = flat.Search("ojk", 10)
+	if len(results) != 2 || results[0].Score != results[1].Score {
+		t.Errorf("Without length normalization scores should tie, got %v", results)
+	}
+}
+
+func TestIndex_AddRemove(t *testing.T) {
+	idx := NewIndex()
+	idx.Add(1, "bi-fast transfer")
+	idx.Add(2, "fast food")
+
+	// The exact compound term outranks a document with only one part
+	results := idx.Search("BI-FAST", 10)
+	if len(results) != 2 || results[0].ID != 1 {
+		t.Errorf("Exact term match should rank first, got %v", results)
+	}
+
+	if !idx.Remove(1) {
+		t.Error("Remove(1) = false, want true")
+	}
+	if idx.Remove(1) {
+		t.Error("Second Remove(1) = true, want false")
+	}
+	if idx.Count() != 1 {
+		t.Errorf("Count() = %d, want 1", idx.Count())
+	}
+	if results := idx.Search("transfer", 10); len(results) != 0 {
+		t.Errorf("Removed document still found: %v", results)
+	}
+
+	// Re-adding replaces the old text
+	idx.Add(2, "gopay wallet")
+	if results := idx.Search("food", 10); len(results) != 0 {
+		t.Errorf("Replaced text still found: %v", results)
+	}
+	if results := idx.Search("wallet", 10); len(results) != 1 || results[0].ID != 2 {
+		t.Errorf("Search(wallet) = %v, want document 2", results)
+	}
+}
+
+func TestIndex_SearchLimits(t *testing.T) {
+	idx := NewIndex()
+	if results := idx.Search("anything", 10); results != nil {
+		t.Errorf("Empty index returned %v", results)
+	}
+
+	for i := uint64(1); i <= 5; i++ {
+		idx.Add(i, "bank")
+	}
+	if results := idx.Search("bank", 3); len(results) != 3 {
+		t.Errorf("Search(k=3) returned %d results", len(results))
+	}
+	if results := idx.Search("bank", 0); results != nil {
+		t.Errorf("Search(k=0) returned %v", results)
+	}
+	if results := idx.Search("...", 10); len(results) != 0 {
+		t.Errorf("Query without terms returned %v", results)
+	}
+}
@@ -0,0 +1,100 @@
+// Package tracing provides OpenTelemetry tracing for GibRAM
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/gibram-io/gibram/pkg/config"
+	"github.com/gibram-io/gibram/pkg/version"
+	pb "github.com/gibram-io/gibram/proto/gibrampb"
+)
+
+// instrumentationName names the tracer all GibRAM spans come from
+const instrumentationName = "github.com/gibram-io/gibram"
+
+// traceparentHeader is the W3C Trace Context header carried in envelopes
+const traceparentHeader = "traceparent"
+
+var propagator = propagation.TraceContext{}
+
+// Tracer returns the GibRAM tracer of the global tracer provider. Until
+// Setup or the embedding program installs a provider, its spans are no-ops.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartCommand starts a span of the given kind for cmd, named like
+// "gibram.query". Client and server spans of a command share the name.
+func StartCommand(ctx context.Context, cmd pb.CommandType, kind trace.SpanKind) (context.Context, trace.Span) {
+	name := "gibram." + strings.ToLower(strings.TrimPrefix(cmd.String(), "CMD_"))
+	return Tracer().Start(ctx, name,
+		trace.WithSpanKind(kind),
+		trace.WithAttributes(attribute.String("gibram.command", cmd.String())),
+	)
+}
+
+// End marks span failed when err is non-nil and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject returns the W3C traceparent of the span in ctx, or "" when ctx
+// has no span
+func Inject(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	return carrier[traceparentHeader]
+}
+
+// Extract returns ctx with the remote span described by traceparent as its
+// parent. An empty or malformed traceparent leaves ctx unchanged.
+func Extract(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{traceparentHeader: traceparent})
+}
+
+// Setup installs a global tracer provider exporting spans over OTLP/HTTP to
+// cfg.Endpoint. With no endpoint it installs nothing and spans stay no-ops.
+// The returned function flushes pending spans and stops the exporter.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "gibram"),
+			attribute.String("service.version", version.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -2,6 +2,7 @@
 package types
 
 import (
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -144,6 +145,45 @@ func TestIdleTTL(t *testing.T) {
 	}
 }
 
+// TestSessionMetadata tests metadata merge, removal and size bounds
+func TestSessionMetadata(t *testing.T) {
+	session := NewSession("test-session")
+
+	if err := session.SetMetadata(map[string]string{"tenant": "acme", "owner": "ops"}, false); err != nil {
+		t.Fatalf("SetMetadata() error: %v", err)
+	}
+	if err := session.SetMetadata(map[string]string{"owner": "", "version": "v2"}, false); err != nil {
+		t.Fatalf("SetMetadata() error: %v", err)
+	}
+
+	md := session.GetMetadata()
+	if len(md) != 2 || md["tenant"] != "acme" || md["version"] != "v2" {
+		t.Errorf("Unexpected metadata after merge: %v", md)
+	}
+	if info := session.GetInfo(); info.Metadata["tenant"] != "acme" {
+		t.Errorf("GetInfo() should include metadata, got %v", info.Metadata)
+	}
+
+	if err := session.SetMetadata(map[string]string{"": "x"}, false); err != ErrSessionMetadataEmptyKey {
+		t.Errorf("Expected ErrSessionMetadataEmptyKey, got %v", err)
+	}
+
+	tooBig := map[string]string{"blob": strings.Repeat("x", MaxSessionMetadataBytes)}
+	if err := session.SetMetadata(tooBig, false); err != ErrSessionMetadataTooLarge {
+		t.Errorf("Expected ErrSessionMetadataTooLarge, got %v", err)
+	}
+	if len(session.GetMetadata()) != 2 {
+		t.Error("Rejected update should leave metadata unchanged")
+	}
+
+	if err := session.SetMetadata(map[string]string{"only": "this"}, true); err != nil {
+		t.Fatalf("SetMetadata(replace) error: %v", err)
+	}
+	if md := session.GetMetadata(); len(md) != 1 || md["only"] != "this" {
+		t.Errorf("Unexpected metadata after replace: %v", md)
+	}
+}
+
 // BenchmarkSessionQuotaCheck benchmarks quota checking performance
 func BenchmarkSessionQuotaCheck(b *testing.B) {
 	session := NewSession("bench-session")
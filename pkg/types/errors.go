@@ -147,5 +147,6 @@ var (
 	ErrDuplicateTitle      = NewError(ErrConflict, "Title already exists")
 	ErrVectorDimMismatch   = NewError(ErrInvalidVector, "Vector dimension mismatch")
 	ErrEmptyVector         = NewError(ErrInvalidVector, "Vector cannot be empty")
+	ErrSuspiciousEmbedding = NewError(ErrInvalidVector, "Embedding L2 norm out of range")
 	ErrServerShuttingDown  = NewError(ErrShuttingDown, "Server is shutting down")
 )
@@ -15,6 +15,18 @@ var (
 	ErrMemoryQuotaExceeded       = errors.New("memory quota exceeded")
 )
 
+// Session metadata limits
+const (
+	MaxSessionMetadataKeys  = 64        // max metadata entries per session
+	MaxSessionMetadataBytes = 16 * 1024 // max total size of metadata keys and values
+)
+
+// Session metadata errors
+var (
+	ErrSessionMetadataTooLarge = errors.New("session metadata too large")
+	ErrSessionMetadataEmptyKey = errors.New("session metadata key is empty")
+)
+
 // =============================================================================
 // Session - Represents an isolated data context
 // =============================================================================
@@ -43,6 +55,15 @@ type Session struct {
 	RelationshipCount int   `json:"relationship_count"`
 	DocumentCount     int   `json:"document_count"`
 	MemoryBytes       int64 `json:"memory_bytes"` // approximate memory usage
+
+	// Application-defined annotations (tenant, corpus version, owner, ...)
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Vector search metric for this session's indices (empty = cosine)
+	DistanceMetric DistanceMetric `json:"distance_metric,omitempty"`
+
+	// Vector storage precision for this session's indices (empty = server default)
+	VectorPrecision string `json:"vector_precision,omitempty"`
 }
 
 // NewSession creates a new session with the given ID
@@ -55,6 +76,29 @@ func NewSession(id string) *Session {
 	}
 }
 
+// Clone returns a new session with ID id and s's TTLs, quotas, usage,
+// metadata and index settings. Its creation and access times are now.
+func (s *Session) Clone(id string) *Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clone := NewSession(id)
+	clone.TTL = s.TTL
+	clone.IdleTTL = s.IdleTTL
+	clone.MaxEntities = s.MaxEntities
+	clone.MaxRelationships = s.MaxRelationships
+	clone.MaxDocuments = s.MaxDocuments
+	clone.MaxMemoryBytes = s.MaxMemoryBytes
+	clone.EntityCount = s.EntityCount
+	clone.RelationshipCount = s.RelationshipCount
+	clone.DocumentCount = s.DocumentCount
+	clone.MemoryBytes = s.MemoryBytes
+	clone.Metadata = s.copyMetadata()
+	clone.DistanceMetric = s.DistanceMetric
+	clone.VectorPrecision = s.VectorPrecision
+	return clone
+}
+
 // Touch updates the last access time
 func (s *Session) Touch() {
 	s.mu.Lock()
@@ -81,6 +125,38 @@ func (s *Session) SetIdleTTL(idleTTL int64) {
 	s.IdleTTL = idleTTL
 }
 
+// SetDistanceMetric sets the session's vector search metric
+func (s *Session) SetDistanceMetric(metric DistanceMetric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.DistanceMetric = metric
+}
+
+// GetDistanceMetric returns the session's vector search metric
+func (s *Session) GetDistanceMetric() DistanceMetric {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.DistanceMetric == "" {
+		return DistanceCosine
+	}
+	return s.DistanceMetric
+}
+
+// SetVectorPrecision sets the session's vector storage precision
+func (s *Session) SetVectorPrecision(precision string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.VectorPrecision = precision
+}
+
+// GetVectorPrecision returns the session's vector storage precision, empty
+// when the session uses the server default
+func (s *Session) GetVectorPrecision() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.VectorPrecision
+}
+
 // SetIdleTTLSeconds sets the idle TTL in seconds (convenience method)
 func (s *Session) SetIdleTTLSeconds(seconds int64) {
 	s.SetIdleTTL(seconds * int64(time.Second))
@@ -204,6 +280,64 @@ func (s *Session) SubMemory(bytes int64) {
 	}
 }
 
+// SetMetadata merges updates into the session metadata. An empty value removes
+// the key; replace clears existing metadata first. The result must stay within
+// MaxSessionMetadataKeys and MaxSessionMetadataBytes, otherwise nothing changes.
+func (s *Session) SetMetadata(updates map[string]string, replace bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	merged := make(map[string]string, len(s.Metadata)+len(updates))
+	if !replace {
+		for k, v := range s.Metadata {
+			merged[k] = v
+		}
+	}
+	for k, v := range updates {
+		if k == "" {
+			return ErrSessionMetadataEmptyKey
+		}
+		if v == "" {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+
+	size := 0
+	for k, v := range merged {
+		size += len(k) + len(v)
+	}
+	if len(merged) > MaxSessionMetadataKeys || size > MaxSessionMetadataBytes {
+		return ErrSessionMetadataTooLarge
+	}
+
+	if len(merged) == 0 {
+		merged = nil
+	}
+	s.Metadata = merged
+	return nil
+}
+
+// GetMetadata returns a copy of the session metadata
+func (s *Session) GetMetadata() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.copyMetadata()
+}
+
+// copyMetadata copies the metadata map (caller holds s.mu)
+func (s *Session) copyMetadata() map[string]string {
+	if len(s.Metadata) == 0 {
+		return nil
+	}
+	md := make(map[string]string, len(s.Metadata))
+	for k, v := range s.Metadata {
+		md[k] = v
+	}
+	return md
+}
+
 // IsExpired checks if the session has expired
 func (s *Session) IsExpired() bool {
 	s.mu.RLock()
@@ -270,6 +404,7 @@ func (s *Session) GetInfo() SessionInfo {
 		LastAccess: s.LastAccess,
 		TTL:        s.TTL,
 		IdleTTL:    s.IdleTTL,
+		Metadata:   s.copyMetadata(),
 	}
 }
 
@@ -289,9 +424,12 @@ type SessionInfo struct {
 	EntityCount       int    `json:"entity_count"`
 	RelationshipCount int    `json:"relationship_count"`
 	CommunityCount    int    `json:"community_count"`
+	CommunitiesDirty  bool   `json:"communities_dirty"` // edges changed since communities were computed
 	MemoryBytes       int64  `json:"memory_bytes"`
 	MaxEntities       int    `json:"max_entities,omitempty"`
 	MaxRelationships  int    `json:"max_relationships,omitempty"`
 	MaxDocuments      int    `json:"max_documents,omitempty"`
 	MaxMemoryBytes    int64  `json:"max_memory_bytes,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
@@ -2,6 +2,9 @@
 package types
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -148,13 +151,14 @@ func NewDocument(id uint64, extID, filename string) *Document {
 // =============================================================================
 
 type TextUnit struct {
-	ID         uint64   `json:"id"`
-	ExternalID string   `json:"external_id"` // "chunk-001"
-	DocumentID uint64   `json:"document_id"` // parent document
-	Content    string   `json:"content"`     // full text
-	EntityIDs  []uint64 `json:"entity_ids"`  // linked entities
-	TokenCount int      `json:"token_count"`
-	CreatedAt  int64    `json:"created_at"`
+	ID         uint64    `json:"id"`
+	ExternalID string    `json:"external_id"` // "chunk-001"
+	DocumentID uint64    `json:"document_id"` // parent document
+	Content    string    `json:"content"`     // full text
+	EntityIDs  []uint64  `json:"entity_ids"`  // linked entities
+	TokenCount int       `json:"token_count"`
+	CreatedAt  int64     `json:"created_at"`
+	Embedding  []float32 `json:"embedding,omitempty"` // stored embedding, set on GET responses only
 }
 
 // NewTextUnit creates a new text unit with auto-set timestamp
@@ -196,21 +200,27 @@ type Entity struct {
 	ExternalID  string            `json:"external_id"`   // "ent-001"
 	Title       string            `json:"title"`         // "BANK INDONESIA" (uppercase for dedup)
 	Type        string            `json:"type"`          // "organization", "person", "location", "concept"
-	Description string            `json:"description"`   // semantic content for embedding
-	Attrs       map[string]string `json:"attrs,omitempty"`
-	TextUnitIDs []uint64          `json:"text_unit_ids"` // linked chunks
+	Description string            `json:"description"`        // semantic content for embedding
+	Metadata    map[string]string `json:"metadata,omitempty"` // structured attributes, e.g. ticker=BBRI
+	TextUnitIDs []uint64          `json:"text_unit_ids"`      // linked chunks
 	CreatedAt   int64             `json:"created_at"`
+	UpdatedAt   int64             `json:"updated_at,omitempty"` // last change to title, description, metadata or merge
+	Popularity  float64           `json:"popularity,omitempty"` // decayed access count, set on GET responses when tracking is on
+	PageRank    float64           `json:"pagerank,omitempty"`   // centrality from the last ComputePageRank (scores sum to 1)
+	Embedding   []float32         `json:"embedding,omitempty"`  // stored embedding, set on GET responses only
 }
 
-// NewEntity creates a new entity with auto-set timestamp
+// NewEntity creates a new entity with auto-set timestamps
 func NewEntity(id uint64, extID, title, entType, description string) *Entity {
+	now := time.Now().Unix()
 	return &Entity{
 		ID:          id,
 		ExternalID:  extID,
 		Title:       title,
 		Type:        entType,
 		Description: description,
-		CreatedAt:   time.Now().Unix(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 }
 
@@ -246,10 +256,52 @@ type Relationship struct {
 	Weight      float32  `json:"weight"`
 	TextUnitIDs []uint64 `json:"text_unit_ids"` // provenance chunks
 	CreatedAt   int64    `json:"created_at"`
+	UpdatedAt   int64    `json:"updated_at,omitempty"` // last change to weight, validity or direction
+
+	// Temporal validity window in unix seconds (0 = unbounded)
+	ValidFrom  int64 `json:"valid_from,omitempty"`
+	ValidUntil int64 `json:"valid_until,omitempty"`
+
+	// Directed relationships are traversed source to target only, unless
+	// the query asks otherwise (see QuerySpec.TraversalDirection).
+	// Undirected ones are traversed from either endpoint.
+	Directed bool `json:"directed,omitempty"`
+}
+
+// Direction selects which of an entity's relationships to follow
+type Direction int
+
+const (
+	DirectionBoth     Direction = iota // incoming and outgoing
+	DirectionOutgoing                  // the entity is the source
+	DirectionIncoming                  // the entity is the target
+)
+
+// ConflictPolicy decides what happens to merged records that clash with the
+// destination session's: a taken external ID, entity title or relationship
+// endpoint pair
+type ConflictPolicy int
+
+const (
+	ConflictReject     ConflictPolicy = iota // fail the merge without changes
+	ConflictSkip                             // leave the clashing record out
+	ConflictDedupTitle                       // fold entities into the destination's entity with the same title; skip other clashes
+)
+
+// ErrInvalidValidityWindow is returned when valid_until does not follow valid_from
+var ErrInvalidValidityWindow = errors.New("valid_until must be after valid_from")
+
+// RelationshipOptions are the attributes a relationship can be created with
+// beyond its endpoints, type, description and weight
+type RelationshipOptions struct {
+	ValidFrom  int64 // unix seconds, 0 = unbounded
+	ValidUntil int64 // unix seconds, 0 = unbounded
+	Directed   bool
 }
 
-// NewRelationship creates a new relationship with auto-set timestamp
+// NewRelationship creates a new relationship with auto-set timestamps
 func NewRelationship(id uint64, extID string, sourceID, targetID uint64, relType, description string, weight float32) *Relationship {
+	now := time.Now().Unix()
 	return &Relationship{
 		ID:          id,
 		ExternalID:  extID,
@@ -258,8 +310,24 @@ func NewRelationship(id uint64, extID string, sourceID, targetID uint64, relType
 		Type:        relType,
 		Description: description,
 		Weight:      weight,
-		CreatedAt:   time.Now().Unix(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// IsValidAt reports whether the relationship is valid at the given unix time.
+// The window is half-open: [ValidFrom, ValidUntil). A zero ts matches everything.
+func (r *Relationship) IsValidAt(ts int64) bool {
+	if ts == 0 {
+		return true
+	}
+	if r.ValidFrom != 0 && ts < r.ValidFrom {
+		return false
+	}
+	if r.ValidUntil != 0 && ts >= r.ValidUntil {
+		return false
 	}
+	return true
 }
 
 func (r *Relationship) AddTextUnitID(tuID uint64) {
@@ -271,6 +339,61 @@ func (r *Relationship) AddTextUnitID(tuID uint64) {
 	r.TextUnitIDs = append(r.TextUnitIDs, tuID)
 }
 
+// RelationshipTypeStat aggregates relationships of one type
+type RelationshipTypeStat struct {
+	Type      string  `json:"type"`
+	Count     int     `json:"count"`
+	AvgWeight float32 `json:"avg_weight"`
+}
+
+// RelationshipFilter selects relationships by type and endpoints; zero
+// fields match everything
+type RelationshipFilter struct {
+	Type     string `json:"type,omitempty"`
+	SourceID uint64 `json:"source_id,omitempty"`
+	TargetID uint64 `json:"target_id,omitempty"`
+}
+
+// Matches reports whether rel passes every set field of the filter
+func (f RelationshipFilter) Matches(rel *Relationship) bool {
+	return (f.Type == "" || rel.Type == f.Type) &&
+		(f.SourceID == 0 || rel.SourceID == f.SourceID) &&
+		(f.TargetID == 0 || rel.TargetID == f.TargetID)
+}
+
+// SessionFilter selects sessions by ID prefix and creation time; zero fields
+// match everything
+type SessionFilter struct {
+	Prefix       string `json:"prefix,omitempty"`
+	CreatedAfter int64  `json:"created_after,omitempty"` // unix nanoseconds
+
+	// Visible, when set, hides sessions it returns false for, such as those
+	// outside an API key's scope
+	Visible func(sessionID string) bool `json:"-"`
+}
+
+// MatchesID reports whether a session ID passes the filter's ID checks
+func (f SessionFilter) MatchesID(sessionID string) bool {
+	return strings.HasPrefix(sessionID, f.Prefix) && (f.Visible == nil || f.Visible(sessionID))
+}
+
+// ItemType names a kind of stored item, as counted by CMD_COUNT
+type ItemType string
+
+const (
+	ItemTypeDocument     ItemType = "document"
+	ItemTypeTextUnit     ItemType = "textunit"
+	ItemTypeEntity       ItemType = "entity"
+	ItemTypeRelationship ItemType = "relationship"
+	ItemTypeCommunity    ItemType = "community"
+)
+
+// TypeCounts breaks a session's entities and relationships down by type
+type TypeCounts struct {
+	EntityTypes       map[string]int `json:"entity_types"`
+	RelationshipTypes map[string]int `json:"relationship_types"`
+}
+
 // =============================================================================
 // Community - Result of Leiden clustering with LLM summary
 // =============================================================================
@@ -312,8 +435,71 @@ const (
 	SearchTypeTextUnit  SearchType = "textunit"
 	SearchTypeEntity    SearchType = "entity"
 	SearchTypeCommunity SearchType = "community"
+	SearchTypeKeyword   SearchType = "keyword" // BM25 over text unit content, using QuerySpec.KeywordQuery
+)
+
+// DistanceMetric selects how vector search scores embeddings. Scores are
+// always similarities (higher = closer).
+type DistanceMetric string
+
+const (
+	DistanceCosine     DistanceMetric = "cosine"    // cosine similarity (default)
+	DistanceDotProduct DistanceMetric = "dot"       // raw dot product, for pre-normalized embeddings
+	DistanceEuclidean  DistanceMetric = "euclidean" // 1/(1+L2 distance)
+)
+
+// ParseDistanceMetric validates a metric name; empty means cosine
+func ParseDistanceMetric(name string) (DistanceMetric, error) {
+	switch DistanceMetric(name) {
+	case "", DistanceCosine:
+		return DistanceCosine, nil
+	case DistanceDotProduct, DistanceEuclidean:
+		return DistanceMetric(name), nil
+	}
+	return "", fmt.Errorf("unknown distance metric %q (want cosine, dot or euclidean)", name)
+}
+
+// FusionMethod selects how hybrid (vector + keyword) text unit rankings are
+// combined
+type FusionMethod string
+
+const (
+	FusionWeighted FusionMethod = "weighted" // blend scores by KeywordWeight (default)
+	FusionRRF      FusionMethod = "rrf"      // reciprocal rank fusion, 1/(k+rank) per ranking
+)
+
+// ParseFusionMethod validates a fusion method name; empty means weighted
+func ParseFusionMethod(name string) (FusionMethod, error) {
+	switch FusionMethod(name) {
+	case "", FusionWeighted:
+		return FusionWeighted, nil
+	case FusionRRF:
+		return FusionRRF, nil
+	}
+	return "", fmt.Errorf("unknown fusion method %q (want weighted or rrf)", name)
+}
+
+// TraversalDirection selects which way query traversal follows directed
+// relationships. Undirected relationships are followed either way.
+type TraversalDirection string
+
+const (
+	TraversalForward TraversalDirection = "forward" // source to target (default)
+	TraversalReverse TraversalDirection = "reverse" // target to source
+	TraversalBoth    TraversalDirection = "both"    // either way
 )
 
+// ParseTraversalDirection validates a traversal direction; empty means forward
+func ParseTraversalDirection(name string) (TraversalDirection, error) {
+	switch TraversalDirection(name) {
+	case "", TraversalForward:
+		return TraversalForward, nil
+	case TraversalReverse, TraversalBoth:
+		return TraversalDirection(name), nil
+	}
+	return "", fmt.Errorf("unknown traversal direction %q (want forward, reverse or both)", name)
+}
+
 type QuerySpec struct {
 	QueryVector    []float32    `json:"query_vector"`
 	SearchTypes    []SearchType `json:"search_types"` // which indices to search
@@ -322,7 +508,104 @@ type QuerySpec struct {
 	MaxEntities    int          `json:"max_entities"`
 	MaxTextUnits   int          `json:"max_text_units"`
 	MaxCommunities int          `json:"max_communities"`
-	DeadlineMs     int          `json:"deadline_ms"`
+	DeadlineMs     int          `json:"deadline_ms"` // client call deadline; the sooner of this and the context deadline applies (0 = none)
+	AsOf           int64        `json:"as_of,omitempty"` // unix seconds; traverse only relationships valid at this time (0 = all)
+
+	// CreatedAfter and CreatedBefore restrict entities and relationships to
+	// those created strictly inside the window (unix seconds, 0 = open).
+	// Records outside it are neither returned nor traversed.
+	CreatedAfter  int64 `json:"created_after,omitempty"`
+	CreatedBefore int64 `json:"created_before,omitempty"`
+
+	// IncludeTextStats fills TokenCount/ContentLength on text unit results
+	// so callers can do token-budget packing without re-fetching units.
+	IncludeTextStats bool `json:"include_text_stats,omitempty"`
+
+	// HubPenalty down-weights high-degree entities: each entity score is
+	// multiplied by (1+degree)^-HubPenalty. 0 disables it, 1 is plain
+	// inverse-degree scaling; values in between soften the penalty.
+	HubPenalty float32 `json:"hub_penalty,omitempty"`
+
+	// MinResults makes the engine relax the query (lower MinSimilarity, wider
+	// vector search, more hops) in bounded steps until at least this many context items are
+	// returned. Applied steps are reported in QueryStats.Relaxations.
+	MinResults int `json:"min_results,omitempty"`
+
+	// PopularityBoost scales entity scores by 1 + PopularityBoost*ln(1+p),
+	// where p is the entity's decayed access count. Needs popularity
+	// tracking enabled on the engine; 0 disables it.
+	PopularityBoost float32 `json:"popularity_boost,omitempty"`
+
+	// PageRankWeight scales entity scores by 1 + PageRankWeight*PageRank*N,
+	// where N is the session's entity count, so an entity of average
+	// centrality gets 1 + PageRankWeight. Needs ComputePageRank to have run;
+	// 0 disables it.
+	PageRankWeight float32 `json:"pagerank_weight,omitempty"`
+
+	// MaxExpansionPerHop caps how many new neighbors each frontier node adds
+	// during traversal, preferring highest-weight edges (0 = no cap).
+	MaxExpansionPerHop int `json:"max_expansion_per_hop,omitempty"`
+
+	// TraversalDirection is the way directed relationships are followed:
+	// forward (default), reverse or both.
+	TraversalDirection TraversalDirection `json:"traversal_direction,omitempty"`
+
+	// DecayFactor > 0 scores traversed entities by their best path from a
+	// seed: seed score * product(edge weights) * DecayFactor^hop, so
+	// entities behind strong edges outrank weakly linked ones at the same
+	// hop. Traversal then runs to KHops and MaxEntities keeps the best
+	// scores. 0 keeps the plain 1/(1+hop) score.
+	DecayFactor float32 `json:"decay_factor,omitempty"`
+
+	// EntityTypes restricts returned entities (seeds and traversal hits) to
+	// these types. Other entities are still traversed through. Empty = all.
+	EntityTypes []string `json:"entity_types,omitempty"`
+
+	// MetadataFilters restricts returned entities like EntityTypes, keeping
+	// only those whose metadata has every given key with an equal value.
+	MetadataFilters map[string]string `json:"metadata_filters,omitempty"`
+
+	// TitleWeight and DescriptionWeight score entities by the weighted mean
+	// of their title and description embedding similarity. Only applies
+	// when TitleWeight > 0 and the session stores title embeddings;
+	// DescriptionWeight 0 then means title-only matching.
+	TitleWeight       float32 `json:"title_weight,omitempty"`
+	DescriptionWeight float32 `json:"description_weight,omitempty"`
+
+	// MinSimilarity drops vector search hits (text units, entities,
+	// communities) below this similarity before traversal (0 = off).
+	MinSimilarity float32 `json:"min_similarity,omitempty"`
+
+	// EfSearch is the HNSW candidate list size for this query's vector
+	// searches; higher improves recall at some latency (0 = index default).
+	EfSearch int `json:"ef_search,omitempty"`
+
+	// KeywordQuery is the text matched by SearchTypeKeyword. Keyword hits
+	// join the text unit seeds, which are rescored by FusionMethod; weighted
+	// fusion scores (1-KeywordWeight)*similarity + KeywordWeight*keyword
+	// score, with keyword scores scaled so the best hit is 1
+	// (KeywordWeight 0 = 0.5).
+	KeywordQuery  string  `json:"keyword_query,omitempty"`
+	KeywordWeight float32 `json:"keyword_weight,omitempty"`
+
+	// FusionMethod combines the vector and keyword rankings: weighted score
+	// blending (default) or reciprocal rank fusion with rank offset RRFK
+	// (0 = 60), which ignores the differing score scales.
+	FusionMethod FusionMethod `json:"fusion_method,omitempty"`
+	RRFK         int          `json:"rrf_k,omitempty"`
+
+	// Diversity (0..1) reorders text units by Maximal Marginal Relevance
+	// before the MaxTextUnits cap, trading relevance for distance from
+	// units already picked. 0 keeps plain score order.
+	Diversity float32 `json:"diversity,omitempty"`
+
+	// PageSize > 0 returns results a page at a time: each result list is cut
+	// to PageSize items (at most 10000) and ContextPack.NextCursor fetches
+	// the next page. Passing that Cursor back pages through the ranking
+	// cached by the first call, so pages stay consistent while the session
+	// changes; the rest of the spec is then ignored.
+	PageSize int    `json:"page_size,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`
 }
 
 func DefaultQuerySpec() QuerySpec {
@@ -333,7 +616,6 @@ func DefaultQuerySpec() QuerySpec {
 		MaxEntities:    50,
 		MaxTextUnits:   10,
 		MaxCommunities: 5,
-		DeadlineMs:     100,
 	}
 }
 
@@ -346,6 +628,13 @@ type TextUnitResult struct {
 	Score      float32   `json:"score"`
 	Similarity float32   `json:"similarity"`
 	Hop        int       `json:"hop"`
+
+	// KeywordScore is the scaled BM25 score for SearchTypeKeyword hits
+	KeywordScore float32 `json:"keyword_score,omitempty"`
+
+	// Set only when QuerySpec.IncludeTextStats is true
+	TokenCount    int `json:"token_count,omitempty"`
+	ContentLength int `json:"content_length,omitempty"` // characters
 }
 
 type EntityResult struct {
@@ -373,6 +662,9 @@ type QueryStats struct {
 	CommunitiesSearched int   `json:"communities_searched"`
 	EdgesScanned        int   `json:"edges_scanned"`
 	DurationMicros      int64 `json:"duration_micros"`
+
+	Relaxations []string `json:"relaxations,omitempty"` // relaxation steps applied for MinResults
+	CacheHit    bool     `json:"cache_hit,omitempty"`   // served from the engine's query cache
 }
 
 type ContextPack struct {
@@ -382,6 +674,101 @@ type ContextPack struct {
 	Communities   []CommunityResult    `json:"communities"`
 	Relationships []RelationshipResult `json:"relationships"`
 	Stats         QueryStats           `json:"stats"`
+	NextCursor    string               `json:"next_cursor,omitempty"` // set when a paged query has more results
+}
+
+// QueryRecord is one query in a session's query history
+type QueryRecord struct {
+	QueryID     uint64     `json:"query_id"`
+	Spec        QuerySpec  `json:"spec"` // as run, after any MinResults relaxation
+	Stats       QueryStats `json:"stats"`
+	At          int64      `json:"at"` // unix seconds
+	TextUnits   int        `json:"text_units"`
+	Entities    int        `json:"entities"`
+	Communities int        `json:"communities"`
+}
+
+// QueryStatsSummary aggregates recent queries into a workload profile
+type QueryStatsSummary struct {
+	WindowSeconds    int64   `json:"window_seconds"`
+	Count            int     `json:"count"`
+	AvgLatencyMicros float64 `json:"avg_latency_micros"`
+	P50LatencyMicros float64 `json:"p50_latency_micros"`
+	P95LatencyMicros float64 `json:"p95_latency_micros"`
+	P99LatencyMicros float64 `json:"p99_latency_micros"`
+	AvgTextUnits     float64 `json:"avg_text_units"`
+	AvgEntities      float64 `json:"avg_entities"`
+	AvgCommunities   float64 `json:"avg_communities"`
+	AvgKHops         float64 `json:"avg_k_hops"`
+	EmptyResults     int     `json:"empty_results"` // queries that returned no context
+	Relaxed          int     `json:"relaxed"`       // queries relaxed for MinResults
+	CacheHits        int     `json:"cache_hits"`    // queries served from the query cache
+	CacheHitRate     float64 `json:"cache_hit_rate"`
+}
+
+// CommandLatency is one protocol command's count and latency since server start
+type CommandLatency struct {
+	Command          string  `json:"command"`
+	Count            int64   `json:"count"`
+	Errors           int64   `json:"errors"`
+	AvgLatencyMicros float64 `json:"avg_latency_micros"`
+	P50LatencyMicros float64 `json:"p50_latency_micros"`
+	P95LatencyMicros float64 `json:"p95_latency_micros"`
+	P99LatencyMicros float64 `json:"p99_latency_micros"`
+}
+
+// CommandStats holds server-wide per-command latency
+type CommandStats struct {
+	UptimeSeconds        int64            `json:"uptime_seconds"`
+	SlowQueryThresholdMs int64            `json:"slow_query_threshold_ms"` // 0 = slow-query log disabled
+	Commands             []CommandLatency `json:"commands"`
+}
+
+// IndexState is whether a session's vector indices are being rebuilt
+type IndexState string
+
+const (
+	IndexBuilding IndexState = "building"
+	IndexReady    IndexState = "ready"
+)
+
+// IndexStatus reports a session's vector indices and their latest rebuild
+type IndexStatus struct {
+	State       IndexState `json:"state"`
+	Percent     float64    `json:"percent"`         // rebuild progress, 0-100
+	VectorCount int        `json:"vector_count"`    // vectors in the indices, or being rebuilt
+	Error       string     `json:"error,omitempty"` // why the latest rebuild failed
+	StartedAt   int64      `json:"started_at"`      // latest rebuild start, 0 if none
+	FinishedAt  int64      `json:"finished_at"`     // latest rebuild end, 0 while building
+}
+
+// =============================================================================
+// Graph Diff Types
+// =============================================================================
+
+// DiffOp describes how an object changed between two graph states
+type DiffOp string
+
+const (
+	DiffAdded    DiffOp = "added"
+	DiffRemoved  DiffOp = "removed"
+	DiffModified DiffOp = "modified"
+)
+
+// GraphChange is a single entity or relationship change.
+// Exactly one of Entity/Relationship is set: the new state for added and
+// modified objects, the old state for removed ones.
+type GraphChange struct {
+	Op           DiffOp        `json:"op"`
+	Entity       *Entity       `json:"entity,omitempty"`
+	Relationship *Relationship `json:"relationship,omitempty"`
+}
+
+// GraphDiff is one page of changes between two graph states
+type GraphDiff struct {
+	Changes    []GraphChange `json:"changes"`
+	Total      int           `json:"total"`       // total changes across all pages
+	NextCursor uint64        `json:"next_cursor"` // 0 = no more
 }
 
 // =============================================================================
@@ -425,6 +812,8 @@ type ServerInfo struct {
 	CommunityCount    int    `json:"community_count"`
 	VectorDim         int    `json:"vector_dim"`
 	SessionCount      int    `json:"session_count"`
+
+	DisabledCommands []string `json:"disabled_commands,omitempty"`
 }
 
 // =============================================================================
@@ -453,6 +842,9 @@ type BulkEntityInput struct {
 	Type        string
 	Description string
 	Embedding   []float32
+
+	TitleEmbedding []float32         // optional, see QuerySpec.TitleWeight
+	Metadata       map[string]string // optional structured attributes
 }
 
 // BulkRelationshipInput represents input for bulk relationship creation.
@@ -463,4 +855,20 @@ type BulkRelationshipInput struct {
 	Type        string
 	Description string
 	Weight      float32
+	ValidFrom   int64
+	ValidUntil  int64
+	Directed    bool
+}
+
+// BulkLinkInput represents one text unit -> entity link in a bulk link.
+type BulkLinkInput struct {
+	TextUnitID uint64
+	EntityID   uint64
+}
+
+// BulkLinkResult reports the outcome of one bulk link pair.
+type BulkLinkResult struct {
+	TextUnitID uint64
+	EntityID   uint64
+	Error      string // empty on success
 }
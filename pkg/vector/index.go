@@ -11,6 +11,7 @@ import (
 	"sync"
 
 	"github.com/gibram-io/gibram/pkg/simd"
+	"github.com/gibram-io/gibram/pkg/types"
 )
 
 // =============================================================================
@@ -26,6 +27,9 @@ type Index interface {
 	Add(id uint64, vector []float32) error
 	Remove(id uint64) bool
 	Search(query []float32, k int) []SearchResult
+	SearchWithEf(query []float32, k, ef int) []SearchResult // ef <= 0 uses the index default
+	Similarity(id uint64, query []float32) (float32, bool)  // score one stored vector
+	Vector(id uint64) ([]float32, bool)                     // copy of one stored vector
 	Count() int
 	Dimension() int
 	Save(w io.Writer) error
@@ -47,6 +51,9 @@ type HNSWConfig struct {
 	EfSearch       int     // size of dynamic candidate list during search
 	MaxLevel       int     // max layer
 	ML             float64 // level multiplier (1/ln(M))
+
+	Metric    types.DistanceMetric // similarity used for search (empty = cosine)
+	Precision Precision            // vector storage precision (empty = float32)
 }
 
 func DefaultHNSWConfig() HNSWConfig {
@@ -59,29 +66,165 @@ func DefaultHNSWConfig() HNSWConfig {
 	}
 }
 
+// NewHNSWConfig returns the default config with the given graph degree and
+// candidate list sizes. Zero keeps the default; M below 2 is raised to 2.
+func NewHNSWConfig(m, efConstruction, efSearch int) HNSWConfig {
+	config := DefaultHNSWConfig()
+	if m > 0 {
+		config.M = max(m, 2)
+		config.ML = 1.0 / math.Log(float64(config.M))
+	}
+	if efConstruction > 0 {
+		config.EfConstruction = efConstruction
+	}
+	if efSearch > 0 {
+		config.EfSearch = efSearch
+	}
+	return config
+}
+
 type hnswNode struct {
 	id      uint64
 	vector  []float32
+	half    []uint16     // set instead of vector under PrecisionFloat16
+	scalar  *scalarCodes // set instead of vector under PrecisionInt8
 	level   int
 	friends [][]uint64 // friends[level] = list of connected node IDs
 }
 
+// values returns the node's vector as float32: the stored slice itself at
+// full precision (not to be modified), otherwise a decoded copy
+func (n *hnswNode) values() []float32 {
+	if n.half != nil {
+		return decodeHalf(n.half)
+	}
+	if n.scalar != nil {
+		return n.scalar.decode()
+	}
+	return n.vector
+}
+
+// vectorCopy returns a copy of the node's vector that callers may keep
+func (n *hnswNode) vectorCopy() []float32 {
+	if n.half != nil {
+		return decodeHalf(n.half)
+	}
+	if n.scalar != nil {
+		return n.scalar.decode()
+	}
+	copied := make([]float32, len(n.vector))
+	copy(copied, n.vector)
+	return copied
+}
+
+// dim returns the length of the stored vector
+func (n *hnswNode) dim() int {
+	if n.scalar != nil {
+		return len(n.scalar.codes)
+	}
+	return len(n.vector) + len(n.half)
+}
+
 type HNSWIndex struct {
-	mu        sync.RWMutex
-	config    HNSWConfig
-	dimension int
-	nodes     map[uint64]*hnswNode
-	entryID   uint64
-	maxLevel  int
+	mu               sync.RWMutex
+	config           HNSWConfig
+	dimension        int
+	nodes            map[uint64]*hnswNode
+	entryID          uint64
+	maxLevel         int
+	similarity       func(a, b []float32) float32
+	halfSimilarity   func(a []float32, b []uint16) float32
+	scalarSimilarity func(a []float32, b *scalarCodes) float32
 }
 
 func NewHNSWIndex(dimension int, config HNSWConfig) *HNSWIndex {
 	return &HNSWIndex{
-		config:    config,
-		dimension: dimension,
-		nodes:     make(map[uint64]*hnswNode),
-		entryID:   0,
-		maxLevel:  -1,
+		config:           config,
+		dimension:        dimension,
+		nodes:            make(map[uint64]*hnswNode),
+		entryID:          0,
+		maxLevel:         -1,
+		similarity:       SimilarityFunc(config.Metric),
+		halfSimilarity:   halfSimilarityFunc(config.Metric),
+		scalarSimilarity: scalarSimilarityFunc(config.Metric),
+	}
+}
+
+// newNode creates an unlinked node holding a copy of vector at the index
+// precision
+func (h *HNSWIndex) newNode(id uint64, vector []float32, level int) *hnswNode {
+	node := &hnswNode{
+		id:      id,
+		level:   level,
+		friends: make([][]uint64, level+1),
+	}
+	h.setVector(node, vector)
+	for i := range node.friends {
+		node.friends[i] = make([]uint64, 0, h.config.M)
+	}
+	return node
+}
+
+// setVector stores a copy of vector on node at the index precision
+func (h *HNSWIndex) setVector(node *hnswNode, vector []float32) {
+	node.vector, node.half, node.scalar = nil, nil, nil
+	switch h.config.Precision {
+	case PrecisionFloat16:
+		node.half = encodeHalf(vector)
+	case PrecisionInt8:
+		node.scalar = encodeScalar(vector)
+	default:
+		node.vector = make([]float32, len(vector))
+		copy(node.vector, vector)
+	}
+}
+
+// score returns the similarity between query and node's stored vector
+func (h *HNSWIndex) score(query []float32, node *hnswNode) float32 {
+	if node.half != nil {
+		return h.halfSimilarity(query, node.half)
+	}
+	if node.scalar != nil {
+		return h.scalarSimilarity(query, node.scalar)
+	}
+	return h.similarity(query, node.vector)
+}
+
+// VectorBytes returns the memory held by stored vector components
+func (h *HNSWIndex) VectorBytes() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var total int64
+	for _, node := range h.nodes {
+		total += int64(len(node.vector))*4 + int64(len(node.half))*2
+		if node.scalar != nil {
+			total += int64(len(node.scalar.codes)) + 8 // codes plus lo and step
+		}
+	}
+	return total
+}
+
+// Precision returns the precision vectors are stored at
+func (h *HNSWIndex) Precision() Precision {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.config.Precision == "" {
+		return PrecisionFloat32
+	}
+	return h.config.Precision
+}
+
+// SetPrecision converts every stored vector to precision in place, keeping
+// the graph as is. Vectors added later are stored at the new precision.
+// Converting to a higher precision cannot restore what was already lost.
+func (h *HNSWIndex) SetPrecision(precision Precision) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.config.Precision = precision
+	for _, node := range h.nodes {
+		h.setVector(node, node.values())
 	}
 }
 
@@ -95,13 +238,11 @@ func (h *HNSWIndex) Count() int {
 	return len(h.nodes)
 }
 
-// randomLevel generates a random level for a new node
+// randomLevel draws a node level from the exponential distribution
+// floor(-ln(U) * ML), so each layer holds about 1/M of the one below
 func (h *HNSWIndex) randomLevel() int {
-	level := 0
-	for rand.Float64() < h.config.ML && level < h.config.MaxLevel {
-		level++
-	}
-	return level
+	level := int(-math.Log(1-rand.Float64()) * h.config.ML)
+	return min(level, h.config.MaxLevel)
 }
 
 // cosineSimilarity calculates cosine similarity between two vectors
@@ -110,6 +251,23 @@ func cosineSimilarity(a, b []float32) float32 {
 	return simd.CosineSimilarity(a, b)
 }
 
+// euclideanSimilarity maps L2 distance to a similarity in (0, 1]
+func euclideanSimilarity(a, b []float32) float32 {
+	return 1 / (1 + simd.EuclideanDistance(a, b))
+}
+
+// SimilarityFunc returns the scoring function for a metric (higher = closer)
+func SimilarityFunc(metric types.DistanceMetric) func(a, b []float32) float32 {
+	switch metric {
+	case types.DistanceDotProduct:
+		return simd.DotProduct
+	case types.DistanceEuclidean:
+		return euclideanSimilarity
+	default:
+		return cosineSimilarity
+	}
+}
+
 // Add inserts a vector into the index
 func (h *HNSWIndex) Add(id uint64, vector []float32) error {
 	if len(vector) != h.dimension {
@@ -126,17 +284,7 @@ func (h *HNSWIndex) Add(id uint64, vector []float32) error {
 
 	// Create new node
 	level := h.randomLevel()
-	node := &hnswNode{
-		id:      id,
-		vector:  make([]float32, len(vector)),
-		level:   level,
-		friends: make([][]uint64, level+1),
-	}
-	copy(node.vector, vector)
-
-	for i := range node.friends {
-		node.friends[i] = make([]uint64, 0, h.config.M)
-	}
+	node := h.newNode(id, vector, level)
 
 	// First node
 	if len(h.nodes) == 0 {
@@ -172,7 +320,7 @@ func (h *HNSWIndex) Add(id uint64, vector []float32) error {
 
 				// Prune if too many connections
 				if len(neighbor.friends[l]) > h.config.M*2 {
-					neighbor.friends[l] = h.selectNeighbors(neighbor.vector, neighbor.friends[l], h.config.M)
+					neighbor.friends[l] = h.selectNeighbors(neighbor.values(), neighbor.friends[l], h.config.M)
 				}
 			}
 		}
@@ -196,7 +344,7 @@ func (h *HNSWIndex) Add(id uint64, vector []float32) error {
 // searchLayerClosest finds the closest node to query in a single layer
 func (h *HNSWIndex) searchLayerClosest(query []float32, entryID uint64, level int) uint64 {
 	currID := entryID
-	currDist := cosineSimilarity(query, h.nodes[currID].vector)
+	currDist := h.score(query, h.nodes[currID])
 
 	changed := true
 	for changed {
@@ -211,7 +359,7 @@ func (h *HNSWIndex) searchLayerClosest(query []float32, entryID uint64, level in
 			if friend == nil {
 				continue
 			}
-			dist := cosineSimilarity(query, friend.vector)
+			dist := h.score(query, friend)
 			if dist > currDist {
 				currID = friendID
 				currDist = dist
@@ -223,7 +371,9 @@ func (h *HNSWIndex) searchLayerClosest(query []float32, entryID uint64, level in
 	return currID
 }
 
-// searchLayer finds ef closest nodes to query starting from entry
+// searchLayer finds ef closest nodes to query starting from entry.
+// candidates is a max-heap on similarity (closest first); result stores
+// negated similarity so its top is the worst of the current ef best.
 func (h *HNSWIndex) searchLayer(query []float32, entryID uint64, ef int, level int) []uint64 {
 	visited := make(map[uint64]bool)
 	candidates := &priorityQueue{}
@@ -234,11 +384,11 @@ func (h *HNSWIndex) searchLayer(query []float32, entryID uint64, ef int, level i
 		return nil
 	}
 
-	dist := cosineSimilarity(query, entry.vector)
+	dist := h.score(query, entry)
 	visited[entryID] = true
 
 	candidates.Push(pqItem{id: entryID, priority: dist})
-	result.Push(pqItem{id: entryID, priority: dist})
+	result.Push(pqItem{id: entryID, priority: -dist})
 
 	for candidates.Len() > 0 {
 		curr := candidates.Pop()
@@ -247,11 +397,11 @@ func (h *HNSWIndex) searchLayer(query []float32, entryID uint64, ef int, level i
 			continue
 		}
 
-		// Get worst result
-		worst := result.Peek()
+		// Similarity of the worst kept result
+		worst := -result.Peek().priority
 
 		// If current is farther than worst result and we have enough, stop
-		if curr.priority < worst.priority && result.Len() >= ef {
+		if curr.priority < worst && result.Len() >= ef {
 			break
 		}
 
@@ -268,15 +418,15 @@ func (h *HNSWIndex) searchLayer(query []float32, entryID uint64, ef int, level i
 					continue
 				}
 
-				neighborDist := cosineSimilarity(query, neighbor.vector)
-				worst = result.Peek()
+				neighborDist := h.score(query, neighbor)
+				worst = -result.Peek().priority
 
-				if result.Len() < ef || neighborDist > worst.priority {
+				if result.Len() < ef || neighborDist > worst {
 					candidates.Push(pqItem{id: neighborID, priority: neighborDist})
-					result.Push(pqItem{id: neighborID, priority: neighborDist})
+					result.Push(pqItem{id: neighborID, priority: -neighborDist})
 
 					if result.Len() > ef {
-						result.PopWorst()
+						result.Pop()
 					}
 				}
 			}
@@ -308,7 +458,7 @@ func (h *HNSWIndex) selectNeighbors(query []float32, candidates []uint64, M int)
 	for _, id := range candidates {
 		node := h.nodes[id]
 		if node != nil {
-			scoredCandidates = append(scoredCandidates, scored{id: id, score: cosineSimilarity(query, node.vector)})
+			scoredCandidates = append(scoredCandidates, scored{id: id, score: h.score(query, node)})
 		}
 	}
 
@@ -326,6 +476,12 @@ func (h *HNSWIndex) selectNeighbors(query []float32, candidates []uint64, M int)
 
 // Search finds the k most similar vectors to query
 func (h *HNSWIndex) Search(query []float32, k int) []SearchResult {
+	return h.SearchWithEf(query, k, 0)
+}
+
+// SearchWithEf is Search with a per-query candidate list size. Larger ef
+// trades latency for recall; ef <= 0 uses the configured EfSearch.
+func (h *HNSWIndex) SearchWithEf(query []float32, k, ef int) []SearchResult {
 	if len(query) != h.dimension {
 		return nil
 	}
@@ -345,7 +501,10 @@ func (h *HNSWIndex) Search(query []float32, k int) []SearchResult {
 	}
 
 	// Search at level 0 with ef neighbors
-	ef := max(h.config.EfSearch, k)
+	if ef <= 0 {
+		ef = h.config.EfSearch
+	}
+	ef = max(ef, k)
 	neighborIDs := h.searchLayer(query, currID, ef, 0)
 
 	// Score all neighbors
@@ -357,7 +516,7 @@ func (h *HNSWIndex) Search(query []float32, k int) []SearchResult {
 	for _, id := range neighborIDs {
 		node := h.nodes[id]
 		if node != nil {
-			scoredNeighbors = append(scoredNeighbors, scored{id: id, score: cosineSimilarity(query, node.vector)})
+			scoredNeighbors = append(scoredNeighbors, scored{id: id, score: h.score(query, node)})
 		}
 	}
 
@@ -440,6 +599,34 @@ func (h *HNSWIndex) Remove(id uint64) bool {
 	return true
 }
 
+// Similarity returns the cosine similarity between query and the stored vector for id
+func (h *HNSWIndex) Similarity(id uint64, query []float32) (float32, bool) {
+	if len(query) != h.dimension {
+		return 0, false
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	node, ok := h.nodes[id]
+	if !ok {
+		return 0, false
+	}
+	return h.score(query, node), true
+}
+
+// Vector returns a copy of the stored vector for id
+func (h *HNSWIndex) Vector(id uint64) ([]float32, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	node, ok := h.nodes[id]
+	if !ok {
+		return nil, false
+	}
+	return node.vectorCopy(), true
+}
+
 // reconnectNeighbors ensures affected neighbors maintain connectivity after node removal
 func (h *HNSWIndex) reconnectNeighbors(level int, affected map[uint64]bool, deletedID uint64) {
 	if len(affected) < 2 {
@@ -486,7 +673,7 @@ func (h *HNSWIndex) reconnectNeighbors(level int, affected map[uint64]bool, dele
 			}
 
 			// Select best candidates based on similarity
-			selected := h.selectNeighborsForReconnect(neighbor.vector, candidates, maxFriends-currentFriendCount)
+			selected := h.selectNeighborsForReconnect(neighbor.values(), candidates, maxFriends-currentFriendCount)
 
 			// Add bidirectional connections
 			for _, selectedID := range selected {
@@ -531,7 +718,7 @@ func (h *HNSWIndex) selectNeighborsForReconnect(query []float32, candidates []ui
 	for _, id := range candidates {
 		node := h.nodes[id]
 		if node != nil {
-			scoredCandidates = append(scoredCandidates, scored{id: id, score: cosineSimilarity(query, node.vector)})
+			scoredCandidates = append(scoredCandidates, scored{id: id, score: h.score(query, node)})
 		}
 	}
 
@@ -583,8 +770,8 @@ func (h *HNSWIndex) Save(w io.Writer) error {
 			return err
 		}
 
-		// Write vector
-		if err := binary.Write(w, binary.LittleEndian, node.vector); err != nil {
+		// Write vector (always float32, whatever the storage precision)
+		if err := binary.Write(w, binary.LittleEndian, node.values()); err != nil {
 			return err
 		}
 
@@ -635,6 +822,7 @@ func (h *HNSWIndex) Load(r io.Reader) error {
 	h.entryID = header.EntryID
 	h.maxLevel = int(header.MaxLevel)
 	h.nodes = make(map[uint64]*hnswNode, header.Count)
+	vector := make([]float32, h.dimension)
 
 	// Read each node
 	for i := 0; i < int(header.Count); i++ {
@@ -654,14 +842,14 @@ func (h *HNSWIndex) Load(r io.Reader) error {
 		node := &hnswNode{
 			id:      nodeHeader.ID,
 			level:   int(nodeHeader.Level),
-			vector:  make([]float32, h.dimension),
 			friends: make([][]uint64, nodeHeader.Level+1),
 		}
 
 		// Read vector
-		if err := binary.Read(r, binary.LittleEndian, node.vector); err != nil {
+		if err := binary.Read(r, binary.LittleEndian, vector); err != nil {
 			return fmt.Errorf("failed to read node %d vector: %w", i, err)
 		}
+		h.setVector(node, vector)
 
 		// Read friends for each level
 		for l := 0; l <= node.level; l++ {
@@ -696,9 +884,7 @@ func (h *HNSWIndex) GetAllVectors() map[uint64][]float32 {
 
 	result := make(map[uint64][]float32, len(h.nodes))
 	for id, node := range h.nodes {
-		copied := make([]float32, len(node.vector))
-		copy(copied, node.vector)
-		result[id] = copied
+		result[id] = node.vectorCopy()
 	}
 	return result
 }
@@ -717,9 +903,7 @@ func (h *HNSWIndex) Rebuild() error {
 	vectors := make(map[uint64][]float32, len(h.nodes))
 	for id, node := range h.nodes {
 		// Deep copy vector to prevent external modification during rebuild
-		vectorCopy := make([]float32, len(node.vector))
-		copy(vectorCopy, node.vector)
-		vectors[id] = vectorCopy
+		vectors[id] = node.vectorCopy()
 	}
 
 	// Create backup of current graph state for rollback
@@ -742,15 +926,7 @@ func (h *HNSWIndex) Rebuild() error {
 	for id, vector := range vectors {
 		// Create new node with random level
 		level := h.randomLevel()
-		node := &hnswNode{
-			id:      id,
-			vector:  vector,
-			level:   level,
-			friends: make([][]uint64, level+1),
-		}
-		for i := range node.friends {
-			node.friends[i] = make([]uint64, 0, h.config.M)
-		}
+		node := h.newNode(id, vector, level)
 
 		// First node
 		if len(h.nodes) == 0 {
@@ -780,7 +956,7 @@ func (h *HNSWIndex) Rebuild() error {
 				if neighbor != nil && l < len(neighbor.friends) {
 					neighbor.friends[l] = append(neighbor.friends[l], id)
 					if len(neighbor.friends[l]) > h.config.M*2 {
-						neighbor.friends[l] = h.selectNeighbors(neighbor.vector, neighbor.friends[l], h.config.M)
+						neighbor.friends[l] = h.selectNeighbors(neighbor.values(), neighbor.friends[l], h.config.M)
 					}
 				}
 			}
@@ -836,8 +1012,8 @@ func (h *HNSWIndex) validateIntegrityLocked() error {
 
 	for id, node := range h.nodes {
 		// Check vector dimension
-		if len(node.vector) != h.dimension {
-			return fmt.Errorf("node %d has wrong dimension: expected %d, got %d", id, h.dimension, len(node.vector))
+		if node.dim() != h.dimension {
+			return fmt.Errorf("node %d has wrong dimension: expected %d, got %d", id, h.dimension, node.dim())
 		}
 
 		// Check level consistency
@@ -951,7 +1127,13 @@ func (pq *priorityQueue) PopWorst() pqItem {
 		}
 	}
 	item := pq.items[minIdx]
-	pq.items = append(pq.items[:minIdx], pq.items[minIdx+1:]...)
+	n := len(pq.items) - 1
+	pq.items[minIdx] = pq.items[n]
+	pq.items = pq.items[:n]
+	if minIdx < n {
+		pq.bubbleUp(minIdx)
+		pq.bubbleDown(minIdx)
+	}
 	return item
 }
 
@@ -994,15 +1176,22 @@ func (pq *priorityQueue) bubbleDown(i int) {
 // =============================================================================
 
 type BruteForceIndex struct {
-	mu        sync.RWMutex
-	dimension int
-	vectors   map[uint64][]float32
+	mu         sync.RWMutex
+	dimension  int
+	vectors    map[uint64][]float32
+	similarity func(a, b []float32) float32
 }
 
 func NewBruteForceIndex(dimension int) *BruteForceIndex {
+	return NewBruteForceIndexWithMetric(dimension, types.DistanceCosine)
+}
+
+// NewBruteForceIndexWithMetric creates a brute force index scoring with metric
+func NewBruteForceIndexWithMetric(dimension int, metric types.DistanceMetric) *BruteForceIndex {
 	return &BruteForceIndex{
-		dimension: dimension,
-		vectors:   make(map[uint64][]float32),
+		dimension:  dimension,
+		vectors:    make(map[uint64][]float32),
+		similarity: SimilarityFunc(metric),
 	}
 }
 
@@ -1045,6 +1234,11 @@ func (b *BruteForceIndex) Remove(id uint64) bool {
 	return true
 }
 
+// SearchWithEf is an exact search; ef is ignored
+func (b *BruteForceIndex) SearchWithEf(query []float32, k, ef int) []SearchResult {
+	return b.Search(query, k)
+}
+
 func (b *BruteForceIndex) Search(query []float32, k int) []SearchResult {
 	if len(query) != b.dimension {
 		return nil
@@ -1060,7 +1254,7 @@ func (b *BruteForceIndex) Search(query []float32, k int) []SearchResult {
 
 	scoredVectors := make([]scored, 0, len(b.vectors))
 	for id, vec := range b.vectors {
-		scoredVectors = append(scoredVectors, scored{id: id, score: cosineSimilarity(query, vec)})
+		scoredVectors = append(scoredVectors, scored{id: id, score: b.similarity(query, vec)})
 	}
 
 	sort.Slice(scoredVectors, func(i, j int) bool {
@@ -1078,6 +1272,35 @@ func (b *BruteForceIndex) Search(query []float32, k int) []SearchResult {
 	return results
 }
 
+func (b *BruteForceIndex) Similarity(id uint64, query []float32) (float32, bool) {
+	if len(query) != b.dimension {
+		return 0, false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	vec, ok := b.vectors[id]
+	if !ok {
+		return 0, false
+	}
+	return b.similarity(query, vec), true
+}
+
+// Vector returns a copy of the stored vector for id
+func (b *BruteForceIndex) Vector(id uint64) ([]float32, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	vec, ok := b.vectors[id]
+	if !ok {
+		return nil, false
+	}
+	copied := make([]float32, len(vec))
+	copy(copied, vec)
+	return copied, true
+}
+
 func (b *BruteForceIndex) Save(w io.Writer) error {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
@@ -3,6 +3,8 @@ package vector
 
 import (
 	"testing"
+
+	"github.com/gibram-io/gibram/pkg/types"
 )
 
 // =============================================================================
@@ -261,6 +263,71 @@ func BenchmarkHNSWIndex_SaveLoad(b *testing.B) {
 	})
 }
 
+// =============================================================================
+// Distance Metric Benchmarks
+// =============================================================================
+
+// BenchmarkBruteForce_Metric_50K compares exhaustive search throughput per
+// metric on 50k vectors, isolating the cost of the scoring function.
+func BenchmarkBruteForce_Metric_50K(b *testing.B) {
+	if testing.Short() {
+		b.Skip("skipping in short mode")
+	}
+
+	dim := 128
+	vectors := make([][]float32, 50000)
+	for i := range vectors {
+		vectors[i] = normalizeVector(randomVector(dim))
+	}
+	query := normalizeVector(randomVector(dim))
+
+	for _, metric := range []types.DistanceMetric{types.DistanceCosine, types.DistanceDotProduct, types.DistanceEuclidean} {
+		idx := NewBruteForceIndexWithMetric(dim, metric)
+		for i, vec := range vectors {
+			mustAdd(b, idx, uint64(i), vec)
+		}
+
+		b.Run(string(metric), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				idx.Search(query, 10)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// Scaling Benchmarks
+// =============================================================================
+
+// BenchmarkSearch_Scaling compares HNSW with exact search as the index grows;
+// HNSW latency should grow far slower than the linear brute-force scan.
+func BenchmarkSearch_Scaling(b *testing.B) {
+	dim := 64
+	query := normalizeVector(randomVector(dim))
+
+	for _, size := range []int{1000, 10000, 50000} {
+		hnsw := NewHNSWIndex(dim, DefaultHNSWConfig())
+		exact := NewBruteForceIndex(dim)
+		for i := 0; i < size; i++ {
+			vec := normalizeVector(randomVector(dim))
+			mustAdd(b, hnsw, uint64(i), vec)
+			mustAdd(b, exact, uint64(i), vec)
+		}
+
+		b.Run(testName("hnsw", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				hnsw.Search(query, 10)
+			}
+		})
+		b.Run(testName("bruteforce", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				exact.Search(query, 10)
+			}
+		})
+	}
+}
+
 // discardWriter discards all written data
 type discardWriter struct{}
 
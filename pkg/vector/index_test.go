@@ -5,8 +5,11 @@ import (
 	"bytes"
 	"math"
 	"math/rand"
+	"reflect"
 	"sync"
 	"testing"
+
+	"github.com/gibram-io/gibram/pkg/types"
 )
 
 var (
@@ -156,6 +159,92 @@ func TestHNSWIndex_Search(t *testing.T) {
 	}
 }
 
+func TestIndex_Similarity(t *testing.T) {
+	for name, idx := range map[string]Index{
+		"hnsw":  NewHNSWIndex(3, DefaultHNSWConfig()),
+		"brute": NewBruteForceIndex(3),
+	} {
+		mustAdd(t, idx, 1, []float32{1, 0, 0})
+
+		if sim, ok := idx.Similarity(1, []float32{1, 0, 0}); !ok || math.Abs(float64(sim-1)) > 1e-5 {
+			t.Errorf("%s: Similarity(1) = %v, %v; want 1, true", name, sim, ok)
+		}
+		if sim, ok := idx.Similarity(1, []float32{0, 1, 0}); !ok || math.Abs(float64(sim)) > 1e-5 {
+			t.Errorf("%s: orthogonal Similarity(1) = %v, %v; want 0, true", name, sim, ok)
+		}
+		if _, ok := idx.Similarity(2, []float32{1, 0, 0}); ok {
+			t.Errorf("%s: Similarity of missing id should report false", name)
+		}
+		if _, ok := idx.Similarity(1, []float32{1, 0}); ok {
+			t.Errorf("%s: Similarity with wrong dimension should report false", name)
+		}
+
+		vec, ok := idx.Vector(1)
+		if !ok || !reflect.DeepEqual(vec, []float32{1, 0, 0}) {
+			t.Errorf("%s: Vector(1) = %v, %v; want [1 0 0], true", name, vec, ok)
+		}
+		vec[0] = 5
+		if again, _ := idx.Vector(1); again[0] != 1 {
+			t.Errorf("%s: Vector should return a copy", name)
+		}
+		if _, ok := idx.Vector(2); ok {
+			t.Errorf("%s: Vector of missing id should report false", name)
+		}
+	}
+}
+
+func TestSimilarityFunc(t *testing.T) {
+	a := []float32{3, 0}
+	b := []float32{1, 0}
+
+	tests := []struct {
+		metric types.DistanceMetric
+		want   float32
+	}{
+		{types.DistanceCosine, 1},
+		{"", 1},
+		{types.DistanceDotProduct, 3},
+		{types.DistanceEuclidean, 1.0 / 3},
+	}
+	for _, tt := range tests {
+		if got := SimilarityFunc(tt.metric)(a, b); math.Abs(float64(got-tt.want)) > 1e-5 {
+			t.Errorf("%q: similarity = %v, want %v", tt.metric, got, tt.want)
+		}
+	}
+}
+
+func TestIndex_DistanceMetricOrdering(t *testing.T) {
+	query := []float32{1, 0}
+	long := []float32{4, 3}    // cosine 0.8, dot 4, L2 ~4.24
+	short := []float32{1, 0.1} // cosine ~0.995, dot 1, L2 0.1
+
+	for _, tt := range []struct {
+		metric types.DistanceMetric
+		want   uint64
+	}{
+		{types.DistanceCosine, 2},
+		{types.DistanceDotProduct, 1},
+		{types.DistanceEuclidean, 2},
+	} {
+		config := DefaultHNSWConfig()
+		config.Metric = tt.metric
+		for name, idx := range map[string]Index{
+			"hnsw":  NewHNSWIndex(2, config),
+			"brute": NewBruteForceIndexWithMetric(2, tt.metric),
+		} {
+			mustAdd(t, idx, 1, long)
+			mustAdd(t, idx, 2, short)
+			results := idx.Search(query, 2)
+			if len(results) != 2 || results[0].ID != tt.want {
+				t.Errorf("%s/%s: expected id %d first, got %v", name, tt.metric, tt.want, results)
+			}
+			if len(results) == 2 && results[0].Similarity < results[1].Similarity {
+				t.Errorf("%s/%s: results not in descending order: %v", name, tt.metric, results)
+			}
+		}
+	}
+}
+
 func TestHNSWIndex_SearchEmpty(t *testing.T) {
 	config := DefaultHNSWConfig()
 	idx := NewHNSWIndex(4, config)
@@ -539,6 +628,52 @@ func TestHNSWIndex_SearchQuality(t *testing.T) {
 	}
 }
 
+func TestHNSWIndex_RecallAt10(t *testing.T) {
+	const (
+		dim     = 32
+		count   = 3000
+		queries = 50
+		k       = 10
+	)
+
+	hnsw := NewHNSWIndex(dim, DefaultHNSWConfig())
+	exact := NewBruteForceIndex(dim)
+	for i := 0; i < count; i++ {
+		vec := normalizeVector(randomVector(dim))
+		mustAdd(t, hnsw, uint64(i), vec)
+		mustAdd(t, exact, uint64(i), vec)
+	}
+
+	queryVecs := make([][]float32, queries)
+	for i := range queryVecs {
+		queryVecs[i] = normalizeVector(randomVector(dim))
+	}
+
+	recall := func(ef int) float64 {
+		hits := 0
+		for _, q := range queryVecs {
+			truth := make(map[uint64]bool, k)
+			for _, r := range exact.Search(q, k) {
+				truth[r.ID] = true
+			}
+			for _, r := range hnsw.SearchWithEf(q, k, ef) {
+				if truth[r.ID] {
+					hits++
+				}
+			}
+		}
+		return float64(hits) / float64(queries*k)
+	}
+
+	defaultRecall := recall(0)
+	if defaultRecall < 0.9 {
+		t.Errorf("recall@10 with default efSearch = %.3f, want >= 0.9", defaultRecall)
+	}
+	if highRecall := recall(400); highRecall < 0.97 || highRecall < defaultRecall {
+		t.Errorf("recall@10 with efSearch 400 = %.3f (default %.3f), want >= 0.97", highRecall, defaultRecall)
+	}
+}
+
 // =============================================================================
 // Additional Coverage Tests
 // =============================================================================
@@ -0,0 +1,308 @@
+// Package vector - Reduced-precision vector storage
+package vector
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+// Precision selects how an index stores vectors. Queries and the wire
+// format stay float32; stored vectors are converted when scored.
+type Precision string
+
+const (
+	// PrecisionFloat32 stores vectors as given (default)
+	PrecisionFloat32 Precision = "float32"
+
+	// PrecisionFloat16 stores IEEE 754 half floats, halving vector memory.
+	// Half floats keep 11 significant bits, so each component is off by at
+	// most 2^-11 (about 0.05%) of its value and cosine scores of normalized
+	// embeddings move by around 1e-4. That only reorders near-ties, so
+	// recall@10 stays within a fraction of a percent of float32. Components
+	// beyond ±65504 overflow to infinity; normalized embeddings never do.
+	PrecisionFloat16 Precision = "float16"
+
+	// PrecisionInt8 stores each vector as 8-bit codes scaled between its own
+	// minimum and maximum component, a quarter of float32 memory plus 8 bytes
+	// per vector. Components are off by at most half a step, (max-min)/510,
+	// which for normalized embeddings is a few thousandths, so scores move
+	// by around 1e-3 and recall@10 drops by a percent or two against float32.
+	PrecisionInt8 Precision = "int8"
+)
+
+// ParsePrecision validates a precision name (empty = float32)
+func ParsePrecision(s string) (Precision, error) {
+	switch Precision(s) {
+	case "", PrecisionFloat32:
+		return PrecisionFloat32, nil
+	case PrecisionFloat16:
+		return PrecisionFloat16, nil
+	case PrecisionInt8:
+		return PrecisionInt8, nil
+	default:
+		return "", fmt.Errorf("unknown vector precision %q (want float32, float16 or int8)", s)
+	}
+}
+
+// VectorBytes returns the memory one stored vector of dim components takes
+// at precision p
+func (p Precision) VectorBytes(dim int) int64 {
+	switch p {
+	case PrecisionFloat16:
+		return int64(dim) * 2
+	case PrecisionInt8:
+		return int64(dim) + 8 // codes plus lo and step
+	default:
+		return int64(dim) * 4
+	}
+}
+
+// float32ToHalf converts f to the nearest half float, ties to even
+func float32ToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int((bits>>23)&0xff) - 127 + 15
+	mant := bits & 0x7fffff
+
+	switch {
+	case (bits>>23)&0xff == 0xff: // Inf, NaN
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	case exp >= 0x1f: // too large
+		return sign | 0x7c00
+	case exp <= 0: // subnormal half, or zero
+		if exp < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint32(14 - exp)
+		half := uint16(mant >> shift)
+		rem, halfway := mant&(1<<shift-1), uint32(1)<<(shift-1)
+		if rem > halfway || (rem == halfway && half&1 == 1) {
+			half++
+		}
+		return sign | half
+	}
+
+	half := sign | uint16(exp)<<10 | uint16(mant>>13)
+	// A carry out of the mantissa correctly bumps the exponent
+	if rem := mant & 0x1fff; rem > 0x1000 || (rem == 0x1000 && half&1 == 1) {
+		half++
+	}
+	return half
+}
+
+// halfToFloat32 converts a half float to float32 exactly
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+
+	switch exp {
+	case 0x1f: // Inf, NaN
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// Subnormal: shift the leading bit into the implicit position
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+var (
+	halfTableOnce sync.Once
+	halfTable     []float32 // halfTable[h] = halfToFloat32(h)
+)
+
+// halfFloats returns the half-to-float32 lookup table, built on first use
+func halfFloats() []float32 {
+	halfTableOnce.Do(func() {
+		halfTable = make([]float32, 1<<16)
+		for i := range halfTable {
+			halfTable[i] = halfToFloat32(uint16(i))
+		}
+	})
+	return halfTable
+}
+
+// encodeHalf converts v to half floats
+func encodeHalf(v []float32) []uint16 {
+	out := make([]uint16, len(v))
+	for i, f := range v {
+		out[i] = float32ToHalf(f)
+	}
+	return out
+}
+
+// decodeHalf converts half floats back to float32
+func decodeHalf(v []uint16) []float32 {
+	table := halfFloats()
+	out := make([]float32, len(v))
+	for i, h := range v {
+		out[i] = table[h]
+	}
+	return out
+}
+
+// halfSimilarityFunc is SimilarityFunc for a float32 query against a
+// half-float stored vector, converting components as it goes
+func halfSimilarityFunc(metric types.DistanceMetric) func(a []float32, b []uint16) float32 {
+	switch metric {
+	case types.DistanceDotProduct:
+		return halfDotProduct
+	case types.DistanceEuclidean:
+		return halfEuclideanSimilarity
+	default:
+		return halfCosineSimilarity
+	}
+}
+
+func halfDotProduct(a []float32, b []uint16) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	table := halfFloats()
+	var dot float32
+	for i, h := range b {
+		dot += a[i] * table[h]
+	}
+	return dot
+}
+
+func halfCosineSimilarity(a []float32, b []uint16) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	table := halfFloats()
+	var dot, normA, normB float32
+	for i, h := range b {
+		f := table[h]
+		dot += a[i] * f
+		normA += a[i] * a[i]
+		normB += f * f
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (float32(math.Sqrt(float64(normA))) * float32(math.Sqrt(float64(normB))))
+}
+
+func halfEuclideanSimilarity(a []float32, b []uint16) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	table := halfFloats()
+	var sum float32
+	for i, h := range b {
+		diff := a[i] - table[h]
+		sum += diff * diff
+	}
+	return 1 / (1 + float32(math.Sqrt(float64(sum))))
+}
+
+// scalarCodes is a vector quantized to 8 bits per component: component i is
+// approximately lo + step*codes[i]
+type scalarCodes struct {
+	codes []uint8
+	lo    float32 // minimum component
+	step  float32 // (max - min) / 255
+}
+
+// encodeScalar quantizes v between its minimum and maximum component
+func encodeScalar(v []float32) *scalarCodes {
+	q := &scalarCodes{codes: make([]uint8, len(v))}
+	if len(v) == 0 {
+		return q
+	}
+	lo, hi := v[0], v[0]
+	for _, f := range v[1:] {
+		lo = min(lo, f)
+		hi = max(hi, f)
+	}
+	q.lo = lo
+	q.step = (hi - lo) / 255
+	if q.step == 0 {
+		return q
+	}
+	for i, f := range v {
+		code := math.Round(float64((f - lo) / q.step))
+		q.codes[i] = uint8(min(max(code, 0), 255))
+	}
+	return q
+}
+
+// decode dequantizes the codes back to float32
+func (q *scalarCodes) decode() []float32 {
+	out := make([]float32, len(q.codes))
+	for i, c := range q.codes {
+		out[i] = q.lo + q.step*float32(c)
+	}
+	return out
+}
+
+// scalarSimilarityFunc is SimilarityFunc for a float32 query against an
+// 8-bit quantized stored vector, dequantizing components as it goes
+func scalarSimilarityFunc(metric types.DistanceMetric) func(a []float32, b *scalarCodes) float32 {
+	switch metric {
+	case types.DistanceDotProduct:
+		return scalarDotProduct
+	case types.DistanceEuclidean:
+		return scalarEuclideanSimilarity
+	default:
+		return scalarCosineSimilarity
+	}
+}
+
+func scalarDotProduct(a []float32, b *scalarCodes) float32 {
+	if len(a) != len(b.codes) {
+		return 0
+	}
+	// sum(a[i] * (lo + step*c[i])) = lo*sum(a) + step*sum(a[i]*c[i])
+	var sumA, dot float32
+	for i, c := range b.codes {
+		sumA += a[i]
+		dot += a[i] * float32(c)
+	}
+	return b.lo*sumA + b.step*dot
+}
+
+func scalarCosineSimilarity(a []float32, b *scalarCodes) float32 {
+	if len(a) != len(b.codes) {
+		return 0
+	}
+	var dot, normA, normB float32
+	for i, c := range b.codes {
+		f := b.lo + b.step*float32(c)
+		dot += a[i] * f
+		normA += a[i] * a[i]
+		normB += f * f
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (float32(math.Sqrt(float64(normA))) * float32(math.Sqrt(float64(normB))))
+}
+
+func scalarEuclideanSimilarity(a []float32, b *scalarCodes) float32 {
+	if len(a) != len(b.codes) {
+		return 0
+	}
+	var sum float32
+	for i, c := range b.codes {
+		diff := a[i] - (b.lo + b.step*float32(c))
+		sum += diff * diff
+	}
+	return 1 / (1 + float32(math.Sqrt(float64(sum))))
+}
@@ -0,0 +1,300 @@
+// Package vector - reduced-precision storage tests
+package vector
+
+import (
+	"bytes"
+	"math"
+	"runtime"
+	"testing"
+
+	"github.com/gibram-io/gibram/pkg/types"
+)
+
+func TestHalfConversion_RoundTrip(t *testing.T) {
+	exact := []float32{0, 1, -1, 0.5, -2, 1024, 65504, -65504, 0.0009765625, 5.9604645e-08} // last: smallest subnormal
+	for _, f := range exact {
+		if got := halfToFloat32(float32ToHalf(f)); got != f {
+			t.Errorf("round trip of %g = %g, want exact", f, got)
+		}
+	}
+
+	if got := float32ToHalf(float32(math.Copysign(0, -1))); got != 0x8000 {
+		t.Errorf("float32ToHalf(-0) = %#x, want 0x8000", got)
+	}
+	if got := halfToFloat32(float32ToHalf(1e6)); !math.IsInf(float64(got), 1) {
+		t.Errorf("1e6 = %g, want +Inf", got)
+	}
+	if got := halfToFloat32(float32ToHalf(float32(math.NaN()))); !math.IsNaN(float64(got)) {
+		t.Errorf("NaN = %g, want NaN", got)
+	}
+	if got := halfToFloat32(float32ToHalf(1e-9)); got != 0 {
+		t.Errorf("1e-9 = %g, want underflow to 0", got)
+	}
+
+	// Within half a unit in the last place: relative 2^-11 for normal
+	// halves, absolute 2^-25 below 2^-14 where halves are subnormal
+	for i := 0; i < 10000; i++ {
+		f := (testRand.Float32()*2 - 1) * float32(math.Pow(2, float64(testRand.Intn(30)-14)))
+		got := halfToFloat32(float32ToHalf(f))
+		diff := math.Abs(float64(got - f))
+		if math.Abs(float64(f)) < math.Pow(2, -14) {
+			if diff > math.Pow(2, -25) {
+				t.Fatalf("round trip of subnormal %g = %g, error %g > 2^-25", f, got, diff)
+			}
+		} else if rel := diff / math.Abs(float64(f)); rel > 1.0/2048 {
+			t.Fatalf("round trip of %g = %g, relative error %g > 2^-11", f, got, rel)
+		}
+	}
+
+	// Ties round to even: 1+2^-11 is halfway between 1 and 1+2^-10
+	if got := halfToFloat32(float32ToHalf(1 + 1.0/2048)); got != 1 {
+		t.Errorf("1+2^-11 = %g, want 1", got)
+	}
+	if got := halfToFloat32(float32ToHalf(1 + 3.0/2048)); got != 1+2.0/1024 {
+		t.Errorf("1+3*2^-11 = %g, want 1+2^-9", got)
+	}
+}
+
+func TestParsePrecision(t *testing.T) {
+	for in, want := range map[string]Precision{"": PrecisionFloat32, "float32": PrecisionFloat32, "float16": PrecisionFloat16, "int8": PrecisionInt8} {
+		if got, err := ParsePrecision(in); err != nil || got != want {
+			t.Errorf("ParsePrecision(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePrecision("float8"); err == nil {
+		t.Error("ParsePrecision(float8) should fail")
+	}
+}
+
+func TestHNSWIndex_Float16(t *testing.T) {
+	const (
+		dim     = 32
+		count   = 2000
+		queries = 50
+		k       = 10
+	)
+
+	config := DefaultHNSWConfig()
+	config.Precision = PrecisionFloat16
+	half := NewHNSWIndex(dim, config)
+	exact := NewBruteForceIndex(dim)
+	vectors := make([][]float32, count)
+	for i := range vectors {
+		vectors[i] = normalizeVector(randomVector(dim))
+		mustAdd(t, half, uint64(i), vectors[i])
+		mustAdd(t, exact, uint64(i), vectors[i])
+	}
+
+	// Stored vectors and scores stay within half-float error
+	got, ok := half.Vector(7)
+	if !ok {
+		t.Fatal("Vector(7) not found")
+	}
+	for i := range got {
+		if math.Abs(float64(got[i]-vectors[7][i])) > 1e-3 {
+			t.Fatalf("Vector(7)[%d] = %g, want %g", i, got[i], vectors[7][i])
+		}
+	}
+	if sim, _ := half.Similarity(7, vectors[7]); math.Abs(float64(sim-1)) > 1e-3 {
+		t.Errorf("Similarity of a vector to itself = %g, want ~1", sim)
+	}
+
+	hits := 0
+	for q := 0; q < queries; q++ {
+		query := normalizeVector(randomVector(dim))
+		truth := make(map[uint64]bool, k)
+		for _, r := range exact.Search(query, k) {
+			truth[r.ID] = true
+		}
+		for _, r := range half.SearchWithEf(query, k, 400) {
+			if truth[r.ID] {
+				hits++
+			}
+		}
+	}
+	if recall := float64(hits) / float64(queries*k); recall < 0.97 {
+		t.Errorf("float16 recall@10 = %.3f, want >= 0.97", recall)
+	}
+
+	// Save writes float32; Load stores at the loading index's precision
+	var buf bytes.Buffer
+	if err := half.Save(&buf); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	loaded := NewHNSWIndex(dim, config)
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if loaded.VectorBytes() != half.VectorBytes() {
+		t.Errorf("loaded VectorBytes = %d, want %d", loaded.VectorBytes(), half.VectorBytes())
+	}
+	if err := loaded.Rebuild(); err != nil {
+		t.Errorf("Rebuild() error: %v", err)
+	}
+}
+
+func TestHNSWIndex_Float16Memory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds two 10k-vector indices")
+	}
+
+	const (
+		dim   = 256
+		count = 10000
+	)
+
+	vectors := make([][]float32, count)
+	for i := range vectors {
+		vectors[i] = randomVector(dim)
+	}
+
+	// build returns the index and the heap it retains
+	build := func(precision Precision) (*HNSWIndex, uint64) {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+
+		config := NewHNSWConfig(8, 16, 16)
+		config.Precision = precision
+		idx := NewHNSWIndex(dim, config)
+		for i, vec := range vectors {
+			mustAdd(t, idx, uint64(i), vec)
+		}
+
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		return idx, after.HeapAlloc - before.HeapAlloc
+	}
+
+	full, fullHeap := build(PrecisionFloat32)
+	half, halfHeap := build(PrecisionFloat16)
+	runtime.KeepAlive(full)
+
+	if full.VectorBytes() != count*dim*4 {
+		t.Errorf("float32 VectorBytes = %d, want %d", full.VectorBytes(), count*dim*4)
+	}
+	if half.VectorBytes()*2 != full.VectorBytes() {
+		t.Errorf("float16 VectorBytes = %d, want half of %d", half.VectorBytes(), full.VectorBytes())
+	}
+
+	// The graph is the same size either way, so the heap saving is the
+	// vector half less some slack for allocator noise
+	if saved := int64(fullHeap) - int64(halfHeap); saved < count*dim*2*8/10 {
+		t.Errorf("float16 heap %d vs float32 %d saves %d bytes, want at least %d", halfHeap, fullHeap, saved, count*dim*2*8/10)
+	}
+	runtime.KeepAlive(half)
+}
+
+func TestScalarCodes_RoundTrip(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		v := randomVector(64)
+		q := encodeScalar(v)
+		got := q.decode()
+		// Rounding to the nearest code is off by at most half a step
+		for j := range v {
+			if diff := math.Abs(float64(got[j] - v[j])); diff > float64(q.step)/2+1e-6 {
+				t.Fatalf("component %d: %g -> %g, error %g > half step %g", j, v[j], got[j], diff, q.step/2)
+			}
+		}
+	}
+
+	// A constant vector has a zero step and decodes exactly
+	if got := encodeScalar([]float32{0.5, 0.5, 0.5}).decode(); got[0] != 0.5 || got[2] != 0.5 {
+		t.Errorf("constant vector decodes to %v", got)
+	}
+
+	// The dot product kernel folds lo out of the sum; it must match scoring
+	// the decoded vector
+	a, b := randomVector(32), encodeScalar(randomVector(32))
+	if got, want := scalarDotProduct(a, b), SimilarityFunc(types.DistanceDotProduct)(a, b.decode()); math.Abs(float64(got-want)) > 1e-4 {
+		t.Errorf("scalarDotProduct = %g, want %g", got, want)
+	}
+}
+
+// clusteredVectors returns count normalized vectors drawn around clusters
+// random centers, the shape of real embedding collections
+func clusteredVectors(count, dim, clusters int, spread float32) [][]float32 {
+	centers := make([][]float32, clusters)
+	for i := range centers {
+		centers[i] = normalizeVector(randomVector(dim))
+	}
+	vectors := make([][]float32, count)
+	for i := range vectors {
+		noise := randomVector(dim)
+		v := make([]float32, dim)
+		for j := range v {
+			v[j] = centers[i%clusters][j] + spread*noise[j]
+		}
+		vectors[i] = normalizeVector(v)
+	}
+	return vectors
+}
+
+func TestHNSWIndex_Int8(t *testing.T) {
+	const (
+		dim     = 128
+		count   = 3000
+		queries = 100
+		k       = 10
+		ef      = 200
+	)
+
+	vectors := clusteredVectors(count+queries, dim, 20, 0.15)
+	idx := NewHNSWIndex(dim, DefaultHNSWConfig())
+	exact := NewBruteForceIndex(dim)
+	for i, vec := range vectors[:count] {
+		mustAdd(t, idx, uint64(i), vec)
+		mustAdd(t, exact, uint64(i), vec)
+	}
+
+	truth := make([]map[uint64]bool, queries)
+	for q := range truth {
+		truth[q] = make(map[uint64]bool, k)
+		for _, r := range exact.Search(vectors[count+q], k) {
+			truth[q][r.ID] = true
+		}
+	}
+	recall := func() float64 {
+		hits := 0
+		for q := range truth {
+			for _, r := range idx.SearchWithEf(vectors[count+q], k, ef) {
+				if truth[q][r.ID] {
+					hits++
+				}
+			}
+		}
+		return float64(hits) / float64(queries*k)
+	}
+
+	// Converting in place keeps the graph, so the recall difference is the
+	// quantization error alone
+	fullRecall, fullBytes := recall(), idx.VectorBytes()
+	idx.SetPrecision(PrecisionInt8)
+	int8Recall, int8Bytes := recall(), idx.VectorBytes()
+
+	ratio := float64(fullBytes) / float64(int8Bytes)
+	t.Logf("int8: %d -> %d vector bytes (%.2fx), recall@%d %.3f -> %.3f (drop %.3f)",
+		fullBytes, int8Bytes, ratio, k, fullRecall, int8Recall, fullRecall-int8Recall)
+
+	if int8Bytes != count*(dim+8) {
+		t.Errorf("int8 VectorBytes = %d, want %d", int8Bytes, count*(dim+8))
+	}
+	if ratio < 3.7 {
+		t.Errorf("compression ratio %.2f, want >= 3.7", ratio)
+	}
+	if drop := fullRecall - int8Recall; drop > 0.03 {
+		t.Errorf("int8 recall@%d %.3f is %.3f below float32 %.3f, want <= 0.03", k, int8Recall, drop, fullRecall)
+	}
+	if idx.Precision() != PrecisionInt8 {
+		t.Errorf("Precision() = %q, want int8", idx.Precision())
+	}
+
+	// New vectors follow the converted precision, and the graph stays sound
+	mustAdd(t, idx, count, vectors[count])
+	if got := idx.VectorBytes(); got != int8Bytes+dim+8 {
+		t.Errorf("VectorBytes after Add = %d, want %d", got, int8Bytes+dim+8)
+	}
+	if err := idx.ValidateIntegrity(); err != nil {
+		t.Errorf("ValidateIntegrity() error: %v", err)
+	}
+}
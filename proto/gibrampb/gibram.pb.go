@@ -46,39 +46,57 @@ const (
 	CommandType_CMD_LINK_TEXTUNIT_ENTITY CommandType = 23
 	CommandType_CMD_TEXTUNIT_RESPONSE    CommandType = 24
 	// Entity (30-39)
-	CommandType_CMD_ADD_ENTITY          CommandType = 30
-	CommandType_CMD_GET_ENTITY          CommandType = 31
-	CommandType_CMD_GET_ENTITY_BY_TITLE CommandType = 32
-	CommandType_CMD_UPDATE_ENTITY_DESC  CommandType = 33
-	CommandType_CMD_DELETE_ENTITY       CommandType = 34
-	CommandType_CMD_ENTITY_RESPONSE     CommandType = 35
+	CommandType_CMD_ADD_ENTITY             CommandType = 30
+	CommandType_CMD_GET_ENTITY             CommandType = 31
+	CommandType_CMD_GET_ENTITY_BY_TITLE    CommandType = 32
+	CommandType_CMD_UPDATE_ENTITY_DESC     CommandType = 33
+	CommandType_CMD_DELETE_ENTITY          CommandType = 34
+	CommandType_CMD_ENTITY_RESPONSE        CommandType = 35
+	CommandType_CMD_MERGE_ENTITIES         CommandType = 36
+	CommandType_CMD_UPDATE_ENTITY_TITLE    CommandType = 37
+	CommandType_CMD_GET_ENTITY_COMMUNITIES CommandType = 38 // payload: GetByIDRequest, response: CMD_COMMUNITIES_RESPONSE
 	// Relationship (40-49)
-	CommandType_CMD_ADD_RELATIONSHIP      CommandType = 40
-	CommandType_CMD_GET_RELATIONSHIP      CommandType = 41
-	CommandType_CMD_DELETE_RELATIONSHIP   CommandType = 42
-	CommandType_CMD_RELATIONSHIP_RESPONSE CommandType = 43
+	CommandType_CMD_ADD_RELATIONSHIP                 CommandType = 40
+	CommandType_CMD_GET_RELATIONSHIP                 CommandType = 41
+	CommandType_CMD_DELETE_RELATIONSHIP              CommandType = 42
+	CommandType_CMD_RELATIONSHIP_RESPONSE            CommandType = 43
+	CommandType_CMD_RELATIONSHIP_TYPE_STATS          CommandType = 44
+	CommandType_CMD_RELATIONSHIP_TYPE_STATS_RESPONSE CommandType = 45
+	CommandType_CMD_GET_NEIGHBORS                    CommandType = 46 // response: CMD_RELATIONSHIPS_RESPONSE
+	CommandType_CMD_SUBGRAPH                         CommandType = 47
+	CommandType_CMD_SUBGRAPH_RESPONSE                CommandType = 48
+	CommandType_CMD_UPDATE_RELATIONSHIP_WEIGHT       CommandType = 49
 	// Community (50-59)
 	CommandType_CMD_ADD_COMMUNITY        CommandType = 50
 	CommandType_CMD_GET_COMMUNITY        CommandType = 51
 	CommandType_CMD_DELETE_COMMUNITY     CommandType = 52
 	CommandType_CMD_COMPUTE_COMMUNITIES  CommandType = 53
 	CommandType_CMD_HIERARCHICAL_LEIDEN  CommandType = 54
-	CommandType_CMD_REBUILD_INDEX        CommandType = 55
+	CommandType_CMD_REBUILD_INDEX        CommandType = 55 // payload: RebuildIndexRequest (empty = synchronous)
 	CommandType_CMD_COMMUNITY_RESPONSE   CommandType = 56
 	CommandType_CMD_COMMUNITIES_RESPONSE CommandType = 57
+	CommandType_CMD_COMPUTE_PAGERANK     CommandType = 58 // payload: Empty
+	CommandType_CMD_PAGERANK_RESPONSE    CommandType = 59
 	// Query (60-69)
-	CommandType_CMD_QUERY            CommandType = 60
-	CommandType_CMD_QUERY_RESPONSE   CommandType = 61
-	CommandType_CMD_EXPLAIN          CommandType = 62
-	CommandType_CMD_EXPLAIN_RESPONSE CommandType = 63
+	CommandType_CMD_QUERY                        CommandType = 60
+	CommandType_CMD_QUERY_RESPONSE               CommandType = 61
+	CommandType_CMD_EXPLAIN                      CommandType = 62
+	CommandType_CMD_EXPLAIN_RESPONSE             CommandType = 63
+	CommandType_CMD_QUERY_STATS_SUMMARY          CommandType = 64
+	CommandType_CMD_QUERY_STATS_SUMMARY_RESPONSE CommandType = 65
+	CommandType_CMD_QUERY_STREAM                 CommandType = 66 // payload: QueryRequest; response: sequence of CMD_QUERY_STREAM_CHUNK
+	CommandType_CMD_QUERY_STREAM_CHUNK           CommandType = 67
 	// Session Management (70-79) - replaces per-object TTL
-	CommandType_CMD_LIST_SESSIONS         CommandType = 70
+	CommandType_CMD_LIST_SESSIONS         CommandType = 70 // payload: ListSessionsRequest (optional)
 	CommandType_CMD_DELETE_SESSION        CommandType = 71
 	CommandType_CMD_SESSION_INFO          CommandType = 72
 	CommandType_CMD_SET_SESSION_TTL       CommandType = 73
 	CommandType_CMD_TOUCH_SESSION         CommandType = 74
 	CommandType_CMD_SESSIONS_RESPONSE     CommandType = 75
 	CommandType_CMD_SESSION_INFO_RESPONSE CommandType = 76
+	CommandType_CMD_GRAPH_DIFF            CommandType = 77
+	CommandType_CMD_GRAPH_DIFF_RESPONSE   CommandType = 78
+	CommandType_CMD_FLUSH_SESSION         CommandType = 79 // payload: Empty; response: CMD_OK
 	// Bulk Operations (80-99)
 	CommandType_CMD_MSET_ENTITIES          CommandType = 80
 	CommandType_CMD_MGET_ENTITIES          CommandType = 81
@@ -94,6 +112,12 @@ const (
 	CommandType_CMD_RELATIONSHIPS_RESPONSE CommandType = 91
 	CommandType_CMD_LIST_ENTITIES          CommandType = 92
 	CommandType_CMD_LIST_RELATIONSHIPS     CommandType = 93
+	CommandType_CMD_MLINK_TEXTUNIT_ENTITY  CommandType = 94
+	CommandType_CMD_MLINK_RESPONSE         CommandType = 95
+	CommandType_CMD_MDELETE_TEXTUNITS      CommandType = 96 // payload: MDeleteRequest
+	CommandType_CMD_MDELETE_ENTITIES       CommandType = 97 // payload: MDeleteRequest
+	CommandType_CMD_MDELETE_RELATIONSHIPS  CommandType = 98 // payload: MDeleteRequest
+	CommandType_CMD_MDELETE_RESPONSE       CommandType = 99
 	// Pipeline (100-109)
 	CommandType_CMD_PIPELINE          CommandType = 100
 	CommandType_CMD_PIPELINE_RESPONSE CommandType = 101
@@ -109,8 +133,44 @@ const (
 	CommandType_CMD_WAL_STATUS      CommandType = 118
 	CommandType_CMD_BACKUP_RESPONSE CommandType = 119
 	// Auth (120-129)
-	CommandType_CMD_AUTH          CommandType = 120
-	CommandType_CMD_AUTH_RESPONSE CommandType = 121
+	CommandType_CMD_AUTH                CommandType = 120
+	CommandType_CMD_AUTH_RESPONSE       CommandType = 121
+	CommandType_CMD_ROTATE_KEY          CommandType = 122 // payload: RotateKeyRequest (admin)
+	CommandType_CMD_ROTATE_KEY_RESPONSE CommandType = 123
+	// Session Metadata (130-139)
+	CommandType_CMD_SET_SESSION_METADATA      CommandType = 130
+	CommandType_CMD_GET_SESSION_METADATA      CommandType = 131
+	CommandType_CMD_SESSION_METADATA_RESPONSE CommandType = 132
+	// Snapshot Streaming (140-149)
+	CommandType_CMD_STREAM_SNAPSHOT CommandType = 140 // response: sequence of CMD_SNAPSHOT_CHUNK
+	CommandType_CMD_UPLOAD_SNAPSHOT CommandType = 141 // one per SnapshotChunk; each acked with CMD_OK
+	CommandType_CMD_SNAPSHOT_CHUNK  CommandType = 142
+	// Server Stats (150-159)
+	CommandType_CMD_STATS          CommandType = 150
+	CommandType_CMD_STATS_RESPONSE CommandType = 151
+	// Graph Statistics (160-169)
+	CommandType_CMD_ENTITY_STATS          CommandType = 160 // payload: Empty
+	CommandType_CMD_ENTITY_STATS_RESPONSE CommandType = 161
+	CommandType_CMD_COUNT                 CommandType = 162
+	CommandType_CMD_COUNT_RESPONSE        CommandType = 163
+	// Vector Index (170-179)
+	CommandType_CMD_QUANTIZE_INDEX          CommandType = 170
+	CommandType_CMD_QUANTIZE_INDEX_RESPONSE CommandType = 171
+	CommandType_CMD_INDEX_STATUS            CommandType = 172 // payload: Empty
+	CommandType_CMD_INDEX_STATUS_RESPONSE   CommandType = 173
+	// Session Transfer (180-189)
+	CommandType_CMD_EXPORT_SESSION CommandType = 180 // response: sequence of CMD_SNAPSHOT_CHUNK
+	CommandType_CMD_IMPORT_SESSION CommandType = 181 // one per SnapshotChunk; each acked with CMD_OK
+	CommandType_CMD_MERGE_SESSION  CommandType = 182 // payload: MergeSessionRequest; response: CMD_OK
+	// External ID Lookups (190-199); payload: GetByExternalIDRequest
+	CommandType_CMD_GET_DOCUMENT_BY_EXTERNAL_ID     CommandType = 190 // response: CMD_DOCUMENT_RESPONSE
+	CommandType_CMD_GET_TEXTUNIT_BY_EXTERNAL_ID     CommandType = 191 // response: CMD_TEXTUNIT_RESPONSE
+	CommandType_CMD_GET_ENTITY_BY_EXTERNAL_ID       CommandType = 192 // response: CMD_ENTITY_RESPONSE
+	CommandType_CMD_GET_RELATIONSHIP_BY_EXTERNAL_ID CommandType = 193 // response: CMD_RELATIONSHIP_RESPONSE
+	// Query History (200-209)
+	CommandType_CMD_LIST_QUERIES          CommandType = 200 // payload: ListQueriesRequest (optional)
+	CommandType_CMD_LIST_QUERIES_RESPONSE CommandType = 201
+	CommandType_CMD_GET_QUERY_RESULT      CommandType = 202 // payload: GetByIDRequest (query ID); response: CMD_QUERY_RESPONSE
 )
 
 // Enum value maps for CommandType.
@@ -140,10 +200,19 @@ var (
 		33:  "CMD_UPDATE_ENTITY_DESC",
 		34:  "CMD_DELETE_ENTITY",
 		35:  "CMD_ENTITY_RESPONSE",
+		36:  "CMD_MERGE_ENTITIES",
+		37:  "CMD_UPDATE_ENTITY_TITLE",
+		38:  "CMD_GET_ENTITY_COMMUNITIES",
 		40:  "CMD_ADD_RELATIONSHIP",
 		41:  "CMD_GET_RELATIONSHIP",
 		42:  "CMD_DELETE_RELATIONSHIP",
 		43:  "CMD_RELATIONSHIP_RESPONSE",
+		44:  "CMD_RELATIONSHIP_TYPE_STATS",
+		45:  "CMD_RELATIONSHIP_TYPE_STATS_RESPONSE",
+		46:  "CMD_GET_NEIGHBORS",
+		47:  "CMD_SUBGRAPH",
+		48:  "CMD_SUBGRAPH_RESPONSE",
+		49:  "CMD_UPDATE_RELATIONSHIP_WEIGHT",
 		50:  "CMD_ADD_COMMUNITY",
 		51:  "CMD_GET_COMMUNITY",
 		52:  "CMD_DELETE_COMMUNITY",
@@ -152,10 +221,16 @@ var (
 		55:  "CMD_REBUILD_INDEX",
 		56:  "CMD_COMMUNITY_RESPONSE",
 		57:  "CMD_COMMUNITIES_RESPONSE",
+		58:  "CMD_COMPUTE_PAGERANK",
+		59:  "CMD_PAGERANK_RESPONSE",
 		60:  "CMD_QUERY",
 		61:  "CMD_QUERY_RESPONSE",
 		62:  "CMD_EXPLAIN",
 		63:  "CMD_EXPLAIN_RESPONSE",
+		64:  "CMD_QUERY_STATS_SUMMARY",
+		65:  "CMD_QUERY_STATS_SUMMARY_RESPONSE",
+		66:  "CMD_QUERY_STREAM",
+		67:  "CMD_QUERY_STREAM_CHUNK",
 		70:  "CMD_LIST_SESSIONS",
 		71:  "CMD_DELETE_SESSION",
 		72:  "CMD_SESSION_INFO",
@@ -163,6 +238,9 @@ var (
 		74:  "CMD_TOUCH_SESSION",
 		75:  "CMD_SESSIONS_RESPONSE",
 		76:  "CMD_SESSION_INFO_RESPONSE",
+		77:  "CMD_GRAPH_DIFF",
+		78:  "CMD_GRAPH_DIFF_RESPONSE",
+		79:  "CMD_FLUSH_SESSION",
 		80:  "CMD_MSET_ENTITIES",
 		81:  "CMD_MGET_ENTITIES",
 		82:  "CMD_MSET_DOCUMENTS",
@@ -177,6 +255,12 @@ var (
 		91:  "CMD_RELATIONSHIPS_RESPONSE",
 		92:  "CMD_LIST_ENTITIES",
 		93:  "CMD_LIST_RELATIONSHIPS",
+		94:  "CMD_MLINK_TEXTUNIT_ENTITY",
+		95:  "CMD_MLINK_RESPONSE",
+		96:  "CMD_MDELETE_TEXTUNITS",
+		97:  "CMD_MDELETE_ENTITIES",
+		98:  "CMD_MDELETE_RELATIONSHIPS",
+		99:  "CMD_MDELETE_RESPONSE",
 		100: "CMD_PIPELINE",
 		101: "CMD_PIPELINE_RESPONSE",
 		110: "CMD_BGSAVE",
@@ -191,83 +275,163 @@ var (
 		119: "CMD_BACKUP_RESPONSE",
 		120: "CMD_AUTH",
 		121: "CMD_AUTH_RESPONSE",
+		122: "CMD_ROTATE_KEY",
+		123: "CMD_ROTATE_KEY_RESPONSE",
+		130: "CMD_SET_SESSION_METADATA",
+		131: "CMD_GET_SESSION_METADATA",
+		132: "CMD_SESSION_METADATA_RESPONSE",
+		140: "CMD_STREAM_SNAPSHOT",
+		141: "CMD_UPLOAD_SNAPSHOT",
+		142: "CMD_SNAPSHOT_CHUNK",
+		150: "CMD_STATS",
+		151: "CMD_STATS_RESPONSE",
+		160: "CMD_ENTITY_STATS",
+		161: "CMD_ENTITY_STATS_RESPONSE",
+		162: "CMD_COUNT",
+		163: "CMD_COUNT_RESPONSE",
+		170: "CMD_QUANTIZE_INDEX",
+		171: "CMD_QUANTIZE_INDEX_RESPONSE",
+		172: "CMD_INDEX_STATUS",
+		173: "CMD_INDEX_STATUS_RESPONSE",
+		180: "CMD_EXPORT_SESSION",
+		181: "CMD_IMPORT_SESSION",
+		182: "CMD_MERGE_SESSION",
+		190: "CMD_GET_DOCUMENT_BY_EXTERNAL_ID",
+		191: "CMD_GET_TEXTUNIT_BY_EXTERNAL_ID",
+		192: "CMD_GET_ENTITY_BY_EXTERNAL_ID",
+		193: "CMD_GET_RELATIONSHIP_BY_EXTERNAL_ID",
+		200: "CMD_LIST_QUERIES",
+		201: "CMD_LIST_QUERIES_RESPONSE",
+		202: "CMD_GET_QUERY_RESULT",
 	}
 	CommandType_value = map[string]int32{
-		"CMD_UNKNOWN":                0,
-		"CMD_PING":                   1,
-		"CMD_PONG":                   2,
-		"CMD_INFO":                   3,
-		"CMD_INFO_RESPONSE":          4,
-		"CMD_ERROR":                  5,
-		"CMD_OK":                     6,
-		"CMD_HEALTH":                 7,
-		"CMD_HEALTH_RESPONSE":        8,
-		"CMD_ADD_DOCUMENT":           10,
-		"CMD_GET_DOCUMENT":           11,
-		"CMD_DELETE_DOCUMENT":        12,
-		"CMD_DOCUMENT_RESPONSE":      13,
-		"CMD_ADD_TEXTUNIT":           20,
-		"CMD_GET_TEXTUNIT":           21,
-		"CMD_DELETE_TEXTUNIT":        22,
-		"CMD_LINK_TEXTUNIT_ENTITY":   23,
-		"CMD_TEXTUNIT_RESPONSE":      24,
-		"CMD_ADD_ENTITY":             30,
-		"CMD_GET_ENTITY":             31,
-		"CMD_GET_ENTITY_BY_TITLE":    32,
-		"CMD_UPDATE_ENTITY_DESC":     33,
-		"CMD_DELETE_ENTITY":          34,
-		"CMD_ENTITY_RESPONSE":        35,
-		"CMD_ADD_RELATIONSHIP":       40,
-		"CMD_GET_RELATIONSHIP":       41,
-		"CMD_DELETE_RELATIONSHIP":    42,
-		"CMD_RELATIONSHIP_RESPONSE":  43,
-		"CMD_ADD_COMMUNITY":          50,
-		"CMD_GET_COMMUNITY":          51,
-		"CMD_DELETE_COMMUNITY":       52,
-		"CMD_COMPUTE_COMMUNITIES":    53,
-		"CMD_HIERARCHICAL_LEIDEN":    54,
-		"CMD_REBUILD_INDEX":          55,
-		"CMD_COMMUNITY_RESPONSE":     56,
-		"CMD_COMMUNITIES_RESPONSE":   57,
-		"CMD_QUERY":                  60,
-		"CMD_QUERY_RESPONSE":         61,
-		"CMD_EXPLAIN":                62,
-		"CMD_EXPLAIN_RESPONSE":       63,
-		"CMD_LIST_SESSIONS":          70,
-		"CMD_DELETE_SESSION":         71,
-		"CMD_SESSION_INFO":           72,
-		"CMD_SET_SESSION_TTL":        73,
-		"CMD_TOUCH_SESSION":          74,
-		"CMD_SESSIONS_RESPONSE":      75,
-		"CMD_SESSION_INFO_RESPONSE":  76,
-		"CMD_MSET_ENTITIES":          80,
-		"CMD_MGET_ENTITIES":          81,
-		"CMD_MSET_DOCUMENTS":         82,
-		"CMD_MGET_DOCUMENTS":         83,
-		"CMD_MSET_TEXTUNITS":         84,
-		"CMD_MGET_TEXTUNITS":         85,
-		"CMD_MSET_RELATIONSHIPS":     86,
-		"CMD_MGET_RELATIONSHIPS":     87,
-		"CMD_ENTITIES_RESPONSE":      88,
-		"CMD_DOCUMENTS_RESPONSE":     89,
-		"CMD_TEXTUNITS_RESPONSE":     90,
-		"CMD_RELATIONSHIPS_RESPONSE": 91,
-		"CMD_LIST_ENTITIES":          92,
-		"CMD_LIST_RELATIONSHIPS":     93,
-		"CMD_PIPELINE":               100,
-		"CMD_PIPELINE_RESPONSE":      101,
-		"CMD_BGSAVE":                 110,
-		"CMD_SAVE":                   111,
-		"CMD_LASTSAVE":               112,
-		"CMD_BGRESTORE":              113,
-		"CMD_BACKUP_STATUS":          114,
-		"CMD_WAL_CHECKPOINT":         115,
-		"CMD_WAL_TRUNCATE":           116,
-		"CMD_WAL_ROTATE":             117,
-		"CMD_WAL_STATUS":             118,
-		"CMD_BACKUP_RESPONSE":        119,
-		"CMD_AUTH":                   120,
-		"CMD_AUTH_RESPONSE":          121,
+		"CMD_UNKNOWN":                          0,
+		"CMD_PING":                             1,
+		"CMD_PONG":                             2,
+		"CMD_INFO":                             3,
+		"CMD_INFO_RESPONSE":                    4,
+		"CMD_ERROR":                            5,
+		"CMD_OK":                               6,
+		"CMD_HEALTH":                           7,
+		"CMD_HEALTH_RESPONSE":                  8,
+		"CMD_ADD_DOCUMENT":                     10,
+		"CMD_GET_DOCUMENT":                     11,
+		"CMD_DELETE_DOCUMENT":                  12,
+		"CMD_DOCUMENT_RESPONSE":                13,
+		"CMD_ADD_TEXTUNIT":                     20,
+		"CMD_GET_TEXTUNIT":                     21,
+		"CMD_DELETE_TEXTUNIT":                  22,
+		"CMD_LINK_TEXTUNIT_ENTITY":             23,
+		"CMD_TEXTUNIT_RESPONSE":                24,
+		"CMD_ADD_ENTITY":                       30,
+		"CMD_GET_ENTITY":                       31,
+		"CMD_GET_ENTITY_BY_TITLE":              32,
+		"CMD_UPDATE_ENTITY_DESC":               33,
+		"CMD_DELETE_ENTITY":                    34,
+		"CMD_ENTITY_RESPONSE":                  35,
+		"CMD_MERGE_ENTITIES":                   36,
+		"CMD_UPDATE_ENTITY_TITLE":              37,
+		"CMD_GET_ENTITY_COMMUNITIES":           38,
+		"CMD_ADD_RELATIONSHIP":                 40,
+		"CMD_GET_RELATIONSHIP":                 41,
+		"CMD_DELETE_RELATIONSHIP":              42,
+		"CMD_RELATIONSHIP_RESPONSE":            43,
+		"CMD_RELATIONSHIP_TYPE_STATS":          44,
+		"CMD_RELATIONSHIP_TYPE_STATS_RESPONSE": 45,
+		"CMD_GET_NEIGHBORS":                    46,
+		"CMD_SUBGRAPH":                         47,
+		"CMD_SUBGRAPH_RESPONSE":                48,
+		"CMD_UPDATE_RELATIONSHIP_WEIGHT":       49,
+		"CMD_ADD_COMMUNITY":                    50,
+		"CMD_GET_COMMUNITY":                    51,
+		"CMD_DELETE_COMMUNITY":                 52,
+		"CMD_COMPUTE_COMMUNITIES":              53,
+		"CMD_HIERARCHICAL_LEIDEN":              54,
+		"CMD_REBUILD_INDEX":                    55,
+		"CMD_COMMUNITY_RESPONSE":               56,
+		"CMD_COMMUNITIES_RESPONSE":             57,
+		"CMD_COMPUTE_PAGERANK":                 58,
+		"CMD_PAGERANK_RESPONSE":                59,
+		"CMD_QUERY":                            60,
+		"CMD_QUERY_RESPONSE":                   61,
+		"CMD_EXPLAIN":                          62,
+		"CMD_EXPLAIN_RESPONSE":                 63,
+		"CMD_QUERY_STATS_SUMMARY":              64,
+		"CMD_QUERY_STATS_SUMMARY_RESPONSE":     65,
+		"CMD_QUERY_STREAM":                     66,
+		"CMD_QUERY_STREAM_CHUNK":               67,
+		"CMD_LIST_SESSIONS":                    70,
+		"CMD_DELETE_SESSION":                   71,
+		"CMD_SESSION_INFO":                     72,
+		"CMD_SET_SESSION_TTL":                  73,
+		"CMD_TOUCH_SESSION":                    74,
+		"CMD_SESSIONS_RESPONSE":                75,
+		"CMD_SESSION_INFO_RESPONSE":            76,
+		"CMD_GRAPH_DIFF":                       77,
+		"CMD_GRAPH_DIFF_RESPONSE":              78,
+		"CMD_FLUSH_SESSION":                    79,
+		"CMD_MSET_ENTITIES":                    80,
+		"CMD_MGET_ENTITIES":                    81,
+		"CMD_MSET_DOCUMENTS":                   82,
+		"CMD_MGET_DOCUMENTS":                   83,
+		"CMD_MSET_TEXTUNITS":                   84,
+		"CMD_MGET_TEXTUNITS":                   85,
+		"CMD_MSET_RELATIONSHIPS":               86,
+		"CMD_MGET_RELATIONSHIPS":               87,
+		"CMD_ENTITIES_RESPONSE":                88,
+		"CMD_DOCUMENTS_RESPONSE":               89,
+		"CMD_TEXTUNITS_RESPONSE":               90,
+		"CMD_RELATIONSHIPS_RESPONSE":           91,
+		"CMD_LIST_ENTITIES":                    92,
+		"CMD_LIST_RELATIONSHIPS":               93,
+		"CMD_MLINK_TEXTUNIT_ENTITY":            94,
+		"CMD_MLINK_RESPONSE":                   95,
+		"CMD_MDELETE_TEXTUNITS":                96,
+		"CMD_MDELETE_ENTITIES":                 97,
+		"CMD_MDELETE_RELATIONSHIPS":            98,
+		"CMD_MDELETE_RESPONSE":                 99,
+		"CMD_PIPELINE":                         100,
+		"CMD_PIPELINE_RESPONSE":                101,
+		"CMD_BGSAVE":                           110,
+		"CMD_SAVE":                             111,
+		"CMD_LASTSAVE":                         112,
+		"CMD_BGRESTORE":                        113,
+		"CMD_BACKUP_STATUS":                    114,
+		"CMD_WAL_CHECKPOINT":                   115,
+		"CMD_WAL_TRUNCATE":                     116,
+		"CMD_WAL_ROTATE":                       117,
+		"CMD_WAL_STATUS":                       118,
+		"CMD_BACKUP_RESPONSE":                  119,
+		"CMD_AUTH":                             120,
+		"CMD_AUTH_RESPONSE":                    121,
+		"CMD_ROTATE_KEY":                       122,
+		"CMD_ROTATE_KEY_RESPONSE":              123,
+		"CMD_SET_SESSION_METADATA":             130,
+		"CMD_GET_SESSION_METADATA":             131,
+		"CMD_SESSION_METADATA_RESPONSE":        132,
+		"CMD_STREAM_SNAPSHOT":                  140,
+		"CMD_UPLOAD_SNAPSHOT":                  141,
+		"CMD_SNAPSHOT_CHUNK":                   142,
+		"CMD_STATS":                            150,
+		"CMD_STATS_RESPONSE":                   151,
+		"CMD_ENTITY_STATS":                     160,
+		"CMD_ENTITY_STATS_RESPONSE":            161,
+		"CMD_COUNT":                            162,
+		"CMD_COUNT_RESPONSE":                   163,
+		"CMD_QUANTIZE_INDEX":                   170,
+		"CMD_QUANTIZE_INDEX_RESPONSE":          171,
+		"CMD_INDEX_STATUS":                     172,
+		"CMD_INDEX_STATUS_RESPONSE":            173,
+		"CMD_EXPORT_SESSION":                   180,
+		"CMD_IMPORT_SESSION":                   181,
+		"CMD_MERGE_SESSION":                    182,
+		"CMD_GET_DOCUMENT_BY_EXTERNAL_ID":      190,
+		"CMD_GET_TEXTUNIT_BY_EXTERNAL_ID":      191,
+		"CMD_GET_ENTITY_BY_EXTERNAL_ID":        192,
+		"CMD_GET_RELATIONSHIP_BY_EXTERNAL_ID":  193,
+		"CMD_LIST_QUERIES":                     200,
+		"CMD_LIST_QUERIES_RESPONSE":            201,
+		"CMD_GET_QUERY_RESULT":                 202,
 	}
 )
 
@@ -298,6 +462,104 @@ func (CommandType) EnumDescriptor() ([]byte, []int) {
 	return file_proto_gibram_proto_rawDescGZIP(), []int{0}
 }
 
+type EdgeDirection int32
+
+const (
+	EdgeDirection_EDGE_DIRECTION_BOTH     EdgeDirection = 0
+	EdgeDirection_EDGE_DIRECTION_OUTGOING EdgeDirection = 1
+	EdgeDirection_EDGE_DIRECTION_INCOMING EdgeDirection = 2
+)
+
+// Enum value maps for EdgeDirection.
+var (
+	EdgeDirection_name = map[int32]string{
+		0: "EDGE_DIRECTION_BOTH",
+		1: "EDGE_DIRECTION_OUTGOING",
+		2: "EDGE_DIRECTION_INCOMING",
+	}
+	EdgeDirection_value = map[string]int32{
+		"EDGE_DIRECTION_BOTH":     0,
+		"EDGE_DIRECTION_OUTGOING": 1,
+		"EDGE_DIRECTION_INCOMING": 2,
+	}
+)
+
+func (x EdgeDirection) Enum() *EdgeDirection {
+	p := new(EdgeDirection)
+	*p = x
+	return p
+}
+
+func (x EdgeDirection) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (EdgeDirection) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_gibram_proto_enumTypes[1].Descriptor()
+}
+
+func (EdgeDirection) Type() protoreflect.EnumType {
+	return &file_proto_gibram_proto_enumTypes[1]
+}
+
+func (x EdgeDirection) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use EdgeDirection.Descriptor instead.
+func (EdgeDirection) EnumDescriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{1}
+}
+
+type MergeConflictPolicy int32
+
+const (
+	MergeConflictPolicy_MERGE_CONFLICT_REJECT      MergeConflictPolicy = 0 // fail the merge without changes
+	MergeConflictPolicy_MERGE_CONFLICT_SKIP        MergeConflictPolicy = 1 // leave clashing records out
+	MergeConflictPolicy_MERGE_CONFLICT_DEDUP_TITLE MergeConflictPolicy = 2 // fold same-title entities into the destination's; skip other clashes
+)
+
+// Enum value maps for MergeConflictPolicy.
+var (
+	MergeConflictPolicy_name = map[int32]string{
+		0: "MERGE_CONFLICT_REJECT",
+		1: "MERGE_CONFLICT_SKIP",
+		2: "MERGE_CONFLICT_DEDUP_TITLE",
+	}
+	MergeConflictPolicy_value = map[string]int32{
+		"MERGE_CONFLICT_REJECT":      0,
+		"MERGE_CONFLICT_SKIP":        1,
+		"MERGE_CONFLICT_DEDUP_TITLE": 2,
+	}
+)
+
+func (x MergeConflictPolicy) Enum() *MergeConflictPolicy {
+	p := new(MergeConflictPolicy)
+	*p = x
+	return p
+}
+
+func (x MergeConflictPolicy) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (MergeConflictPolicy) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_gibram_proto_enumTypes[2].Descriptor()
+}
+
+func (MergeConflictPolicy) Type() protoreflect.EnumType {
+	return &file_proto_gibram_proto_enumTypes[2]
+}
+
+func (x MergeConflictPolicy) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use MergeConflictPolicy.Descriptor instead.
+func (MergeConflictPolicy) EnumDescriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{2}
+}
+
 type Envelope struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Version       uint32                 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`                                           // protocol version (1)
@@ -305,6 +567,7 @@ type Envelope struct {
 	CmdType       CommandType            `protobuf:"varint,3,opt,name=cmd_type,json=cmdType,proto3,enum=gibram.v1.CommandType" json:"cmd_type,omitempty"` // command type
 	Payload       []byte                 `protobuf:"bytes,4,opt,name=payload,proto3" json:"payload,omitempty"`                                            // serialized command/response
 	SessionId     string                 `protobuf:"bytes,5,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`                       // mandatory session identifier
+	Traceparent   string                 `protobuf:"bytes,6,opt,name=traceparent,proto3" json:"traceparent,omitempty"`                                    // W3C trace context of the caller's span (optional)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -374,6 +637,13 @@ func (x *Envelope) GetSessionId() string {
 	return ""
 }
 
+func (x *Envelope) GetTraceparent() string {
+	if x != nil {
+		return x.Traceparent
+	}
+	return ""
+}
+
 type Empty struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -515,7 +785,8 @@ type InfoResponse struct {
 	RelationshipCount uint64                 `protobuf:"varint,5,opt,name=relationship_count,json=relationshipCount,proto3" json:"relationship_count,omitempty"`
 	CommunityCount    uint64                 `protobuf:"varint,6,opt,name=community_count,json=communityCount,proto3" json:"community_count,omitempty"`
 	VectorDim         int32                  `protobuf:"varint,7,opt,name=vector_dim,json=vectorDim,proto3" json:"vector_dim,omitempty"`
-	SessionCount      int32                  `protobuf:"varint,8,opt,name=session_count,json=sessionCount,proto3" json:"session_count,omitempty"` // number of active sessions
+	SessionCount      int32                  `protobuf:"varint,8,opt,name=session_count,json=sessionCount,proto3" json:"session_count,omitempty"`            // number of active sessions
+	DisabledCommands  []string               `protobuf:"bytes,9,rep,name=disabled_commands,json=disabledCommands,proto3" json:"disabled_commands,omitempty"` // commands rejected by server policy
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
@@ -606,6 +877,13 @@ func (x *InfoResponse) GetSessionCount() int32 {
 	return 0
 }
 
+func (x *InfoResponse) GetDisabledCommands() []string {
+	if x != nil {
+		return x.DisabledCommands
+	}
+	return nil
+}
+
 type SessionInfo struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	SessionId         string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
@@ -618,6 +896,9 @@ type SessionInfo struct {
 	EntityCount       uint64                 `protobuf:"varint,8,opt,name=entity_count,json=entityCount,proto3" json:"entity_count,omitempty"`
 	RelationshipCount uint64                 `protobuf:"varint,9,opt,name=relationship_count,json=relationshipCount,proto3" json:"relationship_count,omitempty"`
 	CommunityCount    uint64                 `protobuf:"varint,10,opt,name=community_count,json=communityCount,proto3" json:"community_count,omitempty"`
+	Metadata          map[string]string      `protobuf:"bytes,11,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	CommunitiesDirty  bool                   `protobuf:"varint,12,opt,name=communities_dirty,json=communitiesDirty,proto3" json:"communities_dirty,omitempty"` // edges changed since communities were last computed
+	MemoryBytes       int64                  `protobuf:"varint,13,opt,name=memory_bytes,json=memoryBytes,proto3" json:"memory_bytes,omitempty"`                // approximate bytes of record text and vectors
 	unknownFields     protoimpl.UnknownFields
 	sizeCache         protoimpl.SizeCache
 }
@@ -722,16 +1003,106 @@ func (x *SessionInfo) GetCommunityCount() uint64 {
 	return 0
 }
 
+func (x *SessionInfo) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *SessionInfo) GetCommunitiesDirty() bool {
+	if x != nil {
+		return x.CommunitiesDirty
+	}
+	return false
+}
+
+func (x *SessionInfo) GetMemoryBytes() int64 {
+	if x != nil {
+		return x.MemoryBytes
+	}
+	return 0
+}
+
+type ListSessionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`                                  // only sessions whose ID starts with this (empty = all)
+	CreatedAfter  int64                  `protobuf:"varint,2,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"` // only sessions created after this unix time in nanoseconds (0 = all)
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`                                   // max sessions to return (0 = all)
+	Cursor        string                 `protobuf:"bytes,4,opt,name=cursor,proto3" json:"cursor,omitempty"`                                  // last seen session ID (empty = start)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListSessionsRequest) Reset() {
+	*x = ListSessionsRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListSessionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSessionsRequest) ProtoMessage() {}
+
+func (x *ListSessionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSessionsRequest.ProtoReflect.Descriptor instead.
+func (*ListSessionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListSessionsRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ListSessionsRequest) GetCreatedAfter() int64 {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return 0
+}
+
+func (x *ListSessionsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListSessionsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
 type ListSessionsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Sessions      []*SessionInfo         `protobuf:"bytes,1,rep,name=sessions,proto3" json:"sessions,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // empty = no more
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListSessionsResponse) Reset() {
 	*x = ListSessionsResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[6]
+	mi := &file_proto_gibram_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -743,7 +1114,7 @@ func (x *ListSessionsResponse) String() string {
 func (*ListSessionsResponse) ProtoMessage() {}
 
 func (x *ListSessionsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[6]
+	mi := &file_proto_gibram_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -756,7 +1127,7 @@ func (x *ListSessionsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListSessionsResponse.ProtoReflect.Descriptor instead.
 func (*ListSessionsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{6}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ListSessionsResponse) GetSessions() []*SessionInfo {
@@ -766,6 +1137,13 @@ func (x *ListSessionsResponse) GetSessions() []*SessionInfo {
 	return nil
 }
 
+func (x *ListSessionsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
 type DeleteSessionRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
@@ -775,7 +1153,7 @@ type DeleteSessionRequest struct {
 
 func (x *DeleteSessionRequest) Reset() {
 	*x = DeleteSessionRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[7]
+	mi := &file_proto_gibram_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -787,7 +1165,7 @@ func (x *DeleteSessionRequest) String() string {
 func (*DeleteSessionRequest) ProtoMessage() {}
 
 func (x *DeleteSessionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[7]
+	mi := &file_proto_gibram_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -800,7 +1178,7 @@ func (x *DeleteSessionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteSessionRequest.ProtoReflect.Descriptor instead.
 func (*DeleteSessionRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{7}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *DeleteSessionRequest) GetSessionId() string {
@@ -819,7 +1197,7 @@ type SessionInfoRequest struct {
 
 func (x *SessionInfoRequest) Reset() {
 	*x = SessionInfoRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[8]
+	mi := &file_proto_gibram_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -831,7 +1209,7 @@ func (x *SessionInfoRequest) String() string {
 func (*SessionInfoRequest) ProtoMessage() {}
 
 func (x *SessionInfoRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[8]
+	mi := &file_proto_gibram_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -844,7 +1222,7 @@ func (x *SessionInfoRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SessionInfoRequest.ProtoReflect.Descriptor instead.
 func (*SessionInfoRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{8}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *SessionInfoRequest) GetSessionId() string {
@@ -865,7 +1243,7 @@ type SetSessionTTLRequest struct {
 
 func (x *SetSessionTTLRequest) Reset() {
 	*x = SetSessionTTLRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[9]
+	mi := &file_proto_gibram_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -877,7 +1255,7 @@ func (x *SetSessionTTLRequest) String() string {
 func (*SetSessionTTLRequest) ProtoMessage() {}
 
 func (x *SetSessionTTLRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[9]
+	mi := &file_proto_gibram_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -890,7 +1268,7 @@ func (x *SetSessionTTLRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SetSessionTTLRequest.ProtoReflect.Descriptor instead.
 func (*SetSessionTTLRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{9}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *SetSessionTTLRequest) GetSessionId() string {
@@ -923,7 +1301,7 @@ type TouchSessionRequest struct {
 
 func (x *TouchSessionRequest) Reset() {
 	*x = TouchSessionRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[10]
+	mi := &file_proto_gibram_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -935,7 +1313,7 @@ func (x *TouchSessionRequest) String() string {
 func (*TouchSessionRequest) ProtoMessage() {}
 
 func (x *TouchSessionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[10]
+	mi := &file_proto_gibram_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -948,7 +1326,7 @@ func (x *TouchSessionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TouchSessionRequest.ProtoReflect.Descriptor instead.
 func (*TouchSessionRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{10}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *TouchSessionRequest) GetSessionId() string {
@@ -958,6 +1336,102 @@ func (x *TouchSessionRequest) GetSessionId() string {
 	return ""
 }
 
+type SetSessionMetadataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      map[string]string      `protobuf:"bytes,1,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // empty value removes the key
+	Replace       bool                   `protobuf:"varint,2,opt,name=replace,proto3" json:"replace,omitempty"`                                                                            // clear existing metadata first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetSessionMetadataRequest) Reset() {
+	*x = SetSessionMetadataRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetSessionMetadataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetSessionMetadataRequest) ProtoMessage() {}
+
+func (x *SetSessionMetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetSessionMetadataRequest.ProtoReflect.Descriptor instead.
+func (*SetSessionMetadataRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SetSessionMetadataRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *SetSessionMetadataRequest) GetReplace() bool {
+	if x != nil {
+		return x.Replace
+	}
+	return false
+}
+
+type SessionMetadataResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      map[string]string      `protobuf:"bytes,1,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SessionMetadataResponse) Reset() {
+	*x = SessionMetadataResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SessionMetadataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SessionMetadataResponse) ProtoMessage() {}
+
+func (x *SessionMetadataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SessionMetadataResponse.ProtoReflect.Descriptor instead.
+func (*SessionMetadataResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SessionMetadataResponse) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
 type Document struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -972,7 +1446,7 @@ type Document struct {
 
 func (x *Document) Reset() {
 	*x = Document{}
-	mi := &file_proto_gibram_proto_msgTypes[11]
+	mi := &file_proto_gibram_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -984,7 +1458,7 @@ func (x *Document) String() string {
 func (*Document) ProtoMessage() {}
 
 func (x *Document) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[11]
+	mi := &file_proto_gibram_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -997,7 +1471,7 @@ func (x *Document) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Document.ProtoReflect.Descriptor instead.
 func (*Document) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{11}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *Document) GetId() uint64 {
@@ -1052,7 +1526,7 @@ type AddDocumentRequest struct {
 
 func (x *AddDocumentRequest) Reset() {
 	*x = AddDocumentRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[12]
+	mi := &file_proto_gibram_proto_msgTypes[15]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1064,7 +1538,7 @@ func (x *AddDocumentRequest) String() string {
 func (*AddDocumentRequest) ProtoMessage() {}
 
 func (x *AddDocumentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[12]
+	mi := &file_proto_gibram_proto_msgTypes[15]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1077,7 +1551,7 @@ func (x *AddDocumentRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddDocumentRequest.ProtoReflect.Descriptor instead.
 func (*AddDocumentRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{12}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *AddDocumentRequest) GetExternalId() string {
@@ -1103,13 +1577,14 @@ type TextUnit struct {
 	TokenCount    int32                  `protobuf:"varint,5,opt,name=token_count,json=tokenCount,proto3" json:"token_count,omitempty"`
 	EntityIds     []uint64               `protobuf:"varint,6,rep,packed,name=entity_ids,json=entityIds,proto3" json:"entity_ids,omitempty"`
 	CreatedAt     int64                  `protobuf:"varint,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Embedding     []float32              `protobuf:"fixed32,8,rep,packed,name=embedding,proto3" json:"embedding,omitempty"` // stored embedding (GET responses only)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TextUnit) Reset() {
 	*x = TextUnit{}
-	mi := &file_proto_gibram_proto_msgTypes[13]
+	mi := &file_proto_gibram_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1121,7 +1596,7 @@ func (x *TextUnit) String() string {
 func (*TextUnit) ProtoMessage() {}
 
 func (x *TextUnit) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[13]
+	mi := &file_proto_gibram_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1134,7 +1609,7 @@ func (x *TextUnit) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TextUnit.ProtoReflect.Descriptor instead.
 func (*TextUnit) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{13}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *TextUnit) GetId() uint64 {
@@ -1186,10 +1661,17 @@ func (x *TextUnit) GetCreatedAt() int64 {
 	return 0
 }
 
-type AddTextUnitRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ExternalId    string                 `protobuf:"bytes,1,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
-	DocumentId    uint64                 `protobuf:"varint,2,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"`
+func (x *TextUnit) GetEmbedding() []float32 {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+type AddTextUnitRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExternalId    string                 `protobuf:"bytes,1,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	DocumentId    uint64                 `protobuf:"varint,2,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"`
 	Content       string                 `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
 	Embedding     []float32              `protobuf:"fixed32,4,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
 	TokenCount    int32                  `protobuf:"varint,5,opt,name=token_count,json=tokenCount,proto3" json:"token_count,omitempty"`
@@ -1199,7 +1681,7 @@ type AddTextUnitRequest struct {
 
 func (x *AddTextUnitRequest) Reset() {
 	*x = AddTextUnitRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[14]
+	mi := &file_proto_gibram_proto_msgTypes[17]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1211,7 +1693,7 @@ func (x *AddTextUnitRequest) String() string {
 func (*AddTextUnitRequest) ProtoMessage() {}
 
 func (x *AddTextUnitRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[14]
+	mi := &file_proto_gibram_proto_msgTypes[17]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1224,7 +1706,7 @@ func (x *AddTextUnitRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddTextUnitRequest.ProtoReflect.Descriptor instead.
 func (*AddTextUnitRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{14}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *AddTextUnitRequest) GetExternalId() string {
@@ -1271,13 +1753,18 @@ type Entity struct {
 	Description   string                 `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
 	TextunitIds   []uint64               `protobuf:"varint,6,rep,packed,name=textunit_ids,json=textunitIds,proto3" json:"textunit_ids,omitempty"`
 	CreatedAt     int64                  `protobuf:"varint,7,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Popularity    float64                `protobuf:"fixed64,8,opt,name=popularity,proto3" json:"popularity,omitempty"` // decayed access count (GET responses, when tracking is on)
+	Metadata      map[string]string      `protobuf:"bytes,9,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Pagerank      float64                `protobuf:"fixed64,10,opt,name=pagerank,proto3" json:"pagerank,omitempty"`          // centrality from the last CMD_COMPUTE_PAGERANK
+	Embedding     []float32              `protobuf:"fixed32,11,rep,packed,name=embedding,proto3" json:"embedding,omitempty"` // stored embedding (GET responses only)
+	UpdatedAt     int64                  `protobuf:"varint,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Entity) Reset() {
 	*x = Entity{}
-	mi := &file_proto_gibram_proto_msgTypes[15]
+	mi := &file_proto_gibram_proto_msgTypes[18]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1289,7 +1776,7 @@ func (x *Entity) String() string {
 func (*Entity) ProtoMessage() {}
 
 func (x *Entity) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[15]
+	mi := &file_proto_gibram_proto_msgTypes[18]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1302,7 +1789,7 @@ func (x *Entity) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Entity.ProtoReflect.Descriptor instead.
 func (*Entity) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{15}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *Entity) GetId() uint64 {
@@ -1354,20 +1841,57 @@ func (x *Entity) GetCreatedAt() int64 {
 	return 0
 }
 
+func (x *Entity) GetPopularity() float64 {
+	if x != nil {
+		return x.Popularity
+	}
+	return 0
+}
+
+func (x *Entity) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Entity) GetPagerank() float64 {
+	if x != nil {
+		return x.Pagerank
+	}
+	return 0
+}
+
+func (x *Entity) GetEmbedding() []float32 {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+func (x *Entity) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
 type AddEntityRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	ExternalId    string                 `protobuf:"bytes,1,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
-	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
-	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
-	Description   string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
-	Embedding     []float32              `protobuf:"fixed32,5,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ExternalId     string                 `protobuf:"bytes,1,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	Title          string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Type           string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Description    string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	Embedding      []float32              `protobuf:"fixed32,5,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
+	TitleEmbedding []float32              `protobuf:"fixed32,6,rep,packed,name=title_embedding,json=titleEmbedding,proto3" json:"title_embedding,omitempty"` // optional separate title embedding
+	Metadata       map[string]string      `protobuf:"bytes,7,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
 }
 
 func (x *AddEntityRequest) Reset() {
 	*x = AddEntityRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[16]
+	mi := &file_proto_gibram_proto_msgTypes[19]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1379,7 +1903,7 @@ func (x *AddEntityRequest) String() string {
 func (*AddEntityRequest) ProtoMessage() {}
 
 func (x *AddEntityRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[16]
+	mi := &file_proto_gibram_proto_msgTypes[19]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1392,7 +1916,7 @@ func (x *AddEntityRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddEntityRequest.ProtoReflect.Descriptor instead.
 func (*AddEntityRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{16}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *AddEntityRequest) GetExternalId() string {
@@ -1430,6 +1954,20 @@ func (x *AddEntityRequest) GetEmbedding() []float32 {
 	return nil
 }
 
+func (x *AddEntityRequest) GetTitleEmbedding() []float32 {
+	if x != nil {
+		return x.TitleEmbedding
+	}
+	return nil
+}
+
+func (x *AddEntityRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
 type GetEntityByTitleRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Title         string                 `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
@@ -1439,7 +1977,7 @@ type GetEntityByTitleRequest struct {
 
 func (x *GetEntityByTitleRequest) Reset() {
 	*x = GetEntityByTitleRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[17]
+	mi := &file_proto_gibram_proto_msgTypes[20]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1451,7 +1989,7 @@ func (x *GetEntityByTitleRequest) String() string {
 func (*GetEntityByTitleRequest) ProtoMessage() {}
 
 func (x *GetEntityByTitleRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[17]
+	mi := &file_proto_gibram_proto_msgTypes[20]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1464,7 +2002,7 @@ func (x *GetEntityByTitleRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetEntityByTitleRequest.ProtoReflect.Descriptor instead.
 func (*GetEntityByTitleRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{17}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *GetEntityByTitleRequest) GetTitle() string {
@@ -1479,13 +2017,14 @@ type UpdateEntityDescRequest struct {
 	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
 	Embedding     []float32              `protobuf:"fixed32,3,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
+	Metadata      map[string]string      `protobuf:"bytes,4,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // merged into existing metadata; empty value removes the key
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *UpdateEntityDescRequest) Reset() {
 	*x = UpdateEntityDescRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[18]
+	mi := &file_proto_gibram_proto_msgTypes[21]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1497,7 +2036,7 @@ func (x *UpdateEntityDescRequest) String() string {
 func (*UpdateEntityDescRequest) ProtoMessage() {}
 
 func (x *UpdateEntityDescRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[18]
+	mi := &file_proto_gibram_proto_msgTypes[21]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1510,7 +2049,7 @@ func (x *UpdateEntityDescRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use UpdateEntityDescRequest.ProtoReflect.Descriptor instead.
 func (*UpdateEntityDescRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{18}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *UpdateEntityDescRequest) GetId() uint64 {
@@ -1534,6 +2073,117 @@ func (x *UpdateEntityDescRequest) GetEmbedding() []float32 {
 	return nil
 }
 
+func (x *UpdateEntityDescRequest) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type UpdateEntityTitleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"` // normalized like AddEntity; must not belong to another entity
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateEntityTitleRequest) Reset() {
+	*x = UpdateEntityTitleRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateEntityTitleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateEntityTitleRequest) ProtoMessage() {}
+
+func (x *UpdateEntityTitleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateEntityTitleRequest.ProtoReflect.Descriptor instead.
+func (*UpdateEntityTitleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *UpdateEntityTitleRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateEntityTitleRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+type MergeEntitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeepId        uint64                 `protobuf:"varint,1,opt,name=keep_id,json=keepId,proto3" json:"keep_id,omitempty"`    // surviving entity
+	MergeId       uint64                 `protobuf:"varint,2,opt,name=merge_id,json=mergeId,proto3" json:"merge_id,omitempty"` // entity folded into keep_id and deleted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MergeEntitiesRequest) Reset() {
+	*x = MergeEntitiesRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MergeEntitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MergeEntitiesRequest) ProtoMessage() {}
+
+func (x *MergeEntitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MergeEntitiesRequest.ProtoReflect.Descriptor instead.
+func (*MergeEntitiesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *MergeEntitiesRequest) GetKeepId() uint64 {
+	if x != nil {
+		return x.KeepId
+	}
+	return 0
+}
+
+func (x *MergeEntitiesRequest) GetMergeId() uint64 {
+	if x != nil {
+		return x.MergeId
+	}
+	return 0
+}
+
 type Relationship struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -1544,13 +2194,17 @@ type Relationship struct {
 	Description   string                 `protobuf:"bytes,6,opt,name=description,proto3" json:"description,omitempty"`
 	Weight        float32                `protobuf:"fixed32,7,opt,name=weight,proto3" json:"weight,omitempty"`
 	CreatedAt     int64                  `protobuf:"varint,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ValidFrom     int64                  `protobuf:"varint,9,opt,name=valid_from,json=validFrom,proto3" json:"valid_from,omitempty"`     // unix seconds (0 = unbounded)
+	ValidUntil    int64                  `protobuf:"varint,10,opt,name=valid_until,json=validUntil,proto3" json:"valid_until,omitempty"` // unix seconds, exclusive (0 = unbounded)
+	UpdatedAt     int64                  `protobuf:"varint,11,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Directed      bool                   `protobuf:"varint,12,opt,name=directed,proto3" json:"directed,omitempty"` // traversed source to target only, unless the query says otherwise
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *Relationship) Reset() {
 	*x = Relationship{}
-	mi := &file_proto_gibram_proto_msgTypes[19]
+	mi := &file_proto_gibram_proto_msgTypes[24]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1562,7 +2216,7 @@ func (x *Relationship) String() string {
 func (*Relationship) ProtoMessage() {}
 
 func (x *Relationship) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[19]
+	mi := &file_proto_gibram_proto_msgTypes[24]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1575,7 +2229,7 @@ func (x *Relationship) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Relationship.ProtoReflect.Descriptor instead.
 func (*Relationship) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{19}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{24}
 }
 
 func (x *Relationship) GetId() uint64 {
@@ -1634,6 +2288,34 @@ func (x *Relationship) GetCreatedAt() int64 {
 	return 0
 }
 
+func (x *Relationship) GetValidFrom() int64 {
+	if x != nil {
+		return x.ValidFrom
+	}
+	return 0
+}
+
+func (x *Relationship) GetValidUntil() int64 {
+	if x != nil {
+		return x.ValidUntil
+	}
+	return 0
+}
+
+func (x *Relationship) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+func (x *Relationship) GetDirected() bool {
+	if x != nil {
+		return x.Directed
+	}
+	return false
+}
+
 type AddRelationshipRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ExternalId    string                 `protobuf:"bytes,1,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
@@ -1642,13 +2324,16 @@ type AddRelationshipRequest struct {
 	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
 	Description   string                 `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`
 	Weight        float32                `protobuf:"fixed32,6,opt,name=weight,proto3" json:"weight,omitempty"`
+	ValidFrom     int64                  `protobuf:"varint,7,opt,name=valid_from,json=validFrom,proto3" json:"valid_from,omitempty"`
+	ValidUntil    int64                  `protobuf:"varint,8,opt,name=valid_until,json=validUntil,proto3" json:"valid_until,omitempty"`
+	Directed      bool                   `protobuf:"varint,9,opt,name=directed,proto3" json:"directed,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AddRelationshipRequest) Reset() {
 	*x = AddRelationshipRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[20]
+	mi := &file_proto_gibram_proto_msgTypes[25]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1660,7 +2345,7 @@ func (x *AddRelationshipRequest) String() string {
 func (*AddRelationshipRequest) ProtoMessage() {}
 
 func (x *AddRelationshipRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[20]
+	mi := &file_proto_gibram_proto_msgTypes[25]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1673,7 +2358,7 @@ func (x *AddRelationshipRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AddRelationshipRequest.ProtoReflect.Descriptor instead.
 func (*AddRelationshipRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{20}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{25}
 }
 
 func (x *AddRelationshipRequest) GetExternalId() string {
@@ -1718,36 +2403,50 @@ func (x *AddRelationshipRequest) GetWeight() float32 {
 	return 0
 }
 
-type Community struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	Id              uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	ExternalId      string                 `protobuf:"bytes,2,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
-	Title           string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
-	Summary         string                 `protobuf:"bytes,4,opt,name=summary,proto3" json:"summary,omitempty"`
-	FullContent     string                 `protobuf:"bytes,5,opt,name=full_content,json=fullContent,proto3" json:"full_content,omitempty"`
-	Level           int32                  `protobuf:"varint,6,opt,name=level,proto3" json:"level,omitempty"`
-	EntityIds       []uint64               `protobuf:"varint,7,rep,packed,name=entity_ids,json=entityIds,proto3" json:"entity_ids,omitempty"`
-	RelationshipIds []uint64               `protobuf:"varint,8,rep,packed,name=relationship_ids,json=relationshipIds,proto3" json:"relationship_ids,omitempty"`
-	CreatedAt       int64                  `protobuf:"varint,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+func (x *AddRelationshipRequest) GetValidFrom() int64 {
+	if x != nil {
+		return x.ValidFrom
+	}
+	return 0
 }
 
-func (x *Community) Reset() {
-	*x = Community{}
-	mi := &file_proto_gibram_proto_msgTypes[21]
+func (x *AddRelationshipRequest) GetValidUntil() int64 {
+	if x != nil {
+		return x.ValidUntil
+	}
+	return 0
+}
+
+func (x *AddRelationshipRequest) GetDirected() bool {
+	if x != nil {
+		return x.Directed
+	}
+	return false
+}
+
+type UpdateRelationshipWeightRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Weight        float32                `protobuf:"fixed32,2,opt,name=weight,proto3" json:"weight,omitempty"` // must be positive
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRelationshipWeightRequest) Reset() {
+	*x = UpdateRelationshipWeightRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Community) String() string {
+func (x *UpdateRelationshipWeightRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Community) ProtoMessage() {}
+func (*UpdateRelationshipWeightRequest) ProtoMessage() {}
 
-func (x *Community) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[21]
+func (x *UpdateRelationshipWeightRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1758,103 +2457,203 @@ func (x *Community) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Community.ProtoReflect.Descriptor instead.
-func (*Community) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{21}
+// Deprecated: Use UpdateRelationshipWeightRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRelationshipWeightRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{26}
 }
 
-func (x *Community) GetId() uint64 {
+func (x *UpdateRelationshipWeightRequest) GetId() uint64 {
 	if x != nil {
 		return x.Id
 	}
 	return 0
 }
 
-func (x *Community) GetExternalId() string {
+func (x *UpdateRelationshipWeightRequest) GetWeight() float32 {
 	if x != nil {
-		return x.ExternalId
+		return x.Weight
 	}
-	return ""
+	return 0
 }
 
-func (x *Community) GetTitle() string {
-	if x != nil {
-		return x.Title
-	}
-	return ""
-}
+type RelationshipTypeStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Limit         int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"` // top N types (0 = all)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
 
-func (x *Community) GetSummary() string {
+func (x *RelationshipTypeStatsRequest) Reset() {
+	*x = RelationshipTypeStatsRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelationshipTypeStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelationshipTypeStatsRequest) ProtoMessage() {}
+
+func (x *RelationshipTypeStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[27]
 	if x != nil {
-		return x.Summary
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *Community) GetFullContent() string {
+// Deprecated: Use RelationshipTypeStatsRequest.ProtoReflect.Descriptor instead.
+func (*RelationshipTypeStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *RelationshipTypeStatsRequest) GetLimit() int32 {
 	if x != nil {
-		return x.FullContent
+		return x.Limit
+	}
+	return 0
+}
+
+type RelationshipTypeStat struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Count         int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	AvgWeight     float32                `protobuf:"fixed32,3,opt,name=avg_weight,json=avgWeight,proto3" json:"avg_weight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelationshipTypeStat) Reset() {
+	*x = RelationshipTypeStat{}
+	mi := &file_proto_gibram_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelationshipTypeStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelationshipTypeStat) ProtoMessage() {}
+
+func (x *RelationshipTypeStat) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelationshipTypeStat.ProtoReflect.Descriptor instead.
+func (*RelationshipTypeStat) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *RelationshipTypeStat) GetType() string {
+	if x != nil {
+		return x.Type
 	}
 	return ""
 }
 
-func (x *Community) GetLevel() int32 {
+func (x *RelationshipTypeStat) GetCount() int64 {
 	if x != nil {
-		return x.Level
+		return x.Count
 	}
 	return 0
 }
 
-func (x *Community) GetEntityIds() []uint64 {
+func (x *RelationshipTypeStat) GetAvgWeight() float32 {
 	if x != nil {
-		return x.EntityIds
+		return x.AvgWeight
 	}
-	return nil
+	return 0
 }
 
-func (x *Community) GetRelationshipIds() []uint64 {
+type RelationshipTypeStatsResponse struct {
+	state              protoimpl.MessageState  `protogen:"open.v1"`
+	Stats              []*RelationshipTypeStat `protobuf:"bytes,1,rep,name=stats,proto3" json:"stats,omitempty"` // ordered by count descending
+	TotalRelationships int64                   `protobuf:"varint,2,opt,name=total_relationships,json=totalRelationships,proto3" json:"total_relationships,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *RelationshipTypeStatsResponse) Reset() {
+	*x = RelationshipTypeStatsResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelationshipTypeStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelationshipTypeStatsResponse) ProtoMessage() {}
+
+func (x *RelationshipTypeStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[29]
 	if x != nil {
-		return x.RelationshipIds
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelationshipTypeStatsResponse.ProtoReflect.Descriptor instead.
+func (*RelationshipTypeStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *RelationshipTypeStatsResponse) GetStats() []*RelationshipTypeStat {
+	if x != nil {
+		return x.Stats
 	}
 	return nil
 }
 
-func (x *Community) GetCreatedAt() int64 {
+func (x *RelationshipTypeStatsResponse) GetTotalRelationships() int64 {
 	if x != nil {
-		return x.CreatedAt
+		return x.TotalRelationships
 	}
 	return 0
 }
 
-type AddCommunityRequest struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	ExternalId      string                 `protobuf:"bytes,1,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
-	Title           string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
-	Summary         string                 `protobuf:"bytes,3,opt,name=summary,proto3" json:"summary,omitempty"`
-	FullContent     string                 `protobuf:"bytes,4,opt,name=full_content,json=fullContent,proto3" json:"full_content,omitempty"`
-	Level           int32                  `protobuf:"varint,5,opt,name=level,proto3" json:"level,omitempty"`
-	EntityIds       []uint64               `protobuf:"varint,6,rep,packed,name=entity_ids,json=entityIds,proto3" json:"entity_ids,omitempty"`
-	RelationshipIds []uint64               `protobuf:"varint,7,rep,packed,name=relationship_ids,json=relationshipIds,proto3" json:"relationship_ids,omitempty"`
-	Embedding       []float32              `protobuf:"fixed32,8,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+type CountRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ItemType      string                 `protobuf:"bytes,1,opt,name=item_type,json=itemType,proto3" json:"item_type,omitempty"` // document, textunit, entity, relationship or community
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AddCommunityRequest) Reset() {
-	*x = AddCommunityRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[22]
+func (x *CountRequest) Reset() {
+	*x = CountRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[30]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AddCommunityRequest) String() string {
+func (x *CountRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AddCommunityRequest) ProtoMessage() {}
+func (*CountRequest) ProtoMessage() {}
 
-func (x *AddCommunityRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[22]
+func (x *CountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[30]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1865,142 +2664,1693 @@ func (x *AddCommunityRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AddCommunityRequest.ProtoReflect.Descriptor instead.
-func (*AddCommunityRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{22}
+// Deprecated: Use CountRequest.ProtoReflect.Descriptor instead.
+func (*CountRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{30}
 }
 
-func (x *AddCommunityRequest) GetExternalId() string {
+func (x *CountRequest) GetItemType() string {
 	if x != nil {
-		return x.ExternalId
+		return x.ItemType
 	}
 	return ""
 }
 
-func (x *AddCommunityRequest) GetTitle() string {
+type CountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         uint64                 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountResponse) Reset() {
+	*x = CountResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountResponse) ProtoMessage() {}
+
+func (x *CountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[31]
 	if x != nil {
-		return x.Title
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *AddCommunityRequest) GetSummary() string {
+// Deprecated: Use CountResponse.ProtoReflect.Descriptor instead.
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *CountResponse) GetCount() uint64 {
 	if x != nil {
-		return x.Summary
+		return x.Count
+	}
+	return 0
+}
+
+type RebuildIndexRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Async         bool                   `protobuf:"varint,1,opt,name=async,proto3" json:"async,omitempty"` // return at once; poll CMD_INDEX_STATUS for progress
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RebuildIndexRequest) Reset() {
+	*x = RebuildIndexRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RebuildIndexRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RebuildIndexRequest) ProtoMessage() {}
+
+func (x *RebuildIndexRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RebuildIndexRequest.ProtoReflect.Descriptor instead.
+func (*RebuildIndexRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *RebuildIndexRequest) GetAsync() bool {
+	if x != nil {
+		return x.Async
+	}
+	return false
+}
+
+type IndexStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	State         string                 `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`       // building or ready
+	Percent       float64                `protobuf:"fixed64,2,opt,name=percent,proto3" json:"percent,omitempty"` // rebuild progress, 0-100
+	VectorCount   uint64                 `protobuf:"varint,3,opt,name=vector_count,json=vectorCount,proto3" json:"vector_count,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`                              // why the latest rebuild failed
+	StartedAt     int64                  `protobuf:"varint,5,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`    // latest rebuild start (unix seconds), 0 if none
+	FinishedAt    int64                  `protobuf:"varint,6,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"` // latest rebuild end, 0 while building
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IndexStatusResponse) Reset() {
+	*x = IndexStatusResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IndexStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IndexStatusResponse) ProtoMessage() {}
+
+func (x *IndexStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IndexStatusResponse.ProtoReflect.Descriptor instead.
+func (*IndexStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *IndexStatusResponse) GetState() string {
+	if x != nil {
+		return x.State
 	}
 	return ""
 }
 
-func (x *AddCommunityRequest) GetFullContent() string {
+func (x *IndexStatusResponse) GetPercent() float64 {
 	if x != nil {
-		return x.FullContent
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *IndexStatusResponse) GetVectorCount() uint64 {
+	if x != nil {
+		return x.VectorCount
+	}
+	return 0
+}
+
+func (x *IndexStatusResponse) GetError() string {
+	if x != nil {
+		return x.Error
 	}
 	return ""
 }
 
-func (x *AddCommunityRequest) GetLevel() int32 {
+func (x *IndexStatusResponse) GetStartedAt() int64 {
+	if x != nil {
+		return x.StartedAt
+	}
+	return 0
+}
+
+func (x *IndexStatusResponse) GetFinishedAt() int64 {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return 0
+}
+
+type QuantizeIndexRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Precision     string                 `protobuf:"bytes,1,opt,name=precision,proto3" json:"precision,omitempty"` // float32, float16 or int8
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuantizeIndexRequest) Reset() {
+	*x = QuantizeIndexRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuantizeIndexRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuantizeIndexRequest) ProtoMessage() {}
+
+func (x *QuantizeIndexRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuantizeIndexRequest.ProtoReflect.Descriptor instead.
+func (*QuantizeIndexRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *QuantizeIndexRequest) GetPrecision() string {
+	if x != nil {
+		return x.Precision
+	}
+	return ""
+}
+
+type QuantizeIndexResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	BytesBefore   int64                  `protobuf:"varint,1,opt,name=bytes_before,json=bytesBefore,proto3" json:"bytes_before,omitempty"` // vector memory before the conversion
+	BytesAfter    int64                  `protobuf:"varint,2,opt,name=bytes_after,json=bytesAfter,proto3" json:"bytes_after,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuantizeIndexResponse) Reset() {
+	*x = QuantizeIndexResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuantizeIndexResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuantizeIndexResponse) ProtoMessage() {}
+
+func (x *QuantizeIndexResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuantizeIndexResponse.ProtoReflect.Descriptor instead.
+func (*QuantizeIndexResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *QuantizeIndexResponse) GetBytesBefore() int64 {
+	if x != nil {
+		return x.BytesBefore
+	}
+	return 0
+}
+
+func (x *QuantizeIndexResponse) GetBytesAfter() int64 {
+	if x != nil {
+		return x.BytesAfter
+	}
+	return 0
+}
+
+type EntityStatsResponse struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	EntityTypes       map[string]int64       `protobuf:"bytes,1,rep,name=entity_types,json=entityTypes,proto3" json:"entity_types,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`                   // entity type -> count
+	RelationshipTypes map[string]int64       `protobuf:"bytes,2,rep,name=relationship_types,json=relationshipTypes,proto3" json:"relationship_types,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"` // relationship type -> count
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *EntityStatsResponse) Reset() {
+	*x = EntityStatsResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EntityStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EntityStatsResponse) ProtoMessage() {}
+
+func (x *EntityStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EntityStatsResponse.ProtoReflect.Descriptor instead.
+func (*EntityStatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *EntityStatsResponse) GetEntityTypes() map[string]int64 {
+	if x != nil {
+		return x.EntityTypes
+	}
+	return nil
+}
+
+func (x *EntityStatsResponse) GetRelationshipTypes() map[string]int64 {
+	if x != nil {
+		return x.RelationshipTypes
+	}
+	return nil
+}
+
+type GetNeighborsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	EntityId      uint64                 `protobuf:"varint,1,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	Direction     EdgeDirection          `protobuf:"varint,2,opt,name=direction,proto3,enum=gibram.v1.EdgeDirection" json:"direction,omitempty"`
+	RelTypes      []string               `protobuf:"bytes,3,rep,name=rel_types,json=relTypes,proto3" json:"rel_types,omitempty"` // only these relationship types (empty = all)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNeighborsRequest) Reset() {
+	*x = GetNeighborsRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNeighborsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNeighborsRequest) ProtoMessage() {}
+
+func (x *GetNeighborsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNeighborsRequest.ProtoReflect.Descriptor instead.
+func (*GetNeighborsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *GetNeighborsRequest) GetEntityId() uint64 {
+	if x != nil {
+		return x.EntityId
+	}
+	return 0
+}
+
+func (x *GetNeighborsRequest) GetDirection() EdgeDirection {
+	if x != nil {
+		return x.Direction
+	}
+	return EdgeDirection_EDGE_DIRECTION_BOTH
+}
+
+func (x *GetNeighborsRequest) GetRelTypes() []string {
+	if x != nil {
+		return x.RelTypes
+	}
+	return nil
+}
+
+type SubgraphRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SeedIds       []uint64               `protobuf:"varint,1,rep,packed,name=seed_ids,json=seedIds,proto3" json:"seed_ids,omitempty"`
+	Hops          int32                  `protobuf:"varint,2,opt,name=hops,proto3" json:"hops,omitempty"`                         // 0 = seeds only
+	MaxNodes      int32                  `protobuf:"varint,3,opt,name=max_nodes,json=maxNodes,proto3" json:"max_nodes,omitempty"` // cap on returned entities (0 = server default)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubgraphRequest) Reset() {
+	*x = SubgraphRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubgraphRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubgraphRequest) ProtoMessage() {}
+
+func (x *SubgraphRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubgraphRequest.ProtoReflect.Descriptor instead.
+func (*SubgraphRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *SubgraphRequest) GetSeedIds() []uint64 {
+	if x != nil {
+		return x.SeedIds
+	}
+	return nil
+}
+
+func (x *SubgraphRequest) GetHops() int32 {
+	if x != nil {
+		return x.Hops
+	}
+	return 0
+}
+
+func (x *SubgraphRequest) GetMaxNodes() int32 {
+	if x != nil {
+		return x.MaxNodes
+	}
+	return 0
+}
+
+type SubgraphResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entities      []*Entity              `protobuf:"bytes,1,rep,name=entities,proto3" json:"entities,omitempty"`           // BFS order from the seeds
+	Relationships []*Relationship        `protobuf:"bytes,2,rep,name=relationships,proto3" json:"relationships,omitempty"` // all edges among the entities
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubgraphResponse) Reset() {
+	*x = SubgraphResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubgraphResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubgraphResponse) ProtoMessage() {}
+
+func (x *SubgraphResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubgraphResponse.ProtoReflect.Descriptor instead.
+func (*SubgraphResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *SubgraphResponse) GetEntities() []*Entity {
+	if x != nil {
+		return x.Entities
+	}
+	return nil
+}
+
+func (x *SubgraphResponse) GetRelationships() []*Relationship {
+	if x != nil {
+		return x.Relationships
+	}
+	return nil
+}
+
+type Community struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Id              uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	ExternalId      string                 `protobuf:"bytes,2,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	Title           string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Summary         string                 `protobuf:"bytes,4,opt,name=summary,proto3" json:"summary,omitempty"`
+	FullContent     string                 `protobuf:"bytes,5,opt,name=full_content,json=fullContent,proto3" json:"full_content,omitempty"`
+	Level           int32                  `protobuf:"varint,6,opt,name=level,proto3" json:"level,omitempty"`
+	EntityIds       []uint64               `protobuf:"varint,7,rep,packed,name=entity_ids,json=entityIds,proto3" json:"entity_ids,omitempty"`
+	RelationshipIds []uint64               `protobuf:"varint,8,rep,packed,name=relationship_ids,json=relationshipIds,proto3" json:"relationship_ids,omitempty"`
+	CreatedAt       int64                  `protobuf:"varint,9,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Community) Reset() {
+	*x = Community{}
+	mi := &file_proto_gibram_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Community) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Community) ProtoMessage() {}
+
+func (x *Community) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Community.ProtoReflect.Descriptor instead.
+func (*Community) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *Community) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Community) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+func (x *Community) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Community) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *Community) GetFullContent() string {
+	if x != nil {
+		return x.FullContent
+	}
+	return ""
+}
+
+func (x *Community) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *Community) GetEntityIds() []uint64 {
+	if x != nil {
+		return x.EntityIds
+	}
+	return nil
+}
+
+func (x *Community) GetRelationshipIds() []uint64 {
+	if x != nil {
+		return x.RelationshipIds
+	}
+	return nil
+}
+
+func (x *Community) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type AddCommunityRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ExternalId      string                 `protobuf:"bytes,1,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	Title           string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	Summary         string                 `protobuf:"bytes,3,opt,name=summary,proto3" json:"summary,omitempty"`
+	FullContent     string                 `protobuf:"bytes,4,opt,name=full_content,json=fullContent,proto3" json:"full_content,omitempty"`
+	Level           int32                  `protobuf:"varint,5,opt,name=level,proto3" json:"level,omitempty"`
+	EntityIds       []uint64               `protobuf:"varint,6,rep,packed,name=entity_ids,json=entityIds,proto3" json:"entity_ids,omitempty"`
+	RelationshipIds []uint64               `protobuf:"varint,7,rep,packed,name=relationship_ids,json=relationshipIds,proto3" json:"relationship_ids,omitempty"`
+	Embedding       []float32              `protobuf:"fixed32,8,rep,packed,name=embedding,proto3" json:"embedding,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *AddCommunityRequest) Reset() {
+	*x = AddCommunityRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddCommunityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddCommunityRequest) ProtoMessage() {}
+
+func (x *AddCommunityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddCommunityRequest.ProtoReflect.Descriptor instead.
+func (*AddCommunityRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *AddCommunityRequest) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+func (x *AddCommunityRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *AddCommunityRequest) GetSummary() string {
+	if x != nil {
+		return x.Summary
+	}
+	return ""
+}
+
+func (x *AddCommunityRequest) GetFullContent() string {
+	if x != nil {
+		return x.FullContent
+	}
+	return ""
+}
+
+func (x *AddCommunityRequest) GetLevel() int32 {
+	if x != nil {
+		return x.Level
+	}
+	return 0
+}
+
+func (x *AddCommunityRequest) GetEntityIds() []uint64 {
+	if x != nil {
+		return x.EntityIds
+	}
+	return nil
+}
+
+func (x *AddCommunityRequest) GetRelationshipIds() []uint64 {
+	if x != nil {
+		return x.RelationshipIds
+	}
+	return nil
+}
+
+func (x *AddCommunityRequest) GetEmbedding() []float32 {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+type ComputeCommunitiesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Resolution    float64                `protobuf:"fixed64,1,opt,name=resolution,proto3" json:"resolution,omitempty"`
+	Iterations    int32                  `protobuf:"varint,2,opt,name=iterations,proto3" json:"iterations,omitempty"`
+	Algorithm     string                 `protobuf:"bytes,3,opt,name=algorithm,proto3" json:"algorithm,omitempty"` // "leiden" (default) or "louvain"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ComputeCommunitiesRequest) Reset() {
+	*x = ComputeCommunitiesRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ComputeCommunitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComputeCommunitiesRequest) ProtoMessage() {}
+
+func (x *ComputeCommunitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComputeCommunitiesRequest.ProtoReflect.Descriptor instead.
+func (*ComputeCommunitiesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *ComputeCommunitiesRequest) GetResolution() float64 {
+	if x != nil {
+		return x.Resolution
+	}
+	return 0
+}
+
+func (x *ComputeCommunitiesRequest) GetIterations() int32 {
+	if x != nil {
+		return x.Iterations
+	}
+	return 0
+}
+
+func (x *ComputeCommunitiesRequest) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+type ComputeCommunitiesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Communities   []*Community           `protobuf:"bytes,2,rep,name=communities,proto3" json:"communities,omitempty"`
+	Modularity    float64                `protobuf:"fixed64,3,opt,name=modularity,proto3" json:"modularity,omitempty"` // quality of the partition at the requested resolution
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ComputeCommunitiesResponse) Reset() {
+	*x = ComputeCommunitiesResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ComputeCommunitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ComputeCommunitiesResponse) ProtoMessage() {}
+
+func (x *ComputeCommunitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ComputeCommunitiesResponse.ProtoReflect.Descriptor instead.
+func (*ComputeCommunitiesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{43}
+}
+
+func (x *ComputeCommunitiesResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ComputeCommunitiesResponse) GetCommunities() []*Community {
+	if x != nil {
+		return x.Communities
+	}
+	return nil
+}
+
+func (x *ComputeCommunitiesResponse) GetModularity() float64 {
+	if x != nil {
+		return x.Modularity
+	}
+	return 0
+}
+
+type PageRankResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scores        map[uint64]float64     `protobuf:"bytes,1,rep,name=scores,proto3" json:"scores,omitempty" protobuf_key:"varint,1,opt,name=key" protobuf_val:"fixed64,2,opt,name=value"` // entity id -> PageRank, summing to 1
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PageRankResponse) Reset() {
+	*x = PageRankResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PageRankResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PageRankResponse) ProtoMessage() {}
+
+func (x *PageRankResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PageRankResponse.ProtoReflect.Descriptor instead.
+func (*PageRankResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *PageRankResponse) GetScores() map[uint64]float64 {
+	if x != nil {
+		return x.Scores
+	}
+	return nil
+}
+
+type LinkTextUnitEntityRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TextunitId    uint64                 `protobuf:"varint,1,opt,name=textunit_id,json=textunitId,proto3" json:"textunit_id,omitempty"`
+	EntityId      uint64                 `protobuf:"varint,2,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkTextUnitEntityRequest) Reset() {
+	*x = LinkTextUnitEntityRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkTextUnitEntityRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkTextUnitEntityRequest) ProtoMessage() {}
+
+func (x *LinkTextUnitEntityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkTextUnitEntityRequest.ProtoReflect.Descriptor instead.
+func (*LinkTextUnitEntityRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *LinkTextUnitEntityRequest) GetTextunitId() uint64 {
+	if x != nil {
+		return x.TextunitId
+	}
+	return 0
+}
+
+func (x *LinkTextUnitEntityRequest) GetEntityId() uint64 {
+	if x != nil {
+		return x.EntityId
+	}
+	return 0
+}
+
+type QueryRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	QueryVector        []float32              `protobuf:"fixed32,1,rep,packed,name=query_vector,json=queryVector,proto3" json:"query_vector,omitempty"`
+	SearchTypes        []string               `protobuf:"bytes,2,rep,name=search_types,json=searchTypes,proto3" json:"search_types,omitempty"` // "textunit", "entity", "community", "keyword"
+	TopK               int32                  `protobuf:"varint,3,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
+	KHops              int32                  `protobuf:"varint,4,opt,name=k_hops,json=kHops,proto3" json:"k_hops,omitempty"`
+	MaxEntities        int32                  `protobuf:"varint,5,opt,name=max_entities,json=maxEntities,proto3" json:"max_entities,omitempty"`
+	MaxTextunits       int32                  `protobuf:"varint,6,opt,name=max_textunits,json=maxTextunits,proto3" json:"max_textunits,omitempty"`
+	MaxCommunities     int32                  `protobuf:"varint,7,opt,name=max_communities,json=maxCommunities,proto3" json:"max_communities,omitempty"`
+	SeedEntityIds      []uint64               `protobuf:"varint,8,rep,packed,name=seed_entity_ids,json=seedEntityIds,proto3" json:"seed_entity_ids,omitempty"`
+	FilterEntityTypes  []string               `protobuf:"bytes,9,rep,name=filter_entity_types,json=filterEntityTypes,proto3" json:"filter_entity_types,omitempty"` // only return entities of these types (empty = all)
+	FilterRelTypes     []string               `protobuf:"bytes,10,rep,name=filter_rel_types,json=filterRelTypes,proto3" json:"filter_rel_types,omitempty"`
+	AsOf               int64                  `protobuf:"varint,11,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`                                                                                                           // only traverse relationships valid at this unix time (0 = all)
+	IncludeTextStats   bool                   `protobuf:"varint,12,opt,name=include_text_stats,json=includeTextStats,proto3" json:"include_text_stats,omitempty"`                                                                     // fill token_count/content_length on text unit results
+	HubPenalty         float32                `protobuf:"fixed32,13,opt,name=hub_penalty,json=hubPenalty,proto3" json:"hub_penalty,omitempty"`                                                                                        // scale entity scores by (1+degree)^-hub_penalty (0 = off)
+	MinResults         int32                  `protobuf:"varint,14,opt,name=min_results,json=minResults,proto3" json:"min_results,omitempty"`                                                                                         // relax min_similarity/top_k/k_hops until this many results (0 = off)
+	PopularityBoost    float32                `protobuf:"fixed32,15,opt,name=popularity_boost,json=popularityBoost,proto3" json:"popularity_boost,omitempty"`                                                                         // scale entity scores by 1 + boost*ln(1+popularity) (0 = off)
+	MaxExpansionPerHop int32                  `protobuf:"varint,16,opt,name=max_expansion_per_hop,json=maxExpansionPerHop,proto3" json:"max_expansion_per_hop,omitempty"`                                                             // new neighbors per frontier node, highest weight first (0 = no cap)
+	TitleWeight        float32                `protobuf:"fixed32,17,opt,name=title_weight,json=titleWeight,proto3" json:"title_weight,omitempty"`                                                                                     // weight of entity title similarity (0 = description only)
+	DescriptionWeight  float32                `protobuf:"fixed32,18,opt,name=description_weight,json=descriptionWeight,proto3" json:"description_weight,omitempty"`                                                                   // weight of entity description similarity when title_weight > 0
+	MinSimilarity      float32                `protobuf:"fixed32,19,opt,name=min_similarity,json=minSimilarity,proto3" json:"min_similarity,omitempty"`                                                                               // drop seeds below this similarity (0 = off)
+	EfSearch           int32                  `protobuf:"varint,20,opt,name=ef_search,json=efSearch,proto3" json:"ef_search,omitempty"`                                                                                               // HNSW candidate list size (0 = index default)
+	MetadataFilters    map[string]string      `protobuf:"bytes,21,rep,name=metadata_filters,json=metadataFilters,proto3" json:"metadata_filters,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // only entities with these metadata values
+	PagerankWeight     float32                `protobuf:"fixed32,22,opt,name=pagerank_weight,json=pagerankWeight,proto3" json:"pagerank_weight,omitempty"`                                                                            // scale entity scores by 1 + weight*pagerank*entity_count (0 = off)
+	KeywordQuery       string                 `protobuf:"bytes,23,opt,name=keyword_query,json=keywordQuery,proto3" json:"keyword_query,omitempty"`                                                                                    // text matched by the "keyword" search type (BM25 over text units)
+	KeywordWeight      float32                `protobuf:"fixed32,24,opt,name=keyword_weight,json=keywordWeight,proto3" json:"keyword_weight,omitempty"`                                                                               // share of the keyword score in hybrid text unit scores (0 = 0.5)
+	FusionMethod       string                 `protobuf:"bytes,25,opt,name=fusion_method,json=fusionMethod,proto3" json:"fusion_method,omitempty"`                                                                                    // hybrid ranking: "weighted" (default) or "rrf"
+	RrfK               int32                  `protobuf:"varint,26,opt,name=rrf_k,json=rrfK,proto3" json:"rrf_k,omitempty"`                                                                                                           // reciprocal rank fusion offset k in 1/(k+rank) (0 = 60)
+	Diversity          float32                `protobuf:"fixed32,27,opt,name=diversity,proto3" json:"diversity,omitempty"`                                                                                                            // MMR reranking of text units, 0..1 (0 = off)
+	PageSize           int32                  `protobuf:"varint,28,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`                                                                                               // results per page; next_cursor fetches the rest (0 = unpaged)
+	Cursor             string                 `protobuf:"bytes,29,opt,name=cursor,proto3" json:"cursor,omitempty"`                                                                                                                    // next_cursor of a previous paged query; other fields are ignored
+	DecayFactor        float32                `protobuf:"fixed32,30,opt,name=decay_factor,json=decayFactor,proto3" json:"decay_factor,omitempty"`                                                                                     // score traversed entities by seed score * edge weights * decay^hop (0 = 1/(1+hop))
+	CreatedAfter       int64                  `protobuf:"varint,31,opt,name=created_after,json=createdAfter,proto3" json:"created_after,omitempty"`                                                                                   // only entities/relationships created after this unix time (0 = open)
+	CreatedBefore      int64                  `protobuf:"varint,32,opt,name=created_before,json=createdBefore,proto3" json:"created_before,omitempty"`                                                                                // only entities/relationships created before this unix time (0 = open)
+	TraversalDirection string                 `protobuf:"bytes,33,opt,name=traversal_direction,json=traversalDirection,proto3" json:"traversal_direction,omitempty"`                                                                  // how directed relationships are followed: "forward" (default), "reverse" or "both"
+	DeadlineMs         int32                  `protobuf:"varint,34,opt,name=deadline_ms,json=deadlineMs,proto3" json:"deadline_ms,omitempty"`                                                                                         // server-side time limit; the sooner of this and security.command_timeout applies (0 = none)
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *QueryRequest) GetQueryVector() []float32 {
+	if x != nil {
+		return x.QueryVector
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetSearchTypes() []string {
+	if x != nil {
+		return x.SearchTypes
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetTopK() int32 {
+	if x != nil {
+		return x.TopK
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetKHops() int32 {
+	if x != nil {
+		return x.KHops
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetMaxEntities() int32 {
+	if x != nil {
+		return x.MaxEntities
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetMaxTextunits() int32 {
+	if x != nil {
+		return x.MaxTextunits
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetMaxCommunities() int32 {
+	if x != nil {
+		return x.MaxCommunities
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetSeedEntityIds() []uint64 {
+	if x != nil {
+		return x.SeedEntityIds
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetFilterEntityTypes() []string {
+	if x != nil {
+		return x.FilterEntityTypes
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetFilterRelTypes() []string {
+	if x != nil {
+		return x.FilterRelTypes
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetAsOf() int64 {
+	if x != nil {
+		return x.AsOf
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetIncludeTextStats() bool {
+	if x != nil {
+		return x.IncludeTextStats
+	}
+	return false
+}
+
+func (x *QueryRequest) GetHubPenalty() float32 {
+	if x != nil {
+		return x.HubPenalty
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetMinResults() int32 {
+	if x != nil {
+		return x.MinResults
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetPopularityBoost() float32 {
+	if x != nil {
+		return x.PopularityBoost
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetMaxExpansionPerHop() int32 {
+	if x != nil {
+		return x.MaxExpansionPerHop
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetTitleWeight() float32 {
+	if x != nil {
+		return x.TitleWeight
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetDescriptionWeight() float32 {
+	if x != nil {
+		return x.DescriptionWeight
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetMinSimilarity() float32 {
+	if x != nil {
+		return x.MinSimilarity
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetEfSearch() int32 {
+	if x != nil {
+		return x.EfSearch
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetMetadataFilters() map[string]string {
+	if x != nil {
+		return x.MetadataFilters
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetPagerankWeight() float32 {
+	if x != nil {
+		return x.PagerankWeight
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetKeywordQuery() string {
+	if x != nil {
+		return x.KeywordQuery
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetKeywordWeight() float32 {
+	if x != nil {
+		return x.KeywordWeight
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetFusionMethod() string {
+	if x != nil {
+		return x.FusionMethod
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetRrfK() int32 {
+	if x != nil {
+		return x.RrfK
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetDiversity() float32 {
+	if x != nil {
+		return x.Diversity
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetDecayFactor() float32 {
+	if x != nil {
+		return x.DecayFactor
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetCreatedAfter() int64 {
+	if x != nil {
+		return x.CreatedAfter
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetCreatedBefore() int64 {
+	if x != nil {
+		return x.CreatedBefore
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetTraversalDirection() string {
+	if x != nil {
+		return x.TraversalDirection
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetDeadlineMs() int32 {
+	if x != nil {
+		return x.DeadlineMs
+	}
+	return 0
+}
+
+type TextUnitResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Textunit      *TextUnit              `protobuf:"bytes,1,opt,name=textunit,proto3" json:"textunit,omitempty"`
+	Similarity    float32                `protobuf:"fixed32,2,opt,name=similarity,proto3" json:"similarity,omitempty"`
+	Hop           int32                  `protobuf:"varint,3,opt,name=hop,proto3" json:"hop,omitempty"`
+	TokenCount    int32                  `protobuf:"varint,4,opt,name=token_count,json=tokenCount,proto3" json:"token_count,omitempty"`          // set when include_text_stats
+	ContentLength int32                  `protobuf:"varint,5,opt,name=content_length,json=contentLength,proto3" json:"content_length,omitempty"` // content length in characters, set when include_text_stats
+	KeywordScore  float32                `protobuf:"fixed32,6,opt,name=keyword_score,json=keywordScore,proto3" json:"keyword_score,omitempty"`   // scaled BM25 score, set for keyword search hits
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TextUnitResult) Reset() {
+	*x = TextUnitResult{}
+	mi := &file_proto_gibram_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TextUnitResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TextUnitResult) ProtoMessage() {}
+
+func (x *TextUnitResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TextUnitResult.ProtoReflect.Descriptor instead.
+func (*TextUnitResult) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *TextUnitResult) GetTextunit() *TextUnit {
+	if x != nil {
+		return x.Textunit
+	}
+	return nil
+}
+
+func (x *TextUnitResult) GetSimilarity() float32 {
+	if x != nil {
+		return x.Similarity
+	}
+	return 0
+}
+
+func (x *TextUnitResult) GetHop() int32 {
+	if x != nil {
+		return x.Hop
+	}
+	return 0
+}
+
+func (x *TextUnitResult) GetTokenCount() int32 {
+	if x != nil {
+		return x.TokenCount
+	}
+	return 0
+}
+
+func (x *TextUnitResult) GetContentLength() int32 {
+	if x != nil {
+		return x.ContentLength
+	}
+	return 0
+}
+
+func (x *TextUnitResult) GetKeywordScore() float32 {
+	if x != nil {
+		return x.KeywordScore
+	}
+	return 0
+}
+
+type EntityResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entity        *Entity                `protobuf:"bytes,1,opt,name=entity,proto3" json:"entity,omitempty"`
+	Similarity    float32                `protobuf:"fixed32,2,opt,name=similarity,proto3" json:"similarity,omitempty"`
+	Hop           int32                  `protobuf:"varint,3,opt,name=hop,proto3" json:"hop,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EntityResult) Reset() {
+	*x = EntityResult{}
+	mi := &file_proto_gibram_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EntityResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EntityResult) ProtoMessage() {}
+
+func (x *EntityResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EntityResult.ProtoReflect.Descriptor instead.
+func (*EntityResult) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *EntityResult) GetEntity() *Entity {
+	if x != nil {
+		return x.Entity
+	}
+	return nil
+}
+
+func (x *EntityResult) GetSimilarity() float32 {
+	if x != nil {
+		return x.Similarity
+	}
+	return 0
+}
+
+func (x *EntityResult) GetHop() int32 {
+	if x != nil {
+		return x.Hop
+	}
+	return 0
+}
+
+type CommunityResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Community     *Community             `protobuf:"bytes,1,opt,name=community,proto3" json:"community,omitempty"`
+	Similarity    float32                `protobuf:"fixed32,2,opt,name=similarity,proto3" json:"similarity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommunityResult) Reset() {
+	*x = CommunityResult{}
+	mi := &file_proto_gibram_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommunityResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommunityResult) ProtoMessage() {}
+
+func (x *CommunityResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommunityResult.ProtoReflect.Descriptor instead.
+func (*CommunityResult) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *CommunityResult) GetCommunity() *Community {
+	if x != nil {
+		return x.Community
+	}
+	return nil
+}
+
+func (x *CommunityResult) GetSimilarity() float32 {
+	if x != nil {
+		return x.Similarity
+	}
+	return 0
+}
+
+type RelationshipResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Relationship  *Relationship          `protobuf:"bytes,1,opt,name=relationship,proto3" json:"relationship,omitempty"`
+	SourceTitle   string                 `protobuf:"bytes,2,opt,name=source_title,json=sourceTitle,proto3" json:"source_title,omitempty"`
+	TargetTitle   string                 `protobuf:"bytes,3,opt,name=target_title,json=targetTitle,proto3" json:"target_title,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelationshipResult) Reset() {
+	*x = RelationshipResult{}
+	mi := &file_proto_gibram_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelationshipResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelationshipResult) ProtoMessage() {}
+
+func (x *RelationshipResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelationshipResult.ProtoReflect.Descriptor instead.
+func (*RelationshipResult) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *RelationshipResult) GetRelationship() *Relationship {
+	if x != nil {
+		return x.Relationship
+	}
+	return nil
+}
+
+func (x *RelationshipResult) GetSourceTitle() string {
+	if x != nil {
+		return x.SourceTitle
+	}
+	return ""
+}
+
+func (x *RelationshipResult) GetTargetTitle() string {
+	if x != nil {
+		return x.TargetTitle
+	}
+	return ""
+}
+
+type QueryStats struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	DurationMicros  int64                  `protobuf:"varint,1,opt,name=duration_micros,json=durationMicros,proto3" json:"duration_micros,omitempty"`
+	VectorSearches  int32                  `protobuf:"varint,2,opt,name=vector_searches,json=vectorSearches,proto3" json:"vector_searches,omitempty"`
+	GraphTraversals int32                  `protobuf:"varint,3,opt,name=graph_traversals,json=graphTraversals,proto3" json:"graph_traversals,omitempty"`
+	Relaxations     []string               `protobuf:"bytes,4,rep,name=relaxations,proto3" json:"relaxations,omitempty"`            // relaxation steps applied for min_results
+	CacheHit        bool                   `protobuf:"varint,5,opt,name=cache_hit,json=cacheHit,proto3" json:"cache_hit,omitempty"` // served from the server's query cache
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *QueryStats) Reset() {
+	*x = QueryStats{}
+	mi := &file_proto_gibram_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryStats) ProtoMessage() {}
+
+func (x *QueryStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryStats.ProtoReflect.Descriptor instead.
+func (*QueryStats) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{51}
+}
+
+func (x *QueryStats) GetDurationMicros() int64 {
+	if x != nil {
+		return x.DurationMicros
+	}
+	return 0
+}
+
+func (x *QueryStats) GetVectorSearches() int32 {
+	if x != nil {
+		return x.VectorSearches
+	}
+	return 0
+}
+
+func (x *QueryStats) GetGraphTraversals() int32 {
+	if x != nil {
+		return x.GraphTraversals
+	}
+	return 0
+}
+
+func (x *QueryStats) GetRelaxations() []string {
+	if x != nil {
+		return x.Relaxations
+	}
+	return nil
+}
+
+func (x *QueryStats) GetCacheHit() bool {
+	if x != nil {
+		return x.CacheHit
+	}
+	return false
+}
+
+type QueryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	QueryId       uint64                 `protobuf:"varint,1,opt,name=query_id,json=queryId,proto3" json:"query_id,omitempty"`
+	Textunits     []*TextUnitResult      `protobuf:"bytes,2,rep,name=textunits,proto3" json:"textunits,omitempty"`
+	Entities      []*EntityResult        `protobuf:"bytes,3,rep,name=entities,proto3" json:"entities,omitempty"`
+	Communities   []*CommunityResult     `protobuf:"bytes,4,rep,name=communities,proto3" json:"communities,omitempty"`
+	Relationships []*RelationshipResult  `protobuf:"bytes,5,rep,name=relationships,proto3" json:"relationships,omitempty"`
+	Stats         *QueryStats            `protobuf:"bytes,6,opt,name=stats,proto3" json:"stats,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,7,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // set when a paged query has more results
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *QueryResponse) GetQueryId() uint64 {
 	if x != nil {
-		return x.Level
+		return x.QueryId
 	}
 	return 0
 }
 
-func (x *AddCommunityRequest) GetEntityIds() []uint64 {
+func (x *QueryResponse) GetTextunits() []*TextUnitResult {
 	if x != nil {
-		return x.EntityIds
+		return x.Textunits
 	}
 	return nil
 }
 
-func (x *AddCommunityRequest) GetRelationshipIds() []uint64 {
+func (x *QueryResponse) GetEntities() []*EntityResult {
 	if x != nil {
-		return x.RelationshipIds
+		return x.Entities
 	}
 	return nil
 }
 
-func (x *AddCommunityRequest) GetEmbedding() []float32 {
+func (x *QueryResponse) GetCommunities() []*CommunityResult {
 	if x != nil {
-		return x.Embedding
+		return x.Communities
 	}
 	return nil
 }
 
-type ComputeCommunitiesRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Resolution    float64                `protobuf:"fixed64,1,opt,name=resolution,proto3" json:"resolution,omitempty"`
-	Iterations    int32                  `protobuf:"varint,2,opt,name=iterations,proto3" json:"iterations,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
-}
-
-func (x *ComputeCommunitiesRequest) Reset() {
-	*x = ComputeCommunitiesRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[23]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
-}
-
-func (x *ComputeCommunitiesRequest) String() string {
-	return protoimpl.X.MessageStringOf(x)
-}
-
-func (*ComputeCommunitiesRequest) ProtoMessage() {}
-
-func (x *ComputeCommunitiesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[23]
+func (x *QueryResponse) GetRelationships() []*RelationshipResult {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.Relationships
 	}
-	return mi.MessageOf(x)
-}
-
-// Deprecated: Use ComputeCommunitiesRequest.ProtoReflect.Descriptor instead.
-func (*ComputeCommunitiesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{23}
+	return nil
 }
 
-func (x *ComputeCommunitiesRequest) GetResolution() float64 {
+func (x *QueryResponse) GetStats() *QueryStats {
 	if x != nil {
-		return x.Resolution
+		return x.Stats
 	}
-	return 0
+	return nil
 }
 
-func (x *ComputeCommunitiesRequest) GetIterations() int32 {
+func (x *QueryResponse) GetNextCursor() string {
 	if x != nil {
-		return x.Iterations
+		return x.NextCursor
 	}
-	return 0
+	return ""
 }
 
-type ComputeCommunitiesResponse struct {
+// One frame of a CMD_QUERY_STREAM response. Results arrive text units
+// first, then entities, communities and relationships; query_id and stats
+// are only set in the last chunk.
+type QueryStreamChunk struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
-	Communities   []*Community           `protobuf:"bytes,2,rep,name=communities,proto3" json:"communities,omitempty"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Results       *QueryResponse         `protobuf:"bytes,2,opt,name=results,proto3" json:"results,omitempty"`
+	Last          bool                   `protobuf:"varint,3,opt,name=last,proto3" json:"last,omitempty"` // terminal marker: no chunks follow
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ComputeCommunitiesResponse) Reset() {
-	*x = ComputeCommunitiesResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[24]
+func (x *QueryStreamChunk) Reset() {
+	*x = QueryStreamChunk{}
+	mi := &file_proto_gibram_proto_msgTypes[53]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ComputeCommunitiesResponse) String() string {
+func (x *QueryStreamChunk) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ComputeCommunitiesResponse) ProtoMessage() {}
+func (*QueryStreamChunk) ProtoMessage() {}
 
-func (x *ComputeCommunitiesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[24]
+func (x *QueryStreamChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[53]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2011,48 +4361,55 @@ func (x *ComputeCommunitiesResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ComputeCommunitiesResponse.ProtoReflect.Descriptor instead.
-func (*ComputeCommunitiesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{24}
+// Deprecated: Use QueryStreamChunk.ProtoReflect.Descriptor instead.
+func (*QueryStreamChunk) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{53}
 }
 
-func (x *ComputeCommunitiesResponse) GetCount() int32 {
+func (x *QueryStreamChunk) GetSeq() uint64 {
 	if x != nil {
-		return x.Count
+		return x.Seq
 	}
 	return 0
 }
 
-func (x *ComputeCommunitiesResponse) GetCommunities() []*Community {
+func (x *QueryStreamChunk) GetResults() *QueryResponse {
 	if x != nil {
-		return x.Communities
+		return x.Results
 	}
 	return nil
 }
 
-type LinkTextUnitEntityRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	TextunitId    uint64                 `protobuf:"varint,1,opt,name=textunit_id,json=textunitId,proto3" json:"textunit_id,omitempty"`
-	EntityId      uint64                 `protobuf:"varint,2,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *QueryStreamChunk) GetLast() bool {
+	if x != nil {
+		return x.Last
+	}
+	return false
 }
 
-func (x *LinkTextUnitEntityRequest) Reset() {
-	*x = LinkTextUnitEntityRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[25]
+type ListQueriesRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Limit          int32                  `protobuf:"varint,1,opt,name=limit,proto3" json:"limit,omitempty"`                                         // most recent queries to return (0 = whole history)
+	IncludeVectors bool                   `protobuf:"varint,2,opt,name=include_vectors,json=includeVectors,proto3" json:"include_vectors,omitempty"` // keep query_vector in the returned specs
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ListQueriesRequest) Reset() {
+	*x = ListQueriesRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[54]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LinkTextUnitEntityRequest) String() string {
+func (x *ListQueriesRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LinkTextUnitEntityRequest) ProtoMessage() {}
+func (*ListQueriesRequest) ProtoMessage() {}
 
-func (x *LinkTextUnitEntityRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[25]
+func (x *ListQueriesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[54]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2063,56 +4420,53 @@ func (x *LinkTextUnitEntityRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LinkTextUnitEntityRequest.ProtoReflect.Descriptor instead.
-func (*LinkTextUnitEntityRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{25}
+// Deprecated: Use ListQueriesRequest.ProtoReflect.Descriptor instead.
+func (*ListQueriesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{54}
 }
 
-func (x *LinkTextUnitEntityRequest) GetTextunitId() uint64 {
+func (x *ListQueriesRequest) GetLimit() int32 {
 	if x != nil {
-		return x.TextunitId
+		return x.Limit
 	}
 	return 0
 }
 
-func (x *LinkTextUnitEntityRequest) GetEntityId() uint64 {
+func (x *ListQueriesRequest) GetIncludeVectors() bool {
 	if x != nil {
-		return x.EntityId
+		return x.IncludeVectors
 	}
-	return 0
+	return false
 }
 
-type QueryRequest struct {
-	state             protoimpl.MessageState `protogen:"open.v1"`
-	QueryVector       []float32              `protobuf:"fixed32,1,rep,packed,name=query_vector,json=queryVector,proto3" json:"query_vector,omitempty"`
-	SearchTypes       []string               `protobuf:"bytes,2,rep,name=search_types,json=searchTypes,proto3" json:"search_types,omitempty"` // "textunit", "entity", "community"
-	TopK              int32                  `protobuf:"varint,3,opt,name=top_k,json=topK,proto3" json:"top_k,omitempty"`
-	KHops             int32                  `protobuf:"varint,4,opt,name=k_hops,json=kHops,proto3" json:"k_hops,omitempty"`
-	MaxEntities       int32                  `protobuf:"varint,5,opt,name=max_entities,json=maxEntities,proto3" json:"max_entities,omitempty"`
-	MaxTextunits      int32                  `protobuf:"varint,6,opt,name=max_textunits,json=maxTextunits,proto3" json:"max_textunits,omitempty"`
-	MaxCommunities    int32                  `protobuf:"varint,7,opt,name=max_communities,json=maxCommunities,proto3" json:"max_communities,omitempty"`
-	SeedEntityIds     []uint64               `protobuf:"varint,8,rep,packed,name=seed_entity_ids,json=seedEntityIds,proto3" json:"seed_entity_ids,omitempty"`
-	FilterEntityTypes []string               `protobuf:"bytes,9,rep,name=filter_entity_types,json=filterEntityTypes,proto3" json:"filter_entity_types,omitempty"`
-	FilterRelTypes    []string               `protobuf:"bytes,10,rep,name=filter_rel_types,json=filterRelTypes,proto3" json:"filter_rel_types,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+type QueryHistoryEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	QueryId       uint64                 `protobuf:"varint,1,opt,name=query_id,json=queryId,proto3" json:"query_id,omitempty"`
+	Spec          *QueryRequest          `protobuf:"bytes,2,opt,name=spec,proto3" json:"spec,omitempty"` // as run, after any min_results relaxation
+	Stats         *QueryStats            `protobuf:"bytes,3,opt,name=stats,proto3" json:"stats,omitempty"`
+	At            int64                  `protobuf:"varint,4,opt,name=at,proto3" json:"at,omitempty"`               // unix seconds
+	Textunits     int32                  `protobuf:"varint,5,opt,name=textunits,proto3" json:"textunits,omitempty"` // result counts
+	Entities      int32                  `protobuf:"varint,6,opt,name=entities,proto3" json:"entities,omitempty"`
+	Communities   int32                  `protobuf:"varint,7,opt,name=communities,proto3" json:"communities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
-func (x *QueryRequest) Reset() {
-	*x = QueryRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[26]
+func (x *QueryHistoryEntry) Reset() {
+	*x = QueryHistoryEntry{}
+	mi := &file_proto_gibram_proto_msgTypes[55]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *QueryRequest) String() string {
+func (x *QueryHistoryEntry) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*QueryRequest) ProtoMessage() {}
+func (*QueryHistoryEntry) ProtoMessage() {}
 
-func (x *QueryRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[26]
+func (x *QueryHistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[55]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2123,105 +4477,82 @@ func (x *QueryRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
-func (*QueryRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{26}
+// Deprecated: Use QueryHistoryEntry.ProtoReflect.Descriptor instead.
+func (*QueryHistoryEntry) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{55}
 }
 
-func (x *QueryRequest) GetQueryVector() []float32 {
+func (x *QueryHistoryEntry) GetQueryId() uint64 {
 	if x != nil {
-		return x.QueryVector
+		return x.QueryId
 	}
-	return nil
+	return 0
 }
 
-func (x *QueryRequest) GetSearchTypes() []string {
+func (x *QueryHistoryEntry) GetSpec() *QueryRequest {
 	if x != nil {
-		return x.SearchTypes
+		return x.Spec
 	}
 	return nil
 }
 
-func (x *QueryRequest) GetTopK() int32 {
+func (x *QueryHistoryEntry) GetStats() *QueryStats {
 	if x != nil {
-		return x.TopK
+		return x.Stats
 	}
-	return 0
+	return nil
 }
 
-func (x *QueryRequest) GetKHops() int32 {
+func (x *QueryHistoryEntry) GetAt() int64 {
 	if x != nil {
-		return x.KHops
+		return x.At
 	}
 	return 0
 }
 
-func (x *QueryRequest) GetMaxEntities() int32 {
+func (x *QueryHistoryEntry) GetTextunits() int32 {
 	if x != nil {
-		return x.MaxEntities
+		return x.Textunits
 	}
 	return 0
 }
 
-func (x *QueryRequest) GetMaxTextunits() int32 {
+func (x *QueryHistoryEntry) GetEntities() int32 {
 	if x != nil {
-		return x.MaxTextunits
+		return x.Entities
 	}
 	return 0
 }
 
-func (x *QueryRequest) GetMaxCommunities() int32 {
+func (x *QueryHistoryEntry) GetCommunities() int32 {
 	if x != nil {
-		return x.MaxCommunities
+		return x.Communities
 	}
 	return 0
 }
 
-func (x *QueryRequest) GetSeedEntityIds() []uint64 {
-	if x != nil {
-		return x.SeedEntityIds
-	}
-	return nil
-}
-
-func (x *QueryRequest) GetFilterEntityTypes() []string {
-	if x != nil {
-		return x.FilterEntityTypes
-	}
-	return nil
-}
-
-func (x *QueryRequest) GetFilterRelTypes() []string {
-	if x != nil {
-		return x.FilterRelTypes
-	}
-	return nil
-}
-
-type TextUnitResult struct {
+type ListQueriesResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Textunit      *TextUnit              `protobuf:"bytes,1,opt,name=textunit,proto3" json:"textunit,omitempty"`
-	Similarity    float32                `protobuf:"fixed32,2,opt,name=similarity,proto3" json:"similarity,omitempty"`
-	Hop           int32                  `protobuf:"varint,3,opt,name=hop,proto3" json:"hop,omitempty"`
+	Queries       []*QueryHistoryEntry   `protobuf:"bytes,1,rep,name=queries,proto3" json:"queries,omitempty"` // most recent first
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *TextUnitResult) Reset() {
-	*x = TextUnitResult{}
-	mi := &file_proto_gibram_proto_msgTypes[27]
+func (x *ListQueriesResponse) Reset() {
+	*x = ListQueriesResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[56]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TextUnitResult) String() string {
+func (x *ListQueriesResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TextUnitResult) ProtoMessage() {}
+func (*ListQueriesResponse) ProtoMessage() {}
 
-func (x *TextUnitResult) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[27]
+func (x *ListQueriesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[56]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2232,56 +4563,41 @@ func (x *TextUnitResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TextUnitResult.ProtoReflect.Descriptor instead.
-func (*TextUnitResult) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{27}
+// Deprecated: Use ListQueriesResponse.ProtoReflect.Descriptor instead.
+func (*ListQueriesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{56}
 }
 
-func (x *TextUnitResult) GetTextunit() *TextUnit {
+func (x *ListQueriesResponse) GetQueries() []*QueryHistoryEntry {
 	if x != nil {
-		return x.Textunit
+		return x.Queries
 	}
 	return nil
 }
 
-func (x *TextUnitResult) GetSimilarity() float32 {
-	if x != nil {
-		return x.Similarity
-	}
-	return 0
-}
-
-func (x *TextUnitResult) GetHop() int32 {
-	if x != nil {
-		return x.Hop
-	}
-	return 0
-}
-
-type EntityResult struct {
+type QueryStatsSummaryRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Entity        *Entity                `protobuf:"bytes,1,opt,name=entity,proto3" json:"entity,omitempty"`
-	Similarity    float32                `protobuf:"fixed32,2,opt,name=similarity,proto3" json:"similarity,omitempty"`
-	Hop           int32                  `protobuf:"varint,3,opt,name=hop,proto3" json:"hop,omitempty"`
+	WindowSeconds int64                  `protobuf:"varint,1,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"` // aggregation window (0 = 15 minutes)
+	SessionOnly   bool                   `protobuf:"varint,2,opt,name=session_only,json=sessionOnly,proto3" json:"session_only,omitempty"`       // only queries from the envelope session (always set for session-scoped keys)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *EntityResult) Reset() {
-	*x = EntityResult{}
-	mi := &file_proto_gibram_proto_msgTypes[28]
+func (x *QueryStatsSummaryRequest) Reset() {
+	*x = QueryStatsSummaryRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[57]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *EntityResult) String() string {
+func (x *QueryStatsSummaryRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*EntityResult) ProtoMessage() {}
+func (*QueryStatsSummaryRequest) ProtoMessage() {}
 
-func (x *EntityResult) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[28]
+func (x *QueryStatsSummaryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[57]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2292,55 +4608,60 @@ func (x *EntityResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use EntityResult.ProtoReflect.Descriptor instead.
-func (*EntityResult) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{28}
-}
-
-func (x *EntityResult) GetEntity() *Entity {
-	if x != nil {
-		return x.Entity
-	}
-	return nil
+// Deprecated: Use QueryStatsSummaryRequest.ProtoReflect.Descriptor instead.
+func (*QueryStatsSummaryRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{57}
 }
 
-func (x *EntityResult) GetSimilarity() float32 {
+func (x *QueryStatsSummaryRequest) GetWindowSeconds() int64 {
 	if x != nil {
-		return x.Similarity
+		return x.WindowSeconds
 	}
 	return 0
 }
 
-func (x *EntityResult) GetHop() int32 {
+func (x *QueryStatsSummaryRequest) GetSessionOnly() bool {
 	if x != nil {
-		return x.Hop
+		return x.SessionOnly
 	}
-	return 0
+	return false
 }
 
-type CommunityResult struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Community     *Community             `protobuf:"bytes,1,opt,name=community,proto3" json:"community,omitempty"`
-	Similarity    float32                `protobuf:"fixed32,2,opt,name=similarity,proto3" json:"similarity,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type QueryStatsSummaryResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	WindowSeconds    int64                  `protobuf:"varint,1,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"`
+	Count            int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	AvgLatencyMicros float64                `protobuf:"fixed64,3,opt,name=avg_latency_micros,json=avgLatencyMicros,proto3" json:"avg_latency_micros,omitempty"`
+	P50LatencyMicros float64                `protobuf:"fixed64,4,opt,name=p50_latency_micros,json=p50LatencyMicros,proto3" json:"p50_latency_micros,omitempty"`
+	P95LatencyMicros float64                `protobuf:"fixed64,5,opt,name=p95_latency_micros,json=p95LatencyMicros,proto3" json:"p95_latency_micros,omitempty"`
+	P99LatencyMicros float64                `protobuf:"fixed64,6,opt,name=p99_latency_micros,json=p99LatencyMicros,proto3" json:"p99_latency_micros,omitempty"`
+	AvgTextunits     float64                `protobuf:"fixed64,7,opt,name=avg_textunits,json=avgTextunits,proto3" json:"avg_textunits,omitempty"`
+	AvgEntities      float64                `protobuf:"fixed64,8,opt,name=avg_entities,json=avgEntities,proto3" json:"avg_entities,omitempty"`
+	AvgCommunities   float64                `protobuf:"fixed64,9,opt,name=avg_communities,json=avgCommunities,proto3" json:"avg_communities,omitempty"`
+	AvgKHops         float64                `protobuf:"fixed64,10,opt,name=avg_k_hops,json=avgKHops,proto3" json:"avg_k_hops,omitempty"`
+	EmptyResults     int64                  `protobuf:"varint,11,opt,name=empty_results,json=emptyResults,proto3" json:"empty_results,omitempty"` // queries that returned no context
+	Relaxed          int64                  `protobuf:"varint,12,opt,name=relaxed,proto3" json:"relaxed,omitempty"`                               // queries relaxed for min_results
+	CacheHits        int64                  `protobuf:"varint,13,opt,name=cache_hits,json=cacheHits,proto3" json:"cache_hits,omitempty"`          // queries served from the query cache
+	CacheHitRate     float64                `protobuf:"fixed64,14,opt,name=cache_hit_rate,json=cacheHitRate,proto3" json:"cache_hit_rate,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
-func (x *CommunityResult) Reset() {
-	*x = CommunityResult{}
-	mi := &file_proto_gibram_proto_msgTypes[29]
+func (x *QueryStatsSummaryResponse) Reset() {
+	*x = QueryStatsSummaryResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[58]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *CommunityResult) String() string {
+func (x *QueryStatsSummaryResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CommunityResult) ProtoMessage() {}
+func (*QueryStatsSummaryResponse) ProtoMessage() {}
 
-func (x *CommunityResult) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[29]
+func (x *QueryStatsSummaryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[58]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2351,109 +4672,138 @@ func (x *CommunityResult) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CommunityResult.ProtoReflect.Descriptor instead.
-func (*CommunityResult) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{29}
+// Deprecated: Use QueryStatsSummaryResponse.ProtoReflect.Descriptor instead.
+func (*QueryStatsSummaryResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{58}
 }
 
-func (x *CommunityResult) GetCommunity() *Community {
+func (x *QueryStatsSummaryResponse) GetWindowSeconds() int64 {
 	if x != nil {
-		return x.Community
+		return x.WindowSeconds
 	}
-	return nil
+	return 0
 }
 
-func (x *CommunityResult) GetSimilarity() float32 {
+func (x *QueryStatsSummaryResponse) GetCount() int64 {
 	if x != nil {
-		return x.Similarity
+		return x.Count
 	}
 	return 0
 }
 
-type RelationshipResult struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Relationship  *Relationship          `protobuf:"bytes,1,opt,name=relationship,proto3" json:"relationship,omitempty"`
-	SourceTitle   string                 `protobuf:"bytes,2,opt,name=source_title,json=sourceTitle,proto3" json:"source_title,omitempty"`
-	TargetTitle   string                 `protobuf:"bytes,3,opt,name=target_title,json=targetTitle,proto3" json:"target_title,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *QueryStatsSummaryResponse) GetAvgLatencyMicros() float64 {
+	if x != nil {
+		return x.AvgLatencyMicros
+	}
+	return 0
 }
 
-func (x *RelationshipResult) Reset() {
-	*x = RelationshipResult{}
-	mi := &file_proto_gibram_proto_msgTypes[30]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *QueryStatsSummaryResponse) GetP50LatencyMicros() float64 {
+	if x != nil {
+		return x.P50LatencyMicros
+	}
+	return 0
 }
 
-func (x *RelationshipResult) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *QueryStatsSummaryResponse) GetP95LatencyMicros() float64 {
+	if x != nil {
+		return x.P95LatencyMicros
+	}
+	return 0
 }
 
-func (*RelationshipResult) ProtoMessage() {}
+func (x *QueryStatsSummaryResponse) GetP99LatencyMicros() float64 {
+	if x != nil {
+		return x.P99LatencyMicros
+	}
+	return 0
+}
 
-func (x *RelationshipResult) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[30]
+func (x *QueryStatsSummaryResponse) GetAvgTextunits() float64 {
 	if x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+		return x.AvgTextunits
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use RelationshipResult.ProtoReflect.Descriptor instead.
-func (*RelationshipResult) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{30}
+func (x *QueryStatsSummaryResponse) GetAvgEntities() float64 {
+	if x != nil {
+		return x.AvgEntities
+	}
+	return 0
 }
 
-func (x *RelationshipResult) GetRelationship() *Relationship {
+func (x *QueryStatsSummaryResponse) GetAvgCommunities() float64 {
 	if x != nil {
-		return x.Relationship
+		return x.AvgCommunities
 	}
-	return nil
+	return 0
 }
 
-func (x *RelationshipResult) GetSourceTitle() string {
+func (x *QueryStatsSummaryResponse) GetAvgKHops() float64 {
 	if x != nil {
-		return x.SourceTitle
+		return x.AvgKHops
 	}
-	return ""
+	return 0
 }
 
-func (x *RelationshipResult) GetTargetTitle() string {
+func (x *QueryStatsSummaryResponse) GetEmptyResults() int64 {
 	if x != nil {
-		return x.TargetTitle
+		return x.EmptyResults
 	}
-	return ""
+	return 0
 }
 
-type QueryStats struct {
-	state           protoimpl.MessageState `protogen:"open.v1"`
-	DurationMicros  int64                  `protobuf:"varint,1,opt,name=duration_micros,json=durationMicros,proto3" json:"duration_micros,omitempty"`
-	VectorSearches  int32                  `protobuf:"varint,2,opt,name=vector_searches,json=vectorSearches,proto3" json:"vector_searches,omitempty"`
-	GraphTraversals int32                  `protobuf:"varint,3,opt,name=graph_traversals,json=graphTraversals,proto3" json:"graph_traversals,omitempty"`
-	unknownFields   protoimpl.UnknownFields
-	sizeCache       protoimpl.SizeCache
+func (x *QueryStatsSummaryResponse) GetRelaxed() int64 {
+	if x != nil {
+		return x.Relaxed
+	}
+	return 0
 }
 
-func (x *QueryStats) Reset() {
-	*x = QueryStats{}
-	mi := &file_proto_gibram_proto_msgTypes[31]
+func (x *QueryStatsSummaryResponse) GetCacheHits() int64 {
+	if x != nil {
+		return x.CacheHits
+	}
+	return 0
+}
+
+func (x *QueryStatsSummaryResponse) GetCacheHitRate() float64 {
+	if x != nil {
+		return x.CacheHitRate
+	}
+	return 0
+}
+
+// Per-command latency since server start (CMD_STATS takes an Empty payload)
+type CommandStats struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Command          string                 `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"` // e.g. "query", "add_entity"
+	Count            int64                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Errors           int64                  `protobuf:"varint,3,opt,name=errors,proto3" json:"errors,omitempty"`
+	AvgLatencyMicros float64                `protobuf:"fixed64,4,opt,name=avg_latency_micros,json=avgLatencyMicros,proto3" json:"avg_latency_micros,omitempty"`
+	P50LatencyMicros float64                `protobuf:"fixed64,5,opt,name=p50_latency_micros,json=p50LatencyMicros,proto3" json:"p50_latency_micros,omitempty"` // estimated from histogram buckets
+	P95LatencyMicros float64                `protobuf:"fixed64,6,opt,name=p95_latency_micros,json=p95LatencyMicros,proto3" json:"p95_latency_micros,omitempty"`
+	P99LatencyMicros float64                `protobuf:"fixed64,7,opt,name=p99_latency_micros,json=p99LatencyMicros,proto3" json:"p99_latency_micros,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *CommandStats) Reset() {
+	*x = CommandStats{}
+	mi := &file_proto_gibram_proto_msgTypes[59]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *QueryStats) String() string {
+func (x *CommandStats) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*QueryStats) ProtoMessage() {}
+func (*CommandStats) ProtoMessage() {}
 
-func (x *QueryStats) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[31]
+func (x *CommandStats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[59]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2464,59 +4814,84 @@ func (x *QueryStats) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use QueryStats.ProtoReflect.Descriptor instead.
-func (*QueryStats) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{31}
+// Deprecated: Use CommandStats.ProtoReflect.Descriptor instead.
+func (*CommandStats) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{59}
 }
 
-func (x *QueryStats) GetDurationMicros() int64 {
+func (x *CommandStats) GetCommand() string {
 	if x != nil {
-		return x.DurationMicros
+		return x.Command
+	}
+	return ""
+}
+
+func (x *CommandStats) GetCount() int64 {
+	if x != nil {
+		return x.Count
 	}
 	return 0
 }
 
-func (x *QueryStats) GetVectorSearches() int32 {
+func (x *CommandStats) GetErrors() int64 {
 	if x != nil {
-		return x.VectorSearches
+		return x.Errors
 	}
 	return 0
 }
 
-func (x *QueryStats) GetGraphTraversals() int32 {
+func (x *CommandStats) GetAvgLatencyMicros() float64 {
 	if x != nil {
-		return x.GraphTraversals
+		return x.AvgLatencyMicros
 	}
 	return 0
 }
 
-type QueryResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	QueryId       uint64                 `protobuf:"varint,1,opt,name=query_id,json=queryId,proto3" json:"query_id,omitempty"`
-	Textunits     []*TextUnitResult      `protobuf:"bytes,2,rep,name=textunits,proto3" json:"textunits,omitempty"`
-	Entities      []*EntityResult        `protobuf:"bytes,3,rep,name=entities,proto3" json:"entities,omitempty"`
-	Communities   []*CommunityResult     `protobuf:"bytes,4,rep,name=communities,proto3" json:"communities,omitempty"`
-	Relationships []*RelationshipResult  `protobuf:"bytes,5,rep,name=relationships,proto3" json:"relationships,omitempty"`
-	Stats         *QueryStats            `protobuf:"bytes,6,opt,name=stats,proto3" json:"stats,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *CommandStats) GetP50LatencyMicros() float64 {
+	if x != nil {
+		return x.P50LatencyMicros
+	}
+	return 0
 }
 
-func (x *QueryResponse) Reset() {
-	*x = QueryResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[32]
+func (x *CommandStats) GetP95LatencyMicros() float64 {
+	if x != nil {
+		return x.P95LatencyMicros
+	}
+	return 0
+}
+
+func (x *CommandStats) GetP99LatencyMicros() float64 {
+	if x != nil {
+		return x.P99LatencyMicros
+	}
+	return 0
+}
+
+type StatsResponse struct {
+	state                protoimpl.MessageState `protogen:"open.v1"`
+	UptimeSeconds        int64                  `protobuf:"varint,1,opt,name=uptime_seconds,json=uptimeSeconds,proto3" json:"uptime_seconds,omitempty"`
+	SlowQueryThresholdMs int64                  `protobuf:"varint,2,opt,name=slow_query_threshold_ms,json=slowQueryThresholdMs,proto3" json:"slow_query_threshold_ms,omitempty"` // 0 = slow-query log disabled
+	Commands             []*CommandStats        `protobuf:"bytes,3,rep,name=commands,proto3" json:"commands,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *StatsResponse) Reset() {
+	*x = StatsResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[60]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *QueryResponse) String() string {
+func (x *StatsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*QueryResponse) ProtoMessage() {}
+func (*StatsResponse) ProtoMessage() {}
 
-func (x *QueryResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[32]
+func (x *StatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[60]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2527,49 +4902,28 @@ func (x *QueryResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
-func (*QueryResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{32}
+// Deprecated: Use StatsResponse.ProtoReflect.Descriptor instead.
+func (*StatsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{60}
 }
 
-func (x *QueryResponse) GetQueryId() uint64 {
+func (x *StatsResponse) GetUptimeSeconds() int64 {
 	if x != nil {
-		return x.QueryId
+		return x.UptimeSeconds
 	}
 	return 0
 }
 
-func (x *QueryResponse) GetTextunits() []*TextUnitResult {
-	if x != nil {
-		return x.Textunits
-	}
-	return nil
-}
-
-func (x *QueryResponse) GetEntities() []*EntityResult {
-	if x != nil {
-		return x.Entities
-	}
-	return nil
-}
-
-func (x *QueryResponse) GetCommunities() []*CommunityResult {
-	if x != nil {
-		return x.Communities
-	}
-	return nil
-}
-
-func (x *QueryResponse) GetRelationships() []*RelationshipResult {
+func (x *StatsResponse) GetSlowQueryThresholdMs() int64 {
 	if x != nil {
-		return x.Relationships
+		return x.SlowQueryThresholdMs
 	}
-	return nil
+	return 0
 }
 
-func (x *QueryResponse) GetStats() *QueryStats {
+func (x *StatsResponse) GetCommands() []*CommandStats {
 	if x != nil {
-		return x.Stats
+		return x.Commands
 	}
 	return nil
 }
@@ -2583,7 +4937,7 @@ type ExplainRequest struct {
 
 func (x *ExplainRequest) Reset() {
 	*x = ExplainRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[33]
+	mi := &file_proto_gibram_proto_msgTypes[61]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2595,7 +4949,7 @@ func (x *ExplainRequest) String() string {
 func (*ExplainRequest) ProtoMessage() {}
 
 func (x *ExplainRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[33]
+	mi := &file_proto_gibram_proto_msgTypes[61]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2608,7 +4962,7 @@ func (x *ExplainRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExplainRequest.ProtoReflect.Descriptor instead.
 func (*ExplainRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{33}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{61}
 }
 
 func (x *ExplainRequest) GetQueryId() uint64 {
@@ -2630,7 +4984,7 @@ type SeedInfo struct {
 
 func (x *SeedInfo) Reset() {
 	*x = SeedInfo{}
-	mi := &file_proto_gibram_proto_msgTypes[34]
+	mi := &file_proto_gibram_proto_msgTypes[62]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2642,7 +4996,7 @@ func (x *SeedInfo) String() string {
 func (*SeedInfo) ProtoMessage() {}
 
 func (x *SeedInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[34]
+	mi := &file_proto_gibram_proto_msgTypes[62]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2655,7 +5009,7 @@ func (x *SeedInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SeedInfo.ProtoReflect.Descriptor instead.
 func (*SeedInfo) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{34}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{62}
 }
 
 func (x *SeedInfo) GetType() string {
@@ -2700,7 +5054,7 @@ type TraversalStep struct {
 
 func (x *TraversalStep) Reset() {
 	*x = TraversalStep{}
-	mi := &file_proto_gibram_proto_msgTypes[35]
+	mi := &file_proto_gibram_proto_msgTypes[63]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2712,7 +5066,7 @@ func (x *TraversalStep) String() string {
 func (*TraversalStep) ProtoMessage() {}
 
 func (x *TraversalStep) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[35]
+	mi := &file_proto_gibram_proto_msgTypes[63]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2725,7 +5079,7 @@ func (x *TraversalStep) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TraversalStep.ProtoReflect.Descriptor instead.
 func (*TraversalStep) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{35}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{63}
 }
 
 func (x *TraversalStep) GetFromEntityId() uint64 {
@@ -2781,7 +5135,7 @@ type ExplainResponse struct {
 
 func (x *ExplainResponse) Reset() {
 	*x = ExplainResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[36]
+	mi := &file_proto_gibram_proto_msgTypes[64]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2793,7 +5147,7 @@ func (x *ExplainResponse) String() string {
 func (*ExplainResponse) ProtoMessage() {}
 
 func (x *ExplainResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[36]
+	mi := &file_proto_gibram_proto_msgTypes[64]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2806,7 +5160,7 @@ func (x *ExplainResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExplainResponse.ProtoReflect.Descriptor instead.
 func (*ExplainResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{36}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{64}
 }
 
 func (x *ExplainResponse) GetQueryId() uint64 {
@@ -2839,7 +5193,7 @@ type GetByIDRequest struct {
 
 func (x *GetByIDRequest) Reset() {
 	*x = GetByIDRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[37]
+	mi := &file_proto_gibram_proto_msgTypes[65]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2851,7 +5205,7 @@ func (x *GetByIDRequest) String() string {
 func (*GetByIDRequest) ProtoMessage() {}
 
 func (x *GetByIDRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[37]
+	mi := &file_proto_gibram_proto_msgTypes[65]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2864,14 +5218,58 @@ func (x *GetByIDRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetByIDRequest.ProtoReflect.Descriptor instead.
 func (*GetByIDRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{37}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *GetByIDRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type GetByExternalIDRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ExternalId    string                 `protobuf:"bytes,1,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetByExternalIDRequest) Reset() {
+	*x = GetByExternalIDRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetByExternalIDRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetByExternalIDRequest) ProtoMessage() {}
+
+func (x *GetByExternalIDRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[66]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetByExternalIDRequest.ProtoReflect.Descriptor instead.
+func (*GetByExternalIDRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{66}
 }
 
-func (x *GetByIDRequest) GetId() uint64 {
+func (x *GetByExternalIDRequest) GetExternalId() string {
 	if x != nil {
-		return x.Id
+		return x.ExternalId
 	}
-	return 0
+	return ""
 }
 
 type DeleteByIDRequest struct {
@@ -2883,7 +5281,7 @@ type DeleteByIDRequest struct {
 
 func (x *DeleteByIDRequest) Reset() {
 	*x = DeleteByIDRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[38]
+	mi := &file_proto_gibram_proto_msgTypes[67]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2895,7 +5293,7 @@ func (x *DeleteByIDRequest) String() string {
 func (*DeleteByIDRequest) ProtoMessage() {}
 
 func (x *DeleteByIDRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[38]
+	mi := &file_proto_gibram_proto_msgTypes[67]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2908,7 +5306,7 @@ func (x *DeleteByIDRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DeleteByIDRequest.ProtoReflect.Descriptor instead.
 func (*DeleteByIDRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{38}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{67}
 }
 
 func (x *DeleteByIDRequest) GetId() uint64 {
@@ -2928,7 +5326,7 @@ type HealthResponse struct {
 
 func (x *HealthResponse) Reset() {
 	*x = HealthResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[39]
+	mi := &file_proto_gibram_proto_msgTypes[68]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2940,7 +5338,7 @@ func (x *HealthResponse) String() string {
 func (*HealthResponse) ProtoMessage() {}
 
 func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[39]
+	mi := &file_proto_gibram_proto_msgTypes[68]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -2953,7 +5351,7 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
 func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{39}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{68}
 }
 
 func (x *HealthResponse) GetStatus() string {
@@ -2974,13 +5372,14 @@ type ListEntitiesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Cursor        uint64                 `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"` // last seen entity ID (0 = start)
 	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`   // max entities to return (0 = server default)
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`      // only entities of this type (empty = all)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListEntitiesRequest) Reset() {
 	*x = ListEntitiesRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[40]
+	mi := &file_proto_gibram_proto_msgTypes[69]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -2992,7 +5391,7 @@ func (x *ListEntitiesRequest) String() string {
 func (*ListEntitiesRequest) ProtoMessage() {}
 
 func (x *ListEntitiesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[40]
+	mi := &file_proto_gibram_proto_msgTypes[69]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3005,7 +5404,7 @@ func (x *ListEntitiesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListEntitiesRequest.ProtoReflect.Descriptor instead.
 func (*ListEntitiesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{40}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{69}
 }
 
 func (x *ListEntitiesRequest) GetCursor() uint64 {
@@ -3022,6 +5421,13 @@ func (x *ListEntitiesRequest) GetLimit() int32 {
 	return 0
 }
 
+func (x *ListEntitiesRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
 type MSetEntitiesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Entities      []*AddEntityRequest    `protobuf:"bytes,1,rep,name=entities,proto3" json:"entities,omitempty"`
@@ -3031,7 +5437,7 @@ type MSetEntitiesRequest struct {
 
 func (x *MSetEntitiesRequest) Reset() {
 	*x = MSetEntitiesRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[41]
+	mi := &file_proto_gibram_proto_msgTypes[70]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3043,7 +5449,7 @@ func (x *MSetEntitiesRequest) String() string {
 func (*MSetEntitiesRequest) ProtoMessage() {}
 
 func (x *MSetEntitiesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[41]
+	mi := &file_proto_gibram_proto_msgTypes[70]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3056,7 +5462,7 @@ func (x *MSetEntitiesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MSetEntitiesRequest.ProtoReflect.Descriptor instead.
 func (*MSetEntitiesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{41}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{70}
 }
 
 func (x *MSetEntitiesRequest) GetEntities() []*AddEntityRequest {
@@ -3075,7 +5481,7 @@ type MGetEntitiesRequest struct {
 
 func (x *MGetEntitiesRequest) Reset() {
 	*x = MGetEntitiesRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[42]
+	mi := &file_proto_gibram_proto_msgTypes[71]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3087,7 +5493,7 @@ func (x *MGetEntitiesRequest) String() string {
 func (*MGetEntitiesRequest) ProtoMessage() {}
 
 func (x *MGetEntitiesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[42]
+	mi := &file_proto_gibram_proto_msgTypes[71]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3100,7 +5506,7 @@ func (x *MGetEntitiesRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MGetEntitiesRequest.ProtoReflect.Descriptor instead.
 func (*MGetEntitiesRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{42}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{71}
 }
 
 func (x *MGetEntitiesRequest) GetIds() []uint64 {
@@ -3121,7 +5527,7 @@ type EntitiesResponse struct {
 
 func (x *EntitiesResponse) Reset() {
 	*x = EntitiesResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[43]
+	mi := &file_proto_gibram_proto_msgTypes[72]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3133,7 +5539,7 @@ func (x *EntitiesResponse) String() string {
 func (*EntitiesResponse) ProtoMessage() {}
 
 func (x *EntitiesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[43]
+	mi := &file_proto_gibram_proto_msgTypes[72]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3146,7 +5552,7 @@ func (x *EntitiesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use EntitiesResponse.ProtoReflect.Descriptor instead.
 func (*EntitiesResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{43}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{72}
 }
 
 func (x *EntitiesResponse) GetEntities() []*Entity {
@@ -3179,7 +5585,7 @@ type MSetDocumentsRequest struct {
 
 func (x *MSetDocumentsRequest) Reset() {
 	*x = MSetDocumentsRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[44]
+	mi := &file_proto_gibram_proto_msgTypes[73]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3191,7 +5597,7 @@ func (x *MSetDocumentsRequest) String() string {
 func (*MSetDocumentsRequest) ProtoMessage() {}
 
 func (x *MSetDocumentsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[44]
+	mi := &file_proto_gibram_proto_msgTypes[73]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3204,7 +5610,7 @@ func (x *MSetDocumentsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MSetDocumentsRequest.ProtoReflect.Descriptor instead.
 func (*MSetDocumentsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{44}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{73}
 }
 
 func (x *MSetDocumentsRequest) GetDocuments() []*AddDocumentRequest {
@@ -3223,7 +5629,7 @@ type MGetDocumentsRequest struct {
 
 func (x *MGetDocumentsRequest) Reset() {
 	*x = MGetDocumentsRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[45]
+	mi := &file_proto_gibram_proto_msgTypes[74]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3235,7 +5641,7 @@ func (x *MGetDocumentsRequest) String() string {
 func (*MGetDocumentsRequest) ProtoMessage() {}
 
 func (x *MGetDocumentsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[45]
+	mi := &file_proto_gibram_proto_msgTypes[74]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3248,7 +5654,7 @@ func (x *MGetDocumentsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MGetDocumentsRequest.ProtoReflect.Descriptor instead.
 func (*MGetDocumentsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{45}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{74}
 }
 
 func (x *MGetDocumentsRequest) GetIds() []uint64 {
@@ -3268,7 +5674,7 @@ type DocumentsResponse struct {
 
 func (x *DocumentsResponse) Reset() {
 	*x = DocumentsResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[46]
+	mi := &file_proto_gibram_proto_msgTypes[75]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3277,10 +5683,245 @@ func (x *DocumentsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DocumentsResponse) ProtoMessage() {}
+func (*DocumentsResponse) ProtoMessage() {}
+
+func (x *DocumentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[75]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DocumentsResponse.ProtoReflect.Descriptor instead.
+func (*DocumentsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{75}
+}
+
+func (x *DocumentsResponse) GetDocuments() []*Document {
+	if x != nil {
+		return x.Documents
+	}
+	return nil
+}
+
+func (x *DocumentsResponse) GetCreatedIds() []uint64 {
+	if x != nil {
+		return x.CreatedIds
+	}
+	return nil
+}
+
+type MSetTextUnitsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Textunits     []*AddTextUnitRequest  `protobuf:"bytes,1,rep,name=textunits,proto3" json:"textunits,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MSetTextUnitsRequest) Reset() {
+	*x = MSetTextUnitsRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[76]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MSetTextUnitsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MSetTextUnitsRequest) ProtoMessage() {}
+
+func (x *MSetTextUnitsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[76]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MSetTextUnitsRequest.ProtoReflect.Descriptor instead.
+func (*MSetTextUnitsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{76}
+}
+
+func (x *MSetTextUnitsRequest) GetTextunits() []*AddTextUnitRequest {
+	if x != nil {
+		return x.Textunits
+	}
+	return nil
+}
+
+type MGetTextUnitsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []uint64               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MGetTextUnitsRequest) Reset() {
+	*x = MGetTextUnitsRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[77]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MGetTextUnitsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MGetTextUnitsRequest) ProtoMessage() {}
+
+func (x *MGetTextUnitsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[77]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MGetTextUnitsRequest.ProtoReflect.Descriptor instead.
+func (*MGetTextUnitsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{77}
+}
+
+func (x *MGetTextUnitsRequest) GetIds() []uint64 {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+type TextUnitsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Textunits     []*TextUnit            `protobuf:"bytes,1,rep,name=textunits,proto3" json:"textunits,omitempty"`
+	CreatedIds    []uint64               `protobuf:"varint,2,rep,packed,name=created_ids,json=createdIds,proto3" json:"created_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TextUnitsResponse) Reset() {
+	*x = TextUnitsResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[78]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TextUnitsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TextUnitsResponse) ProtoMessage() {}
+
+func (x *TextUnitsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[78]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TextUnitsResponse.ProtoReflect.Descriptor instead.
+func (*TextUnitsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{78}
+}
+
+func (x *TextUnitsResponse) GetTextunits() []*TextUnit {
+	if x != nil {
+		return x.Textunits
+	}
+	return nil
+}
+
+func (x *TextUnitsResponse) GetCreatedIds() []uint64 {
+	if x != nil {
+		return x.CreatedIds
+	}
+	return nil
+}
+
+type MSetRelationshipsRequest struct {
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Relationships []*AddRelationshipRequest `protobuf:"bytes,1,rep,name=relationships,proto3" json:"relationships,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MSetRelationshipsRequest) Reset() {
+	*x = MSetRelationshipsRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[79]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MSetRelationshipsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MSetRelationshipsRequest) ProtoMessage() {}
+
+func (x *MSetRelationshipsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[79]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MSetRelationshipsRequest.ProtoReflect.Descriptor instead.
+func (*MSetRelationshipsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{79}
+}
+
+func (x *MSetRelationshipsRequest) GetRelationships() []*AddRelationshipRequest {
+	if x != nil {
+		return x.Relationships
+	}
+	return nil
+}
+
+type MGetRelationshipsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []uint64               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MGetRelationshipsRequest) Reset() {
+	*x = MGetRelationshipsRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[80]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MGetRelationshipsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MGetRelationshipsRequest) ProtoMessage() {}
 
-func (x *DocumentsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[46]
+func (x *MGetRelationshipsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[80]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3291,47 +5932,40 @@ func (x *DocumentsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DocumentsResponse.ProtoReflect.Descriptor instead.
-func (*DocumentsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{46}
-}
-
-func (x *DocumentsResponse) GetDocuments() []*Document {
-	if x != nil {
-		return x.Documents
-	}
-	return nil
+// Deprecated: Use MGetRelationshipsRequest.ProtoReflect.Descriptor instead.
+func (*MGetRelationshipsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{80}
 }
 
-func (x *DocumentsResponse) GetCreatedIds() []uint64 {
+func (x *MGetRelationshipsRequest) GetIds() []uint64 {
 	if x != nil {
-		return x.CreatedIds
+		return x.Ids
 	}
 	return nil
 }
 
-type MSetTextUnitsRequest struct {
+type MDeleteRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Textunits     []*AddTextUnitRequest  `protobuf:"bytes,1,rep,name=textunits,proto3" json:"textunits,omitempty"`
+	Ids           []uint64               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MSetTextUnitsRequest) Reset() {
-	*x = MSetTextUnitsRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[47]
+func (x *MDeleteRequest) Reset() {
+	*x = MDeleteRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[81]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MSetTextUnitsRequest) String() string {
+func (x *MDeleteRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MSetTextUnitsRequest) ProtoMessage() {}
+func (*MDeleteRequest) ProtoMessage() {}
 
-func (x *MSetTextUnitsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[47]
+func (x *MDeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[81]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3342,40 +5976,40 @@ func (x *MSetTextUnitsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MSetTextUnitsRequest.ProtoReflect.Descriptor instead.
-func (*MSetTextUnitsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{47}
+// Deprecated: Use MDeleteRequest.ProtoReflect.Descriptor instead.
+func (*MDeleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{81}
 }
 
-func (x *MSetTextUnitsRequest) GetTextunits() []*AddTextUnitRequest {
+func (x *MDeleteRequest) GetIds() []uint64 {
 	if x != nil {
-		return x.Textunits
+		return x.Ids
 	}
 	return nil
 }
 
-type MGetTextUnitsRequest struct {
+type MDeleteResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ids           []uint64               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	DeletedCount  int32                  `protobuf:"varint,1,opt,name=deleted_count,json=deletedCount,proto3" json:"deleted_count,omitempty"` // IDs that existed and were deleted
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MGetTextUnitsRequest) Reset() {
-	*x = MGetTextUnitsRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[48]
+func (x *MDeleteResponse) Reset() {
+	*x = MDeleteResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[82]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MGetTextUnitsRequest) String() string {
+func (x *MDeleteResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MGetTextUnitsRequest) ProtoMessage() {}
+func (*MDeleteResponse) ProtoMessage() {}
 
-func (x *MGetTextUnitsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[48]
+func (x *MDeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[82]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3386,41 +6020,41 @@ func (x *MGetTextUnitsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MGetTextUnitsRequest.ProtoReflect.Descriptor instead.
-func (*MGetTextUnitsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{48}
+// Deprecated: Use MDeleteResponse.ProtoReflect.Descriptor instead.
+func (*MDeleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{82}
 }
 
-func (x *MGetTextUnitsRequest) GetIds() []uint64 {
+func (x *MDeleteResponse) GetDeletedCount() int32 {
 	if x != nil {
-		return x.Ids
+		return x.DeletedCount
 	}
-	return nil
+	return 0
 }
 
-type TextUnitsResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Textunits     []*TextUnit            `protobuf:"bytes,1,rep,name=textunits,proto3" json:"textunits,omitempty"`
-	CreatedIds    []uint64               `protobuf:"varint,2,rep,packed,name=created_ids,json=createdIds,proto3" json:"created_ids,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+type MLinkTextUnitEntityRequest struct {
+	state           protoimpl.MessageState       `protogen:"open.v1"`
+	Links           []*LinkTextUnitEntityRequest `protobuf:"bytes,1,rep,name=links,proto3" json:"links,omitempty"`
+	ContinueOnError bool                         `protobuf:"varint,2,opt,name=continue_on_error,json=continueOnError,proto3" json:"continue_on_error,omitempty"` // keep applying pairs after a failure
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
-func (x *TextUnitsResponse) Reset() {
-	*x = TextUnitsResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[49]
+func (x *MLinkTextUnitEntityRequest) Reset() {
+	*x = MLinkTextUnitEntityRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[83]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *TextUnitsResponse) String() string {
+func (x *MLinkTextUnitEntityRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*TextUnitsResponse) ProtoMessage() {}
+func (*MLinkTextUnitEntityRequest) ProtoMessage() {}
 
-func (x *TextUnitsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[49]
+func (x *MLinkTextUnitEntityRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[83]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3431,47 +6065,50 @@ func (x *TextUnitsResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use TextUnitsResponse.ProtoReflect.Descriptor instead.
-func (*TextUnitsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{49}
+// Deprecated: Use MLinkTextUnitEntityRequest.ProtoReflect.Descriptor instead.
+func (*MLinkTextUnitEntityRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{83}
 }
 
-func (x *TextUnitsResponse) GetTextunits() []*TextUnit {
+func (x *MLinkTextUnitEntityRequest) GetLinks() []*LinkTextUnitEntityRequest {
 	if x != nil {
-		return x.Textunits
+		return x.Links
 	}
 	return nil
 }
 
-func (x *TextUnitsResponse) GetCreatedIds() []uint64 {
+func (x *MLinkTextUnitEntityRequest) GetContinueOnError() bool {
 	if x != nil {
-		return x.CreatedIds
+		return x.ContinueOnError
 	}
-	return nil
+	return false
 }
 
-type MSetRelationshipsRequest struct {
-	state         protoimpl.MessageState    `protogen:"open.v1"`
-	Relationships []*AddRelationshipRequest `protobuf:"bytes,1,rep,name=relationships,proto3" json:"relationships,omitempty"`
+type LinkResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TextunitId    uint64                 `protobuf:"varint,1,opt,name=textunit_id,json=textunitId,proto3" json:"textunit_id,omitempty"`
+	EntityId      uint64                 `protobuf:"varint,2,opt,name=entity_id,json=entityId,proto3" json:"entity_id,omitempty"`
+	Ok            bool                   `protobuf:"varint,3,opt,name=ok,proto3" json:"ok,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MSetRelationshipsRequest) Reset() {
-	*x = MSetRelationshipsRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[50]
+func (x *LinkResult) Reset() {
+	*x = LinkResult{}
+	mi := &file_proto_gibram_proto_msgTypes[84]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MSetRelationshipsRequest) String() string {
+func (x *LinkResult) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MSetRelationshipsRequest) ProtoMessage() {}
+func (*LinkResult) ProtoMessage() {}
 
-func (x *MSetRelationshipsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[50]
+func (x *LinkResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[84]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3482,40 +6119,63 @@ func (x *MSetRelationshipsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MSetRelationshipsRequest.ProtoReflect.Descriptor instead.
-func (*MSetRelationshipsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{50}
+// Deprecated: Use LinkResult.ProtoReflect.Descriptor instead.
+func (*LinkResult) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{84}
 }
 
-func (x *MSetRelationshipsRequest) GetRelationships() []*AddRelationshipRequest {
+func (x *LinkResult) GetTextunitId() uint64 {
 	if x != nil {
-		return x.Relationships
+		return x.TextunitId
 	}
-	return nil
+	return 0
 }
 
-type MGetRelationshipsRequest struct {
+func (x *LinkResult) GetEntityId() uint64 {
+	if x != nil {
+		return x.EntityId
+	}
+	return 0
+}
+
+func (x *LinkResult) GetOk() bool {
+	if x != nil {
+		return x.Ok
+	}
+	return false
+}
+
+func (x *LinkResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type MLinkTextUnitEntityResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Ids           []uint64               `protobuf:"varint,1,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	Results       []*LinkResult          `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"` // one per attempted pair, in request order
+	LinkedCount   int32                  `protobuf:"varint,2,opt,name=linked_count,json=linkedCount,proto3" json:"linked_count,omitempty"`
+	FailedCount   int32                  `protobuf:"varint,3,opt,name=failed_count,json=failedCount,proto3" json:"failed_count,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *MGetRelationshipsRequest) Reset() {
-	*x = MGetRelationshipsRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[51]
+func (x *MLinkTextUnitEntityResponse) Reset() {
+	*x = MLinkTextUnitEntityResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[85]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *MGetRelationshipsRequest) String() string {
+func (x *MLinkTextUnitEntityResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*MGetRelationshipsRequest) ProtoMessage() {}
+func (*MLinkTextUnitEntityResponse) ProtoMessage() {}
 
-func (x *MGetRelationshipsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[51]
+func (x *MLinkTextUnitEntityResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[85]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3526,18 +6186,32 @@ func (x *MGetRelationshipsRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use MGetRelationshipsRequest.ProtoReflect.Descriptor instead.
-func (*MGetRelationshipsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{51}
+// Deprecated: Use MLinkTextUnitEntityResponse.ProtoReflect.Descriptor instead.
+func (*MLinkTextUnitEntityResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{85}
 }
 
-func (x *MGetRelationshipsRequest) GetIds() []uint64 {
+func (x *MLinkTextUnitEntityResponse) GetResults() []*LinkResult {
 	if x != nil {
-		return x.Ids
+		return x.Results
 	}
 	return nil
 }
 
+func (x *MLinkTextUnitEntityResponse) GetLinkedCount() int32 {
+	if x != nil {
+		return x.LinkedCount
+	}
+	return 0
+}
+
+func (x *MLinkTextUnitEntityResponse) GetFailedCount() int32 {
+	if x != nil {
+		return x.FailedCount
+	}
+	return 0
+}
+
 type RelationshipsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Relationships []*Relationship        `protobuf:"bytes,1,rep,name=relationships,proto3" json:"relationships,omitempty"`
@@ -3549,7 +6223,7 @@ type RelationshipsResponse struct {
 
 func (x *RelationshipsResponse) Reset() {
 	*x = RelationshipsResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[52]
+	mi := &file_proto_gibram_proto_msgTypes[86]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3561,7 +6235,7 @@ func (x *RelationshipsResponse) String() string {
 func (*RelationshipsResponse) ProtoMessage() {}
 
 func (x *RelationshipsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[52]
+	mi := &file_proto_gibram_proto_msgTypes[86]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3574,7 +6248,7 @@ func (x *RelationshipsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RelationshipsResponse.ProtoReflect.Descriptor instead.
 func (*RelationshipsResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{52}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{86}
 }
 
 func (x *RelationshipsResponse) GetRelationships() []*Relationship {
@@ -3598,17 +6272,64 @@ func (x *RelationshipsResponse) GetNextCursor() uint64 {
 	return 0
 }
 
+type CommunitiesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Communities   []*Community           `protobuf:"bytes,1,rep,name=communities,proto3" json:"communities,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CommunitiesResponse) Reset() {
+	*x = CommunitiesResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[87]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CommunitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CommunitiesResponse) ProtoMessage() {}
+
+func (x *CommunitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[87]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CommunitiesResponse.ProtoReflect.Descriptor instead.
+func (*CommunitiesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{87}
+}
+
+func (x *CommunitiesResponse) GetCommunities() []*Community {
+	if x != nil {
+		return x.Communities
+	}
+	return nil
+}
+
 type ListRelationshipsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Cursor        uint64                 `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"` // last seen relationship ID (0 = start)
-	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`   // max relationships to return (0 = server default)
+	Cursor        uint64                 `protobuf:"varint,1,opt,name=cursor,proto3" json:"cursor,omitempty"`                     // last seen relationship ID (0 = start)
+	Limit         int32                  `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`                       // max relationships to return (0 = server default)
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`                          // only relationships of this type (empty = all)
+	SourceId      uint64                 `protobuf:"varint,4,opt,name=source_id,json=sourceId,proto3" json:"source_id,omitempty"` // only relationships from this entity (0 = any)
+	TargetId      uint64                 `protobuf:"varint,5,opt,name=target_id,json=targetId,proto3" json:"target_id,omitempty"` // only relationships to this entity (0 = any)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListRelationshipsRequest) Reset() {
 	*x = ListRelationshipsRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[53]
+	mi := &file_proto_gibram_proto_msgTypes[88]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3620,7 +6341,7 @@ func (x *ListRelationshipsRequest) String() string {
 func (*ListRelationshipsRequest) ProtoMessage() {}
 
 func (x *ListRelationshipsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[53]
+	mi := &file_proto_gibram_proto_msgTypes[88]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3633,7 +6354,7 @@ func (x *ListRelationshipsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListRelationshipsRequest.ProtoReflect.Descriptor instead.
 func (*ListRelationshipsRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{53}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{88}
 }
 
 func (x *ListRelationshipsRequest) GetCursor() uint64 {
@@ -3650,16 +6371,40 @@ func (x *ListRelationshipsRequest) GetLimit() int32 {
 	return 0
 }
 
+func (x *ListRelationshipsRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ListRelationshipsRequest) GetSourceId() uint64 {
+	if x != nil {
+		return x.SourceId
+	}
+	return 0
+}
+
+func (x *ListRelationshipsRequest) GetTargetId() uint64 {
+	if x != nil {
+		return x.TargetId
+	}
+	return 0
+}
+
 type PipelineRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Commands      []*Envelope            `protobuf:"bytes,1,rep,name=commands,proto3" json:"commands,omitempty"`
+	Atomic        bool                   `protobuf:"varint,2,opt,name=atomic,proto3" json:"atomic,omitempty"` // apply every command or none: stop at the first error and roll back
+	Labels        []string               `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty"`  // labels[i] names the ID commands[i] returns, for refs of later commands ("" = none)
+	Refs          []*PipelineRef         `protobuf:"bytes,4,rep,name=refs,proto3" json:"refs,omitempty"`      // ID fields filled in from earlier commands before a command runs
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PipelineRequest) Reset() {
 	*x = PipelineRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[54]
+	mi := &file_proto_gibram_proto_msgTypes[89]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3671,7 +6416,7 @@ func (x *PipelineRequest) String() string {
 func (*PipelineRequest) ProtoMessage() {}
 
 func (x *PipelineRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[54]
+	mi := &file_proto_gibram_proto_msgTypes[89]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3684,7 +6429,7 @@ func (x *PipelineRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PipelineRequest.ProtoReflect.Descriptor instead.
 func (*PipelineRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{54}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{89}
 }
 
 func (x *PipelineRequest) GetCommands() []*Envelope {
@@ -3694,16 +6439,101 @@ func (x *PipelineRequest) GetCommands() []*Envelope {
 	return nil
 }
 
+func (x *PipelineRequest) GetAtomic() bool {
+	if x != nil {
+		return x.Atomic
+	}
+	return false
+}
+
+func (x *PipelineRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *PipelineRequest) GetRefs() []*PipelineRef {
+	if x != nil {
+		return x.Refs
+	}
+	return nil
+}
+
+// Fills an ID field of a pipeline command with the ID an earlier, labeled
+// command returned, so a relationship can point at entities added in the
+// same pipeline
+type PipelineRef struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Command       uint32                 `protobuf:"varint,1,opt,name=command,proto3" json:"command,omitempty"` // index of the command to fill in
+	Field         string                 `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`      // proto name of a uint64 payload field; a repeated field gets the ID appended
+	Ref           string                 `protobuf:"bytes,3,opt,name=ref,proto3" json:"ref,omitempty"`          // "$" and the label of an earlier command, like "$alice"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineRef) Reset() {
+	*x = PipelineRef{}
+	mi := &file_proto_gibram_proto_msgTypes[90]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineRef) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineRef) ProtoMessage() {}
+
+func (x *PipelineRef) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[90]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PipelineRef.ProtoReflect.Descriptor instead.
+func (*PipelineRef) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{90}
+}
+
+func (x *PipelineRef) GetCommand() uint32 {
+	if x != nil {
+		return x.Command
+	}
+	return 0
+}
+
+func (x *PipelineRef) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *PipelineRef) GetRef() string {
+	if x != nil {
+		return x.Ref
+	}
+	return ""
+}
+
 type PipelineResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Responses     []*Envelope            `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	RolledBack    bool                   `protobuf:"varint,2,opt,name=rolled_back,json=rolledBack,proto3" json:"rolled_back,omitempty"` // an atomic pipeline failed and its changes were undone
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *PipelineResponse) Reset() {
 	*x = PipelineResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[55]
+	mi := &file_proto_gibram_proto_msgTypes[91]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3715,7 +6545,7 @@ func (x *PipelineResponse) String() string {
 func (*PipelineResponse) ProtoMessage() {}
 
 func (x *PipelineResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[55]
+	mi := &file_proto_gibram_proto_msgTypes[91]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3728,7 +6558,7 @@ func (x *PipelineResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use PipelineResponse.ProtoReflect.Descriptor instead.
 func (*PipelineResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{55}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{91}
 }
 
 func (x *PipelineResponse) GetResponses() []*Envelope {
@@ -3738,6 +6568,13 @@ func (x *PipelineResponse) GetResponses() []*Envelope {
 	return nil
 }
 
+func (x *PipelineResponse) GetRolledBack() bool {
+	if x != nil {
+		return x.RolledBack
+	}
+	return false
+}
+
 type HierarchicalLeidenRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	MaxLevels     int32                  `protobuf:"varint,1,opt,name=max_levels,json=maxLevels,proto3" json:"max_levels,omitempty"`
@@ -3748,7 +6585,7 @@ type HierarchicalLeidenRequest struct {
 
 func (x *HierarchicalLeidenRequest) Reset() {
 	*x = HierarchicalLeidenRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[56]
+	mi := &file_proto_gibram_proto_msgTypes[92]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3760,7 +6597,7 @@ func (x *HierarchicalLeidenRequest) String() string {
 func (*HierarchicalLeidenRequest) ProtoMessage() {}
 
 func (x *HierarchicalLeidenRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[56]
+	mi := &file_proto_gibram_proto_msgTypes[92]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3773,7 +6610,7 @@ func (x *HierarchicalLeidenRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HierarchicalLeidenRequest.ProtoReflect.Descriptor instead.
 func (*HierarchicalLeidenRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{56}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{92}
 }
 
 func (x *HierarchicalLeidenRequest) GetMaxLevels() int32 {
@@ -3800,7 +6637,7 @@ type HierarchicalLeidenResponse struct {
 
 func (x *HierarchicalLeidenResponse) Reset() {
 	*x = HierarchicalLeidenResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[57]
+	mi := &file_proto_gibram_proto_msgTypes[93]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3812,7 +6649,7 @@ func (x *HierarchicalLeidenResponse) String() string {
 func (*HierarchicalLeidenResponse) ProtoMessage() {}
 
 func (x *HierarchicalLeidenResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[57]
+	mi := &file_proto_gibram_proto_msgTypes[93]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3825,7 +6662,7 @@ func (x *HierarchicalLeidenResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HierarchicalLeidenResponse.ProtoReflect.Descriptor instead.
 func (*HierarchicalLeidenResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{57}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{93}
 }
 
 func (x *HierarchicalLeidenResponse) GetLevelCounts() map[int32]int32 {
@@ -3851,7 +6688,7 @@ type SaveRequest struct {
 
 func (x *SaveRequest) Reset() {
 	*x = SaveRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[58]
+	mi := &file_proto_gibram_proto_msgTypes[94]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3863,7 +6700,7 @@ func (x *SaveRequest) String() string {
 func (*SaveRequest) ProtoMessage() {}
 
 func (x *SaveRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[58]
+	mi := &file_proto_gibram_proto_msgTypes[94]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3876,7 +6713,7 @@ func (x *SaveRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SaveRequest.ProtoReflect.Descriptor instead.
 func (*SaveRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{58}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{94}
 }
 
 func (x *SaveRequest) GetPath() string {
@@ -3895,7 +6732,7 @@ type RestoreRequest struct {
 
 func (x *RestoreRequest) Reset() {
 	*x = RestoreRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[59]
+	mi := &file_proto_gibram_proto_msgTypes[95]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3907,7 +6744,7 @@ func (x *RestoreRequest) String() string {
 func (*RestoreRequest) ProtoMessage() {}
 
 func (x *RestoreRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[59]
+	mi := &file_proto_gibram_proto_msgTypes[95]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3920,7 +6757,7 @@ func (x *RestoreRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RestoreRequest.ProtoReflect.Descriptor instead.
 func (*RestoreRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{59}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{95}
 }
 
 func (x *RestoreRequest) GetPath() string {
@@ -3945,7 +6782,7 @@ type BackupStatusResponse struct {
 
 func (x *BackupStatusResponse) Reset() {
 	*x = BackupStatusResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[60]
+	mi := &file_proto_gibram_proto_msgTypes[96]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -3957,7 +6794,7 @@ func (x *BackupStatusResponse) String() string {
 func (*BackupStatusResponse) ProtoMessage() {}
 
 func (x *BackupStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[60]
+	mi := &file_proto_gibram_proto_msgTypes[96]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -3970,7 +6807,7 @@ func (x *BackupStatusResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use BackupStatusResponse.ProtoReflect.Descriptor instead.
 func (*BackupStatusResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{60}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{96}
 }
 
 func (x *BackupStatusResponse) GetInProgress() bool {
@@ -3994,57 +6831,347 @@ func (x *BackupStatusResponse) GetStartTime() int64 {
 	return 0
 }
 
-func (x *BackupStatusResponse) GetProgressPercent() int32 {
-	if x != nil {
-		return x.ProgressPercent
-	}
-	return 0
+func (x *BackupStatusResponse) GetProgressPercent() int32 {
+	if x != nil {
+		return x.ProgressPercent
+	}
+	return 0
+}
+
+func (x *BackupStatusResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BackupStatusResponse) GetLastSaveTime() int64 {
+	if x != nil {
+		return x.LastSaveTime
+	}
+	return 0
+}
+
+func (x *BackupStatusResponse) GetLastSavePath() string {
+	if x != nil {
+		return x.LastSavePath
+	}
+	return ""
+}
+
+type LastSaveResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LastSaveResponse) Reset() {
+	*x = LastSaveResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[97]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LastSaveResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LastSaveResponse) ProtoMessage() {}
+
+func (x *LastSaveResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[97]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LastSaveResponse.ProtoReflect.Descriptor instead.
+func (*LastSaveResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{97}
+}
+
+func (x *LastSaveResponse) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *LastSaveResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type WALStatusResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	CurrentLsn     uint64                 `protobuf:"varint,1,opt,name=current_lsn,json=currentLsn,proto3" json:"current_lsn,omitempty"`
+	FlushedLsn     uint64                 `protobuf:"varint,2,opt,name=flushed_lsn,json=flushedLsn,proto3" json:"flushed_lsn,omitempty"`
+	SegmentCount   int32                  `protobuf:"varint,3,opt,name=segment_count,json=segmentCount,proto3" json:"segment_count,omitempty"`
+	TotalSizeBytes int64                  `protobuf:"varint,4,opt,name=total_size_bytes,json=totalSizeBytes,proto3" json:"total_size_bytes,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *WALStatusResponse) Reset() {
+	*x = WALStatusResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[98]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WALStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WALStatusResponse) ProtoMessage() {}
+
+func (x *WALStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[98]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WALStatusResponse.ProtoReflect.Descriptor instead.
+func (*WALStatusResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{98}
+}
+
+func (x *WALStatusResponse) GetCurrentLsn() uint64 {
+	if x != nil {
+		return x.CurrentLsn
+	}
+	return 0
+}
+
+func (x *WALStatusResponse) GetFlushedLsn() uint64 {
+	if x != nil {
+		return x.FlushedLsn
+	}
+	return 0
+}
+
+func (x *WALStatusResponse) GetSegmentCount() int32 {
+	if x != nil {
+		return x.SegmentCount
+	}
+	return 0
+}
+
+func (x *WALStatusResponse) GetTotalSizeBytes() int64 {
+	if x != nil {
+		return x.TotalSizeBytes
+	}
+	return 0
+}
+
+type WALTruncateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TargetLsn     uint64                 `protobuf:"varint,1,opt,name=target_lsn,json=targetLsn,proto3" json:"target_lsn,omitempty"` // Truncate WAL entries before this LSN
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WALTruncateRequest) Reset() {
+	*x = WALTruncateRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[99]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WALTruncateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WALTruncateRequest) ProtoMessage() {}
+
+func (x *WALTruncateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[99]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WALTruncateRequest.ProtoReflect.Descriptor instead.
+func (*WALTruncateRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{99}
+}
+
+func (x *WALTruncateRequest) GetTargetLsn() uint64 {
+	if x != nil {
+		return x.TargetLsn
+	}
+	return 0
+}
+
+// SnapshotChunk carries part of a gzip-compressed engine snapshot or session
+// export. Chunks of one transfer share a request ID and arrive in seq order
+// from 0.
+type SnapshotChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	Last          bool                   `protobuf:"varint,3,opt,name=last,proto3" json:"last,omitempty"`                            // final chunk of the transfer
+	TotalSize     uint64                 `protobuf:"varint,4,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"` // full snapshot size in bytes (download only)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SnapshotChunk) Reset() {
+	*x = SnapshotChunk{}
+	mi := &file_proto_gibram_proto_msgTypes[100]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SnapshotChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SnapshotChunk) ProtoMessage() {}
+
+func (x *SnapshotChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[100]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SnapshotChunk.ProtoReflect.Descriptor instead.
+func (*SnapshotChunk) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{100}
+}
+
+func (x *SnapshotChunk) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *SnapshotChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *SnapshotChunk) GetLast() bool {
+	if x != nil {
+		return x.Last
+	}
+	return false
+}
+
+func (x *SnapshotChunk) GetTotalSize() uint64 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+// MergeSessionRequest folds another session into the envelope's session
+type MergeSessionRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	SourceSessionId string                 `protobuf:"bytes,1,opt,name=source_session_id,json=sourceSessionId,proto3" json:"source_session_id,omitempty"`
+	OnConflict      MergeConflictPolicy    `protobuf:"varint,2,opt,name=on_conflict,json=onConflict,proto3,enum=gibram.v1.MergeConflictPolicy" json:"on_conflict,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *MergeSessionRequest) Reset() {
+	*x = MergeSessionRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[101]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MergeSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-func (x *BackupStatusResponse) GetError() string {
+func (*MergeSessionRequest) ProtoMessage() {}
+
+func (x *MergeSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[101]
 	if x != nil {
-		return x.Error
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
 	}
-	return ""
+	return mi.MessageOf(x)
 }
 
-func (x *BackupStatusResponse) GetLastSaveTime() int64 {
+// Deprecated: Use MergeSessionRequest.ProtoReflect.Descriptor instead.
+func (*MergeSessionRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{101}
+}
+
+func (x *MergeSessionRequest) GetSourceSessionId() string {
 	if x != nil {
-		return x.LastSaveTime
+		return x.SourceSessionId
 	}
-	return 0
+	return ""
 }
 
-func (x *BackupStatusResponse) GetLastSavePath() string {
+func (x *MergeSessionRequest) GetOnConflict() MergeConflictPolicy {
 	if x != nil {
-		return x.LastSavePath
+		return x.OnConflict
 	}
-	return ""
+	return MergeConflictPolicy_MERGE_CONFLICT_REJECT
 }
 
-type LastSaveResponse struct {
+type GraphDiffRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Timestamp     int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	FromPath      string                 `protobuf:"bytes,1,opt,name=from_path,json=fromPath,proto3" json:"from_path,omitempty"` // snapshot file for the old state, in the data dir (required)
+	ToPath        string                 `protobuf:"bytes,2,opt,name=to_path,json=toPath,proto3" json:"to_path,omitempty"`       // snapshot file for the new state, in the data dir (empty = live session)
+	Cursor        uint64                 `protobuf:"varint,3,opt,name=cursor,proto3" json:"cursor,omitempty"`                    // offset into the change list
+	Limit         int32                  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`                      // max changes per page (default 1000)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *LastSaveResponse) Reset() {
-	*x = LastSaveResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[61]
+func (x *GraphDiffRequest) Reset() {
+	*x = GraphDiffRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[102]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *LastSaveResponse) String() string {
+func (x *GraphDiffRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*LastSaveResponse) ProtoMessage() {}
+func (*GraphDiffRequest) ProtoMessage() {}
 
-func (x *LastSaveResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[61]
+func (x *GraphDiffRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[102]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4055,50 +7182,63 @@ func (x *LastSaveResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use LastSaveResponse.ProtoReflect.Descriptor instead.
-func (*LastSaveResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{61}
+// Deprecated: Use GraphDiffRequest.ProtoReflect.Descriptor instead.
+func (*GraphDiffRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{102}
 }
 
-func (x *LastSaveResponse) GetTimestamp() int64 {
+func (x *GraphDiffRequest) GetFromPath() string {
 	if x != nil {
-		return x.Timestamp
+		return x.FromPath
 	}
-	return 0
+	return ""
 }
 
-func (x *LastSaveResponse) GetPath() string {
+func (x *GraphDiffRequest) GetToPath() string {
 	if x != nil {
-		return x.Path
+		return x.ToPath
 	}
 	return ""
 }
 
-type WALStatusResponse struct {
-	state          protoimpl.MessageState `protogen:"open.v1"`
-	CurrentLsn     uint64                 `protobuf:"varint,1,opt,name=current_lsn,json=currentLsn,proto3" json:"current_lsn,omitempty"`
-	FlushedLsn     uint64                 `protobuf:"varint,2,opt,name=flushed_lsn,json=flushedLsn,proto3" json:"flushed_lsn,omitempty"`
-	SegmentCount   int32                  `protobuf:"varint,3,opt,name=segment_count,json=segmentCount,proto3" json:"segment_count,omitempty"`
-	TotalSizeBytes int64                  `protobuf:"varint,4,opt,name=total_size_bytes,json=totalSizeBytes,proto3" json:"total_size_bytes,omitempty"`
-	unknownFields  protoimpl.UnknownFields
-	sizeCache      protoimpl.SizeCache
+func (x *GraphDiffRequest) GetCursor() uint64 {
+	if x != nil {
+		return x.Cursor
+	}
+	return 0
 }
 
-func (x *WALStatusResponse) Reset() {
-	*x = WALStatusResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[62]
+func (x *GraphDiffRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GraphChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Op            string                 `protobuf:"bytes,1,opt,name=op,proto3" json:"op,omitempty"`                     // "added", "removed", "modified"
+	Entity        *Entity                `protobuf:"bytes,2,opt,name=entity,proto3" json:"entity,omitempty"`             // set for entity changes
+	Relationship  *Relationship          `protobuf:"bytes,3,opt,name=relationship,proto3" json:"relationship,omitempty"` // set for relationship changes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GraphChange) Reset() {
+	*x = GraphChange{}
+	mi := &file_proto_gibram_proto_msgTypes[103]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WALStatusResponse) String() string {
+func (x *GraphChange) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WALStatusResponse) ProtoMessage() {}
+func (*GraphChange) ProtoMessage() {}
 
-func (x *WALStatusResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[62]
+func (x *GraphChange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[103]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4109,61 +7249,56 @@ func (x *WALStatusResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WALStatusResponse.ProtoReflect.Descriptor instead.
-func (*WALStatusResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{62}
-}
-
-func (x *WALStatusResponse) GetCurrentLsn() uint64 {
-	if x != nil {
-		return x.CurrentLsn
-	}
-	return 0
+// Deprecated: Use GraphChange.ProtoReflect.Descriptor instead.
+func (*GraphChange) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{103}
 }
 
-func (x *WALStatusResponse) GetFlushedLsn() uint64 {
+func (x *GraphChange) GetOp() string {
 	if x != nil {
-		return x.FlushedLsn
+		return x.Op
 	}
-	return 0
+	return ""
 }
 
-func (x *WALStatusResponse) GetSegmentCount() int32 {
+func (x *GraphChange) GetEntity() *Entity {
 	if x != nil {
-		return x.SegmentCount
+		return x.Entity
 	}
-	return 0
+	return nil
 }
 
-func (x *WALStatusResponse) GetTotalSizeBytes() int64 {
+func (x *GraphChange) GetRelationship() *Relationship {
 	if x != nil {
-		return x.TotalSizeBytes
+		return x.Relationship
 	}
-	return 0
+	return nil
 }
 
-type WALTruncateRequest struct {
+type GraphDiffResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	TargetLsn     uint64                 `protobuf:"varint,1,opt,name=target_lsn,json=targetLsn,proto3" json:"target_lsn,omitempty"` // Truncate WAL entries before this LSN
+	Changes       []*GraphChange         `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`                             // total changes across all pages
+	NextCursor    uint64                 `protobuf:"varint,3,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"` // 0 = no more
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *WALTruncateRequest) Reset() {
-	*x = WALTruncateRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[63]
+func (x *GraphDiffResponse) Reset() {
+	*x = GraphDiffResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[104]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *WALTruncateRequest) String() string {
+func (x *GraphDiffResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*WALTruncateRequest) ProtoMessage() {}
+func (*GraphDiffResponse) ProtoMessage() {}
 
-func (x *WALTruncateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[63]
+func (x *GraphDiffResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[104]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4174,14 +7309,28 @@ func (x *WALTruncateRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use WALTruncateRequest.ProtoReflect.Descriptor instead.
-func (*WALTruncateRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{63}
+// Deprecated: Use GraphDiffResponse.ProtoReflect.Descriptor instead.
+func (*GraphDiffResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{104}
 }
 
-func (x *WALTruncateRequest) GetTargetLsn() uint64 {
+func (x *GraphDiffResponse) GetChanges() []*GraphChange {
 	if x != nil {
-		return x.TargetLsn
+		return x.Changes
+	}
+	return nil
+}
+
+func (x *GraphDiffResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *GraphDiffResponse) GetNextCursor() uint64 {
+	if x != nil {
+		return x.NextCursor
 	}
 	return 0
 }
@@ -4189,13 +7338,14 @@ func (x *WALTruncateRequest) GetTargetLsn() uint64 {
 type AuthRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ApiKey        string                 `protobuf:"bytes,1,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`
+	Compression   []string               `protobuf:"bytes,2,rep,name=compression,proto3" json:"compression,omitempty"` // frame compressions accepted, preferred first
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AuthRequest) Reset() {
 	*x = AuthRequest{}
-	mi := &file_proto_gibram_proto_msgTypes[64]
+	mi := &file_proto_gibram_proto_msgTypes[105]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4207,7 +7357,7 @@ func (x *AuthRequest) String() string {
 func (*AuthRequest) ProtoMessage() {}
 
 func (x *AuthRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[64]
+	mi := &file_proto_gibram_proto_msgTypes[105]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4220,7 +7370,7 @@ func (x *AuthRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AuthRequest.ProtoReflect.Descriptor instead.
 func (*AuthRequest) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{64}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{105}
 }
 
 func (x *AuthRequest) GetApiKey() string {
@@ -4230,19 +7380,27 @@ func (x *AuthRequest) GetApiKey() string {
 	return ""
 }
 
+func (x *AuthRequest) GetCompression() []string {
+	if x != nil {
+		return x.Compression
+	}
+	return nil
+}
+
 type AuthResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
 	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
 	KeyId         string                 `protobuf:"bytes,3,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"` // which key was used
 	Permissions   []string               `protobuf:"bytes,4,rep,name=permissions,proto3" json:"permissions,omitempty"`  // granted permissions
+	Compression   string                 `protobuf:"bytes,5,opt,name=compression,proto3" json:"compression,omitempty"`  // negotiated frame compression ("" = none)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *AuthResponse) Reset() {
 	*x = AuthResponse{}
-	mi := &file_proto_gibram_proto_msgTypes[65]
+	mi := &file_proto_gibram_proto_msgTypes[106]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -4254,7 +7412,7 @@ func (x *AuthResponse) String() string {
 func (*AuthResponse) ProtoMessage() {}
 
 func (x *AuthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_gibram_proto_msgTypes[65]
+	mi := &file_proto_gibram_proto_msgTypes[106]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -4267,7 +7425,7 @@ func (x *AuthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AuthResponse.ProtoReflect.Descriptor instead.
 func (*AuthResponse) Descriptor() ([]byte, []int) {
-	return file_proto_gibram_proto_rawDescGZIP(), []int{65}
+	return file_proto_gibram_proto_rawDescGZIP(), []int{106}
 }
 
 func (x *AuthResponse) GetSuccess() bool {
@@ -4298,11 +7456,146 @@ func (x *AuthResponse) GetPermissions() []string {
 	return nil
 }
 
+func (x *AuthResponse) GetCompression() string {
+	if x != nil {
+		return x.Compression
+	}
+	return ""
+}
+
+type RotateKeyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	NewKey        string                 `protobuf:"bytes,2,opt,name=new_key,json=newKey,proto3" json:"new_key,omitempty"`                    // plaintext key to add (empty = server generates one)
+	RetireOldest  bool                   `protobuf:"varint,3,opt,name=retire_oldest,json=retireOldest,proto3" json:"retire_oldest,omitempty"` // stop accepting the key's oldest hash
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateKeyRequest) Reset() {
+	*x = RotateKeyRequest{}
+	mi := &file_proto_gibram_proto_msgTypes[107]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateKeyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateKeyRequest) ProtoMessage() {}
+
+func (x *RotateKeyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[107]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateKeyRequest.ProtoReflect.Descriptor instead.
+func (*RotateKeyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{107}
+}
+
+func (x *RotateKeyRequest) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *RotateKeyRequest) GetNewKey() string {
+	if x != nil {
+		return x.NewKey
+	}
+	return ""
+}
+
+func (x *RotateKeyRequest) GetRetireOldest() bool {
+	if x != nil {
+		return x.RetireOldest
+	}
+	return false
+}
+
+type RotateKeyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	KeyId         string                 `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	ApiKey        string                 `protobuf:"bytes,2,opt,name=api_key,json=apiKey,proto3" json:"api_key,omitempty"`              // the generated key (empty when new_key was given)
+	KeyHash       string                 `protobuf:"bytes,3,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`           // bcrypt hash of the new key, for key_hashes in the config
+	ActiveKeys    int32                  `protobuf:"varint,4,opt,name=active_keys,json=activeKeys,proto3" json:"active_keys,omitempty"` // hashes the key now accepts
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateKeyResponse) Reset() {
+	*x = RotateKeyResponse{}
+	mi := &file_proto_gibram_proto_msgTypes[108]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateKeyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateKeyResponse) ProtoMessage() {}
+
+func (x *RotateKeyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_gibram_proto_msgTypes[108]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateKeyResponse.ProtoReflect.Descriptor instead.
+func (*RotateKeyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_gibram_proto_rawDescGZIP(), []int{108}
+}
+
+func (x *RotateKeyResponse) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *RotateKeyResponse) GetApiKey() string {
+	if x != nil {
+		return x.ApiKey
+	}
+	return ""
+}
+
+func (x *RotateKeyResponse) GetKeyHash() string {
+	if x != nil {
+		return x.KeyHash
+	}
+	return ""
+}
+
+func (x *RotateKeyResponse) GetActiveKeys() int32 {
+	if x != nil {
+		return x.ActiveKeys
+	}
+	return 0
+}
+
 var File_proto_gibram_proto protoreflect.FileDescriptor
 
 const file_proto_gibram_proto_rawDesc = "" +
 	"\n" +
-	"\x12proto/gibram.proto\x12\tgibram.v1\"\xaf\x01\n" +
+	"\x12proto/gibram.proto\x12\tgibram.v1\"\xd1\x01\n" +
 	"\bEnvelope\x12\x18\n" +
 	"\aversion\x18\x01 \x01(\rR\aversion\x12\x1d\n" +
 	"\n" +
@@ -4310,13 +7603,14 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\bcmd_type\x18\x03 \x01(\x0e2\x16.gibram.v1.CommandTypeR\acmdType\x12\x18\n" +
 	"\apayload\x18\x04 \x01(\fR\apayload\x12\x1d\n" +
 	"\n" +
-	"session_id\x18\x05 \x01(\tR\tsessionId\"\a\n" +
+	"session_id\x18\x05 \x01(\tR\tsessionId\x12 \n" +
+	"\vtraceparent\x18\x06 \x01(\tR\vtraceparent\"\a\n" +
 	"\x05Empty\"5\n" +
 	"\x05Error\x12\x18\n" +
 	"\amessage\x18\x01 \x01(\tR\amessage\x12\x12\n" +
 	"\x04code\x18\x02 \x01(\x05R\x04code\"\x1a\n" +
 	"\bOkWithID\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x04R\x02id\"\xb5\x02\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"\xe2\x02\n" +
 	"\fInfoResponse\x12\x18\n" +
 	"\aversion\x18\x01 \x01(\tR\aversion\x12%\n" +
 	"\x0edocument_count\x18\x02 \x01(\x04R\rdocumentCount\x12%\n" +
@@ -4326,7 +7620,8 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x0fcommunity_count\x18\x06 \x01(\x04R\x0ecommunityCount\x12\x1d\n" +
 	"\n" +
 	"vector_dim\x18\a \x01(\x05R\tvectorDim\x12#\n" +
-	"\rsession_count\x18\b \x01(\x05R\fsessionCount\"\xe2\x02\n" +
+	"\rsession_count\x18\b \x01(\x05R\fsessionCount\x12+\n" +
+	"\x11disabled_commands\x18\t \x03(\tR\x10disabledCommands\"\xb1\x04\n" +
 	"\vSessionInfo\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1d\n" +
@@ -4341,9 +7636,22 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\fentity_count\x18\b \x01(\x04R\ventityCount\x12-\n" +
 	"\x12relationship_count\x18\t \x01(\x04R\x11relationshipCount\x12'\n" +
 	"\x0fcommunity_count\x18\n" +
-	" \x01(\x04R\x0ecommunityCount\"J\n" +
+	" \x01(\x04R\x0ecommunityCount\x12@\n" +
+	"\bmetadata\x18\v \x03(\v2$.gibram.v1.SessionInfo.MetadataEntryR\bmetadata\x12+\n" +
+	"\x11communities_dirty\x18\f \x01(\bR\x10communitiesDirty\x12!\n" +
+	"\fmemory_bytes\x18\r \x01(\x03R\vmemoryBytes\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x80\x01\n" +
+	"\x13ListSessionsRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12#\n" +
+	"\rcreated_after\x18\x02 \x01(\x03R\fcreatedAfter\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\x04 \x01(\tR\x06cursor\"k\n" +
 	"\x14ListSessionsResponse\x122\n" +
-	"\bsessions\x18\x01 \x03(\v2\x16.gibram.v1.SessionInfoR\bsessions\"5\n" +
+	"\bsessions\x18\x01 \x03(\v2\x16.gibram.v1.SessionInfoR\bsessions\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"5\n" +
 	"\x14DeleteSessionRequest\x12\x1d\n" +
 	"\n" +
 	"session_id\x18\x01 \x01(\tR\tsessionId\"3\n" +
@@ -4357,7 +7665,18 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\bidle_ttl\x18\x03 \x01(\x03R\aidleTtl\"4\n" +
 	"\x13TouchSessionRequest\x12\x1d\n" +
 	"\n" +
-	"session_id\x18\x01 \x01(\tR\tsessionId\"\xb1\x01\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"\xc2\x01\n" +
+	"\x19SetSessionMetadataRequest\x12N\n" +
+	"\bmetadata\x18\x01 \x03(\v22.gibram.v1.SetSessionMetadataRequest.MetadataEntryR\bmetadata\x12\x18\n" +
+	"\areplace\x18\x02 \x01(\bR\areplace\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xa4\x01\n" +
+	"\x17SessionMetadataResponse\x12L\n" +
+	"\bmetadata\x18\x01 \x03(\v20.gibram.v1.SessionMetadataResponse.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xb1\x01\n" +
 	"\bDocument\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1f\n" +
 	"\vexternal_id\x18\x02 \x01(\tR\n" +
@@ -4370,7 +7689,7 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x12AddDocumentRequest\x12\x1f\n" +
 	"\vexternal_id\x18\x01 \x01(\tR\n" +
 	"externalId\x12\x1a\n" +
-	"\bfilename\x18\x02 \x01(\tR\bfilename\"\xd5\x01\n" +
+	"\bfilename\x18\x02 \x01(\tR\bfilename\"\xf3\x01\n" +
 	"\bTextUnit\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1f\n" +
 	"\vexternal_id\x18\x02 \x01(\tR\n" +
@@ -4383,7 +7702,8 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\n" +
 	"entity_ids\x18\x06 \x03(\x04R\tentityIds\x12\x1d\n" +
 	"\n" +
-	"created_at\x18\a \x01(\x03R\tcreatedAt\"\xaf\x01\n" +
+	"created_at\x18\a \x01(\x03R\tcreatedAt\x12\x1c\n" +
+	"\tembedding\x18\b \x03(\x02R\tembedding\"\xaf\x01\n" +
 	"\x12AddTextUnitRequest\x12\x1f\n" +
 	"\vexternal_id\x18\x01 \x01(\tR\n" +
 	"externalId\x12\x1f\n" +
@@ -4392,7 +7712,7 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\acontent\x18\x03 \x01(\tR\acontent\x12\x1c\n" +
 	"\tembedding\x18\x04 \x03(\x02R\tembedding\x12\x1f\n" +
 	"\vtoken_count\x18\x05 \x01(\x05R\n" +
-	"tokenCount\"\xc7\x01\n" +
+	"tokenCount\"\xba\x03\n" +
 	"\x06Entity\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1f\n" +
 	"\vexternal_id\x18\x02 \x01(\tR\n" +
@@ -4402,20 +7722,47 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\vdescription\x18\x05 \x01(\tR\vdescription\x12!\n" +
 	"\ftextunit_ids\x18\x06 \x03(\x04R\vtextunitIds\x12\x1d\n" +
 	"\n" +
-	"created_at\x18\a \x01(\x03R\tcreatedAt\"\x9d\x01\n" +
+	"created_at\x18\a \x01(\x03R\tcreatedAt\x12\x1e\n" +
+	"\n" +
+	"popularity\x18\b \x01(\x01R\n" +
+	"popularity\x12;\n" +
+	"\bmetadata\x18\t \x03(\v2\x1f.gibram.v1.Entity.MetadataEntryR\bmetadata\x12\x1a\n" +
+	"\bpagerank\x18\n" +
+	" \x01(\x01R\bpagerank\x12\x1c\n" +
+	"\tembedding\x18\v \x03(\x02R\tembedding\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\f \x01(\x03R\tupdatedAt\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xca\x02\n" +
 	"\x10AddEntityRequest\x12\x1f\n" +
 	"\vexternal_id\x18\x01 \x01(\tR\n" +
 	"externalId\x12\x14\n" +
 	"\x05title\x18\x02 \x01(\tR\x05title\x12\x12\n" +
 	"\x04type\x18\x03 \x01(\tR\x04type\x12 \n" +
 	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x1c\n" +
-	"\tembedding\x18\x05 \x03(\x02R\tembedding\"/\n" +
+	"\tembedding\x18\x05 \x03(\x02R\tembedding\x12'\n" +
+	"\x0ftitle_embedding\x18\x06 \x03(\x02R\x0etitleEmbedding\x12E\n" +
+	"\bmetadata\x18\a \x03(\v2).gibram.v1.AddEntityRequest.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"/\n" +
 	"\x17GetEntityByTitleRequest\x12\x14\n" +
-	"\x05title\x18\x01 \x01(\tR\x05title\"i\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\"\xf4\x01\n" +
 	"\x17UpdateEntityDescRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\x12 \n" +
 	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1c\n" +
-	"\tembedding\x18\x03 \x03(\x02R\tembedding\"\xe6\x01\n" +
+	"\tembedding\x18\x03 \x03(\x02R\tembedding\x12L\n" +
+	"\bmetadata\x18\x04 \x03(\v20.gibram.v1.UpdateEntityDescRequest.MetadataEntryR\bmetadata\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"@\n" +
+	"\x18UpdateEntityTitleRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\"J\n" +
+	"\x14MergeEntitiesRequest\x12\x17\n" +
+	"\akeep_id\x18\x01 \x01(\x04R\x06keepId\x12\x19\n" +
+	"\bmerge_id\x18\x02 \x01(\x04R\amergeId\"\xe1\x02\n" +
 	"\fRelationship\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1f\n" +
 	"\vexternal_id\x18\x02 \x01(\tR\n" +
@@ -4426,7 +7773,15 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\vdescription\x18\x06 \x01(\tR\vdescription\x12\x16\n" +
 	"\x06weight\x18\a \x01(\x02R\x06weight\x12\x1d\n" +
 	"\n" +
-	"created_at\x18\b \x01(\x03R\tcreatedAt\"\xc1\x01\n" +
+	"created_at\x18\b \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"valid_from\x18\t \x01(\x03R\tvalidFrom\x12\x1f\n" +
+	"\vvalid_until\x18\n" +
+	" \x01(\x03R\n" +
+	"validUntil\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\v \x01(\x03R\tupdatedAt\x12\x1a\n" +
+	"\bdirected\x18\f \x01(\bR\bdirected\"\x9d\x02\n" +
 	"\x16AddRelationshipRequest\x12\x1f\n" +
 	"\vexternal_id\x18\x01 \x01(\tR\n" +
 	"externalId\x12\x1b\n" +
@@ -4434,7 +7789,66 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\ttarget_id\x18\x03 \x01(\x04R\btargetId\x12\x12\n" +
 	"\x04type\x18\x04 \x01(\tR\x04type\x12 \n" +
 	"\vdescription\x18\x05 \x01(\tR\vdescription\x12\x16\n" +
-	"\x06weight\x18\x06 \x01(\x02R\x06weight\"\x8e\x02\n" +
+	"\x06weight\x18\x06 \x01(\x02R\x06weight\x12\x1d\n" +
+	"\n" +
+	"valid_from\x18\a \x01(\x03R\tvalidFrom\x12\x1f\n" +
+	"\vvalid_until\x18\b \x01(\x03R\n" +
+	"validUntil\x12\x1a\n" +
+	"\bdirected\x18\t \x01(\bR\bdirected\"I\n" +
+	"\x1fUpdateRelationshipWeightRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x16\n" +
+	"\x06weight\x18\x02 \x01(\x02R\x06weight\"4\n" +
+	"\x1cRelationshipTypeStatsRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\"_\n" +
+	"\x14RelationshipTypeStat\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x1d\n" +
+	"\n" +
+	"avg_weight\x18\x03 \x01(\x02R\tavgWeight\"\x87\x01\n" +
+	"\x1dRelationshipTypeStatsResponse\x125\n" +
+	"\x05stats\x18\x01 \x03(\v2\x1f.gibram.v1.RelationshipTypeStatR\x05stats\x12/\n" +
+	"\x13total_relationships\x18\x02 \x01(\x03R\x12totalRelationships\"+\n" +
+	"\fCountRequest\x12\x1b\n" +
+	"\titem_type\x18\x01 \x01(\tR\bitemType\"%\n" +
+	"\rCountResponse\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x04R\x05count\"+\n" +
+	"\x13RebuildIndexRequest\x12\x14\n" +
+	"\x05async\x18\x01 \x01(\bR\x05async\"\xbe\x01\n" +
+	"\x13IndexStatusResponse\x12\x14\n" +
+	"\x05state\x18\x01 \x01(\tR\x05state\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x01R\apercent\x12!\n" +
+	"\fvector_count\x18\x03 \x01(\x04R\vvectorCount\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"started_at\x18\x05 \x01(\x03R\tstartedAt\x12\x1f\n" +
+	"\vfinished_at\x18\x06 \x01(\x03R\n" +
+	"finishedAt\"4\n" +
+	"\x14QuantizeIndexRequest\x12\x1c\n" +
+	"\tprecision\x18\x01 \x01(\tR\tprecision\"[\n" +
+	"\x15QuantizeIndexResponse\x12!\n" +
+	"\fbytes_before\x18\x01 \x01(\x03R\vbytesBefore\x12\x1f\n" +
+	"\vbytes_after\x18\x02 \x01(\x03R\n" +
+	"bytesAfter\"\xd5\x02\n" +
+	"\x13EntityStatsResponse\x12R\n" +
+	"\fentity_types\x18\x01 \x03(\v2/.gibram.v1.EntityStatsResponse.EntityTypesEntryR\ventityTypes\x12d\n" +
+	"\x12relationship_types\x18\x02 \x03(\v25.gibram.v1.EntityStatsResponse.RelationshipTypesEntryR\x11relationshipTypes\x1a>\n" +
+	"\x10EntityTypesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\x1aD\n" +
+	"\x16RelationshipTypesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"\x87\x01\n" +
+	"\x13GetNeighborsRequest\x12\x1b\n" +
+	"\tentity_id\x18\x01 \x01(\x04R\bentityId\x126\n" +
+	"\tdirection\x18\x02 \x01(\x0e2\x18.gibram.v1.EdgeDirectionR\tdirection\x12\x1b\n" +
+	"\trel_types\x18\x03 \x03(\tR\brelTypes\"]\n" +
+	"\x0fSubgraphRequest\x12\x19\n" +
+	"\bseed_ids\x18\x01 \x03(\x04R\aseedIds\x12\x12\n" +
+	"\x04hops\x18\x02 \x01(\x05R\x04hops\x12\x1b\n" +
+	"\tmax_nodes\x18\x03 \x01(\x05R\bmaxNodes\"\x80\x01\n" +
+	"\x10SubgraphResponse\x12-\n" +
+	"\bentities\x18\x01 \x03(\v2\x11.gibram.v1.EntityR\bentities\x12=\n" +
+	"\rrelationships\x18\x02 \x03(\v2\x17.gibram.v1.RelationshipR\rrelationships\"\x8e\x02\n" +
 	"\tCommunity\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x1f\n" +
 	"\vexternal_id\x18\x02 \x01(\tR\n" +
@@ -4458,21 +7872,31 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\n" +
 	"entity_ids\x18\x06 \x03(\x04R\tentityIds\x12)\n" +
 	"\x10relationship_ids\x18\a \x03(\x04R\x0frelationshipIds\x12\x1c\n" +
-	"\tembedding\x18\b \x03(\x02R\tembedding\"[\n" +
+	"\tembedding\x18\b \x03(\x02R\tembedding\"y\n" +
 	"\x19ComputeCommunitiesRequest\x12\x1e\n" +
 	"\n" +
 	"resolution\x18\x01 \x01(\x01R\n" +
 	"resolution\x12\x1e\n" +
 	"\n" +
 	"iterations\x18\x02 \x01(\x05R\n" +
-	"iterations\"j\n" +
+	"iterations\x12\x1c\n" +
+	"\talgorithm\x18\x03 \x01(\tR\talgorithm\"\x8a\x01\n" +
 	"\x1aComputeCommunitiesResponse\x12\x14\n" +
 	"\x05count\x18\x01 \x01(\x05R\x05count\x126\n" +
-	"\vcommunities\x18\x02 \x03(\v2\x14.gibram.v1.CommunityR\vcommunities\"Y\n" +
+	"\vcommunities\x18\x02 \x03(\v2\x14.gibram.v1.CommunityR\vcommunities\x12\x1e\n" +
+	"\n" +
+	"modularity\x18\x03 \x01(\x01R\n" +
+	"modularity\"\x8e\x01\n" +
+	"\x10PageRankResponse\x12?\n" +
+	"\x06scores\x18\x01 \x03(\v2'.gibram.v1.PageRankResponse.ScoresEntryR\x06scores\x1a9\n" +
+	"\vScoresEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\x04R\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value:\x028\x01\"Y\n" +
 	"\x19LinkTextUnitEntityRequest\x12\x1f\n" +
 	"\vtextunit_id\x18\x01 \x01(\x04R\n" +
 	"textunitId\x12\x1b\n" +
-	"\tentity_id\x18\x02 \x01(\x04R\bentityId\"\xf3\x02\n" +
+	"\tentity_id\x18\x02 \x01(\x04R\bentityId\"\xcc\n" +
+	"\n" +
 	"\fQueryRequest\x12!\n" +
 	"\fquery_vector\x18\x01 \x03(\x02R\vqueryVector\x12!\n" +
 	"\fsearch_types\x18\x02 \x03(\tR\vsearchTypes\x12\x13\n" +
@@ -4484,13 +7908,47 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x0fseed_entity_ids\x18\b \x03(\x04R\rseedEntityIds\x12.\n" +
 	"\x13filter_entity_types\x18\t \x03(\tR\x11filterEntityTypes\x12(\n" +
 	"\x10filter_rel_types\x18\n" +
-	" \x03(\tR\x0efilterRelTypes\"s\n" +
+	" \x03(\tR\x0efilterRelTypes\x12\x13\n" +
+	"\x05as_of\x18\v \x01(\x03R\x04asOf\x12,\n" +
+	"\x12include_text_stats\x18\f \x01(\bR\x10includeTextStats\x12\x1f\n" +
+	"\vhub_penalty\x18\r \x01(\x02R\n" +
+	"hubPenalty\x12\x1f\n" +
+	"\vmin_results\x18\x0e \x01(\x05R\n" +
+	"minResults\x12)\n" +
+	"\x10popularity_boost\x18\x0f \x01(\x02R\x0fpopularityBoost\x121\n" +
+	"\x15max_expansion_per_hop\x18\x10 \x01(\x05R\x12maxExpansionPerHop\x12!\n" +
+	"\ftitle_weight\x18\x11 \x01(\x02R\vtitleWeight\x12-\n" +
+	"\x12description_weight\x18\x12 \x01(\x02R\x11descriptionWeight\x12%\n" +
+	"\x0emin_similarity\x18\x13 \x01(\x02R\rminSimilarity\x12\x1b\n" +
+	"\tef_search\x18\x14 \x01(\x05R\befSearch\x12W\n" +
+	"\x10metadata_filters\x18\x15 \x03(\v2,.gibram.v1.QueryRequest.MetadataFiltersEntryR\x0fmetadataFilters\x12'\n" +
+	"\x0fpagerank_weight\x18\x16 \x01(\x02R\x0epagerankWeight\x12#\n" +
+	"\rkeyword_query\x18\x17 \x01(\tR\fkeywordQuery\x12%\n" +
+	"\x0ekeyword_weight\x18\x18 \x01(\x02R\rkeywordWeight\x12#\n" +
+	"\rfusion_method\x18\x19 \x01(\tR\ffusionMethod\x12\x13\n" +
+	"\x05rrf_k\x18\x1a \x01(\x05R\x04rrfK\x12\x1c\n" +
+	"\tdiversity\x18\x1b \x01(\x02R\tdiversity\x12\x1b\n" +
+	"\tpage_size\x18\x1c \x01(\x05R\bpageSize\x12\x16\n" +
+	"\x06cursor\x18\x1d \x01(\tR\x06cursor\x12!\n" +
+	"\fdecay_factor\x18\x1e \x01(\x02R\vdecayFactor\x12#\n" +
+	"\rcreated_after\x18\x1f \x01(\x03R\fcreatedAfter\x12%\n" +
+	"\x0ecreated_before\x18  \x01(\x03R\rcreatedBefore\x12/\n" +
+	"\x13traversal_direction\x18! \x01(\tR\x12traversalDirection\x12\x1f\n" +
+	"\vdeadline_ms\x18\" \x01(\x05R\n" +
+	"deadlineMs\x1aB\n" +
+	"\x14MetadataFiltersEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xe0\x01\n" +
 	"\x0eTextUnitResult\x12/\n" +
 	"\btextunit\x18\x01 \x01(\v2\x13.gibram.v1.TextUnitR\btextunit\x12\x1e\n" +
 	"\n" +
 	"similarity\x18\x02 \x01(\x02R\n" +
 	"similarity\x12\x10\n" +
-	"\x03hop\x18\x03 \x01(\x05R\x03hop\"k\n" +
+	"\x03hop\x18\x03 \x01(\x05R\x03hop\x12\x1f\n" +
+	"\vtoken_count\x18\x04 \x01(\x05R\n" +
+	"tokenCount\x12%\n" +
+	"\x0econtent_length\x18\x05 \x01(\x05R\rcontentLength\x12#\n" +
+	"\rkeyword_score\x18\x06 \x01(\x02R\fkeywordScore\"k\n" +
 	"\fEntityResult\x12)\n" +
 	"\x06entity\x18\x01 \x01(\v2\x11.gibram.v1.EntityR\x06entity\x12\x1e\n" +
 	"\n" +
@@ -4505,19 +7963,73 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x12RelationshipResult\x12;\n" +
 	"\frelationship\x18\x01 \x01(\v2\x17.gibram.v1.RelationshipR\frelationship\x12!\n" +
 	"\fsource_title\x18\x02 \x01(\tR\vsourceTitle\x12!\n" +
-	"\ftarget_title\x18\x03 \x01(\tR\vtargetTitle\"\x89\x01\n" +
+	"\ftarget_title\x18\x03 \x01(\tR\vtargetTitle\"\xc8\x01\n" +
 	"\n" +
 	"QueryStats\x12'\n" +
 	"\x0fduration_micros\x18\x01 \x01(\x03R\x0edurationMicros\x12'\n" +
 	"\x0fvector_searches\x18\x02 \x01(\x05R\x0evectorSearches\x12)\n" +
-	"\x10graph_traversals\x18\x03 \x01(\x05R\x0fgraphTraversals\"\xc8\x02\n" +
+	"\x10graph_traversals\x18\x03 \x01(\x05R\x0fgraphTraversals\x12 \n" +
+	"\vrelaxations\x18\x04 \x03(\tR\vrelaxations\x12\x1b\n" +
+	"\tcache_hit\x18\x05 \x01(\bR\bcacheHit\"\xe9\x02\n" +
 	"\rQueryResponse\x12\x19\n" +
 	"\bquery_id\x18\x01 \x01(\x04R\aqueryId\x127\n" +
 	"\ttextunits\x18\x02 \x03(\v2\x19.gibram.v1.TextUnitResultR\ttextunits\x123\n" +
 	"\bentities\x18\x03 \x03(\v2\x17.gibram.v1.EntityResultR\bentities\x12<\n" +
 	"\vcommunities\x18\x04 \x03(\v2\x1a.gibram.v1.CommunityResultR\vcommunities\x12C\n" +
 	"\rrelationships\x18\x05 \x03(\v2\x1d.gibram.v1.RelationshipResultR\rrelationships\x12+\n" +
-	"\x05stats\x18\x06 \x01(\v2\x15.gibram.v1.QueryStatsR\x05stats\"+\n" +
+	"\x05stats\x18\x06 \x01(\v2\x15.gibram.v1.QueryStatsR\x05stats\x12\x1f\n" +
+	"\vnext_cursor\x18\a \x01(\tR\n" +
+	"nextCursor\"l\n" +
+	"\x10QueryStreamChunk\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x122\n" +
+	"\aresults\x18\x02 \x01(\v2\x18.gibram.v1.QueryResponseR\aresults\x12\x12\n" +
+	"\x04last\x18\x03 \x01(\bR\x04last\"S\n" +
+	"\x12ListQueriesRequest\x12\x14\n" +
+	"\x05limit\x18\x01 \x01(\x05R\x05limit\x12'\n" +
+	"\x0finclude_vectors\x18\x02 \x01(\bR\x0eincludeVectors\"\xf4\x01\n" +
+	"\x11QueryHistoryEntry\x12\x19\n" +
+	"\bquery_id\x18\x01 \x01(\x04R\aqueryId\x12+\n" +
+	"\x04spec\x18\x02 \x01(\v2\x17.gibram.v1.QueryRequestR\x04spec\x12+\n" +
+	"\x05stats\x18\x03 \x01(\v2\x15.gibram.v1.QueryStatsR\x05stats\x12\x0e\n" +
+	"\x02at\x18\x04 \x01(\x03R\x02at\x12\x1c\n" +
+	"\ttextunits\x18\x05 \x01(\x05R\ttextunits\x12\x1a\n" +
+	"\bentities\x18\x06 \x01(\x05R\bentities\x12 \n" +
+	"\vcommunities\x18\a \x01(\x05R\vcommunities\"M\n" +
+	"\x13ListQueriesResponse\x126\n" +
+	"\aqueries\x18\x01 \x03(\v2\x1c.gibram.v1.QueryHistoryEntryR\aqueries\"d\n" +
+	"\x18QueryStatsSummaryRequest\x12%\n" +
+	"\x0ewindow_seconds\x18\x01 \x01(\x03R\rwindowSeconds\x12!\n" +
+	"\fsession_only\x18\x02 \x01(\bR\vsessionOnly\"\xa3\x04\n" +
+	"\x19QueryStatsSummaryResponse\x12%\n" +
+	"\x0ewindow_seconds\x18\x01 \x01(\x03R\rwindowSeconds\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12,\n" +
+	"\x12avg_latency_micros\x18\x03 \x01(\x01R\x10avgLatencyMicros\x12,\n" +
+	"\x12p50_latency_micros\x18\x04 \x01(\x01R\x10p50LatencyMicros\x12,\n" +
+	"\x12p95_latency_micros\x18\x05 \x01(\x01R\x10p95LatencyMicros\x12,\n" +
+	"\x12p99_latency_micros\x18\x06 \x01(\x01R\x10p99LatencyMicros\x12#\n" +
+	"\ravg_textunits\x18\a \x01(\x01R\favgTextunits\x12!\n" +
+	"\favg_entities\x18\b \x01(\x01R\vavgEntities\x12'\n" +
+	"\x0favg_communities\x18\t \x01(\x01R\x0eavgCommunities\x12\x1c\n" +
+	"\n" +
+	"avg_k_hops\x18\n" +
+	" \x01(\x01R\bavgKHops\x12#\n" +
+	"\rempty_results\x18\v \x01(\x03R\femptyResults\x12\x18\n" +
+	"\arelaxed\x18\f \x01(\x03R\arelaxed\x12\x1d\n" +
+	"\n" +
+	"cache_hits\x18\r \x01(\x03R\tcacheHits\x12$\n" +
+	"\x0ecache_hit_rate\x18\x0e \x01(\x01R\fcacheHitRate\"\x8e\x02\n" +
+	"\fCommandStats\x12\x18\n" +
+	"\acommand\x18\x01 \x01(\tR\acommand\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x03R\x05count\x12\x16\n" +
+	"\x06errors\x18\x03 \x01(\x03R\x06errors\x12,\n" +
+	"\x12avg_latency_micros\x18\x04 \x01(\x01R\x10avgLatencyMicros\x12,\n" +
+	"\x12p50_latency_micros\x18\x05 \x01(\x01R\x10p50LatencyMicros\x12,\n" +
+	"\x12p95_latency_micros\x18\x06 \x01(\x01R\x10p95LatencyMicros\x12,\n" +
+	"\x12p99_latency_micros\x18\a \x01(\x01R\x10p99LatencyMicros\"\xa2\x01\n" +
+	"\rStatsResponse\x12%\n" +
+	"\x0euptime_seconds\x18\x01 \x01(\x03R\ruptimeSeconds\x125\n" +
+	"\x17slow_query_threshold_ms\x18\x02 \x01(\x03R\x14slowQueryThresholdMs\x123\n" +
+	"\bcommands\x18\x03 \x03(\v2\x17.gibram.v1.CommandStatsR\bcommands\"+\n" +
 	"\x0eExplainRequest\x12\x19\n" +
 	"\bquery_id\x18\x01 \x01(\x04R\aqueryId\"o\n" +
 	"\bSeedInfo\x12\x12\n" +
@@ -4541,7 +8053,10 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x05seeds\x18\x02 \x03(\v2\x13.gibram.v1.SeedInfoR\x05seeds\x126\n" +
 	"\ttraversal\x18\x03 \x03(\v2\x18.gibram.v1.TraversalStepR\ttraversal\" \n" +
 	"\x0eGetByIDRequest\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\x04R\x02id\"#\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"9\n" +
+	"\x16GetByExternalIDRequest\x12\x1f\n" +
+	"\vexternal_id\x18\x01 \x01(\tR\n" +
+	"externalId\"#\n" +
 	"\x11DeleteByIDRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\x04R\x02id\"\xb2\x01\n" +
 	"\x0eHealthResponse\x12\x16\n" +
@@ -4551,10 +8066,11 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"components\x1a=\n" +
 	"\x0fComponentsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"C\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"W\n" +
 	"\x13ListEntitiesRequest\x12\x16\n" +
 	"\x06cursor\x18\x01 \x01(\x04R\x06cursor\x12\x14\n" +
-	"\x05limit\x18\x02 \x01(\x05R\x05limit\"N\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\"N\n" +
 	"\x13MSetEntitiesRequest\x127\n" +
 	"\bentities\x18\x01 \x03(\v2\x1b.gibram.v1.AddEntityRequestR\bentities\"'\n" +
 	"\x13MGetEntitiesRequest\x12\x10\n" +
@@ -4584,20 +8100,52 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x18MSetRelationshipsRequest\x12G\n" +
 	"\rrelationships\x18\x01 \x03(\v2!.gibram.v1.AddRelationshipRequestR\rrelationships\",\n" +
 	"\x18MGetRelationshipsRequest\x12\x10\n" +
-	"\x03ids\x18\x01 \x03(\x04R\x03ids\"\x98\x01\n" +
+	"\x03ids\x18\x01 \x03(\x04R\x03ids\"\"\n" +
+	"\x0eMDeleteRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\x04R\x03ids\"6\n" +
+	"\x0fMDeleteResponse\x12#\n" +
+	"\rdeleted_count\x18\x01 \x01(\x05R\fdeletedCount\"\x84\x01\n" +
+	"\x1aMLinkTextUnitEntityRequest\x12:\n" +
+	"\x05links\x18\x01 \x03(\v2$.gibram.v1.LinkTextUnitEntityRequestR\x05links\x12*\n" +
+	"\x11continue_on_error\x18\x02 \x01(\bR\x0fcontinueOnError\"p\n" +
+	"\n" +
+	"LinkResult\x12\x1f\n" +
+	"\vtextunit_id\x18\x01 \x01(\x04R\n" +
+	"textunitId\x12\x1b\n" +
+	"\tentity_id\x18\x02 \x01(\x04R\bentityId\x12\x0e\n" +
+	"\x02ok\x18\x03 \x01(\bR\x02ok\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\x94\x01\n" +
+	"\x1bMLinkTextUnitEntityResponse\x12/\n" +
+	"\aresults\x18\x01 \x03(\v2\x15.gibram.v1.LinkResultR\aresults\x12!\n" +
+	"\flinked_count\x18\x02 \x01(\x05R\vlinkedCount\x12!\n" +
+	"\ffailed_count\x18\x03 \x01(\x05R\vfailedCount\"\x98\x01\n" +
 	"\x15RelationshipsResponse\x12=\n" +
 	"\rrelationships\x18\x01 \x03(\v2\x17.gibram.v1.RelationshipR\rrelationships\x12\x1f\n" +
 	"\vcreated_ids\x18\x02 \x03(\x04R\n" +
 	"createdIds\x12\x1f\n" +
 	"\vnext_cursor\x18\x03 \x01(\x04R\n" +
-	"nextCursor\"H\n" +
+	"nextCursor\"M\n" +
+	"\x13CommunitiesResponse\x126\n" +
+	"\vcommunities\x18\x01 \x03(\v2\x14.gibram.v1.CommunityR\vcommunities\"\x96\x01\n" +
 	"\x18ListRelationshipsRequest\x12\x16\n" +
 	"\x06cursor\x18\x01 \x01(\x04R\x06cursor\x12\x14\n" +
-	"\x05limit\x18\x02 \x01(\x05R\x05limit\"B\n" +
+	"\x05limit\x18\x02 \x01(\x05R\x05limit\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x1b\n" +
+	"\tsource_id\x18\x04 \x01(\x04R\bsourceId\x12\x1b\n" +
+	"\ttarget_id\x18\x05 \x01(\x04R\btargetId\"\x9e\x01\n" +
 	"\x0fPipelineRequest\x12/\n" +
-	"\bcommands\x18\x01 \x03(\v2\x13.gibram.v1.EnvelopeR\bcommands\"E\n" +
+	"\bcommands\x18\x01 \x03(\v2\x13.gibram.v1.EnvelopeR\bcommands\x12\x16\n" +
+	"\x06atomic\x18\x02 \x01(\bR\x06atomic\x12\x16\n" +
+	"\x06labels\x18\x03 \x03(\tR\x06labels\x12*\n" +
+	"\x04refs\x18\x04 \x03(\v2\x16.gibram.v1.PipelineRefR\x04refs\"O\n" +
+	"\vPipelineRef\x12\x18\n" +
+	"\acommand\x18\x01 \x01(\rR\acommand\x12\x14\n" +
+	"\x05field\x18\x02 \x01(\tR\x05field\x12\x10\n" +
+	"\x03ref\x18\x03 \x01(\tR\x03ref\"f\n" +
 	"\x10PipelineResponse\x121\n" +
-	"\tresponses\x18\x01 \x03(\v2\x13.gibram.v1.EnvelopeR\tresponses\"Z\n" +
+	"\tresponses\x18\x01 \x03(\v2\x13.gibram.v1.EnvelopeR\tresponses\x12\x1f\n" +
+	"\vrolled_back\x18\x02 \x01(\bR\n" +
+	"rolledBack\"Z\n" +
 	"\x19HierarchicalLeidenRequest\x12\x1d\n" +
 	"\n" +
 	"max_levels\x18\x01 \x01(\x05R\tmaxLevels\x12\x1e\n" +
@@ -4636,14 +8184,50 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x10total_size_bytes\x18\x04 \x01(\x03R\x0etotalSizeBytes\"3\n" +
 	"\x12WALTruncateRequest\x12\x1d\n" +
 	"\n" +
-	"target_lsn\x18\x01 \x01(\x04R\ttargetLsn\"&\n" +
+	"target_lsn\x18\x01 \x01(\x04R\ttargetLsn\"h\n" +
+	"\rSnapshotChunk\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\x12\x12\n" +
+	"\x04last\x18\x03 \x01(\bR\x04last\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x04 \x01(\x04R\ttotalSize\"\x82\x01\n" +
+	"\x13MergeSessionRequest\x12*\n" +
+	"\x11source_session_id\x18\x01 \x01(\tR\x0fsourceSessionId\x12?\n" +
+	"\von_conflict\x18\x02 \x01(\x0e2\x1e.gibram.v1.MergeConflictPolicyR\n" +
+	"onConflict\"v\n" +
+	"\x10GraphDiffRequest\x12\x1b\n" +
+	"\tfrom_path\x18\x01 \x01(\tR\bfromPath\x12\x17\n" +
+	"\ato_path\x18\x02 \x01(\tR\x06toPath\x12\x16\n" +
+	"\x06cursor\x18\x03 \x01(\x04R\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"\x85\x01\n" +
+	"\vGraphChange\x12\x0e\n" +
+	"\x02op\x18\x01 \x01(\tR\x02op\x12)\n" +
+	"\x06entity\x18\x02 \x01(\v2\x11.gibram.v1.EntityR\x06entity\x12;\n" +
+	"\frelationship\x18\x03 \x01(\v2\x17.gibram.v1.RelationshipR\frelationship\"|\n" +
+	"\x11GraphDiffResponse\x120\n" +
+	"\achanges\x18\x01 \x03(\v2\x16.gibram.v1.GraphChangeR\achanges\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\x12\x1f\n" +
+	"\vnext_cursor\x18\x03 \x01(\x04R\n" +
+	"nextCursor\"H\n" +
 	"\vAuthRequest\x12\x17\n" +
-	"\aapi_key\x18\x01 \x01(\tR\x06apiKey\"{\n" +
+	"\aapi_key\x18\x01 \x01(\tR\x06apiKey\x12 \n" +
+	"\vcompression\x18\x02 \x03(\tR\vcompression\"\x9d\x01\n" +
 	"\fAuthResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x15\n" +
 	"\x06key_id\x18\x03 \x01(\tR\x05keyId\x12 \n" +
-	"\vpermissions\x18\x04 \x03(\tR\vpermissions*\xdd\r\n" +
+	"\vpermissions\x18\x04 \x03(\tR\vpermissions\x12 \n" +
+	"\vcompression\x18\x05 \x01(\tR\vcompression\"g\n" +
+	"\x10RotateKeyRequest\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x12\x17\n" +
+	"\anew_key\x18\x02 \x01(\tR\x06newKey\x12#\n" +
+	"\rretire_oldest\x18\x03 \x01(\bR\fretireOldest\"\x7f\n" +
+	"\x11RotateKeyResponse\x12\x15\n" +
+	"\x06key_id\x18\x01 \x01(\tR\x05keyId\x12\x17\n" +
+	"\aapi_key\x18\x02 \x01(\tR\x06apiKey\x12\x19\n" +
+	"\bkey_hash\x18\x03 \x01(\tR\akeyHash\x12\x1f\n" +
+	"\vactive_keys\x18\x04 \x01(\x05R\n" +
+	"activeKeys*\x8d\x19\n" +
 	"\vCommandType\x12\x0f\n" +
 	"\vCMD_UNKNOWN\x10\x00\x12\f\n" +
 	"\bCMD_PING\x10\x01\x12\f\n" +
@@ -4671,11 +8255,20 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x17CMD_GET_ENTITY_BY_TITLE\x10 \x12\x1a\n" +
 	"\x16CMD_UPDATE_ENTITY_DESC\x10!\x12\x15\n" +
 	"\x11CMD_DELETE_ENTITY\x10\"\x12\x17\n" +
-	"\x13CMD_ENTITY_RESPONSE\x10#\x12\x18\n" +
+	"\x13CMD_ENTITY_RESPONSE\x10#\x12\x16\n" +
+	"\x12CMD_MERGE_ENTITIES\x10$\x12\x1b\n" +
+	"\x17CMD_UPDATE_ENTITY_TITLE\x10%\x12\x1e\n" +
+	"\x1aCMD_GET_ENTITY_COMMUNITIES\x10&\x12\x18\n" +
 	"\x14CMD_ADD_RELATIONSHIP\x10(\x12\x18\n" +
 	"\x14CMD_GET_RELATIONSHIP\x10)\x12\x1b\n" +
 	"\x17CMD_DELETE_RELATIONSHIP\x10*\x12\x1d\n" +
-	"\x19CMD_RELATIONSHIP_RESPONSE\x10+\x12\x15\n" +
+	"\x19CMD_RELATIONSHIP_RESPONSE\x10+\x12\x1f\n" +
+	"\x1bCMD_RELATIONSHIP_TYPE_STATS\x10,\x12(\n" +
+	"$CMD_RELATIONSHIP_TYPE_STATS_RESPONSE\x10-\x12\x15\n" +
+	"\x11CMD_GET_NEIGHBORS\x10.\x12\x10\n" +
+	"\fCMD_SUBGRAPH\x10/\x12\x19\n" +
+	"\x15CMD_SUBGRAPH_RESPONSE\x100\x12\"\n" +
+	"\x1eCMD_UPDATE_RELATIONSHIP_WEIGHT\x101\x12\x15\n" +
 	"\x11CMD_ADD_COMMUNITY\x102\x12\x15\n" +
 	"\x11CMD_GET_COMMUNITY\x103\x12\x18\n" +
 	"\x14CMD_DELETE_COMMUNITY\x104\x12\x1b\n" +
@@ -4683,18 +8276,27 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x17CMD_HIERARCHICAL_LEIDEN\x106\x12\x15\n" +
 	"\x11CMD_REBUILD_INDEX\x107\x12\x1a\n" +
 	"\x16CMD_COMMUNITY_RESPONSE\x108\x12\x1c\n" +
-	"\x18CMD_COMMUNITIES_RESPONSE\x109\x12\r\n" +
+	"\x18CMD_COMMUNITIES_RESPONSE\x109\x12\x18\n" +
+	"\x14CMD_COMPUTE_PAGERANK\x10:\x12\x19\n" +
+	"\x15CMD_PAGERANK_RESPONSE\x10;\x12\r\n" +
 	"\tCMD_QUERY\x10<\x12\x16\n" +
 	"\x12CMD_QUERY_RESPONSE\x10=\x12\x0f\n" +
 	"\vCMD_EXPLAIN\x10>\x12\x18\n" +
-	"\x14CMD_EXPLAIN_RESPONSE\x10?\x12\x15\n" +
+	"\x14CMD_EXPLAIN_RESPONSE\x10?\x12\x1b\n" +
+	"\x17CMD_QUERY_STATS_SUMMARY\x10@\x12$\n" +
+	" CMD_QUERY_STATS_SUMMARY_RESPONSE\x10A\x12\x14\n" +
+	"\x10CMD_QUERY_STREAM\x10B\x12\x1a\n" +
+	"\x16CMD_QUERY_STREAM_CHUNK\x10C\x12\x15\n" +
 	"\x11CMD_LIST_SESSIONS\x10F\x12\x16\n" +
 	"\x12CMD_DELETE_SESSION\x10G\x12\x14\n" +
 	"\x10CMD_SESSION_INFO\x10H\x12\x17\n" +
 	"\x13CMD_SET_SESSION_TTL\x10I\x12\x15\n" +
 	"\x11CMD_TOUCH_SESSION\x10J\x12\x19\n" +
 	"\x15CMD_SESSIONS_RESPONSE\x10K\x12\x1d\n" +
-	"\x19CMD_SESSION_INFO_RESPONSE\x10L\x12\x15\n" +
+	"\x19CMD_SESSION_INFO_RESPONSE\x10L\x12\x12\n" +
+	"\x0eCMD_GRAPH_DIFF\x10M\x12\x1b\n" +
+	"\x17CMD_GRAPH_DIFF_RESPONSE\x10N\x12\x15\n" +
+	"\x11CMD_FLUSH_SESSION\x10O\x12\x15\n" +
 	"\x11CMD_MSET_ENTITIES\x10P\x12\x15\n" +
 	"\x11CMD_MGET_ENTITIES\x10Q\x12\x16\n" +
 	"\x12CMD_MSET_DOCUMENTS\x10R\x12\x16\n" +
@@ -4708,7 +8310,13 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x16CMD_TEXTUNITS_RESPONSE\x10Z\x12\x1e\n" +
 	"\x1aCMD_RELATIONSHIPS_RESPONSE\x10[\x12\x15\n" +
 	"\x11CMD_LIST_ENTITIES\x10\\\x12\x1a\n" +
-	"\x16CMD_LIST_RELATIONSHIPS\x10]\x12\x10\n" +
+	"\x16CMD_LIST_RELATIONSHIPS\x10]\x12\x1d\n" +
+	"\x19CMD_MLINK_TEXTUNIT_ENTITY\x10^\x12\x16\n" +
+	"\x12CMD_MLINK_RESPONSE\x10_\x12\x19\n" +
+	"\x15CMD_MDELETE_TEXTUNITS\x10`\x12\x18\n" +
+	"\x14CMD_MDELETE_ENTITIES\x10a\x12\x1d\n" +
+	"\x19CMD_MDELETE_RELATIONSHIPS\x10b\x12\x18\n" +
+	"\x14CMD_MDELETE_RESPONSE\x10c\x12\x10\n" +
 	"\fCMD_PIPELINE\x10d\x12\x19\n" +
 	"\x15CMD_PIPELINE_RESPONSE\x10e\x12\x0e\n" +
 	"\n" +
@@ -4723,7 +8331,43 @@ const file_proto_gibram_proto_rawDesc = "" +
 	"\x0eCMD_WAL_STATUS\x10v\x12\x17\n" +
 	"\x13CMD_BACKUP_RESPONSE\x10w\x12\f\n" +
 	"\bCMD_AUTH\x10x\x12\x15\n" +
-	"\x11CMD_AUTH_RESPONSE\x10yB,Z*github.com/gibram-io/gibram/proto/gibrampbb\x06proto3"
+	"\x11CMD_AUTH_RESPONSE\x10y\x12\x12\n" +
+	"\x0eCMD_ROTATE_KEY\x10z\x12\x1b\n" +
+	"\x17CMD_ROTATE_KEY_RESPONSE\x10{\x12\x1d\n" +
+	"\x18CMD_SET_SESSION_METADATA\x10\x82\x01\x12\x1d\n" +
+	"\x18CMD_GET_SESSION_METADATA\x10\x83\x01\x12\"\n" +
+	"\x1dCMD_SESSION_METADATA_RESPONSE\x10\x84\x01\x12\x18\n" +
+	"\x13CMD_STREAM_SNAPSHOT\x10\x8c\x01\x12\x18\n" +
+	"\x13CMD_UPLOAD_SNAPSHOT\x10\x8d\x01\x12\x17\n" +
+	"\x12CMD_SNAPSHOT_CHUNK\x10\x8e\x01\x12\x0e\n" +
+	"\tCMD_STATS\x10\x96\x01\x12\x17\n" +
+	"\x12CMD_STATS_RESPONSE\x10\x97\x01\x12\x15\n" +
+	"\x10CMD_ENTITY_STATS\x10\xa0\x01\x12\x1e\n" +
+	"\x19CMD_ENTITY_STATS_RESPONSE\x10\xa1\x01\x12\x0e\n" +
+	"\tCMD_COUNT\x10\xa2\x01\x12\x17\n" +
+	"\x12CMD_COUNT_RESPONSE\x10\xa3\x01\x12\x17\n" +
+	"\x12CMD_QUANTIZE_INDEX\x10\xaa\x01\x12 \n" +
+	"\x1bCMD_QUANTIZE_INDEX_RESPONSE\x10\xab\x01\x12\x15\n" +
+	"\x10CMD_INDEX_STATUS\x10\xac\x01\x12\x1e\n" +
+	"\x19CMD_INDEX_STATUS_RESPONSE\x10\xad\x01\x12\x17\n" +
+	"\x12CMD_EXPORT_SESSION\x10\xb4\x01\x12\x17\n" +
+	"\x12CMD_IMPORT_SESSION\x10\xb5\x01\x12\x16\n" +
+	"\x11CMD_MERGE_SESSION\x10\xb6\x01\x12$\n" +
+	"\x1fCMD_GET_DOCUMENT_BY_EXTERNAL_ID\x10\xbe\x01\x12$\n" +
+	"\x1fCMD_GET_TEXTUNIT_BY_EXTERNAL_ID\x10\xbf\x01\x12\"\n" +
+	"\x1dCMD_GET_ENTITY_BY_EXTERNAL_ID\x10\xc0\x01\x12(\n" +
+	"#CMD_GET_RELATIONSHIP_BY_EXTERNAL_ID\x10\xc1\x01\x12\x15\n" +
+	"\x10CMD_LIST_QUERIES\x10\xc8\x01\x12\x1e\n" +
+	"\x19CMD_LIST_QUERIES_RESPONSE\x10\xc9\x01\x12\x19\n" +
+	"\x14CMD_GET_QUERY_RESULT\x10\xca\x01*b\n" +
+	"\rEdgeDirection\x12\x17\n" +
+	"\x13EDGE_DIRECTION_BOTH\x10\x00\x12\x1b\n" +
+	"\x17EDGE_DIRECTION_OUTGOING\x10\x01\x12\x1b\n" +
+	"\x17EDGE_DIRECTION_INCOMING\x10\x02*i\n" +
+	"\x13MergeConflictPolicy\x12\x19\n" +
+	"\x15MERGE_CONFLICT_REJECT\x10\x00\x12\x17\n" +
+	"\x13MERGE_CONFLICT_SKIP\x10\x01\x12\x1e\n" +
+	"\x1aMERGE_CONFLICT_DEDUP_TITLE\x10\x02B,Z*github.com/gibram-io/gibram/proto/gibrampbb\x06proto3"
 
 var (
 	file_proto_gibram_proto_rawDescOnce sync.Once
@@ -4737,111 +8381,193 @@ func file_proto_gibram_proto_rawDescGZIP() []byte {
 	return file_proto_gibram_proto_rawDescData
 }
 
-var file_proto_gibram_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_proto_gibram_proto_msgTypes = make([]protoimpl.MessageInfo, 68)
+var file_proto_gibram_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_proto_gibram_proto_msgTypes = make([]protoimpl.MessageInfo, 121)
 var file_proto_gibram_proto_goTypes = []any{
-	(CommandType)(0),                   // 0: gibram.v1.CommandType
-	(*Envelope)(nil),                   // 1: gibram.v1.Envelope
-	(*Empty)(nil),                      // 2: gibram.v1.Empty
-	(*Error)(nil),                      // 3: gibram.v1.Error
-	(*OkWithID)(nil),                   // 4: gibram.v1.OkWithID
-	(*InfoResponse)(nil),               // 5: gibram.v1.InfoResponse
-	(*SessionInfo)(nil),                // 6: gibram.v1.SessionInfo
-	(*ListSessionsResponse)(nil),       // 7: gibram.v1.ListSessionsResponse
-	(*DeleteSessionRequest)(nil),       // 8: gibram.v1.DeleteSessionRequest
-	(*SessionInfoRequest)(nil),         // 9: gibram.v1.SessionInfoRequest
-	(*SetSessionTTLRequest)(nil),       // 10: gibram.v1.SetSessionTTLRequest
-	(*TouchSessionRequest)(nil),        // 11: gibram.v1.TouchSessionRequest
-	(*Document)(nil),                   // 12: gibram.v1.Document
-	(*AddDocumentRequest)(nil),         // 13: gibram.v1.AddDocumentRequest
-	(*TextUnit)(nil),                   // 14: gibram.v1.TextUnit
-	(*AddTextUnitRequest)(nil),         // 15: gibram.v1.AddTextUnitRequest
-	(*Entity)(nil),                     // 16: gibram.v1.Entity
-	(*AddEntityRequest)(nil),           // 17: gibram.v1.AddEntityRequest
-	(*GetEntityByTitleRequest)(nil),    // 18: gibram.v1.GetEntityByTitleRequest
-	(*UpdateEntityDescRequest)(nil),    // 19: gibram.v1.UpdateEntityDescRequest
-	(*Relationship)(nil),               // 20: gibram.v1.Relationship
-	(*AddRelationshipRequest)(nil),     // 21: gibram.v1.AddRelationshipRequest
-	(*Community)(nil),                  // 22: gibram.v1.Community
-	(*AddCommunityRequest)(nil),        // 23: gibram.v1.AddCommunityRequest
-	(*ComputeCommunitiesRequest)(nil),  // 24: gibram.v1.ComputeCommunitiesRequest
-	(*ComputeCommunitiesResponse)(nil), // 25: gibram.v1.ComputeCommunitiesResponse
-	(*LinkTextUnitEntityRequest)(nil),  // 26: gibram.v1.LinkTextUnitEntityRequest
-	(*QueryRequest)(nil),               // 27: gibram.v1.QueryRequest
-	(*TextUnitResult)(nil),             // 28: gibram.v1.TextUnitResult
-	(*EntityResult)(nil),               // 29: gibram.v1.EntityResult
-	(*CommunityResult)(nil),            // 30: gibram.v1.CommunityResult
-	(*RelationshipResult)(nil),         // 31: gibram.v1.RelationshipResult
-	(*QueryStats)(nil),                 // 32: gibram.v1.QueryStats
-	(*QueryResponse)(nil),              // 33: gibram.v1.QueryResponse
-	(*ExplainRequest)(nil),             // 34: gibram.v1.ExplainRequest
-	(*SeedInfo)(nil),                   // 35: gibram.v1.SeedInfo
-	(*TraversalStep)(nil),              // 36: gibram.v1.TraversalStep
-	(*ExplainResponse)(nil),            // 37: gibram.v1.ExplainResponse
-	(*GetByIDRequest)(nil),             // 38: gibram.v1.GetByIDRequest
-	(*DeleteByIDRequest)(nil),          // 39: gibram.v1.DeleteByIDRequest
-	(*HealthResponse)(nil),             // 40: gibram.v1.HealthResponse
-	(*ListEntitiesRequest)(nil),        // 41: gibram.v1.ListEntitiesRequest
-	(*MSetEntitiesRequest)(nil),        // 42: gibram.v1.MSetEntitiesRequest
-	(*MGetEntitiesRequest)(nil),        // 43: gibram.v1.MGetEntitiesRequest
-	(*EntitiesResponse)(nil),           // 44: gibram.v1.EntitiesResponse
-	(*MSetDocumentsRequest)(nil),       // 45: gibram.v1.MSetDocumentsRequest
-	(*MGetDocumentsRequest)(nil),       // 46: gibram.v1.MGetDocumentsRequest
-	(*DocumentsResponse)(nil),          // 47: gibram.v1.DocumentsResponse
-	(*MSetTextUnitsRequest)(nil),       // 48: gibram.v1.MSetTextUnitsRequest
-	(*MGetTextUnitsRequest)(nil),       // 49: gibram.v1.MGetTextUnitsRequest
-	(*TextUnitsResponse)(nil),          // 50: gibram.v1.TextUnitsResponse
-	(*MSetRelationshipsRequest)(nil),   // 51: gibram.v1.MSetRelationshipsRequest
-	(*MGetRelationshipsRequest)(nil),   // 52: gibram.v1.MGetRelationshipsRequest
-	(*RelationshipsResponse)(nil),      // 53: gibram.v1.RelationshipsResponse
-	(*ListRelationshipsRequest)(nil),   // 54: gibram.v1.ListRelationshipsRequest
-	(*PipelineRequest)(nil),            // 55: gibram.v1.PipelineRequest
-	(*PipelineResponse)(nil),           // 56: gibram.v1.PipelineResponse
-	(*HierarchicalLeidenRequest)(nil),  // 57: gibram.v1.HierarchicalLeidenRequest
-	(*HierarchicalLeidenResponse)(nil), // 58: gibram.v1.HierarchicalLeidenResponse
-	(*SaveRequest)(nil),                // 59: gibram.v1.SaveRequest
-	(*RestoreRequest)(nil),             // 60: gibram.v1.RestoreRequest
-	(*BackupStatusResponse)(nil),       // 61: gibram.v1.BackupStatusResponse
-	(*LastSaveResponse)(nil),           // 62: gibram.v1.LastSaveResponse
-	(*WALStatusResponse)(nil),          // 63: gibram.v1.WALStatusResponse
-	(*WALTruncateRequest)(nil),         // 64: gibram.v1.WALTruncateRequest
-	(*AuthRequest)(nil),                // 65: gibram.v1.AuthRequest
-	(*AuthResponse)(nil),               // 66: gibram.v1.AuthResponse
-	nil,                                // 67: gibram.v1.HealthResponse.ComponentsEntry
-	nil,                                // 68: gibram.v1.HierarchicalLeidenResponse.LevelCountsEntry
+	(CommandType)(0),                        // 0: gibram.v1.CommandType
+	(EdgeDirection)(0),                      // 1: gibram.v1.EdgeDirection
+	(MergeConflictPolicy)(0),                // 2: gibram.v1.MergeConflictPolicy
+	(*Envelope)(nil),                        // 3: gibram.v1.Envelope
+	(*Empty)(nil),                           // 4: gibram.v1.Empty
+	(*Error)(nil),                           // 5: gibram.v1.Error
+	(*OkWithID)(nil),                        // 6: gibram.v1.OkWithID
+	(*InfoResponse)(nil),                    // 7: gibram.v1.InfoResponse
+	(*SessionInfo)(nil),                     // 8: gibram.v1.SessionInfo
+	(*ListSessionsRequest)(nil),             // 9: gibram.v1.ListSessionsRequest
+	(*ListSessionsResponse)(nil),            // 10: gibram.v1.ListSessionsResponse
+	(*DeleteSessionRequest)(nil),            // 11: gibram.v1.DeleteSessionRequest
+	(*SessionInfoRequest)(nil),              // 12: gibram.v1.SessionInfoRequest
+	(*SetSessionTTLRequest)(nil),            // 13: gibram.v1.SetSessionTTLRequest
+	(*TouchSessionRequest)(nil),             // 14: gibram.v1.TouchSessionRequest
+	(*SetSessionMetadataRequest)(nil),       // 15: gibram.v1.SetSessionMetadataRequest
+	(*SessionMetadataResponse)(nil),         // 16: gibram.v1.SessionMetadataResponse
+	(*Document)(nil),                        // 17: gibram.v1.Document
+	(*AddDocumentRequest)(nil),              // 18: gibram.v1.AddDocumentRequest
+	(*TextUnit)(nil),                        // 19: gibram.v1.TextUnit
+	(*AddTextUnitRequest)(nil),              // 20: gibram.v1.AddTextUnitRequest
+	(*Entity)(nil),                          // 21: gibram.v1.Entity
+	(*AddEntityRequest)(nil),                // 22: gibram.v1.AddEntityRequest
+	(*GetEntityByTitleRequest)(nil),         // 23: gibram.v1.GetEntityByTitleRequest
+	(*UpdateEntityDescRequest)(nil),         // 24: gibram.v1.UpdateEntityDescRequest
+	(*UpdateEntityTitleRequest)(nil),        // 25: gibram.v1.UpdateEntityTitleRequest
+	(*MergeEntitiesRequest)(nil),            // 26: gibram.v1.MergeEntitiesRequest
+	(*Relationship)(nil),                    // 27: gibram.v1.Relationship
+	(*AddRelationshipRequest)(nil),          // 28: gibram.v1.AddRelationshipRequest
+	(*UpdateRelationshipWeightRequest)(nil), // 29: gibram.v1.UpdateRelationshipWeightRequest
+	(*RelationshipTypeStatsRequest)(nil),    // 30: gibram.v1.RelationshipTypeStatsRequest
+	(*RelationshipTypeStat)(nil),            // 31: gibram.v1.RelationshipTypeStat
+	(*RelationshipTypeStatsResponse)(nil),   // 32: gibram.v1.RelationshipTypeStatsResponse
+	(*CountRequest)(nil),                    // 33: gibram.v1.CountRequest
+	(*CountResponse)(nil),                   // 34: gibram.v1.CountResponse
+	(*RebuildIndexRequest)(nil),             // 35: gibram.v1.RebuildIndexRequest
+	(*IndexStatusResponse)(nil),             // 36: gibram.v1.IndexStatusResponse
+	(*QuantizeIndexRequest)(nil),            // 37: gibram.v1.QuantizeIndexRequest
+	(*QuantizeIndexResponse)(nil),           // 38: gibram.v1.QuantizeIndexResponse
+	(*EntityStatsResponse)(nil),             // 39: gibram.v1.EntityStatsResponse
+	(*GetNeighborsRequest)(nil),             // 40: gibram.v1.GetNeighborsRequest
+	(*SubgraphRequest)(nil),                 // 41: gibram.v1.SubgraphRequest
+	(*SubgraphResponse)(nil),                // 42: gibram.v1.SubgraphResponse
+	(*Community)(nil),                       // 43: gibram.v1.Community
+	(*AddCommunityRequest)(nil),             // 44: gibram.v1.AddCommunityRequest
+	(*ComputeCommunitiesRequest)(nil),       // 45: gibram.v1.ComputeCommunitiesRequest
+	(*ComputeCommunitiesResponse)(nil),      // 46: gibram.v1.ComputeCommunitiesResponse
+	(*PageRankResponse)(nil),                // 47: gibram.v1.PageRankResponse
+	(*LinkTextUnitEntityRequest)(nil),       // 48: gibram.v1.LinkTextUnitEntityRequest
+	(*QueryRequest)(nil),                    // 49: gibram.v1.QueryRequest
+	(*TextUnitResult)(nil),                  // 50: gibram.v1.TextUnitResult
+	(*EntityResult)(nil),                    // 51: gibram.v1.EntityResult
+	(*CommunityResult)(nil),                 // 52: gibram.v1.CommunityResult
+	(*RelationshipResult)(nil),              // 53: gibram.v1.RelationshipResult
+	(*QueryStats)(nil),                      // 54: gibram.v1.QueryStats
+	(*QueryResponse)(nil),                   // 55: gibram.v1.QueryResponse
+	(*QueryStreamChunk)(nil),                // 56: gibram.v1.QueryStreamChunk
+	(*ListQueriesRequest)(nil),              // 57: gibram.v1.ListQueriesRequest
+	(*QueryHistoryEntry)(nil),               // 58: gibram.v1.QueryHistoryEntry
+	(*ListQueriesResponse)(nil),             // 59: gibram.v1.ListQueriesResponse
+	(*QueryStatsSummaryRequest)(nil),        // 60: gibram.v1.QueryStatsSummaryRequest
+	(*QueryStatsSummaryResponse)(nil),       // 61: gibram.v1.QueryStatsSummaryResponse
+	(*CommandStats)(nil),                    // 62: gibram.v1.CommandStats
+	(*StatsResponse)(nil),                   // 63: gibram.v1.StatsResponse
+	(*ExplainRequest)(nil),                  // 64: gibram.v1.ExplainRequest
+	(*SeedInfo)(nil),                        // 65: gibram.v1.SeedInfo
+	(*TraversalStep)(nil),                   // 66: gibram.v1.TraversalStep
+	(*ExplainResponse)(nil),                 // 67: gibram.v1.ExplainResponse
+	(*GetByIDRequest)(nil),                  // 68: gibram.v1.GetByIDRequest
+	(*GetByExternalIDRequest)(nil),          // 69: gibram.v1.GetByExternalIDRequest
+	(*DeleteByIDRequest)(nil),               // 70: gibram.v1.DeleteByIDRequest
+	(*HealthResponse)(nil),                  // 71: gibram.v1.HealthResponse
+	(*ListEntitiesRequest)(nil),             // 72: gibram.v1.ListEntitiesRequest
+	(*MSetEntitiesRequest)(nil),             // 73: gibram.v1.MSetEntitiesRequest
+	(*MGetEntitiesRequest)(nil),             // 74: gibram.v1.MGetEntitiesRequest
+	(*EntitiesResponse)(nil),                // 75: gibram.v1.EntitiesResponse
+	(*MSetDocumentsRequest)(nil),            // 76: gibram.v1.MSetDocumentsRequest
+	(*MGetDocumentsRequest)(nil),            // 77: gibram.v1.MGetDocumentsRequest
+	(*DocumentsResponse)(nil),               // 78: gibram.v1.DocumentsResponse
+	(*MSetTextUnitsRequest)(nil),            // 79: gibram.v1.MSetTextUnitsRequest
+	(*MGetTextUnitsRequest)(nil),            // 80: gibram.v1.MGetTextUnitsRequest
+	(*TextUnitsResponse)(nil),               // 81: gibram.v1.TextUnitsResponse
+	(*MSetRelationshipsRequest)(nil),        // 82: gibram.v1.MSetRelationshipsRequest
+	(*MGetRelationshipsRequest)(nil),        // 83: gibram.v1.MGetRelationshipsRequest
+	(*MDeleteRequest)(nil),                  // 84: gibram.v1.MDeleteRequest
+	(*MDeleteResponse)(nil),                 // 85: gibram.v1.MDeleteResponse
+	(*MLinkTextUnitEntityRequest)(nil),      // 86: gibram.v1.MLinkTextUnitEntityRequest
+	(*LinkResult)(nil),                      // 87: gibram.v1.LinkResult
+	(*MLinkTextUnitEntityResponse)(nil),     // 88: gibram.v1.MLinkTextUnitEntityResponse
+	(*RelationshipsResponse)(nil),           // 89: gibram.v1.RelationshipsResponse
+	(*CommunitiesResponse)(nil),             // 90: gibram.v1.CommunitiesResponse
+	(*ListRelationshipsRequest)(nil),        // 91: gibram.v1.ListRelationshipsRequest
+	(*PipelineRequest)(nil),                 // 92: gibram.v1.PipelineRequest
+	(*PipelineRef)(nil),                     // 93: gibram.v1.PipelineRef
+	(*PipelineResponse)(nil),                // 94: gibram.v1.PipelineResponse
+	(*HierarchicalLeidenRequest)(nil),       // 95: gibram.v1.HierarchicalLeidenRequest
+	(*HierarchicalLeidenResponse)(nil),      // 96: gibram.v1.HierarchicalLeidenResponse
+	(*SaveRequest)(nil),                     // 97: gibram.v1.SaveRequest
+	(*RestoreRequest)(nil),                  // 98: gibram.v1.RestoreRequest
+	(*BackupStatusResponse)(nil),            // 99: gibram.v1.BackupStatusResponse
+	(*LastSaveResponse)(nil),                // 100: gibram.v1.LastSaveResponse
+	(*WALStatusResponse)(nil),               // 101: gibram.v1.WALStatusResponse
+	(*WALTruncateRequest)(nil),              // 102: gibram.v1.WALTruncateRequest
+	(*SnapshotChunk)(nil),                   // 103: gibram.v1.SnapshotChunk
+	(*MergeSessionRequest)(nil),             // 104: gibram.v1.MergeSessionRequest
+	(*GraphDiffRequest)(nil),                // 105: gibram.v1.GraphDiffRequest
+	(*GraphChange)(nil),                     // 106: gibram.v1.GraphChange
+	(*GraphDiffResponse)(nil),               // 107: gibram.v1.GraphDiffResponse
+	(*AuthRequest)(nil),                     // 108: gibram.v1.AuthRequest
+	(*AuthResponse)(nil),                    // 109: gibram.v1.AuthResponse
+	(*RotateKeyRequest)(nil),                // 110: gibram.v1.RotateKeyRequest
+	(*RotateKeyResponse)(nil),               // 111: gibram.v1.RotateKeyResponse
+	nil,                                     // 112: gibram.v1.SessionInfo.MetadataEntry
+	nil,                                     // 113: gibram.v1.SetSessionMetadataRequest.MetadataEntry
+	nil,                                     // 114: gibram.v1.SessionMetadataResponse.MetadataEntry
+	nil,                                     // 115: gibram.v1.Entity.MetadataEntry
+	nil,                                     // 116: gibram.v1.AddEntityRequest.MetadataEntry
+	nil,                                     // 117: gibram.v1.UpdateEntityDescRequest.MetadataEntry
+	nil,                                     // 118: gibram.v1.EntityStatsResponse.EntityTypesEntry
+	nil,                                     // 119: gibram.v1.EntityStatsResponse.RelationshipTypesEntry
+	nil,                                     // 120: gibram.v1.PageRankResponse.ScoresEntry
+	nil,                                     // 121: gibram.v1.QueryRequest.MetadataFiltersEntry
+	nil,                                     // 122: gibram.v1.HealthResponse.ComponentsEntry
+	nil,                                     // 123: gibram.v1.HierarchicalLeidenResponse.LevelCountsEntry
 }
 var file_proto_gibram_proto_depIdxs = []int32{
-	0,  // 0: gibram.v1.Envelope.cmd_type:type_name -> gibram.v1.CommandType
-	6,  // 1: gibram.v1.ListSessionsResponse.sessions:type_name -> gibram.v1.SessionInfo
-	22, // 2: gibram.v1.ComputeCommunitiesResponse.communities:type_name -> gibram.v1.Community
-	14, // 3: gibram.v1.TextUnitResult.textunit:type_name -> gibram.v1.TextUnit
-	16, // 4: gibram.v1.EntityResult.entity:type_name -> gibram.v1.Entity
-	22, // 5: gibram.v1.CommunityResult.community:type_name -> gibram.v1.Community
-	20, // 6: gibram.v1.RelationshipResult.relationship:type_name -> gibram.v1.Relationship
-	28, // 7: gibram.v1.QueryResponse.textunits:type_name -> gibram.v1.TextUnitResult
-	29, // 8: gibram.v1.QueryResponse.entities:type_name -> gibram.v1.EntityResult
-	30, // 9: gibram.v1.QueryResponse.communities:type_name -> gibram.v1.CommunityResult
-	31, // 10: gibram.v1.QueryResponse.relationships:type_name -> gibram.v1.RelationshipResult
-	32, // 11: gibram.v1.QueryResponse.stats:type_name -> gibram.v1.QueryStats
-	35, // 12: gibram.v1.ExplainResponse.seeds:type_name -> gibram.v1.SeedInfo
-	36, // 13: gibram.v1.ExplainResponse.traversal:type_name -> gibram.v1.TraversalStep
-	67, // 14: gibram.v1.HealthResponse.components:type_name -> gibram.v1.HealthResponse.ComponentsEntry
-	17, // 15: gibram.v1.MSetEntitiesRequest.entities:type_name -> gibram.v1.AddEntityRequest
-	16, // 16: gibram.v1.EntitiesResponse.entities:type_name -> gibram.v1.Entity
-	13, // 17: gibram.v1.MSetDocumentsRequest.documents:type_name -> gibram.v1.AddDocumentRequest
-	12, // 18: gibram.v1.DocumentsResponse.documents:type_name -> gibram.v1.Document
-	15, // 19: gibram.v1.MSetTextUnitsRequest.textunits:type_name -> gibram.v1.AddTextUnitRequest
-	14, // 20: gibram.v1.TextUnitsResponse.textunits:type_name -> gibram.v1.TextUnit
-	21, // 21: gibram.v1.MSetRelationshipsRequest.relationships:type_name -> gibram.v1.AddRelationshipRequest
-	20, // 22: gibram.v1.RelationshipsResponse.relationships:type_name -> gibram.v1.Relationship
-	1,  // 23: gibram.v1.PipelineRequest.commands:type_name -> gibram.v1.Envelope
-	1,  // 24: gibram.v1.PipelineResponse.responses:type_name -> gibram.v1.Envelope
-	68, // 25: gibram.v1.HierarchicalLeidenResponse.level_counts:type_name -> gibram.v1.HierarchicalLeidenResponse.LevelCountsEntry
-	26, // [26:26] is the sub-list for method output_type
-	26, // [26:26] is the sub-list for method input_type
-	26, // [26:26] is the sub-list for extension type_name
-	26, // [26:26] is the sub-list for extension extendee
-	0,  // [0:26] is the sub-list for field type_name
+	0,   // 0: gibram.v1.Envelope.cmd_type:type_name -> gibram.v1.CommandType
+	112, // 1: gibram.v1.SessionInfo.metadata:type_name -> gibram.v1.SessionInfo.MetadataEntry
+	8,   // 2: gibram.v1.ListSessionsResponse.sessions:type_name -> gibram.v1.SessionInfo
+	113, // 3: gibram.v1.SetSessionMetadataRequest.metadata:type_name -> gibram.v1.SetSessionMetadataRequest.MetadataEntry
+	114, // 4: gibram.v1.SessionMetadataResponse.metadata:type_name -> gibram.v1.SessionMetadataResponse.MetadataEntry
+	115, // 5: gibram.v1.Entity.metadata:type_name -> gibram.v1.Entity.MetadataEntry
+	116, // 6: gibram.v1.AddEntityRequest.metadata:type_name -> gibram.v1.AddEntityRequest.MetadataEntry
+	117, // 7: gibram.v1.UpdateEntityDescRequest.metadata:type_name -> gibram.v1.UpdateEntityDescRequest.MetadataEntry
+	31,  // 8: gibram.v1.RelationshipTypeStatsResponse.stats:type_name -> gibram.v1.RelationshipTypeStat
+	118, // 9: gibram.v1.EntityStatsResponse.entity_types:type_name -> gibram.v1.EntityStatsResponse.EntityTypesEntry
+	119, // 10: gibram.v1.EntityStatsResponse.relationship_types:type_name -> gibram.v1.EntityStatsResponse.RelationshipTypesEntry
+	1,   // 11: gibram.v1.GetNeighborsRequest.direction:type_name -> gibram.v1.EdgeDirection
+	21,  // 12: gibram.v1.SubgraphResponse.entities:type_name -> gibram.v1.Entity
+	27,  // 13: gibram.v1.SubgraphResponse.relationships:type_name -> gibram.v1.Relationship
+	43,  // 14: gibram.v1.ComputeCommunitiesResponse.communities:type_name -> gibram.v1.Community
+	120, // 15: gibram.v1.PageRankResponse.scores:type_name -> gibram.v1.PageRankResponse.ScoresEntry
+	121, // 16: gibram.v1.QueryRequest.metadata_filters:type_name -> gibram.v1.QueryRequest.MetadataFiltersEntry
+	19,  // 17: gibram.v1.TextUnitResult.textunit:type_name -> gibram.v1.TextUnit
+	21,  // 18: gibram.v1.EntityResult.entity:type_name -> gibram.v1.Entity
+	43,  // 19: gibram.v1.CommunityResult.community:type_name -> gibram.v1.Community
+	27,  // 20: gibram.v1.RelationshipResult.relationship:type_name -> gibram.v1.Relationship
+	50,  // 21: gibram.v1.QueryResponse.textunits:type_name -> gibram.v1.TextUnitResult
+	51,  // 22: gibram.v1.QueryResponse.entities:type_name -> gibram.v1.EntityResult
+	52,  // 23: gibram.v1.QueryResponse.communities:type_name -> gibram.v1.CommunityResult
+	53,  // 24: gibram.v1.QueryResponse.relationships:type_name -> gibram.v1.RelationshipResult
+	54,  // 25: gibram.v1.QueryResponse.stats:type_name -> gibram.v1.QueryStats
+	55,  // 26: gibram.v1.QueryStreamChunk.results:type_name -> gibram.v1.QueryResponse
+	49,  // 27: gibram.v1.QueryHistoryEntry.spec:type_name -> gibram.v1.QueryRequest
+	54,  // 28: gibram.v1.QueryHistoryEntry.stats:type_name -> gibram.v1.QueryStats
+	58,  // 29: gibram.v1.ListQueriesResponse.queries:type_name -> gibram.v1.QueryHistoryEntry
+	62,  // 30: gibram.v1.StatsResponse.commands:type_name -> gibram.v1.CommandStats
+	65,  // 31: gibram.v1.ExplainResponse.seeds:type_name -> gibram.v1.SeedInfo
+	66,  // 32: gibram.v1.ExplainResponse.traversal:type_name -> gibram.v1.TraversalStep
+	122, // 33: gibram.v1.HealthResponse.components:type_name -> gibram.v1.HealthResponse.ComponentsEntry
+	22,  // 34: gibram.v1.MSetEntitiesRequest.entities:type_name -> gibram.v1.AddEntityRequest
+	21,  // 35: gibram.v1.EntitiesResponse.entities:type_name -> gibram.v1.Entity
+	18,  // 36: gibram.v1.MSetDocumentsRequest.documents:type_name -> gibram.v1.AddDocumentRequest
+	17,  // 37: gibram.v1.DocumentsResponse.documents:type_name -> gibram.v1.Document
+	20,  // 38: gibram.v1.MSetTextUnitsRequest.textunits:type_name -> gibram.v1.AddTextUnitRequest
+	19,  // 39: gibram.v1.TextUnitsResponse.textunits:type_name -> gibram.v1.TextUnit
+	28,  // 40: gibram.v1.MSetRelationshipsRequest.relationships:type_name -> gibram.v1.AddRelationshipRequest
+	48,  // 41: gibram.v1.MLinkTextUnitEntityRequest.links:type_name -> gibram.v1.LinkTextUnitEntityRequest
+	87,  // 42: gibram.v1.MLinkTextUnitEntityResponse.results:type_name -> gibram.v1.LinkResult
+	27,  // 43: gibram.v1.RelationshipsResponse.relationships:type_name -> gibram.v1.Relationship
+	43,  // 44: gibram.v1.CommunitiesResponse.communities:type_name -> gibram.v1.Community
+	3,   // 45: gibram.v1.PipelineRequest.commands:type_name -> gibram.v1.Envelope
+	93,  // 46: gibram.v1.PipelineRequest.refs:type_name -> gibram.v1.PipelineRef
+	3,   // 47: gibram.v1.PipelineResponse.responses:type_name -> gibram.v1.Envelope
+	123, // 48: gibram.v1.HierarchicalLeidenResponse.level_counts:type_name -> gibram.v1.HierarchicalLeidenResponse.LevelCountsEntry
+	2,   // 49: gibram.v1.MergeSessionRequest.on_conflict:type_name -> gibram.v1.MergeConflictPolicy
+	21,  // 50: gibram.v1.GraphChange.entity:type_name -> gibram.v1.Entity
+	27,  // 51: gibram.v1.GraphChange.relationship:type_name -> gibram.v1.Relationship
+	106, // 52: gibram.v1.GraphDiffResponse.changes:type_name -> gibram.v1.GraphChange
+	53,  // [53:53] is the sub-list for method output_type
+	53,  // [53:53] is the sub-list for method input_type
+	53,  // [53:53] is the sub-list for extension type_name
+	53,  // [53:53] is the sub-list for extension extendee
+	0,   // [0:53] is the sub-list for field type_name
 }
 
 func init() { file_proto_gibram_proto_init() }
@@ -4854,8 +8580,8 @@ func file_proto_gibram_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_gibram_proto_rawDesc), len(file_proto_gibram_proto_rawDesc)),
-			NumEnums:      1,
-			NumMessages:   68,
+			NumEnums:      3,
+			NumMessages:   121,
 			NumExtensions: 0,
 			NumServices:   0,
 		},